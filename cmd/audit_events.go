@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+const auditTimeLayout = "2006-01-02 15:04:05"
+
+var auditFormats = []string{"table", "json", "csv"}
+
+var auditEventsCmd = &cobra.Command{
+	Use:   "audit:events",
+	Short: "Query and export audit events",
+	Long: `Query the audit log of security-relevant account events, by user or time range.
+The events can be printed as a table, or exported as JSON or CSV.`,
+	Run: runAuditEventsCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(auditEventsCmd)
+
+	auditEventsCmd.Flags().IntP("user", "u", 0, "ID of the user that acted or was acted on")
+	auditEventsCmd.Flags().String("since", "", "Only show events from this time on (YYYY-MM-DD or RFC 3339)")
+	auditEventsCmd.Flags().String("until", "", "Only show events before this time (YYYY-MM-DD or RFC 3339)")
+	auditEventsCmd.Flags().IntP("limit", "l", 100, "The maximum number of events, or 0 for all of them")
+	auditEventsCmd.Flags().StringP("format", "f", "table", "The output format: table, json or csv")
+	auditEventsCmd.Flags().StringP("output", "o", "", "File to write the events to (default: stdout)")
+}
+
+type auditEventsDeps struct {
+	dbNew      dbConstructor
+	findEvents func(database.DatabaseInterface, audit.Query) ([]audit.Event, error)
+}
+
+func defaultAuditEventsDeps() auditEventsDeps {
+	return auditEventsDeps{
+		dbNew: func(cfg databaseConfig, log *logger.Logger) (database.DatabaseInterface, error) {
+			return database.New(cfg, log)
+		},
+		findEvents: audit.Find,
+	}
+}
+
+type auditEventJSON struct {
+	ID        int64           `json:"id"`
+	Type      audit.EventType `json:"type"`
+	ActorID   int             `json:"actor_user_id,omitempty"`
+	TargetID  int             `json:"target_user_id,omitempty"`
+	IP        string          `json:"ip"`
+	UserAgent string          `json:"user_agent"`
+	Metadata  audit.Metadata  `json:"metadata"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+func auditQueryFromFlags(cmd *cobra.Command) (audit.Query, error) {
+	userID, _ := cmd.Flags().GetInt("user")
+	limit, _ := cmd.Flags().GetInt("limit")
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	untilFlag, _ := cmd.Flags().GetString("until")
+
+	since, err := parseAuditTime(sinceFlag)
+
+	if err != nil {
+		return audit.Query{}, fmt.Errorf("invalid --since: %w", err)
+	}
+
+	until, err := parseAuditTime(untilFlag)
+
+	if err != nil {
+		return audit.Query{}, fmt.Errorf("invalid --until: %w", err)
+	}
+
+	return audit.Query{UserID: userID, Since: since, Until: until, Limit: limit}, nil
+}
+
+func writeAuditEvents(w io.Writer, format string, events []audit.Event) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "ID\tTIME\tEVENT\tACTOR\tTARGET\tIP\tMETADATA")
+
+		for _, event := range events {
+			metadata, _ := json.Marshal(event.Metadata)
+
+			_, _ = fmt.Fprintf(
+				tw,
+				"%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				event.ID,
+				event.CreatedAt.Format(auditTimeLayout),
+				event.Type,
+				formatAuditUserID(event.ActorID),
+				formatAuditUserID(event.TargetID),
+				event.IP,
+				metadata,
+			)
+		}
+
+		return tw.Flush()
+
+	case "json":
+		rows := make([]auditEventJSON, len(events))
+
+		for i, event := range events {
+			rows[i] = auditEventJSON(event)
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(rows)
+
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"id", "created_at", "type", "actor_user_id", "target_user_id", "ip", "user_agent", "metadata"})
+
+		for _, event := range events {
+			metadata, _ := json.Marshal(event.Metadata)
+
+			_ = cw.Write([]string{
+				strconv.FormatInt(event.ID, 10),
+				event.CreatedAt.Format(time.RFC3339),
+				string(event.Type),
+				formatAuditUserID(event.ActorID),
+				formatAuditUserID(event.TargetID),
+				event.IP,
+				event.UserAgent,
+				string(metadata),
+			})
+		}
+
+		cw.Flush()
+
+		return cw.Error()
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
+
+func formatAuditUserID(id int) string {
+	if id == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(id)
+}
+
+func runAuditEventsCmdWithDeps(cmd *cobra.Command, deps auditEventsDeps) {
+	log := logger.New(logger.Level(config.GetLogLevel()), os.Stdout)
+
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+
+	if !slices.Contains(auditFormats, format) {
+		log.Error("Unknown output format", logger.Fields{"format": format})
+
+		osExit(1)
+		return
+	}
+
+	query, err := auditQueryFromFlags(cmd)
+
+	if err != nil {
+		log.Error("Invalid filter", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	db, err := deps.dbNew(getDatabaseConfigForCmd(), log)
+
+	if err != nil {
+		log.Error("Failed to connect to database", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	defer func() { _ = db.Close() }()
+
+	events, err := deps.findEvents(db, query)
+
+	if err != nil {
+		log.Error("Failed to find audit events", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	var w io.Writer = os.Stdout
+
+	if output != "" {
+		file, err := os.Create(output)
+
+		if err != nil {
+			log.Error("Failed to create the output file", logger.Fields{"error": err.Error()})
+
+			osExit(1)
+			return
+		}
+
+		defer func() { _ = file.Close() }()
+
+		w = file
+	}
+
+	if err = writeAuditEvents(w, format, events); err != nil {
+		log.Error("Failed to write audit events", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	if output != "" {
+		fmt.Printf("Exported %d audit events to %s\n", len(events), output)
+	}
+}
+
+func runAuditEventsCmd(cmd *cobra.Command, args []string) {
+	runAuditEventsCmdWithDeps(cmd, defaultAuditEventsDeps())
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuditEventsTestCmd(flags map[string]string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().Int("user", 0, "")
+	cmd.Flags().String("since", "", "")
+	cmd.Flags().String("until", "", "")
+	cmd.Flags().Int("limit", 100, "")
+	cmd.Flags().String("format", "table", "")
+	cmd.Flags().String("output", "", "")
+
+	for name, value := range flags {
+		_ = cmd.Flags().Set(name, value)
+	}
+
+	return cmd
+}
+
+func testAuditEvents() []audit.Event {
+	return []audit.Event{
+		{
+			ID:        1,
+			Type:      audit.EventLoginFailed,
+			TargetID:  3,
+			IP:        "192.0.2.1",
+			UserAgent: "curl/8.0.1",
+			Metadata:  audit.Metadata{"method": "password"},
+			CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}
+}
+
+func TestParseAuditTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: ""},
+		{value: "2026-01-02", want: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{value: "2026-01-02T03:04:05Z", want: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseAuditTime(tt.value)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, tt.want.Equal(got))
+		})
+	}
+}
+
+func TestWriteAuditEvents(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    []string
+		wantErr bool
+	}{
+		{format: "table", want: []string{"EVENT", "login.failed", "2026-01-02 03:04:05", `{"method":"password"}`}},
+		{format: "json", want: []string{`"type": "login.failed"`, `"target_user_id": 3`, `"method": "password"`}},
+		{format: "csv", want: []string{"id,created_at,type", `1,2026-01-02T03:04:05Z,login.failed,,3,192.0.2.1,curl/8.0.1,"{""method"":""password""}"`}},
+		{format: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := writeAuditEvents(&buf, tt.format, testAuditEvents())
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			for _, want := range tt.want {
+				assert.Contains(t, buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestRunAuditEventsCmd(t *testing.T) {
+	mockDBNew := func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+		return &mockDB{}, nil
+	}
+
+	findEvents := func(database.DatabaseInterface, audit.Query) ([]audit.Event, error) {
+		return testAuditEvents(), nil
+	}
+
+	output := filepath.Join(t.TempDir(), "audit.csv")
+
+	tests := []struct {
+		name        string
+		flags       map[string]string
+		dbNew       dbConstructor
+		findEvents  func(database.DatabaseInterface, audit.Query) ([]audit.Event, error)
+		expectQuery audit.Query
+		wantOutput  string
+		wantExit    bool
+	}{
+		{
+			name:        "table",
+			flags:       map[string]string{"user": "3", "since": "2026-01-01", "limit": "10"},
+			dbNew:       mockDBNew,
+			findEvents:  findEvents,
+			expectQuery: audit.Query{UserID: 3, Since: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Limit: 10},
+			wantOutput:  "login.failed",
+		},
+		{
+			name:        "export to a file",
+			flags:       map[string]string{"format": "csv", "output": output},
+			dbNew:       mockDBNew,
+			findEvents:  findEvents,
+			expectQuery: audit.Query{Limit: 100},
+			wantOutput:  "Exported 1 audit events to " + output,
+		},
+		{
+			name:     "unknown format",
+			flags:    map[string]string{"format": "xml"},
+			wantExit: true,
+		},
+		{
+			name:     "invalid time",
+			flags:    map[string]string{"until": "tomorrow"},
+			wantExit: true,
+		},
+		{
+			name:  "database connection error",
+			flags: map[string]string{},
+			dbNew: func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+				return nil, errors.New("connection failed")
+			},
+			wantExit: true,
+		},
+		{
+			name:  "find error",
+			flags: map[string]string{},
+			dbNew: mockDBNew,
+			findEvents: func(database.DatabaseInterface, audit.Query) ([]audit.Event, error) {
+				return nil, errors.New("db fail")
+			},
+			expectQuery: audit.Query{Limit: 100},
+			wantExit:    true,
+		},
+		{
+			name:        "output error",
+			flags:       map[string]string{"output": filepath.Join(t.TempDir(), "missing", "audit.txt")},
+			dbNew:       mockDBNew,
+			findEvents:  findEvents,
+			expectQuery: audit.Query{Limit: 100},
+			wantExit:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalOsExit := osExit
+			defer func() { osExit = originalOsExit }()
+
+			osExitCalled := false
+			osExit = func(code int) { osExitCalled = true }
+
+			var query audit.Query
+			deps := auditEventsDeps{dbNew: tt.dbNew}
+
+			if tt.findEvents != nil {
+				deps.findEvents = func(db database.DatabaseInterface, q audit.Query) ([]audit.Event, error) {
+					query = q
+					return tt.findEvents(db, q)
+				}
+			}
+
+			out := captureStdout(func() {
+				runAuditEventsCmdWithDeps(newAuditEventsTestCmd(tt.flags), deps)
+			})
+
+			assert.Equal(t, tt.wantExit, osExitCalled)
+			assert.Contains(t, out, tt.wantOutput)
+			assert.Equal(t, tt.expectQuery, query)
+		})
+	}
+
+	data, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "login.failed")
+}
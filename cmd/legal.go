@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/legal"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var legalPublishCmd = &cobra.Command{
+	Use:   "legal:publish",
+	Short: "Publish a new version of a legal document",
+	Long: `Publish a new version of the terms of service or the privacy policy from a text file.
+Logged in users are asked to accept the new version before they can continue.`,
+	Run: runLegalPublishCmd,
+}
+
+var legalCoverageCmd = &cobra.Command{
+	Use:   "legal:coverage",
+	Short: "Report how many users accepted the legal documents",
+	Long:  `Report how many of the users have accepted each version of the terms of service and the privacy policy.`,
+	Run:   runLegalCoverageCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(legalPublishCmd)
+	rootCmd.AddCommand(legalCoverageCmd)
+
+	legalPublishCmd.Flags().StringP("kind", "k", "", fmt.Sprintf("The kind of document: %q or %q", legal.KindTerms, legal.KindPrivacy))
+	legalPublishCmd.Flags().String("version", "", "The version of the document, e.g. a date")
+	legalPublishCmd.Flags().StringP("file", "f", "", "Text file with the contents of the document")
+}
+
+type legalDeps struct {
+	dbNew    dbConstructor
+	publish  func(database.DatabaseInterface, legal.Kind, string, string) (*legal.Document, error)
+	coverage func(database.DatabaseInterface) ([]legal.Coverage, error)
+}
+
+func defaultLegalDeps() legalDeps {
+	return legalDeps{
+		dbNew: func(cfg databaseConfig, log *logger.Logger) (database.DatabaseInterface, error) {
+			return database.New(cfg, log)
+		},
+		publish:  legal.Publish,
+		coverage: legal.CoverageReport,
+	}
+}
+
+func runLegalPublishCmdWithDeps(cmd *cobra.Command, deps legalDeps) {
+	log := logger.New(logger.Level(config.GetLogLevel()), os.Stdout)
+
+	kindFlag, _ := cmd.Flags().GetString("kind")
+	version, _ := cmd.Flags().GetString("version")
+	file, _ := cmd.Flags().GetString("file")
+
+	kind, err := legal.ParseKind(kindFlag)
+
+	if err != nil {
+		log.Error("Unknown kind of document", logger.Fields{"kind": kindFlag})
+
+		osExit(1)
+		return
+	}
+
+	if version == "" || file == "" {
+		log.Error("A version and a file must be provided.", nil)
+
+		osExit(1)
+		return
+	}
+
+	body, err := os.ReadFile(file)
+
+	if err != nil {
+		log.Error("Failed to read the document", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	db, err := deps.dbNew(getDatabaseConfigForCmd(), log)
+
+	if err != nil {
+		log.Error("Failed to connect to database", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	defer func() { _ = db.Close() }()
+
+	doc, err := deps.publish(db, kind, version, string(body))
+
+	if err != nil {
+		if errors.Is(err, legal.ErrInvalidVersion) || errors.Is(err, legal.ErrEmptyBody) || errors.Is(err, legal.ErrVersionExists) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		} else {
+			log.Error("Failed to publish the document", logger.Fields{"error": err.Error()})
+		}
+
+		osExit(1)
+		return
+	}
+
+	fmt.Printf("Published version %s of the %s\n", doc.Version, doc.Title())
+}
+
+func writeLegalCoverage(w io.Writer, report []legal.Coverage) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "DOCUMENT\tVERSION\tPUBLISHED\tCURRENT\tACCEPTED\tUSERS\tCOVERAGE")
+
+	for _, coverage := range report {
+		current := ""
+
+		if coverage.Current {
+			current = "yes"
+		}
+
+		_, _ = fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%s\t%d\t%d\t%.1f%%\n",
+			coverage.Document.Title(),
+			coverage.Document.Version,
+			coverage.Document.PublishedAt.Format(auditTimeLayout),
+			current,
+			coverage.Accepted,
+			coverage.Users,
+			coverage.Percentage(),
+		)
+	}
+
+	return tw.Flush()
+}
+
+func runLegalCoverageCmdWithDeps(deps legalDeps) {
+	log := logger.New(logger.Level(config.GetLogLevel()), os.Stdout)
+
+	db, err := deps.dbNew(getDatabaseConfigForCmd(), log)
+
+	if err != nil {
+		log.Error("Failed to connect to database", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	defer func() { _ = db.Close() }()
+
+	report, err := deps.coverage(db)
+
+	if err != nil {
+		log.Error("Failed to report the coverage of the legal documents", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	if len(report) == 0 {
+		fmt.Println("No legal documents have been published")
+		return
+	}
+
+	if err = writeLegalCoverage(os.Stdout, report); err != nil {
+		log.Error("Failed to write the coverage", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+}
+
+func runLegalPublishCmd(cmd *cobra.Command, args []string) {
+	runLegalPublishCmdWithDeps(cmd, defaultLegalDeps())
+}
+
+func runLegalCoverageCmd(cmd *cobra.Command, args []string) {
+	runLegalCoverageCmdWithDeps(defaultLegalDeps())
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/legal"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLegalPublishTestCmd(flags map[string]string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("kind", "", "")
+	cmd.Flags().String("version", "", "")
+	cmd.Flags().String("file", "", "")
+
+	for name, value := range flags {
+		_ = cmd.Flags().Set(name, value)
+	}
+
+	return cmd
+}
+
+func mockLegalDBNew(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+	return &mockDB{}, nil
+}
+
+func testLegalCoverage() []legal.Coverage {
+	publishedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	return []legal.Coverage{
+		{Document: legal.Document{Kind: legal.KindPrivacy, Version: "2026-10", PublishedAt: publishedAt}, Current: true, Accepted: 1, Users: 3},
+		{Document: legal.Document{Kind: legal.KindPrivacy, Version: "2026-01", PublishedAt: publishedAt}, Accepted: 3, Users: 3},
+	}
+}
+
+func TestRunLegalPublishCmd(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "terms.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("The terms"), 0o600))
+
+	published := func(_ database.DatabaseInterface, kind legal.Kind, version string, body string) (*legal.Document, error) {
+		assert.Equal(t, legal.KindTerms, kind)
+		assert.Equal(t, "The terms", body)
+
+		return &legal.Document{ID: 1, Kind: kind, Version: version, Body: body}, nil
+	}
+
+	tests := []struct {
+		name       string
+		flags      map[string]string
+		dbNew      dbConstructor
+		publish    func(database.DatabaseInterface, legal.Kind, string, string) (*legal.Document, error)
+		wantOutput string
+		wantExit   bool
+	}{
+		{
+			name:       "success",
+			flags:      map[string]string{"kind": "terms", "version": "2026-10", "file": file},
+			dbNew:      mockLegalDBNew,
+			publish:    published,
+			wantOutput: "Published version 2026-10 of the Terms of Service",
+		},
+		{
+			name:     "unknown kind",
+			flags:    map[string]string{"kind": "cookies", "version": "2026-10", "file": file},
+			dbNew:    mockLegalDBNew,
+			publish:  published,
+			wantExit: true,
+		},
+		{
+			name:     "missing version",
+			flags:    map[string]string{"kind": "terms", "file": file},
+			dbNew:    mockLegalDBNew,
+			publish:  published,
+			wantExit: true,
+		},
+		{
+			name:     "missing file",
+			flags:    map[string]string{"kind": "terms", "version": "2026-10", "file": filepath.Join(t.TempDir(), "missing.txt")},
+			dbNew:    mockLegalDBNew,
+			publish:  published,
+			wantExit: true,
+		},
+		{
+			name:  "database connection error",
+			flags: map[string]string{"kind": "terms", "version": "2026-10", "file": file},
+			dbNew: func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+				return nil, errors.New("connection failed")
+			},
+			publish:  published,
+			wantExit: true,
+		},
+		{
+			name:  "version exists",
+			flags: map[string]string{"kind": "terms", "version": "2026-10", "file": file},
+			dbNew: mockLegalDBNew,
+			publish: func(database.DatabaseInterface, legal.Kind, string, string) (*legal.Document, error) {
+				return nil, legal.ErrVersionExists
+			},
+			wantExit: true,
+		},
+		{
+			name:  "publish error",
+			flags: map[string]string{"kind": "terms", "version": "2026-10", "file": file},
+			dbNew: mockLegalDBNew,
+			publish: func(database.DatabaseInterface, legal.Kind, string, string) (*legal.Document, error) {
+				return nil, errors.New("db fail")
+			},
+			wantExit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalOsExit := osExit
+			defer func() { osExit = originalOsExit }()
+
+			osExitCalled := false
+			osExit = func(code int) { osExitCalled = true }
+
+			output, _ := captureStdoutStderr(func() {
+				runLegalPublishCmdWithDeps(newLegalPublishTestCmd(tt.flags), legalDeps{dbNew: tt.dbNew, publish: tt.publish})
+			})
+
+			assert.Equal(t, tt.wantExit, osExitCalled)
+			assert.Contains(t, output, tt.wantOutput)
+		})
+	}
+}
+
+func TestWriteLegalCoverage(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := writeLegalCoverage(&buf, testLegalCoverage())
+	assert.NoError(t, err)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 3)
+	assert.Regexp(t, `^DOCUMENT\s+VERSION\s+PUBLISHED\s+CURRENT\s+ACCEPTED\s+USERS\s+COVERAGE$`, string(lines[0]))
+	assert.Regexp(t, `^Privacy Policy\s+2026-10\s+2026-10-01 12:00:00\s+yes\s+1\s+3\s+33\.3%$`, string(lines[1]))
+	assert.Regexp(t, `^Privacy Policy\s+2026-01\s+2026-10-01 12:00:00\s+3\s+3\s+100\.0%$`, string(lines[2]))
+}
+
+func TestRunLegalCoverageCmd(t *testing.T) {
+	tests := []struct {
+		name       string
+		dbNew      dbConstructor
+		coverage   func(database.DatabaseInterface) ([]legal.Coverage, error)
+		wantOutput string
+		wantExit   bool
+	}{
+		{
+			name:       "success",
+			dbNew:      mockLegalDBNew,
+			coverage:   func(database.DatabaseInterface) ([]legal.Coverage, error) { return testLegalCoverage(), nil },
+			wantOutput: "33.3%",
+		},
+		{
+			name:       "no documents",
+			dbNew:      mockLegalDBNew,
+			coverage:   func(database.DatabaseInterface) ([]legal.Coverage, error) { return []legal.Coverage{}, nil },
+			wantOutput: "No legal documents have been published",
+		},
+		{
+			name: "database connection error",
+			dbNew: func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+				return nil, errors.New("connection failed")
+			},
+			coverage: func(database.DatabaseInterface) ([]legal.Coverage, error) {
+				return nil, errors.New("should not be called")
+			},
+			wantExit: true,
+		},
+		{
+			name:     "coverage error",
+			dbNew:    mockLegalDBNew,
+			coverage: func(database.DatabaseInterface) ([]legal.Coverage, error) { return nil, errors.New("db fail") },
+			wantExit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalOsExit := osExit
+			defer func() { osExit = originalOsExit }()
+
+			osExitCalled := false
+			osExit = func(code int) { osExitCalled = true }
+
+			output := captureStdout(func() {
+				runLegalCoverageCmdWithDeps(legalDeps{dbNew: tt.dbNew, coverage: tt.coverage})
+			})
+
+			assert.Equal(t, tt.wantExit, osExitCalled)
+			assert.Contains(t, output, tt.wantOutput)
+		})
+	}
+}
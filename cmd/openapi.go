@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/server/routes"
+	"github.com/spf13/cobra"
+)
+
+var openAPICmd = &cobra.Command{
+	Use:   "openapi:export",
+	Short: "Export the OpenAPI document",
+	Long:  `Export the OpenAPI document of the JSON API, to generate clients from.`,
+	Run:   runOpenAPICmd,
+}
+
+func init() {
+	rootCmd.AddCommand(openAPICmd)
+	openAPICmd.Flags().StringP("output", "o", "", "File to write the document to (default: stdout)")
+}
+
+func runOpenAPICmd(cmd *cobra.Command, args []string) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	output, _ := cmd.Flags().GetString("output")
+
+	doc, err := routes.OpenAPIDocument()
+
+	if err != nil {
+		log.Error("Failed to generate the OpenAPI document", logger.Fields{"error": err.Error()})
+		return
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+
+	if err != nil {
+		log.Error("Failed to encode the OpenAPI document", logger.Fields{"error": err.Error()})
+		return
+	}
+
+	if output == "" {
+		fmt.Println(string(data))
+		return
+	}
+
+	if err = os.WriteFile(output, append(data, '\n'), 0o644); err != nil {
+		log.Error("Failed to write the OpenAPI document", logger.Fields{"error": err.Error()})
+		return
+	}
+
+	fmt.Printf("OpenAPI document written to %s\n", output)
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/openapi"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunOpenAPICmd(t *testing.T) {
+	viper.Reset()
+	viper.Set("site.name", "Test Site")
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name: "stdout",
+			want: `"openapi": "` + openapi.Version + `"`,
+		},
+		{
+			name:   "file",
+			output: filepath.Join(dir, "openapi.json"),
+			want:   "OpenAPI document written to",
+		},
+		{
+			name:   "write error",
+			output: filepath.Join(dir, "missing", "openapi.json"),
+			want:   "Failed to write the OpenAPI document",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().String("output", tc.output, "")
+
+			output := captureOutput(func() {
+				runOpenAPICmd(cmd, []string{})
+			})
+
+			assert.Contains(t, output, tc.want)
+
+			if tc.name == "file" {
+				data, err := os.ReadFile(tc.output)
+				assert.NoError(t, err)
+
+				var doc openapi.Document
+				assert.NoError(t, json.Unmarshal(data, &doc))
+				assert.Equal(t, "Test Site API", doc.Info.Title)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/spf13/cobra"
+)
+
+var tokenCleanupCmd = &cobra.Command{
+	Use:   "token:cleanup",
+	Short: "Delete expired and used tokens",
+	Long:  `Delete all single-use tokens, such as password reset links, that have expired or have already been used.`,
+	Run:   runTokenCleanupCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(tokenCleanupCmd)
+}
+
+type tokenCleanupDeps struct {
+	dbNew         dbConstructor
+	deleteExpired func(database.DatabaseInterface) (int64, error)
+}
+
+func defaultTokenCleanupDeps() tokenCleanupDeps {
+	return tokenCleanupDeps{
+		dbNew: func(cfg databaseConfig, log *logger.Logger) (database.DatabaseInterface, error) {
+			return database.New(cfg, log)
+		},
+		deleteExpired: user.DeleteExpiredTokens,
+	}
+}
+
+func runTokenCleanupCmdWithDeps(deps tokenCleanupDeps) {
+	log := logger.New(logger.Level(config.GetLogLevel()), os.Stdout)
+
+	db, err := deps.dbNew(getDatabaseConfigForCmd(), log)
+
+	if err != nil {
+		log.Error("Failed to connect to database", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	defer func() { _ = db.Close() }()
+
+	deleted, err := deps.deleteExpired(db)
+
+	if err != nil {
+		log.Error("Failed to delete expired tokens", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	fmt.Printf("Deleted %d expired tokens\n", deleted)
+}
+
+func runTokenCleanupCmd(cmd *cobra.Command, args []string) {
+	runTokenCleanupCmdWithDeps(defaultTokenCleanupDeps())
+}
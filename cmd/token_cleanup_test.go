@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunTokenCleanupCmd(t *testing.T) {
+	mockDBNew := func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+		return &mockDB{}, nil
+	}
+
+	tests := []struct {
+		name          string
+		dbNew         dbConstructor
+		deleteExpired func(database.DatabaseInterface) (int64, error)
+		wantOutput    string
+		wantExit      bool
+	}{
+		{
+			name:          "success",
+			dbNew:         mockDBNew,
+			deleteExpired: func(database.DatabaseInterface) (int64, error) { return 3, nil },
+			wantOutput:    "Deleted 3 expired tokens",
+		},
+		{
+			name: "database connection error",
+			dbNew: func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+				return nil, errors.New("connection failed")
+			},
+			deleteExpired: func(database.DatabaseInterface) (int64, error) { return 0, errors.New("should not be called") },
+			wantExit:      true,
+		},
+		{
+			name:          "delete error",
+			dbNew:         mockDBNew,
+			deleteExpired: func(database.DatabaseInterface) (int64, error) { return 0, errors.New("db fail") },
+			wantExit:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalOsExit := osExit
+			defer func() { osExit = originalOsExit }()
+
+			osExitCalled := false
+			osExit = func(code int) { osExitCalled = true }
+
+			output := captureStdout(func() {
+				runTokenCleanupCmdWithDeps(tokenCleanupDeps{dbNew: tt.dbNew, deleteExpired: tt.deleteExpired})
+			})
+
+			assert.Equal(t, tt.wantExit, osExitCalled)
+			assert.Contains(t, output, tt.wantOutput)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/export"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var userExportCmd = &cobra.Command{
+	Use:   "user:export",
+	Short: "Export the data of a user as a ZIP archive",
+	Long: `Export everything that is stored about a user as a ZIP archive with a JSON file,
+like the "Download my data" action of the account page. Use it to answer subject access requests.`,
+	Run: runUserExportCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(userExportCmd)
+
+	userExportCmd.Flags().IntP("id", "i", 0, "ID of the user to export")
+	userExportCmd.Flags().StringP("output", "o", "", "File to write the archive to (default: user-<id>-export.zip)")
+}
+
+type userExportDeps struct {
+	dbNew       dbConstructor
+	newSessions func(db database.DatabaseInterface, log *logger.Logger) (sessionstore.Backend, error)
+	newStorage  func() (storage.Storage, error)
+	findByID    func(database.DatabaseInterface, int) (*user.User, error)
+	collect     func(context.Context, database.DatabaseInterface, sessionstore.Backend, storage.Storage, *user.User) (*export.Data, error)
+	writeFile   func(name string, data []byte, perm os.FileMode) error
+	recordEvent func(database.DatabaseInterface, *audit.Event) error
+}
+
+func newSessionBackendForCmd(db database.DatabaseInterface, log *logger.Logger) (sessionstore.Backend, error) {
+	if !viper.GetBool("redis.enable") {
+		return sessionstore.NewDatabaseBackend(db), nil
+	}
+
+	redisClient, err := newRedisForCmd(log)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sessionstore.NewRedisBackend(redisClient), nil
+}
+
+func defaultUserExportDeps() userExportDeps {
+	return userExportDeps{
+		dbNew: func(cfg databaseConfig, log *logger.Logger) (database.DatabaseInterface, error) {
+			return database.New(cfg, log)
+		},
+		newSessions: newSessionBackendForCmd,
+		newStorage:  storage.FromConfig,
+		findByID:    user.FindByID,
+		collect:     export.Collect,
+		writeFile:   os.WriteFile,
+		recordEvent: audit.Record,
+	}
+}
+
+func runUserExportCmdWithDeps(cmd *cobra.Command, deps userExportDeps) {
+	log := logger.New(logger.Level(config.GetLogLevel()), os.Stdout)
+
+	id, _ := cmd.Flags().GetInt("id")
+	output, _ := cmd.Flags().GetString("output")
+
+	if id <= 0 {
+		log.Error("The ID of the user must be provided with --id", nil)
+
+		osExit(1)
+		return
+	}
+
+	if output == "" {
+		output = fmt.Sprintf("user-%d-export.zip", id)
+	}
+
+	db, err := deps.dbNew(getDatabaseConfigForCmd(), log)
+
+	if err != nil {
+		log.Error("Failed to connect to database", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	defer func() { _ = db.Close() }()
+
+	foundUser, err := deps.findByID(db, id)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error finding user: %v\n", err)
+
+		osExit(1)
+		return
+	}
+
+	sessions, err := deps.newSessions(db, log)
+
+	if err != nil {
+		log.Error("Failed to connect to the session store", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	store, err := deps.newStorage()
+
+	if err != nil {
+		log.Error("Failed to initialize file storage", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	data, err := deps.collect(context.Background(), db, sessions, store, foundUser)
+
+	if err != nil {
+		log.Error("Failed to collect the data of the user", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	archive, err := data.Archive()
+
+	if err != nil {
+		log.Error("Failed to create the archive", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	// The archive holds personal data, so only the owner of the file may read it.
+	if err = deps.writeFile(output, archive, 0o600); err != nil {
+		log.Error("Failed to write the archive", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	err = deps.recordEvent(db, &audit.Event{
+		Type:     audit.EventDataExportRequested,
+		TargetID: foundUser.GetID(),
+		Metadata: audit.Metadata{"method": "cli"},
+	})
+
+	if err != nil {
+		log.Warn("Failed to record an audit event", logger.Fields{"error": err.Error()})
+	}
+
+	fmt.Printf("Exported the data of user %s to %s\n", foundUser.GetEmail(), output)
+}
+
+func runUserExportCmd(cmd *cobra.Command, args []string) {
+	runUserExportCmdWithDeps(cmd, defaultUserExportDeps())
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/export"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunUserExportCmd(t *testing.T) {
+	mockDBNew := func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+		return &mockDB{}, nil
+	}
+
+	tests := []struct {
+		name           string
+		id             int
+		output         string
+		dbNew          dbConstructor
+		findErr        error
+		newSessionsErr error
+		newStorageErr  error
+		collectErr     error
+		writeErr       error
+		recordErr      error
+		wantFile       string
+		wantOutput     string
+		wantEvent      bool
+		wantExit       bool
+	}{
+		{
+			name:       "success",
+			id:         1,
+			wantFile:   "user-1-export.zip",
+			wantOutput: "Exported the data of user user@example.com to user-1-export.zip",
+			wantEvent:  true,
+		},
+		{
+			name:       "custom output",
+			id:         1,
+			output:     "export.zip",
+			wantFile:   "export.zip",
+			wantOutput: "to export.zip",
+			wantEvent:  true,
+		},
+		{
+			name:       "audit error",
+			id:         1,
+			recordErr:  errors.New("db fail"),
+			wantFile:   "user-1-export.zip",
+			wantOutput: "Exported the data of user user@example.com",
+			wantEvent:  true,
+		},
+		{
+			name:     "missing ID",
+			wantExit: true,
+		},
+		{
+			name: "database error",
+			id:   1,
+			dbNew: func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+				return nil, errors.New("connection failed")
+			},
+			wantExit: true,
+		},
+		{
+			name:     "user not found",
+			id:       1,
+			findErr:  user.ErrInvalidCredentials,
+			wantExit: true,
+		},
+		{
+			name:           "session store error",
+			id:             1,
+			newSessionsErr: errors.New("redis fail"),
+			wantExit:       true,
+		},
+		{
+			name:          "storage error",
+			id:            1,
+			newStorageErr: errors.New("unknown driver"),
+			wantExit:      true,
+		},
+		{
+			name:       "collect error",
+			id:         1,
+			collectErr: errors.New("db fail"),
+			wantExit:   true,
+		},
+		{
+			name:     "write error",
+			id:       1,
+			writeErr: errors.New("disk full"),
+			wantFile: "user-1-export.zip",
+			wantExit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalOsExit := osExit
+			defer func() { osExit = originalOsExit }()
+
+			osExitCalled := false
+			osExit = func(code int) { osExitCalled = true }
+
+			var (
+				writtenFile string
+				events      []audit.Event
+			)
+
+			deps := userExportDeps{
+				dbNew: mockDBNew,
+				newSessions: func(database.DatabaseInterface, *logger.Logger) (sessionstore.Backend, error) {
+					return nil, tt.newSessionsErr
+				},
+				newStorage: func() (storage.Storage, error) {
+					return nil, tt.newStorageErr
+				},
+				findByID: func(_ database.DatabaseInterface, id int) (*user.User, error) {
+					if tt.findErr != nil {
+						return nil, tt.findErr
+					}
+
+					return user.New(user.UserFields{Id: id, Email: "user@example.com"}), nil
+				},
+				collect: func(_ context.Context, _ database.DatabaseInterface, _ sessionstore.Backend, _ storage.Storage, usr *user.User) (*export.Data, error) {
+					return &export.Data{User: export.Account{ID: usr.GetID()}}, tt.collectErr
+				},
+				writeFile: func(name string, data []byte, perm os.FileMode) error {
+					writtenFile = name
+
+					assert.NotEmpty(t, data)
+					assert.Equal(t, os.FileMode(0o600), perm)
+
+					return tt.writeErr
+				},
+				recordEvent: func(_ database.DatabaseInterface, event *audit.Event) error {
+					events = append(events, *event)
+					return tt.recordErr
+				},
+			}
+
+			if tt.dbNew != nil {
+				deps.dbNew = tt.dbNew
+			}
+
+			cmd := &cobra.Command{}
+			cmd.Flags().Int("id", tt.id, "")
+			cmd.Flags().String("output", tt.output, "")
+
+			output := captureStdout(func() { runUserExportCmdWithDeps(cmd, deps) })
+
+			assert.Equal(t, tt.wantExit, osExitCalled)
+			assert.Equal(t, tt.wantFile, writtenFile)
+			assert.Contains(t, output, tt.wantOutput)
+
+			if !tt.wantEvent {
+				assert.Empty(t, events)
+				return
+			}
+
+			assert.Len(t, events, 1)
+			assert.Equal(t, audit.EventDataExportRequested, events[0].Type)
+			assert.Equal(t, 1, events[0].TargetID)
+		})
+	}
+}
+
+func TestNewSessionBackendForCmd(t *testing.T) {
+	log := logger.New(logger.InfoLevel, io.Discard)
+	origEnable := viper.Get("redis.enable")
+
+	t.Cleanup(func() { viper.Set("redis.enable", origEnable) })
+
+	viper.Set("redis.enable", false)
+	backend, err := newSessionBackendForCmd(&mockDB{}, log)
+	assert.NoError(t, err)
+	assert.IsType(t, &sessionstore.DatabaseBackend{}, backend)
+
+	viper.Set("redis.enable", true)
+	backend, err = newSessionBackendForCmd(&mockDB{}, log)
+	assert.NoError(t, err)
+	assert.IsType(t, &sessionstore.RedisBackend{}, backend)
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/avatar"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/spf13/cobra"
+)
+
+var userPurgeCmd = &cobra.Command{
+	Use:   "user:purge",
+	Short: "Remove deleted accounts after their grace period",
+	Long: `Remove the accounts that were deleted longer ago than the grace period (site.deletiongracedays) for good.
+Until then, deleted accounts can still be restored. Run this command regularly, for example from cron.`,
+	Run: runUserPurgeCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(userPurgeCmd)
+}
+
+type userPurgeDeps struct {
+	dbNew        dbConstructor
+	purgeDeleted func(database.DatabaseInterface, time.Time) (int64, []string, error)
+	newStorage   func() (storage.Storage, error)
+	now          func() time.Time
+}
+
+func defaultUserPurgeDeps() userPurgeDeps {
+	return userPurgeDeps{
+		dbNew: func(cfg databaseConfig, log *logger.Logger) (database.DatabaseInterface, error) {
+			return database.New(cfg, log)
+		},
+		purgeDeleted: user.PurgeDeleted,
+		newStorage:   storage.FromConfig,
+		now:          time.Now,
+	}
+}
+
+func runUserPurgeCmdWithDeps(deps userPurgeDeps) {
+	log := logger.New(logger.Level(config.GetLogLevel()), os.Stdout)
+
+	db, err := deps.dbNew(getDatabaseConfigForCmd(), log)
+
+	if err != nil {
+		log.Error("Failed to connect to database", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	defer func() { _ = db.Close() }()
+
+	purged, avatars, err := deps.purgeDeleted(db, deps.now().Add(-config.DeletionGracePeriod()))
+
+	if err != nil {
+		log.Error("Failed to purge deleted users", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	fmt.Printf("Purged %d deleted users\n", purged)
+
+	if len(avatars) > 0 {
+		removePurgedAvatars(log, deps, avatars)
+	}
+}
+
+func removePurgedAvatars(log *logger.Logger, deps userPurgeDeps, avatars []string) {
+	store, err := deps.newStorage()
+
+	if err != nil {
+		log.Error("Failed to get the avatar storage", logger.Fields{"error": err.Error()})
+		return
+	}
+
+	for _, key := range avatars {
+		if err = avatar.Remove(context.Background(), store, key); err != nil {
+			log.Warn("Could not remove the avatar files", logger.Fields{"key": key, "error": err.Error()})
+		}
+	}
+}
+
+func runUserPurgeCmd(cmd *cobra.Command, args []string) {
+	runUserPurgeCmdWithDeps(defaultUserPurgeDeps())
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/avatar"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunUserPurgeCmd(t *testing.T) {
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	mockDBNew := func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+		return &mockDB{}, nil
+	}
+
+	tests := []struct {
+		name       string
+		graceDays  int
+		dbNew      dbConstructor
+		purgeErr   error
+		avatars    []string
+		storageErr error
+		wantBefore time.Time
+		wantOutput string
+		wantExit   bool
+	}{
+		{
+			name:       "success",
+			graceDays:  30,
+			dbNew:      mockDBNew,
+			wantBefore: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+			wantOutput: "Purged 2 deleted users",
+		},
+		{
+			name:       "with avatars",
+			graceDays:  30,
+			dbNew:      mockDBNew,
+			avatars:    []string{"avatars/1/abc"},
+			wantBefore: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+			wantOutput: "Purged 2 deleted users",
+		},
+		{
+			name:       "storage error",
+			graceDays:  30,
+			dbNew:      mockDBNew,
+			avatars:    []string{"avatars/1/abc"},
+			storageErr: storage.ErrUnknownDriver,
+			wantBefore: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+			wantOutput: "Purged 2 deleted users",
+		},
+		{
+			name:       "without grace period",
+			graceDays:  0,
+			dbNew:      mockDBNew,
+			wantBefore: now,
+			wantOutput: "Purged 2 deleted users",
+		},
+		{
+			name:      "database connection error",
+			graceDays: 30,
+			dbNew: func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+				return nil, errors.New("connection failed")
+			},
+			wantExit: true,
+		},
+		{
+			name:      "purge error",
+			graceDays: 30,
+			dbNew:     mockDBNew,
+			purgeErr:  errors.New("db fail"),
+			wantExit:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("site.deletiongracedays", tt.graceDays)
+			defer viper.Reset()
+
+			originalOsExit := osExit
+			defer func() { osExit = originalOsExit }()
+
+			osExitCalled := false
+			osExit = func(code int) { osExitCalled = true }
+
+			dir := t.TempDir()
+			assert.NoError(t, os.MkdirAll(filepath.Join(dir, "avatars", "1"), 0o755))
+
+			for _, size := range avatar.Sizes {
+				assert.NoError(t, os.WriteFile(filepath.Join(dir, filepath.FromSlash(avatar.Key("avatars/1/abc", size))), []byte("avatar"), 0o644))
+			}
+
+			var purgedBefore time.Time
+
+			output := captureStdout(func() {
+				runUserPurgeCmdWithDeps(userPurgeDeps{
+					dbNew: tt.dbNew,
+					purgeDeleted: func(_ database.DatabaseInterface, before time.Time) (int64, []string, error) {
+						purgedBefore = before
+						return 2, tt.avatars, tt.purgeErr
+					},
+					newStorage: func() (storage.Storage, error) {
+						if tt.storageErr != nil {
+							return nil, tt.storageErr
+						}
+
+						return storage.NewLocalStorage(dir, "/uploads"), nil
+					},
+					now: func() time.Time { return now },
+				})
+			})
+
+			assert.Equal(t, tt.wantExit, osExitCalled)
+			assert.Contains(t, output, tt.wantOutput)
+
+			if !tt.wantExit {
+				assert.Equal(t, tt.wantBefore, purgedBefore)
+			}
+
+			files, err := os.ReadDir(filepath.Join(dir, "avatars", "1"))
+			assert.NoError(t, err)
+
+			if len(tt.avatars) > 0 && tt.storageErr == nil {
+				assert.Empty(t, files)
+			} else {
+				assert.Len(t, files, len(avatar.Sizes))
+			}
+		})
+	}
+}
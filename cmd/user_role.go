@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/spf13/cobra"
+)
+
+var userRoleGrantCmd = &cobra.Command{
+	Use:   "user:role:grant",
+	Short: "Grant a role to a user",
+	Run:   runUserRoleGrantCmd,
+}
+
+var userRoleRevokeCmd = &cobra.Command{
+	Use:   "user:role:revoke",
+	Short: "Revoke a role from a user",
+	Run:   runUserRoleRevokeCmd,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{userRoleGrantCmd, userRoleRevokeCmd} {
+		rootCmd.AddCommand(cmd)
+
+		cmd.Flags().StringP("email", "e", "", "Email of the user")
+		cmd.Flags().IntP("id", "i", 0, "ID of the user (takes precedence over email)")
+		cmd.Flags().StringP("role", "r", "", fmt.Sprintf("Name of the role, e.g. %q", user.RoleAdmin))
+	}
+}
+
+type userRoleDeps struct {
+	dbNew       dbConstructor
+	findByID    func(database.DatabaseInterface, int) (*user.User, error)
+	findByEmail func(database.DatabaseInterface, string) (*user.User, error)
+	apply       func(database.DatabaseInterface, *user.User, string) error
+	event       audit.EventType
+	recordEvent func(database.DatabaseInterface, *audit.Event) error
+}
+
+func defaultUserRoleDeps(apply func(database.DatabaseInterface, *user.User, string) error, event audit.EventType) userRoleDeps {
+	return userRoleDeps{
+		dbNew: func(cfg databaseConfig, log *logger.Logger) (database.DatabaseInterface, error) {
+			return database.New(cfg, log)
+		},
+		findByID:    user.FindByID,
+		findByEmail: user.FindByEmail,
+		apply:       apply,
+		event:       event,
+		recordEvent: audit.Record,
+	}
+}
+
+func grantRole(db database.DatabaseInterface, usr *user.User, role string) error {
+	return usr.GrantRole(db, role)
+}
+
+func revokeRole(db database.DatabaseInterface, usr *user.User, role string) error {
+	return usr.RevokeRole(db, role)
+}
+
+func runUserRoleCmdWithDeps(cmd *cobra.Command, deps userRoleDeps, done string) {
+	log := logger.New(logger.Level(config.GetLogLevel()), os.Stdout)
+
+	identifier, _ := cmd.Flags().GetString("email")
+	flagID, _ := cmd.Flags().GetInt("id")
+	role, _ := cmd.Flags().GetString("role")
+
+	if flagID > 0 {
+		identifier = strconv.Itoa(flagID)
+	}
+
+	if identifier == "" {
+		input, err := promptForString("Enter user's email or ID: ")
+
+		if err != nil || input == "" {
+			log.Error("Email or ID must be provided.", nil)
+
+			osExit(1)
+			return
+		}
+
+		identifier = input
+	}
+
+	if role == "" {
+		input, err := promptForString("Enter the role: ")
+
+		if err != nil || input == "" {
+			log.Error("A role must be provided.", nil)
+
+			osExit(1)
+			return
+		}
+
+		role = input
+	}
+
+	db, err := deps.dbNew(getDatabaseConfigForCmd(), log)
+
+	if err != nil {
+		log.Error("Failed to connect to database", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	defer func() { _ = db.Close() }()
+
+	foundUser, err := runUserDetails(db, log, identifier, deps.findByID, deps.findByEmail)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error finding user: %v\n", err)
+
+		osExit(1)
+		return
+	}
+
+	err = deps.apply(db, foundUser, role)
+
+	if err != nil {
+		if errors.Is(err, user.ErrRoleNotFound) || errors.Is(err, user.ErrRoleNotGranted) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		} else {
+			log.Error("Failed to update the roles of the user", logger.Fields{"error": err.Error()})
+		}
+
+		osExit(1)
+		return
+	}
+
+	err = deps.recordEvent(db, &audit.Event{
+		Type:     deps.event,
+		TargetID: foundUser.GetID(),
+		Metadata: audit.Metadata{"role": role, "method": "cli"},
+	})
+
+	if err != nil {
+		log.Warn("Failed to record an audit event", logger.Fields{"error": err.Error()})
+	}
+
+	fmt.Printf("Role %s has been %s user %s\n", role, done, foundUser.GetEmail())
+}
+
+func runUserRoleGrantCmd(cmd *cobra.Command, args []string) {
+	runUserRoleCmdWithDeps(cmd, defaultUserRoleDeps(grantRole, audit.EventRoleGranted), "granted to")
+}
+
+func runUserRoleRevokeCmd(cmd *cobra.Command, args []string) {
+	runUserRoleCmdWithDeps(cmd, defaultUserRoleDeps(revokeRole, audit.EventRoleRevoked), "revoked from")
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunUserRoleCmd(t *testing.T) {
+	mockDBNew := func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+		return &mockDB{}, nil
+	}
+
+	findUser := func(db database.DatabaseInterface, id int) (*user.User, error) {
+		return user.New(user.UserFields{Id: id, Email: "user@example.com"}), nil
+	}
+
+	findByEmail := func(db database.DatabaseInterface, email string) (*user.User, error) {
+		return user.New(user.UserFields{Id: 2, Email: email}), nil
+	}
+
+	tests := []struct {
+		name       string
+		id         int
+		email      string
+		role       string
+		prompt     func(string) (string, error)
+		dbNew      dbConstructor
+		findByID   func(database.DatabaseInterface, int) (*user.User, error)
+		applyErr   error
+		recordErr  error
+		wantApply  []string
+		wantExit   bool
+		wantOutput string
+	}{
+		{
+			name:       "by ID",
+			id:         1,
+			role:       user.RoleAdmin,
+			wantApply:  []string{"user@example.com", user.RoleAdmin},
+			wantOutput: "Role admin has been granted to user user@example.com",
+		},
+		{
+			name:       "by email",
+			email:      "other@example.com",
+			role:       user.RoleAdmin,
+			wantApply:  []string{"other@example.com", user.RoleAdmin},
+			wantOutput: "Role admin has been granted to user other@example.com",
+		},
+		{
+			name: "by prompt",
+			prompt: func(label string) (string, error) {
+				if label == "Enter the role: " {
+					return "editor", nil
+				}
+
+				return "prompt@example.com", nil
+			},
+			wantApply:  []string{"prompt@example.com", "editor"},
+			wantOutput: "Role editor has been granted to user prompt@example.com",
+		},
+		{
+			name:     "identifier prompt error",
+			role:     user.RoleAdmin,
+			prompt:   func(string) (string, error) { return "", errors.New("input error") },
+			wantExit: true,
+		},
+		{
+			name:     "role prompt error",
+			id:       1,
+			prompt:   func(string) (string, error) { return "", nil },
+			wantExit: true,
+		},
+		{
+			name: "database error",
+			id:   1,
+			role: user.RoleAdmin,
+			dbNew: func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+				return nil, errors.New("connection failed")
+			},
+			wantExit: true,
+		},
+		{
+			name: "user not found",
+			id:   1,
+			role: user.RoleAdmin,
+			findByID: func(database.DatabaseInterface, int) (*user.User, error) {
+				return nil, user.ErrInvalidCredentials
+			},
+			wantExit: true,
+		},
+		{
+			name:       "audit error",
+			id:         1,
+			role:       user.RoleAdmin,
+			recordErr:  errors.New("database error"),
+			wantApply:  []string{"user@example.com", user.RoleAdmin},
+			wantOutput: "Role admin has been granted to user user@example.com",
+		},
+		{
+			name:      "unknown role",
+			id:        1,
+			role:      "bogus",
+			applyErr:  user.ErrRoleNotFound,
+			wantApply: []string{"user@example.com", "bogus"},
+			wantExit:  true,
+		},
+		{
+			name:      "apply error",
+			id:        1,
+			role:      user.RoleAdmin,
+			applyErr:  errors.New("database error"),
+			wantApply: []string{"user@example.com", user.RoleAdmin},
+			wantExit:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalOsExit := osExit
+			originalPrompt := promptForString
+
+			defer func() {
+				osExit = originalOsExit
+				promptForString = originalPrompt
+			}()
+
+			osExitCalled := false
+			osExit = func(code int) { osExitCalled = true }
+
+			if tt.prompt != nil {
+				promptForString = tt.prompt
+			}
+
+			var applied []string
+			var events []audit.Event
+
+			deps := userRoleDeps{
+				dbNew:       mockDBNew,
+				findByID:    findUser,
+				findByEmail: findByEmail,
+				apply: func(_ database.DatabaseInterface, usr *user.User, role string) error {
+					applied = []string{usr.GetEmail(), role}
+					return tt.applyErr
+				},
+				event: audit.EventRoleGranted,
+				recordEvent: func(_ database.DatabaseInterface, event *audit.Event) error {
+					events = append(events, *event)
+					return tt.recordErr
+				},
+			}
+
+			if tt.dbNew != nil {
+				deps.dbNew = tt.dbNew
+			}
+
+			if tt.findByID != nil {
+				deps.findByID = tt.findByID
+			}
+
+			cmd := &cobra.Command{}
+			cmd.Flags().Int("id", tt.id, "")
+			cmd.Flags().String("email", tt.email, "")
+			cmd.Flags().String("role", tt.role, "")
+
+			output := captureStdout(func() { runUserRoleCmdWithDeps(cmd, deps, "granted to") })
+
+			assert.Equal(t, tt.wantExit, osExitCalled)
+			assert.Equal(t, tt.wantApply, applied)
+			assert.Contains(t, output, tt.wantOutput)
+
+			if tt.wantExit {
+				assert.Empty(t, events)
+				return
+			}
+
+			assert.Len(t, events, 1)
+			assert.Equal(t, audit.EventRoleGranted, events[0].Type)
+			assert.Equal(t, tt.wantApply[1], events[0].Metadata["role"])
+		})
+	}
+}
+
+func TestUserRoleCommandsRegistered(t *testing.T) {
+	for _, use := range []string{"user:role:grant", "user:role:revoke"} {
+		cmd, _, err := rootCmd.Find([]string{use})
+
+		assert.NoError(t, err)
+		assert.Equal(t, use, cmd.Use)
+		assert.NotNil(t, cmd.Flags().Lookup("role"))
+	}
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/lockout"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/redis"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var userUnlockCmd = &cobra.Command{
+	Use:   "user:unlock",
+	Short: "Unlock a user that has been locked after too many failed logins",
+	Run:   runUserUnlockCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(userUnlockCmd)
+
+	userUnlockCmd.Flags().StringP("email", "e", "", "Email of the user to unlock")
+	userUnlockCmd.Flags().IntP("id", "i", 0, "ID of the user to unlock (takes precedence over email)")
+}
+
+type userUnlockDeps struct {
+	dbNew       dbConstructor
+	newStore    func(db database.DatabaseInterface, log *logger.Logger) (lockout.Store, error)
+	findByID    func(database.DatabaseInterface, int) (*user.User, error)
+	findByEmail func(database.DatabaseInterface, string) (*user.User, error)
+}
+
+func newLockoutStore(db database.DatabaseInterface, log *logger.Logger) (lockout.Store, error) {
+	if !viper.GetBool("redis.enable") {
+		return lockout.NewDatabaseStore(db), nil
+	}
+
+	redisClient, err := newRedisForCmd(log)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lockout.NewRedisStore(redisClient), nil
+}
+
+func newRedisForCmd(log *logger.Logger) (*redis.Redis, error) {
+	return redis.New(config.Redis{
+		Enable:   true,
+		Host:     viper.GetString("redis.host"),
+		Port:     viper.GetInt("redis.port"),
+		Password: viper.GetString("redis.password"),
+		DB:       viper.GetInt("redis.db"),
+	}, log)
+}
+
+func defaultUserUnlockDeps() userUnlockDeps {
+	return userUnlockDeps{
+		dbNew: func(cfg databaseConfig, log *logger.Logger) (database.DatabaseInterface, error) {
+			return database.New(cfg, log)
+		},
+		newStore:    newLockoutStore,
+		findByID:    user.FindByID,
+		findByEmail: user.FindByEmail,
+	}
+}
+
+func runUserUnlockCmdWithDeps(cmd *cobra.Command, deps userUnlockDeps) {
+	log := logger.New(logger.Level(config.GetLogLevel()), os.Stdout)
+
+	identifier, _ := cmd.Flags().GetString("email")
+	flagID, _ := cmd.Flags().GetInt("id")
+
+	if flagID > 0 {
+		identifier = strconv.Itoa(flagID)
+	}
+
+	if identifier == "" {
+		input, err := promptForString("Enter user's email or ID: ")
+
+		if err != nil || input == "" {
+			log.Error("Email or ID must be provided.", nil)
+
+			osExit(1)
+			return
+		}
+
+		identifier = input
+	}
+
+	db, err := deps.dbNew(getDatabaseConfigForCmd(), log)
+
+	if err != nil {
+		log.Error("Failed to connect to database", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	defer func() { _ = db.Close() }()
+
+	foundUser, err := runUserDetails(db, log, identifier, deps.findByID, deps.findByEmail)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error finding user: %v\n", err)
+
+		osExit(1)
+		return
+	}
+
+	store, err := deps.newStore(db, log)
+
+	if err != nil {
+		log.Error("Failed to connect to the login attempts store", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	limiter := lockout.New(store, lockout.AccountPolicy)
+	err = limiter.Reset(context.Background(), lockout.AccountKey(foundUser.GetEmail()))
+
+	if err != nil {
+		log.Error("Failed to unlock the user", logger.Fields{"error": err.Error()})
+
+		osExit(1)
+		return
+	}
+
+	fmt.Printf("User %s has been unlocked\n", foundUser.GetEmail())
+}
+
+func runUserUnlockCmd(cmd *cobra.Command, args []string) {
+	runUserUnlockCmdWithDeps(cmd, defaultUserUnlockDeps())
+}
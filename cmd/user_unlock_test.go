@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/lockout"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockLockoutStore struct {
+	deleted []string
+	err     error
+}
+
+func (s *mockLockoutStore) Get(context.Context, string) (*lockout.Attempts, error) { return nil, nil }
+
+func (s *mockLockoutStore) Increment(context.Context, string, time.Duration) (*lockout.Attempts, error) {
+	return &lockout.Attempts{}, nil
+}
+
+func (s *mockLockoutStore) Lock(context.Context, string, time.Time) (bool, error) { return false, nil }
+
+func (s *mockLockoutStore) Delete(_ context.Context, key string) error {
+	s.deleted = append(s.deleted, key)
+	return s.err
+}
+
+func TestRunUserUnlockCmd(t *testing.T) {
+	mockDBNew := func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+		return &mockDB{}, nil
+	}
+
+	findUser := func(db database.DatabaseInterface, id int) (*user.User, error) {
+		return user.New(user.UserFields{Id: id, Email: "User@example.com"}), nil
+	}
+
+	findByEmail := func(db database.DatabaseInterface, email string) (*user.User, error) {
+		return user.New(user.UserFields{Id: 2, Email: email}), nil
+	}
+
+	tests := []struct {
+		name        string
+		id          int
+		email       string
+		prompt      func(string) (string, error)
+		dbNew       dbConstructor
+		storeErr    error
+		newStoreErr error
+		findByID    func(database.DatabaseInterface, int) (*user.User, error)
+		wantDeleted []string
+		wantExit    bool
+	}{
+		{
+			name:        "by ID",
+			id:          1,
+			wantDeleted: []string{"account:user@example.com"},
+		},
+		{
+			name:        "by email",
+			email:       "other@example.com",
+			wantDeleted: []string{"account:other@example.com"},
+		},
+		{
+			name:        "by prompt",
+			prompt:      func(string) (string, error) { return "prompt@example.com", nil },
+			wantDeleted: []string{"account:prompt@example.com"},
+		},
+		{
+			name:     "prompt error",
+			prompt:   func(string) (string, error) { return "", errors.New("input error") },
+			wantExit: true,
+		},
+		{
+			name: "database error",
+			id:   1,
+			dbNew: func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+				return nil, errors.New("connection failed")
+			},
+			wantExit: true,
+		},
+		{
+			name: "user not found",
+			id:   1,
+			findByID: func(database.DatabaseInterface, int) (*user.User, error) {
+				return nil, user.ErrInvalidCredentials
+			},
+			wantExit: true,
+		},
+		{
+			name:        "store error",
+			id:          1,
+			newStoreErr: errors.New("redis fail"),
+			wantExit:    true,
+		},
+		{
+			name:        "reset error",
+			id:          1,
+			storeErr:    errors.New("delete fail"),
+			wantDeleted: []string{"account:user@example.com"},
+			wantExit:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalOsExit := osExit
+			originalPrompt := promptForString
+
+			defer func() {
+				osExit = originalOsExit
+				promptForString = originalPrompt
+			}()
+
+			osExitCalled := false
+			osExit = func(code int) { osExitCalled = true }
+
+			if tt.prompt != nil {
+				promptForString = tt.prompt
+			}
+
+			store := &mockLockoutStore{err: tt.storeErr}
+			deps := userUnlockDeps{
+				dbNew:       mockDBNew,
+				findByID:    findUser,
+				findByEmail: findByEmail,
+				newStore: func(database.DatabaseInterface, *logger.Logger) (lockout.Store, error) {
+					return store, tt.newStoreErr
+				},
+			}
+
+			if tt.dbNew != nil {
+				deps.dbNew = tt.dbNew
+			}
+
+			if tt.findByID != nil {
+				deps.findByID = tt.findByID
+			}
+
+			cmd := &cobra.Command{}
+			cmd.Flags().Int("id", tt.id, "")
+			cmd.Flags().String("email", tt.email, "")
+
+			_ = captureStdout(func() { runUserUnlockCmdWithDeps(cmd, deps) })
+
+			assert.Equal(t, tt.wantExit, osExitCalled)
+			assert.Equal(t, tt.wantDeleted, store.deleted)
+		})
+	}
+}
+
+func TestNewLockoutStore(t *testing.T) {
+	log := logger.New(logger.InfoLevel, io.Discard)
+	origEnable := viper.Get("redis.enable")
+
+	t.Cleanup(func() { viper.Set("redis.enable", origEnable) })
+
+	viper.Set("redis.enable", false)
+	store, err := newLockoutStore(&mockDB{}, log)
+	assert.NoError(t, err)
+	assert.IsType(t, &lockout.DatabaseStore{}, store)
+
+	viper.Set("redis.enable", true)
+	store, err = newLockoutStore(&mockDB{}, log)
+	assert.NoError(t, err)
+	assert.IsType(t, &lockout.RedisStore{}, store)
+}
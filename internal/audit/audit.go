@@ -0,0 +1,253 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+)
+
+type EventType string
+
+const (
+	EventLoginSucceeded         EventType = "login.succeeded"
+	EventLoginFailed            EventType = "login.failed"
+	EventLoginNewDevice         EventType = "login.new_device"
+	EventLoginRevoked           EventType = "login.revoked"
+	EventLogout                 EventType = "logout"
+	EventPasswordResetRequested EventType = "password_reset.requested"
+	EventPasswordReset          EventType = "password_reset.completed"
+	EventPasswordChanged        EventType = "password.changed"
+	EventAccountVerified        EventType = "account.verified"
+	EventProfileUpdated         EventType = "profile.updated"
+	EventAvatarUpdated          EventType = "avatar.updated"
+	EventAvatarRemoved          EventType = "avatar.removed"
+	EventAccountDeleted         EventType = "account.deleted"
+	EventAccountRestored        EventType = "account.restored"
+	EventDataExportRequested    EventType = "data_export.requested"
+	EventDataExportDownloaded   EventType = "data_export.downloaded"
+	EventInviteSent             EventType = "invite.sent"
+	EventInviteAccepted         EventType = "invite.accepted"
+	EventImpersonationStarted   EventType = "impersonation.started"
+	EventImpersonationStopped   EventType = "impersonation.stopped"
+	EventOrganizationCreated    EventType = "organization.created"
+	EventOrganizationDeleted    EventType = "organization.deleted"
+	EventOrganizationInvited    EventType = "organization.invited"
+	EventOrganizationJoined     EventType = "organization.joined"
+	EventOrganizationLeft       EventType = "organization.left"
+	EventOrganizationRole       EventType = "organization.role_changed"
+	EventOrganizationRemoved    EventType = "organization.member_removed"
+	EventUserAdminAction        EventType = "user.admin_action"
+	EventRoleGranted            EventType = "role.granted"
+	EventRoleRevoked            EventType = "role.revoked"
+)
+
+const (
+	eventColumns     = `id, event_type, actor_user_id, target_user_id, ip, user_agent, metadata, created_at`
+	insertEventQuery = `INSERT INTO audit_events (event_type, actor_user_id, target_user_id, ip, user_agent, metadata, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	findEventsQuery  = `SELECT ` + eventColumns + ` FROM audit_events`
+)
+
+var eventDescriptions = map[EventType]string{
+	EventLoginSucceeded:         "Signed in",
+	EventLoginFailed:            "Failed sign-in attempt",
+	EventLoginNewDevice:         "Signed in from a new device",
+	EventLoginRevoked:           "Sign-in reported as not yours",
+	EventLogout:                 "Signed out",
+	EventPasswordResetRequested: "Password reset requested",
+	EventPasswordReset:          "Password reset",
+	EventPasswordChanged:        "Password changed",
+	EventAccountVerified:        "Account verified",
+	EventProfileUpdated:         "Profile updated",
+	EventAvatarUpdated:          "Avatar changed",
+	EventAvatarRemoved:          "Avatar removed",
+	EventAccountDeleted:         "Account deleted",
+	EventAccountRestored:        "Account restored",
+	EventDataExportRequested:    "Data export requested",
+	EventDataExportDownloaded:   "Data export downloaded",
+	EventInviteSent:             "Invitation sent",
+	EventInviteAccepted:         "Invitation accepted",
+	EventImpersonationStarted:   "Support session started",
+	EventImpersonationStopped:   "Support session ended",
+	EventOrganizationCreated:    "Organization created",
+	EventOrganizationDeleted:    "Organization deleted",
+	EventOrganizationInvited:    "Organization invitation sent",
+	EventOrganizationJoined:     "Joined an organization",
+	EventOrganizationLeft:       "Left an organization",
+	EventOrganizationRole:       "Organization role changed",
+	EventOrganizationRemoved:    "Removed from an organization",
+	EventUserAdminAction:        "Account changed by an admin",
+	EventRoleGranted:            "Role granted",
+	EventRoleRevoked:            "Role revoked",
+}
+
+var timeNow = time.Now
+
+func (t EventType) Description() string {
+	if description, ok := eventDescriptions[t]; ok {
+		return description
+	}
+
+	return string(t)
+}
+
+type Metadata map[string]any
+
+type Event struct {
+	ID        int64
+	Type      EventType
+	ActorID   int
+	TargetID  int
+	IP        string
+	UserAgent string
+	Metadata  Metadata
+	CreatedAt time.Time
+}
+
+type Query struct {
+	UserID   int
+	TargetID int
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+func Record(db database.DatabaseInterface, event *Event) error {
+	if event.Metadata == nil {
+		event.Metadata = Metadata{}
+	}
+
+	metadata, err := json.Marshal(event.Metadata)
+
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event metadata: %w", err)
+	}
+
+	event.CreatedAt = timeNow()
+
+	err = db.QueryRow(
+		insertEventQuery,
+		string(event.Type),
+		nullableID(event.ActorID),
+		nullableID(event.TargetID),
+		event.IP,
+		event.UserAgent,
+		metadata,
+		event.CreatedAt,
+	).Scan(&event.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to save audit event: %w", err)
+	}
+
+	return nil
+}
+
+func Find(db database.DatabaseInterface, q Query) ([]Event, error) {
+	query, args := q.build()
+	rows, err := db.Query(query, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("error finding audit events: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	events := []Event{}
+
+	for rows.Next() {
+		event, err := scanEvent(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, *event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error finding audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+func (q Query) build() (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if q.UserID > 0 {
+		placeholder := arg(q.UserID)
+		conditions = append(conditions, fmt.Sprintf("(actor_user_id = %s OR target_user_id = %s)", placeholder, placeholder))
+	}
+
+	if q.TargetID > 0 {
+		conditions = append(conditions, "target_user_id = "+arg(q.TargetID))
+	}
+
+	if !q.Since.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(q.Since))
+	}
+
+	if !q.Until.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(q.Until))
+	}
+
+	query := findEventsQuery
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY created_at DESC, id DESC"
+
+	if q.Limit > 0 {
+		query += " LIMIT " + arg(q.Limit)
+	}
+
+	return query, args
+}
+
+func scanEvent(rows *sql.Rows) (*Event, error) {
+	event := &Event{}
+
+	var (
+		eventType string
+		actorID   sql.NullInt64
+		targetID  sql.NullInt64
+		metadata  []byte
+	)
+
+	err := rows.Scan(&event.ID, &eventType, &actorID, &targetID, &event.IP, &event.UserAgent, &metadata, &event.CreatedAt)
+
+	if err != nil {
+		return nil, fmt.Errorf("error scanning audit event: %w", err)
+	}
+
+	event.Type = EventType(eventType)
+	event.ActorID = int(actorID.Int64)
+	event.TargetID = int(targetID.Int64)
+	event.Metadata = Metadata{}
+
+	if len(metadata) > 0 {
+		if err = json.Unmarshal(metadata, &event.Metadata); err != nil {
+			return nil, fmt.Errorf("error decoding audit event metadata: %w", err)
+		}
+	}
+
+	return event, nil
+}
+
+func nullableID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id > 0}
+}
@@ -0,0 +1,213 @@
+package audit
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var eventColumnNames = []string{"id", "event_type", "actor_user_id", "target_user_id", "ip", "user_agent", "metadata", "created_at"}
+
+func freezeTime(t *testing.T) time.Time {
+	now := time.Unix(1000000, 0)
+	timeNowOrig := timeNow
+
+	t.Cleanup(func() { timeNow = timeNowOrig })
+	timeNow = func() time.Time { return now }
+
+	return now
+}
+
+func TestEventTypeDescription(t *testing.T) {
+	assert.Equal(t, "Signed in", EventLoginSucceeded.Description())
+	assert.Equal(t, "custom.event", EventType("custom.event").Description())
+}
+
+func TestRecord(t *testing.T) {
+	now := freezeTime(t)
+
+	tests := []struct {
+		name      string
+		event     Event
+		expectArg []driver.Value
+		err       error
+	}{
+		{
+			name:      "with actor and target",
+			event:     Event{Type: EventLoginSucceeded, ActorID: 1, TargetID: 1, IP: "192.0.2.1", UserAgent: "Firefox", Metadata: Metadata{"method": "password"}},
+			expectArg: []driver.Value{"login.succeeded", sql.NullInt64{Int64: 1, Valid: true}, sql.NullInt64{Int64: 1, Valid: true}, "192.0.2.1", "Firefox", []byte(`{"method":"password"}`), now},
+		},
+		{
+			name:      "anonymous",
+			event:     Event{Type: EventLoginFailed},
+			expectArg: []driver.Value{"login.failed", sql.NullInt64{}, sql.NullInt64{}, "", "", []byte(`{}`), now},
+		},
+		{
+			name:      "database error",
+			event:     Event{Type: EventLogout, ActorID: 1},
+			expectArg: []driver.Value{"logout", sql.NullInt64{Int64: 1, Valid: true}, sql.NullInt64{}, "", "", []byte(`{}`), now},
+			err:       errors.New("db fail"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			query := mock.ExpectQuery(regexp.QuoteMeta(insertEventQuery)).WithArgs(tc.expectArg...)
+
+			if tc.err != nil {
+				query.WillReturnError(tc.err)
+			} else {
+				query.WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			}
+
+			event := tc.event
+			err := Record(db, &event)
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(7), event.ID)
+			assert.Equal(t, now, event.CreatedAt)
+		})
+	}
+}
+
+func TestRecordInvalidMetadata(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer func() { _ = db.Close() }()
+
+	err := Record(db, &Event{Type: EventLogout, Metadata: Metadata{"invalid": make(chan int)}})
+
+	assert.ErrorContains(t, err, "failed to encode audit event metadata")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryBuild(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		query       Query
+		expectQuery string
+		expectArgs  []any
+	}{
+		{
+			name:        "everything",
+			expectQuery: findEventsQuery + " ORDER BY created_at DESC, id DESC",
+		},
+		{
+			name:        "user with limit",
+			query:       Query{UserID: 3, Limit: 50},
+			expectQuery: findEventsQuery + " WHERE (actor_user_id = $1 OR target_user_id = $1) ORDER BY created_at DESC, id DESC LIMIT $2",
+			expectArgs:  []any{3, 50},
+		},
+		{
+			name:        "target",
+			query:       Query{TargetID: 3},
+			expectQuery: findEventsQuery + " WHERE target_user_id = $1 ORDER BY created_at DESC, id DESC",
+			expectArgs:  []any{3},
+		},
+		{
+			name:        "time range",
+			query:       Query{Since: since, Until: until},
+			expectQuery: findEventsQuery + " WHERE created_at >= $1 AND created_at < $2 ORDER BY created_at DESC, id DESC",
+			expectArgs:  []any{since, until},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			query, args := tc.query.build()
+
+			assert.Equal(t, tc.expectQuery, query)
+			assert.Equal(t, tc.expectArgs, args)
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		setupMock func(mock sqlmock.Sqlmock)
+		expect    []Event
+		expectErr bool
+	}{
+		{
+			name: "success",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT .+ FROM audit_events").WithArgs(1, 10).WillReturnRows(
+					sqlmock.NewRows(eventColumnNames).
+						AddRow(2, "logout", 1, 1, "192.0.2.1", "Firefox", []byte(`{}`), now).
+						AddRow(1, "login.failed", nil, 1, "192.0.2.1", "curl", []byte(`{"method":"password"}`), now),
+				)
+			},
+			expect: []Event{
+				{ID: 2, Type: EventLogout, ActorID: 1, TargetID: 1, IP: "192.0.2.1", UserAgent: "Firefox", Metadata: Metadata{}, CreatedAt: now},
+				{ID: 1, Type: EventLoginFailed, TargetID: 1, IP: "192.0.2.1", UserAgent: "curl", Metadata: Metadata{"method": "password"}, CreatedAt: now},
+			},
+		},
+		{
+			name: "query error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT .+ FROM audit_events").WillReturnError(errors.New("db fail"))
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid metadata",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT .+ FROM audit_events").WillReturnRows(
+					sqlmock.NewRows(eventColumnNames).AddRow(1, "logout", 1, 1, "", "", []byte(`{`), now),
+				)
+			},
+			expectErr: true,
+		},
+		{
+			name: "scan error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT .+ FROM audit_events").WillReturnRows(
+					sqlmock.NewRows(eventColumnNames).AddRow("invalid", "logout", 1, 1, "", "", []byte(`{}`), now),
+				)
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tc.setupMock(mock)
+
+			events, err := Find(db, Query{UserID: 1, Limit: 10})
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, events)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package avatar
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+	"slices"
+
+	"github.com/Dobefu/go-web-starter/internal/storage"
+)
+
+const (
+	MaxBytes = 5 << 20
+
+	MinDimension = 32
+	MaxDimension = 4096
+
+	SizeSmall = 64
+	SizeLarge = 256
+
+	ContentType = "image/png"
+
+	keyRandomBytes = 8
+)
+
+var (
+	ErrUnsupportedType = errors.New("the image must be a JPEG, PNG or GIF file")
+	ErrTooSmall        = fmt.Errorf("the image must be at least %d by %d pixels", MinDimension, MinDimension)
+	ErrTooLarge        = fmt.Errorf("the image must be at most %d by %d pixels", MaxDimension, MaxDimension)
+	ErrInvalidImage    = errors.New("the image could not be read")
+)
+
+var Sizes = []int{SizeSmall, SizeLarge}
+
+var allowedTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+var randRead = rand.Read
+
+type Image struct {
+	Size int
+	Data []byte
+}
+
+func Process(data []byte) ([]Image, error) {
+	if !slices.Contains(allowedTypes, http.DetectContentType(data)) {
+		return nil, ErrUnsupportedType
+	}
+
+	// The dimensions are checked before decoding, so that a small file that
+	// claims to be huge cannot make the server allocate its pixels.
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+
+	if cfg.Width < MinDimension || cfg.Height < MinDimension {
+		return nil, ErrTooSmall
+	}
+
+	if cfg.Width > MaxDimension || cfg.Height > MaxDimension {
+		return nil, ErrTooLarge
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+
+	img := toRGBA(decoded)
+
+	if format == "jpeg" {
+		img = orient(img, exifOrientation(data))
+	}
+
+	img = cropSquare(img)
+	images := make([]Image, 0, len(Sizes))
+
+	for _, size := range Sizes {
+		var buf bytes.Buffer
+		err = png.Encode(&buf, resize(img, size))
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode the avatar: %w", err)
+		}
+
+		images = append(images, Image{Size: size, Data: buf.Bytes()})
+	}
+
+	return images, nil
+}
+
+func Key(key string, size int) string {
+	return fmt.Sprintf("%s-%d.png", key, size)
+}
+
+// Upload stores the avatar in every size, and returns the key it is stored
+// under. Every upload gets a new key, so that browsers do not keep showing
+// an old avatar from their cache.
+func Upload(ctx context.Context, store storage.Storage, userID int, images []Image) (string, error) {
+	b := make([]byte, keyRandomBytes)
+	_, err := randRead(b)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to generate the avatar key: %w", err)
+	}
+
+	key := fmt.Sprintf("avatars/%d/%s", userID, hex.EncodeToString(b))
+
+	for _, img := range images {
+		err = store.Put(ctx, Key(key, img.Size), bytes.NewReader(img.Data), ContentType)
+
+		if err != nil {
+			_ = Remove(ctx, store, key)
+			return "", fmt.Errorf("failed to store the avatar: %w", err)
+		}
+	}
+
+	return key, nil
+}
+
+func Remove(ctx context.Context, store storage.Storage, key string) error {
+	var errs []error
+
+	for _, size := range Sizes {
+		errs = append(errs, store.Delete(ctx, Key(key, size)))
+	}
+
+	return errors.Join(errs...)
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+
+	return rgba
+}
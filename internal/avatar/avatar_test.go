@@ -0,0 +1,228 @@
+package avatar
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func halvesImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := range h {
+		for x := range w {
+			if x < w/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+
+	return img
+}
+
+func encodeImage(t *testing.T, format string, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var err error
+
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100})
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+
+	assert.NoError(t, err)
+
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	testCases := []struct {
+		name   string
+		data   func(t *testing.T) []byte
+		top    color.RGBA
+		bottom color.RGBA
+	}{
+		{
+			name:   "png",
+			data:   func(t *testing.T) []byte { return encodeImage(t, "png", halvesImage(64, 32)) },
+			top:    color.RGBA{R: 255, A: 255},
+			bottom: color.RGBA{R: 255, A: 255},
+		},
+		{
+			name:   "gif",
+			data:   func(t *testing.T) []byte { return encodeImage(t, "gif", halvesImage(64, 32)) },
+			top:    color.RGBA{R: 255, A: 255},
+			bottom: color.RGBA{R: 255, A: 255},
+		},
+		{
+			name: "jpeg rotated by its EXIF orientation",
+			data: func(t *testing.T) []byte {
+				return withExif(encodeImage(t, "jpeg", halvesImage(64, 32)), exifData(6, false))
+			},
+			top:    color.RGBA{R: 255, A: 255},
+			bottom: color.RGBA{B: 255, A: 255},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			images, err := Process(tc.data(t))
+			assert.NoError(t, err)
+			assert.Len(t, images, len(Sizes))
+
+			for i, img := range images {
+				assert.Equal(t, Sizes[i], img.Size)
+				assert.NotContains(t, string(img.Data), "Exif")
+
+				decoded, format, err := image.Decode(bytes.NewReader(img.Data))
+				assert.NoError(t, err)
+				assert.Equal(t, "png", format)
+				assert.Equal(t, image.Rect(0, 0, img.Size, img.Size), decoded.Bounds())
+
+				assertColorNear(t, tc.top, decoded.At(img.Size/4, img.Size/8))
+				assertColorNear(t, tc.bottom, decoded.At(img.Size/4, img.Size*7/8))
+			}
+		})
+	}
+}
+
+func assertColorNear(t *testing.T, expected color.RGBA, actual color.Color) {
+	t.Helper()
+
+	r, g, b, _ := actual.RGBA()
+	diff := func(a uint8, b uint32) int { return int(a) - int(b>>8) }
+
+	for _, d := range []int{diff(expected.R, r), diff(expected.G, g), diff(expected.B, b)} {
+		assert.LessOrEqual(t, d*d, 32*32, "expected %v, got %v", expected, actual)
+	}
+}
+
+func TestProcessErrors(t *testing.T) {
+	validPNG := encodeImage(t, "png", halvesImage(64, 64))
+
+	testCases := []struct {
+		name      string
+		data      []byte
+		expectErr error
+	}{
+		{name: "not an image", data: []byte("<svg></svg>"), expectErr: ErrUnsupportedType},
+		{name: "webp", data: []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), expectErr: ErrUnsupportedType},
+		{name: "empty", data: nil, expectErr: ErrUnsupportedType},
+		{name: "too small", data: encodeImage(t, "png", halvesImage(MinDimension-1, 64)), expectErr: ErrTooSmall},
+		{name: "too large", data: encodeImage(t, "png", halvesImage(MaxDimension+1, 64)), expectErr: ErrTooLarge},
+		{name: "corrupt header", data: append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...), expectErr: ErrInvalidImage},
+		{name: "truncated", data: validPNG[:len(validPNG)/2], expectErr: ErrInvalidImage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			images, err := Process(tc.data)
+			assert.ErrorIs(t, err, tc.expectErr)
+			assert.Nil(t, images)
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "avatars/1/abc-64.png", Key("avatars/1/abc", 64))
+}
+
+type failingStorage struct {
+	storage.Storage
+	failKey string
+}
+
+func (s failingStorage) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	if key == s.failKey {
+		return errors.New("put fail")
+	}
+
+	return s.Storage.Put(ctx, key, body, contentType)
+}
+
+func TestUpload(t *testing.T) {
+	origRandRead := randRead
+	defer func() { randRead = origRandRead }()
+
+	randRead = func(b []byte) (int, error) {
+		for i := range b {
+			b[i] = 0xAB
+		}
+
+		return len(b), nil
+	}
+
+	images := []Image{{Size: SizeSmall, Data: []byte("small")}, {Size: SizeLarge, Data: []byte("large")}}
+
+	t.Run("success", func(t *testing.T) {
+		dir := t.TempDir()
+
+		key, err := Upload(context.Background(), storage.NewLocalStorage(dir, "/uploads"), 7, images)
+		assert.NoError(t, err)
+		assert.Equal(t, "avatars/7/abababababababab", key)
+
+		data, err := os.ReadFile(filepath.Join(dir, "avatars", "7", "abababababababab-256.png"))
+		assert.NoError(t, err)
+		assert.Equal(t, "large", string(data))
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		dir := t.TempDir()
+		store := failingStorage{
+			Storage: storage.NewLocalStorage(dir, "/uploads"),
+			failKey: "avatars/7/abababababababab-256.png",
+		}
+
+		key, err := Upload(context.Background(), store, 7, images)
+		assert.ErrorContains(t, err, "put fail")
+		assert.Empty(t, key)
+
+		// The sizes that were stored before the error have been removed again.
+		_, err = os.Stat(filepath.Join(dir, "avatars", "7", "abababababababab-64.png"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("random error", func(t *testing.T) {
+		randRead = func([]byte) (int, error) { return 0, errors.New("rand fail") }
+
+		key, err := Upload(context.Background(), storage.NewLocalStorage(t.TempDir(), "/uploads"), 7, images)
+		assert.ErrorContains(t, err, "rand fail")
+		assert.Empty(t, key)
+	})
+}
+
+func TestRemove(t *testing.T) {
+	dir := t.TempDir()
+	store := storage.NewLocalStorage(dir, "/uploads")
+
+	for _, size := range Sizes {
+		assert.NoError(t, store.Put(context.Background(), Key("avatars/1/abc", size), bytes.NewReader(nil), ContentType))
+	}
+
+	assert.NoError(t, Remove(context.Background(), store, "avatars/1/abc"))
+
+	entries, err := os.ReadDir(filepath.Join(dir, "avatars", "1"))
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	assert.ErrorIs(t, Remove(context.Background(), store, "../abc"), storage.ErrInvalidKey)
+}
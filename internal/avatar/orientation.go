@@ -0,0 +1,160 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+const (
+	orientationUpright = 1
+	orientationTag     = 0x0112
+	tiffTypeShort      = 3
+)
+
+func exifOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return orientationUpright
+	}
+
+	i := 2
+
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return orientationUpright
+		}
+
+		marker := data[i+1]
+
+		// Fill bytes may come before a marker.
+		if marker == 0xFF {
+			i++
+			continue
+		}
+
+		// Markers that have no length or data.
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD8) {
+			i += 2
+			continue
+		}
+
+		// The image data starts here, and EXIF data always comes before it.
+		if marker == 0xDA || marker == 0xD9 {
+			return orientationUpright
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+
+		if length < 2 || i+2+length > len(data) {
+			return orientationUpright
+		}
+
+		segment := data[i+4 : i+2+length]
+
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+
+		i += 2 + length
+	}
+
+	return orientationUpright
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return orientationUpright
+	}
+
+	var order binary.ByteOrder
+
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return orientationUpright
+	}
+
+	if order.Uint16(tiff[2:]) != 42 {
+		return orientationUpright
+	}
+
+	offset := int64(order.Uint32(tiff[4:]))
+
+	if offset < 8 || offset+2 > int64(len(tiff)) {
+		return orientationUpright
+	}
+
+	count := int64(order.Uint16(tiff[offset:]))
+
+	for n := range count {
+		entry := offset + 2 + n*12
+
+		if entry+12 > int64(len(tiff)) {
+			return orientationUpright
+		}
+
+		if order.Uint16(tiff[entry:]) != orientationTag {
+			continue
+		}
+
+		if order.Uint16(tiff[entry+2:]) != tiffTypeShort {
+			return orientationUpright
+		}
+
+		orientation := int(order.Uint16(tiff[entry+8:]))
+
+		if orientation < 1 || orientation > 8 {
+			return orientationUpright
+		}
+
+		return orientation
+	}
+
+	return orientationUpright
+}
+
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= orientationUpright || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := range dh {
+		for x := range dw {
+			var sx, sy int
+
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+
+			s := src.PixOffset(src.Bounds().Min.X+sx, src.Bounds().Min.Y+sy)
+			copy(dst.Pix[dst.PixOffset(x, y):], src.Pix[s:s+4])
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,143 @@
+package avatar
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func exifData(orientation uint16, bigEndian bool) []byte {
+	var order binary.AppendByteOrder = binary.LittleEndian
+	tiff := []byte("II")
+
+	if bigEndian {
+		order = binary.BigEndian
+		tiff = []byte("MM")
+	}
+
+	tiff = order.AppendUint16(tiff, 42)
+	tiff = order.AppendUint32(tiff, 8)
+
+	// An IFD with an unrelated tag first, and then the orientation.
+	tiff = order.AppendUint16(tiff, 2)
+	tiff = order.AppendUint16(tiff, 0x010F)
+	tiff = order.AppendUint16(tiff, 2)
+	tiff = order.AppendUint32(tiff, 4)
+	tiff = append(tiff, "Go\x00\x00"...)
+	tiff = order.AppendUint16(tiff, orientationTag)
+	tiff = order.AppendUint16(tiff, tiffTypeShort)
+	tiff = order.AppendUint32(tiff, 1)
+	tiff = order.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0)
+	tiff = order.AppendUint32(tiff, 0)
+
+	return append([]byte("Exif\x00\x00"), tiff...)
+}
+
+func withExif(jpegData []byte, exif []byte) []byte {
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(exif)+2))
+	segment = append(segment, exif...)
+
+	data := append([]byte{}, jpegData[:2]...)
+	data = append(data, segment...)
+
+	return append(data, jpegData[2:]...)
+}
+
+func TestExifOrientation(t *testing.T) {
+	jpegStart := []byte{0xFF, 0xD8}
+	app0 := []byte{0xFF, 0xE0, 0x00, 0x04, 0x00, 0x00}
+	imageData := []byte{0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9}
+
+	join := func(parts ...[]byte) []byte {
+		var data []byte
+
+		for _, part := range parts {
+			data = append(data, part...)
+		}
+
+		return data
+	}
+
+	app1 := func(exif []byte) []byte {
+		return withExif(jpegStart, exif)[2:]
+	}
+
+	invalidType := exifData(6, false)
+	binary.LittleEndian.PutUint16(invalidType[6+8+2+12+2:], 4)
+
+	testCases := []struct {
+		name     string
+		data     []byte
+		expected int
+	}{
+		{name: "little endian", data: join(jpegStart, app1(exifData(6, false)), imageData), expected: 6},
+		{name: "big endian", data: join(jpegStart, app1(exifData(8, true)), imageData), expected: 8},
+		{name: "after other segments", data: join(jpegStart, app0, []byte{0xFF}, app1(exifData(3, false)), imageData), expected: 3},
+		{name: "no EXIF data", data: join(jpegStart, app0, imageData), expected: 1},
+		{name: "EXIF data after the image data", data: join(jpegStart, imageData, app1(exifData(6, false))), expected: 1},
+		{name: "not a JPEG file", data: []byte("\x89PNG\r\n\x1a\n"), expected: 1},
+		{name: "invalid orientation", data: join(jpegStart, app1(exifData(9, false))), expected: 1},
+		{name: "invalid tag type", data: join(jpegStart, app1(invalidType)), expected: 1},
+		{name: "invalid byte order", data: join(jpegStart, app1(append([]byte("Exif\x00\x00XX"), exifData(6, false)[8:]...))), expected: 1},
+		{name: "invalid TIFF magic", data: join(jpegStart, app1([]byte("Exif\x00\x00II\x2B\x00\x08\x00\x00\x00"))), expected: 1},
+		{name: "invalid IFD offset", data: join(jpegStart, app1([]byte("Exif\x00\x00II\x2A\x00\xFF\x00\x00\x00"))), expected: 1},
+		{name: "truncated IFD", data: join(jpegStart, app1(exifData(6, false)[:6+8+2+12])), expected: 1},
+		{name: "truncated TIFF header", data: join(jpegStart, app1([]byte("Exif\x00\x00II"))), expected: 1},
+		{name: "no orientation tag", data: join(jpegStart, app1([]byte("Exif\x00\x00II\x2A\x00\x08\x00\x00\x00\x00\x00"))), expected: 1},
+		{name: "truncated segment", data: join(jpegStart, []byte{0xFF, 0xE1, 0x10, 0x00, 0x00}), expected: 1},
+		{name: "invalid segment length", data: join(jpegStart, []byte{0xFF, 0xE1, 0x00, 0x01, 0x00}), expected: 1},
+		{name: "garbage between segments", data: join(jpegStart, []byte{0x00, 0x00, 0x00, 0x00}), expected: 1},
+		{name: "markers without a length", data: join(jpegStart, []byte{0xFF, 0xD0}, app1(exifData(5, false))), expected: 5},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, exifOrientation(tc.data))
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 3x2 image with a different color in every pixel:
+	//   1 2 3
+	//   4 5 6
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+
+	for i := range 6 {
+		src.Set(i%3, i/3, color.RGBA{R: uint8(i + 1), A: 255})
+	}
+
+	testCases := []struct {
+		orientation int
+		expected    [][]uint8
+	}{
+		{orientation: 0, expected: [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+		{orientation: 1, expected: [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+		{orientation: 2, expected: [][]uint8{{3, 2, 1}, {6, 5, 4}}},
+		{orientation: 3, expected: [][]uint8{{6, 5, 4}, {3, 2, 1}}},
+		{orientation: 4, expected: [][]uint8{{4, 5, 6}, {1, 2, 3}}},
+		{orientation: 5, expected: [][]uint8{{1, 4}, {2, 5}, {3, 6}}},
+		{orientation: 6, expected: [][]uint8{{4, 1}, {5, 2}, {6, 3}}},
+		{orientation: 7, expected: [][]uint8{{6, 3}, {5, 2}, {4, 1}}},
+		{orientation: 8, expected: [][]uint8{{3, 6}, {2, 5}, {1, 4}}},
+		{orientation: 9, expected: [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(rune('0'+tc.orientation)), func(t *testing.T) {
+			dst := orient(src, tc.orientation)
+			assert.Equal(t, image.Rect(0, 0, len(tc.expected[0]), len(tc.expected)), dst.Bounds())
+
+			for y, row := range tc.expected {
+				for x, expected := range row {
+					assert.Equal(t, expected, dst.RGBAAt(x, y).R, "pixel %d,%d", x, y)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package avatar
+
+import (
+	"image"
+)
+
+func cropSquare(img *image.RGBA) *image.RGBA {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+
+	return img.SubImage(image.Rect(x, y, x+side, y+side)).(*image.RGBA)
+}
+
+// The pixels are premultiplied by their alpha, so that transparent
+// pixels do not bleed their color into the average.
+func resize(src *image.RGBA, size int) *image.RGBA {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	for y := range size {
+		y0 := y * h / size
+		y1 := max((y+1)*h/size, y0+1)
+
+		for x := range size {
+			x0 := x * w / size
+			x1 := max((x+1)*w/size, x0+1)
+
+			var sum [4]int
+
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(bounds.Min.X+x0, bounds.Min.Y+sy)
+
+				for range x1 - x0 {
+					for c := range sum {
+						sum[c] += int(src.Pix[offset+c])
+					}
+
+					offset += 4
+				}
+			}
+
+			count := (x1 - x0) * (y1 - y0)
+			d := dst.PixOffset(x, y)
+
+			for c := range sum {
+				dst.Pix[d+c] = uint8((sum[c] + count/2) / count)
+			}
+		}
+	}
+
+	return dst
+}
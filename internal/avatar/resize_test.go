@@ -0,0 +1,56 @@
+package avatar
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCropSquare(t *testing.T) {
+	testCases := []struct {
+		name     string
+		bounds   image.Rectangle
+		expected image.Rectangle
+	}{
+		{name: "landscape", bounds: image.Rect(0, 0, 10, 4), expected: image.Rect(3, 0, 7, 4)},
+		{name: "portrait", bounds: image.Rect(0, 0, 4, 9), expected: image.Rect(0, 2, 4, 6)},
+		{name: "square", bounds: image.Rect(0, 0, 5, 5), expected: image.Rect(0, 0, 5, 5)},
+		{name: "offset", bounds: image.Rect(2, 2, 6, 4), expected: image.Rect(3, 2, 5, 4)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, cropSquare(image.NewRGBA(tc.bounds)).Bounds())
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+	// Black and white columns average out to gray when they are halved.
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	for y := range 4 {
+		for x := range 4 {
+			if x%2 == 0 {
+				src.Set(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+			} else {
+				src.Set(x, y, color.RGBA{A: 255})
+			}
+		}
+	}
+
+	down := resize(src, 2)
+	assert.Equal(t, image.Rect(0, 0, 2, 2), down.Bounds())
+	assert.Equal(t, color.RGBA{R: 128, G: 128, B: 128, A: 255}, down.RGBAAt(1, 1))
+
+	up := resize(src, 8)
+	assert.Equal(t, image.Rect(0, 0, 8, 8), up.Bounds())
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, up.RGBAAt(1, 7))
+	assert.Equal(t, color.RGBA{A: 255}, up.RGBAAt(2, 0))
+
+	// A cropped image is resized from its own bounds.
+	crop := resize(src.SubImage(image.Rect(1, 0, 2, 1)).(*image.RGBA), 2)
+	assert.Equal(t, color.RGBA{A: 255}, crop.RGBAAt(1, 1))
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/base64"
+	"time"
 
 	"github.com/Dobefu/go-web-starter/internal/logger"
 	"github.com/gorilla/securecookie"
@@ -11,8 +12,9 @@ import (
 var defaultHost = "127.0.0.1"
 
 type Server struct {
-	Port int    `mapstructure:"port"`
-	Host string `mapstructure:"host"`
+	Port           int      `mapstructure:"port"`
+	Host           string   `mapstructure:"host"`
+	TrustedProxies []string `mapstructure:"trustedproxies"`
 }
 
 type Database struct {
@@ -36,9 +38,12 @@ type Log struct {
 }
 
 type Site struct {
-	Name  string `mapstructure:"name"`
-	Host  string `mapstructure:"host"`
-	Email string `mapstructure:"email"`
+	Name              string `mapstructure:"name"`
+	Host              string `mapstructure:"host"`
+	Email             string `mapstructure:"email"`
+	MagicLink         bool   `mapstructure:"magiclink"`
+	DeletionGraceDays int    `mapstructure:"deletiongracedays"`
+	InviteOnly        bool   `mapstructure:"inviteonly"`
 }
 
 type Redis struct {
@@ -49,10 +54,52 @@ type Redis struct {
 	DB       int    `mapstructure:"db"`
 }
 
+type Password struct {
+	Algorithm         string `mapstructure:"algorithm"`
+	Argon2Memory      uint32 `mapstructure:"argon2memory"`
+	Argon2Iterations  uint32 `mapstructure:"argon2iterations"`
+	Argon2Parallelism uint8  `mapstructure:"argon2parallelism"`
+	BcryptCost        int    `mapstructure:"bcryptcost"`
+	BreachedPasswords string `mapstructure:"breachedpasswords"`
+}
+
 type Session struct {
 	Secret string `mapstructure:"secret"`
 }
 
+type OIDCProvider struct {
+	Name         string   `mapstructure:"name"`
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"clientid"`
+	ClientSecret string   `mapstructure:"clientsecret"`
+	Scopes       []string `mapstructure:"scopes"`
+}
+
+type OIDC struct {
+	Providers map[string]OIDCProvider `mapstructure:"providers"`
+}
+
+type Storage struct {
+	Driver string       `mapstructure:"driver"`
+	Local  StorageLocal `mapstructure:"local"`
+	S3     StorageS3    `mapstructure:"s3"`
+}
+
+type StorageLocal struct {
+	Dir string `mapstructure:"dir"`
+	URL string `mapstructure:"url"`
+}
+
+type StorageS3 struct {
+	Endpoint  string `mapstructure:"endpoint"`
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"accesskey"`
+	SecretKey string `mapstructure:"secretkey"`
+	PathStyle bool   `mapstructure:"pathstyle"`
+	PublicURL string `mapstructure:"publicurl"`
+}
+
 type Config struct {
 	Server   Server   `mapstructure:"server"`
 	Database Database `mapstructure:"database"`
@@ -61,6 +108,9 @@ type Config struct {
 	Site     Site     `mapstructure:"site"`
 	Redis    Redis    `mapstructure:"redis"`
 	Session  Session  `mapstructure:"session"`
+	Password Password `mapstructure:"password"`
+	OIDC     OIDC     `mapstructure:"oidc"`
+	Storage  Storage  `mapstructure:"storage"`
 }
 
 func GetLogLevel() logger.Level {
@@ -73,10 +123,59 @@ func GetLogLevel() logger.Level {
 	return logger.Level(DefaultConfig.Log.Level)
 }
 
+func MagicLinkEnabled() bool {
+	if viper.IsSet("site.magiclink") {
+		return viper.GetBool("site.magiclink")
+	}
+
+	return DefaultConfig.Site.MagicLink
+}
+
+func TrustedProxies() []string {
+	if viper.IsSet("server.trustedproxies") {
+		return viper.GetStringSlice("server.trustedproxies")
+	}
+
+	return DefaultConfig.Server.TrustedProxies
+}
+
+func InviteOnly() bool {
+	if viper.IsSet("site.inviteonly") {
+		return viper.GetBool("site.inviteonly")
+	}
+
+	return DefaultConfig.Site.InviteOnly
+}
+
+func DeletionGracePeriod() time.Duration {
+	days := DefaultConfig.Site.DeletionGraceDays
+
+	if viper.IsSet("site.deletiongracedays") {
+		days = viper.GetInt("site.deletiongracedays")
+	}
+
+	return time.Duration(max(days, 0)) * 24 * time.Hour
+}
+
+func GetPassword() Password {
+	password := DefaultConfig.Password
+	_ = viper.UnmarshalKey("password", &password)
+
+	return password
+}
+
+func GetStorage() Storage {
+	storage := DefaultConfig.Storage
+	_ = viper.UnmarshalKey("storage", &storage)
+
+	return storage
+}
+
 var DefaultConfig = Config{
 	Server: Server{
-		Port: 4000,
-		Host: "localhost",
+		Port:           4000,
+		Host:           "localhost",
+		TrustedProxies: []string{},
 	},
 	Database: Database{
 		Host:     defaultHost,
@@ -96,9 +195,12 @@ var DefaultConfig = Config{
 		Level: int(logger.InfoLevel),
 	},
 	Site: Site{
-		Name:  "Go Web Starter",
-		Host:  "http://localhost:4000",
-		Email: "info@example.com",
+		Name:              "Go Web Starter",
+		Host:              "http://localhost:4000",
+		Email:             "info@example.com",
+		MagicLink:         true,
+		DeletionGraceDays: 30,
+		InviteOnly:        false,
 	},
 	Redis: Redis{
 		Enable:   true,
@@ -110,4 +212,31 @@ var DefaultConfig = Config{
 	Session: Session{
 		Secret: base64.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(64)),
 	},
+	Password: Password{
+		Algorithm:         "argon2id",
+		Argon2Memory:      64 * 1024,
+		Argon2Iterations:  3,
+		Argon2Parallelism: 4,
+		BcryptCost:        10,
+		BreachedPasswords: "",
+	},
+	OIDC: OIDC{
+		Providers: nil,
+	},
+	Storage: Storage{
+		Driver: "local",
+		Local: StorageLocal{
+			Dir: "uploads",
+			URL: "/uploads",
+		},
+		S3: StorageS3{
+			Endpoint:  "",
+			Region:    "us-east-1",
+			Bucket:    "",
+			AccessKey: "",
+			SecretKey: "",
+			PathStyle: false,
+			PublicURL: "",
+		},
+	},
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/Dobefu/go-web-starter/internal/logger"
 	"github.com/spf13/viper"
@@ -23,3 +24,73 @@ func TestGetLogLevel(t *testing.T) {
 	defaultLogLevel := GetLogLevel()
 	assert.Equal(t, logger.Level(DefaultConfig.Log.Level), defaultLogLevel)
 }
+
+func TestMagicLinkEnabled(t *testing.T) {
+	viper.Reset()
+	assert.Equal(t, DefaultConfig.Site.MagicLink, MagicLinkEnabled())
+
+	viper.Set("site.magiclink", false)
+	assert.False(t, MagicLinkEnabled())
+
+	viper.Set("site.magiclink", true)
+	assert.True(t, MagicLinkEnabled())
+}
+
+func TestInviteOnly(t *testing.T) {
+	viper.Reset()
+	assert.False(t, InviteOnly())
+
+	viper.Set("site.inviteonly", true)
+	assert.True(t, InviteOnly())
+}
+
+func TestTrustedProxies(t *testing.T) {
+	viper.Reset()
+	assert.Empty(t, TrustedProxies())
+
+	viper.Set("server.trustedproxies", []string{"10.0.0.0/8"})
+	assert.Equal(t, []string{"10.0.0.0/8"}, TrustedProxies())
+}
+
+func TestDeletionGracePeriod(t *testing.T) {
+	viper.Reset()
+	assert.Equal(t, 30*24*time.Hour, DeletionGracePeriod())
+
+	viper.Set("site.deletiongracedays", 7)
+	assert.Equal(t, 7*24*time.Hour, DeletionGracePeriod())
+
+	viper.Set("site.deletiongracedays", 0)
+	assert.Zero(t, DeletionGracePeriod())
+
+	viper.Set("site.deletiongracedays", -1)
+	assert.Zero(t, DeletionGracePeriod())
+}
+
+func TestGetPassword(t *testing.T) {
+	viper.Reset()
+	assert.Equal(t, DefaultConfig.Password, GetPassword())
+
+	viper.Set("password.algorithm", "bcrypt")
+	viper.Set("password.bcryptcost", 12)
+
+	password := GetPassword()
+	assert.Equal(t, "bcrypt", password.Algorithm)
+	assert.Equal(t, 12, password.BcryptCost)
+	assert.Equal(t, DefaultConfig.Password.Argon2Memory, password.Argon2Memory)
+}
+
+func TestGetStorage(t *testing.T) {
+	viper.Reset()
+	assert.Equal(t, DefaultConfig.Storage, GetStorage())
+
+	viper.Set("storage.driver", "s3")
+	viper.Set("storage.s3.bucket", "avatars")
+	viper.Set("storage.s3.pathstyle", true)
+
+	storage := GetStorage()
+	assert.Equal(t, "s3", storage.Driver)
+	assert.Equal(t, "avatars", storage.S3.Bucket)
+	assert.True(t, storage.S3.PathStyle)
+	assert.Equal(t, DefaultConfig.Storage.S3.Region, storage.S3.Region)
+	assert.Equal(t, DefaultConfig.Storage.Local, storage.Local)
+}
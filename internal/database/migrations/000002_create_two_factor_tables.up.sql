@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS user_two_factor(
+  user_id bigint NOT NULL PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  secret TEXT NOT NULL,
+  last_used_step bigint NOT NULL DEFAULT 0,
+  confirmed_at timestamp without time zone,
+  created_at timestamp without time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes(
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash TEXT NOT NULL,
+  used_at timestamp without time zone,
+  created_at timestamp without time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON user_recovery_codes(user_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_passkeys;
+ALTER TABLE users DROP COLUMN IF EXISTS passkey_user_handle;
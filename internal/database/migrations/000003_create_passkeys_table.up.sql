@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS user_passkeys(
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL CONSTRAINT name_length CHECK (CHAR_LENGTH(name) <= 64),
+  credential_id bytea NOT NULL UNIQUE,
+  public_key bytea NOT NULL,
+  attestation_type TEXT NOT NULL DEFAULT '',
+  attestation_format TEXT NOT NULL DEFAULT '',
+  transports TEXT NOT NULL DEFAULT '',
+  flags smallint NOT NULL DEFAULT 0,
+  aaguid bytea,
+  sign_count bigint NOT NULL DEFAULT 0,
+  clone_warning boolean NOT NULL DEFAULT false,
+  attachment TEXT NOT NULL DEFAULT '',
+  attestation jsonb NOT NULL DEFAULT '{}',
+  created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  last_used_at timestamp without time zone
+);
+
+CREATE INDEX ON user_passkeys(user_id);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS passkey_user_handle bytea UNIQUE;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS user_tokens(
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  purpose TEXT NOT NULL,
+  token_hash TEXT NOT NULL UNIQUE,
+  expires_at timestamp without time zone NOT NULL,
+  consumed_at timestamp without time zone,
+  created_at timestamp without time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON user_tokens(user_id, purpose);
+CREATE INDEX ON user_tokens(expires_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS login_attempts(
+  key TEXT NOT NULL PRIMARY KEY,
+  failures integer NOT NULL DEFAULT 0,
+  last_failure_at timestamp without time zone NOT NULL,
+  locked_until timestamp without time zone,
+  expires_at timestamp without time zone NOT NULL
+);
+
+CREATE INDEX ON login_attempts(expires_at);
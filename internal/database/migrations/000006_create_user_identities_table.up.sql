@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS user_identities(
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  provider TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  email citext NOT NULL,
+  created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  last_used_at timestamp without time zone,
+  UNIQUE (provider, subject)
+);
+
+CREATE INDEX ON user_identities(user_id);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS user_email_changes(
+  user_id bigint PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  new_email citext NOT NULL CONSTRAINT new_email_length CHECK (CHAR_LENGTH(new_email) <= 254),
+  old_email citext NOT NULL,
+  requested_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  confirmed_at timestamp without time zone
+);
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS roles(
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE CONSTRAINT name_length CHECK (CHAR_LENGTH(name) <= 64),
+  description TEXT NOT NULL DEFAULT '',
+  created_at timestamp without time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions(
+  role_id bigint NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+  permission TEXT NOT NULL CONSTRAINT permission_length CHECK (CHAR_LENGTH(permission) <= 64),
+  PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles(
+  user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role_id bigint NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+  created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX ON user_roles(role_id);
+
+INSERT INTO roles (name, description) VALUES ('admin', 'Can manage users and their roles');
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, permission FROM roles, (VALUES ('users.manage'), ('roles.manage')) AS p(permission)
+WHERE name = 'admin';
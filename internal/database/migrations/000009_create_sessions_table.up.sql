@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS sessions(
+  id TEXT NOT NULL PRIMARY KEY,
+  user_id bigint REFERENCES users(id) ON DELETE CASCADE,
+  data bytea NOT NULL,
+  user_agent TEXT NOT NULL DEFAULT '',
+  ip TEXT NOT NULL DEFAULT '',
+  created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  last_seen_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  expires_at timestamp without time zone NOT NULL
+);
+
+CREATE INDEX ON sessions(user_id);
+CREATE INDEX ON sessions(expires_at);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_api_tokens(
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL CONSTRAINT name_length CHECK (CHAR_LENGTH(name) <= 64),
+  token_hash TEXT NOT NULL UNIQUE,
+  scopes TEXT NOT NULL DEFAULT '',
+  created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  last_used_at timestamp without time zone
+);
+
+CREATE INDEX ON user_api_tokens(user_id);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS audit_events(
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  event_type TEXT NOT NULL,
+  actor_user_id bigint,
+  target_user_id bigint,
+  ip TEXT NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  metadata jsonb NOT NULL DEFAULT '{}',
+  created_at timestamp without time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON audit_events(actor_user_id, created_at);
+CREATE INDEX ON audit_events(target_user_id, created_at);
+CREATE INDEX ON audit_events(created_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS user_data_exports(
+  user_id bigint NOT NULL PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  token_hash TEXT NOT NULL UNIQUE,
+  archive bytea NOT NULL,
+  created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  expires_at timestamp without time zone NOT NULL
+);
+
+CREATE INDEX ON user_data_exports(expires_at);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS user_invites(
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  email citext NOT NULL,
+  role_id bigint REFERENCES roles(id) ON DELETE SET NULL,
+  inviter_user_id bigint REFERENCES users(id) ON DELETE SET NULL,
+  token_hash TEXT NOT NULL UNIQUE,
+  created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  expires_at timestamp without time zone NOT NULL,
+  accepted_at timestamp without time zone,
+  accepted_user_id bigint REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX ON user_invites(email);
+CREATE INDEX ON user_invites(inviter_user_id);
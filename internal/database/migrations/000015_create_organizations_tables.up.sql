@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS organizations(
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  name TEXT NOT NULL CONSTRAINT name_length CHECK (CHAR_LENGTH(name) <= 64),
+  slug TEXT NOT NULL UNIQUE CONSTRAINT slug_length CHECK (CHAR_LENGTH(slug) <= 64),
+  created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp without time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS organization_members(
+  organization_id bigint NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role TEXT NOT NULL CONSTRAINT valid_role CHECK (role IN ('owner', 'admin', 'member')),
+  created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX ON organization_members(user_id);
+
+CREATE TABLE IF NOT EXISTS organization_invites(
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  organization_id bigint NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  email citext NOT NULL,
+  role TEXT NOT NULL CONSTRAINT valid_role CHECK (role IN ('owner', 'admin', 'member')),
+  inviter_user_id bigint REFERENCES users(id) ON DELETE SET NULL,
+  created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  expires_at timestamp without time zone NOT NULL,
+  UNIQUE (organization_id, email)
+);
+
+CREATE INDEX ON organization_invites(email);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS legal_documents(
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  kind varchar(32) NOT NULL,
+  version varchar(64) NOT NULL,
+  body TEXT NOT NULL,
+  published_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  UNIQUE (kind, version)
+);
+
+CREATE TABLE IF NOT EXISTS legal_acceptances(
+  user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  document_id bigint NOT NULL REFERENCES legal_documents(id) ON DELETE CASCADE,
+  ip TEXT NOT NULL DEFAULT '',
+  accepted_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, document_id)
+);
+
+CREATE INDEX ON legal_acceptances(document_id);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_known_devices(
+  id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  fingerprint TEXT NOT NULL,
+  user_agent TEXT NOT NULL DEFAULT '',
+  ip TEXT NOT NULL DEFAULT '',
+  first_seen_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  last_seen_at timestamp without time zone NOT NULL DEFAULT NOW(),
+  UNIQUE (user_id, fingerprint)
+);
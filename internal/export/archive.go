@@ -0,0 +1,109 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+)
+
+const (
+	TTL             = 48 * time.Hour
+	RequestInterval = time.Hour
+	ArchiveDataFile = "data.json"
+
+	// Saving also removes the exports that have expired,
+	// so that old archives do not linger in the database.
+	saveArchiveQuery   = `WITH expired AS (DELETE FROM user_data_exports WHERE expires_at <= $4) INSERT INTO user_data_exports (user_id, token_hash, archive, created_at, expires_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, archive = EXCLUDED.archive, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at`
+	findArchiveQuery   = `SELECT archive FROM user_data_exports WHERE user_id = $1 AND token_hash = $2 AND expires_at > $3`
+	recentExportsQuery = `SELECT EXISTS (SELECT 1 FROM user_data_exports WHERE user_id = $1 AND created_at > $2)`
+)
+
+var ErrNotFound = errors.New("the export could not be found or has expired")
+
+func (data *Data) Archive() ([]byte, error) {
+	encoded, err := json.MarshalIndent(data, "", "  ")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode export: %w", err)
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	file, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     ArchiveDataFile,
+		Method:   zip.Deflate,
+		Modified: data.ExportedAt,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export archive: %w", err)
+	}
+
+	if _, err = file.Write(encoded); err != nil {
+		return nil, fmt.Errorf("failed to write export archive: %w", err)
+	}
+
+	if err = archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write export archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func Save(db database.DatabaseInterface, userID int, archive []byte) (string, error) {
+	token := rand.Text()
+	now := timeNow()
+
+	_, err := db.Exec(saveArchiveQuery, userID, hashToken(token), archive, now, now.Add(TTL))
+
+	if err != nil {
+		return "", fmt.Errorf("failed to save export: %w", err)
+	}
+
+	return token, nil
+}
+
+func Find(db database.DatabaseInterface, userID int, token string) ([]byte, error) {
+	if token == "" {
+		return nil, ErrNotFound
+	}
+
+	var archive []byte
+	err := db.QueryRow(findArchiveQuery, userID, hashToken(token), timeNow()).Scan(&archive)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("error finding export: %w", err)
+	}
+
+	return archive, nil
+}
+
+func Recent(db database.DatabaseInterface, userID int) (bool, error) {
+	var recent bool
+	err := db.QueryRow(recentExportsQuery, userID, timeNow().Add(-RequestInterval)).Scan(&recent)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to check recent exports: %w", err)
+	}
+
+	return recent, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,196 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchive(t *testing.T) {
+	now := freezeTime(t)
+
+	data := &Data{
+		ExportedAt: now,
+		User:       Account{ID: testUserID, Username: "user", Email: testEmail},
+		Roles:      []string{"admin"},
+	}
+
+	archive, err := data.Archive()
+	assert.NoError(t, err)
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	assert.NoError(t, err)
+	assert.Len(t, reader.File, 1)
+	assert.Equal(t, ArchiveDataFile, reader.File[0].Name)
+
+	file, err := reader.File[0].Open()
+	assert.NoError(t, err)
+
+	defer func() { _ = file.Close() }()
+
+	content, err := io.ReadAll(file)
+	assert.NoError(t, err)
+
+	var decoded Data
+	assert.NoError(t, json.Unmarshal(content, &decoded))
+	assert.Equal(t, data.User, decoded.User)
+	assert.Equal(t, data.Roles, decoded.Roles)
+	assert.True(t, data.ExportedAt.Equal(decoded.ExportedAt))
+	assert.NotContains(t, string(content), "password")
+}
+
+func TestSave(t *testing.T) {
+	now := freezeTime(t)
+
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{name: "success"},
+		{name: "database error", err: errDB, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			expectation := mock.ExpectExec(regexp.QuoteMeta(saveArchiveQuery)).
+				WithArgs(testUserID, sqlmock.AnyArg(), []byte("archive"), now, now.Add(TTL))
+
+			if tt.err != nil {
+				expectation.WillReturnError(tt.err)
+			} else {
+				expectation.WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			token, err := Save(db, testUserID, []byte("archive"))
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, token)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	now := freezeTime(t)
+
+	tests := []struct {
+		name      string
+		token     string
+		setupMock func(mock sqlmock.Sqlmock)
+		want      []byte
+		wantErr   error
+	}{
+		{
+			name:  "success",
+			token: "token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findArchiveQuery)).
+					WithArgs(testUserID, hashToken("token"), now).
+					WillReturnRows(sqlmock.NewRows([]string{"archive"}).AddRow([]byte("archive")))
+			},
+			want: []byte("archive"),
+		},
+		{
+			name:      "empty token",
+			setupMock: func(mock sqlmock.Sqlmock) {},
+			wantErr:   ErrNotFound,
+		},
+		{
+			name:  "not found",
+			token: "token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findArchiveQuery)).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrNotFound,
+		},
+		{
+			name:  "database error",
+			token: "token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findArchiveQuery)).WillReturnError(errDB)
+			},
+			wantErr: errDB,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			archive, err := Find(db, testUserID, tt.token)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, archive)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRecent(t *testing.T) {
+	now := freezeTime(t)
+
+	tests := []struct {
+		name      string
+		setupMock func(mock sqlmock.Sqlmock)
+		want      bool
+		wantErr   error
+	}{
+		{
+			name: "recent",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(recentExportsQuery)).
+					WithArgs(testUserID, now.Add(-RequestInterval)).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			want: true,
+		},
+		{
+			name: "not recent",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(recentExportsQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(recentExportsQuery)).WillReturnError(errDB)
+			},
+			wantErr: errDB,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			recent, err := Recent(db, testUserID)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, recent)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/avatar"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/legal"
+	"github.com/Dobefu/go-web-starter/internal/organization"
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/Dobefu/go-web-starter/internal/user"
+)
+
+var timeNow = time.Now
+
+// Data is everything that is stored about a user, in the form that is handed
+// to them. Secrets such as password hashes, key material and tokens are left out.
+type Data struct {
+	ExportedAt       time.Time         `json:"exported_at"`
+	User             Account           `json:"user"`
+	Avatar           *Avatar           `json:"avatar"`
+	Roles            []string          `json:"roles"`
+	TwoFactorEnabled bool              `json:"two_factor_enabled"`
+	Passkeys         []Passkey         `json:"passkeys"`
+	APITokens        []APIToken        `json:"api_tokens"`
+	Identities       []Identity        `json:"identities"`
+	Tokens           []Token           `json:"tokens"`
+	Sessions         []Session         `json:"sessions"`
+	AuditEvents      []AuditEvent      `json:"audit_events"`
+	LegalAcceptances []LegalAcceptance `json:"legal_acceptances"`
+	Organizations    []Organization    `json:"organizations"`
+	KnownDevices     []KnownDevice     `json:"known_devices"`
+}
+
+type Account struct {
+	ID        int        `json:"id"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	Status    bool       `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	LastLogin *time.Time `json:"last_login"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+type Avatar struct {
+	Key string `json:"key"`
+	URL string `json:"url,omitempty"`
+}
+
+type Passkey struct {
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type APIToken struct {
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type Identity struct {
+	Provider   string     `json:"provider"`
+	Subject    string     `json:"subject"`
+	Email      string     `json:"email"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type Token struct {
+	Purpose    string     `json:"purpose"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	ConsumedAt *time.Time `json:"consumed_at"`
+}
+
+type Session struct {
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type AuditEvent struct {
+	Type      string         `json:"type"`
+	ActorID   int            `json:"actor_id,omitempty"`
+	IP        string         `json:"ip"`
+	UserAgent string         `json:"user_agent"`
+	Metadata  audit.Metadata `json:"metadata"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type LegalAcceptance struct {
+	Document   string    `json:"document"`
+	Version    string    `json:"version"`
+	IP         string    `json:"ip"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+type Organization struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+	Role string `json:"role"`
+}
+
+type KnownDevice struct {
+	Device      string    `json:"device"`
+	UserAgent   string    `json:"user_agent"`
+	IP          string    `json:"ip"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// Collect gathers the data of the user. Only the audit events that happened
+// to the account of the user are included, so that the actions of an
+// administrator on other accounts do not end up in their export.
+func Collect(ctx context.Context, db database.DatabaseInterface, sessions sessionstore.Backend, store storage.Storage, usr *user.User) (*Data, error) {
+	data := &Data{
+		ExportedAt: timeNow(),
+		User: Account{
+			ID:        usr.GetID(),
+			Username:  usr.GetUsername(),
+			Email:     usr.GetEmail(),
+			Status:    usr.GetStatus(),
+			CreatedAt: usr.GetCreatedAt(),
+			UpdatedAt: usr.GetUpdatedAt(),
+			LastLogin: optionalTime(usr.GetLastLogin()),
+			DeletedAt: optionalTime(usr.GetDeletedAt()),
+		},
+		Avatar: collectAvatar(store, usr),
+	}
+
+	var err error
+
+	if data.Roles, err = usr.GetRoles(db); err != nil {
+		return nil, fmt.Errorf("failed to export roles: %w", err)
+	}
+
+	twoFactor, err := usr.GetTwoFactor(db)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to export two-factor settings: %w", err)
+	}
+
+	data.TwoFactorEnabled = twoFactor.IsEnabled()
+
+	if data.Passkeys, err = collectPasskeys(db, usr); err != nil {
+		return nil, err
+	}
+
+	if data.APITokens, err = collectAPITokens(db, usr); err != nil {
+		return nil, err
+	}
+
+	if data.Identities, err = collectIdentities(db, usr); err != nil {
+		return nil, err
+	}
+
+	if data.Tokens, err = collectTokens(db, usr); err != nil {
+		return nil, err
+	}
+
+	if data.Sessions, err = collectSessions(ctx, sessions, usr); err != nil {
+		return nil, err
+	}
+
+	if data.AuditEvents, err = collectAuditEvents(db, usr); err != nil {
+		return nil, err
+	}
+
+	if data.LegalAcceptances, err = collectLegalAcceptances(db, usr); err != nil {
+		return nil, err
+	}
+
+	if data.Organizations, err = collectOrganizations(db, usr); err != nil {
+		return nil, err
+	}
+
+	if data.KnownDevices, err = collectKnownDevices(db, usr); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func collectAvatar(store storage.Storage, usr *user.User) *Avatar {
+	if !usr.HasAvatar() {
+		return nil
+	}
+
+	return &Avatar{
+		Key: usr.GetAvatar(),
+		URL: usr.GetAvatarURL(store, avatar.SizeLarge),
+	}
+}
+
+func collectPasskeys(db database.DatabaseInterface, usr *user.User) ([]Passkey, error) {
+	passkeys, err := usr.GetPasskeys(db)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to export passkeys: %w", err)
+	}
+
+	exported := make([]Passkey, 0, len(passkeys))
+
+	for _, passkey := range passkeys {
+		exported = append(exported, Passkey{
+			Name:       passkey.Name,
+			CreatedAt:  passkey.CreatedAt,
+			LastUsedAt: passkey.LastUsedAt,
+		})
+	}
+
+	return exported, nil
+}
+
+func collectAPITokens(db database.DatabaseInterface, usr *user.User) ([]APIToken, error) {
+	tokens, err := usr.GetAPITokens(db)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to export API tokens: %w", err)
+	}
+
+	exported := make([]APIToken, 0, len(tokens))
+
+	for _, token := range tokens {
+		exported = append(exported, APIToken{
+			Name:       token.Name,
+			Scopes:     token.Scopes,
+			CreatedAt:  token.CreatedAt,
+			LastUsedAt: token.LastUsedAt,
+		})
+	}
+
+	return exported, nil
+}
+
+func collectIdentities(db database.DatabaseInterface, usr *user.User) ([]Identity, error) {
+	identities, err := usr.GetIdentities(db)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to export identities: %w", err)
+	}
+
+	exported := make([]Identity, 0, len(identities))
+
+	for _, identity := range identities {
+		exported = append(exported, Identity(identity))
+	}
+
+	return exported, nil
+}
+
+func collectTokens(db database.DatabaseInterface, usr *user.User) ([]Token, error) {
+	tokens, err := usr.GetTokens(db)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to export tokens: %w", err)
+	}
+
+	exported := make([]Token, 0, len(tokens))
+
+	for _, token := range tokens {
+		exported = append(exported, Token{
+			Purpose:    string(token.Purpose),
+			CreatedAt:  token.CreatedAt,
+			ExpiresAt:  token.ExpiresAt,
+			ConsumedAt: token.ConsumedAt,
+		})
+	}
+
+	return exported, nil
+}
+
+func collectSessions(ctx context.Context, sessions sessionstore.Backend, usr *user.User) ([]Session, error) {
+	records, err := sessions.ListByUser(ctx, usr.GetID())
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to export sessions: %w", err)
+	}
+
+	exported := make([]Session, 0, len(records))
+
+	for _, record := range records {
+		exported = append(exported, Session{
+			Device:     record.Device(),
+			UserAgent:  record.UserAgent,
+			IP:         record.IP,
+			CreatedAt:  record.CreatedAt,
+			LastSeenAt: record.LastSeenAt,
+			ExpiresAt:  record.ExpiresAt,
+		})
+	}
+
+	return exported, nil
+}
+
+func collectAuditEvents(db database.DatabaseInterface, usr *user.User) ([]AuditEvent, error) {
+	events, err := audit.Find(db, audit.Query{TargetID: usr.GetID()})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to export audit events: %w", err)
+	}
+
+	exported := make([]AuditEvent, 0, len(events))
+
+	for _, event := range events {
+		exported = append(exported, AuditEvent{
+			Type:      string(event.Type),
+			ActorID:   event.ActorID,
+			IP:        event.IP,
+			UserAgent: event.UserAgent,
+			Metadata:  event.Metadata,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	return exported, nil
+}
+
+func collectLegalAcceptances(db database.DatabaseInterface, usr *user.User) ([]LegalAcceptance, error) {
+	acceptances, err := legal.Acceptances(db, usr.GetID())
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to export legal acceptances: %w", err)
+	}
+
+	exported := make([]LegalAcceptance, 0, len(acceptances))
+
+	for _, acceptance := range acceptances {
+		exported = append(exported, LegalAcceptance{
+			Document:   acceptance.Document.Title(),
+			Version:    acceptance.Document.Version,
+			IP:         acceptance.IP,
+			AcceptedAt: acceptance.AcceptedAt,
+		})
+	}
+
+	return exported, nil
+}
+
+func collectOrganizations(db database.DatabaseInterface, usr *user.User) ([]Organization, error) {
+	memberships, err := organization.FindMemberships(db, usr.GetID())
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to export organizations: %w", err)
+	}
+
+	exported := make([]Organization, 0, len(memberships))
+
+	for _, membership := range memberships {
+		exported = append(exported, Organization{
+			Name: membership.Organization.GetName(),
+			Slug: membership.Organization.GetSlug(),
+			Role: membership.Role,
+		})
+	}
+
+	return exported, nil
+}
+
+func collectKnownDevices(db database.DatabaseInterface, usr *user.User) ([]KnownDevice, error) {
+	devices, err := usr.GetKnownDevices(db)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to export known devices: %w", err)
+	}
+
+	exported := make([]KnownDevice, 0, len(devices))
+
+	for _, device := range devices {
+		exported = append(exported, KnownDevice{
+			Device:      sessionstore.DeviceName(device.UserAgent),
+			UserAgent:   device.UserAgent,
+			IP:          device.IP,
+			FirstSeenAt: device.FirstSeenAt,
+			LastSeenAt:  device.LastSeenAt,
+		})
+	}
+
+	return exported, nil
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,244 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testUserID = 1
+	testEmail  = "user@example.com"
+)
+
+var errDB = errors.New("db fail")
+
+type fakeSessionBackend struct {
+	sessionstore.Backend
+
+	records []*sessionstore.Record
+	err     error
+}
+
+func (b *fakeSessionBackend) ListByUser(_ context.Context, _ int) ([]*sessionstore.Record, error) {
+	return b.records, b.err
+}
+
+func freezeTime(t *testing.T) time.Time {
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	timeNowOrig := timeNow
+
+	t.Cleanup(func() { timeNow = timeNowOrig })
+	timeNow = func() time.Time { return now }
+
+	return now
+}
+
+func testUser(now time.Time) *user.User {
+	return user.New(user.UserFields{
+		Id:        testUserID,
+		Username:  "user",
+		Email:     testEmail,
+		Password:  "hash",
+		Status:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+}
+
+// expectCollect sets up the queries that Collect runs, and makes the one at
+// failAt return an error. Passing -1 lets all of them succeed.
+func expectCollect(mock sqlmock.Sqlmock, now time.Time, failAt int) {
+	queries := []struct {
+		query string
+		rows  *sqlmock.Rows
+	}{
+		{
+			query: `SELECT r.name FROM roles`,
+			rows:  sqlmock.NewRows([]string{"name"}).AddRow("admin"),
+		},
+		{
+			query: `SELECT secret, last_used_step, confirmed_at FROM user_two_factor`,
+			rows:  sqlmock.NewRows([]string{"secret", "last_used_step", "confirmed_at"}).AddRow("SECRET", 0, now),
+		},
+		{
+			query: `SELECT (.+) FROM user_passkeys`,
+			rows: sqlmock.NewRows([]string{"id", "name", "credential_id", "public_key", "attestation_type", "attestation_format", "transports", "flags", "aaguid", "sign_count", "clone_warning", "attachment", "attestation", "created_at", "last_used_at"}).
+				AddRow(1, "Laptop", []byte("credential"), []byte("public key"), "none", "", "", 0, []byte{}, 0, false, "", nil, now, nil),
+		},
+		{
+			query: `SELECT (.+) FROM user_api_tokens`,
+			rows:  sqlmock.NewRows([]string{"id", "name", "scopes", "created_at", "last_used_at"}).AddRow(1, "CI", "account:read", now, now),
+		},
+		{
+			query: `SELECT (.+) FROM user_identities`,
+			rows:  sqlmock.NewRows([]string{"provider", "subject", "email", "created_at", "last_used_at"}).AddRow("company", "subject", testEmail, now, nil),
+		},
+		{
+			query: `SELECT (.+) FROM user_tokens`,
+			rows:  sqlmock.NewRows([]string{"purpose", "created_at", "expires_at", "consumed_at"}).AddRow("verify", now, now, now),
+		},
+		{
+			query: `SELECT (.+) FROM audit_events WHERE target_user_id = \$1`,
+			rows: sqlmock.NewRows([]string{"id", "event_type", "actor_user_id", "target_user_id", "ip", "user_agent", "metadata", "created_at"}).
+				AddRow(1, "login.succeeded", testUserID, testUserID, "127.0.0.1", "curl", []byte(`{"method":"password"}`), now),
+		},
+		{
+			query: `SELECT (.+) FROM legal_acceptances`,
+			rows: sqlmock.NewRows([]string{"id", "kind", "version", "body", "published_at", "ip", "accepted_at"}).
+				AddRow(1, "terms", "2026-01", "Terms", now, "127.0.0.1", now),
+		},
+		{
+			query: `SELECT (.+) FROM organization_members`,
+			rows: sqlmock.NewRows([]string{"id", "name", "slug", "created_at", "updated_at", "role"}).
+				AddRow(1, "Company", "company", now, now, "owner"),
+		},
+		{
+			query: `SELECT (.+) FROM user_known_devices`,
+			rows: sqlmock.NewRows([]string{"user_agent", "ip", "first_seen_at", "last_seen_at"}).
+				AddRow("curl", "127.0.0.1", now, now),
+		},
+	}
+
+	for i, q := range queries {
+		expectation := mock.ExpectQuery(q.query).WithArgs(testUserID)
+
+		if i == failAt {
+			expectation.WillReturnError(errDB)
+			return
+		}
+
+		expectation.WillReturnRows(q.rows)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	now := freezeTime(t)
+
+	db, mock, _ := sqlmock.New()
+	defer func() { _ = db.Close() }()
+
+	expectCollect(mock, now, -1)
+
+	sessions := &fakeSessionBackend{records: []*sessionstore.Record{{
+		ID:         "secret session id",
+		UserID:     testUserID,
+		Data:       []byte("secret session data"),
+		UserAgent:  "Mozilla/5.0 (X11; Linux x86_64) Firefox/120.0",
+		IP:         "127.0.0.1",
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now,
+	}}}
+
+	data, err := Collect(context.Background(), db, sessions, nil, testUser(now))
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, now, data.ExportedAt)
+	assert.Equal(t, Account{ID: testUserID, Username: "user", Email: testEmail, Status: true, CreatedAt: now, UpdatedAt: now}, data.User)
+	assert.Nil(t, data.Avatar)
+	assert.Equal(t, []string{"admin"}, data.Roles)
+	assert.True(t, data.TwoFactorEnabled)
+	assert.Equal(t, []Passkey{{Name: "Laptop", CreatedAt: now}}, data.Passkeys)
+	assert.Equal(t, []APIToken{{Name: "CI", Scopes: []string{"account:read"}, CreatedAt: now, LastUsedAt: &now}}, data.APITokens)
+	assert.Equal(t, []Identity{{Provider: "company", Subject: "subject", Email: testEmail, CreatedAt: now}}, data.Identities)
+	assert.Equal(t, []Token{{Purpose: "verify", CreatedAt: now, ExpiresAt: now, ConsumedAt: &now}}, data.Tokens)
+	assert.Equal(t, []Session{{
+		Device:     sessionstore.DeviceName(sessions.records[0].UserAgent),
+		UserAgent:  sessions.records[0].UserAgent,
+		IP:         "127.0.0.1",
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now,
+	}}, data.Sessions)
+	assert.Equal(t, []AuditEvent{{
+		Type:      string(audit.EventLoginSucceeded),
+		ActorID:   testUserID,
+		IP:        "127.0.0.1",
+		UserAgent: "curl",
+		Metadata:  audit.Metadata{"method": "password"},
+		CreatedAt: now,
+	}}, data.AuditEvents)
+	assert.Equal(t, []LegalAcceptance{{Document: "Terms of Service", Version: "2026-01", IP: "127.0.0.1", AcceptedAt: now}}, data.LegalAcceptances)
+	assert.Equal(t, []Organization{{Name: "Company", Slug: "company", Role: "owner"}}, data.Organizations)
+	assert.Equal(t, []KnownDevice{{Device: sessionstore.DeviceName("curl"), UserAgent: "curl", IP: "127.0.0.1", FirstSeenAt: now, LastSeenAt: now}}, data.KnownDevices)
+}
+
+func TestCollectAvatar(t *testing.T) {
+	testCases := []struct {
+		name     string
+		store    storage.Storage
+		expected *Avatar
+	}{
+		{
+			name:     "storage",
+			store:    storage.NewLocalStorage("uploads", "/uploads"),
+			expected: &Avatar{Key: "avatars/1/abc", URL: "/uploads/avatars/1/abc-256.png"},
+		},
+		{
+			name:     "no storage",
+			expected: &Avatar{Key: "avatars/1/abc"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			usr := user.New(user.UserFields{Id: testUserID, Avatar: "avatars/1/abc"})
+
+			assert.Equal(t, tc.expected, collectAvatar(tc.store, usr))
+		})
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	now := freezeTime(t)
+
+	tests := []struct {
+		name        string
+		failAt      int
+		sessionsErr error
+		wantErr     string
+	}{
+		{name: "roles", failAt: 0, wantErr: "failed to export roles"},
+		{name: "two-factor", failAt: 1, wantErr: "failed to export two-factor settings"},
+		{name: "passkeys", failAt: 2, wantErr: "failed to export passkeys"},
+		{name: "API tokens", failAt: 3, wantErr: "failed to export API tokens"},
+		{name: "identities", failAt: 4, wantErr: "failed to export identities"},
+		{name: "tokens", failAt: 5, wantErr: "failed to export tokens"},
+		{name: "sessions", failAt: 6, sessionsErr: errDB, wantErr: "failed to export sessions"},
+		{name: "audit events", failAt: 6, wantErr: "failed to export audit events"},
+		{name: "legal acceptances", failAt: 7, wantErr: "failed to export legal acceptances"},
+		{name: "organizations", failAt: 8, wantErr: "failed to export organizations"},
+		{name: "known devices", failAt: 9, wantErr: "failed to export known devices"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			failAt := tt.failAt
+
+			if tt.sessionsErr != nil {
+				failAt = -1
+			}
+
+			expectCollect(mock, now, failAt)
+
+			_, err := Collect(context.Background(), db, &fakeSessionBackend{err: tt.sessionsErr}, nil, testUser(now))
+
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, errDB)
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package legal
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/lib/pq"
+)
+
+type Kind string
+
+const (
+	KindTerms   Kind = "terms"
+	KindPrivacy Kind = "privacy"
+)
+
+var Kinds = []Kind{KindTerms, KindPrivacy}
+
+const MaxVersionLength = 64
+
+var (
+	ErrUnknownKind      = errors.New("unknown kind of legal document")
+	ErrInvalidVersion   = fmt.Errorf("the version must be between 1 and %d characters", MaxVersionLength)
+	ErrEmptyBody        = errors.New("the document cannot be empty")
+	ErrVersionExists    = errors.New("this version of the document has already been published")
+	ErrDocumentNotFound = errors.New("the document could not be found")
+)
+
+const (
+	pqUniqueViolation = "23505"
+
+	documentColumns       = `id, kind, version, body, published_at`
+	insertDocumentQuery   = `INSERT INTO legal_documents (kind, version, body, published_at) VALUES ($1, $2, $3, $4) RETURNING id`
+	currentDocumentsQuery = `SELECT DISTINCT ON (kind) ` + documentColumns + ` FROM legal_documents ORDER BY kind, published_at DESC, id DESC`
+	currentDocumentQuery  = `SELECT ` + documentColumns + ` FROM legal_documents WHERE kind = $1 ORDER BY published_at DESC, id DESC LIMIT 1`
+	pendingDocumentsQuery = `SELECT ` + documentColumns + ` FROM (` + currentDocumentsQuery + `) AS current_documents WHERE NOT EXISTS (SELECT 1 FROM legal_acceptances WHERE legal_acceptances.document_id = current_documents.id AND legal_acceptances.user_id = $1)`
+	insertAcceptanceQuery = `INSERT INTO legal_acceptances (user_id, document_id, ip, accepted_at) VALUES ($1, $2, $3, $4) ON CONFLICT (user_id, document_id) DO NOTHING`
+	acceptancesQuery      = `SELECT d.id, d.kind, d.version, d.body, d.published_at, a.ip, a.accepted_at FROM legal_acceptances a JOIN legal_documents d ON d.id = a.document_id WHERE a.user_id = $1 ORDER BY a.accepted_at, d.id`
+	countUsersQuery       = `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`
+	coverageQuery         = `SELECT d.id, d.kind, d.version, d.body, d.published_at, COUNT(users.id) FROM legal_documents d LEFT JOIN legal_acceptances a ON a.document_id = d.id LEFT JOIN users ON users.id = a.user_id AND users.deleted_at IS NULL GROUP BY d.id ORDER BY d.kind, d.published_at DESC, d.id DESC`
+)
+
+var kindTitles = map[Kind]string{
+	KindTerms:   "Terms of Service",
+	KindPrivacy: "Privacy Policy",
+}
+
+var timeNow = time.Now
+
+func ParseKind(name string) (Kind, error) {
+	kind := Kind(name)
+
+	if !slices.Contains(Kinds, kind) {
+		return "", ErrUnknownKind
+	}
+
+	return kind, nil
+}
+
+func (k Kind) Title() string {
+	if title, ok := kindTitles[k]; ok {
+		return title
+	}
+
+	return string(k)
+}
+
+type Document struct {
+	ID          int
+	Kind        Kind
+	Version     string
+	Body        string
+	PublishedAt time.Time
+}
+
+func (d Document) Title() string {
+	return d.Kind.Title()
+}
+
+type Coverage struct {
+	Document Document
+	Current  bool
+	Accepted int
+	Users    int
+}
+
+type Acceptance struct {
+	Document   Document
+	IP         string
+	AcceptedAt time.Time
+}
+
+func (c Coverage) Percentage() float64 {
+	if c.Users == 0 {
+		return 0
+	}
+
+	return float64(c.Accepted) / float64(c.Users) * 100
+}
+
+func Publish(db database.DatabaseInterface, kind Kind, version string, body string) (*Document, error) {
+	if !slices.Contains(Kinds, kind) {
+		return nil, ErrUnknownKind
+	}
+
+	version = strings.TrimSpace(version)
+
+	if version == "" || len(version) > MaxVersionLength {
+		return nil, ErrInvalidVersion
+	}
+
+	if strings.TrimSpace(body) == "" {
+		return nil, ErrEmptyBody
+	}
+
+	doc := &Document{Kind: kind, Version: version, Body: body, PublishedAt: timeNow()}
+	err := db.QueryRow(insertDocumentQuery, string(doc.Kind), doc.Version, doc.Body, doc.PublishedAt).Scan(&doc.ID)
+
+	if err != nil {
+		var pqErr *pq.Error
+
+		if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+			return nil, ErrVersionExists
+		}
+
+		return nil, fmt.Errorf("failed to publish legal document: %w", err)
+	}
+
+	return doc, nil
+}
+
+func Current(db database.DatabaseInterface) ([]Document, error) {
+	return findDocuments(db, currentDocumentsQuery)
+}
+
+func CurrentByKind(db database.DatabaseInterface, kind Kind) (*Document, error) {
+	doc := &Document{}
+
+	var kindName string
+
+	err := db.QueryRow(currentDocumentQuery, string(kind)).Scan(&doc.ID, &kindName, &doc.Version, &doc.Body, &doc.PublishedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDocumentNotFound
+		}
+
+		return nil, fmt.Errorf("error finding legal document: %w", err)
+	}
+
+	doc.Kind = Kind(kindName)
+
+	return doc, nil
+}
+
+func Pending(db database.DatabaseInterface, userID int) ([]Document, error) {
+	return findDocuments(db, pendingDocumentsQuery, userID)
+}
+
+func Accept(db database.DatabaseInterface, userID int, docs []Document, ip string) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return fmt.Errorf("failed to accept legal documents: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	now := timeNow()
+
+	for _, doc := range docs {
+		_, err = tx.Exec(insertAcceptanceQuery, userID, doc.ID, ip, now)
+
+		if err != nil {
+			return fmt.Errorf("failed to accept legal document %d: %w", doc.ID, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to accept legal documents: %w", err)
+	}
+
+	return nil
+}
+
+func Acceptances(db database.DatabaseInterface, userID int) ([]Acceptance, error) {
+	rows, err := db.Query(acceptancesQuery, userID)
+
+	if err != nil {
+		return nil, fmt.Errorf("error finding legal acceptances: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	acceptances := []Acceptance{}
+
+	for rows.Next() {
+		var (
+			acceptance Acceptance
+			kind       string
+		)
+
+		err = rows.Scan(
+			&acceptance.Document.ID,
+			&kind,
+			&acceptance.Document.Version,
+			&acceptance.Document.Body,
+			&acceptance.Document.PublishedAt,
+			&acceptance.IP,
+			&acceptance.AcceptedAt,
+		)
+
+		if err != nil {
+			return nil, fmt.Errorf("error scanning legal acceptance: %w", err)
+		}
+
+		acceptance.Document.Kind = Kind(kind)
+		acceptances = append(acceptances, acceptance)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error finding legal acceptances: %w", err)
+	}
+
+	return acceptances, nil
+}
+
+func CoverageReport(db database.DatabaseInterface) ([]Coverage, error) {
+	var users int
+
+	err := db.QueryRow(countUsersQuery).Scan(&users)
+
+	if err != nil {
+		return nil, fmt.Errorf("error counting users: %w", err)
+	}
+
+	rows, err := db.Query(coverageQuery)
+
+	if err != nil {
+		return nil, fmt.Errorf("error finding legal document coverage: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	report := []Coverage{}
+
+	for rows.Next() {
+		var (
+			coverage = Coverage{Users: users}
+			kind     string
+		)
+
+		err = rows.Scan(
+			&coverage.Document.ID,
+			&kind,
+			&coverage.Document.Version,
+			&coverage.Document.Body,
+			&coverage.Document.PublishedAt,
+			&coverage.Accepted,
+		)
+
+		if err != nil {
+			return nil, fmt.Errorf("error scanning legal document coverage: %w", err)
+		}
+
+		coverage.Document.Kind = Kind(kind)
+		coverage.Current = len(report) == 0 || report[len(report)-1].Document.Kind != coverage.Document.Kind
+
+		report = append(report, coverage)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error finding legal document coverage: %w", err)
+	}
+
+	return report, nil
+}
+
+func findDocuments(db database.DatabaseInterface, query string, args ...any) ([]Document, error) {
+	rows, err := db.Query(query, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("error finding legal documents: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	docs := []Document{}
+
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, *doc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error finding legal documents: %w", err)
+	}
+
+	slices.SortStableFunc(docs, func(a, b Document) int {
+		return slices.Index(Kinds, a.Kind) - slices.Index(Kinds, b.Kind)
+	})
+
+	return docs, nil
+}
+
+func scanDocument(rows *sql.Rows) (*Document, error) {
+	doc := &Document{}
+
+	var kind string
+
+	err := rows.Scan(&doc.ID, &kind, &doc.Version, &doc.Body, &doc.PublishedAt)
+
+	if err != nil {
+		return nil, fmt.Errorf("error scanning legal document: %w", err)
+	}
+
+	doc.Kind = Kind(kind)
+
+	return doc, nil
+}
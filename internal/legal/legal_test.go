@@ -0,0 +1,508 @@
+package legal
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testUserID        = 2
+	testIP            = "192.0.2.1"
+	testPublishedUnix = 100000
+)
+
+var documentColumnNames = []string{"id", "kind", "version", "body", "published_at"}
+
+func freezeTime(t *testing.T) time.Time {
+	now := time.Unix(testPublishedUnix, 0)
+	timeNowOrig := timeNow
+
+	t.Cleanup(func() { timeNow = timeNowOrig })
+	timeNow = func() time.Time { return now }
+
+	return now
+}
+
+func testDocuments() []Document {
+	return []Document{
+		{ID: 1, Kind: KindTerms, Version: "2026-01", Body: "Terms", PublishedAt: time.Unix(testPublishedUnix, 0)},
+		{ID: 2, Kind: KindPrivacy, Version: "2026-02", Body: "Privacy", PublishedAt: time.Unix(testPublishedUnix, 0)},
+	}
+}
+
+func documentRows(docs ...Document) *sqlmock.Rows {
+	rows := sqlmock.NewRows(documentColumnNames)
+
+	for _, doc := range docs {
+		rows.AddRow(doc.ID, string(doc.Kind), doc.Version, doc.Body, doc.PublishedAt)
+	}
+
+	return rows
+}
+
+func TestParseKind(t *testing.T) {
+	kind, err := ParseKind("terms")
+	assert.NoError(t, err)
+	assert.Equal(t, KindTerms, kind)
+
+	_, err = ParseKind("cookies")
+	assert.ErrorIs(t, err, ErrUnknownKind)
+}
+
+func TestTitle(t *testing.T) {
+	assert.Equal(t, "Terms of Service", KindTerms.Title())
+	assert.Equal(t, "Privacy Policy", Document{Kind: KindPrivacy}.Title())
+	assert.Equal(t, "cookies", Kind("cookies").Title())
+}
+
+func TestPercentage(t *testing.T) {
+	assert.Equal(t, 0.0, Coverage{}.Percentage())
+	assert.Equal(t, 25.0, Coverage{Accepted: 1, Users: 4}.Percentage())
+}
+
+func TestPublish(t *testing.T) {
+	tests := []struct {
+		name      string
+		kind      Kind
+		version   string
+		body      string
+		setupMock func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name:    "success",
+			kind:    KindTerms,
+			version: " 2026-10 ",
+			body:    "Terms",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(insertDocumentQuery)).
+					WithArgs("terms", "2026-10", "Terms", time.Unix(testPublishedUnix, 0)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+			},
+		},
+		{
+			name:    "unknown kind",
+			kind:    "cookies",
+			version: "2026-10",
+			body:    "Cookies",
+			wantErr: ErrUnknownKind,
+		},
+		{
+			name:    "empty version",
+			kind:    KindTerms,
+			version: " ",
+			body:    "Terms",
+			wantErr: ErrInvalidVersion,
+		},
+		{
+			name:    "version too long",
+			kind:    KindTerms,
+			version: strings.Repeat("a", MaxVersionLength+1),
+			body:    "Terms",
+			wantErr: ErrInvalidVersion,
+		},
+		{
+			name:    "empty body",
+			kind:    KindTerms,
+			version: "2026-10",
+			body:    "\n",
+			wantErr: ErrEmptyBody,
+		},
+		{
+			name:    "version exists",
+			kind:    KindTerms,
+			version: "2026-10",
+			body:    "Terms",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(insertDocumentQuery)).WillReturnError(&pq.Error{Code: pqUniqueViolation})
+			},
+			wantErr: ErrVersionExists,
+		},
+		{
+			name:    "database error",
+			kind:    KindTerms,
+			version: "2026-10",
+			body:    "Terms",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(insertDocumentQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := freezeTime(t)
+
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			if tt.setupMock != nil {
+				tt.setupMock(mock)
+			}
+
+			doc, err := Publish(db, tt.kind, tt.version, tt.body)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, doc)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, &Document{ID: 3, Kind: KindTerms, Version: "2026-10", Body: "Terms", PublishedAt: now}, doc)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	docs := testDocuments()
+
+	db, mock, _ := sqlmock.New()
+	defer func() { _ = db.Close() }()
+
+	// The database sorts the kinds by name, which puts the privacy policy first.
+	mock.ExpectQuery(regexp.QuoteMeta(currentDocumentsQuery)).WillReturnRows(documentRows(docs[1], docs[0]))
+
+	got, err := Current(db)
+	assert.NoError(t, err)
+	assert.Equal(t, docs, got)
+
+	mock.ExpectQuery(regexp.QuoteMeta(currentDocumentsQuery)).WillReturnError(sql.ErrConnDone)
+
+	_, err = Current(db)
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+
+	mock.ExpectQuery(regexp.QuoteMeta(currentDocumentsQuery)).
+		WillReturnRows(sqlmock.NewRows(documentColumnNames).AddRow("id", "terms", "1", "Terms", "now"))
+
+	_, err = Current(db)
+	assert.Error(t, err)
+
+	mock.ExpectQuery(regexp.QuoteMeta(currentDocumentsQuery)).
+		WillReturnRows(documentRows(docs[0]).RowError(0, sql.ErrConnDone))
+
+	_, err = Current(db)
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCurrentByKind(t *testing.T) {
+	docs := testDocuments()
+
+	tests := []struct {
+		name    string
+		rows    *sqlmock.Rows
+		err     error
+		wantErr error
+	}{
+		{name: "success", rows: documentRows(docs[0])},
+		{name: "not found", rows: documentRows(), wantErr: ErrDocumentNotFound},
+		{name: "database error", err: sql.ErrConnDone, wantErr: sql.ErrConnDone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			query := mock.ExpectQuery(regexp.QuoteMeta(currentDocumentQuery)).WithArgs("terms")
+
+			if tt.err != nil {
+				query.WillReturnError(tt.err)
+			} else {
+				query.WillReturnRows(tt.rows)
+			}
+
+			doc, err := CurrentByKind(db, KindTerms)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, doc)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, &docs[0], doc)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPending(t *testing.T) {
+	docs := testDocuments()
+
+	db, mock, _ := sqlmock.New()
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(regexp.QuoteMeta(pendingDocumentsQuery)).WithArgs(testUserID).WillReturnRows(documentRows(docs[1]))
+
+	got, err := Pending(db, testUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, docs[1:], got)
+
+	mock.ExpectQuery(regexp.QuoteMeta(pendingDocumentsQuery)).WithArgs(testUserID).WillReturnRows(documentRows())
+
+	got, err = Pending(db, testUserID)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAccept(t *testing.T) {
+	docs := testDocuments()
+
+	tests := []struct {
+		name      string
+		docs      []Document
+		setupMock func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "success",
+			docs: docs,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+
+				for _, doc := range docs {
+					mock.ExpectExec(regexp.QuoteMeta(insertAcceptanceQuery)).
+						WithArgs(testUserID, doc.ID, testIP, time.Unix(testPublishedUnix, 0)).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:      "no documents",
+			setupMock: func(mock sqlmock.Sqlmock) {},
+		},
+		{
+			name: "begin error",
+			docs: docs,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin().WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+		{
+			name: "insert error",
+			docs: docs,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(insertAcceptanceQuery)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "commit error",
+			docs: docs[:1],
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(insertAcceptanceQuery)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit().WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			freezeTime(t)
+
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			err := Accept(db, testUserID, tt.docs, testIP)
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, sql.ErrConnDone)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAcceptances(t *testing.T) {
+	docs := testDocuments()
+	acceptedAt := time.Unix(testPublishedUnix+1, 0)
+	acceptanceColumns := append(documentColumnNames, "ip", "accepted_at")
+
+	tests := []struct {
+		name      string
+		setupMock func(mock sqlmock.Sqlmock)
+		want      []Acceptance
+		wantErr   bool
+	}{
+		{
+			name: "success",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(acceptancesQuery)).WithArgs(testUserID).WillReturnRows(
+					sqlmock.NewRows(acceptanceColumns).
+						AddRow(docs[0].ID, "terms", docs[0].Version, docs[0].Body, docs[0].PublishedAt, testIP, acceptedAt).
+						AddRow(docs[1].ID, "privacy", docs[1].Version, docs[1].Body, docs[1].PublishedAt, "", acceptedAt),
+				)
+			},
+			want: []Acceptance{
+				{Document: docs[0], IP: testIP, AcceptedAt: acceptedAt},
+				{Document: docs[1], AcceptedAt: acceptedAt},
+			},
+		},
+		{
+			name: "none",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(acceptancesQuery)).WithArgs(testUserID).WillReturnRows(sqlmock.NewRows(acceptanceColumns))
+			},
+			want: []Acceptance{},
+		},
+		{
+			name: "query error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(acceptancesQuery)).WithArgs(testUserID).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+		{
+			name: "scan error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(acceptancesQuery)).WithArgs(testUserID).WillReturnRows(
+					sqlmock.NewRows(acceptanceColumns).AddRow("id", "terms", "1", "Terms", time.Now(), testIP, time.Now()),
+				)
+			},
+			wantErr: true,
+		},
+		{
+			name: "rows error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(acceptancesQuery)).WithArgs(testUserID).WillReturnRows(
+					sqlmock.NewRows(acceptanceColumns).AddRow(1, "terms", "1", "Terms", time.Now(), testIP, time.Now()).RowError(0, sql.ErrConnDone),
+				)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			got, err := Acceptances(db, testUserID)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCoverageReport(t *testing.T) {
+	docs := testDocuments()
+	oldPrivacy := Document{ID: 4, Kind: KindPrivacy, Version: "2025-12", Body: "Old", PublishedAt: time.Unix(testPublishedUnix-1, 0)}
+	coverageColumns := append(documentColumnNames, "count")
+
+	tests := []struct {
+		name      string
+		setupMock func(mock sqlmock.Sqlmock)
+		want      []Coverage
+		wantErr   bool
+	}{
+		{
+			name: "success",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countUsersQuery)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+				mock.ExpectQuery(regexp.QuoteMeta(coverageQuery)).WillReturnRows(
+					sqlmock.NewRows(coverageColumns).
+						AddRow(docs[1].ID, "privacy", docs[1].Version, docs[1].Body, docs[1].PublishedAt, 1).
+						AddRow(oldPrivacy.ID, "privacy", oldPrivacy.Version, oldPrivacy.Body, oldPrivacy.PublishedAt, 3).
+						AddRow(docs[0].ID, "terms", docs[0].Version, docs[0].Body, docs[0].PublishedAt, 4),
+				)
+			},
+			want: []Coverage{
+				{Document: docs[1], Current: true, Accepted: 1, Users: 4},
+				{Document: oldPrivacy, Accepted: 3, Users: 4},
+				{Document: docs[0], Current: true, Accepted: 4, Users: 4},
+			},
+		},
+		{
+			name: "count error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countUsersQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+		{
+			name: "query error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countUsersQuery)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+				mock.ExpectQuery(regexp.QuoteMeta(coverageQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+		{
+			name: "scan error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countUsersQuery)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+				mock.ExpectQuery(regexp.QuoteMeta(coverageQuery)).WillReturnRows(
+					sqlmock.NewRows(coverageColumns).AddRow(1, "terms", "1", "Terms", time.Now(), "many"),
+				)
+			},
+			wantErr: true,
+		},
+		{
+			name: "rows error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countUsersQuery)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+				mock.ExpectQuery(regexp.QuoteMeta(coverageQuery)).WillReturnRows(
+					sqlmock.NewRows(coverageColumns).AddRow(1, "terms", "1", "Terms", time.Now(), 1).RowError(0, sql.ErrConnDone),
+				)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			got, err := CoverageReport(db)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+)
+
+const (
+	findAttemptsQuery = `SELECT failures, last_failure_at, locked_until FROM login_attempts WHERE key = $1 AND expires_at > $2`
+
+	// Incrementing also removes the attempts that have expired,
+	// so that the table does not grow without bounds.
+	incrementAttemptsQuery = `WITH expired AS (DELETE FROM login_attempts WHERE expires_at <= $2 AND key <> $1) INSERT INTO login_attempts (key, failures, last_failure_at, expires_at) VALUES ($1, 1, $2, $3) ON CONFLICT (key) DO UPDATE SET failures = CASE WHEN login_attempts.last_failure_at < $4 THEN 1 ELSE login_attempts.failures + 1 END, locked_until = CASE WHEN login_attempts.last_failure_at < $4 THEN NULL ELSE login_attempts.locked_until END, last_failure_at = EXCLUDED.last_failure_at, expires_at = GREATEST(login_attempts.expires_at, EXCLUDED.expires_at) RETURNING failures, last_failure_at, locked_until`
+
+	lockAttemptsQuery = `UPDATE login_attempts SET locked_until = $2, expires_at = GREATEST(expires_at, $2) WHERE key = $1 AND (locked_until IS NULL OR locked_until <= $3)`
+
+	deleteAttemptsQuery = `DELETE FROM login_attempts WHERE key = $1`
+)
+
+type DatabaseStore struct {
+	db database.DatabaseInterface
+}
+
+func NewDatabaseStore(db database.DatabaseInterface) *DatabaseStore {
+	return &DatabaseStore{db: db}
+}
+
+func (s *DatabaseStore) Get(_ context.Context, key string) (*Attempts, error) {
+	attempts, err := scanAttempts(s.db.QueryRow(findAttemptsQuery, key, timeNow()))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return attempts, err
+}
+
+func (s *DatabaseStore) Increment(_ context.Context, key string, window time.Duration) (*Attempts, error) {
+	now := timeNow()
+
+	return scanAttempts(s.db.QueryRow(incrementAttemptsQuery, key, now, now.Add(window), now.Add(-window)))
+}
+
+func (s *DatabaseStore) Lock(_ context.Context, key string, until time.Time) (bool, error) {
+	result, err := s.db.Exec(lockAttemptsQuery, key, until, timeNow())
+
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+func (s *DatabaseStore) Delete(_ context.Context, key string) error {
+	_, err := s.db.Exec(deleteAttemptsQuery, key)
+
+	return err
+}
+
+func scanAttempts(row *sql.Row) (*Attempts, error) {
+	attempts := &Attempts{}
+	var lockedUntil sql.NullTime
+
+	err := row.Scan(&attempts.Failures, &attempts.LastFailure, &lockedUntil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if lockedUntil.Valid {
+		attempts.LockedUntil = lockedUntil.Time
+	}
+
+	return attempts, nil
+}
@@ -0,0 +1,163 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseStoreGet(t *testing.T) {
+	now := freezeTime(t)
+	ctx := context.Background()
+	columns := []string{"failures", "last_failure_at", "locked_until"}
+
+	tests := []struct {
+		name    string
+		rows    *sqlmock.Rows
+		err     error
+		want    *Attempts
+		wantErr bool
+	}{
+		{
+			name: "success",
+			rows: sqlmock.NewRows(columns).AddRow(3, *now, nil),
+			want: &Attempts{Failures: 3, LastFailure: *now},
+		},
+		{
+			name: "locked",
+			rows: sqlmock.NewRows(columns).AddRow(10, *now, now.Add(time.Hour)),
+			want: &Attempts{Failures: 10, LastFailure: *now, LockedUntil: now.Add(time.Hour)},
+		},
+		{
+			name: "not found",
+			err:  sql.ErrNoRows,
+		},
+		{
+			name:    "database error",
+			err:     sql.ErrConnDone,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			query := mock.ExpectQuery(regexp.QuoteMeta(findAttemptsQuery)).WithArgs("key", *now)
+
+			if tt.err != nil {
+				query.WillReturnError(tt.err)
+			} else {
+				query.WillReturnRows(tt.rows)
+			}
+
+			attempts, err := NewDatabaseStore(db).Get(ctx, "key")
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, attempts)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDatabaseStoreIncrement(t *testing.T) {
+	now := freezeTime(t)
+	ctx := context.Background()
+	columns := []string{"failures", "last_failure_at", "locked_until"}
+
+	db, mock, _ := sqlmock.New()
+	defer func() { _ = db.Close() }()
+
+	store := NewDatabaseStore(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(incrementAttemptsQuery)).
+		WithArgs("key", *now, now.Add(time.Hour), now.Add(-time.Hour)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(4, *now, now.Add(time.Hour)))
+	mock.ExpectQuery(regexp.QuoteMeta(incrementAttemptsQuery)).
+		WithArgs("key", *now, now.Add(time.Hour), now.Add(-time.Hour)).
+		WillReturnError(sql.ErrConnDone)
+
+	attempts, err := store.Increment(ctx, "key", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, &Attempts{Failures: 4, LastFailure: *now, LockedUntil: now.Add(time.Hour)}, attempts)
+
+	_, err = store.Increment(ctx, "key", time.Hour)
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDatabaseStoreLock(t *testing.T) {
+	now := freezeTime(t)
+	ctx := context.Background()
+	until := now.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		result  sql.Result
+		err     error
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:   "locked",
+			result: sqlmock.NewResult(0, 1),
+			want:   true,
+		},
+		{
+			name:   "already locked",
+			result: sqlmock.NewResult(0, 0),
+		},
+		{
+			name:    "rows affected error",
+			result:  sqlmock.NewErrorResult(sql.ErrConnDone),
+			wantErr: true,
+		},
+		{
+			name:    "database error",
+			err:     sql.ErrConnDone,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			exec := mock.ExpectExec(regexp.QuoteMeta(lockAttemptsQuery)).WithArgs("key", until, *now)
+
+			if tt.err != nil {
+				exec.WillReturnError(tt.err)
+			} else {
+				exec.WillReturnResult(tt.result)
+			}
+
+			locked, err := NewDatabaseStore(db).Lock(ctx, "key", until)
+
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, locked)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDatabaseStoreDelete(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec(regexp.QuoteMeta(deleteAttemptsQuery)).WithArgs("key").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, NewDatabaseStore(db).Delete(context.Background(), "key"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,169 @@
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	accountKeyPrefix = "account:"
+	ipKeyPrefix      = "ip:"
+)
+
+type Attempts struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// Increment and Lock must be atomic, since concurrent logins for the same
+// key would otherwise overwrite each other's failures. Increment starts
+// over when the last failure is older than the window, and Lock reports
+// whether the key was not already locked.
+type Store interface {
+	Get(ctx context.Context, key string) (*Attempts, error)
+	Increment(ctx context.Context, key string, window time.Duration) (*Attempts, error)
+	Lock(ctx context.Context, key string, until time.Time) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type Policy struct {
+	Threshold int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	LockAfter    int
+	LockDuration time.Duration
+
+	Window time.Duration
+}
+
+var (
+	AccountPolicy = Policy{
+		Threshold:    3,
+		BaseDelay:    2 * time.Second,
+		MaxDelay:     5 * time.Minute,
+		LockAfter:    10,
+		LockDuration: 30 * time.Minute,
+		Window:       time.Hour,
+	}
+
+	IPPolicy = Policy{
+		Threshold: 10,
+		BaseDelay: time.Second,
+		MaxDelay:  15 * time.Minute,
+		Window:    time.Hour,
+	}
+)
+
+var timeNow = time.Now
+
+type Status struct {
+	RetryAfter time.Duration
+	Locked     bool
+	JustLocked bool
+}
+
+func (s Status) Blocked() bool {
+	return s.RetryAfter > 0
+}
+
+type Limiter struct {
+	store  Store
+	policy Policy
+}
+
+func New(store Store, policy Policy) *Limiter {
+	return &Limiter{store: store, policy: policy}
+}
+
+func AccountKey(email string) string {
+	return accountKeyPrefix + strings.ToLower(strings.TrimSpace(email))
+}
+
+func IPKey(ip string) string {
+	return ipKeyPrefix + ip
+}
+
+func (l *Limiter) Check(ctx context.Context, key string) (Status, error) {
+	attempts, err := l.store.Get(ctx, key)
+
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+
+	if attempts == nil {
+		return Status{}, nil
+	}
+
+	return l.status(attempts, timeNow()), nil
+}
+
+func (l *Limiter) Fail(ctx context.Context, key string) (Status, error) {
+	attempts, err := l.store.Increment(ctx, key, l.policy.Window)
+
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to save login attempts: %w", err)
+	}
+
+	now := timeNow()
+	justLocked := false
+
+	if l.policy.LockAfter > 0 && attempts.Failures >= l.policy.LockAfter && !attempts.LockedUntil.After(now) {
+		attempts.LockedUntil = now.Add(l.policy.LockDuration)
+		justLocked, err = l.store.Lock(ctx, key, attempts.LockedUntil)
+
+		if err != nil {
+			return Status{}, fmt.Errorf("failed to lock login attempts: %w", err)
+		}
+	}
+
+	status := l.status(attempts, now)
+	status.JustLocked = justLocked
+
+	return status, nil
+}
+
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	err := l.store.Delete(ctx, key)
+
+	if err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+
+	return nil
+}
+
+func (l *Limiter) status(attempts *Attempts, now time.Time) Status {
+	if attempts.LockedUntil.After(now) {
+		return Status{RetryAfter: attempts.LockedUntil.Sub(now), Locked: true}
+	}
+
+	if attempts.Failures < l.policy.Threshold {
+		return Status{}
+	}
+
+	retryAfter := attempts.LastFailure.Add(l.delay(attempts.Failures)).Sub(now)
+
+	if retryAfter <= 0 {
+		return Status{}
+	}
+
+	return Status{RetryAfter: retryAfter}
+}
+
+func (l *Limiter) delay(failures int) time.Duration {
+	delay := l.policy.BaseDelay
+
+	for range failures - l.policy.Threshold {
+		delay *= 2
+
+		if delay >= l.policy.MaxDelay {
+			return l.policy.MaxDelay
+		}
+	}
+
+	return min(delay, l.policy.MaxDelay)
+}
@@ -0,0 +1,227 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errStore = errors.New("store error")
+
+type memoryStore struct {
+	attempts map[string]Attempts
+	windows  map[string]time.Duration
+	err      error
+	lockErr  error
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{attempts: map[string]Attempts{}, windows: map[string]time.Duration{}}
+}
+
+func (s *memoryStore) Get(_ context.Context, key string) (*Attempts, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	attempts, ok := s.attempts[key]
+
+	if !ok {
+		return nil, nil
+	}
+
+	return &attempts, nil
+}
+
+func (s *memoryStore) Increment(_ context.Context, key string, window time.Duration) (*Attempts, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	now := timeNow()
+	attempts := s.attempts[key]
+
+	if now.Sub(attempts.LastFailure) > window {
+		attempts = Attempts{}
+	}
+
+	attempts.Failures++
+	attempts.LastFailure = now
+
+	s.attempts[key] = attempts
+	s.windows[key] = window
+
+	return &attempts, nil
+}
+
+func (s *memoryStore) Lock(_ context.Context, key string, until time.Time) (bool, error) {
+	if s.lockErr != nil {
+		return false, s.lockErr
+	}
+
+	attempts := s.attempts[key]
+
+	if attempts.LockedUntil.After(timeNow()) {
+		return false, nil
+	}
+
+	attempts.LockedUntil = until
+	s.attempts[key] = attempts
+
+	return true, nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, key string) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	delete(s.attempts, key)
+
+	return nil
+}
+
+var testPolicy = Policy{
+	Threshold:    2,
+	BaseDelay:    time.Second,
+	MaxDelay:     5 * time.Second,
+	LockAfter:    6,
+	LockDuration: 2 * time.Hour,
+	Window:       time.Hour,
+}
+
+func freezeTime(t *testing.T) *time.Time {
+	now := time.Unix(1000000, 0)
+	timeNowOrig := timeNow
+
+	t.Cleanup(func() { timeNow = timeNowOrig })
+	timeNow = func() time.Time { return now }
+
+	return &now
+}
+
+func TestKeys(t *testing.T) {
+	assert.Equal(t, "account:user@example.com", AccountKey(" User@Example.com "))
+	assert.Equal(t, "ip:127.0.0.1", IPKey("127.0.0.1"))
+}
+
+func TestLimiterBackoff(t *testing.T) {
+	now := freezeTime(t)
+	store := newMemoryStore()
+	limiter := New(store, testPolicy)
+	ctx := context.Background()
+
+	status, err := limiter.Check(ctx, "key")
+	assert.NoError(t, err)
+	assert.False(t, status.Blocked())
+
+	// Failures below the threshold do not cause a delay.
+	status, err = limiter.Fail(ctx, "key")
+	assert.NoError(t, err)
+	assert.False(t, status.Blocked())
+
+	wantDelays := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+
+	for _, want := range wantDelays {
+		status, err = limiter.Fail(ctx, "key")
+		assert.NoError(t, err)
+		assert.Equal(t, want, status.RetryAfter)
+		assert.False(t, status.Locked)
+
+		*now = now.Add(want)
+
+		status, err = limiter.Check(ctx, "key")
+		assert.NoError(t, err)
+		assert.False(t, status.Blocked())
+	}
+
+	assert.Equal(t, time.Hour, store.windows["key"])
+}
+
+func TestLimiterLock(t *testing.T) {
+	now := freezeTime(t)
+	store := newMemoryStore()
+	limiter := New(store, testPolicy)
+	ctx := context.Background()
+
+	store.attempts["key"] = Attempts{Failures: 5, LastFailure: *now}
+
+	status, err := limiter.Fail(ctx, "key")
+	assert.NoError(t, err)
+	assert.True(t, status.Locked)
+	assert.True(t, status.JustLocked)
+	assert.Equal(t, 2*time.Hour, status.RetryAfter)
+	assert.Equal(t, now.Add(2*time.Hour), store.attempts["key"].LockedUntil)
+
+	*now = now.Add(time.Hour)
+
+	status, err = limiter.Check(ctx, "key")
+	assert.NoError(t, err)
+	assert.True(t, status.Locked)
+	assert.Equal(t, time.Hour, status.RetryAfter)
+
+	// A failure while locked does not extend the lock.
+	status, err = limiter.Fail(ctx, "key")
+	assert.NoError(t, err)
+	assert.True(t, status.Locked)
+	assert.False(t, status.JustLocked)
+
+	assert.NoError(t, limiter.Reset(ctx, "key"))
+
+	status, err = limiter.Check(ctx, "key")
+	assert.NoError(t, err)
+	assert.False(t, status.Blocked())
+}
+
+func TestLimiterWindow(t *testing.T) {
+	now := freezeTime(t)
+	store := newMemoryStore()
+	limiter := New(store, testPolicy)
+
+	store.attempts["key"] = Attempts{Failures: 5, LastFailure: now.Add(-2 * time.Hour)}
+
+	status, err := limiter.Fail(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.False(t, status.Blocked())
+	assert.Equal(t, 1, store.attempts["key"].Failures)
+}
+
+func TestLimiterWithoutLocking(t *testing.T) {
+	now := freezeTime(t)
+	store := newMemoryStore()
+	limiter := New(store, Policy{Threshold: 1, BaseDelay: time.Second, MaxDelay: time.Minute, Window: time.Hour})
+
+	store.attempts["key"] = Attempts{Failures: 100, LastFailure: *now}
+
+	status, err := limiter.Fail(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.False(t, status.Locked)
+	assert.Equal(t, time.Minute, status.RetryAfter)
+}
+
+func TestLimiterErrors(t *testing.T) {
+	ctx := context.Background()
+
+	store := newMemoryStore()
+	store.err = errStore
+	limiter := New(store, testPolicy)
+
+	_, err := limiter.Check(ctx, "key")
+	assert.ErrorIs(t, err, errStore)
+
+	_, err = limiter.Fail(ctx, "key")
+	assert.ErrorIs(t, err, errStore)
+
+	assert.ErrorIs(t, limiter.Reset(ctx, "key"), errStore)
+
+	store = newMemoryStore()
+	store.lockErr = errStore
+	store.attempts["key"] = Attempts{Failures: 5, LastFailure: timeNow()}
+	limiter = New(store, testPolicy)
+
+	_, err = limiter.Fail(ctx, "key")
+	assert.ErrorIs(t, err, errStore)
+}
@@ -0,0 +1,132 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/redis"
+	redisClient "github.com/redis/go-redis/v9"
+)
+
+const (
+	redisFailuresPrefix    = "login_failures:"
+	redisLastFailurePrefix = "login_last_failure:"
+	redisLockedPrefix      = "login_locked:"
+)
+
+type RedisStore struct {
+	redis redis.RedisInterface
+}
+
+func NewRedisStore(redis redis.RedisInterface) *RedisStore {
+	return &RedisStore{redis: redis}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (*Attempts, error) {
+	failures, err := s.getInt(ctx, redisFailuresPrefix+key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lastFailure, err := s.getInt(ctx, redisLastFailurePrefix+key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lockedUntil, err := s.getInt(ctx, redisLockedPrefix+key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if failures == 0 && lockedUntil == 0 {
+		return nil, nil
+	}
+
+	return newRedisAttempts(failures, lastFailure, lockedUntil), nil
+}
+
+// Increment relies on the failures expiring a window after the last one,
+// so that they start over without comparing the time of the last failure.
+func (s *RedisStore) Increment(ctx context.Context, key string, window time.Duration) (*Attempts, error) {
+	cmd, err := s.redis.Incr(ctx, redisFailuresPrefix+key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.redis.Expire(ctx, redisFailuresPrefix+key, window)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := timeNow().UnixMilli()
+	_, err = s.redis.Set(ctx, redisLastFailurePrefix+key, now, window)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lockedUntil, err := s.getInt(ctx, redisLockedPrefix+key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newRedisAttempts(cmd.Val(), now, lockedUntil), nil
+}
+
+func (s *RedisStore) Lock(ctx context.Context, key string, until time.Time) (bool, error) {
+	cmd, err := s.redis.SetNX(ctx, redisLockedPrefix+key, until.UnixMilli(), until.Sub(timeNow()))
+
+	if err != nil {
+		return false, err
+	}
+
+	return cmd.Val(), nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	_, err := s.redis.Del(ctx, redisFailuresPrefix+key, redisLastFailurePrefix+key, redisLockedPrefix+key)
+
+	return err
+}
+
+func (s *RedisStore) getInt(ctx context.Context, key string) (int64, error) {
+	cmd, err := s.redis.Get(ctx, key)
+
+	if err != nil {
+		if errors.Is(err, redisClient.Nil) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	value, err := strconv.ParseInt(cmd.Val(), 10, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse login attempts: %w", err)
+	}
+
+	return value, nil
+}
+
+func newRedisAttempts(failures int64, lastFailure int64, lockedUntil int64) *Attempts {
+	attempts := &Attempts{
+		Failures:    int(failures),
+		LastFailure: time.UnixMilli(lastFailure),
+	}
+
+	if lockedUntil > 0 {
+		attempts.LockedUntil = time.UnixMilli(lockedUntil)
+	}
+
+	return attempts
+}
@@ -0,0 +1,222 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/redis"
+	redisClient "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockRedis struct {
+	mock.Mock
+	redis.RedisInterface
+}
+
+func (m *mockRedis) Get(ctx context.Context, key string) (*redisClient.StringCmd, error) {
+	args := m.Called(ctx, key)
+	cmd := redisClient.NewStringCmd(ctx)
+	cmd.SetVal(args.String(0))
+
+	return cmd, args.Error(1)
+}
+
+func (m *mockRedis) Set(ctx context.Context, key string, value any, expiration time.Duration) (*redisClient.StatusCmd, error) {
+	args := m.Called(ctx, key, value, expiration)
+
+	return redisClient.NewStatusCmd(ctx), args.Error(0)
+}
+
+func (m *mockRedis) Incr(ctx context.Context, key string) (*redisClient.IntCmd, error) {
+	args := m.Called(ctx, key)
+	cmd := redisClient.NewIntCmd(ctx)
+	cmd.SetVal(int64(args.Int(0)))
+
+	return cmd, args.Error(1)
+}
+
+func (m *mockRedis) Expire(ctx context.Context, key string, expiration time.Duration) (*redisClient.BoolCmd, error) {
+	args := m.Called(ctx, key, expiration)
+
+	return redisClient.NewBoolCmd(ctx), args.Error(0)
+}
+
+func (m *mockRedis) SetNX(ctx context.Context, key string, value any, expiration time.Duration) (*redisClient.BoolCmd, error) {
+	args := m.Called(ctx, key, value, expiration)
+	cmd := redisClient.NewBoolCmd(ctx)
+	cmd.SetVal(args.Bool(0))
+
+	return cmd, args.Error(1)
+}
+
+func (m *mockRedis) Del(ctx context.Context, keys ...string) (*redisClient.IntCmd, error) {
+	args := m.Called(ctx, keys)
+
+	return redisClient.NewIntCmd(ctx), args.Error(0)
+}
+
+func TestRedisStoreGet(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		failures    string
+		lastFailure string
+		lockedUntil string
+		err         error
+		want        *Attempts
+		wantErr     bool
+	}{
+		{
+			name:        "success",
+			failures:    "3",
+			lastFailure: "1000",
+			want:        &Attempts{Failures: 3, LastFailure: time.UnixMilli(1000)},
+		},
+		{
+			name:        "locked",
+			failures:    "10",
+			lastFailure: "1000",
+			lockedUntil: "5000",
+			want:        &Attempts{Failures: 10, LastFailure: time.UnixMilli(1000), LockedUntil: time.UnixMilli(5000)},
+		},
+		{
+			name: "not found",
+		},
+		{
+			name:    "redis error",
+			err:     errStore,
+			wantErr: true,
+		},
+		{
+			name:     "invalid value",
+			failures: "bogus",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockRedis{}
+			mockGet(ctx, client, "login_failures:key", tt.failures, tt.err)
+			mockGet(ctx, client, "login_last_failure:key", tt.lastFailure, nil)
+			mockGet(ctx, client, "login_locked:key", tt.lockedUntil, nil)
+
+			attempts, err := NewRedisStore(client).Get(ctx, "key")
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, attempts)
+		})
+	}
+}
+
+func TestRedisStoreIncrement(t *testing.T) {
+	now := freezeTime(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		lockedUntil string
+		incrErr     error
+		expireErr   error
+		setErr      error
+		want        *Attempts
+		wantErr     bool
+	}{
+		{
+			name: "success",
+			want: &Attempts{Failures: 4, LastFailure: time.UnixMilli(now.UnixMilli())},
+		},
+		{
+			name:        "locked",
+			lockedUntil: "5000",
+			want:        &Attempts{Failures: 4, LastFailure: time.UnixMilli(now.UnixMilli()), LockedUntil: time.UnixMilli(5000)},
+		},
+		{
+			name:    "incr error",
+			incrErr: errStore,
+			wantErr: true,
+		},
+		{
+			name:      "expire error",
+			expireErr: errStore,
+			wantErr:   true,
+		},
+		{
+			name:    "set error",
+			setErr:  errStore,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockRedis{}
+			client.On("Incr", ctx, "login_failures:key").Return(4, tt.incrErr)
+			client.On("Expire", ctx, "login_failures:key", time.Hour).Return(tt.expireErr)
+			client.On("Set", ctx, "login_last_failure:key", now.UnixMilli(), time.Hour).Return(tt.setErr)
+			mockGet(ctx, client, "login_locked:key", tt.lockedUntil, nil)
+
+			attempts, err := NewRedisStore(client).Increment(ctx, "key", time.Hour)
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errStore)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, attempts)
+		})
+	}
+}
+
+func TestRedisStoreLock(t *testing.T) {
+	now := freezeTime(t)
+	ctx := context.Background()
+	until := now.Add(time.Hour)
+	client := &mockRedis{}
+	store := NewRedisStore(client)
+
+	client.On("SetNX", ctx, "login_locked:key", until.UnixMilli(), time.Hour).Return(true, nil).Once()
+	client.On("SetNX", ctx, "login_locked:key", until.UnixMilli(), time.Hour).Return(false, nil).Once()
+	client.On("SetNX", ctx, "login_locked:key", until.UnixMilli(), time.Hour).Return(false, errStore).Once()
+
+	locked, err := store.Lock(ctx, "key", until)
+	assert.NoError(t, err)
+	assert.True(t, locked)
+
+	locked, err = store.Lock(ctx, "key", until)
+	assert.NoError(t, err)
+	assert.False(t, locked)
+
+	_, err = store.Lock(ctx, "key", until)
+	assert.ErrorIs(t, err, errStore)
+
+	client.AssertExpectations(t)
+}
+
+func TestRedisStoreDelete(t *testing.T) {
+	ctx := context.Background()
+	client := &mockRedis{}
+
+	client.On("Del", ctx, []string{"login_failures:key", "login_last_failure:key", "login_locked:key"}).Return(nil)
+
+	assert.NoError(t, NewRedisStore(client).Delete(ctx, "key"))
+	client.AssertExpectations(t)
+}
+
+func mockGet(ctx context.Context, client *mockRedis, key string, value string, err error) {
+	if value == "" && err == nil {
+		err = redisClient.Nil
+	}
+
+	client.On("Get", ctx, key).Return(value, err).Maybe()
+}
@@ -0,0 +1,234 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrInvalidSiteHost   = errors.New("the site host must be an absolute URL")
+	ErrInvalidID         = errors.New("the provider ID may only contain lowercase letters, digits and dashes")
+	ErrReservedID        = errors.New("the provider ID is already used by another login route")
+	ErrMissingIssuer     = errors.New("the provider has no issuer")
+	ErrMissingClientID   = errors.New("the provider has no client ID")
+	ErrNonceMismatch     = errors.New("the ID token was not issued for this login")
+	ErrMissingIDToken    = errors.New("the token response does not contain an ID token")
+	ErrIssuerUnreachable = errors.New("the issuer could not be reached")
+)
+
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+var reservedIDs = []string{"two-factor", "passkey", "unlock", "magic-link"}
+
+var defaultScopes = []string{gooidc.ScopeOpenID, "email", "profile"}
+
+type Identity struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// The discovery document of the issuer is only fetched once it is needed,
+// so that an identity provider that is down does not keep the site from starting.
+type Provider struct {
+	ID   string
+	Name string
+
+	issuer       string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	redirectURL  string
+
+	mu       sync.Mutex
+	provider *gooidc.Provider
+}
+
+type Registry struct {
+	providers map[string]*Provider
+}
+
+func NewRegistry(siteHost string, providers map[string]config.OIDCProvider) (*Registry, error) {
+	if len(providers) == 0 {
+		return &Registry{}, nil
+	}
+
+	host, err := url.Parse(siteHost)
+
+	if err != nil || host.Scheme == "" || host.Host == "" {
+		return nil, ErrInvalidSiteHost
+	}
+
+	registry := &Registry{providers: make(map[string]*Provider, len(providers))}
+
+	for id, cfg := range providers {
+		provider, err := newProvider(host, id, cfg)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid OIDC provider %q: %w", id, err)
+		}
+
+		registry.providers[id] = provider
+	}
+
+	return registry, nil
+}
+
+func newProvider(host *url.URL, id string, cfg config.OIDCProvider) (*Provider, error) {
+	if !validID.MatchString(id) {
+		return nil, ErrInvalidID
+	}
+
+	if slices.Contains(reservedIDs, id) {
+		return nil, ErrReservedID
+	}
+
+	if cfg.Issuer == "" {
+		return nil, ErrMissingIssuer
+	}
+
+	if cfg.ClientID == "" {
+		return nil, ErrMissingClientID
+	}
+
+	name := cfg.Name
+
+	if name == "" {
+		name = id
+	}
+
+	scopes := cfg.Scopes
+
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+
+	if !slices.Contains(scopes, gooidc.ScopeOpenID) {
+		scopes = append([]string{gooidc.ScopeOpenID}, scopes...)
+	}
+
+	return &Provider{
+		ID:           id,
+		Name:         name,
+		issuer:       cfg.Issuer,
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		scopes:       scopes,
+		redirectURL:  host.JoinPath("login", id, "callback").String(),
+	}, nil
+}
+
+func (r *Registry) Get(id string) (*Provider, bool) {
+	provider, ok := r.providers[id]
+	return provider, ok
+}
+
+func (r *Registry) List() []*Provider {
+	providers := make([]*Provider, 0, len(r.providers))
+
+	for _, provider := range r.providers {
+		providers = append(providers, provider)
+	}
+
+	slices.SortFunc(providers, func(a, b *Provider) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return providers
+}
+
+func (p *Provider) RedirectURL() string {
+	return p.redirectURL
+}
+
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	oauthConfig, _, err := p.discover(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	return oauthConfig.AuthCodeURL(
+		state,
+		gooidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(verifier),
+	), nil
+}
+
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	oauthConfig, idTokenVerifier, err := p.discover(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange the authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+
+	if !ok || rawIDToken == "" {
+		return nil, ErrMissingIDToken
+	}
+
+	idToken, err := idTokenVerifier.Verify(ctx, rawIDToken)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify the ID token: %w", err)
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	identity := &Identity{}
+	err = idToken.Claims(identity)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the ID token claims: %w", err)
+	}
+
+	return identity, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, *gooidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.provider == nil {
+		provider, err := gooidc.NewProvider(ctx, p.issuer)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrIssuerUnreachable, err)
+		}
+
+		p.provider = provider
+	}
+
+	oauthConfig := &oauth2.Config{
+		ClientID:     p.clientID,
+		ClientSecret: p.clientSecret,
+		Endpoint:     p.provider.Endpoint(),
+		RedirectURL:  p.redirectURL,
+		Scopes:       p.scopes,
+	}
+
+	verifier := p.provider.Verifier(&gooidc.Config{ClientID: p.clientID})
+
+	return oauthConfig, verifier, nil
+}
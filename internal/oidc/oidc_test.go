@@ -0,0 +1,204 @@
+package oidc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+const testSiteHost = "http://localhost:4000"
+
+func setupProvider(t *testing.T) (*Provider, *oidctest.Server) {
+	issuer := oidctest.NewServer(t)
+
+	registry, err := NewRegistry(testSiteHost, map[string]config.OIDCProvider{
+		"company": {
+			Name:         "Company SSO",
+			Issuer:       issuer.URL,
+			ClientID:     oidctest.ClientID,
+			ClientSecret: oidctest.ClientSecret,
+		},
+	})
+	assert.NoError(t, err)
+
+	provider, ok := registry.Get("company")
+	assert.True(t, ok)
+
+	return provider, issuer
+}
+
+func TestNewRegistry(t *testing.T) {
+	valid := config.OIDCProvider{Issuer: "https://sso.example.com", ClientID: "client"}
+
+	tests := []struct {
+		name      string
+		siteHost  string
+		providers map[string]config.OIDCProvider
+		wantErr   error
+	}{
+		{"no providers", testSiteHost, nil, nil},
+		{"no providers without site host", "", nil, nil},
+		{"valid provider", testSiteHost, map[string]config.OIDCProvider{"company": valid}, nil},
+		{"invalid site host", "localhost", map[string]config.OIDCProvider{"company": valid}, ErrInvalidSiteHost},
+		{"invalid ID", testSiteHost, map[string]config.OIDCProvider{"Company SSO": valid}, ErrInvalidID},
+		{"reserved ID", testSiteHost, map[string]config.OIDCProvider{"passkey": valid}, ErrReservedID},
+		{"missing issuer", testSiteHost, map[string]config.OIDCProvider{"company": {ClientID: "client"}}, ErrMissingIssuer},
+		{"missing client ID", testSiteHost, map[string]config.OIDCProvider{"company": {Issuer: valid.Issuer}}, ErrMissingClientID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := NewRegistry(tt.siteHost, tt.providers)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, registry)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, registry.List(), len(tt.providers))
+		})
+	}
+}
+
+func TestNewRegistryDefaults(t *testing.T) {
+	registry, err := NewRegistry(testSiteHost+"/", map[string]config.OIDCProvider{
+		"company": {Issuer: "https://sso.example.com", ClientID: "client"},
+		"other":   {Name: "Another SSO", Issuer: "https://sso.example.com", ClientID: "client", Scopes: []string{"email"}},
+	})
+	assert.NoError(t, err)
+
+	company, ok := registry.Get("company")
+	assert.True(t, ok)
+	assert.Equal(t, "company", company.Name)
+	assert.Equal(t, defaultScopes, company.scopes)
+	assert.Equal(t, "http://localhost:4000/login/company/callback", company.RedirectURL())
+
+	other, ok := registry.Get("other")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"openid", "email"}, other.scopes)
+
+	_, ok = registry.Get("missing")
+	assert.False(t, ok)
+
+	providers := registry.List()
+	assert.Equal(t, "Another SSO", providers[0].Name)
+	assert.Equal(t, "company", providers[1].Name)
+}
+
+func TestProviderLogin(t *testing.T) {
+	provider, issuer := setupProvider(t)
+
+	issuer.SetClaims(oidctest.Claims{
+		Subject:           "user-1",
+		Email:             "sso@example.com",
+		EmailVerified:     true,
+		Name:              "SSO User",
+		PreferredUsername: "sso-user",
+	})
+
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce", verifier)
+	assert.NoError(t, err)
+
+	callback, err := issuer.Authorize(authURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "/login/company/callback", callback.Path)
+	assert.Equal(t, "state", callback.Query().Get("state"))
+
+	identity, err := provider.Exchange(context.Background(), callback.Query().Get("code"), verifier, "nonce")
+	assert.NoError(t, err)
+	assert.Equal(t, &Identity{
+		Subject:           "user-1",
+		Email:             "sso@example.com",
+		EmailVerified:     true,
+		Name:              "SSO User",
+		PreferredUsername: "sso-user",
+	}, identity)
+}
+
+func TestProviderExchangeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier func(verifier string) string
+		nonce    string
+		wantErr  error
+	}{
+		{
+			name:     "nonce mismatch",
+			verifier: func(verifier string) string { return verifier },
+			nonce:    "other",
+			wantErr:  ErrNonceMismatch,
+		},
+		{
+			name:     "wrong PKCE verifier",
+			verifier: func(string) string { return oauth2.GenerateVerifier() },
+			nonce:    "nonce",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, issuer := setupProvider(t)
+			verifier := oauth2.GenerateVerifier()
+
+			authURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce", verifier)
+			assert.NoError(t, err)
+
+			callback, err := issuer.Authorize(authURL)
+			assert.NoError(t, err)
+
+			identity, err := provider.Exchange(context.Background(), callback.Query().Get("code"), tt.verifier(verifier), tt.nonce)
+			assert.Error(t, err)
+			assert.Nil(t, identity)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProviderCodeIsSingleUse(t *testing.T) {
+	provider, issuer := setupProvider(t)
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce", verifier)
+	assert.NoError(t, err)
+
+	callback, err := issuer.Authorize(authURL)
+	assert.NoError(t, err)
+
+	code := callback.Query().Get("code")
+
+	_, err = provider.Exchange(context.Background(), code, verifier, "nonce")
+	assert.NoError(t, err)
+
+	_, err = provider.Exchange(context.Background(), code, verifier, "nonce")
+	assert.Error(t, err)
+}
+
+func TestProviderIssuerUnreachable(t *testing.T) {
+	issuer := oidctest.NewServer(t)
+	issuerURL := issuer.URL + "/missing"
+
+	registry, err := NewRegistry(testSiteHost, map[string]config.OIDCProvider{
+		"company": {Issuer: issuerURL, ClientID: oidctest.ClientID},
+	})
+	assert.NoError(t, err)
+
+	provider, _ := registry.Get("company")
+
+	_, err = provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	assert.ErrorIs(t, err, ErrIssuerUnreachable)
+
+	_, err = provider.Exchange(context.Background(), "code", "verifier", "nonce")
+	assert.ErrorIs(t, err, ErrIssuerUnreachable)
+}
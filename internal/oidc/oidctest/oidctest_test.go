@@ -0,0 +1,111 @@
+package oidctest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func authorizeURL(s *Server, params url.Values) string {
+	return s.URL + "/authorize?" + params.Encode()
+}
+
+func challenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func TestDiscovery(t *testing.T) {
+	s := NewServer(t)
+
+	resp, err := http.Get(s.URL + "/.well-known/openid-configuration")
+	assert.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	var discovery map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&discovery))
+	assert.Equal(t, s.URL, discovery["issuer"])
+	assert.Equal(t, s.URL+"/keys", discovery["jwks_uri"])
+}
+
+func TestAuthorize(t *testing.T) {
+	s := NewServer(t)
+
+	valid := url.Values{
+		"client_id":             {ClientID},
+		"redirect_uri":          {"http://localhost/callback"},
+		"response_type":         {"code"},
+		"state":                 {"state"},
+		"code_challenge":        {challenge("verifier")},
+		"code_challenge_method": {"S256"},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		callback, err := s.Authorize(authorizeURL(s, valid))
+		assert.NoError(t, err)
+		assert.Equal(t, "state", callback.Query().Get("state"))
+		assert.NotEmpty(t, callback.Query().Get("code"))
+	})
+
+	t.Run("without PKCE", func(t *testing.T) {
+		params := url.Values{}
+
+		for k, v := range valid {
+			params[k] = v
+		}
+
+		params.Del("code_challenge")
+
+		callback, err := s.Authorize(authorizeURL(s, params))
+		assert.NoError(t, err)
+		assert.Equal(t, "invalid_request", callback.Query().Get("error"))
+		assert.Empty(t, callback.Query().Get("code"))
+	})
+
+	t.Run("unknown client", func(t *testing.T) {
+		_, err := s.Authorize(authorizeURL(s, url.Values{"client_id": {"other"}}))
+		assert.Error(t, err)
+	})
+}
+
+func TestToken(t *testing.T) {
+	s := NewServer(t)
+
+	callback, err := s.Authorize(authorizeURL(s, url.Values{
+		"client_id":             {ClientID},
+		"redirect_uri":          {"http://localhost/callback"},
+		"response_type":         {"code"},
+		"code_challenge":        {challenge("verifier")},
+		"code_challenge_method": {"S256"},
+	}))
+	assert.NoError(t, err)
+
+	exchange := func(secret, verifier string) int {
+		form := url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {callback.Query().Get("code")},
+			"redirect_uri":  {"http://localhost/callback"},
+			"code_verifier": {verifier},
+			"client_id":     {ClientID},
+			"client_secret": {secret},
+		}
+
+		resp, err := http.Post(s.URL+"/token", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+		assert.NoError(t, err)
+		_ = resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, exchange("wrong", "verifier"))
+	assert.Equal(t, http.StatusBadRequest, exchange(ClientSecret, "wrong"))
+
+	// The failed attempt above used up the code.
+	assert.Equal(t, http.StatusBadRequest, exchange(ClientSecret, "verifier"))
+}
@@ -0,0 +1,205 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	Version = "3.1.0"
+
+	SecuritySchemeBearer = "bearerAuth"
+
+	contentTypeJSON = "application/json"
+)
+
+var pathParamPattern = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+type Route struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	Description string
+	Tags        []string
+
+	Auth   bool
+	Scopes []string
+
+	Request   any
+	Responses []Response
+}
+
+type Response struct {
+	Status      int
+	Description string
+	Body        any
+	ContentType string
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type PathItem map[string]*Operation
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Reply     `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Reply struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+func OpenAPIPath(path string) string {
+	return pathParamPattern.ReplaceAllString(path, "{$1}")
+}
+
+func Generate(info Info, servers []Server, routes []Route) (*Document, error) {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Servers: servers,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]*SecurityScheme{
+				SecuritySchemeBearer: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "Personal API token",
+				},
+			},
+		},
+	}
+
+	schemas := newSchemaBuilder(doc.Components.Schemas)
+
+	for _, route := range routes {
+		path := OpenAPIPath(route.Path)
+		method := strings.ToLower(route.Method)
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = PathItem{}
+		}
+
+		if _, exists := doc.Paths[path][method]; exists {
+			return nil, fmt.Errorf("duplicate route: %s %s", route.Method, route.Path)
+		}
+
+		doc.Paths[path][method] = newOperation(route, schemas)
+	}
+
+	return doc, nil
+}
+
+func newOperation(route Route, schemas *schemaBuilder) *Operation {
+	op := &Operation{
+		OperationID: route.OperationID,
+		Summary:     route.Summary,
+		Description: route.Description,
+		Tags:        route.Tags,
+		Responses:   map[string]*Reply{},
+	}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+
+	if route.Auth {
+		scopes := route.Scopes
+
+		if scopes == nil {
+			scopes = []string{}
+		}
+
+		op.Security = []map[string][]string{{SecuritySchemeBearer: scopes}}
+	}
+
+	if route.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{contentTypeJSON: {Schema: schemas.schemaOf(route.Request)}},
+		}
+	}
+
+	for _, response := range route.Responses {
+		reply := &Reply{Description: response.Description}
+
+		if reply.Description == "" {
+			reply.Description = http.StatusText(response.Status)
+		}
+
+		if response.Body != nil {
+			contentType := response.ContentType
+
+			if contentType == "" {
+				contentType = contentTypeJSON
+			}
+
+			reply.Content = map[string]MediaType{contentType: {Schema: schemas.schemaOf(response.Body)}}
+		}
+
+		op.Responses[strconv.Itoa(response.Status)] = reply
+	}
+
+	return op
+}
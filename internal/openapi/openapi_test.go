@@ -0,0 +1,105 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type testProblem struct {
+	Title string `json:"title"`
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "static", path: "/api/v1/user", expected: "/api/v1/user"},
+		{name: "parameter", path: "/api/v1/users/:id", expected: "/api/v1/users/{id}"},
+		{name: "multiple parameters", path: "/orgs/:org/members/:id", expected: "/orgs/{org}/members/{id}"},
+		{name: "wildcard", path: "/files/*path", expected: "/files/{path}"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, OpenAPIPath(tc.path))
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	doc, err := Generate(
+		Info{Title: "Test", Version: "1.0.0"},
+		[]Server{{URL: "https://example.com"}},
+		[]Route{
+			{
+				Method:      http.MethodGet,
+				Path:        "/users/:id",
+				OperationID: "getUser",
+				Auth:        true,
+				Scopes:      []string{"account:read"},
+				Responses: []Response{
+					{Status: http.StatusOK, Body: testUser{}},
+					{Status: http.StatusNotFound, Body: testProblem{}, ContentType: "application/problem+json"},
+				},
+			},
+			{
+				Method:    http.MethodPost,
+				Path:      "/users",
+				Request:   testUser{},
+				Responses: []Response{{Status: http.StatusCreated, Description: "Created user", Body: testUser{}}},
+			},
+			{
+				Method:    http.MethodDelete,
+				Path:      "/users/:id",
+				Auth:      true,
+				Responses: []Response{{Status: http.StatusNoContent}},
+			},
+		},
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Len(t, doc.Paths, 2)
+	assert.Contains(t, doc.Components.SecuritySchemes, SecuritySchemeBearer)
+
+	get := doc.Paths["/users/{id}"]["get"]
+	assert.Equal(t, "getUser", get.OperationID)
+	assert.Equal(t, []Parameter{{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}}, get.Parameters)
+	assert.Equal(t, []map[string][]string{{SecuritySchemeBearer: {"account:read"}}}, get.Security)
+	assert.Equal(t, "#/components/schemas/TestUser", get.Responses["200"].Content[contentTypeJSON].Schema.Ref)
+	assert.Contains(t, get.Responses["404"].Content, "application/problem+json")
+	assert.Equal(t, "OK", get.Responses["200"].Description)
+
+	post := doc.Paths["/users"]["post"]
+	assert.Nil(t, post.Security)
+	assert.True(t, post.RequestBody.Required)
+	assert.Equal(t, "Created user", post.Responses["201"].Description)
+
+	del := doc.Paths["/users/{id}"]["delete"]
+	assert.Equal(t, []map[string][]string{{SecuritySchemeBearer: {}}}, del.Security)
+	assert.Nil(t, del.Responses["204"].Content)
+
+	assert.Len(t, doc.Components.Schemas, 2)
+
+	_, err = json.Marshal(doc)
+	assert.NoError(t, err)
+}
+
+func TestGenerateDuplicateRoute(t *testing.T) {
+	route := Route{Method: http.MethodGet, Path: "/users/:id"}
+
+	doc, err := Generate(Info{}, nil, []Route{route, {Method: http.MethodGet, Path: "/users/:userID"}, route})
+
+	assert.Nil(t, doc)
+	assert.EqualError(t, err, "duplicate route: GET /users/:id")
+}
@@ -0,0 +1,166 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var timeType = reflect.TypeFor[time.Time]()
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+type schemaBuilder struct {
+	components map[string]*Schema
+}
+
+func newSchemaBuilder(components map[string]*Schema) *schemaBuilder {
+	return &schemaBuilder{components: components}
+}
+
+func (b *schemaBuilder) schemaOf(value any) *Schema {
+	return b.schemaOfType(reflect.TypeOf(value))
+}
+
+func (b *schemaBuilder) schemaOfType(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+
+	case t.Kind() == reflect.Pointer:
+		return nullable(b.schemaOfType(t.Elem()))
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: b.schemaOfType(t.Elem())}
+
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaOfType(t.Elem())}
+
+	case reflect.Struct:
+		return b.structSchema(t)
+	}
+
+	return &Schema{}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	name := schemaName(t)
+
+	if name == "" {
+		return b.objectSchema(t)
+	}
+
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+
+	if _, exists := b.components[name]; !exists {
+		// The name is taken before the properties are built,
+		// so that types which refer to themselves end.
+		b.components[name] = &Schema{}
+		*b.components[name] = *b.objectSchema(t)
+	}
+
+	return ref
+}
+
+func (b *schemaBuilder) objectSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitEmpty, ok := jsonName(field)
+
+		if !ok {
+			continue
+		}
+
+		schema.Properties[name] = b.schemaOfType(field.Type)
+
+		if !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+func jsonName(field reflect.StructField) (name string, omitEmpty bool, ok bool) {
+	tag := field.Tag.Get("json")
+
+	if tag == "-" {
+		return "", false, false
+	}
+
+	name, options, _ := strings.Cut(tag, ",")
+
+	if name == "" {
+		name = field.Name
+	}
+
+	for option := range strings.SplitSeq(options, ",") {
+		if option == "omitempty" || option == "omitzero" {
+			omitEmpty = true
+		}
+	}
+
+	return name, omitEmpty, true
+}
+
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+
+	if rest, found := strings.CutPrefix(name, "api"); found && rest != "" && unicode.IsUpper([]rune(rest)[0]) {
+		return rest
+	}
+
+	if name == "" {
+		return ""
+	}
+
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+
+	return string(runes)
+}
+
+func nullable(schema *Schema) *Schema {
+	switch typ := schema.Type.(type) {
+	case string:
+		schema.Type = []string{typ, "null"}
+		return schema
+
+	case nil:
+		return &Schema{OneOf: []*Schema{schema, {Type: "null"}}}
+	}
+
+	return schema
+}
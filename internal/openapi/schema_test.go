@@ -0,0 +1,116 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type apiTestAddress struct {
+	Street string `json:"street"`
+}
+
+type testNode struct {
+	Name     string      `json:"name"`
+	Children []*testNode `json:"children,omitempty"`
+}
+
+type testFields struct {
+	Name      string            `json:"name"`
+	Count     int               `json:"count"`
+	Ratio     float64           `json:"ratio"`
+	Active    bool              `json:"active"`
+	Tags      []string          `json:"tags,omitempty"`
+	Labels    map[string]string `json:"labels,omitzero"`
+	CreatedAt time.Time         `json:"created_at"`
+	DeletedAt *time.Time        `json:"deleted_at"`
+	Address   *apiTestAddress   `json:"address"`
+	Inline    struct {
+		Value string `json:"value"`
+	} `json:"inline"`
+	Untagged string
+	Ignored  string `json:"-"`
+	hidden   string
+}
+
+func TestSchemaOf(t *testing.T) {
+	components := map[string]*Schema{}
+	b := newSchemaBuilder(components)
+
+	ref := b.schemaOf(testFields{})
+	assert.Equal(t, "#/components/schemas/TestFields", ref.Ref)
+
+	schema := components["TestFields"]
+	assert.Equal(t, "object", schema.Type)
+	assert.Equal(t, []string{"name", "count", "ratio", "active", "created_at", "deleted_at", "address", "inline", "Untagged"}, schema.Required)
+	assert.NotContains(t, schema.Properties, "Ignored")
+	assert.NotContains(t, schema.Properties, "hidden")
+
+	tests := []struct {
+		name     string
+		expected *Schema
+	}{
+		{name: "name", expected: &Schema{Type: "string"}},
+		{name: "count", expected: &Schema{Type: "integer"}},
+		{name: "ratio", expected: &Schema{Type: "number"}},
+		{name: "active", expected: &Schema{Type: "boolean"}},
+		{name: "tags", expected: &Schema{Type: "array", Items: &Schema{Type: "string"}}},
+		{name: "labels", expected: &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}}},
+		{name: "created_at", expected: &Schema{Type: "string", Format: "date-time"}},
+		{name: "deleted_at", expected: &Schema{Type: []string{"string", "null"}, Format: "date-time"}},
+		{
+			name: "address",
+			expected: &Schema{OneOf: []*Schema{
+				{Ref: "#/components/schemas/TestAddress"},
+				{Type: "null"},
+			}},
+		},
+		{
+			name: "inline",
+			expected: &Schema{
+				Type:       "object",
+				Properties: map[string]*Schema{"value": {Type: "string"}},
+				Required:   []string{"value"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, schema.Properties[tc.name])
+		})
+	}
+
+	assert.Contains(t, components, "TestAddress")
+}
+
+func TestSchemaOfRecursiveType(t *testing.T) {
+	components := map[string]*Schema{}
+	b := newSchemaBuilder(components)
+
+	b.schemaOf(testNode{})
+
+	children := components["TestNode"].Properties["children"]
+	assert.Equal(t, "#/components/schemas/TestNode", children.Items.OneOf[0].Ref)
+}
+
+func TestSchemaName(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{name: "api prefix", value: apiTestAddress{}, expected: "TestAddress"},
+		{name: "lowercase", value: testNode{}, expected: "TestNode"},
+		{name: "exported", value: time.Time{}, expected: "Time"},
+		{name: "anonymous", value: struct{}{}, expected: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, schemaName(reflect.TypeOf(tc.value)))
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package organization
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+)
+
+const InviteTTL = 7 * 24 * time.Hour
+
+var ErrInviteNotFound = errors.New("the invitation could not be found or has expired")
+
+const (
+	inviteColumns                  = `i.id, i.organization_id, o.name, i.email, i.role, COALESCE(i.inviter_user_id, 0), i.created_at, i.expires_at`
+	findMemberByEmailQuery         = `SELECT EXISTS (SELECT 1 FROM organization_members m JOIN users u ON u.id = m.user_id WHERE m.organization_id = $1 AND LOWER(u.email) = $2)`
+	upsertInviteQuery              = `INSERT INTO organization_invites (organization_id, email, role, inviter_user_id, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (organization_id, email) DO UPDATE SET role = EXCLUDED.role, inviter_user_id = EXCLUDED.inviter_user_id, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at`
+	findInvitesByOrganizationQuery = `SELECT ` + inviteColumns + ` FROM organization_invites i JOIN organizations o ON o.id = i.organization_id WHERE i.organization_id = $1 AND i.expires_at > $2 ORDER BY i.created_at DESC, i.id DESC`
+	findInvitesByEmailQuery        = `SELECT ` + inviteColumns + ` FROM organization_invites i JOIN organizations o ON o.id = i.organization_id WHERE i.email = LOWER($1) AND i.expires_at > $2 ORDER BY o.name, i.id`
+	revokeInviteQuery              = `DELETE FROM organization_invites WHERE id = $1 AND organization_id = $2`
+	takeInviteQuery                = `DELETE FROM organization_invites WHERE id = $1 AND email = LOWER($2) AND expires_at > $3 RETURNING organization_id, role`
+	declineInviteQuery             = `DELETE FROM organization_invites WHERE id = $1 AND email = LOWER($2)`
+)
+
+var inviteTimeNow = time.Now
+
+type Invite struct {
+	ID               int
+	OrganizationID   int
+	OrganizationName string
+	Email            string
+	Role             string
+	InviterID        int
+	CreatedAt        time.Time
+	ExpiresAt        time.Time
+}
+
+func (org *Organization) CreateInvite(db database.DatabaseInterface, inviterID int, email string, role string) error {
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	var isMember bool
+
+	if err := db.QueryRow(findMemberByEmailQuery, org.id, email).Scan(&isMember); err != nil {
+		return fmt.Errorf("failed to check the members of the organization: %w", err)
+	}
+
+	if isMember {
+		return ErrAlreadyMember
+	}
+
+	now := inviteTimeNow()
+
+	_, err := db.Exec(
+		upsertInviteQuery,
+		org.id,
+		email,
+		role,
+		sql.NullInt64{Int64: int64(inviterID), Valid: inviterID > 0},
+		now,
+		now.Add(InviteTTL),
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to save invitation: %w", err)
+	}
+
+	return nil
+}
+
+func (org *Organization) GetInvites(db database.DatabaseInterface) ([]Invite, error) {
+	return findInvites(db, findInvitesByOrganizationQuery, org.id)
+}
+
+func (org *Organization) RevokeInvite(db database.DatabaseInterface, id int) error {
+	result, err := db.Exec(revokeInviteQuery, id, org.id)
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrInviteNotFound
+	}
+
+	return nil
+}
+
+func FindInvitesForEmail(db database.DatabaseInterface, email string) ([]Invite, error) {
+	return findInvites(db, findInvitesByEmailQuery, email)
+}
+
+func AcceptInvite(db database.DatabaseInterface, id int, userID int, email string) (int, error) {
+	tx, err := db.Begin()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	now := inviteTimeNow()
+
+	var organizationID int
+	var role string
+
+	err = tx.QueryRow(takeInviteQuery, id, email, now).Scan(&organizationID, &role)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInviteNotFound
+		}
+
+		return 0, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	// Someone that already is a member keeps the role they have.
+	if _, err = tx.Exec(insertMemberQuery, organizationID, userID, role, now); err != nil {
+		return 0, fmt.Errorf("failed to add member: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	return organizationID, nil
+}
+
+func DeclineInvite(db database.DatabaseInterface, id int, email string) error {
+	result, err := db.Exec(declineInviteQuery, id, email)
+
+	if err != nil {
+		return fmt.Errorf("failed to decline invitation: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrInviteNotFound
+	}
+
+	return nil
+}
+
+func findInvites(db database.DatabaseInterface, query string, arg any) ([]Invite, error) {
+	rows, err := db.Query(query, arg, inviteTimeNow())
+
+	if err != nil {
+		return nil, fmt.Errorf("error finding invitations: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	invites := []Invite{}
+
+	for rows.Next() {
+		invite := Invite{}
+
+		err = rows.Scan(
+			&invite.ID,
+			&invite.OrganizationID,
+			&invite.OrganizationName,
+			&invite.Email,
+			&invite.Role,
+			&invite.InviterID,
+			&invite.CreatedAt,
+			&invite.ExpiresAt,
+		)
+
+		if err != nil {
+			return nil, fmt.Errorf("error scanning invitation: %w", err)
+		}
+
+		invites = append(invites, invite)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error finding invitations: %w", err)
+	}
+
+	return invites, nil
+}
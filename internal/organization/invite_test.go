@@ -0,0 +1,391 @@
+package organization
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var inviteColumnNames = []string{"id", "organization_id", "name", "email", "role", "inviter_user_id", "created_at", "expires_at"}
+
+func freezeInviteTime(t *testing.T) time.Time {
+	now := time.Unix(testUpdatedAtUnix, 0)
+	inviteTimeNowOrig := inviteTimeNow
+
+	t.Cleanup(func() { inviteTimeNow = inviteTimeNowOrig })
+	inviteTimeNow = func() time.Time { return now }
+
+	return now
+}
+
+func TestCreateInvite(t *testing.T) {
+	now := freezeInviteTime(t)
+
+	tests := []struct {
+		name      string
+		email     string
+		role      string
+		setupMock func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name:  "success",
+			email: " Test@User.com ",
+			role:  RoleAdmin,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findMemberByEmailQuery)).
+					WithArgs(testOrganizationID, testEmail).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec(regexp.QuoteMeta(upsertInviteQuery)).
+					WithArgs(testOrganizationID, testEmail, RoleAdmin, sql.NullInt64{Int64: testUserID, Valid: true}, now, now.Add(InviteTTL)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{
+			name:      "invalid role",
+			email:     testEmail,
+			role:      "superuser",
+			setupMock: func(mock sqlmock.Sqlmock) {},
+			wantErr:   ErrInvalidRole,
+		},
+		{
+			name:  "already a member",
+			email: testEmail,
+			role:  RoleMember,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findMemberByEmailQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			wantErr: ErrAlreadyMember,
+		},
+		{
+			name:  "member check error",
+			email: testEmail,
+			role:  RoleMember,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findMemberByEmailQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name:  "insert error",
+			email: testEmail,
+			role:  RoleMember,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findMemberByEmailQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec(regexp.QuoteMeta(upsertInviteQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			org := setupOrganizationTests()
+			err := org.CreateInvite(db, testUserID, tt.email, tt.role)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestFindInvites(t *testing.T) {
+	now := freezeInviteTime(t)
+	invite := Invite{
+		ID:               3,
+		OrganizationID:   testOrganizationID,
+		OrganizationName: testOrganizationName,
+		Email:            testEmail,
+		Role:             RoleMember,
+		InviterID:        testUserID,
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(InviteTTL),
+	}
+
+	inviteRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(inviteColumnNames).AddRow(
+			invite.ID, invite.OrganizationID, invite.OrganizationName, invite.Email,
+			invite.Role, invite.InviterID, invite.CreatedAt, invite.ExpiresAt,
+		)
+	}
+
+	org := setupOrganizationTests()
+
+	tests := []struct {
+		name      string
+		find      func(db *sql.DB) ([]Invite, error)
+		setupMock func(mock sqlmock.Sqlmock)
+		want      []Invite
+		wantErr   bool
+	}{
+		{
+			name: "for an organization",
+			find: func(db *sql.DB) ([]Invite, error) { return org.GetInvites(db) },
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findInvitesByOrganizationQuery)).
+					WithArgs(testOrganizationID, now).
+					WillReturnRows(inviteRows())
+			},
+			want: []Invite{invite},
+		},
+		{
+			name: "for an email address",
+			find: func(db *sql.DB) ([]Invite, error) { return FindInvitesForEmail(db, testEmail) },
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findInvitesByEmailQuery)).
+					WithArgs(testEmail, now).
+					WillReturnRows(inviteRows())
+			},
+			want: []Invite{invite},
+		},
+		{
+			name: "none",
+			find: func(db *sql.DB) ([]Invite, error) { return FindInvitesForEmail(db, testEmail) },
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findInvitesByEmailQuery)).WillReturnRows(sqlmock.NewRows(inviteColumnNames))
+			},
+			want: []Invite{},
+		},
+		{
+			name: "query error",
+			find: func(db *sql.DB) ([]Invite, error) { return org.GetInvites(db) },
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findInvitesByOrganizationQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+		{
+			name: "scan error",
+			find: func(db *sql.DB) ([]Invite, error) { return org.GetInvites(db) },
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findInvitesByOrganizationQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			},
+			wantErr: true,
+		},
+		{
+			name: "rows error",
+			find: func(db *sql.DB) ([]Invite, error) { return org.GetInvites(db) },
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findInvitesByOrganizationQuery)).
+					WillReturnRows(inviteRows().RowError(0, sql.ErrConnDone))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			invites, err := tt.find(db)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, invites)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRevokeAndDeclineInvite(t *testing.T) {
+	org := setupOrganizationTests()
+
+	tests := []struct {
+		name    string
+		run     func(db *sql.DB) error
+		query   string
+		args    []driver.Value
+		result  sql.Result
+		err     error
+		wantErr error
+	}{
+		{
+			name:   "revoke",
+			run:    func(db *sql.DB) error { return org.RevokeInvite(db, 3) },
+			query:  revokeInviteQuery,
+			args:   []driver.Value{3, testOrganizationID},
+			result: sqlmock.NewResult(0, 1),
+		},
+		{
+			name:    "revoke an unknown invitation",
+			run:     func(db *sql.DB) error { return org.RevokeInvite(db, 3) },
+			query:   revokeInviteQuery,
+			args:    []driver.Value{3, testOrganizationID},
+			result:  sqlmock.NewResult(0, 0),
+			wantErr: ErrInviteNotFound,
+		},
+		{
+			name:    "revoke error",
+			run:     func(db *sql.DB) error { return org.RevokeInvite(db, 3) },
+			query:   revokeInviteQuery,
+			args:    []driver.Value{3, testOrganizationID},
+			err:     sql.ErrConnDone,
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name:   "decline",
+			run:    func(db *sql.DB) error { return DeclineInvite(db, 3, testEmail) },
+			query:  declineInviteQuery,
+			args:   []driver.Value{3, testEmail},
+			result: sqlmock.NewResult(0, 1),
+		},
+		{
+			name:    "decline someone else's invitation",
+			run:     func(db *sql.DB) error { return DeclineInvite(db, 3, testEmail) },
+			query:   declineInviteQuery,
+			args:    []driver.Value{3, testEmail},
+			result:  sqlmock.NewResult(0, 0),
+			wantErr: ErrInviteNotFound,
+		},
+		{
+			name:    "decline error",
+			run:     func(db *sql.DB) error { return DeclineInvite(db, 3, testEmail) },
+			query:   declineInviteQuery,
+			args:    []driver.Value{3, testEmail},
+			err:     sql.ErrConnDone,
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			exec := mock.ExpectExec(regexp.QuoteMeta(tt.query)).WithArgs(tt.args...)
+
+			if tt.err != nil {
+				exec.WillReturnError(tt.err)
+			} else {
+				exec.WillReturnResult(tt.result)
+			}
+
+			err := tt.run(db)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAcceptInvite(t *testing.T) {
+	now := freezeInviteTime(t)
+
+	tests := []struct {
+		name      string
+		setupMock func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "success",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(takeInviteQuery)).
+					WithArgs(3, testEmail, now).
+					WillReturnRows(sqlmock.NewRows([]string{"organization_id", "role"}).AddRow(testOrganizationID, RoleAdmin))
+				mock.ExpectExec(regexp.QuoteMeta(insertMemberQuery)).
+					WithArgs(testOrganizationID, testUserID, RoleAdmin, now).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "not found",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(takeInviteQuery)).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			wantErr: ErrInviteNotFound,
+		},
+		{
+			name: "begin error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin().WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name: "take error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(takeInviteQuery)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name: "insert error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(takeInviteQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"organization_id", "role"}).AddRow(testOrganizationID, RoleAdmin))
+				mock.ExpectExec(regexp.QuoteMeta(insertMemberQuery)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name: "commit error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(takeInviteQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"organization_id", "role"}).AddRow(testOrganizationID, RoleAdmin))
+				mock.ExpectExec(regexp.QuoteMeta(insertMemberQuery)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit().WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			organizationID, err := AcceptInvite(db, 3, testUserID, testEmail)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Zero(t, organizationID)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, testOrganizationID, organizationID)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package organization
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+)
+
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+var Roles = []string{RoleOwner, RoleAdmin, RoleMember}
+
+var (
+	ErrInvalidRole   = errors.New("the role is not valid")
+	ErrNotMember     = errors.New("the user is not a member of the organization")
+	ErrAlreadyMember = errors.New("this person is already a member of the organization")
+	ErrLastOwner     = errors.New("the organization needs at least one other owner")
+)
+
+const (
+	membershipColumns     = `o.id, o.name, o.slug, o.created_at, o.updated_at, m.role`
+	findMembershipsQuery  = `SELECT ` + membershipColumns + ` FROM organization_members m JOIN organizations o ON o.id = m.organization_id WHERE m.user_id = $1 ORDER BY o.name, o.id`
+	findMembershipQuery   = `SELECT ` + membershipColumns + ` FROM organization_members m JOIN organizations o ON o.id = m.organization_id WHERE m.organization_id = $1 AND m.user_id = $2`
+	findMembersQuery      = `SELECT u.id, u.username, u.email, m.role, m.created_at FROM organization_members m JOIN users u ON u.id = m.user_id WHERE m.organization_id = $1 AND u.deleted_at IS NULL ORDER BY u.username, u.id`
+	insertMemberQuery     = `INSERT INTO organization_members (organization_id, user_id, role, created_at) VALUES ($1, $2, $3, $4) ON CONFLICT (organization_id, user_id) DO NOTHING`
+	lockOrganizationQuery = `SELECT id FROM organizations WHERE id = $1 FOR UPDATE`
+	findMemberRoleQuery   = `SELECT role FROM organization_members WHERE organization_id = $1 AND user_id = $2`
+	countOwnersQuery      = `SELECT COUNT(*) FROM organization_members WHERE organization_id = $1 AND role = 'owner'`
+	updateMemberRoleQuery = `UPDATE organization_members SET role = $1 WHERE organization_id = $2 AND user_id = $3`
+	deleteMemberQuery     = `DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`
+)
+
+type Member struct {
+	UserID    int
+	Username  string
+	Email     string
+	Role      string
+	CreatedAt time.Time
+}
+
+type Membership struct {
+	Organization *Organization
+	Role         string
+}
+
+func ValidRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
+
+func (membership *Membership) IsOwner() bool {
+	return membership.Role == RoleOwner
+}
+
+func (membership *Membership) CanManageMembers() bool {
+	return membership.Role == RoleOwner || membership.Role == RoleAdmin
+}
+
+func (membership *Membership) CanAssignRole(role string) bool {
+	if !membership.CanManageMembers() {
+		return false
+	}
+
+	return membership.Role == RoleOwner || role != RoleOwner
+}
+
+func FindMemberships(db database.DatabaseInterface, userID int) ([]Membership, error) {
+	rows, err := db.Query(findMembershipsQuery, userID)
+
+	if err != nil {
+		return nil, fmt.Errorf("error finding organizations: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	memberships := []Membership{}
+
+	for rows.Next() {
+		membership, err := scanMembership(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("error scanning organization: %w", err)
+		}
+
+		memberships = append(memberships, *membership)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error finding organizations: %w", err)
+	}
+
+	return memberships, nil
+}
+
+func FindMembership(db database.DatabaseInterface, organizationID int, userID int) (*Membership, error) {
+	membership, err := scanMembership(db.QueryRow(findMembershipQuery, organizationID, userID))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotMember
+		}
+
+		return nil, fmt.Errorf("error finding organization: %w", err)
+	}
+
+	return membership, nil
+}
+
+func (org *Organization) GetMembers(db database.DatabaseInterface) ([]Member, error) {
+	rows, err := db.Query(findMembersQuery, org.id)
+
+	if err != nil {
+		return nil, fmt.Errorf("error finding members: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	members := []Member{}
+
+	for rows.Next() {
+		member := Member{}
+		err = rows.Scan(&member.UserID, &member.Username, &member.Email, &member.Role, &member.CreatedAt)
+
+		if err != nil {
+			return nil, fmt.Errorf("error scanning member: %w", err)
+		}
+
+		members = append(members, member)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error finding members: %w", err)
+	}
+
+	return members, nil
+}
+
+func (org *Organization) SetMemberRole(db database.DatabaseInterface, userID int, role string) error {
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	return org.changeMember(db, userID, role == RoleOwner, updateMemberRoleQuery, role, org.id, userID)
+}
+
+func (org *Organization) RemoveMember(db database.DatabaseInterface, userID int) error {
+	return org.changeMember(db, userID, false, deleteMemberQuery, org.id, userID)
+}
+
+// changeMember runs the query that changes a member, unless the member is the
+// last owner and would no longer be an owner afterwards. The organization is
+// locked meanwhile, so that two owners cannot demote each other at once.
+func (org *Organization) changeMember(db database.DatabaseInterface, userID int, staysOwner bool, query string, args ...any) error {
+	tx, err := db.Begin()
+
+	if err != nil {
+		return fmt.Errorf("failed to change member: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.Exec(lockOrganizationQuery, org.id); err != nil {
+		return fmt.Errorf("failed to lock organization: %w", err)
+	}
+
+	var role string
+
+	if err = tx.QueryRow(findMemberRoleQuery, org.id, userID).Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotMember
+		}
+
+		return fmt.Errorf("failed to find member: %w", err)
+	}
+
+	if role == RoleOwner && !staysOwner {
+		var owners int
+
+		if err = tx.QueryRow(countOwnersQuery, org.id).Scan(&owners); err != nil {
+			return fmt.Errorf("failed to count owners: %w", err)
+		}
+
+		if owners <= 1 {
+			return ErrLastOwner
+		}
+	}
+
+	if _, err = tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to change member: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to change member: %w", err)
+	}
+
+	return nil
+}
+
+type membershipScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMembership(row membershipScanner) (*Membership, error) {
+	org := &Organization{}
+	membership := &Membership{Organization: org}
+
+	err := row.Scan(
+		&org.id,
+		&org.name,
+		&org.slug,
+		&org.createdAt,
+		&org.updatedAt,
+		&membership.Role,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return membership, nil
+}
@@ -0,0 +1,463 @@
+package organization
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var membershipColumnNames = []string{"id", "name", "slug", "created_at", "updated_at", "role"}
+
+func membershipRows(roles ...string) *sqlmock.Rows {
+	org := setupOrganizationTests()
+	rows := sqlmock.NewRows(membershipColumnNames)
+
+	for i, role := range roles {
+		rows.AddRow(org.id+i, org.name, org.slug, org.createdAt, org.updatedAt, role)
+	}
+
+	return rows
+}
+
+func TestValidRole(t *testing.T) {
+	assert.True(t, ValidRole(RoleOwner))
+	assert.True(t, ValidRole(RoleAdmin))
+	assert.True(t, ValidRole(RoleMember))
+	assert.False(t, ValidRole(""))
+	assert.False(t, ValidRole("superuser"))
+}
+
+func TestMembershipPermissions(t *testing.T) {
+	tests := []struct {
+		role             string
+		isOwner          bool
+		canManage        bool
+		canAssignOwner   bool
+		canAssignAdmin   bool
+		canAssignMember  bool
+		canAssignUnknown bool
+	}{
+		{role: RoleOwner, isOwner: true, canManage: true, canAssignOwner: true, canAssignAdmin: true, canAssignMember: true, canAssignUnknown: true},
+		{role: RoleAdmin, canManage: true, canAssignAdmin: true, canAssignMember: true, canAssignUnknown: true},
+		{role: RoleMember},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			membership := &Membership{Role: tt.role}
+
+			assert.Equal(t, tt.isOwner, membership.IsOwner())
+			assert.Equal(t, tt.canManage, membership.CanManageMembers())
+			assert.Equal(t, tt.canAssignOwner, membership.CanAssignRole(RoleOwner))
+			assert.Equal(t, tt.canAssignAdmin, membership.CanAssignRole(RoleAdmin))
+			assert.Equal(t, tt.canAssignMember, membership.CanAssignRole(RoleMember))
+			assert.Equal(t, tt.canAssignUnknown, membership.CanAssignRole("unknown"))
+		})
+	}
+}
+
+func TestFindMemberships(t *testing.T) {
+	tests := []struct {
+		name      string
+		setupMock func(mock sqlmock.Sqlmock)
+		wantRoles []string
+		wantErr   bool
+	}{
+		{
+			name: "success",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findMembershipsQuery)).
+					WithArgs(testUserID).
+					WillReturnRows(membershipRows(RoleOwner, RoleMember))
+			},
+			wantRoles: []string{RoleOwner, RoleMember},
+		},
+		{
+			name: "none",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findMembershipsQuery)).WillReturnRows(membershipRows())
+			},
+			wantRoles: []string{},
+		},
+		{
+			name: "query error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findMembershipsQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+		{
+			name: "scan error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findMembershipsQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			},
+			wantErr: true,
+		},
+		{
+			name: "rows error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findMembershipsQuery)).
+					WillReturnRows(membershipRows(RoleOwner).RowError(0, sql.ErrConnDone))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			memberships, err := FindMemberships(db, testUserID)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+
+				roles := []string{}
+
+				for _, membership := range memberships {
+					roles = append(roles, membership.Role)
+				}
+
+				assert.Equal(t, tt.wantRoles, roles)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestFindMembership(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{name: "success"},
+		{name: "not a member", err: sql.ErrNoRows, wantErr: ErrNotMember},
+		{name: "database error", err: sql.ErrConnDone, wantErr: sql.ErrConnDone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			query := mock.ExpectQuery(regexp.QuoteMeta(findMembershipQuery)).WithArgs(testOrganizationID, testUserID)
+
+			if tt.err != nil {
+				query.WillReturnError(tt.err)
+			} else {
+				query.WillReturnRows(membershipRows(RoleAdmin))
+			}
+
+			membership, err := FindMembership(db, testOrganizationID, testUserID)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, membership)
+			} else {
+				expected := setupOrganizationTests()
+
+				assert.NoError(t, err)
+				assert.Equal(t, &Membership{Organization: &expected, Role: RoleAdmin}, membership)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetMembers(t *testing.T) {
+	joined := time.Unix(testCreatedAtUnix, 0)
+	columns := []string{"id", "username", "email", "role", "created_at"}
+
+	tests := []struct {
+		name      string
+		setupMock func(mock sqlmock.Sqlmock)
+		want      []Member
+		wantErr   bool
+	}{
+		{
+			name: "success",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findMembersQuery)).
+					WithArgs(testOrganizationID).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(testUserID, "user", testEmail, RoleOwner, joined))
+			},
+			want: []Member{{UserID: testUserID, Username: "user", Email: testEmail, Role: RoleOwner, CreatedAt: joined}},
+		},
+		{
+			name: "query error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findMembersQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+		{
+			name: "scan error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findMembersQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			},
+			wantErr: true,
+		},
+		{
+			name: "rows error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findMembersQuery)).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(testUserID, "user", testEmail, RoleOwner, joined).RowError(0, sql.ErrConnDone))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			org := setupOrganizationTests()
+			members, err := org.GetMembers(db)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, members)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func expectMemberRole(mock sqlmock.Sqlmock, role string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(lockOrganizationQuery)).
+		WithArgs(testOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(findMemberRoleQuery)).
+		WithArgs(testOrganizationID, testUserID).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(role))
+}
+
+func expectOwners(mock sqlmock.Sqlmock, owners int) {
+	mock.ExpectQuery(regexp.QuoteMeta(countOwnersQuery)).
+		WithArgs(testOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(owners))
+}
+
+func TestSetMemberRole(t *testing.T) {
+	tests := []struct {
+		name      string
+		role      string
+		setupMock func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "promote",
+			role: RoleAdmin,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberRole(mock, RoleMember)
+				mock.ExpectExec(regexp.QuoteMeta(updateMemberRoleQuery)).
+					WithArgs(RoleAdmin, testOrganizationID, testUserID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "demote an owner",
+			role: RoleAdmin,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberRole(mock, RoleOwner)
+				expectOwners(mock, 2)
+				mock.ExpectExec(regexp.QuoteMeta(updateMemberRoleQuery)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "demote the last owner",
+			role: RoleMember,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberRole(mock, RoleOwner)
+				expectOwners(mock, 1)
+				mock.ExpectRollback()
+			},
+			wantErr: ErrLastOwner,
+		},
+		{
+			name: "owner stays owner",
+			role: RoleOwner,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberRole(mock, RoleOwner)
+				mock.ExpectExec(regexp.QuoteMeta(updateMemberRoleQuery)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:      "invalid role",
+			role:      "superuser",
+			setupMock: func(mock sqlmock.Sqlmock) {},
+			wantErr:   ErrInvalidRole,
+		},
+		{
+			name: "not a member",
+			role: RoleAdmin,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(lockOrganizationQuery)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(findMemberRoleQuery)).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			wantErr: ErrNotMember,
+		},
+		{
+			name: "begin error",
+			role: RoleAdmin,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin().WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name: "lock error",
+			role: RoleAdmin,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(lockOrganizationQuery)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name: "find error",
+			role: RoleAdmin,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(lockOrganizationQuery)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(findMemberRoleQuery)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name: "count error",
+			role: RoleMember,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberRole(mock, RoleOwner)
+				mock.ExpectQuery(regexp.QuoteMeta(countOwnersQuery)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name: "update error",
+			role: RoleAdmin,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberRole(mock, RoleMember)
+				mock.ExpectExec(regexp.QuoteMeta(updateMemberRoleQuery)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name: "commit error",
+			role: RoleAdmin,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberRole(mock, RoleMember)
+				mock.ExpectExec(regexp.QuoteMeta(updateMemberRoleQuery)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit().WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			org := setupOrganizationTests()
+			err := org.SetMemberRole(db, testUserID, tt.role)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	tests := []struct {
+		name      string
+		setupMock func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "member",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberRole(mock, RoleMember)
+				mock.ExpectExec(regexp.QuoteMeta(deleteMemberQuery)).
+					WithArgs(testOrganizationID, testUserID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "one of the owners",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberRole(mock, RoleOwner)
+				expectOwners(mock, 3)
+				mock.ExpectExec(regexp.QuoteMeta(deleteMemberQuery)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "the last owner",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberRole(mock, RoleOwner)
+				expectOwners(mock, 1)
+				mock.ExpectRollback()
+			},
+			wantErr: ErrLastOwner,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			org := setupOrganizationTests()
+			err := org.RemoveMember(db, testUserID)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package organization
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/lib/pq"
+)
+
+const MaxNameLength = 64
+
+var (
+	ErrInvalidName          = errors.New("the name must contain at least one letter or number")
+	ErrNameTooLong          = fmt.Errorf("the name cannot be longer than %d characters", MaxNameLength)
+	ErrOrganizationExists   = errors.New("an organization with this name already exists")
+	ErrOrganizationNotFound = errors.New("the organization could not be found")
+)
+
+const (
+	pqUniqueViolation = "23505"
+
+	organizationColumns         = `id, name, slug, created_at, updated_at`
+	insertOrganizationQuery     = `INSERT INTO organizations (name, slug, created_at, updated_at) VALUES ($1, $2, $3, $3) RETURNING id, created_at, updated_at`
+	updateOrganizationQuery     = `UPDATE organizations SET name = $1, slug = $2, updated_at = $3 WHERE id = $4`
+	findOrganizationByIDQuery   = `SELECT ` + organizationColumns + ` FROM organizations WHERE id = $1`
+	findOrganizationBySlugQuery = `SELECT ` + organizationColumns + ` FROM organizations WHERE slug = $1`
+	deleteOrganizationQuery     = `DELETE FROM organizations WHERE id = $1`
+)
+
+var organizationTimeNow = time.Now
+
+type Organization struct {
+	id        int
+	name      string
+	slug      string
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+type OrganizationFields struct {
+	Id        int
+	Name      string
+	Slug      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (org *Organization) GetID() (id int) {
+	return org.id
+}
+
+func (org *Organization) GetName() (name string) {
+	return org.name
+}
+
+func (org *Organization) SetName(name string) {
+	org.name = strings.TrimSpace(name)
+	org.slug = Slugify(org.name)
+}
+
+func (org *Organization) GetSlug() (slug string) {
+	return org.slug
+}
+
+func (org *Organization) GetCreatedAt() (createdAt time.Time) {
+	return org.createdAt
+}
+
+func (org *Organization) GetUpdatedAt() (updatedAt time.Time) {
+	return org.updatedAt
+}
+
+func (org *Organization) Validate() error {
+	if org.slug == "" {
+		return ErrInvalidName
+	}
+
+	if utf8.RuneCountInString(org.name) > MaxNameLength {
+		return ErrNameTooLong
+	}
+
+	return nil
+}
+
+func (org *Organization) Save(db database.DatabaseInterface) error {
+	if org.id == 0 {
+		return ErrOrganizationNotFound
+	}
+
+	if err := org.Validate(); err != nil {
+		return err
+	}
+
+	now := organizationTimeNow()
+	_, err := db.Exec(updateOrganizationQuery, org.name, org.slug, now, org.id)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrOrganizationExists
+		}
+
+		return fmt.Errorf("failed to update organization: %w", err)
+	}
+
+	org.updatedAt = now
+
+	return nil
+}
+
+func (org *Organization) Delete(db database.DatabaseInterface) error {
+	_, err := db.Exec(deleteOrganizationQuery, org.id)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+
+	return nil
+}
+
+func (org *Organization) create(db database.DatabaseInterface, ownerID int) error {
+	tx, err := db.Begin()
+
+	if err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	now := organizationTimeNow()
+
+	err = tx.QueryRow(insertOrganizationQuery, org.name, org.slug, now).Scan(&org.id, &org.createdAt, &org.updatedAt)
+
+	if err != nil {
+		org.id = 0
+
+		if isUniqueViolation(err) {
+			return ErrOrganizationExists
+		}
+
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	_, err = tx.Exec(insertMemberQuery, org.id, ownerID, RoleOwner, now)
+
+	if err == nil {
+		err = tx.Commit()
+	}
+
+	if err != nil {
+		org.id = 0
+		return fmt.Errorf("failed to add the owner of the organization: %w", err)
+	}
+
+	return nil
+}
+
+func FindByID(db database.DatabaseInterface, id int) (*Organization, error) {
+	return findOrganization(db, findOrganizationByIDQuery, id)
+}
+
+func FindBySlug(db database.DatabaseInterface, slug string) (*Organization, error) {
+	return findOrganization(db, findOrganizationBySlugQuery, slug)
+}
+
+func findOrganization(db database.DatabaseInterface, query string, arg any) (*Organization, error) {
+	org := &Organization{}
+
+	err := db.QueryRow(query, arg).Scan(
+		&org.id,
+		&org.name,
+		&org.slug,
+		&org.createdAt,
+		&org.updatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrganizationNotFound
+		}
+
+		return nil, fmt.Errorf("error finding organization: %w", err)
+	}
+
+	return org, nil
+}
+
+func Slugify(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	return strings.Join(words, "-")
+}
+
+func NewOrganization(name string) *Organization {
+	org := &Organization{}
+	org.SetName(name)
+
+	return org
+}
+
+func New(fields OrganizationFields) *Organization {
+	return &Organization{
+		id:        fields.Id,
+		name:      fields.Name,
+		slug:      fields.Slug,
+		createdAt: fields.CreatedAt,
+		updatedAt: fields.UpdatedAt,
+	}
+}
+
+type OrganizationRepository interface {
+	FindBySlug(slug string) (*Organization, error)
+	CreateOrganization(org *Organization, ownerID int) error
+}
+
+type DbOrganizationRepository struct {
+	DB database.DatabaseInterface
+}
+
+func (r *DbOrganizationRepository) FindBySlug(slug string) (*Organization, error) {
+	return FindBySlug(r.DB, slug)
+}
+
+func (r *DbOrganizationRepository) CreateOrganization(org *Organization, ownerID int) error {
+	return org.create(r.DB, ownerID)
+}
+
+func CreateWithRepo(repo OrganizationRepository, ownerID int, name string) (*Organization, error) {
+	org := NewOrganization(name)
+
+	if err := org.Validate(); err != nil {
+		return nil, err
+	}
+
+	_, findErr := repo.FindBySlug(org.slug)
+
+	if findErr == nil {
+		return nil, ErrOrganizationExists
+	} else if !errors.Is(findErr, ErrOrganizationNotFound) {
+		return nil, fmt.Errorf("database error checking for an existing organization: %w", findErr)
+	}
+
+	if err := repo.CreateOrganization(org, ownerID); err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+func Create(db database.DatabaseInterface, ownerID int, name string) (*Organization, error) {
+	repo := &DbOrganizationRepository{DB: db}
+	return CreateWithRepo(repo, ownerID, name)
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation
+}
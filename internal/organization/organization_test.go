@@ -0,0 +1,406 @@
+package organization
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testOrganizationID   = 1
+	testOrganizationName = "Acme Inc."
+	testOrganizationSlug = "acme-inc"
+	testUserID           = 2
+	testEmail            = "test@user.com"
+	testCreatedAtUnix    = 100000
+	testUpdatedAtUnix    = 200000
+)
+
+var organizationColumnNames = []string{"id", "name", "slug", "created_at", "updated_at"}
+
+func setupOrganizationTests() Organization {
+	return Organization{
+		id:        testOrganizationID,
+		name:      testOrganizationName,
+		slug:      testOrganizationSlug,
+		createdAt: time.Unix(testCreatedAtUnix, 0),
+		updatedAt: time.Unix(testUpdatedAtUnix, 0),
+	}
+}
+
+func freezeOrganizationTime(t *testing.T) time.Time {
+	now := time.Unix(testUpdatedAtUnix, 0)
+	organizationTimeNowOrig := organizationTimeNow
+
+	t.Cleanup(func() { organizationTimeNow = organizationTimeNowOrig })
+	organizationTimeNow = func() time.Time { return now }
+
+	return now
+}
+
+func TestOrganizationGetters(t *testing.T) {
+	org := setupOrganizationTests()
+
+	assert.Equal(t, testOrganizationID, org.GetID())
+	assert.Equal(t, testOrganizationName, org.GetName())
+	assert.Equal(t, testOrganizationSlug, org.GetSlug())
+	assert.Equal(t, time.Unix(testCreatedAtUnix, 0), org.GetCreatedAt())
+	assert.Equal(t, time.Unix(testUpdatedAtUnix, 0), org.GetUpdatedAt())
+
+	org.SetName("  New Name ")
+
+	assert.Equal(t, "New Name", org.GetName())
+	assert.Equal(t, "new-name", org.GetSlug())
+}
+
+func TestNew(t *testing.T) {
+	org := New(OrganizationFields{
+		Id:        testOrganizationID,
+		Name:      testOrganizationName,
+		Slug:      testOrganizationSlug,
+		CreatedAt: time.Unix(testCreatedAtUnix, 0),
+		UpdatedAt: time.Unix(testUpdatedAtUnix, 0),
+	})
+
+	expected := setupOrganizationTests()
+	assert.Equal(t, &expected, org)
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "simple", input: "Acme", expected: "acme"},
+		{name: "punctuation", input: "Acme Inc.", expected: "acme-inc"},
+		{name: "repeated separators", input: "  R&D -- Team 2 ", expected: "r-d-team-2"},
+		{name: "unicode", input: "Café Zürich", expected: "café-zürich"},
+		{name: "only punctuation", input: "!!!", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Slugify(tt.input))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		orgName string
+		wantErr error
+	}{
+		{name: "valid", orgName: testOrganizationName},
+		{name: "empty", orgName: "   ", wantErr: ErrInvalidName},
+		{name: "only punctuation", orgName: "---", wantErr: ErrInvalidName},
+		{name: "too long", orgName: strings.Repeat("a", MaxNameLength+1), wantErr: ErrNameTooLong},
+		{name: "longest", orgName: strings.Repeat("ä", MaxNameLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantErr, NewOrganization(tt.orgName).Validate())
+		})
+	}
+}
+
+func TestSave(t *testing.T) {
+	now := freezeOrganizationTime(t)
+
+	tests := []struct {
+		name      string
+		org       Organization
+		setupMock func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "success",
+			org:  setupOrganizationTests(),
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(updateOrganizationQuery)).
+					WithArgs(testOrganizationName, testOrganizationSlug, now, testOrganizationID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:      "new organization",
+			org:       *NewOrganization(testOrganizationName),
+			setupMock: func(mock sqlmock.Sqlmock) {},
+			wantErr:   ErrOrganizationNotFound,
+		},
+		{
+			name:      "invalid name",
+			org:       Organization{id: testOrganizationID},
+			setupMock: func(mock sqlmock.Sqlmock) {},
+			wantErr:   ErrInvalidName,
+		},
+		{
+			name: "name taken",
+			org:  setupOrganizationTests(),
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(updateOrganizationQuery)).WillReturnError(&pq.Error{Code: pqUniqueViolation})
+			},
+			wantErr: ErrOrganizationExists,
+		},
+		{
+			name: "database error",
+			org:  setupOrganizationTests(),
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(updateOrganizationQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			err := tt.org.Save(db)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, now, tt.org.GetUpdatedAt())
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{name: "success"},
+		{name: "database error", err: sql.ErrConnDone, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			exec := mock.ExpectExec(regexp.QuoteMeta(deleteOrganizationQuery)).WithArgs(testOrganizationID)
+
+			if tt.err != nil {
+				exec.WillReturnError(tt.err)
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			org := setupOrganizationTests()
+
+			assert.Equal(t, tt.wantErr, org.Delete(db) != nil)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestFindOrganization(t *testing.T) {
+	expected := setupOrganizationTests()
+
+	tests := []struct {
+		name    string
+		find    func(db *sql.DB) (*Organization, error)
+		query   string
+		arg     any
+		err     error
+		wantErr error
+	}{
+		{
+			name:  "by ID",
+			find:  func(db *sql.DB) (*Organization, error) { return FindByID(db, testOrganizationID) },
+			query: findOrganizationByIDQuery,
+			arg:   testOrganizationID,
+		},
+		{
+			name:  "by slug",
+			find:  func(db *sql.DB) (*Organization, error) { return FindBySlug(db, testOrganizationSlug) },
+			query: findOrganizationBySlugQuery,
+			arg:   testOrganizationSlug,
+		},
+		{
+			name:    "not found",
+			find:    func(db *sql.DB) (*Organization, error) { return FindByID(db, testOrganizationID) },
+			query:   findOrganizationByIDQuery,
+			arg:     testOrganizationID,
+			err:     sql.ErrNoRows,
+			wantErr: ErrOrganizationNotFound,
+		},
+		{
+			name:    "database error",
+			find:    func(db *sql.DB) (*Organization, error) { return FindBySlug(db, testOrganizationSlug) },
+			query:   findOrganizationBySlugQuery,
+			arg:     testOrganizationSlug,
+			err:     sql.ErrConnDone,
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			query := mock.ExpectQuery(regexp.QuoteMeta(tt.query)).WithArgs(tt.arg)
+
+			if tt.err != nil {
+				query.WillReturnError(tt.err)
+			} else {
+				query.WillReturnRows(sqlmock.NewRows(organizationColumnNames).
+					AddRow(expected.id, expected.name, expected.slug, expected.createdAt, expected.updatedAt))
+			}
+
+			org, err := tt.find(db)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, org)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, &expected, org)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	now := freezeOrganizationTime(t)
+
+	expectNotFound := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(findOrganizationBySlugQuery)).
+			WithArgs(testOrganizationSlug).
+			WillReturnError(sql.ErrNoRows)
+	}
+
+	tests := []struct {
+		name      string
+		orgName   string
+		setupMock func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name:    "success",
+			orgName: testOrganizationName,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectNotFound(mock)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(insertOrganizationQuery)).
+					WithArgs(testOrganizationName, testOrganizationSlug, now).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(testOrganizationID, now, now))
+				mock.ExpectExec(regexp.QuoteMeta(insertMemberQuery)).
+					WithArgs(testOrganizationID, testUserID, RoleOwner, now).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:      "invalid name",
+			orgName:   "...",
+			setupMock: func(mock sqlmock.Sqlmock) {},
+			wantErr:   ErrInvalidName,
+		},
+		{
+			name:    "already exists",
+			orgName: testOrganizationName,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findOrganizationBySlugQuery)).
+					WillReturnRows(sqlmock.NewRows(organizationColumnNames).AddRow(3, "Acme, Inc", testOrganizationSlug, now, now))
+			},
+			wantErr: ErrOrganizationExists,
+		},
+		{
+			name:    "find error",
+			orgName: testOrganizationName,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findOrganizationBySlugQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name:    "begin error",
+			orgName: testOrganizationName,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectNotFound(mock)
+				mock.ExpectBegin().WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name:    "created at the same time",
+			orgName: testOrganizationName,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectNotFound(mock)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(insertOrganizationQuery)).WillReturnError(&pq.Error{Code: pqUniqueViolation})
+				mock.ExpectRollback()
+			},
+			wantErr: ErrOrganizationExists,
+		},
+		{
+			name:    "insert error",
+			orgName: testOrganizationName,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectNotFound(mock)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(insertOrganizationQuery)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name:    "owner error",
+			orgName: testOrganizationName,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectNotFound(mock)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(insertOrganizationQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(testOrganizationID, now, now))
+				mock.ExpectExec(regexp.QuoteMeta(insertMemberQuery)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			org, err := Create(db, testUserID, tt.orgName)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, org)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, testOrganizationID, org.GetID())
+				assert.Equal(t, testOrganizationSlug, org.GetSlug())
+				assert.Equal(t, now, org.GetCreatedAt())
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
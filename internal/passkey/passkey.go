@@ -0,0 +1,55 @@
+package passkey
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+var ErrInvalidSiteHost = errors.New("the site host must be an absolute URL")
+
+func New(siteName, siteHost string) (*webauthn.WebAuthn, error) {
+	host, err := url.Parse(siteHost)
+
+	if err != nil || host.Scheme == "" || host.Hostname() == "" {
+		return nil, ErrInvalidSiteHost
+	}
+
+	requireResidentKey := true
+
+	return webauthn.New(&webauthn.Config{
+		RPID:          host.Hostname(),
+		RPDisplayName: siteName,
+		RPOrigins:     []string{fmt.Sprintf("%s://%s", host.Scheme, host.Host)},
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			RequireResidentKey: &requireResidentKey,
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			UserVerification:   protocol.VerificationRequired,
+		},
+	})
+}
+
+func EncodeSession(session *webauthn.SessionData) (string, error) {
+	data, err := json.Marshal(session)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to encode passkey session: %w", err)
+	}
+
+	return string(data), nil
+}
+
+func DecodeSession(data string) (*webauthn.SessionData, error) {
+	session := &webauthn.SessionData{}
+	err := json.Unmarshal([]byte(data), session)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode passkey session: %w", err)
+	}
+
+	return session, nil
+}
@@ -0,0 +1,64 @@
+package passkey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		wantRPID   string
+		wantOrigin string
+		wantErr    bool
+	}{
+		{"with port", "http://localhost:4000", "localhost", "http://localhost:4000", false},
+		{"with path", "https://example.com/app/", "example.com", "https://example.com", false},
+		{"without scheme", "example.com", "", "", true},
+		{"empty", "", "", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			wa, err := New("Go Web Starter", tc.host)
+
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidSiteHost)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantRPID, wa.Config.RPID)
+			assert.Equal(t, []string{tc.wantOrigin}, wa.Config.RPOrigins)
+			assert.Equal(t, "Go Web Starter", wa.Config.RPDisplayName)
+		})
+	}
+}
+
+func TestEncodeDecodeSession(t *testing.T) {
+	session := &webauthn.SessionData{
+		Challenge: "challenge",
+		UserID:    []byte{0, 0, 0, 0, 0, 0, 0, 1},
+		Expires:   time.Now().Add(time.Minute).UTC().Round(time.Second),
+	}
+
+	data, err := EncodeSession(session)
+	assert.NoError(t, err)
+
+	decoded, err := DecodeSession(data)
+	assert.NoError(t, err)
+	assert.Equal(t, session.Challenge, decoded.Challenge)
+	assert.Equal(t, session.UserID, decoded.UserID)
+	assert.True(t, session.Expires.Equal(decoded.Expires))
+}
+
+func TestDecodeSessionError(t *testing.T) {
+	_, err := DecodeSession("{")
+	assert.ErrorContains(t, err, "failed to decode passkey session")
+}
@@ -0,0 +1,137 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2idPrefix     = "$argon2id$"
+	argon2idSaltLength = 16
+	argon2idKeyLength  = 32
+)
+
+var (
+	randRead = rand.Read
+	encoding = base64.RawStdEncoding
+)
+
+type Argon2id struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+type argon2idHash struct {
+	version     int
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, argon2idSaltLength)
+	_, err := randRead(salt)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, argon2idKeyLength)
+
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		a.Memory,
+		a.Iterations,
+		a.Parallelism,
+		encoding.EncodeToString(salt),
+		encoding.EncodeToString(key),
+	), nil
+}
+
+func (a *Argon2id) Verify(hash string, password string) error {
+	parsed, err := parseArgon2id(hash)
+
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey(
+		[]byte(password),
+		parsed.salt,
+		parsed.iterations,
+		parsed.memory,
+		parsed.parallelism,
+		uint32(len(parsed.key)),
+	)
+
+	if subtle.ConstantTimeCompare(key, parsed.key) != 1 {
+		return ErrMismatch
+	}
+
+	return nil
+}
+
+func (a *Argon2id) Handles(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+func (a *Argon2id) NeedsRehash(hash string) bool {
+	parsed, err := parseArgon2id(hash)
+
+	if err != nil {
+		return true
+	}
+
+	return parsed.version != argon2.Version ||
+		parsed.memory != a.Memory ||
+		parsed.iterations != a.Iterations ||
+		parsed.parallelism != a.Parallelism ||
+		len(parsed.salt) != argon2idSaltLength ||
+		len(parsed.key) != argon2idKeyLength
+}
+
+func parseArgon2id(hash string) (*argon2idHash, error) {
+	parts := strings.Split(hash, "$")
+
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrInvalidHash
+	}
+
+	parsed := &argon2idHash{}
+
+	_, err := fmt.Sscanf(parts[2], "v=%d", &parsed.version)
+
+	if err != nil {
+		return nil, ErrInvalidHash
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &parsed.memory, &parsed.iterations, &parsed.parallelism)
+
+	if err != nil || parsed.iterations == 0 || parsed.parallelism == 0 {
+		return nil, ErrInvalidHash
+	}
+
+	parsed.salt, err = encoding.DecodeString(parts[4])
+
+	if err != nil {
+		return nil, ErrInvalidHash
+	}
+
+	parsed.key, err = encoding.DecodeString(parts[5])
+
+	if err != nil || len(parsed.key) == 0 {
+		return nil, ErrInvalidHash
+	}
+
+	return parsed, nil
+}
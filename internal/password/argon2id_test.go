@@ -0,0 +1,90 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArgon2idHash(t *testing.T) {
+	hash, err := testArgon2id.Hash("password")
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	assert.True(t, testArgon2id.Handles(hash))
+	assert.False(t, testArgon2id.NeedsRehash(hash))
+
+	other, err := testArgon2id.Hash("password")
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, other)
+}
+
+func TestArgon2idHashSaltError(t *testing.T) {
+	randReadOrig := randRead
+	defer func() { randRead = randReadOrig }()
+
+	randRead = func([]byte) (int, error) { return 0, errors.New("rand error") }
+
+	_, err := testArgon2id.Hash("password")
+	assert.Error(t, err)
+}
+
+func TestArgon2idVerify(t *testing.T) {
+	hash, err := testArgon2id.Hash("password")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		wantErr  error
+	}{
+		{name: "match", hash: hash, password: "password"},
+		{name: "mismatch", hash: hash, password: "other", wantErr: ErrMismatch},
+		{name: "password longer than 72 bytes", hash: hash, password: "password" + strings.Repeat("a", 80), wantErr: ErrMismatch},
+		{name: "other algorithm", hash: "$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5", wantErr: ErrInvalidHash},
+		{name: "missing parts", hash: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA", wantErr: ErrInvalidHash},
+		{name: "invalid version", hash: "$argon2id$v=x$m=1024,t=1,p=1$c2FsdA$a2V5", wantErr: ErrInvalidHash},
+		{name: "invalid parameters", hash: "$argon2id$v=19$m=1024$c2FsdA$a2V5", wantErr: ErrInvalidHash},
+		{name: "no iterations", hash: "$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5", wantErr: ErrInvalidHash},
+		{name: "invalid salt", hash: "$argon2id$v=19$m=1024,t=1,p=1$!$a2V5", wantErr: ErrInvalidHash},
+		{name: "invalid key", hash: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$!", wantErr: ErrInvalidHash},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := testArgon2id.Verify(tc.hash, tc.password)
+
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{name: "invalid hash", hash: "bogus", want: true},
+		{name: "other version", hash: "$argon2id$v=16$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U", want: true},
+		{name: "other memory", hash: "$argon2id$v=19$m=2048,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U", want: true},
+		{name: "other iterations", hash: "$argon2id$v=19$m=1024,t=2,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U", want: true},
+		{name: "other parallelism", hash: "$argon2id$v=19$m=1024,t=1,p=2$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U", want: true},
+		{name: "short key", hash: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5", want: true},
+		{name: "current", hash: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U", want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, testArgon2id.NeedsRehash(tc.hash))
+		})
+	}
+}
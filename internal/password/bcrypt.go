@@ -0,0 +1,59 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt is the algorithm that passwords used to be hashed with.
+// Note that it only uses the first 72 bytes of a password.
+type Bcrypt struct {
+	Cost int
+}
+
+const bcryptMaxBytes = 72
+
+func (b *Bcrypt) Hash(password string) (string, error) {
+	passwordBytes := []byte(password)
+
+	if len(passwordBytes) > bcryptMaxBytes {
+		passwordBytes = passwordBytes[:bcryptMaxBytes]
+	}
+
+	hashedBytes, err := bcrypt.GenerateFromPassword(passwordBytes, b.Cost)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return string(hashedBytes), nil
+}
+
+func (b *Bcrypt) Verify(hash string, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatch
+		}
+
+		return fmt.Errorf("error comparing password hash: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Bcrypt) Handles(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "$2y$")
+}
+
+func (b *Bcrypt) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+
+	return err != nil || cost != b.Cost
+}
@@ -0,0 +1,44 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBcryptHash(t *testing.T) {
+	hash, err := testBcrypt.Hash("password")
+
+	assert.NoError(t, err)
+	assert.True(t, testBcrypt.Handles(hash))
+	assert.False(t, testBcrypt.NeedsRehash(hash))
+	assert.True(t, (&Bcrypt{Cost: 5}).NeedsRehash(hash))
+
+	long := strings.Repeat("a", 100)
+	hash, err = testBcrypt.Hash(long)
+
+	assert.NoError(t, err)
+	assert.NoError(t, testBcrypt.Verify(hash, long))
+	assert.NoError(t, testBcrypt.Verify(hash, long[:72]))
+}
+
+func TestBcryptVerify(t *testing.T) {
+	hash, err := testBcrypt.Hash("password")
+	assert.NoError(t, err)
+
+	assert.NoError(t, testBcrypt.Verify(hash, "password"))
+	assert.ErrorIs(t, testBcrypt.Verify(hash, "other"), ErrMismatch)
+
+	err = testBcrypt.Verify("$2a$bogus", "password")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrMismatch)
+}
+
+func TestBcryptHandles(t *testing.T) {
+	assert.True(t, testBcrypt.Handles("$2a$10$hash"))
+	assert.True(t, testBcrypt.Handles("$2b$10$hash"))
+	assert.True(t, testBcrypt.Handles("$2y$10$hash"))
+	assert.False(t, testBcrypt.Handles("$argon2id$v=19$hash"))
+	assert.True(t, testBcrypt.NeedsRehash("bogus"))
+}
@@ -0,0 +1,82 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrMismatch         = errors.New("the password does not match the hash")
+	ErrUnknownAlgorithm = errors.New("unknown password hashing algorithm")
+	ErrInvalidHash      = errors.New("invalid password hash")
+)
+
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(hash string, password string) error
+	Handles(hash string) bool
+	NeedsRehash(hash string) bool
+}
+
+type Manager struct {
+	current Hasher
+	hashers []Hasher
+}
+
+func New(current Hasher, others ...Hasher) *Manager {
+	return &Manager{
+		current: current,
+		hashers: append([]Hasher{current}, others...),
+	}
+}
+
+func FromConfig() (*Manager, error) {
+	cfg := config.GetPassword()
+
+	argon2id := &Argon2id{
+		Memory:      cfg.Argon2Memory,
+		Iterations:  cfg.Argon2Iterations,
+		Parallelism: cfg.Argon2Parallelism,
+	}
+
+	bcrypt := &Bcrypt{Cost: cfg.BcryptCost}
+
+	switch strings.ToLower(cfg.Algorithm) {
+	case AlgorithmArgon2id:
+		return New(argon2id, bcrypt), nil
+	case AlgorithmBcrypt:
+		return New(bcrypt, argon2id), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, cfg.Algorithm)
+}
+
+func (m *Manager) Hash(password string) (string, error) {
+	return m.current.Hash(password)
+}
+
+func (m *Manager) Verify(hash string, password string) (needsRehash bool, err error) {
+	for _, hasher := range m.hashers {
+		if !hasher.Handles(hash) {
+			continue
+		}
+
+		err = hasher.Verify(hash, password)
+
+		if err != nil {
+			return false, err
+		}
+
+		return hasher != m.current || hasher.NeedsRehash(hash), nil
+	}
+
+	return false, ErrUnknownAlgorithm
+}
@@ -0,0 +1,96 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testArgon2id = &Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1}
+	testBcrypt   = &Bcrypt{Cost: 4}
+)
+
+func TestFromConfig(t *testing.T) {
+	defer viper.Set("password.algorithm", config.DefaultConfig.Password.Algorithm)
+
+	tests := []struct {
+		name        string
+		algorithm   string
+		wantCurrent Hasher
+		wantErr     error
+	}{
+		{name: "argon2id", algorithm: "argon2id", wantCurrent: &Argon2id{}},
+		{name: "bcrypt", algorithm: "bcrypt", wantCurrent: &Bcrypt{}},
+		{name: "case insensitive", algorithm: "Argon2id", wantCurrent: &Argon2id{}},
+		{name: "unknown", algorithm: "md5", wantErr: ErrUnknownAlgorithm},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Set("password.algorithm", tc.algorithm)
+
+			manager, err := FromConfig()
+
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.IsType(t, tc.wantCurrent, manager.current)
+			assert.Len(t, manager.hashers, 2)
+		})
+	}
+}
+
+func TestManagerVerify(t *testing.T) {
+	manager := New(testArgon2id, testBcrypt)
+
+	argon2idHash, err := testArgon2id.Hash("password")
+	assert.NoError(t, err)
+
+	outdatedArgon2idHash, err := (&Argon2id{Memory: 2048, Iterations: 1, Parallelism: 1}).Hash("password")
+	assert.NoError(t, err)
+
+	bcryptHash, err := testBcrypt.Hash("password")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		hash            string
+		password        string
+		wantNeedsRehash bool
+		wantErr         error
+	}{
+		{name: "current", hash: argon2idHash, password: "password"},
+		{name: "other parameters", hash: outdatedArgon2idHash, password: "password", wantNeedsRehash: true},
+		{name: "other algorithm", hash: bcryptHash, password: "password", wantNeedsRehash: true},
+		{name: "mismatch", hash: argon2idHash, password: "other", wantErr: ErrMismatch},
+		{name: "other algorithm mismatch", hash: bcryptHash, password: "other", wantErr: ErrMismatch},
+		{name: "unknown algorithm", hash: "$1$salt$hash", password: "password", wantErr: ErrUnknownAlgorithm},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			needsRehash, err := manager.Verify(tc.hash, tc.password)
+
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.wantNeedsRehash, needsRehash)
+		})
+	}
+}
+
+func TestManagerHash(t *testing.T) {
+	hash, err := New(testBcrypt, testArgon2id).Hash("password")
+
+	assert.NoError(t, err)
+	assert.True(t, testBcrypt.Handles(hash))
+}
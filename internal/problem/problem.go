@@ -0,0 +1,50 @@
+package problem
+
+import (
+	"net/http"
+
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ContentType = "application/problem+json"
+
+	msgValidation = "The request contains invalid fields"
+)
+
+type Problem struct {
+	Type   string              `json:"type"`
+	Title  string              `json:"title"`
+	Status int                 `json:"status"`
+	Detail string              `json:"detail,omitempty"`
+	Errors map[string][]string `json:"errors,omitempty"`
+}
+
+func New(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func FromValidator(v *validator.Validator) *Problem {
+	p := New(http.StatusUnprocessableEntity, msgValidation)
+
+	if formErrors := v.GetFormErrors(); len(formErrors) > 0 {
+		p.Detail = formErrors[0]
+	}
+
+	if fieldErrors := v.GetFieldErrors(); len(fieldErrors) > 0 {
+		p.Errors = fieldErrors
+	}
+
+	return p
+}
+
+func Abort(c *gin.Context, p *Problem) {
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
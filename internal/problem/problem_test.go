@@ -0,0 +1,63 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	p := New(http.StatusNotFound, "The user could not be found")
+
+	assert.Equal(t, &Problem{
+		Type:   "about:blank",
+		Title:  "Not Found",
+		Status: http.StatusNotFound,
+		Detail: "The user could not be found",
+	}, p)
+}
+
+func TestFromValidator(t *testing.T) {
+	t.Run("field errors", func(t *testing.T) {
+		v := validator.New()
+		v.Required("email", "")
+
+		p := FromValidator(v)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, p.Status)
+		assert.Equal(t, msgValidation, p.Detail)
+		assert.Equal(t, map[string][]string{"email": {"This field is required"}}, p.Errors)
+	})
+
+	t.Run("form error", func(t *testing.T) {
+		v := validator.New()
+		v.AddFormError("Failed to process form data")
+
+		p := FromValidator(v)
+
+		assert.Equal(t, "Failed to process form data", p.Detail)
+		assert.Nil(t, p.Errors)
+	})
+}
+
+func TestAbort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	Abort(c, New(http.StatusForbidden, "Nope"))
+
+	assert.True(t, c.IsAborted())
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+
+	var body map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, map[string]any{"type": "about:blank", "title": "Forbidden", "status": float64(403), "detail": "Nope"}, body)
+}
@@ -19,6 +19,13 @@ type RedisInterface interface {
 	SetRange(ctx context.Context, key string, offset int64, value string) (*redisClient.IntCmd, error)
 	FlushDB(ctx context.Context) (*redisClient.StatusCmd, error)
 	SetWithTTL(ctx context.Context, key string, value any) (*redisClient.StatusCmd, error)
+	Del(ctx context.Context, keys ...string) (*redisClient.IntCmd, error)
+	SAdd(ctx context.Context, key string, members ...any) (*redisClient.IntCmd, error)
+	SMembers(ctx context.Context, key string) (*redisClient.StringSliceCmd, error)
+	SRem(ctx context.Context, key string, members ...any) (*redisClient.IntCmd, error)
+	Incr(ctx context.Context, key string) (*redisClient.IntCmd, error)
+	Expire(ctx context.Context, key string, expiration time.Duration) (*redisClient.BoolCmd, error)
+	SetNX(ctx context.Context, key string, value any, expiration time.Duration) (*redisClient.BoolCmd, error)
 }
 
 type Redis struct {
@@ -245,6 +252,197 @@ func (d *Redis) SetWithTTL(ctx context.Context, key string, value any) (*redisCl
 	return cmd, cmd.Err()
 }
 
+func (d *Redis) Del(ctx context.Context, keys ...string) (*redisClient.IntCmd, error) {
+	if d.db == nil {
+		return nil, errNotInitialized
+	}
+
+	if d.isClientClosed() {
+		return nil, errClientClosed
+	}
+
+	if d.logger != nil {
+		d.logger.Debug("Executing Redis DEL", logger.Fields{
+			"keys": keys,
+		})
+	}
+
+	cmd := d.db.Del(ctx, keys...)
+
+	if cmd.Err() != nil && d.logger != nil {
+		d.logger.Error("Redis DEL failed", logger.Fields{
+			"keys":  keys,
+			"error": cmd.Err().Error(),
+		})
+	}
+
+	return cmd, cmd.Err()
+}
+
+func (d *Redis) SAdd(ctx context.Context, key string, members ...any) (*redisClient.IntCmd, error) {
+	if d.db == nil {
+		return nil, errNotInitialized
+	}
+
+	if d.isClientClosed() {
+		return nil, errClientClosed
+	}
+
+	if d.logger != nil {
+		d.logger.Debug("Executing Redis SADD", logger.Fields{
+			"key": key,
+		})
+	}
+
+	cmd := d.db.SAdd(ctx, key, members...)
+
+	if cmd.Err() != nil && d.logger != nil {
+		d.logger.Error("Redis SADD failed", logger.Fields{
+			"key":   key,
+			"error": cmd.Err().Error(),
+		})
+	}
+
+	return cmd, cmd.Err()
+}
+
+func (d *Redis) SMembers(ctx context.Context, key string) (*redisClient.StringSliceCmd, error) {
+	if d.db == nil {
+		return nil, errNotInitialized
+	}
+
+	if d.isClientClosed() {
+		return nil, errClientClosed
+	}
+
+	if d.logger != nil {
+		d.logger.Debug("Executing Redis SMEMBERS", logger.Fields{
+			"key": key,
+		})
+	}
+
+	cmd := d.db.SMembers(ctx, key)
+
+	if cmd.Err() != nil && d.logger != nil {
+		d.logger.Error("Redis SMEMBERS failed", logger.Fields{
+			"key":   key,
+			"error": cmd.Err().Error(),
+		})
+	}
+
+	return cmd, cmd.Err()
+}
+
+func (d *Redis) SRem(ctx context.Context, key string, members ...any) (*redisClient.IntCmd, error) {
+	if d.db == nil {
+		return nil, errNotInitialized
+	}
+
+	if d.isClientClosed() {
+		return nil, errClientClosed
+	}
+
+	if d.logger != nil {
+		d.logger.Debug("Executing Redis SREM", logger.Fields{
+			"key": key,
+		})
+	}
+
+	cmd := d.db.SRem(ctx, key, members...)
+
+	if cmd.Err() != nil && d.logger != nil {
+		d.logger.Error("Redis SREM failed", logger.Fields{
+			"key":   key,
+			"error": cmd.Err().Error(),
+		})
+	}
+
+	return cmd, cmd.Err()
+}
+
+func (d *Redis) Incr(ctx context.Context, key string) (*redisClient.IntCmd, error) {
+	if d.db == nil {
+		return nil, errNotInitialized
+	}
+
+	if d.isClientClosed() {
+		return nil, errClientClosed
+	}
+
+	if d.logger != nil {
+		d.logger.Debug("Executing Redis INCR", logger.Fields{
+			"key": key,
+		})
+	}
+
+	cmd := d.db.Incr(ctx, key)
+
+	if cmd.Err() != nil && d.logger != nil {
+		d.logger.Error("Redis INCR failed", logger.Fields{
+			"key":   key,
+			"error": cmd.Err().Error(),
+		})
+	}
+
+	return cmd, cmd.Err()
+}
+
+func (d *Redis) Expire(ctx context.Context, key string, expiration time.Duration) (*redisClient.BoolCmd, error) {
+	if d.db == nil {
+		return nil, errNotInitialized
+	}
+
+	if d.isClientClosed() {
+		return nil, errClientClosed
+	}
+
+	if d.logger != nil {
+		d.logger.Debug("Executing Redis EXPIRE", logger.Fields{
+			"key":        key,
+			"expiration": expiration,
+		})
+	}
+
+	cmd := d.db.Expire(ctx, key, expiration)
+
+	if cmd.Err() != nil && d.logger != nil {
+		d.logger.Error("Redis EXPIRE failed", logger.Fields{
+			"key":   key,
+			"error": cmd.Err().Error(),
+		})
+	}
+
+	return cmd, cmd.Err()
+}
+
+func (d *Redis) SetNX(ctx context.Context, key string, value any, expiration time.Duration) (*redisClient.BoolCmd, error) {
+	if d.db == nil {
+		return nil, errNotInitialized
+	}
+
+	if d.isClientClosed() {
+		return nil, errClientClosed
+	}
+
+	if d.logger != nil {
+		d.logger.Debug("Executing Redis SETNX", logger.Fields{
+			"key":        key,
+			"expiration": expiration,
+		})
+	}
+
+	cmd := d.db.SetNX(ctx, key, value, expiration)
+
+	if cmd.Err() != nil && d.logger != nil {
+		d.logger.Error("Redis SETNX failed", logger.Fields{
+			"key":   key,
+			"error": cmd.Err().Error(),
+		})
+	}
+
+	return cmd, cmd.Err()
+}
+
 func NewWithMockDB(db redisClient.Cmdable, log *logger.Logger) *Redis {
 	return &Redis{
 		db:     db,
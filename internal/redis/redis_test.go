@@ -66,6 +66,62 @@ func (m *mockRedisClient) FlushDB(ctx context.Context) *redisClient.StatusCmd {
 	return cmd
 }
 
+func (m *mockRedisClient) Del(ctx context.Context, keys ...string) *redisClient.IntCmd {
+	args := m.Called(ctx, keys)
+	cmd := redisClient.NewIntCmd(ctx)
+	cmd.SetErr(args.Error(0))
+
+	return cmd
+}
+
+func (m *mockRedisClient) SAdd(ctx context.Context, key string, members ...any) *redisClient.IntCmd {
+	args := m.Called(ctx, key, members)
+	cmd := redisClient.NewIntCmd(ctx)
+	cmd.SetErr(args.Error(0))
+
+	return cmd
+}
+
+func (m *mockRedisClient) SMembers(ctx context.Context, key string) *redisClient.StringSliceCmd {
+	args := m.Called(ctx, key)
+	cmd := redisClient.NewStringSliceCmd(ctx)
+	cmd.SetErr(args.Error(0))
+
+	return cmd
+}
+
+func (m *mockRedisClient) SRem(ctx context.Context, key string, members ...any) *redisClient.IntCmd {
+	args := m.Called(ctx, key, members)
+	cmd := redisClient.NewIntCmd(ctx)
+	cmd.SetErr(args.Error(0))
+
+	return cmd
+}
+
+func (m *mockRedisClient) Incr(ctx context.Context, key string) *redisClient.IntCmd {
+	args := m.Called(ctx, key)
+	cmd := redisClient.NewIntCmd(ctx)
+	cmd.SetErr(args.Error(0))
+
+	return cmd
+}
+
+func (m *mockRedisClient) Expire(ctx context.Context, key string, expiration time.Duration) *redisClient.BoolCmd {
+	args := m.Called(ctx, key, expiration)
+	cmd := redisClient.NewBoolCmd(ctx)
+	cmd.SetErr(args.Error(0))
+
+	return cmd
+}
+
+func (m *mockRedisClient) SetNX(ctx context.Context, key string, value any, expiration time.Duration) *redisClient.BoolCmd {
+	args := m.Called(ctx, key, value, expiration)
+	cmd := redisClient.NewBoolCmd(ctx)
+	cmd.SetErr(args.Error(0))
+
+	return cmd
+}
+
 func (m *mockRedisClient) Close() error {
 	args := m.Called()
 	return args.Error(0)
@@ -395,6 +451,318 @@ func TestRedis_FlushDB(t *testing.T) {
 	})
 }
 
+func TestRedis_Del(t *testing.T) {
+	t.Parallel()
+
+	runRedisMethodTests(t, []redisTestCase{
+		{
+			name:      "nil db",
+			nilDB:     true,
+			call:      func(r *Redis) (any, error) { cmd, err := r.Del(newTestContext(), "key"); return cmd, err },
+			expectNil: true,
+			expectErr: errNotInitialized,
+		},
+		{
+			name: "closed client",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(errors.New("redis: client is closed"))
+			},
+			call:      func(r *Redis) (any, error) { cmd, err := r.Del(newTestContext(), "key"); return cmd, err },
+			expectNil: true,
+			expectErr: errClientClosed,
+		},
+		{
+			name: "success",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(nil)
+				m.On("Del", mock.Anything, []string{"key"}).Return(nil)
+			},
+			call:      func(r *Redis) (any, error) { cmd, err := r.Del(newTestContext(), "key"); return cmd, err },
+			expectNil: false,
+		},
+		{
+			name: "del error",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(nil)
+				m.On("Del", mock.Anything, []string{"key"}).Return(errors.New("del error"))
+			},
+			call:      func(r *Redis) (any, error) { cmd, err := r.Del(newTestContext(), "key"); return cmd, err },
+			expectNil: false,
+			expectErr: errors.New("del error"),
+		},
+	})
+}
+
+func TestRedis_SAdd(t *testing.T) {
+	t.Parallel()
+
+	call := func(r *Redis) (any, error) { cmd, err := r.SAdd(newTestContext(), "key", "member"); return cmd, err }
+
+	runRedisMethodTests(t, []redisTestCase{
+		{
+			name:      "nil db",
+			nilDB:     true,
+			call:      call,
+			expectNil: true,
+			expectErr: errNotInitialized,
+		},
+		{
+			name: "closed client",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(errors.New("redis: client is closed"))
+			},
+			call:      call,
+			expectNil: true,
+			expectErr: errClientClosed,
+		},
+		{
+			name: "success",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(nil)
+				m.On("SAdd", mock.Anything, "key", []any{"member"}).Return(nil)
+			},
+			call:      call,
+			expectNil: false,
+		},
+		{
+			name: "sadd error",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(nil)
+				m.On("SAdd", mock.Anything, "key", []any{"member"}).Return(errors.New("sadd error"))
+			},
+			call:      call,
+			expectNil: false,
+			expectErr: errors.New("sadd error"),
+		},
+	})
+}
+
+func TestRedis_SMembers(t *testing.T) {
+	t.Parallel()
+
+	call := func(r *Redis) (any, error) { cmd, err := r.SMembers(newTestContext(), "key"); return cmd, err }
+
+	runRedisMethodTests(t, []redisTestCase{
+		{
+			name:      "nil db",
+			nilDB:     true,
+			call:      call,
+			expectNil: true,
+			expectErr: errNotInitialized,
+		},
+		{
+			name: "closed client",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(errors.New("redis: client is closed"))
+			},
+			call:      call,
+			expectNil: true,
+			expectErr: errClientClosed,
+		},
+		{
+			name: "success",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(nil)
+				m.On("SMembers", mock.Anything, "key").Return(nil)
+			},
+			call:      call,
+			expectNil: false,
+		},
+		{
+			name: "smembers error",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(nil)
+				m.On("SMembers", mock.Anything, "key").Return(errors.New("smembers error"))
+			},
+			call:      call,
+			expectNil: false,
+			expectErr: errors.New("smembers error"),
+		},
+	})
+}
+
+func TestRedis_SRem(t *testing.T) {
+	t.Parallel()
+
+	call := func(r *Redis) (any, error) { cmd, err := r.SRem(newTestContext(), "key", "member"); return cmd, err }
+
+	runRedisMethodTests(t, []redisTestCase{
+		{
+			name:      "nil db",
+			nilDB:     true,
+			call:      call,
+			expectNil: true,
+			expectErr: errNotInitialized,
+		},
+		{
+			name: "closed client",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(errors.New("redis: client is closed"))
+			},
+			call:      call,
+			expectNil: true,
+			expectErr: errClientClosed,
+		},
+		{
+			name: "success",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(nil)
+				m.On("SRem", mock.Anything, "key", []any{"member"}).Return(nil)
+			},
+			call:      call,
+			expectNil: false,
+		},
+		{
+			name: "srem error",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(nil)
+				m.On("SRem", mock.Anything, "key", []any{"member"}).Return(errors.New("srem error"))
+			},
+			call:      call,
+			expectNil: false,
+			expectErr: errors.New("srem error"),
+		},
+	})
+}
+
+func TestRedis_Incr(t *testing.T) {
+	t.Parallel()
+
+	call := func(r *Redis) (any, error) { cmd, err := r.Incr(newTestContext(), "key"); return cmd, err }
+
+	runRedisMethodTests(t, []redisTestCase{
+		{
+			name:      "nil db",
+			nilDB:     true,
+			call:      call,
+			expectNil: true,
+			expectErr: errNotInitialized,
+		},
+		{
+			name: "closed client",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(errors.New("redis: client is closed"))
+			},
+			call:      call,
+			expectNil: true,
+			expectErr: errClientClosed,
+		},
+		{
+			name: "success",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(nil)
+				m.On("Incr", mock.Anything, "key").Return(nil)
+			},
+			call:      call,
+			expectNil: false,
+		},
+		{
+			name: "incr error",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(nil)
+				m.On("Incr", mock.Anything, "key").Return(errors.New("incr error"))
+			},
+			call:      call,
+			expectNil: false,
+			expectErr: errors.New("incr error"),
+		},
+	})
+}
+
+func TestRedis_Expire(t *testing.T) {
+	t.Parallel()
+
+	call := func(r *Redis) (any, error) {
+		cmd, err := r.Expire(newTestContext(), "key", time.Minute)
+		return cmd, err
+	}
+
+	runRedisMethodTests(t, []redisTestCase{
+		{
+			name:      "nil db",
+			nilDB:     true,
+			call:      call,
+			expectNil: true,
+			expectErr: errNotInitialized,
+		},
+		{
+			name: "closed client",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(errors.New("redis: client is closed"))
+			},
+			call:      call,
+			expectNil: true,
+			expectErr: errClientClosed,
+		},
+		{
+			name: "success",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(nil)
+				m.On("Expire", mock.Anything, "key", time.Minute).Return(nil)
+			},
+			call:      call,
+			expectNil: false,
+		},
+		{
+			name: "expire error",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(nil)
+				m.On("Expire", mock.Anything, "key", time.Minute).Return(errors.New("expire error"))
+			},
+			call:      call,
+			expectNil: false,
+			expectErr: errors.New("expire error"),
+		},
+	})
+}
+
+func TestRedis_SetNX(t *testing.T) {
+	t.Parallel()
+
+	call := func(r *Redis) (any, error) {
+		cmd, err := r.SetNX(newTestContext(), "key", "val", time.Minute)
+		return cmd, err
+	}
+
+	runRedisMethodTests(t, []redisTestCase{
+		{
+			name:      "nil db",
+			nilDB:     true,
+			call:      call,
+			expectNil: true,
+			expectErr: errNotInitialized,
+		},
+		{
+			name: "closed client",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(errors.New("redis: client is closed"))
+			},
+			call:      call,
+			expectNil: true,
+			expectErr: errClientClosed,
+		},
+		{
+			name: "success",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(nil)
+				m.On("SetNX", mock.Anything, "key", "val", time.Minute).Return(nil)
+			},
+			call:      call,
+			expectNil: false,
+		},
+		{
+			name: "setnx error",
+			setupMock: func(m *mockRedisClient) {
+				m.On("Ping", mock.Anything).Return(nil)
+				m.On("SetNX", mock.Anything, "key", "val", time.Minute).Return(errors.New("setnx error"))
+			},
+			call:      call,
+			expectNil: false,
+			expectErr: errors.New("setnx error"),
+		},
+	})
+}
+
 func TestRedis_isClientClosed(t *testing.T) {
 	t.Parallel()
 	type testCase struct {
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/problem"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	contextKeyAPIUser  = "apiUser"
+	contextKeyAPIToken = "apiToken"
+
+	errAPIUnauthorized = "A valid API token is required"
+	errAPIScope        = "The API token does not have the required scope"
+)
+
+var authenticateAPIToken = user.AuthenticateAPIToken
+
+func apiError(c *gin.Context, status int, message string) {
+	if status == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	}
+
+	problem.Abort(c, problem.New(status, message))
+}
+
+func bearerToken(c *gin.Context) string {
+	scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+func APIAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := logger.New(config.GetLogLevel(), os.Stdout)
+		token := bearerToken(c)
+
+		if token == "" {
+			apiError(c, http.StatusUnauthorized, errAPIUnauthorized)
+			return
+		}
+
+		dbVal, _ := c.Get("db")
+		db, ok := dbVal.(database.DatabaseInterface)
+
+		if !ok {
+			log.Error("Database not found in context for the API authentication", nil)
+			apiError(c, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+
+			return
+		}
+
+		usr, apiToken, err := authenticateAPIToken(db, token)
+
+		if err != nil {
+			if errors.Is(err, user.ErrInvalidAPIToken) || errors.Is(err, user.ErrNotActive) {
+				log.Warn("API authentication failed", logger.Fields{"error": err.Error()})
+				apiError(c, http.StatusUnauthorized, errAPIUnauthorized)
+
+				return
+			}
+
+			log.Error("Failed to authenticate the API token", logger.Fields{"error": err.Error()})
+			apiError(c, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+
+			return
+		}
+
+		c.Set(contextKeyAPIUser, usr)
+		c.Set(contextKeyAPIToken, apiToken)
+
+		c.Next()
+	}
+}
+
+// RequireScope only lets requests through whose API token has the scope.
+// It has to come after APIAuth.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiToken := GetAPIToken(c)
+
+		if apiToken == nil {
+			apiError(c, http.StatusUnauthorized, errAPIUnauthorized)
+			return
+		}
+
+		if !apiToken.HasScope(scope) {
+			apiError(c, http.StatusForbidden, errAPIScope)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func GetAPIUser(c *gin.Context) *user.User {
+	usr, _ := c.Get(contextKeyAPIUser)
+	apiUser, _ := usr.(*user.User)
+
+	return apiUser
+}
+
+func GetAPIToken(c *gin.Context) *user.APIToken {
+	token, _ := c.Get(contextKeyAPIToken)
+	apiToken, _ := token.(*user.APIToken)
+
+	return apiToken
+}
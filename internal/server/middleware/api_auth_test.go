@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIAuth(t *testing.T) {
+	origAuthenticateAPIToken := authenticateAPIToken
+	t.Cleanup(func() { authenticateAPIToken = origAuthenticateAPIToken })
+
+	apiUser := user.NewUser("username", "test@example.com", "hash", true)
+	apiToken := &user.APIToken{ID: 1, Name: "CI", Scopes: []string{user.APIScopeAccountRead}}
+
+	tests := []struct {
+		name         string
+		header       string
+		withDB       bool
+		err          error
+		expectedCode int
+		challenge    bool
+	}{
+		{
+			name:         "success",
+			header:       "Bearer gws_token",
+			withDB:       true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "scheme is case-insensitive",
+			header:       "bearer gws_token",
+			withDB:       true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "no header",
+			withDB:       true,
+			expectedCode: http.StatusUnauthorized,
+			challenge:    true,
+		},
+		{
+			name:         "wrong scheme",
+			header:       "Basic dXNlcjpwYXNz",
+			withDB:       true,
+			expectedCode: http.StatusUnauthorized,
+			challenge:    true,
+		},
+		{
+			name:         "no database",
+			header:       "Bearer gws_token",
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:         "invalid token",
+			header:       "Bearer gws_token",
+			withDB:       true,
+			err:          user.ErrInvalidAPIToken,
+			expectedCode: http.StatusUnauthorized,
+			challenge:    true,
+		},
+		{
+			name:         "inactive user",
+			header:       "Bearer gws_token",
+			withDB:       true,
+			err:          user.ErrNotActive,
+			expectedCode: http.StatusUnauthorized,
+			challenge:    true,
+		},
+		{
+			name:         "database error",
+			header:       "Bearer gws_token",
+			withDB:       true,
+			err:          errors.New("database error"),
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticateAPIToken = func(_ database.DatabaseInterface, token string) (*user.User, *user.APIToken, error) {
+				assert.Equal(t, "gws_token", token)
+
+				if tt.err != nil {
+					return nil, nil, tt.err
+				}
+
+				return apiUser, apiToken, nil
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+
+			r.Use(func(c *gin.Context) {
+				if tt.withDB {
+					c.Set("db", &MockDatabase{})
+				}
+
+				c.Next()
+			})
+			r.GET("/api/test", APIAuth(), func(c *gin.Context) {
+				assert.Same(t, apiUser, GetAPIUser(c))
+				assert.Same(t, apiToken, GetAPIToken(c))
+
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/test", nil)
+
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.challenge {
+				assert.Equal(t, `Bearer realm="api"`, w.Header().Get("WWW-Authenticate"))
+			} else {
+				assert.Empty(t, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name         string
+		token        *user.APIToken
+		expectedCode int
+	}{
+		{
+			name:         "allows a token with the scope",
+			token:        &user.APIToken{Scopes: []string{user.APIScopeAccountRead}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "forbids a token without the scope",
+			token:        &user.APIToken{Scopes: []string{}},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "requires a token",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+
+			r.Use(func(c *gin.Context) {
+				if tt.token != nil {
+					c.Set(contextKeyAPIToken, tt.token)
+				}
+
+				c.Next()
+			})
+			r.GET("/api/test", RequireScope(user.APIScopeAccountRead), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/test", nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestGetAPIUserWithoutToken(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	assert.Nil(t, GetAPIUser(c))
+	assert.Nil(t, GetAPIToken(c))
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIP makes the IP address of the client, as Gin determines it,
+// available to the session store. It must come before the sessions middleware.
+func ClientIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(sessionstore.WithClientIP(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.RemoteAddr = "192.0.2.1:1234"
+
+	c.Request.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	ClientIP()(c)
+
+	assert.Equal(t, c.ClientIP(), sessionstore.ClientIP(c.Request))
+}
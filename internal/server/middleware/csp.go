@@ -4,8 +4,10 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
+	"net/url"
 	"strings"
 
+	"github.com/Dobefu/go-web-starter/internal/storage"
 	"github.com/gin-gonic/gin"
 )
 
@@ -76,7 +78,8 @@ func (csp CSPConfig) String() string {
 		var quoted []string
 
 		for _, src := range values {
-			if strings.HasSuffix(src, ":") {
+			// Schemes and hosts are used as they are, keywords are quoted.
+			if strings.HasSuffix(src, ":") || strings.Contains(src, "://") {
 				quoted = append(quoted, src)
 			} else {
 				quoted = append(quoted, "'"+src+"'")
@@ -117,9 +120,32 @@ func (config SecurityConfig) SetHeaders(c *gin.Context) {
 	c.Set("nonce", nonce)
 }
 
-func CspHeaders() gin.HandlerFunc {
+func uploadsOrigin(store storage.Storage) string {
+	if store == nil {
+		return ""
+	}
+
+	uploadsURL, err := url.Parse(store.URL(""))
+
+	if err != nil || uploadsURL.Host == "" {
+		return ""
+	}
+
+	return uploadsURL.Scheme + "://" + uploadsURL.Host
+}
+
+func CspHeaders(store storage.Storage) gin.HandlerFunc {
+	origin := uploadsOrigin(store)
+
 	return func(c *gin.Context) {
-		newDefaultConfig().SetHeaders(c)
+		config := newDefaultConfig()
+
+		// Avatars are shown from the storage, which can be on another host.
+		if origin != "" {
+			config.CSP.directives["img-src"] = append(config.CSP.directives["img-src"], origin)
+		}
+
+		config.SetHeaders(c)
 		c.Next()
 	}
 }
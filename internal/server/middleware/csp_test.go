@@ -5,6 +5,8 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -12,7 +14,7 @@ import (
 func TestCspHeaders(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
-	router.Use(CspHeaders())
+	router.Use(CspHeaders(nil))
 
 	router.GET("/", func(c *gin.Context) {
 		c.Status(http.StatusOK)
@@ -30,3 +32,47 @@ func TestCspHeaders(t *testing.T) {
 	assert.Equal(t, "nosniff", headers.Get("X-Content-Type-Options"))
 	assert.Equal(t, "strict-origin-when-cross-origin", headers.Get("Referrer-Policy"))
 }
+
+func TestCspHeadersUploadsOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name     string
+		store    storage.Storage
+		expected string
+	}{
+		{
+			name:     "local storage",
+			store:    storage.NewLocalStorage("uploads", "/uploads"),
+			expected: "img-src 'self' data:;",
+		},
+		{
+			name:     "s3 storage",
+			store:    storage.NewS3Storage(config.StorageS3{Endpoint: "https://s3.amazonaws.com", Bucket: "avatars"}),
+			expected: "img-src 'self' data: https://avatars.s3.amazonaws.com;",
+		},
+		{
+			name:     "s3 storage with a public URL",
+			store:    storage.NewS3Storage(config.StorageS3{PublicURL: "https://cdn.example.com/avatars"}),
+			expected: "img-src 'self' data: https://cdn.example.com;",
+		},
+		{
+			name:     "no storage",
+			expected: "img-src 'self' data:;",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(CspHeaders(tc.store))
+			router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Contains(t, w.Header().Get("Content-Security-Policy"), tc.expected)
+		})
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"encoding/base64"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/Dobefu/go-web-starter/internal/config"
 	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
@@ -21,10 +23,22 @@ func CSRF() gin.HandlerFunc {
 			return
 		}
 
+		// API requests are authenticated with a token in a header instead of
+		// the session cookie, which another site cannot make the browser send.
+		if isAPIRequest(c) {
+			c.Next()
+			return
+		}
+
 		session := sessions.Default(c)
 		token := session.Get("csrf_token")
 		formToken := c.PostForm("_csrf")
 
+		// JSON requests cannot carry the form field, so they send a header instead.
+		if formToken == "" {
+			formToken = c.GetHeader("X-CSRF-Token")
+		}
+
 		if token == nil || formToken == "" || token.(string) != formToken {
 			session := sessions.Default(c)
 			session.AddFlash("Invalid or expired form submission. Please try again.")
@@ -39,6 +53,12 @@ func CSRF() gin.HandlerFunc {
 	}
 }
 
+func isAPIRequest(c *gin.Context) bool {
+	path := c.Request.URL.Path
+
+	return path == paths.PathAPI || strings.HasPrefix(path, paths.PathAPI+"/")
+}
+
 func GetCSRFToken(c *gin.Context) string {
 	log := logger.New(config.GetLogLevel(), os.Stdout)
 
@@ -54,6 +54,7 @@ func TestCSRFMiddleware(t *testing.T) {
 		name           string
 		method         string
 		csrfToken      string
+		useHeader      bool
 		expectedStatus int
 	}{
 		{
@@ -73,6 +74,20 @@ func TestCSRFMiddleware(t *testing.T) {
 			csrfToken:      "invalid-token",
 			expectedStatus: http.StatusSeeOther,
 		},
+		{
+			name:           "POST with valid header token should pass",
+			method:         "POST",
+			csrfToken:      "valid-token",
+			useHeader:      true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "POST with invalid header token should redirect",
+			method:         "POST",
+			csrfToken:      "invalid-token",
+			useHeader:      true,
+			expectedStatus: http.StatusSeeOther,
+		},
 		{
 			name:           "POST with missing token should redirect",
 			method:         "POST",
@@ -108,7 +123,7 @@ func TestCSRFMiddleware(t *testing.T) {
 			w = httptest.NewRecorder()
 			formValues := url.Values{}
 
-			if tt.method == "POST" {
+			if tt.method == "POST" && !tt.useHeader {
 				formValues.Add("_csrf", tt.csrfToken)
 			}
 
@@ -116,6 +131,10 @@ func TestCSRFMiddleware(t *testing.T) {
 			assert.NoError(t, err)
 			addSessionCookies(req, cookies)
 
+			if tt.useHeader {
+				req.Header.Set("X-CSRF-Token", tt.csrfToken)
+			}
+
 			router.Any("/test", func(c *gin.Context) {
 				c.Status(http.StatusOK)
 			})
@@ -126,6 +145,33 @@ func TestCSRFMiddleware(t *testing.T) {
 	}
 }
 
+func TestCSRFMiddlewareSkipsAPI(t *testing.T) {
+	router := setupCSRFTestRouter()
+	router.Use(CSRF())
+
+	router.POST("/api/v1/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	router.POST("/apiary", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, err := setupTestRequest("POST", "/api/v1/test", url.Values{})
+	assert.NoError(t, err)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, err = setupTestRequest("POST", "/apiary", url.Values{})
+	assert.NoError(t, err)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+}
+
 func TestGetCSRFToken(t *testing.T) {
 	t.Run("generates and caches token", func(t *testing.T) {
 		router := setupCSRFTestRouter()
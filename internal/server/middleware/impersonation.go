@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"os"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+const SessionKeyImpersonatorID = "impersonatorID"
+
+func BlockImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session := sessions.Default(c)
+		adminID, ok := session.Get(SessionKeyImpersonatorID).(int)
+
+		if !ok {
+			c.Next()
+			return
+		}
+
+		log := logger.New(config.GetLogLevel(), os.Stdout)
+		log.Warn("Access denied while logged in as another user", logger.Fields{
+			"adminID": adminID,
+			"userID":  session.Get("userID"),
+			"path":    c.Request.URL.Path,
+		})
+
+		forbiddenHandler(c)
+		c.Abort()
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockImpersonation(t *testing.T) {
+	tests := []struct {
+		name          string
+		impersonating bool
+		expectedCode  int
+	}{
+		{
+			name:         "allows users",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:          "blocks admins that are logged in as the user",
+			impersonating: true,
+			expectedCode:  http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+
+			r.Use(sessions.Sessions("test-session", cookie.NewStore([]byte("secret"))))
+			r.Use(func(c *gin.Context) {
+				sessions.Default(c).Set("userID", 1)
+
+				if tt.impersonating {
+					sessions.Default(c).Set(SessionKeyImpersonatorID, 2)
+				}
+
+				c.Next()
+			})
+			r.GET("/protected", BlockImpersonation(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/protected", nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/legal"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+var findPendingDocuments = legal.Pending
+
+// RequireLegalAcceptance sends logged in users that have not accepted the current
+// versions of the legal documents to accept them first. It must come after AuthOnly.
+// Admins that are logged in as a user are let through, since they cannot accept for them.
+func RequireLegalAcceptance() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := logger.New(config.GetLogLevel(), os.Stdout)
+		session := sessions.Default(c)
+		userID, ok := session.Get("userID").(int)
+
+		if !ok || session.Get(SessionKeyImpersonatorID) != nil {
+			c.Next()
+			return
+		}
+
+		dbVal, _ := c.Get("db")
+		db, ok := dbVal.(database.DatabaseInterface)
+
+		if !ok {
+			log.Error("Database not found in context for the legal acceptance check", nil)
+			c.AbortWithStatus(http.StatusInternalServerError)
+
+			return
+		}
+
+		pending, err := findPendingDocuments(db, userID)
+
+		if err != nil {
+			log.Error("Failed to check the accepted legal documents", logger.Fields{"userID": userID, "error": err.Error()})
+			c.AbortWithStatus(http.StatusInternalServerError)
+
+			return
+		}
+
+		if len(pending) > 0 {
+			c.Redirect(http.StatusSeeOther, paths.PathLegalAccept)
+			c.Abort()
+
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/legal"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireLegalAcceptance(t *testing.T) {
+	origFindPendingDocuments := findPendingDocuments
+	t.Cleanup(func() { findPendingDocuments = origFindPendingDocuments })
+
+	tests := []struct {
+		name          string
+		userID        any
+		impersonating bool
+		withDB        bool
+		pending       []legal.Document
+		err           error
+		expectedCode  int
+		expectedLoc   string
+	}{
+		{
+			name:         "allows anonymous users",
+			withDB:       true,
+			pending:      []legal.Document{{ID: 1}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "allows users that accepted everything",
+			userID:       1,
+			withDB:       true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "redirects users with pending documents",
+			userID:       1,
+			withDB:       true,
+			pending:      []legal.Document{{ID: 1}},
+			expectedCode: http.StatusSeeOther,
+			expectedLoc:  paths.PathLegalAccept,
+		},
+		{
+			name:          "allows admins that are logged in as the user",
+			userID:        1,
+			impersonating: true,
+			withDB:        true,
+			pending:       []legal.Document{{ID: 1}},
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:         "check error",
+			userID:       1,
+			withDB:       true,
+			err:          errors.New("database error"),
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:         "no database",
+			userID:       1,
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findPendingDocuments = func(_ database.DatabaseInterface, userID int) ([]legal.Document, error) {
+				assert.Equal(t, 1, userID)
+
+				return tt.pending, tt.err
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+
+			r.Use(sessions.Sessions("test-session", cookie.NewStore([]byte("secret"))))
+			r.Use(func(c *gin.Context) {
+				if tt.withDB {
+					c.Set("db", &MockDatabase{})
+				}
+
+				if tt.userID != nil {
+					sessions.Default(c).Set("userID", tt.userID)
+				}
+
+				if tt.impersonating {
+					sessions.Default(c).Set(SessionKeyImpersonatorID, 2)
+				}
+
+				c.Next()
+			})
+			r.GET("/protected", RequireLegalAcceptance(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/protected", nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedLoc, w.Header().Get("Location"))
+		})
+	}
+}
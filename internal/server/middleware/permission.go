@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+var hasPermissionByID = user.HasPermissionByID
+
+var forbiddenHandler gin.HandlerFunc = func(c *gin.Context) {
+	c.AbortWithStatus(http.StatusForbidden)
+}
+
+func SetForbiddenHandler(handler gin.HandlerFunc) {
+	forbiddenHandler = handler
+}
+
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := logger.New(config.GetLogLevel(), os.Stdout)
+		userID, ok := sessions.Default(c).Get("userID").(int)
+
+		if !ok {
+			c.Redirect(http.StatusSeeOther, paths.PathLogin)
+			c.Abort()
+
+			return
+		}
+
+		dbVal, _ := c.Get("db")
+		db, ok := dbVal.(database.DatabaseInterface)
+
+		if !ok {
+			log.Error("Database not found in context for the permission check", nil)
+			c.AbortWithStatus(http.StatusInternalServerError)
+
+			return
+		}
+
+		hasPermission, err := hasPermissionByID(db, userID, permission)
+
+		if err != nil {
+			log.Error("Failed to check permission", logger.Fields{"userID": userID, "permission": permission, "error": err.Error()})
+			c.AbortWithStatus(http.StatusInternalServerError)
+
+			return
+		}
+
+		if !hasPermission {
+			log.Warn("Access denied: missing permission", logger.Fields{"userID": userID, "permission": permission})
+			forbiddenHandler(c)
+			c.Abort()
+
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequirePermission(t *testing.T) {
+	origHasPermissionByID := hasPermissionByID
+	origForbiddenHandler := forbiddenHandler
+
+	t.Cleanup(func() {
+		hasPermissionByID = origHasPermissionByID
+		forbiddenHandler = origForbiddenHandler
+	})
+
+	tests := []struct {
+		name          string
+		userID        any
+		withDB        bool
+		hasPermission bool
+		err           error
+		forbidden     gin.HandlerFunc
+		expectedCode  int
+		expectedLoc   string
+		expectedBody  string
+	}{
+		{
+			name:         "redirects to login if not authenticated",
+			withDB:       true,
+			expectedCode: http.StatusSeeOther,
+			expectedLoc:  paths.PathLogin,
+		},
+		{
+			name:          "allows access with the permission",
+			userID:        1,
+			withDB:        true,
+			hasPermission: true,
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:         "forbids access without the permission",
+			userID:       1,
+			withDB:       true,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "uses the forbidden handler",
+			userID:       1,
+			withDB:       true,
+			forbidden:    func(c *gin.Context) { c.String(http.StatusForbidden, "custom") },
+			expectedCode: http.StatusForbidden,
+			expectedBody: "custom",
+		},
+		{
+			name:         "permission check error",
+			userID:       1,
+			withDB:       true,
+			err:          errors.New("database error"),
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:         "no database",
+			userID:       1,
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasPermissionByID = func(_ database.DatabaseInterface, userID int, permission string) (bool, error) {
+				assert.Equal(t, 1, userID)
+				assert.Equal(t, "users.manage", permission)
+
+				return tt.hasPermission, tt.err
+			}
+
+			forbiddenHandler = origForbiddenHandler
+
+			if tt.forbidden != nil {
+				SetForbiddenHandler(tt.forbidden)
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+
+			r.Use(sessions.Sessions("test-session", cookie.NewStore([]byte("secret"))))
+			r.Use(func(c *gin.Context) {
+				if tt.withDB {
+					c.Set("db", &MockDatabase{})
+				}
+
+				if tt.userID != nil {
+					sessions.Default(c).Set("userID", tt.userID)
+				}
+
+				c.Next()
+			})
+			r.GET("/protected", RequirePermission("users.manage"), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/protected", nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedLoc, w.Header().Get("Location"))
+
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequirePermissionDeactivatedUser(t *testing.T) {
+	origHasPermissionByID := hasPermissionByID
+	t.Cleanup(func() { hasPermissionByID = origHasPermissionByID })
+
+	hasPermissionByID = user.HasPermissionByID
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`JOIN users u ON u\.id = ur\.user_id AND u\.status AND u\.deleted_at IS NULL`).
+		WithArgs(1, user.PermissionUsersManage).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	r.Use(sessions.Sessions("test-session", cookie.NewStore([]byte("secret"))))
+	r.Use(func(c *gin.Context) {
+		c.Set("db", db)
+		sessions.Default(c).Set("userID", 1)
+		c.Next()
+	})
+	r.GET("/protected", RequirePermission(user.PermissionUsersManage), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/protected", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -96,6 +96,76 @@ func (m *MockRedis) SetWithTTL(ctx context.Context, key string, value any) (*red
 	return args.Get(0).(*redisClient.StatusCmd), args.Error(1)
 }
 
+func (m *MockRedis) Del(ctx context.Context, keys ...string) (*redisClient.IntCmd, error) {
+	args := m.Called(ctx, keys)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*redisClient.IntCmd), args.Error(1)
+}
+
+func (m *MockRedis) SAdd(ctx context.Context, key string, members ...any) (*redisClient.IntCmd, error) {
+	args := m.Called(ctx, key, members)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*redisClient.IntCmd), args.Error(1)
+}
+
+func (m *MockRedis) SMembers(ctx context.Context, key string) (*redisClient.StringSliceCmd, error) {
+	args := m.Called(ctx, key)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*redisClient.StringSliceCmd), args.Error(1)
+}
+
+func (m *MockRedis) SRem(ctx context.Context, key string, members ...any) (*redisClient.IntCmd, error) {
+	args := m.Called(ctx, key, members)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*redisClient.IntCmd), args.Error(1)
+}
+
+func (m *MockRedis) Incr(ctx context.Context, key string) (*redisClient.IntCmd, error) {
+	args := m.Called(ctx, key)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*redisClient.IntCmd), args.Error(1)
+}
+
+func (m *MockRedis) Expire(ctx context.Context, key string, expiration time.Duration) (*redisClient.BoolCmd, error) {
+	args := m.Called(ctx, key, expiration)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*redisClient.BoolCmd), args.Error(1)
+}
+
+func (m *MockRedis) SetNX(ctx context.Context, key string, value any, expiration time.Duration) (*redisClient.BoolCmd, error) {
+	args := m.Called(ctx, key, value, expiration)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*redisClient.BoolCmd), args.Error(1)
+}
+
 func createMockStringCmd(val string, err error) *redisClient.StringCmd {
 	cmd := redisClient.NewStringCmd(context.Background())
 
@@ -0,0 +1,13 @@
+package middleware
+
+import (
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func Storage(store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("storage", store)
+		c.Next()
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewLocalStorage("uploads", "/uploads")
+
+	c, _ := gin.CreateTestContext(nil)
+
+	middleware := Storage(store)
+	middleware(c)
+
+	value, exists := c.Get("storage")
+	assert.True(t, exists)
+	assert.Same(t, store, value)
+}
@@ -1,15 +1,23 @@
 package routes
 
 import (
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
 	"github.com/gin-gonic/gin"
 )
 
 func Account(c *gin.Context) {
+	currentUser := route_utils.GetUserFromSession(c)
+
 	data := RouteData{
 		Template:    "pages/account",
 		Title:       "My Account",
 		Description: "View your account details.",
 		HttpStatus:  200,
+		Data: map[string]any{
+			"PendingEmail": getPendingEmail(c, currentUser),
+		},
+		CSRFToken: middleware.GetCSRFToken(c),
 	}
 
 	RenderRouteHTML(c, data)
@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
+	"github.com/gin-gonic/gin"
+)
+
+const accountActivityLimit = 50
+
+var findAuditEvents = audit.Find
+
+type accountActivityEntry struct {
+	Description string
+	Device      string
+	IP          string
+	CreatedAt   time.Time
+}
+
+func AccountActivity(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	entries, err := accountActivityEntries(db, usr.GetID())
+
+	if err != nil {
+		log.Error("Could not list the audit events", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	data := RouteData{
+		Template:    "pages/account_activity",
+		Title:       "Security Activity",
+		Description: "The recent security activity of your account.",
+		HttpStatus:  http.StatusOK,
+		Data: map[string]any{
+			"Events": entries,
+		},
+	}
+
+	RenderRouteHTML(c, data)
+}
+
+func accountActivityEntries(db database.DatabaseInterface, userID int) ([]accountActivityEntry, error) {
+	events, err := findAuditEvents(db, audit.Query{TargetID: userID, Limit: accountActivityLimit})
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []accountActivityEntry{}
+
+	for _, event := range events {
+		entries = append(entries, accountActivityEntry{
+			Description: event.Type.Description(),
+			Device:      sessionstore.DeviceName(event.UserAgent),
+			IP:          event.IP,
+			CreatedAt:   event.CreatedAt,
+		})
+	}
+
+	return entries, nil
+}
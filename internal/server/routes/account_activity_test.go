@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/stretchr/testify/assert"
+)
+
+var pathAccountActivity = paths.PathAccount + "/activity"
+
+func patchFindAuditEvents(t *testing.T, events []audit.Event, err error) *audit.Query {
+	var query audit.Query
+	findAuditEventsOrig := findAuditEvents
+
+	t.Cleanup(func() { findAuditEvents = findAuditEventsOrig })
+	findAuditEvents = func(_ database.DatabaseInterface, q audit.Query) ([]audit.Event, error) {
+		query = q
+		return events, err
+	}
+
+	return &query
+}
+
+func TestAccountActivity(t *testing.T) {
+	now := time.Now()
+
+	events := []audit.Event{
+		{Type: audit.EventLoginSucceeded, TargetID: 1, IP: "192.0.2.1", UserAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:140.0) Gecko/20100101 Firefox/140.0", CreatedAt: now},
+		{Type: audit.EventLoginFailed, TargetID: 1, IP: "198.51.100.1", CreatedAt: now},
+	}
+
+	tests := []struct {
+		name         string
+		loggedIn     bool
+		events       []audit.Event
+		findErr      error
+		expectStatus int
+		expectBody   []string
+	}{
+		{
+			name:         "not logged in",
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "find error",
+			loggedIn:     true,
+			findErr:      errors.New("db fail"),
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "no activity",
+			loggedIn:     true,
+			expectStatus: http.StatusOK,
+			expectBody:   []string{"There is no activity yet."},
+		},
+		{
+			name:         "success",
+			loggedIn:     true,
+			events:       events,
+			expectStatus: http.StatusOK,
+			expectBody:   []string{"Signed in", "Firefox on Linux", "Failed sign-in attempt", "Unknown device", "198.51.100.1"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			query := patchFindAuditEvents(t, tc.events, tc.findErr)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tc.loggedIn {
+				router.Use(setSessionUserID(1))
+				expectSessionUser(mock, "")
+				expectSessionUser(mock, "")
+			}
+
+			router.GET(pathAccountActivity, AccountActivity)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", pathAccountActivity, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+
+			for _, body := range tc.expectBody {
+				assert.Contains(t, w.Body.String(), body)
+			}
+
+			if tc.loggedIn {
+				assert.Equal(t, audit.Query{TargetID: 1, Limit: accountActivityLimit}, *query)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/avatar"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	pathAccountAvatar = fmt.Sprintf("%s/avatar", paths.PathAccount)
+	pathAccountEdit   = fmt.Sprintf("%s/edit", paths.PathAccount)
+)
+
+var getStorage = route_utils.GetStorageFromContext
+
+const avatarMaxRequestBytes = avatar.MaxBytes + 1<<20
+
+var errAvatarTooLarge = fmt.Sprintf("The avatar must be no larger than %d MB", avatar.MaxBytes>>20)
+
+var avatarErrorMessages = map[error]string{
+	avatar.ErrUnsupportedType: "The avatar must be a JPEG, PNG or GIF image",
+	avatar.ErrTooSmall:        fmt.Sprintf("The avatar must be at least %d by %d pixels", avatar.MinDimension, avatar.MinDimension),
+	avatar.ErrTooLarge:        fmt.Sprintf("The avatar must be at most %d by %d pixels", avatar.MaxDimension, avatar.MaxDimension),
+	avatar.ErrInvalidImage:    "The avatar could not be read. Please try another image",
+}
+
+func AccountAvatarPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	// The body is limited before it is parsed, so that a large upload
+	// is not read in full only to be rejected afterwards.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, avatarMaxRequestBytes)
+	formErr := v.ValidateForm(c.Request)
+
+	if formErr != nil {
+		log.Error("Failed to parse form data", logger.Fields{"error": formErr.Error()})
+	}
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	store, err := getStorage(c)
+
+	if err != nil {
+		log.Error("Could not get the avatar storage", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if v.GetFormValue(c.Request, "action") == "remove" {
+		removeAvatar(c, v, log, db, store, usr)
+		return
+	}
+
+	file := v.GetFormFile(c.Request, "avatar")
+
+	var maxBytesErr *http.MaxBytesError
+
+	if errors.As(formErr, &maxBytesErr) {
+		v.AddFieldError("avatar", errAvatarTooLarge)
+	} else {
+		v.RequiredFile("avatar", file)
+		v.MaxFileSize("avatar", file, avatar.MaxBytes)
+	}
+
+	var images []avatar.Image
+
+	if !v.HasErrors() {
+		images, err = processAvatar(file)
+
+		for avatarErr, msg := range avatarErrorMessages {
+			if errors.Is(err, avatarErr) {
+				v.AddFieldError("avatar", msg)
+			}
+		}
+
+		if err != nil && !v.HasErrors() {
+			log.Error("Could not process the avatar", logger.Fields{"error": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+	}
+
+	if v.HasErrors() {
+		route_utils.RedirectWithError(c, v, map[string]string{}, "Please correct the errors below", pathAccountEdit)
+		return
+	}
+
+	key, err := avatar.Upload(c.Request.Context(), store, usr.GetID(), images)
+
+	if err != nil {
+		log.Error("Could not store the avatar", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	oldKey := usr.GetAvatar()
+	err = usr.SetAvatar(db, key)
+
+	if err != nil {
+		log.Error("Could not update the avatar", logger.Fields{"error": err.Error()})
+		_ = avatar.Remove(c.Request.Context(), store, key)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	// The old files are no longer used. If removing them fails, they are
+	// only left behind, so the new avatar is kept either way.
+	if oldKey != "" {
+		err = avatar.Remove(c.Request.Context(), store, oldKey)
+
+		if err != nil {
+			log.Warn("Could not remove the old avatar", logger.Fields{"key": oldKey, "error": err.Error()})
+		}
+	}
+
+	auditEvent(c, audit.EventAvatarUpdated, usr.GetID(), usr.GetID(), nil)
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: "Your avatar has been updated.",
+	})
+
+	c.Redirect(http.StatusSeeOther, pathAccountEdit)
+}
+
+func processAvatar(file *multipart.FileHeader) ([]avatar.Image, error) {
+	f, err := file.Open()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the upload: %w", err)
+	}
+
+	defer func() { _ = f.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(f, avatar.MaxBytes))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the upload: %w", err)
+	}
+
+	return avatar.Process(data)
+}
+
+func removeAvatar(
+	c *gin.Context,
+	v *validator.Validator,
+	log *logger.Logger,
+	db database.DatabaseInterface,
+	store storage.Storage,
+	usr *user.User,
+) {
+	oldKey := usr.GetAvatar()
+
+	if oldKey != "" {
+		err := usr.SetAvatar(db, "")
+
+		if err != nil {
+			log.Error("Could not remove the avatar", logger.Fields{"error": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+
+		err = avatar.Remove(c.Request.Context(), store, oldKey)
+
+		if err != nil {
+			log.Warn("Could not remove the avatar files", logger.Fields{"key": oldKey, "error": err.Error()})
+		}
+
+		auditEvent(c, audit.EventAvatarRemoved, usr.GetID(), usr.GetID(), nil)
+	}
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: "Your avatar has been removed.",
+	})
+
+	c.Redirect(http.StatusSeeOther, pathAccountEdit)
+}
+
+func removeAccountAvatar(c *gin.Context, log *logger.Logger, usr *user.User) {
+	if !usr.HasAvatar() {
+		return
+	}
+
+	store, err := getStorage(c)
+
+	if err == nil {
+		err = avatar.Remove(c.Request.Context(), store, usr.GetAvatar())
+	}
+
+	if err != nil {
+		log.Warn("Could not remove the avatar files", logger.Fields{"key": usr.GetAvatar(), "error": err.Error()})
+	}
+}
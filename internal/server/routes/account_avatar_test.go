@@ -0,0 +1,358 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/avatar"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const testAvatarKey = "avatars/1/old"
+
+func expectAvatarUser(mock sqlmock.Sqlmock, avatarKey string) {
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(1).
+		WillReturnRows(
+			sqlmock.NewRows(
+				[]string{"id", "username", "email", "password", "status", "created_at", "updated_at", "last_login", "avatar"},
+			).
+				AddRow(1, "username", "test@example.com", "", true, now, now, now, avatarKey),
+		)
+}
+
+func useAvatarStorage(t *testing.T, storageErr error) string {
+	dir := t.TempDir()
+	store := storage.NewLocalStorage(dir, "/uploads")
+	getStorageOrig := getStorage
+
+	t.Cleanup(func() { getStorage = getStorageOrig })
+	getStorage = func(*gin.Context) (storage.Storage, error) {
+		if storageErr != nil {
+			return nil, storageErr
+		}
+
+		return store, nil
+	}
+
+	for _, size := range avatar.Sizes {
+		err := os.MkdirAll(filepath.Join(dir, "avatars", "1"), 0o755)
+		assert.NoError(t, err)
+
+		err = os.WriteFile(filepath.Join(dir, filepath.FromSlash(avatar.Key(testAvatarKey, size))), []byte("old"), 0o644)
+		assert.NoError(t, err)
+	}
+
+	return dir
+}
+
+func countAvatarFiles(t *testing.T, dir string) (oldFiles int, newFiles int) {
+	entries, err := os.ReadDir(filepath.Join(dir, "avatars", "1"))
+	assert.NoError(t, err)
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "old-") {
+			oldFiles++
+		} else {
+			newFiles++
+		}
+	}
+
+	return oldFiles, newFiles
+}
+
+func testAvatarImage(t *testing.T, size int) []byte {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, size, size)))
+	assert.NoError(t, err)
+
+	return buf.Bytes()
+}
+
+func postAvatar(t *testing.T, router http.Handler, action string, file []byte) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	assert.NoError(t, writer.WriteField("action", action))
+
+	if file != nil {
+		part, err := writer.CreateFormFile("avatar", "avatar.png")
+		assert.NoError(t, err)
+
+		_, err = part.Write(file)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, writer.Close())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", pathAccountAvatar, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestAccountAvatarPost(t *testing.T) {
+	sizes := len(avatar.Sizes)
+
+	tests := []struct {
+		name           string
+		action         string
+		file           []byte
+		avatarKey      string
+		storageErr     error
+		setupMock      func(mock sqlmock.Sqlmock)
+		expectStatus   int
+		expectLocation string
+		expectOldFiles int
+		expectNewFiles int
+		expectEvents   []audit.EventType
+	}{
+		{
+			name:   "upload",
+			action: "upload",
+			file:   testAvatarImage(t, 100),
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE users SET avatar = \$1`).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountEdit,
+			expectOldFiles: sizes,
+			expectNewFiles: sizes,
+			expectEvents:   []audit.EventType{audit.EventAvatarUpdated},
+		},
+		{
+			name:      "upload replaces the old avatar",
+			action:    "upload",
+			file:      testAvatarImage(t, 100),
+			avatarKey: testAvatarKey,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE users SET avatar = \$1`).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountEdit,
+			expectNewFiles: sizes,
+			expectEvents:   []audit.EventType{audit.EventAvatarUpdated},
+		},
+		{
+			name:           "no file",
+			action:         "upload",
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountEdit,
+			expectOldFiles: sizes,
+			expectEvents:   []audit.EventType{},
+		},
+		{
+			name:           "file too large",
+			action:         "upload",
+			file:           make([]byte, avatar.MaxBytes+1),
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountEdit,
+			expectOldFiles: sizes,
+			expectEvents:   []audit.EventType{},
+		},
+		{
+			name:           "request too large",
+			action:         "upload",
+			file:           make([]byte, avatarMaxRequestBytes+1),
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountEdit,
+			expectOldFiles: sizes,
+			expectEvents:   []audit.EventType{},
+		},
+		{
+			name:           "not an image",
+			action:         "upload",
+			file:           []byte("<svg></svg>"),
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountEdit,
+			expectOldFiles: sizes,
+			expectEvents:   []audit.EventType{},
+		},
+		{
+			name:           "image too small",
+			action:         "upload",
+			file:           testAvatarImage(t, avatar.MinDimension-1),
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountEdit,
+			expectOldFiles: sizes,
+			expectEvents:   []audit.EventType{},
+		},
+		{
+			name:           "storage error",
+			action:         "upload",
+			file:           testAvatarImage(t, 100),
+			storageErr:     storage.ErrUnknownDriver,
+			expectStatus:   http.StatusInternalServerError,
+			expectOldFiles: sizes,
+			expectEvents:   []audit.EventType{},
+		},
+		{
+			name:   "database error",
+			action: "upload",
+			file:   testAvatarImage(t, 100),
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE users SET avatar = \$1`).WillReturnError(sql.ErrConnDone)
+			},
+			expectStatus:   http.StatusInternalServerError,
+			expectOldFiles: sizes,
+			expectEvents:   []audit.EventType{},
+		},
+		{
+			name:      "remove",
+			action:    "remove",
+			avatarKey: testAvatarKey,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE users SET avatar = \$1`).
+					WithArgs("", sqlmock.AnyArg(), 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountEdit,
+			expectEvents:   []audit.EventType{audit.EventAvatarRemoved},
+		},
+		{
+			name:           "remove without an avatar",
+			action:         "remove",
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountEdit,
+			expectOldFiles: sizes,
+			expectEvents:   []audit.EventType{},
+		},
+		{
+			name:      "remove database error",
+			action:    "remove",
+			avatarKey: testAvatarKey,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE users SET avatar = \$1`).WillReturnError(sql.ErrConnDone)
+			},
+			expectStatus:   http.StatusInternalServerError,
+			expectOldFiles: sizes,
+			expectEvents:   []audit.EventType{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditLog := useRecordingAuditLog(t)
+			dir := useAvatarStorage(t, tt.storageErr)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			router.POST(pathAccountAvatar, setSessionUserID(1), AccountAvatarPost)
+
+			expectAvatarUser(mock, tt.avatarKey)
+
+			if tt.setupMock != nil {
+				tt.setupMock(mock)
+			}
+
+			w := postAvatar(t, router, tt.action, tt.file)
+
+			assert.Equal(t, tt.expectStatus, w.Code)
+			assert.Equal(t, tt.expectLocation, w.Header().Get("Location"))
+			oldFiles, newFiles := countAvatarFiles(t, dir)
+			assert.Equal(t, tt.expectOldFiles, oldFiles)
+			assert.Equal(t, tt.expectNewFiles, newFiles)
+			assert.Equal(t, tt.expectEvents, auditLog.types())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAccountAvatarPostNoDatabase(t *testing.T) {
+	useAvatarStorage(t, nil)
+
+	router, _, mockDB := setupTestRouterWithMocks(t, false)
+	defer func() { _ = mockDB.Close() }()
+
+	router.POST(pathAccountAvatar, setSessionUserID(1), AccountAvatarPost)
+
+	w := postAvatar(t, router, "upload", testAvatarImage(t, 100))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestAccountAvatarPostUploadError(t *testing.T) {
+	useAvatarStorage(t, nil)
+
+	getStorage = func(*gin.Context) (storage.Storage, error) {
+		return failingAvatarStorage{}, nil
+	}
+
+	router, mock, mockDB := setupTestRouterWithMocks(t, true)
+	defer func() { _ = mockDB.Close() }()
+
+	router.POST(pathAccountAvatar, setSessionUserID(1), AccountAvatarPost)
+	expectAvatarUser(mock, "")
+
+	w := postAvatar(t, router, "upload", testAvatarImage(t, 100))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+type failingAvatarStorage struct {
+	storage.Storage
+}
+
+func (failingAvatarStorage) Put(_ context.Context, _ string, _ io.Reader, _ string) error {
+	return errors.New("put fail")
+}
+
+func (failingAvatarStorage) Delete(_ context.Context, _ string) error {
+	return nil
+}
+
+func TestRemoveAccountAvatar(t *testing.T) {
+	tests := []struct {
+		name           string
+		avatarKey      string
+		storageErr     error
+		expectOldFiles int
+	}{
+		{name: "avatar", avatarKey: testAvatarKey},
+		{name: "no avatar", expectOldFiles: len(avatar.Sizes)},
+		{name: "storage error", avatarKey: testAvatarKey, storageErr: storage.ErrUnknownDriver, expectOldFiles: len(avatar.Sizes)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := useAvatarStorage(t, tt.storageErr)
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+
+			usr := user.New(user.UserFields{Id: 1, Avatar: tt.avatarKey})
+			removeAccountAvatar(c, logger.New(logger.ErrorLevel, io.Discard), usr)
+
+			oldFiles, _ := countAvatarFiles(t, dir)
+			assert.Equal(t, tt.expectOldFiles, oldFiles)
+		})
+	}
+}
@@ -32,6 +32,10 @@ func AccountDelete(c *gin.Context) {
 		Description: "Confirm your account deletion.",
 		HttpStatus:  200,
 
+		Data: map[string]any{
+			"GraceDays": int(config.DeletionGracePeriod().Hours() / 24),
+		},
+
 		FormData: FormData{
 			Values: formValues,
 			Errors: v.GetSessionErrors(),
@@ -77,7 +81,16 @@ func AccountDeletePost(c *gin.Context) {
 		return
 	}
 
-	err = usr.CheckPassword(password)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Failed to get database connection from context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	err = usr.CheckPassword(db, password)
 
 	if err != nil {
 		if errors.Is(err, user.ErrInvalidCredentials) {
@@ -99,19 +112,10 @@ func AccountDeletePost(c *gin.Context) {
 		return
 	}
 
-	db, err := route_utils.GetDbFromContext(c)
-
-	if err != nil {
-		log.Error("Failed to get database connection from context", nil)
-		RenderRouteHTML(c, GenericErrorData(c))
-
-		return
-	}
-
-	_, err = db.Exec("DELETE FROM users WHERE id = $1", usr.GetID())
+	purgeAt, err := deleteAccount(c, db, usr.GetID(), usr, "password")
 
 	if err != nil {
-		log.Error("Failed to delete user", logger.Fields{"user_id": usr.GetID})
+		log.Error("Failed to delete user", logger.Fields{"user_id": usr.GetID(), "error": err.Error()})
 		RenderRouteHTML(c, GenericErrorData(c))
 
 		return
@@ -121,7 +125,16 @@ func AccountDeletePost(c *gin.Context) {
 	session.Clear()
 	_ = session.Save()
 
-	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: "Your account has been deleted successfully."})
+	body := "Your account has been deleted successfully."
+
+	if !purgeAt.IsZero() {
+		body = fmt.Sprintf(
+			"Your account has been deleted. You can restore it by logging in until %s, after which it is removed for good.",
+			formatPurgeDate(purgeAt),
+		)
+	}
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: body})
 
 	c.Redirect(http.StatusSeeOther, paths.PathLogin)
 }
@@ -1,16 +1,25 @@
 package routes
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/user"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
+var pathAccountDelete = paths.PathAccount + "/delete"
+
 func TestAccountDelete(t *testing.T) {
 	router, _, mockDB, err := setupTestRouter(true)
 	assert.NoError(t, err)
@@ -33,3 +42,121 @@ func TestAccountDelete(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+func TestAccountDeletePost(t *testing.T) {
+	hash, _ := user.HashPassword("pw")
+
+	tests := []struct {
+		name          string
+		password      string
+		noGracePeriod bool
+		setupMock     func(mock sqlmock.Sqlmock)
+		sendErr       error
+		wantStatus    int
+		wantLocation  string
+		wantSent      bool
+		wantEvents    []audit.EventType
+	}{
+		{
+			name:         "missing password",
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: pathAccountDelete,
+			wantEvents:   []audit.EventType{},
+		},
+		{
+			name:         "wrong password",
+			password:     "wrong",
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: pathAccountDelete,
+			wantEvents:   []audit.EventType{},
+		},
+		{
+			name:         "success",
+			password:     "pw",
+			setupMock:    expectSoftDelete,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathLogin,
+			wantSent:     true,
+			wantEvents:   []audit.EventType{audit.EventAccountDeleted},
+		},
+		{
+			name:         "email error",
+			password:     "pw",
+			setupMock:    expectSoftDelete,
+			sendErr:      errors.New("smtp error"),
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathLogin,
+			wantSent:     true,
+			wantEvents:   []audit.EventType{audit.EventAccountDeleted},
+		},
+		{
+			name:          "without grace period",
+			password:      "pw",
+			noGracePeriod: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM users").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectRevokeAccess(mock, 1)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathLogin,
+			wantEvents:   []audit.EventType{audit.EventAccountDeleted},
+		},
+		{
+			name:     "database error",
+			password: "pw",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE users SET deleted_at").WillReturnError(errors.New("db fail"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantEvents: []audit.EventType{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := useRecordingEmailSender(t)
+			sender.err = tt.sendErr
+			auditLog := useRecordingAuditLog(t)
+
+			if tt.noGracePeriod {
+				setDeletionGraceDays(t, 0)
+			}
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tt.password != "" {
+				expectSessionUser(mock, hash)
+			}
+
+			if tt.setupMock != nil {
+				tt.setupMock(mock)
+			}
+
+			router.Use(setSessionUserID(1))
+			router.POST(pathAccountDelete, AccountDeletePost)
+
+			form := url.Values{"password": {tt.password}}
+			req := httptest.NewRequest(http.MethodPost, pathAccountDelete, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+			assert.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tt.wantEvents, auditLog.types())
+
+			if !tt.wantSent {
+				assert.Empty(t, sender.sent)
+				return
+			}
+
+			assert.Len(t, sender.sent, 1)
+			assert.Equal(t, []string{"test@example.com"}, sender.to[0])
+			assert.Equal(t, "email/account_deleted", sender.sent[0].Template)
+			assert.NotEmpty(t, sender.sent[0].Data["Token"])
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+)
+
+const errDeviceNotFound = "The device could not be found. It may have been signed out already."
+
+var newSessionBackend = func(c *gin.Context, db database.DatabaseInterface) sessionstore.Backend {
+	if r, err := route_utils.GetRedisFromContext(c); err == nil {
+		return sessionstore.NewRedisBackend(r)
+	}
+
+	return sessionstore.NewDatabaseBackend(db)
+}
+
+func AccountDevices(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	devices, err := newSessionBackend(c, db).ListByUser(c.Request.Context(), usr.GetID())
+
+	if err != nil {
+		log.Error("Could not list the sessions", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	supportIDs := make(map[string]bool)
+
+	for _, device := range devices {
+		if _, ok := device.Value(middleware.SessionKeyImpersonatorID).(int); ok {
+			supportIDs[device.ID] = true
+		}
+	}
+
+	data := RouteData{
+		Template:    "pages/account_devices",
+		Title:       "Your Devices",
+		Description: "The devices that are signed in to your account.",
+		HttpStatus:  http.StatusOK,
+		Data: map[string]any{
+			"Devices":    devices,
+			"CurrentID":  sessionstore.RecordID(getSession(c).ID()),
+			"SupportIDs": supportIDs,
+		},
+		CSRFToken: middleware.GetCSRFToken(c),
+	}
+
+	RenderRouteHTML(c, data)
+}
+
+func AccountDevicesPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	err := v.ValidateForm(c.Request)
+
+	if err != nil {
+		log.Error("Failed to parse form data", logger.Fields{"error": err.Error()})
+	}
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	path := fmt.Sprintf("%s/devices", paths.PathAccount)
+	ctx := c.Request.Context()
+	backend := newSessionBackend(c, db)
+	currentID := sessionstore.RecordID(getSession(c).ID())
+	flash := "All other devices have been signed out."
+
+	switch v.GetFormValue(c.Request, "action") {
+	case "revoke":
+		id := v.GetFormValue(c.Request, "id")
+		var devices []*sessionstore.Record
+
+		devices, err = backend.ListByUser(ctx, usr.GetID())
+
+		if err != nil {
+			break
+		}
+
+		if id == "" || id == currentID || !hasDevice(devices, id) {
+			route_utils.RedirectWithError(c, v, nil, errDeviceNotFound, path)
+			return
+		}
+
+		err = backend.Delete(ctx, id)
+		flash = "The device has been signed out."
+
+	case "revoke-others":
+		err = backend.DeleteByUser(ctx, usr.GetID(), currentID)
+
+	default:
+		route_utils.RedirectWithError(c, v, nil, "Unknown action", path)
+		return
+	}
+
+	if err != nil {
+		log.Error("Could not sign out the devices", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Devices signed out", logger.Fields{"userID": usr.GetID()})
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: flash})
+	c.Redirect(http.StatusSeeOther, path)
+}
+
+func hasDevice(devices []*sessionstore.Record, id string) bool {
+	for _, device := range devices {
+		if device.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
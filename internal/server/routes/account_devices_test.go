@@ -0,0 +1,302 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/securecookie"
+	"github.com/stretchr/testify/assert"
+)
+
+var pathAccountDevices = paths.PathAccount + "/devices"
+
+const currentSessionToken = "current-token"
+
+type fakeSessionBackend struct {
+	records      []*sessionstore.Record
+	listErr      error
+	deleteErr    error
+	deleted      []string
+	deletedUsers []int
+	exceptID     string
+}
+
+func (b *fakeSessionBackend) Get(_ context.Context, id string) (*sessionstore.Record, error) {
+	return nil, nil
+}
+
+func (b *fakeSessionBackend) Save(_ context.Context, record *sessionstore.Record) error {
+	return nil
+}
+
+func (b *fakeSessionBackend) Delete(_ context.Context, id string) error {
+	b.deleted = append(b.deleted, id)
+	return b.deleteErr
+}
+
+func (b *fakeSessionBackend) ListByUser(_ context.Context, userID int) ([]*sessionstore.Record, error) {
+	return b.records, b.listErr
+}
+
+func (b *fakeSessionBackend) DeleteByUser(_ context.Context, userID int, exceptID string) error {
+	b.deletedUsers = append(b.deletedUsers, userID)
+	b.exceptID = exceptID
+
+	return b.deleteErr
+}
+
+type tokenSession struct {
+	sessions.Session
+}
+
+func (s tokenSession) ID() string {
+	return currentSessionToken
+}
+
+func patchSessionBackend(t *testing.T, backend *fakeSessionBackend) {
+	newSessionBackendOrig := newSessionBackend
+	getSessionOrig := getSession
+
+	t.Cleanup(func() {
+		newSessionBackend = newSessionBackendOrig
+		getSession = getSessionOrig
+	})
+
+	newSessionBackend = func(*gin.Context, database.DatabaseInterface) sessionstore.Backend {
+		return backend
+	}
+
+	getSession = func(c *gin.Context) sessions.Session {
+		return tokenSession{Session: sessions.Default(c)}
+	}
+}
+
+func testDevices() []*sessionstore.Record {
+	now := time.Now()
+
+	return []*sessionstore.Record{
+		{
+			ID:         sessionstore.RecordID(currentSessionToken),
+			UserID:     1,
+			UserAgent:  "Mozilla/5.0 (X11; Linux x86_64; rv:140.0) Gecko/20100101 Firefox/140.0",
+			IP:         "192.0.2.1",
+			CreatedAt:  now,
+			LastSeenAt: now,
+		},
+		{
+			ID:         "other",
+			UserID:     1,
+			UserAgent:  "Mozilla/5.0 (iPhone; CPU iPhone OS 18_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Mobile/15E148 Safari/604.1",
+			IP:         "198.51.100.1",
+			CreatedAt:  now,
+			LastSeenAt: now,
+		},
+	}
+}
+
+func TestNewSessionBackend(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	assert.IsType(t, &sessionstore.DatabaseBackend{}, newSessionBackend(c, nil))
+}
+
+func TestAccountDevices(t *testing.T) {
+	tests := []struct {
+		name         string
+		loggedIn     bool
+		records      []*sessionstore.Record
+		listErr      error
+		expectStatus int
+		expectBody   []string
+		notExpected  []string
+	}{
+		{
+			name:         "not logged in",
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "list error",
+			loggedIn:     true,
+			listErr:      errors.New("backend error"),
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "success",
+			loggedIn:     true,
+			expectStatus: http.StatusOK,
+			expectBody:   []string{"Firefox on Linux", "Safari on iOS", "This device", "198.51.100.1", "value=revoke-others"},
+			notExpected:  []string{"Support session"},
+		},
+		{
+			name:         "support session",
+			loggedIn:     true,
+			records:      testSupportDevices(t),
+			expectStatus: http.StatusOK,
+			expectBody:   []string{"Safari on iOS", "Support session"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			records := tc.records
+
+			if records == nil {
+				records = testDevices()
+			}
+
+			patchSessionBackend(t, &fakeSessionBackend{records: records, listErr: tc.listErr})
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tc.loggedIn {
+				router.Use(setSessionUserID(1))
+				expectSessionUser(mock, "")
+				expectSessionUser(mock, "")
+			}
+
+			router.GET(pathAccountDevices, AccountDevices)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", pathAccountDevices, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+
+			for _, body := range tc.expectBody {
+				assert.Contains(t, w.Body.String(), body)
+			}
+
+			for _, body := range tc.notExpected {
+				assert.NotContains(t, w.Body.String(), body)
+			}
+		})
+	}
+}
+
+func testSupportDevices(t *testing.T) []*sessionstore.Record {
+	data, err := securecookie.GobEncoder{}.Serialize(map[any]any{"userID": 1, middleware.SessionKeyImpersonatorID: 2})
+	assert.NoError(t, err)
+
+	devices := testDevices()
+	devices[1].Data = data
+
+	return devices
+}
+
+func TestAccountDevicesPost(t *testing.T) {
+	tests := []struct {
+		name           string
+		loggedIn       bool
+		form           url.Values
+		listErr        error
+		deleteErr      error
+		expectStatus   int
+		expectDeleted  []string
+		expectExceptID string
+	}{
+		{
+			name:         "not logged in",
+			form:         url.Values{"action": {"revoke-others"}},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "unknown action",
+			loggedIn:     true,
+			form:         url.Values{"action": {"bogus"}},
+			expectStatus: http.StatusSeeOther,
+		},
+		{
+			name:          "revoke",
+			loggedIn:      true,
+			form:          url.Values{"action": {"revoke"}, "id": {"other"}},
+			expectStatus:  http.StatusSeeOther,
+			expectDeleted: []string{"other"},
+		},
+		{
+			name:         "revoke unknown device",
+			loggedIn:     true,
+			form:         url.Values{"action": {"revoke"}, "id": {"unknown"}},
+			expectStatus: http.StatusSeeOther,
+		},
+		{
+			name:         "revoke current device",
+			loggedIn:     true,
+			form:         url.Values{"action": {"revoke"}, "id": {sessionstore.RecordID(currentSessionToken)}},
+			expectStatus: http.StatusSeeOther,
+		},
+		{
+			name:         "revoke list error",
+			loggedIn:     true,
+			form:         url.Values{"action": {"revoke"}, "id": {"other"}},
+			listErr:      errors.New("backend error"),
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:          "revoke delete error",
+			loggedIn:      true,
+			form:          url.Values{"action": {"revoke"}, "id": {"other"}},
+			deleteErr:     errors.New("backend error"),
+			expectStatus:  http.StatusInternalServerError,
+			expectDeleted: []string{"other"},
+		},
+		{
+			name:           "revoke others",
+			loggedIn:       true,
+			form:           url.Values{"action": {"revoke-others"}},
+			expectStatus:   http.StatusSeeOther,
+			expectExceptID: sessionstore.RecordID(currentSessionToken),
+		},
+		{
+			name:           "revoke others error",
+			loggedIn:       true,
+			form:           url.Values{"action": {"revoke-others"}},
+			deleteErr:      errors.New("backend error"),
+			expectStatus:   http.StatusInternalServerError,
+			expectExceptID: sessionstore.RecordID(currentSessionToken),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			backend := &fakeSessionBackend{records: testDevices(), listErr: tc.listErr, deleteErr: tc.deleteErr}
+			patchSessionBackend(t, backend)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tc.loggedIn {
+				router.Use(setSessionUserID(1))
+				expectSessionUser(mock, "")
+				expectSessionUser(mock, "")
+			}
+
+			router.POST(pathAccountDevices, AccountDevicesPost)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", pathAccountDevices, strings.NewReader(tc.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.Equal(t, tc.expectDeleted, backend.deleted)
+			assert.Equal(t, tc.expectExceptID, backend.exceptID)
+
+			if tc.expectStatus == http.StatusSeeOther {
+				assert.Equal(t, pathAccountDevices, w.Header().Get("Location"))
+			}
+		})
+	}
+}
@@ -1,16 +1,19 @@
 package routes
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 	"os"
 
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/avatar"
 	"github.com/Dobefu/go-web-starter/internal/config"
 	"github.com/Dobefu/go-web-starter/internal/logger"
 	"github.com/Dobefu/go-web-starter/internal/message"
 	"github.com/Dobefu/go-web-starter/internal/server/middleware"
 	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
 	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
 	"github.com/Dobefu/go-web-starter/internal/validator"
 	"github.com/gin-gonic/gin"
 )
@@ -36,6 +39,12 @@ func AccountEdit(c *gin.Context) {
 		Description: "Manage your account details.",
 		HttpStatus:  200,
 
+		Data: map[string]any{
+			"PendingEmail":  getPendingEmail(c, currentUser),
+			"PasswordReset": passwordResetActive(getSession(c)),
+			"AvatarSize":    avatar.SizeLarge,
+			"AvatarMaxMB":   avatar.MaxBytes >> 20,
+		},
 		FormData: FormData{
 			Values: formValues,
 			Errors: v.GetSessionErrors(),
@@ -86,15 +95,32 @@ func AccountEditPost(c *gin.Context) {
 				"username": username,
 			},
 			"Please correct the errors below",
-			fmt.Sprintf("%s/edit", paths.PathAccount),
+			pathAccountEdit,
 		)
 
 		return
 	}
 
+	oldUsername := usr.GetUsername()
+
 	usr.SetUsername(username)
 	err = usr.Save(db)
 
+	if errors.Is(err, user.ErrUserExists) {
+		v.AddFieldError("username", "This username is already taken")
+		route_utils.RedirectWithError(
+			c,
+			v,
+			map[string]string{
+				"username": username,
+			},
+			"Please correct the errors below",
+			pathAccountEdit,
+		)
+
+		return
+	}
+
 	if err != nil {
 		log.Error("Could not update the user", logger.Fields{"error": err.Error()})
 		RenderRouteHTML(c, GenericErrorData(c))
@@ -102,6 +128,8 @@ func AccountEditPost(c *gin.Context) {
 		return
 	}
 
+	auditEvent(c, audit.EventProfileUpdated, usr.GetID(), usr.GetID(), audit.Metadata{"old_username": oldUsername, "new_username": username})
+
 	v.SetFlash(message.Message{
 		Type: message.MessageTypeSuccess,
 		Body: "Your profile has been updated successfully!",
@@ -5,6 +5,9 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/storage"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
@@ -33,3 +36,22 @@ func TestAccountEdit(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+func TestAccountEditAvatar(t *testing.T) {
+	router, mock, mockDB := setupTestRouterWithMocks(t, true)
+	defer func() { _ = mockDB.Close() }()
+
+	expectAvatarUser(mock, "avatars/1/abc")
+	mock.ExpectQuery(`SELECT new_email FROM user_email_changes`).WillReturnRows(sqlmock.NewRows([]string{"new_email"}))
+	expectAvatarUser(mock, "avatars/1/abc")
+
+	router.Use(middleware.Storage(storage.NewLocalStorage("uploads", "/uploads")), setSessionUserID(1))
+	router.GET("/", AccountEdit)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `src="/uploads/avatars/1/abc-64.png"`)
+	assert.Contains(t, w.Body.String(), `src="/uploads/avatars/1/abc-256.png"`)
+}
@@ -0,0 +1,289 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	emailer "github.com/Dobefu/go-web-starter/internal/email"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	errEmailChangeInvalid = "Could not change the email address. The link may have expired."
+	errEmailRevertInvalid = "Could not undo the email address change. The link may have expired."
+	errEmailSame          = "This is already your email address"
+)
+
+var userGetPendingEmail = func(usr *user.User, db database.DatabaseInterface) (string, error) {
+	return usr.GetPendingEmail(db)
+}
+
+func getPendingEmail(c *gin.Context, usr *user.User) string {
+	if usr == nil {
+		return ""
+	}
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		return ""
+	}
+
+	email, err := userGetPendingEmail(usr, db)
+
+	if err != nil {
+		log := logger.New(config.GetLogLevel(), os.Stdout)
+		log.Error("Could not get the pending email address", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+	}
+
+	return email
+}
+
+func AccountEmailPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	err := v.ValidateForm(c.Request)
+
+	if err != nil {
+		log.Error("Failed to parse form data", logger.Fields{"error": err.Error()})
+	}
+
+	email := v.GetFormValue(c.Request, "email")
+
+	v.ValidEmail("email", email)
+	v.Required("email", email)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user for the email change", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if strings.EqualFold(email, usr.GetEmail()) {
+		v.AddFieldError("email", errEmailSame)
+	} else if _, err = findByEmail(db, email); err == nil {
+		v.AddFieldError("email", "This email address is already taken")
+	}
+
+	if v.HasErrors() {
+		route_utils.RedirectWithError(
+			c,
+			v,
+			map[string]string{
+				"email": email,
+			},
+			"Please correct the errors below",
+			fmt.Sprintf("%s/edit", paths.PathAccount),
+		)
+
+		return
+	}
+
+	err = sendEmailChangeEmails(db, usr, email)
+
+	if err != nil {
+		log.Error("Could not start the email change", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Email change requested", logger.Fields{"userID": usr.GetID()})
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: fmt.Sprintf("A confirmation link has been sent to %s. Your email address changes once you open it.", email),
+	})
+
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("%s/edit", paths.PathAccount))
+}
+
+func sendEmailChangeEmails(db database.DatabaseInterface, usr *user.User, email string) error {
+	err := usr.RequestEmailChange(db, email)
+
+	if err != nil {
+		return err
+	}
+
+	confirmToken, err := usr.CreateToken(db, user.TokenPurposeEmailChange, user.TokenTTLEmailChange)
+
+	if err != nil {
+		return err
+	}
+
+	revertToken, err := usr.CreateToken(db, user.TokenPurposeEmailRevert, user.TokenTTLEmailRevert)
+
+	if err != nil {
+		return err
+	}
+
+	mail := newEmailSender()
+	siteName := viper.GetString("site.name")
+
+	err = mail.SendMail(
+		viper.GetString("site.email"),
+		[]string{email},
+		fmt.Sprintf("Confirm your new %s email address", siteName),
+		emailer.EmailBody{
+			Template: "email/email_change_confirm",
+			Data: map[string]any{
+				"Username": usr.GetUsername(),
+				"Token":    confirmToken,
+			},
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return mail.SendMail(
+		viper.GetString("site.email"),
+		[]string{usr.GetEmail()},
+		fmt.Sprintf("Your %s email address is being changed", siteName),
+		emailer.EmailBody{
+			Template: "email/email_change_notice",
+			Data: map[string]any{
+				"Username": usr.GetUsername(),
+				"NewEmail": email,
+				"Token":    revertToken,
+			},
+		},
+	)
+}
+
+// AccountEmailConfirm switches to the new email address. It does not require
+// a login, since the link may be opened on another device.
+func AccountEmailConfirm(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	token := v.GetFormValue(c.Request, "token")
+	redirectPath := emailChangeRedirectPath(c)
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Could not get the database from the context", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	usr, err := user.ConsumeToken(db, user.TokenPurposeEmailChange, token)
+
+	if err == nil {
+		err = usr.ConfirmEmailChange(db)
+	}
+
+	if err != nil {
+		body := errEmailChangeInvalid
+
+		switch {
+		case errors.Is(err, user.ErrEmailTaken):
+			body = user.ErrEmailTaken.Error()
+		case !errors.Is(err, user.ErrInvalidToken) && !errors.Is(err, user.ErrNoEmailChange):
+			log.Error("Could not confirm the email change", logger.Fields{"error": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+
+		v.SetFlash(message.Message{Type: message.MessageTypeError, Body: body})
+		c.Redirect(http.StatusSeeOther, redirectPath)
+
+		return
+	}
+
+	log.Info("Email change confirmed", logger.Fields{"userID": usr.GetID()})
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: fmt.Sprintf("Your email address has been changed to %s.", usr.GetEmail()),
+	})
+
+	c.Redirect(http.StatusSeeOther, redirectPath)
+}
+
+func AccountEmailRevert(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	token := v.GetFormValue(c.Request, "token")
+	redirectPath := emailChangeRedirectPath(c)
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Could not get the database from the context", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	usr, err := user.ConsumeToken(db, user.TokenPurposeEmailRevert, token)
+
+	if err == nil {
+		err = usr.RevertEmailChange(db)
+	}
+
+	if err == nil {
+		err = usr.RevokeTokens(db, user.TokenPurposeEmailChange)
+	}
+
+	if err != nil {
+		body := errEmailRevertInvalid
+
+		switch {
+		case errors.Is(err, user.ErrEmailTaken):
+			body = user.ErrEmailTaken.Error()
+		case !errors.Is(err, user.ErrInvalidToken) && !errors.Is(err, user.ErrNoEmailChange):
+			log.Error("Could not revert the email change", logger.Fields{"error": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+
+		v.SetFlash(message.Message{Type: message.MessageTypeError, Body: body})
+		c.Redirect(http.StatusSeeOther, redirectPath)
+
+		return
+	}
+
+	log.Warn("Email change reverted", logger.Fields{"userID": usr.GetID()})
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: "The email address change has been undone. If you did not request it, please change your password.",
+	})
+
+	c.Redirect(http.StatusSeeOther, redirectPath)
+}
+
+func emailChangeRedirectPath(c *gin.Context) string {
+	if getSession(c).Get("userID") != nil {
+		return paths.PathAccount
+	}
+
+	return paths.PathLogin
+}
@@ -0,0 +1,390 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	server_utils "github.com/Dobefu/go-web-starter/internal/server/utils"
+	"github.com/Dobefu/go-web-starter/internal/templates"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	pathAccountEmail        = paths.PathAccount + "/email"
+	pathAccountEmailConfirm = pathAccountEmail + "/confirm"
+	pathAccountEmailRevert  = pathAccountEmail + "/revert"
+)
+
+func setupAccountEmailRouter(db database.DatabaseInterface, handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	router.Use(sessions.Sessions("mysession", cookie.NewStore([]byte("secret"))))
+	router.Use(middleware.Database(db))
+	router.Use(handlers...)
+
+	router.SetFuncMap(server_utils.TemplateFuncMap())
+	_ = templates.LoadTemplates(router)
+
+	router.POST(pathAccountEmail, AccountEmailPost)
+	router.GET(pathAccountEmailConfirm, AccountEmailConfirm)
+	router.GET(pathAccountEmailRevert, AccountEmailRevert)
+
+	return router
+}
+
+func accountEmailRequest(email string) *http.Request {
+	form := url.Values{"email": {email}}
+
+	req := httptest.NewRequest(http.MethodPost, pathAccountEmail, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req
+}
+
+func expectCreateToken(mock sqlmock.Sqlmock, purpose string) {
+	mock.ExpectExec("DELETE FROM user_tokens").WithArgs(1, purpose).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectIssueToken(mock, purpose)
+}
+
+func expectIssueToken(mock sqlmock.Sqlmock, purpose string) {
+	mock.ExpectExec("INSERT INTO user_tokens").WithArgs(1, purpose, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func TestGetPendingEmail(t *testing.T) {
+	origGetPendingEmail := userGetPendingEmail
+	defer func() { userGetPendingEmail = origGetPendingEmail }()
+
+	tests := []struct {
+		name    string
+		usr     *user.User
+		pending string
+		err     error
+		want    string
+	}{
+		{name: "no user", want: ""},
+		{name: "pending", usr: newTestUserWithID(1), pending: "new@example.com", want: "new@example.com"},
+		{name: "error", usr: newTestUserWithID(1), err: errors.New("database error"), want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userGetPendingEmail = func(*user.User, database.DatabaseInterface) (string, error) {
+				return tt.pending, tt.err
+			}
+
+			db, _, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Set("db", database.DatabaseInterface(db))
+
+			assert.Equal(t, tt.want, getPendingEmail(c, tt.usr))
+		})
+	}
+}
+
+func TestAccountEmailPost(t *testing.T) {
+	takenEmail := func(database.DatabaseInterface, string) (*user.User, error) { return newTestUserWithID(2), nil }
+	freeEmail := func(database.DatabaseInterface, string) (*user.User, error) { return nil, user.ErrInvalidCredentials }
+
+	tests := []struct {
+		name        string
+		email       string
+		findByEmail func(database.DatabaseInterface, string) (*user.User, error)
+		mockSetup   func(mock sqlmock.Sqlmock)
+		sendErr     error
+		wantStatus  int
+		wantSent    int
+	}{
+		{
+			name:        "success",
+			email:       "new@example.com",
+			findByEmail: freeEmail,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO user_email_changes").
+					WithArgs(1, "new@example.com", "test@example.com", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectCreateToken(mock, "email-change")
+				expectCreateToken(mock, "email-revert")
+			},
+			wantStatus: http.StatusSeeOther,
+			wantSent:   2,
+		},
+		{
+			name:        "invalid email",
+			email:       "invalid",
+			findByEmail: freeEmail,
+			mockSetup:   func(mock sqlmock.Sqlmock) {},
+			wantStatus:  http.StatusSeeOther,
+		},
+		{
+			name:        "same email",
+			email:       "TEST@example.com",
+			findByEmail: freeEmail,
+			mockSetup:   func(mock sqlmock.Sqlmock) {},
+			wantStatus:  http.StatusSeeOther,
+		},
+		{
+			name:        "email taken",
+			email:       "taken@example.com",
+			findByEmail: takenEmail,
+			mockSetup:   func(mock sqlmock.Sqlmock) {},
+			wantStatus:  http.StatusSeeOther,
+		},
+		{
+			name:        "request error",
+			email:       "new@example.com",
+			findByEmail: freeEmail,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO user_email_changes").WillReturnError(errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:        "email error",
+			email:       "new@example.com",
+			findByEmail: freeEmail,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO user_email_changes").WillReturnResult(sqlmock.NewResult(1, 1))
+				expectCreateToken(mock, "email-change")
+				expectCreateToken(mock, "email-revert")
+			},
+			sendErr:    errors.New("smtp error"),
+			wantStatus: http.StatusInternalServerError,
+			wantSent:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restoreFinders := patchFinders(nil, tt.findByEmail)
+			defer restoreFinders()
+
+			sender := useRecordingEmailSender(t)
+			sender.err = tt.sendErr
+
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			expectSessionUser(mock, "")
+			tt.mockSetup(mock)
+
+			w := httptest.NewRecorder()
+			setupAccountEmailRouter(db, setSessionUserID(1)).ServeHTTP(w, accountEmailRequest(tt.email))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Len(t, sender.sent, tt.wantSent)
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.wantStatus == http.StatusSeeOther {
+				assert.Equal(t, paths.PathAccount+"/edit", w.Header().Get("Location"))
+			}
+
+			if tt.wantSent == 2 {
+				assert.Equal(t, []string{"new@example.com"}, sender.to[0])
+				assert.Equal(t, "email/email_change_confirm", sender.sent[0].Template)
+				assert.Equal(t, []string{"test@example.com"}, sender.to[1])
+				assert.Equal(t, "email/email_change_notice", sender.sent[1].Template)
+				assert.Equal(t, "new@example.com", sender.sent[1].Data["NewEmail"])
+			}
+		})
+	}
+}
+
+func TestAccountEmailConfirm(t *testing.T) {
+	tests := []struct {
+		name         string
+		loggedIn     bool
+		mockSetup    func(mock sqlmock.Sqlmock)
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:     "success",
+			loggedIn: true,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "email-change", true)
+				mock.ExpectQuery("WITH change AS").
+					WithArgs(1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("new@example.com"))
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathAccount,
+		},
+		{
+			name: "success from another device",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "email-change", true)
+				mock.ExpectQuery("WITH change AS").
+					WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("new@example.com"))
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathLogin,
+		},
+		{
+			name: "invalid token",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("UPDATE user_tokens SET consumed_at").WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathLogin,
+		},
+		{
+			name: "no pending change",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "email-change", true)
+				mock.ExpectQuery("WITH change AS").WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathLogin,
+		},
+		{
+			name: "email taken",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "email-change", true)
+				mock.ExpectQuery("WITH change AS").WillReturnError(&pq.Error{Code: "23505"})
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathLogin,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("UPDATE user_tokens SET consumed_at").WillReturnError(errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			var handlers []gin.HandlerFunc
+
+			if tt.loggedIn {
+				handlers = append(handlers, setSessionUserID(1))
+			}
+
+			w := httptest.NewRecorder()
+			setupAccountEmailRouter(db, handlers...).ServeHTTP(
+				w,
+				httptest.NewRequest(http.MethodGet, pathAccountEmailConfirm+"?token=token", nil),
+			)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAccountEmailRevert(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockSetup  func(mock sqlmock.Sqlmock)
+		wantStatus int
+	}{
+		{
+			name: "success after confirmation",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "email-revert", true)
+				mock.ExpectBegin()
+				mock.ExpectQuery("DELETE FROM user_email_changes").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"old_email", "confirmed"}).AddRow("old@example.com", true))
+				mock.ExpectExec("UPDATE users SET email").
+					WithArgs("old@example.com", sqlmock.AnyArg(), 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectExec("DELETE FROM user_tokens").WithArgs(1, "email-change").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantStatus: http.StatusSeeOther,
+		},
+		{
+			name: "success before confirmation",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "email-revert", true)
+				mock.ExpectBegin()
+				mock.ExpectQuery("DELETE FROM user_email_changes").
+					WillReturnRows(sqlmock.NewRows([]string{"old_email", "confirmed"}).AddRow("test@example.com", false))
+				mock.ExpectCommit()
+				mock.ExpectExec("DELETE FROM user_tokens").WithArgs(1, "email-change").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantStatus: http.StatusSeeOther,
+		},
+		{
+			name: "invalid token",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("UPDATE user_tokens SET consumed_at").WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus: http.StatusSeeOther,
+		},
+		{
+			name: "no change",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "email-revert", true)
+				mock.ExpectBegin()
+				mock.ExpectQuery("DELETE FROM user_email_changes").WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			wantStatus: http.StatusSeeOther,
+		},
+		{
+			name: "revoke error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "email-revert", true)
+				mock.ExpectBegin()
+				mock.ExpectQuery("DELETE FROM user_email_changes").
+					WillReturnRows(sqlmock.NewRows([]string{"old_email", "confirmed"}).AddRow("test@example.com", false))
+				mock.ExpectCommit()
+				mock.ExpectExec("DELETE FROM user_tokens").WillReturnError(errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			w := httptest.NewRecorder()
+			setupAccountEmailRouter(db).ServeHTTP(
+				w,
+				httptest.NewRequest(http.MethodGet, pathAccountEmailRevert+"?token=token", nil),
+			)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.wantStatus == http.StatusSeeOther {
+				assert.Equal(t, paths.PathLogin, w.Header().Get("Location"))
+			}
+		})
+	}
+}
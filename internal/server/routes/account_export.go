@@ -0,0 +1,183 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"sync"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	emailer "github.com/Dobefu/go-web-starter/internal/email"
+	"github.com/Dobefu/go-web-starter/internal/export"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	errDataExportNotFound  = "This download link is invalid or has expired. Please request a new export."
+	errDataExportThrottled = "Your data has been exported recently. Please check your email, or try again later."
+
+	dataExportFilename = "data-export.zip"
+)
+
+var collectExportData = export.Collect
+
+var runInBackground = func(task func()) {
+	go runRecovered(task)
+}
+
+var dataExportsRunning sync.Map
+
+func startDataExport(userID int) bool {
+	_, running := dataExportsRunning.LoadOrStore(userID, true)
+	return !running
+}
+
+// runRecovered logs a panic in the task, since a panic in a goroutine
+// would otherwise take down the whole server.
+func runRecovered(task func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log := logger.New(config.GetLogLevel(), os.Stdout)
+			log.Error("A background task panicked", logger.Fields{"panic": fmt.Sprint(r), "stack": string(debug.Stack())})
+		}
+	}()
+
+	task()
+}
+
+func AccountExportPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	recent, err := export.Recent(db, usr.GetID())
+
+	if err != nil {
+		log.Error("Could not check the recent data exports", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if recent || !startDataExport(usr.GetID()) {
+		v.SetFlash(message.Message{Type: message.MessageTypeError, Body: errDataExportThrottled})
+		c.Redirect(http.StatusSeeOther, paths.PathAccount)
+
+		return
+	}
+
+	sessions := newSessionBackend(c, db)
+	store, _ := getStorage(c)
+	auditEvent(c, audit.EventDataExportRequested, usr.GetID(), usr.GetID(), nil)
+
+	runInBackground(func() {
+		defer dataExportsRunning.Delete(usr.GetID())
+
+		if err := sendDataExport(context.Background(), db, sessions, store, usr); err != nil {
+			log.Error("Failed to export the data of the user", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		}
+	})
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: "Your data is being prepared. You will receive an email with a download link shortly.",
+	})
+
+	c.Redirect(http.StatusSeeOther, paths.PathAccount)
+}
+
+func sendDataExport(ctx context.Context, db database.DatabaseInterface, sessions sessionstore.Backend, store storage.Storage, usr *user.User) error {
+	data, err := collectExportData(ctx, db, sessions, store, usr)
+
+	if err != nil {
+		return err
+	}
+
+	archive, err := data.Archive()
+
+	if err != nil {
+		return err
+	}
+
+	token, err := export.Save(db, usr.GetID(), archive)
+
+	if err != nil {
+		return err
+	}
+
+	return newEmailSender().SendMail(
+		viper.GetString("site.email"),
+		[]string{usr.GetEmail()},
+		fmt.Sprintf("Your %s data export is ready", viper.GetString("site.name")),
+		emailer.EmailBody{
+			Template: "email/data_export",
+			Data: map[string]any{
+				"Username":   usr.GetUsername(),
+				"Token":      token,
+				"ValidHours": int(export.TTL.Hours()),
+			},
+		},
+	)
+}
+
+func AccountExportDownload(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	archive, err := export.Find(db, usr.GetID(), v.GetFormValue(c.Request, "token"))
+
+	if err != nil {
+		if !errors.Is(err, export.ErrNotFound) {
+			log.Error("Could not find the data export", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+
+		v.SetFlash(message.Message{Type: message.MessageTypeError, Body: errDataExportNotFound})
+		c.Redirect(http.StatusSeeOther, paths.PathAccount)
+
+		return
+	}
+
+	auditEvent(c, audit.EventDataExportDownloaded, usr.GetID(), usr.GetID(), nil)
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, dataExportFilename))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", archive)
+}
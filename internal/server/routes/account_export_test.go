@@ -0,0 +1,279 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/export"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	pathAccountExport         = paths.PathAccount + "/export"
+	pathAccountExportDownload = pathAccountExport + "/download"
+)
+
+func useExportData(t *testing.T, data *export.Data, err error) {
+	collectExportDataOrig := collectExportData
+	runInBackgroundOrig := runInBackground
+
+	t.Cleanup(func() {
+		collectExportData = collectExportDataOrig
+		runInBackground = runInBackgroundOrig
+	})
+
+	collectExportData = func(context.Context, database.DatabaseInterface, sessionstore.Backend, storage.Storage, *user.User) (*export.Data, error) {
+		return data, err
+	}
+
+	runInBackground = func(task func()) { task() }
+}
+
+func TestAccountExportPost(t *testing.T) {
+	tests := []struct {
+		name       string
+		loggedIn   bool
+		recent     bool
+		recentErr  error
+		running    bool
+		collectErr error
+		saveErr    error
+		emailErr   error
+		wantStatus int
+		wantEmail  bool
+		wantEvents []audit.EventType
+	}{
+		{
+			name:       "not logged in",
+			wantStatus: http.StatusInternalServerError,
+			wantEvents: []audit.EventType{},
+		},
+		{
+			name:       "success",
+			loggedIn:   true,
+			wantStatus: http.StatusSeeOther,
+			wantEmail:  true,
+			wantEvents: []audit.EventType{audit.EventDataExportRequested},
+		},
+		{
+			name:       "recent export",
+			loggedIn:   true,
+			recent:     true,
+			wantStatus: http.StatusSeeOther,
+			wantEvents: []audit.EventType{},
+		},
+		{
+			name:       "export running",
+			loggedIn:   true,
+			running:    true,
+			wantStatus: http.StatusSeeOther,
+			wantEvents: []audit.EventType{},
+		},
+		{
+			name:       "recent export error",
+			loggedIn:   true,
+			recentErr:  errors.New("db fail"),
+			wantStatus: http.StatusInternalServerError,
+			wantEvents: []audit.EventType{},
+		},
+		{
+			name:       "collect error",
+			loggedIn:   true,
+			collectErr: errors.New("db fail"),
+			wantStatus: http.StatusSeeOther,
+			wantEvents: []audit.EventType{audit.EventDataExportRequested},
+		},
+		{
+			name:       "save error",
+			loggedIn:   true,
+			saveErr:    errors.New("db fail"),
+			wantStatus: http.StatusSeeOther,
+			wantEvents: []audit.EventType{audit.EventDataExportRequested},
+		},
+		{
+			name:       "email error",
+			loggedIn:   true,
+			emailErr:   errors.New("smtp fail"),
+			wantStatus: http.StatusSeeOther,
+			wantEmail:  true,
+			wantEvents: []audit.EventType{audit.EventDataExportRequested},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useExportData(t, &export.Data{User: export.Account{ID: 1}}, tt.collectErr)
+			patchSessionBackend(t, &fakeSessionBackend{})
+			auditLog := useRecordingAuditLog(t)
+			sender := useRecordingEmailSender(t)
+			sender.err = tt.emailErr
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tt.running {
+				dataExportsRunning.Store(1, true)
+				t.Cleanup(func() { dataExportsRunning.Delete(1) })
+			}
+
+			if tt.loggedIn {
+				router.Use(setSessionUserID(1))
+				expectSessionUser(mock, "")
+
+				expectation := mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM user_data_exports`).WithArgs(1, sqlmock.AnyArg())
+
+				if tt.recentErr != nil {
+					expectation.WillReturnError(tt.recentErr)
+				} else {
+					expectation.WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tt.recent))
+				}
+			}
+
+			if len(tt.wantEvents) > 0 && tt.collectErr == nil {
+				expectation := mock.ExpectExec("INSERT INTO user_data_exports").
+					WithArgs(1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg())
+
+				if tt.saveErr != nil {
+					expectation.WillReturnError(tt.saveErr)
+				} else {
+					expectation.WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+			}
+
+			router.POST(pathAccountExport, AccountExportPost)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, pathAccountExport, strings.NewReader(""))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tt.wantEvents, auditLog.types())
+
+			_, running := dataExportsRunning.Load(1)
+			assert.Equal(t, tt.running, running)
+
+			if tt.wantStatus == http.StatusSeeOther {
+				assert.Equal(t, paths.PathAccount, w.Header().Get("Location"))
+			}
+
+			if !tt.wantEmail {
+				assert.Empty(t, sender.sent)
+				return
+			}
+
+			assert.Len(t, sender.sent, 1)
+			assert.Equal(t, "email/data_export", sender.sent[0].Template)
+			assert.NotEmpty(t, sender.sent[0].Data["Token"])
+			assert.Equal(t, []string{"test@example.com"}, sender.to[0])
+		})
+	}
+}
+
+func TestRunRecovered(t *testing.T) {
+	ran := false
+
+	assert.NotPanics(t, func() {
+		runRecovered(func() {
+			ran = true
+			panic("export failed")
+		})
+	})
+
+	assert.True(t, ran)
+}
+
+func TestAccountExportDownload(t *testing.T) {
+	tests := []struct {
+		name       string
+		loggedIn   bool
+		setupMock  func(mock sqlmock.Sqlmock)
+		wantStatus int
+		wantBody   string
+		wantEvents []audit.EventType
+	}{
+		{
+			name:       "not logged in",
+			setupMock:  func(mock sqlmock.Sqlmock) {},
+			wantStatus: http.StatusInternalServerError,
+			wantEvents: []audit.EventType{},
+		},
+		{
+			name:     "success",
+			loggedIn: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT archive FROM user_data_exports").
+					WithArgs(1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"archive"}).AddRow([]byte("archive")))
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "archive",
+			wantEvents: []audit.EventType{audit.EventDataExportDownloaded},
+		},
+		{
+			name:     "not found",
+			loggedIn: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT archive FROM user_data_exports").WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus: http.StatusSeeOther,
+			wantEvents: []audit.EventType{},
+		},
+		{
+			name:     "database error",
+			loggedIn: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT archive FROM user_data_exports").WillReturnError(errors.New("db fail"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantEvents: []audit.EventType{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditLog := useRecordingAuditLog(t)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tt.loggedIn {
+				router.Use(setSessionUserID(1))
+				expectSessionUser(mock, "")
+			}
+
+			tt.setupMock(mock)
+			router.GET(pathAccountExportDownload, AccountExportDownload)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, pathAccountExportDownload+"?token=token", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tt.wantEvents, auditLog.types())
+
+			switch tt.wantStatus {
+			case http.StatusOK:
+				assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+				assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+				assert.Equal(t, tt.wantBody, w.Body.String())
+			case http.StatusSeeOther:
+				assert.Equal(t, paths.PathAccount, w.Header().Get("Location"))
+			}
+		})
+	}
+}
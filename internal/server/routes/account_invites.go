@@ -0,0 +1,248 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	emailer "github.com/Dobefu/go-web-starter/internal/email"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const errInviteRoleForbidden = "You are not allowed to grant roles"
+
+var (
+	createInvite      = user.CreateInvite
+	listRoles         = user.ListRoles
+	userHasPermission = user.HasPermissionByID
+)
+
+func invitableRoles(db database.DatabaseInterface, usr *user.User) ([]string, error) {
+	canManageRoles, err := userHasPermission(db, usr.GetID(), user.PermissionRolesManage)
+
+	if err != nil || !canManageRoles {
+		return nil, err
+	}
+
+	return listRoles(db)
+}
+
+func accountInvitesData(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface) (RouteData, error) {
+	invites, err := usr.GetInvites(db)
+
+	if err != nil {
+		return RouteData{}, err
+	}
+
+	roles, err := invitableRoles(db, usr)
+
+	if err != nil {
+		return RouteData{}, err
+	}
+
+	return RouteData{
+		Template:    "pages/account_invites",
+		Title:       "Invitations",
+		Description: "Invite people to create an account.",
+		HttpStatus:  http.StatusOK,
+		Data: map[string]any{
+			"Invites":   invites,
+			"Roles":     roles,
+			"ValidDays": int(user.InviteTTL.Hours() / 24),
+		},
+		FormData: FormData{
+			Values: v.GetFormData(),
+			Errors: v.GetSessionErrors(),
+		},
+		CSRFToken: middleware.GetCSRFToken(c),
+	}, nil
+}
+
+func AccountInvites(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	data, err := accountInvitesData(c, v, usr, db)
+
+	if err != nil {
+		log.Error("Could not get the invitations", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	RenderRouteHTML(c, data)
+
+	v.ClearSession()
+}
+
+func AccountInvitesPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	path := fmt.Sprintf("%s/invites", paths.PathAccount)
+	err := v.ValidateForm(c.Request)
+
+	if err != nil {
+		log.Error("Failed to parse form data", logger.Fields{"error": err.Error()})
+	}
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	switch v.GetFormValue(c.Request, "action") {
+	case "invite":
+		accountInvitesSend(c, v, usr, db, path)
+
+	case "revoke":
+		accountInvitesRevoke(c, v, usr, db, path)
+
+	default:
+		route_utils.RedirectWithError(c, v, nil, "Unknown action", path)
+	}
+}
+
+func accountInvitesSend(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface, path string) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+
+	email := strings.TrimSpace(v.GetFormValue(c.Request, "email"))
+	role := v.GetFormValue(c.Request, "role")
+
+	v.Required("email", email)
+	v.ValidEmail("email", email)
+
+	if _, err := findByEmail(db, email); err == nil {
+		v.AddFieldError("email", "Someone with this email address already has an account")
+	}
+
+	if role != "" {
+		roles, err := invitableRoles(db, usr)
+
+		if err != nil {
+			log.Error("Could not get the roles that can be granted", logger.Fields{"error": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+
+		if roles == nil {
+			v.AddFieldError("role", errInviteRoleForbidden)
+		}
+	}
+
+	if v.HasErrors() {
+		route_utils.RedirectWithError(c, v, map[string]string{"email": email, "role": role}, "Please correct the errors below", path)
+		return
+	}
+
+	token, err := createInvite(db, usr.GetID(), email, role)
+
+	if err != nil {
+		if errors.Is(err, user.ErrRoleNotFound) {
+			v.AddFieldError("role", err.Error())
+			route_utils.RedirectWithError(c, v, map[string]string{"email": email, "role": role}, "Please correct the errors below", path)
+
+			return
+		}
+
+		log.Error("Could not create the invitation", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if err = sendInviteEmail(usr, email, token); err != nil {
+		log.Error("Failed to send the invitation email", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Invitation sent", logger.Fields{"userID": usr.GetID(), "role": role})
+	auditEvent(c, audit.EventInviteSent, usr.GetID(), 0, audit.Metadata{"email": email, "role": role})
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: fmt.Sprintf("An invitation has been sent to %s.", email),
+	})
+
+	c.Redirect(http.StatusSeeOther, path)
+}
+
+func sendInviteEmail(inviter *user.User, email string, token string) error {
+	return newEmailSender().SendMail(
+		viper.GetString("site.email"),
+		[]string{email},
+		fmt.Sprintf("%s has invited you to %s", inviter.GetUsername(), viper.GetString("site.name")),
+		emailer.EmailBody{
+			Template: "email/invite",
+			Data: map[string]any{
+				"Inviter":   inviter.GetUsername(),
+				"Token":     token,
+				"ValidDays": int(user.InviteTTL.Hours() / 24),
+			},
+		},
+	)
+}
+
+func accountInvitesRevoke(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface, path string) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	id, err := strconv.Atoi(v.GetFormValue(c.Request, "id"))
+
+	if err != nil {
+		route_utils.RedirectWithError(c, v, nil, user.ErrInviteNotFound.Error(), path)
+		return
+	}
+
+	err = usr.RevokeInvite(db, id)
+
+	if err != nil {
+		if errors.Is(err, user.ErrInviteNotFound) {
+			route_utils.RedirectWithError(c, v, nil, user.ErrInviteNotFound.Error(), path)
+			return
+		}
+
+		log.Error("Could not revoke the invitation", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Invitation revoked", logger.Fields{"userID": usr.GetID()})
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: "The invitation has been revoked."})
+	c.Redirect(http.StatusSeeOther, path)
+}
@@ -0,0 +1,309 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	pathAccountInvites = paths.PathAccount + "/invites"
+
+	inviteColumns = []string{"id", "email", "role", "inviter_user_id", "created_at", "expires_at"}
+)
+
+type inviteStubs struct {
+	canManageRoles bool
+	permissionErr  error
+	createErr      error
+	created        []string
+}
+
+func useInviteStubs(t *testing.T, stubs *inviteStubs) {
+	createInviteOrig := createInvite
+	listRolesOrig := listRoles
+	userHasPermissionOrig := userHasPermission
+	findByEmailOrig := findByEmail
+
+	t.Cleanup(func() {
+		createInvite = createInviteOrig
+		listRoles = listRolesOrig
+		userHasPermission = userHasPermissionOrig
+		findByEmail = findByEmailOrig
+	})
+
+	createInvite = func(_ database.DatabaseInterface, _ int, email string, role string) (string, error) {
+		stubs.created = append(stubs.created, email+":"+role)
+		return "invite-token", stubs.createErr
+	}
+
+	listRoles = func(database.DatabaseInterface) ([]string, error) {
+		return []string{user.RoleAdmin, "editor"}, nil
+	}
+
+	userHasPermission = func(database.DatabaseInterface, int, string) (bool, error) {
+		return stubs.canManageRoles, stubs.permissionErr
+	}
+
+	findByEmail = func(_ database.DatabaseInterface, email string) (*user.User, error) {
+		if email == "taken@example.com" {
+			return &user.User{}, nil
+		}
+
+		return nil, user.ErrInvalidCredentials
+	}
+}
+
+func expectInvites(mock sqlmock.Sqlmock) {
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT (.+) FROM user_invites`).
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(inviteColumns).AddRow(2, "friend@example.com", "editor", 1, now, now.Add(user.InviteTTL)))
+}
+
+func TestAccountInvites(t *testing.T) {
+	tests := []struct {
+		name            string
+		stubs           inviteStubs
+		setupMock       func(mock sqlmock.Sqlmock)
+		expectStatus    int
+		expectBody      []string
+		expectNotInBody string
+	}{
+		{
+			name: "success",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				expectInvites(mock)
+			},
+			expectStatus:    http.StatusOK,
+			expectBody:      []string{"friend@example.com", "Role: editor"},
+			expectNotInBody: "invite-role",
+		},
+		{
+			name:  "with roles",
+			stubs: inviteStubs{canManageRoles: true},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				expectInvites(mock)
+			},
+			expectStatus: http.StatusOK,
+			expectBody:   []string{"invite-role", "editor"},
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectQuery(`SELECT (.+) FROM user_invites`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:  "permission error",
+			stubs: inviteStubs{permissionErr: errors.New("db fail")},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				expectInvites(mock)
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useInviteStubs(t, &tc.stubs)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			tc.setupMock(mock)
+
+			router.Use(setSessionUserID(1))
+			router.GET(pathAccountInvites, AccountInvites)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", pathAccountInvites, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+
+			for _, body := range tc.expectBody {
+				assert.Contains(t, w.Body.String(), body)
+			}
+
+			if tc.expectNotInBody != "" {
+				assert.NotContains(t, w.Body.String(), tc.expectNotInBody)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAccountInvitesPost(t *testing.T) {
+	tests := []struct {
+		name          string
+		form          url.Values
+		stubs         inviteStubs
+		emailErr      error
+		setupMock     func(mock sqlmock.Sqlmock)
+		expectStatus  int
+		expectCreated []string
+		expectEmails  int
+		expectEvents  []audit.EventType
+	}{
+		{
+			name:          "invite",
+			form:          url.Values{"action": {"invite"}, "email": {" friend@example.com "}},
+			expectStatus:  http.StatusSeeOther,
+			expectCreated: []string{"friend@example.com:"},
+			expectEmails:  1,
+			expectEvents:  []audit.EventType{audit.EventInviteSent},
+		},
+		{
+			name:          "invite with a role",
+			form:          url.Values{"action": {"invite"}, "email": {"friend@example.com"}, "role": {"editor"}},
+			stubs:         inviteStubs{canManageRoles: true},
+			expectStatus:  http.StatusSeeOther,
+			expectCreated: []string{"friend@example.com:editor"},
+			expectEmails:  1,
+			expectEvents:  []audit.EventType{audit.EventInviteSent},
+		},
+		{
+			name:         "invite with a role without permission",
+			form:         url.Values{"action": {"invite"}, "email": {"friend@example.com"}, "role": {"admin"}},
+			expectStatus: http.StatusSeeOther,
+		},
+		{
+			name:         "invite with a permission error",
+			form:         url.Values{"action": {"invite"}, "email": {"friend@example.com"}, "role": {"admin"}},
+			stubs:        inviteStubs{permissionErr: errors.New("db fail")},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:          "invite with an unknown role",
+			form:          url.Values{"action": {"invite"}, "email": {"friend@example.com"}, "role": {"unknown"}},
+			stubs:         inviteStubs{canManageRoles: true, createErr: user.ErrRoleNotFound},
+			expectStatus:  http.StatusSeeOther,
+			expectCreated: []string{"friend@example.com:unknown"},
+		},
+		{
+			name:         "invite with an invalid email address",
+			form:         url.Values{"action": {"invite"}, "email": {"friend"}},
+			expectStatus: http.StatusSeeOther,
+		},
+		{
+			name:         "invite someone with an account",
+			form:         url.Values{"action": {"invite"}, "email": {"taken@example.com"}},
+			expectStatus: http.StatusSeeOther,
+		},
+		{
+			name:          "invite database error",
+			form:          url.Values{"action": {"invite"}, "email": {"friend@example.com"}},
+			stubs:         inviteStubs{createErr: errors.New("db fail")},
+			expectStatus:  http.StatusInternalServerError,
+			expectCreated: []string{"friend@example.com:"},
+		},
+		{
+			name:          "invite email error",
+			form:          url.Values{"action": {"invite"}, "email": {"friend@example.com"}},
+			emailErr:      errors.New("smtp fail"),
+			expectStatus:  http.StatusInternalServerError,
+			expectCreated: []string{"friend@example.com:"},
+			expectEmails:  1,
+		},
+		{
+			name: "revoke",
+			form: url.Values{"action": {"revoke"}, "id": {"2"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM user_invites`).
+					WithArgs(2, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus: http.StatusSeeOther,
+		},
+		{
+			name:         "revoke with an invalid ID",
+			form:         url.Values{"action": {"revoke"}, "id": {"abc"}},
+			expectStatus: http.StatusSeeOther,
+		},
+		{
+			name: "revoke someone else's invitation",
+			form: url.Values{"action": {"revoke"}, "id": {"3"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM user_invites`).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectStatus: http.StatusSeeOther,
+		},
+		{
+			name: "revoke database error",
+			form: url.Values{"action": {"revoke"}, "id": {"2"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM user_invites`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "unknown action",
+			form:         url.Values{"action": {"other"}},
+			expectStatus: http.StatusSeeOther,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useInviteStubs(t, &tc.stubs)
+
+			sender := useRecordingEmailSender(t)
+			sender.err = tc.emailErr
+			auditLog := useRecordingAuditLog(t)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			expectSessionUser(mock, "")
+
+			if tc.setupMock != nil {
+				tc.setupMock(mock)
+			}
+
+			router.Use(setSessionUserID(1))
+			router.POST(pathAccountInvites, AccountInvitesPost)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", pathAccountInvites, strings.NewReader(tc.form.Encode()))
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+
+			if tc.expectStatus == http.StatusSeeOther {
+				assert.Equal(t, pathAccountInvites, w.Header().Get("Location"))
+			}
+
+			assert.Equal(t, tc.expectCreated, tc.stubs.created)
+			assert.Len(t, sender.sent, tc.expectEmails)
+
+			if tc.expectEmails > 0 {
+				assert.Equal(t, "email/invite", sender.sent[0].Template)
+				assert.Equal(t, "invite-token", sender.sent[0].Data["Token"])
+				assert.Equal(t, []string{"friend@example.com"}, sender.to[0])
+			}
+
+			assert.Equal(t, append([]audit.EventType{}, tc.expectEvents...), auditLog.types())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,765 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	emailer "github.com/Dobefu/go-web-starter/internal/email"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/organization"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	errOrganizationForbidden     = "You are not allowed to do this in this organization."
+	errOrganizationLeaveInstead  = "You cannot remove yourself. Leave the organization instead."
+	errOrganizationDeleteConfirm = "Please enter the name of the organization to confirm."
+)
+
+var pathAccountOrganizations = fmt.Sprintf("%s/organizations", paths.PathAccount)
+
+var (
+	createOrganization       = organization.Create
+	findMemberships          = organization.FindMemberships
+	findMembership           = organization.FindMembership
+	findOrganizationInvites  = organization.FindInvitesForEmail
+	acceptOrganizationInvite = organization.AcceptInvite
+)
+
+func organizationPath(id int) string {
+	return fmt.Sprintf("%s/%d", pathAccountOrganizations, id)
+}
+
+func organizationMetadata(org *organization.Organization) audit.Metadata {
+	return audit.Metadata{"organizationID": org.GetID(), "organization": org.GetName()}
+}
+
+func setActiveOrganization(c *gin.Context, id int) error {
+	session := getSession(c)
+	session.Set(route_utils.SessionKeyOrganizationID, id)
+
+	return session.Save()
+}
+
+func clearActiveOrganization(c *gin.Context, id int) {
+	session := getSession(c)
+
+	if activeID, _ := session.Get(route_utils.SessionKeyOrganizationID).(int); activeID == id {
+		session.Delete(route_utils.SessionKeyOrganizationID)
+		_ = session.Save()
+	}
+}
+
+func accountOrganizationsData(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface) (RouteData, error) {
+	memberships, err := findMemberships(db, usr.GetID())
+
+	if err != nil {
+		return RouteData{}, err
+	}
+
+	invites, err := findOrganizationInvites(db, usr.GetEmail())
+
+	if err != nil {
+		return RouteData{}, err
+	}
+
+	activeID := 0
+
+	if active := route_utils.ActiveOrganization(c, memberships); active != nil {
+		activeID = active.Organization.GetID()
+	}
+
+	return RouteData{
+		Template:    "pages/account_organizations",
+		Title:       "Organizations",
+		Description: "Manage the organizations that you belong to.",
+		HttpStatus:  http.StatusOK,
+		Data: map[string]any{
+			"Memberships": memberships,
+			"Invites":     invites,
+			"ActiveID":    activeID,
+		},
+		FormData: FormData{
+			Values: v.GetFormData(),
+			Errors: v.GetSessionErrors(),
+		},
+		CSRFToken: middleware.GetCSRFToken(c),
+	}, nil
+}
+
+func AccountOrganizations(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	data, err := accountOrganizationsData(c, v, usr, db)
+
+	if err != nil {
+		log.Error("Could not get the organizations", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	RenderRouteHTML(c, data)
+
+	v.ClearSession()
+}
+
+func AccountOrganizationsPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	err := v.ValidateForm(c.Request)
+
+	if err != nil {
+		log.Error("Failed to parse form data", logger.Fields{"error": err.Error()})
+	}
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	switch v.GetFormValue(c.Request, "action") {
+	case "create":
+		accountOrganizationsCreate(c, v, usr, db)
+
+	case "switch":
+		accountOrganizationsSwitch(c, v, usr, db)
+
+	case "accept":
+		accountOrganizationsAccept(c, v, usr, db)
+
+	case "decline":
+		accountOrganizationsDecline(c, v, usr, db)
+
+	default:
+		route_utils.RedirectWithError(c, v, nil, "Unknown action", pathAccountOrganizations)
+	}
+}
+
+func accountOrganizationsCreate(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	name := strings.TrimSpace(v.GetFormValue(c.Request, "name"))
+
+	v.Required("name", name)
+
+	if v.HasErrors() {
+		route_utils.RedirectWithError(c, v, map[string]string{"name": name}, "Please correct the errors below", pathAccountOrganizations)
+		return
+	}
+
+	org, err := createOrganization(db, usr.GetID(), name)
+
+	if err != nil {
+		if errors.Is(err, organization.ErrInvalidName) ||
+			errors.Is(err, organization.ErrNameTooLong) ||
+			errors.Is(err, organization.ErrOrganizationExists) {
+			v.AddFieldError("name", err.Error())
+			route_utils.RedirectWithError(c, v, map[string]string{"name": name}, "Please correct the errors below", pathAccountOrganizations)
+
+			return
+		}
+
+		log.Error("Could not create the organization", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Organization created", logger.Fields{"userID": usr.GetID(), "organizationID": org.GetID()})
+	auditEvent(c, audit.EventOrganizationCreated, usr.GetID(), 0, organizationMetadata(org))
+
+	if err = setActiveOrganization(c, org.GetID()); err != nil {
+		log.Error("Failed to switch to the new organization", logger.Fields{"organizationID": org.GetID(), "error": err.Error()})
+	}
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: fmt.Sprintf("%s has been created.", org.GetName()),
+	})
+
+	c.Redirect(http.StatusSeeOther, organizationPath(org.GetID()))
+}
+
+func accountOrganizationsSwitch(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	id, err := strconv.Atoi(v.GetFormValue(c.Request, "id"))
+
+	if err != nil {
+		route_utils.RedirectWithError(c, v, nil, organization.ErrNotMember.Error(), pathAccountOrganizations)
+		return
+	}
+
+	membership, err := findMembership(db, id, usr.GetID())
+
+	if err != nil {
+		if errors.Is(err, organization.ErrNotMember) {
+			route_utils.RedirectWithError(c, v, nil, organization.ErrNotMember.Error(), pathAccountOrganizations)
+			return
+		}
+
+		log.Error("Could not find the organization", logger.Fields{"organizationID": id, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if err = setActiveOrganization(c, id); err != nil {
+		log.Error("Failed to save the session", logger.Fields{"organizationID": id, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: fmt.Sprintf("You are now working in %s.", membership.Organization.GetName()),
+	})
+
+	c.Redirect(http.StatusSeeOther, pathAccountOrganizations)
+}
+
+func accountOrganizationsAccept(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	id, err := strconv.Atoi(v.GetFormValue(c.Request, "id"))
+
+	if err != nil {
+		route_utils.RedirectWithError(c, v, nil, organization.ErrInviteNotFound.Error(), pathAccountOrganizations)
+		return
+	}
+
+	organizationID, err := acceptOrganizationInvite(db, id, usr.GetID(), usr.GetEmail())
+
+	if err != nil {
+		if errors.Is(err, organization.ErrInviteNotFound) {
+			route_utils.RedirectWithError(c, v, nil, organization.ErrInviteNotFound.Error(), pathAccountOrganizations)
+			return
+		}
+
+		log.Error("Could not accept the invitation", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Organization joined", logger.Fields{"userID": usr.GetID(), "organizationID": organizationID})
+	auditEvent(c, audit.EventOrganizationJoined, usr.GetID(), 0, audit.Metadata{"organizationID": organizationID})
+
+	if err = setActiveOrganization(c, organizationID); err != nil {
+		log.Error("Failed to switch to the joined organization", logger.Fields{"organizationID": organizationID, "error": err.Error()})
+	}
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: "You have joined the organization."})
+	c.Redirect(http.StatusSeeOther, organizationPath(organizationID))
+}
+
+func accountOrganizationsDecline(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	id, err := strconv.Atoi(v.GetFormValue(c.Request, "id"))
+
+	if err != nil {
+		route_utils.RedirectWithError(c, v, nil, organization.ErrInviteNotFound.Error(), pathAccountOrganizations)
+		return
+	}
+
+	err = organization.DeclineInvite(db, id, usr.GetEmail())
+
+	if err != nil {
+		if errors.Is(err, organization.ErrInviteNotFound) {
+			route_utils.RedirectWithError(c, v, nil, organization.ErrInviteNotFound.Error(), pathAccountOrganizations)
+			return
+		}
+
+		log.Error("Could not decline the invitation", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: "The invitation has been declined."})
+	c.Redirect(http.StatusSeeOther, pathAccountOrganizations)
+}
+
+func getOrganizationMembership(c *gin.Context, db database.DatabaseInterface, usr *user.User) *organization.Membership {
+	id, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil || id < 1 {
+		NotFound(c)
+		return nil
+	}
+
+	membership, err := findMembership(db, id, usr.GetID())
+
+	if err != nil {
+		if errors.Is(err, organization.ErrNotMember) {
+			NotFound(c)
+			return nil
+		}
+
+		log := logger.New(config.GetLogLevel(), os.Stdout)
+		log.Error("Could not find the organization", logger.Fields{"organizationID": id, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return nil
+	}
+
+	return membership
+}
+
+func assignableRoles(membership *organization.Membership) []string {
+	roles := []string{}
+
+	for _, role := range organization.Roles {
+		if membership.CanAssignRole(role) {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles
+}
+
+func AccountOrganization(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	membership := getOrganizationMembership(c, db, usr)
+
+	if membership == nil {
+		return
+	}
+
+	org := membership.Organization
+	members, err := org.GetMembers(db)
+
+	if err != nil {
+		log.Error("Could not get the members of the organization", logger.Fields{"organizationID": org.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	invites := []organization.Invite{}
+
+	if membership.CanManageMembers() {
+		invites, err = org.GetInvites(db)
+
+		if err != nil {
+			log.Error("Could not get the invitations of the organization", logger.Fields{"organizationID": org.GetID(), "error": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+	}
+
+	activeID, _ := getSession(c).Get(route_utils.SessionKeyOrganizationID).(int)
+
+	RenderRouteHTML(c, RouteData{
+		Template:    "pages/account_organization",
+		Title:       org.GetName(),
+		Description: "Manage the members of the organization.",
+		HttpStatus:  http.StatusOK,
+		Data: map[string]any{
+			"Membership": membership,
+			"Members":    members,
+			"Invites":    invites,
+			"Roles":      assignableRoles(membership),
+			"IsActive":   activeID == org.GetID(),
+			"UserID":     usr.GetID(),
+			"ValidDays":  int(organization.InviteTTL.Hours() / 24),
+		},
+		FormData: FormData{
+			Values: v.GetFormData(),
+			Errors: v.GetSessionErrors(),
+		},
+		CSRFToken: middleware.GetCSRFToken(c),
+	})
+
+	v.ClearSession()
+}
+
+func AccountOrganizationPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	err := v.ValidateForm(c.Request)
+
+	if err != nil {
+		log.Error("Failed to parse form data", logger.Fields{"error": err.Error()})
+	}
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	membership := getOrganizationMembership(c, db, usr)
+
+	if membership == nil {
+		return
+	}
+
+	path := organizationPath(membership.Organization.GetID())
+	action := v.GetFormValue(c.Request, "action")
+
+	switch action {
+	case "invite", "revoke", "role", "remove":
+		if !membership.CanManageMembers() {
+			route_utils.RedirectWithError(c, v, nil, errOrganizationForbidden, path)
+			return
+		}
+
+	case "delete":
+		if !membership.IsOwner() {
+			route_utils.RedirectWithError(c, v, nil, errOrganizationForbidden, path)
+			return
+		}
+	}
+
+	switch action {
+	case "invite":
+		accountOrganizationInvite(c, v, usr, db, membership, path)
+
+	case "revoke":
+		accountOrganizationRevoke(c, v, db, membership, path)
+
+	case "role":
+		accountOrganizationRole(c, v, usr, db, membership, path)
+
+	case "remove":
+		accountOrganizationRemove(c, v, usr, db, membership, path)
+
+	case "leave":
+		accountOrganizationLeave(c, v, usr, db, membership, path)
+
+	case "delete":
+		accountOrganizationDelete(c, v, usr, db, membership, path)
+
+	default:
+		route_utils.RedirectWithError(c, v, nil, "Unknown action", path)
+	}
+}
+
+func accountOrganizationInvite(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface, membership *organization.Membership, path string) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	org := membership.Organization
+
+	email := strings.TrimSpace(v.GetFormValue(c.Request, "email"))
+	role := v.GetFormValue(c.Request, "role")
+
+	v.Required("email", email)
+	v.ValidEmail("email", email)
+
+	if !membership.CanAssignRole(role) {
+		v.AddFieldError("role", errOrganizationForbidden)
+	}
+
+	if v.HasErrors() {
+		route_utils.RedirectWithError(c, v, map[string]string{"email": email, "role": role}, "Please correct the errors below", path)
+		return
+	}
+
+	err := org.CreateInvite(db, usr.GetID(), email, role)
+
+	if err != nil {
+		if errors.Is(err, organization.ErrAlreadyMember) || errors.Is(err, organization.ErrInvalidRole) {
+			field := "email"
+
+			if errors.Is(err, organization.ErrInvalidRole) {
+				field = "role"
+			}
+
+			v.AddFieldError(field, err.Error())
+			route_utils.RedirectWithError(c, v, map[string]string{"email": email, "role": role}, "Please correct the errors below", path)
+
+			return
+		}
+
+		log.Error("Could not create the invitation", logger.Fields{"organizationID": org.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if err = sendOrganizationInviteEmail(usr, org, email, role); err != nil {
+		log.Error("Failed to send the invitation email", logger.Fields{"organizationID": org.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Organization invitation sent", logger.Fields{"userID": usr.GetID(), "organizationID": org.GetID(), "role": role})
+
+	metadata := organizationMetadata(org)
+	metadata["email"] = email
+	metadata["role"] = role
+	auditEvent(c, audit.EventOrganizationInvited, usr.GetID(), 0, metadata)
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: fmt.Sprintf("An invitation has been sent to %s.", email),
+	})
+
+	c.Redirect(http.StatusSeeOther, path)
+}
+
+func sendOrganizationInviteEmail(inviter *user.User, org *organization.Organization, email string, role string) error {
+	return newEmailSender().SendMail(
+		viper.GetString("site.email"),
+		[]string{email},
+		fmt.Sprintf("%s has invited you to join %s", inviter.GetUsername(), org.GetName()),
+		emailer.EmailBody{
+			Template: "email/organization_invite",
+			Data: map[string]any{
+				"Inviter":      inviter.GetUsername(),
+				"Organization": org.GetName(),
+				"Role":         role,
+				"ValidDays":    int(organization.InviteTTL.Hours() / 24),
+			},
+		},
+	)
+}
+
+func accountOrganizationRevoke(c *gin.Context, v *validator.Validator, db database.DatabaseInterface, membership *organization.Membership, path string) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	id, err := strconv.Atoi(v.GetFormValue(c.Request, "id"))
+
+	if err != nil {
+		route_utils.RedirectWithError(c, v, nil, organization.ErrInviteNotFound.Error(), path)
+		return
+	}
+
+	err = membership.Organization.RevokeInvite(db, id)
+
+	if err != nil {
+		if errors.Is(err, organization.ErrInviteNotFound) {
+			route_utils.RedirectWithError(c, v, nil, organization.ErrInviteNotFound.Error(), path)
+			return
+		}
+
+		log.Error("Could not revoke the invitation", logger.Fields{"organizationID": membership.Organization.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: "The invitation has been revoked."})
+	c.Redirect(http.StatusSeeOther, path)
+}
+
+func organizationTarget(c *gin.Context, v *validator.Validator, db database.DatabaseInterface, membership *organization.Membership, path string) (int, bool) {
+	userID, err := strconv.Atoi(v.GetFormValue(c.Request, "user_id"))
+
+	if err != nil {
+		route_utils.RedirectWithError(c, v, nil, organization.ErrNotMember.Error(), path)
+		return 0, false
+	}
+
+	target, err := findMembership(db, membership.Organization.GetID(), userID)
+
+	if err != nil {
+		if errors.Is(err, organization.ErrNotMember) {
+			route_utils.RedirectWithError(c, v, nil, organization.ErrNotMember.Error(), path)
+			return 0, false
+		}
+
+		log := logger.New(config.GetLogLevel(), os.Stdout)
+		log.Error("Could not find the member", logger.Fields{"organizationID": membership.Organization.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return 0, false
+	}
+
+	if !membership.CanAssignRole(target.Role) {
+		route_utils.RedirectWithError(c, v, nil, errOrganizationForbidden, path)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+func redirectMemberError(c *gin.Context, v *validator.Validator, err error, path string) bool {
+	if errors.Is(err, organization.ErrLastOwner) ||
+		errors.Is(err, organization.ErrInvalidRole) ||
+		errors.Is(err, organization.ErrNotMember) {
+		route_utils.RedirectWithError(c, v, nil, err.Error(), path)
+		return true
+	}
+
+	return false
+}
+
+func accountOrganizationRole(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface, membership *organization.Membership, path string) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	org := membership.Organization
+	role := v.GetFormValue(c.Request, "role")
+
+	userID, ok := organizationTarget(c, v, db, membership, path)
+
+	if !ok {
+		return
+	}
+
+	if !membership.CanAssignRole(role) {
+		route_utils.RedirectWithError(c, v, nil, errOrganizationForbidden, path)
+		return
+	}
+
+	if err := org.SetMemberRole(db, userID, role); err != nil {
+		if redirectMemberError(c, v, err, path) {
+			return
+		}
+
+		log.Error("Could not change the role of the member", logger.Fields{"organizationID": org.GetID(), "userID": userID, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Organization role changed", logger.Fields{"organizationID": org.GetID(), "userID": userID, "role": role})
+
+	metadata := organizationMetadata(org)
+	metadata["role"] = role
+	auditEvent(c, audit.EventOrganizationRole, usr.GetID(), userID, metadata)
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: "The role has been changed."})
+	c.Redirect(http.StatusSeeOther, path)
+}
+
+func accountOrganizationRemove(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface, membership *organization.Membership, path string) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	org := membership.Organization
+
+	if v.GetFormValue(c.Request, "user_id") == strconv.Itoa(usr.GetID()) {
+		route_utils.RedirectWithError(c, v, nil, errOrganizationLeaveInstead, path)
+		return
+	}
+
+	userID, ok := organizationTarget(c, v, db, membership, path)
+
+	if !ok {
+		return
+	}
+
+	if err := org.RemoveMember(db, userID); err != nil {
+		if redirectMemberError(c, v, err, path) {
+			return
+		}
+
+		log.Error("Could not remove the member", logger.Fields{"organizationID": org.GetID(), "userID": userID, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Organization member removed", logger.Fields{"organizationID": org.GetID(), "userID": userID})
+	auditEvent(c, audit.EventOrganizationRemoved, usr.GetID(), userID, organizationMetadata(org))
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: "The member has been removed."})
+	c.Redirect(http.StatusSeeOther, path)
+}
+
+func accountOrganizationLeave(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface, membership *organization.Membership, path string) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	org := membership.Organization
+
+	if err := org.RemoveMember(db, usr.GetID()); err != nil {
+		if redirectMemberError(c, v, err, path) {
+			return
+		}
+
+		log.Error("Could not leave the organization", logger.Fields{"organizationID": org.GetID(), "userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Organization left", logger.Fields{"organizationID": org.GetID(), "userID": usr.GetID()})
+	auditEvent(c, audit.EventOrganizationLeft, usr.GetID(), 0, organizationMetadata(org))
+	clearActiveOrganization(c, org.GetID())
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: fmt.Sprintf("You have left %s.", org.GetName()),
+	})
+
+	c.Redirect(http.StatusSeeOther, pathAccountOrganizations)
+}
+
+func accountOrganizationDelete(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface, membership *organization.Membership, path string) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	org := membership.Organization
+
+	if strings.TrimSpace(v.GetFormValue(c.Request, "confirm")) != org.GetName() {
+		v.AddFieldError("confirm", errOrganizationDeleteConfirm)
+		route_utils.RedirectWithError(c, v, nil, "Please correct the errors below", path)
+
+		return
+	}
+
+	if err := org.Delete(db); err != nil {
+		log.Error("Could not delete the organization", logger.Fields{"organizationID": org.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Organization deleted", logger.Fields{"organizationID": org.GetID(), "userID": usr.GetID()})
+	auditEvent(c, audit.EventOrganizationDeleted, usr.GetID(), 0, organizationMetadata(org))
+	clearActiveOrganization(c, org.GetID())
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: fmt.Sprintf("%s has been deleted.", org.GetName()),
+	})
+
+	c.Redirect(http.StatusSeeOther, pathAccountOrganizations)
+}
@@ -0,0 +1,802 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/organization"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const pathTestOrganization = "/account/organizations/3"
+
+type organizationStubs struct {
+	memberships []organization.Membership
+	role        string
+	targetRole  string
+	findErr     error
+	invites     []organization.Invite
+	invitesErr  error
+	createErr   error
+	acceptErr   error
+}
+
+func testMembership(id int, name string, role string) *organization.Membership {
+	return &organization.Membership{
+		Organization: organization.New(organization.OrganizationFields{Id: id, Name: name, Slug: organization.Slugify(name)}),
+		Role:         role,
+	}
+}
+
+// useOrganizationStubs replaces the lookups of organizations. The user of the
+// session has the role in organization 3, and every other user the target role.
+func useOrganizationStubs(t *testing.T, stubs *organizationStubs) {
+	createOrganizationOrig := createOrganization
+	findMembershipsOrig := findMemberships
+	findMembershipOrig := findMembership
+	findOrganizationInvitesOrig := findOrganizationInvites
+	acceptOrganizationInviteOrig := acceptOrganizationInvite
+
+	t.Cleanup(func() {
+		createOrganization = createOrganizationOrig
+		findMemberships = findMembershipsOrig
+		findMembership = findMembershipOrig
+		findOrganizationInvites = findOrganizationInvitesOrig
+		acceptOrganizationInvite = acceptOrganizationInviteOrig
+	})
+
+	createOrganization = func(_ database.DatabaseInterface, _ int, name string) (*organization.Organization, error) {
+		if stubs.createErr != nil {
+			return nil, stubs.createErr
+		}
+
+		return organization.New(organization.OrganizationFields{Id: 4, Name: name}), nil
+	}
+
+	findMemberships = func(database.DatabaseInterface, int) ([]organization.Membership, error) {
+		return stubs.memberships, stubs.findErr
+	}
+
+	findMembership = func(_ database.DatabaseInterface, organizationID int, userID int) (*organization.Membership, error) {
+		role := stubs.targetRole
+
+		if userID == 1 {
+			role = stubs.role
+		}
+
+		if stubs.findErr != nil {
+			return nil, stubs.findErr
+		}
+
+		if organizationID != 3 || role == "" {
+			return nil, organization.ErrNotMember
+		}
+
+		return testMembership(3, "Acme", role), nil
+	}
+
+	findOrganizationInvites = func(_ database.DatabaseInterface, email string) ([]organization.Invite, error) {
+		assert.Equal(t, "test@example.com", email)
+		return stubs.invites, stubs.invitesErr
+	}
+
+	acceptOrganizationInvite = func(_ database.DatabaseInterface, id int, userID int, email string) (int, error) {
+		if stubs.acceptErr != nil {
+			return 0, stubs.acceptErr
+		}
+
+		return 3, nil
+	}
+}
+
+func setActiveOrganizationID(id int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessions.Default(c).Set(route_utils.SessionKeyOrganizationID, id)
+
+		c.Next()
+	}
+}
+
+func sessionOrganization(router *gin.Engine, w *httptest.ResponseRecorder) string {
+	router.GET("/test/organization", func(c *gin.Context) {
+		c.String(http.StatusOK, "%v", sessions.Default(c).Get(route_utils.SessionKeyOrganizationID))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test/organization", nil)
+
+	if cookies := w.Result().Cookies(); len(cookies) > 0 {
+		req.AddCookie(cookies[len(cookies)-1])
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	return rec.Body.String()
+}
+
+func postForm(router *gin.Engine, path string, form url.Values) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestAccountOrganizations(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name            string
+		stubs           organizationStubs
+		expectStatus    int
+		expectBody      []string
+		expectNotInBody string
+	}{
+		{
+			name: "success",
+			stubs: organizationStubs{
+				memberships: []organization.Membership{
+					*testMembership(3, "Acme", organization.RoleOwner),
+					*testMembership(5, "Globex", organization.RoleMember),
+				},
+				invites: []organization.Invite{
+					{ID: 2, OrganizationID: 6, OrganizationName: "Initech", Role: organization.RoleAdmin, ExpiresAt: now},
+				},
+			},
+			expectStatus: http.StatusOK,
+			expectBody:   []string{"Acme", "Globex", "Active", "Role: member", "Initech", "Join as admin"},
+		},
+		{
+			name:            "no organizations",
+			stubs:           organizationStubs{memberships: []organization.Membership{}},
+			expectStatus:    http.StatusOK,
+			expectBody:      []string{"You do not belong to any organization yet"},
+			expectNotInBody: "Active",
+		},
+		{
+			name:         "database error",
+			stubs:        organizationStubs{findErr: errors.New("db fail")},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "invitations error",
+			stubs:        organizationStubs{invitesErr: errors.New("db fail")},
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useOrganizationStubs(t, &tc.stubs)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			expectSessionUser(mock, "")
+
+			router.Use(setSessionUserID(1))
+			router.GET(pathAccountOrganizations, AccountOrganizations)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", pathAccountOrganizations, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+
+			for _, body := range tc.expectBody {
+				assert.Contains(t, w.Body.String(), body)
+			}
+
+			if tc.expectNotInBody != "" {
+				assert.NotContains(t, w.Body.String(), tc.expectNotInBody)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAccountOrganizationsPost(t *testing.T) {
+	tests := []struct {
+		name               string
+		form               url.Values
+		stubs              organizationStubs
+		setupMock          func(mock sqlmock.Sqlmock)
+		expectStatus       int
+		expectLocation     string
+		expectEvents       []audit.EventType
+		expectOrganization string
+	}{
+		{
+			name:               "create",
+			form:               url.Values{"action": {"create"}, "name": {" Acme "}},
+			expectStatus:       http.StatusSeeOther,
+			expectLocation:     "/account/organizations/4",
+			expectEvents:       []audit.EventType{audit.EventOrganizationCreated},
+			expectOrganization: "4",
+		},
+		{
+			name:           "create without a name",
+			form:           url.Values{"action": {"create"}, "name": {" "}},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountOrganizations,
+		},
+		{
+			name:           "create with a taken name",
+			form:           url.Values{"action": {"create"}, "name": {"Acme"}},
+			stubs:          organizationStubs{createErr: organization.ErrOrganizationExists},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountOrganizations,
+		},
+		{
+			name:         "create database error",
+			form:         url.Values{"action": {"create"}, "name": {"Acme"}},
+			stubs:        organizationStubs{createErr: errors.New("db fail")},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:               "switch",
+			form:               url.Values{"action": {"switch"}, "id": {"3"}},
+			stubs:              organizationStubs{role: organization.RoleMember},
+			expectStatus:       http.StatusSeeOther,
+			expectLocation:     pathAccountOrganizations,
+			expectOrganization: "3",
+		},
+		{
+			name:               "switch to another organization",
+			form:               url.Values{"action": {"switch"}, "id": {"5"}},
+			stubs:              organizationStubs{role: organization.RoleMember},
+			expectStatus:       http.StatusSeeOther,
+			expectLocation:     pathAccountOrganizations,
+			expectOrganization: "<nil>",
+		},
+		{
+			name:           "switch with an invalid ID",
+			form:           url.Values{"action": {"switch"}, "id": {"abc"}},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountOrganizations,
+		},
+		{
+			name:         "switch database error",
+			form:         url.Values{"action": {"switch"}, "id": {"3"}},
+			stubs:        organizationStubs{findErr: errors.New("db fail")},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:               "accept",
+			form:               url.Values{"action": {"accept"}, "id": {"2"}},
+			expectStatus:       http.StatusSeeOther,
+			expectLocation:     pathTestOrganization,
+			expectEvents:       []audit.EventType{audit.EventOrganizationJoined},
+			expectOrganization: "3",
+		},
+		{
+			name:           "accept an unknown invitation",
+			form:           url.Values{"action": {"accept"}, "id": {"2"}},
+			stubs:          organizationStubs{acceptErr: organization.ErrInviteNotFound},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountOrganizations,
+		},
+		{
+			name:         "accept database error",
+			form:         url.Values{"action": {"accept"}, "id": {"2"}},
+			stubs:        organizationStubs{acceptErr: errors.New("db fail")},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "decline",
+			form: url.Values{"action": {"decline"}, "id": {"2"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM organization_invites`).
+					WithArgs(2, "test@example.com").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountOrganizations,
+		},
+		{
+			name: "decline someone else's invitation",
+			form: url.Values{"action": {"decline"}, "id": {"2"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM organization_invites`).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountOrganizations,
+		},
+		{
+			name: "decline database error",
+			form: url.Values{"action": {"decline"}, "id": {"2"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM organization_invites`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "unknown action",
+			form:           url.Values{"action": {"other"}},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountOrganizations,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useOrganizationStubs(t, &tc.stubs)
+			auditLog := useRecordingAuditLog(t)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			expectSessionUser(mock, "")
+
+			if tc.setupMock != nil {
+				tc.setupMock(mock)
+			}
+
+			router.Use(setSessionUserID(1))
+			router.POST(pathAccountOrganizations, AccountOrganizationsPost)
+
+			w := postForm(router, pathAccountOrganizations, tc.form)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.Equal(t, tc.expectLocation, w.Header().Get("Location"))
+			assert.Equal(t, append([]audit.EventType{}, tc.expectEvents...), auditLog.types())
+
+			if tc.expectOrganization != "" {
+				sessionRouter, _, sessionDB := setupTestRouterWithMocks(t, false)
+				defer func() { _ = sessionDB.Close() }()
+
+				assert.Equal(t, tc.expectOrganization, sessionOrganization(sessionRouter, w))
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func expectOrganizationMembers(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT (.+) FROM organization_members m JOIN users u`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "role", "created_at"}).
+			AddRow(1, "username", "test@example.com", organization.RoleOwner, time.Now()).
+			AddRow(2, "colleague", "colleague@example.com", organization.RoleMember, time.Now()))
+}
+
+func TestAccountOrganization(t *testing.T) {
+	tests := []struct {
+		name            string
+		path            string
+		stubs           organizationStubs
+		setupMock       func(mock sqlmock.Sqlmock)
+		expectStatus    int
+		expectBody      []string
+		expectNotInBody []string
+	}{
+		{
+			name:  "owner",
+			path:  pathTestOrganization,
+			stubs: organizationStubs{role: organization.RoleOwner},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectOrganizationMembers(mock)
+				mock.ExpectQuery(`SELECT (.+) FROM organization_invites`).
+					WithArgs(3, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "name", "email", "role", "inviter_user_id", "created_at", "expires_at"}).
+						AddRow(2, 3, "Acme", "friend@example.com", organization.RoleAdmin, 1, time.Now(), time.Now().Add(organization.InviteTTL)))
+			},
+			expectStatus: http.StatusOK,
+			expectBody:   []string{"Acme", "colleague", "Role of colleague", "friend@example.com", "organization-invite-role", "organization-confirm"},
+		},
+		{
+			name:  "member",
+			path:  pathTestOrganization,
+			stubs: organizationStubs{role: organization.RoleMember},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectOrganizationMembers(mock)
+			},
+			expectStatus:    http.StatusOK,
+			expectBody:      []string{"colleague", "Leave Organization"},
+			expectNotInBody: []string{"Role of colleague", "organization-invite-role", "organization-confirm"},
+		},
+		{
+			name:         "not a member",
+			path:         pathTestOrganization,
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			name:         "invalid ID",
+			path:         pathAccountOrganizations + "/abc",
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			name:         "database error",
+			path:         pathTestOrganization,
+			stubs:        organizationStubs{findErr: errors.New("db fail")},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:  "members error",
+			path:  pathTestOrganization,
+			stubs: organizationStubs{role: organization.RoleOwner},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT (.+) FROM organization_members`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:  "invitations error",
+			path:  pathTestOrganization,
+			stubs: organizationStubs{role: organization.RoleAdmin},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectOrganizationMembers(mock)
+				mock.ExpectQuery(`SELECT (.+) FROM organization_invites`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useOrganizationStubs(t, &tc.stubs)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			expectSessionUser(mock, "")
+
+			if tc.setupMock != nil {
+				tc.setupMock(mock)
+			}
+
+			router.Use(setSessionUserID(1))
+			router.GET(pathAccountOrganizations+"/:id", AccountOrganization)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", tc.path, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+
+			for _, body := range tc.expectBody {
+				assert.Contains(t, w.Body.String(), body)
+			}
+
+			for _, body := range tc.expectNotInBody {
+				assert.NotContains(t, w.Body.String(), body)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func expectMemberChange(mock sqlmock.Sqlmock, userID int, role string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT id FROM organizations`).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT role FROM organization_members`).
+		WithArgs(3, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(role))
+}
+
+func TestAccountOrganizationPost(t *testing.T) {
+	tests := []struct {
+		name               string
+		form               url.Values
+		stubs              organizationStubs
+		emailErr           error
+		setupMock          func(mock sqlmock.Sqlmock)
+		expectStatus       int
+		expectLocation     string
+		expectEmails       int
+		expectEvents       []audit.EventType
+		expectOrganization string
+	}{
+		{
+			name:  "invite",
+			form:  url.Values{"action": {"invite"}, "email": {" friend@example.com "}, "role": {"admin"}},
+			stubs: organizationStubs{role: organization.RoleAdmin},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT EXISTS`).
+					WithArgs(3, "friend@example.com").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec(`INSERT INTO organization_invites`).
+					WithArgs(3, "friend@example.com", organization.RoleAdmin, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+			expectEmails:   1,
+			expectEvents:   []audit.EventType{audit.EventOrganizationInvited},
+		},
+		{
+			name:           "invite an owner as an admin",
+			form:           url.Values{"action": {"invite"}, "email": {"friend@example.com"}, "role": {"owner"}},
+			stubs:          organizationStubs{role: organization.RoleAdmin},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+		{
+			name:           "invite as a member",
+			form:           url.Values{"action": {"invite"}, "email": {"friend@example.com"}, "role": {"member"}},
+			stubs:          organizationStubs{role: organization.RoleMember},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+		{
+			name:           "invite with an invalid email address",
+			form:           url.Values{"action": {"invite"}, "email": {"friend"}, "role": {"member"}},
+			stubs:          organizationStubs{role: organization.RoleOwner},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+		{
+			name:           "invite with an invalid role",
+			form:           url.Values{"action": {"invite"}, "email": {"friend@example.com"}, "role": {"superuser"}},
+			stubs:          organizationStubs{role: organization.RoleOwner},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+		{
+			name:  "invite a member",
+			form:  url.Values{"action": {"invite"}, "email": {"colleague@example.com"}, "role": {"member"}},
+			stubs: organizationStubs{role: organization.RoleOwner},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+		{
+			name:  "invite database error",
+			form:  url.Values{"action": {"invite"}, "email": {"friend@example.com"}, "role": {"member"}},
+			stubs: organizationStubs{role: organization.RoleOwner},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT EXISTS`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:     "invite email error",
+			form:     url.Values{"action": {"invite"}, "email": {"friend@example.com"}, "role": {"member"}},
+			stubs:    organizationStubs{role: organization.RoleOwner},
+			emailErr: errors.New("smtp fail"),
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec(`INSERT INTO organization_invites`).WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			expectStatus: http.StatusInternalServerError,
+			expectEmails: 1,
+		},
+		{
+			name:  "revoke",
+			form:  url.Values{"action": {"revoke"}, "id": {"2"}},
+			stubs: organizationStubs{role: organization.RoleAdmin},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM organization_invites`).
+					WithArgs(2, 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+		{
+			name:  "revoke an unknown invitation",
+			form:  url.Values{"action": {"revoke"}, "id": {"2"}},
+			stubs: organizationStubs{role: organization.RoleAdmin},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM organization_invites`).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+		{
+			name:  "change a role",
+			form:  url.Values{"action": {"role"}, "user_id": {"2"}, "role": {"admin"}},
+			stubs: organizationStubs{role: organization.RoleAdmin, targetRole: organization.RoleMember},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberChange(mock, 2, organization.RoleMember)
+				mock.ExpectExec(`UPDATE organization_members`).
+					WithArgs(organization.RoleAdmin, 3, 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+			expectEvents:   []audit.EventType{audit.EventOrganizationRole},
+		},
+		{
+			name:           "change the role of an owner as an admin",
+			form:           url.Values{"action": {"role"}, "user_id": {"2"}, "role": {"member"}},
+			stubs:          organizationStubs{role: organization.RoleAdmin, targetRole: organization.RoleOwner},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+		{
+			name:           "make someone an owner as an admin",
+			form:           url.Values{"action": {"role"}, "user_id": {"2"}, "role": {"owner"}},
+			stubs:          organizationStubs{role: organization.RoleAdmin, targetRole: organization.RoleMember},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+		{
+			name:  "demote the last owner",
+			form:  url.Values{"action": {"role"}, "user_id": {"1"}, "role": {"member"}},
+			stubs: organizationStubs{role: organization.RoleOwner},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberChange(mock, 1, organization.RoleOwner)
+				mock.ExpectQuery(`SELECT COUNT`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectRollback()
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+		{
+			name:           "change the role of someone else",
+			form:           url.Values{"action": {"role"}, "user_id": {"2"}, "role": {"admin"}},
+			stubs:          organizationStubs{role: organization.RoleOwner},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+		{
+			name:  "remove a member",
+			form:  url.Values{"action": {"remove"}, "user_id": {"2"}},
+			stubs: organizationStubs{role: organization.RoleOwner, targetRole: organization.RoleAdmin},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberChange(mock, 2, organization.RoleAdmin)
+				mock.ExpectExec(`DELETE FROM organization_members`).
+					WithArgs(3, 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+			expectEvents:   []audit.EventType{audit.EventOrganizationRemoved},
+		},
+		{
+			name:           "remove yourself",
+			form:           url.Values{"action": {"remove"}, "user_id": {"1"}},
+			stubs:          organizationStubs{role: organization.RoleOwner},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+		{
+			name:  "remove database error",
+			form:  url.Values{"action": {"remove"}, "user_id": {"2"}},
+			stubs: organizationStubs{role: organization.RoleOwner, targetRole: organization.RoleMember},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin().WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:  "leave",
+			form:  url.Values{"action": {"leave"}},
+			stubs: organizationStubs{role: organization.RoleMember},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberChange(mock, 1, organization.RoleMember)
+				mock.ExpectExec(`DELETE FROM organization_members`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectStatus:       http.StatusSeeOther,
+			expectLocation:     pathAccountOrganizations,
+			expectEvents:       []audit.EventType{audit.EventOrganizationLeft},
+			expectOrganization: "<nil>",
+		},
+		{
+			name:  "leave as the last owner",
+			form:  url.Values{"action": {"leave"}},
+			stubs: organizationStubs{role: organization.RoleOwner},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectMemberChange(mock, 1, organization.RoleOwner)
+				mock.ExpectQuery(`SELECT COUNT`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectRollback()
+			},
+			expectStatus:       http.StatusSeeOther,
+			expectLocation:     pathTestOrganization,
+			expectOrganization: "3",
+		},
+		{
+			name:  "delete",
+			form:  url.Values{"action": {"delete"}, "confirm": {"Acme"}},
+			stubs: organizationStubs{role: organization.RoleOwner},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM organizations`).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus:       http.StatusSeeOther,
+			expectLocation:     pathAccountOrganizations,
+			expectEvents:       []audit.EventType{audit.EventOrganizationDeleted},
+			expectOrganization: "<nil>",
+		},
+		{
+			name:           "delete without confirming",
+			form:           url.Values{"action": {"delete"}, "confirm": {"Globex"}},
+			stubs:          organizationStubs{role: organization.RoleOwner},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+		{
+			name:           "delete as an admin",
+			form:           url.Values{"action": {"delete"}, "confirm": {"Acme"}},
+			stubs:          organizationStubs{role: organization.RoleAdmin},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+		{
+			name:  "delete database error",
+			form:  url.Values{"action": {"delete"}, "confirm": {"Acme"}},
+			stubs: organizationStubs{role: organization.RoleOwner},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM organizations`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "not a member",
+			form:         url.Values{"action": {"leave"}},
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unknown action",
+			form:           url.Values{"action": {"other"}},
+			stubs:          organizationStubs{role: organization.RoleOwner},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathTestOrganization,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useOrganizationStubs(t, &tc.stubs)
+
+			sender := useRecordingEmailSender(t)
+			sender.err = tc.emailErr
+			auditLog := useRecordingAuditLog(t)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			expectSessionUser(mock, "")
+
+			if tc.setupMock != nil {
+				tc.setupMock(mock)
+			}
+
+			router.Use(setSessionUserID(1), setActiveOrganizationID(3))
+			router.POST(pathAccountOrganizations+"/:id", AccountOrganizationPost)
+
+			w := postForm(router, pathTestOrganization, tc.form)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.Equal(t, tc.expectLocation, w.Header().Get("Location"))
+			assert.Len(t, sender.sent, tc.expectEmails)
+
+			if tc.expectEmails > 0 {
+				assert.Equal(t, "email/organization_invite", sender.sent[0].Template)
+				assert.Equal(t, "Acme", sender.sent[0].Data["Organization"])
+				assert.Equal(t, []string{"friend@example.com"}, sender.to[0])
+			}
+
+			assert.Equal(t, append([]audit.EventType{}, tc.expectEvents...), auditLog.types())
+
+			if tc.expectOrganization != "" {
+				sessionRouter, _, sessionDB := setupTestRouterWithMocks(t, false)
+				defer func() { _ = sessionDB.Close() }()
+
+				assert.Equal(t, tc.expectOrganization, sessionOrganization(sessionRouter, w))
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
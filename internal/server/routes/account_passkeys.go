@@ -0,0 +1,280 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	defaultPasskeyName           = "Passkey"
+	errPasskeyRegistrationFailed = "The passkey could not be added. Please try again."
+)
+
+func AccountPasskeys(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	passkeys, err := usr.GetPasskeys(db)
+
+	if err != nil {
+		log.Error("Could not get the passkeys", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	data := RouteData{
+		Template:    "pages/account_passkeys",
+		Title:       "Passkeys",
+		Description: "Sign in without a password.",
+		HttpStatus:  http.StatusOK,
+		Data: map[string]any{
+			"Passkeys":      passkeys,
+			"NameMaxLength": user.PasskeyNameMaxLength,
+		},
+		FormData: FormData{
+			Values: v.GetFormData(),
+			Errors: v.GetSessionErrors(),
+		},
+		CSRFToken: middleware.GetCSRFToken(c),
+	}
+
+	RenderRouteHTML(c, data)
+
+	v.ClearSession()
+}
+
+func AccountPasskeysPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	path := fmt.Sprintf("%s/passkeys", paths.PathAccount)
+	err := v.ValidateForm(c.Request)
+
+	if err != nil {
+		log.Error("Failed to parse form data", map[string]any{"error": err.Error()})
+	}
+
+	action := v.GetFormValue(c.Request, "action")
+	name := strings.TrimSpace(v.GetFormValue(c.Request, "name"))
+	id, err := strconv.Atoi(v.GetFormValue(c.Request, "id"))
+
+	if err != nil {
+		route_utils.RedirectWithError(c, v, nil, user.ErrPasskeyNotFound.Error(), path)
+		return
+	}
+
+	if action == "rename" {
+		v.Required("name", name)
+		v.MaxLength("name", name, user.PasskeyNameMaxLength)
+	}
+
+	if v.HasErrors() {
+		route_utils.RedirectWithError(c, v, nil, "Please correct the errors below", path)
+		return
+	}
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	flash := "The passkey has been renamed."
+
+	if action == "delete" {
+		err = usr.DeletePasskey(db, id)
+		flash = "The passkey has been removed."
+	} else {
+		err = usr.RenamePasskey(db, id, name)
+	}
+
+	if err != nil {
+		if errors.Is(err, user.ErrPasskeyNotFound) {
+			route_utils.RedirectWithError(c, v, nil, user.ErrPasskeyNotFound.Error(), path)
+			return
+		}
+
+		log.Error("Could not update the passkey", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: flash})
+	c.Redirect(http.StatusSeeOther, path)
+}
+
+func AccountPasskeysOptions(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		passkeyError(c, http.StatusInternalServerError, errPasskeyRegistrationFailed)
+
+		return
+	}
+
+	webAuthnUser, err := usr.GetWebAuthnUser(db)
+
+	if err != nil {
+		log.Error("Could not get the passkeys", logger.Fields{"error": err.Error()})
+		passkeyError(c, http.StatusInternalServerError, errPasskeyRegistrationFailed)
+
+		return
+	}
+
+	wa, err := newWebAuthn()
+
+	if err != nil {
+		log.Error("Could not set up WebAuthn", logger.Fields{"error": err.Error()})
+		passkeyError(c, http.StatusInternalServerError, errPasskeyRegistrationFailed)
+
+		return
+	}
+
+	credentials := webAuthnUser.WebAuthnCredentials()
+	exclusions := make([]protocol.CredentialDescriptor, len(credentials))
+
+	for i, credential := range credentials {
+		exclusions[i] = credential.Descriptor()
+	}
+
+	creation, sessionData, err := wa.BeginRegistration(
+		webAuthnUser,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+
+	if err != nil {
+		log.Error("Could not start the passkey registration", logger.Fields{"error": err.Error()})
+		passkeyError(c, http.StatusInternalServerError, errPasskeyRegistrationFailed)
+
+		return
+	}
+
+	err = savePasskeySession(getSession(c), sessionKeyPasskeyRegistration, sessionData)
+
+	if err != nil {
+		log.Error("Failed to save session for the passkey registration", logger.Fields{"error": err.Error()})
+		passkeyError(c, http.StatusInternalServerError, errPasskeyRegistrationFailed)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, creation)
+}
+
+func AccountPasskeysRegister(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	name := strings.TrimSpace(c.Query("name"))
+
+	if name == "" {
+		name = defaultPasskeyName
+	}
+
+	if len(name) > user.PasskeyNameMaxLength {
+		passkeyError(c, http.StatusBadRequest, fmt.Sprintf("The name must be at most %d characters long.", user.PasskeyNameMaxLength))
+		return
+	}
+
+	sessionData, err := takePasskeySession(getSession(c), sessionKeyPasskeyRegistration)
+
+	if err != nil {
+		passkeyError(c, http.StatusBadRequest, errPasskeyRegistrationFailed)
+		return
+	}
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		passkeyError(c, http.StatusInternalServerError, errPasskeyRegistrationFailed)
+
+		return
+	}
+
+	webAuthnUser, err := usr.GetWebAuthnUser(db)
+
+	if err != nil {
+		log.Error("Could not get the passkeys", logger.Fields{"error": err.Error()})
+		passkeyError(c, http.StatusInternalServerError, errPasskeyRegistrationFailed)
+
+		return
+	}
+
+	wa, err := newWebAuthn()
+
+	if err != nil {
+		log.Error("Could not set up WebAuthn", logger.Fields{"error": err.Error()})
+		passkeyError(c, http.StatusInternalServerError, errPasskeyRegistrationFailed)
+
+		return
+	}
+
+	credential, err := wa.FinishRegistration(webAuthnUser, *sessionData, c.Request)
+
+	if err != nil {
+		log.Warn("Passkey registration failed", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		passkeyError(c, http.StatusBadRequest, errPasskeyRegistrationFailed)
+
+		return
+	}
+
+	err = usr.AddPasskey(db, name, credential)
+
+	if err != nil {
+		log.Error("Could not save the passkey", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		passkeyError(c, http.StatusInternalServerError, errPasskeyRegistrationFailed)
+
+		return
+	}
+
+	log.Info("Passkey added", logger.Fields{"userID": usr.GetID()})
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: "The passkey has been added."})
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "ok",
+		"redirect": fmt.Sprintf("%s/passkeys", paths.PathAccount),
+	})
+}
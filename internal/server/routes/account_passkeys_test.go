@@ -0,0 +1,393 @@
+package routes
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/descope/virtualwebauthn"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	pathAccountPasskeys         = paths.PathAccount + "/passkeys"
+	pathAccountPasskeysOptions  = paths.PathAccount + "/passkeys/options"
+	pathAccountPasskeysRegister = paths.PathAccount + "/passkeys/register"
+
+	passkeyColumns = []string{
+		"id", "name", "credential_id", "public_key", "attestation_type", "attestation_format", "transports",
+		"flags", "aaguid", "sign_count", "clone_warning", "attachment", "attestation", "created_at", "last_used_at",
+	}
+)
+
+// captureArg is a query argument that remembers the value it was given,
+// so that a row written by one request can be read back by the next one.
+type captureArg struct {
+	value *driver.Value
+}
+
+func (a captureArg) Match(v driver.Value) bool {
+	*a.value = v
+	return true
+}
+
+type testPasskey struct {
+	rp            virtualwebauthn.RelyingParty
+	authenticator virtualwebauthn.Authenticator
+	credential    virtualwebauthn.Credential
+	row           []driver.Value
+}
+
+func setupPasskeyConfig(t *testing.T) virtualwebauthn.RelyingParty {
+	origName := viper.Get("site.name")
+	origHost := viper.Get("site.host")
+
+	t.Cleanup(func() {
+		viper.Set("site.name", origName)
+		viper.Set("site.host", origHost)
+	})
+
+	viper.Set("site.name", "Go Web Starter")
+	viper.Set("site.host", "http://localhost:4000")
+
+	return virtualwebauthn.RelyingParty{Name: "Go Web Starter", ID: "localhost", Origin: "http://localhost:4000"}
+}
+
+func passkeyRequest(path, body string, cookies []*http.Cookie) *http.Request {
+	req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	return req
+}
+
+func expectNoPasskeys(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT (.+) FROM user_passkeys WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(passkeyColumns))
+}
+
+var testPasskeyUserHandle = []byte("random passkey user handle")
+
+func expectPasskeyUserHandle(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`UPDATE users SET passkey_user_handle`).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"passkey_user_handle"}).AddRow(testPasskeyUserHandle))
+}
+
+func expectPasskeys(mock sqlmock.Sqlmock, rows ...[]driver.Value) {
+	result := sqlmock.NewRows(passkeyColumns)
+
+	for _, row := range rows {
+		result.AddRow(row...)
+	}
+
+	mock.ExpectQuery(`SELECT (.+) FROM user_passkeys WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(result)
+}
+
+func setupPasskeyRegistrationRouter(t *testing.T) (*gin.Engine, sqlmock.Sqlmock) {
+	router, mock, mockDB := setupTestRouterWithMocks(t, true)
+	t.Cleanup(func() { _ = mockDB.Close() })
+
+	router.Use(setSessionUserID(1))
+	router.POST(pathAccountPasskeysOptions, AccountPasskeysOptions)
+	router.POST(pathAccountPasskeysRegister, AccountPasskeysRegister)
+
+	return router, mock
+}
+
+func registerTestPasskey(t *testing.T) testPasskey {
+	passkey := testPasskey{
+		rp:            setupPasskeyConfig(t),
+		authenticator: virtualwebauthn.NewAuthenticator(),
+		credential:    virtualwebauthn.NewCredential(virtualwebauthn.KeyTypeEC2),
+	}
+
+	router, mock := setupPasskeyRegistrationRouter(t)
+
+	expectSessionUser(mock, "")
+	expectNoPasskeys(mock)
+	expectPasskeyUserHandle(mock)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, passkeyRequest(pathAccountPasskeysOptions, "", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	options, err := virtualwebauthn.ParseAttestationOptions(w.Body.String())
+	assert.NoError(t, err)
+
+	response := virtualwebauthn.CreateAttestationResponse(passkey.rp, passkey.authenticator, passkey.credential, *options)
+
+	stored := make([]driver.Value, 14)
+	args := make([]driver.Value, len(stored))
+
+	for i := range stored {
+		args[i] = captureArg{value: &stored[i]}
+	}
+
+	expectSessionUser(mock, "")
+	expectNoPasskeys(mock)
+	expectPasskeyUserHandle(mock)
+	mock.ExpectExec(`INSERT INTO user_passkeys`).WithArgs(args...).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	cookies := w.Result().Cookies()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, passkeyRequest(pathAccountPasskeysRegister+"?name=Laptop", response, cookies))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok","redirect":"/account/passkeys"}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, int64(1), stored[0])
+	assert.Equal(t, "Laptop", stored[1])
+
+	// The stored row, in the order of the columns that are selected.
+	passkey.row = append([]driver.Value{int64(1)}, stored[1:]...)
+	passkey.row = append(passkey.row, nil)
+	passkey.authenticator.AddCredential(passkey.credential)
+
+	return passkey
+}
+
+func TestAccountPasskeysRegistration(t *testing.T) {
+	passkey := registerTestPasskey(t)
+
+	assert.Equal(t, passkey.credential.ID, passkey.row[2])
+}
+
+func TestAccountPasskeysRegistrationErrors(t *testing.T) {
+	rp := setupPasskeyConfig(t)
+
+	t.Run("name too long", func(t *testing.T) {
+		router, _ := setupPasskeyRegistrationRouter(t)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, passkeyRequest(pathAccountPasskeysRegister+"?name="+strings.Repeat("a", 65), "{}", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("no ceremony in progress", func(t *testing.T) {
+		router, _ := setupPasskeyRegistrationRouter(t)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, passkeyRequest(pathAccountPasskeysRegister, "{}", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), errPasskeyRegistrationFailed)
+	})
+
+	t.Run("wrong origin", func(t *testing.T) {
+		router, mock := setupPasskeyRegistrationRouter(t)
+
+		expectSessionUser(mock, "")
+		expectNoPasskeys(mock)
+		expectPasskeyUserHandle(mock)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, passkeyRequest(pathAccountPasskeysOptions, "", nil))
+
+		options, err := virtualwebauthn.ParseAttestationOptions(w.Body.String())
+		assert.NoError(t, err)
+
+		evil := rp
+		evil.Origin = "http://evil.example"
+
+		response := virtualwebauthn.CreateAttestationResponse(
+			evil,
+			virtualwebauthn.NewAuthenticator(),
+			virtualwebauthn.NewCredential(virtualwebauthn.KeyTypeEC2),
+			*options,
+		)
+
+		expectSessionUser(mock, "")
+		expectNoPasskeys(mock)
+		expectPasskeyUserHandle(mock)
+
+		cookies := w.Result().Cookies()
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, passkeyRequest(pathAccountPasskeysRegister, response, cookies))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("options database error", func(t *testing.T) {
+		router, mock := setupPasskeyRegistrationRouter(t)
+
+		expectSessionUser(mock, "")
+		mock.ExpectQuery(`FROM user_passkeys`).WillReturnError(errors.New("db fail"))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, passkeyRequest(pathAccountPasskeysOptions, "", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("options user handle error", func(t *testing.T) {
+		router, mock := setupPasskeyRegistrationRouter(t)
+
+		expectSessionUser(mock, "")
+		expectNoPasskeys(mock)
+		mock.ExpectQuery(`UPDATE users SET passkey_user_handle`).WillReturnError(errors.New("db fail"))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, passkeyRequest(pathAccountPasskeysOptions, "", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid site host", func(t *testing.T) {
+		viper.Set("site.host", "localhost")
+		defer viper.Set("site.host", "http://localhost:4000")
+
+		router, mock := setupPasskeyRegistrationRouter(t)
+
+		expectSessionUser(mock, "")
+		expectNoPasskeys(mock)
+		expectPasskeyUserHandle(mock)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, passkeyRequest(pathAccountPasskeysOptions, "", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestAccountPasskeys(t *testing.T) {
+	passkey := registerTestPasskey(t)
+
+	router, mock, mockDB := setupTestRouterWithMocks(t, true)
+	defer func() { _ = mockDB.Close() }()
+
+	router.Use(setSessionUserID(1))
+	router.GET(pathAccountPasskeys, AccountPasskeys)
+
+	expectSessionUser(mock, "")
+	expectPasskeys(mock, passkey.row)
+	expectSessionUser(mock, "")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", pathAccountPasskeys, nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `value="Laptop"`)
+	assert.Contains(t, w.Body.String(), "data-passkey-register")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAccountPasskeysPost(t *testing.T) {
+	tests := []struct {
+		name           string
+		form           url.Values
+		setupMock      func(mock sqlmock.Sqlmock)
+		expectStatus   int
+		expectLocation string
+	}{
+		{
+			name:           "invalid ID",
+			form:           url.Values{"action": {"delete"}, "id": {"abc"}},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountPasskeys,
+		},
+		{
+			name:           "rename without a name",
+			form:           url.Values{"action": {"rename"}, "id": {"1"}, "name": {" "}},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountPasskeys,
+		},
+		{
+			name: "rename",
+			form: url.Values{"action": {"rename"}, "id": {"1"}, "name": {"Phone"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectExec(`UPDATE user_passkeys SET name`).
+					WithArgs("Phone", 1, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountPasskeys,
+		},
+		{
+			name: "delete",
+			form: url.Values{"action": {"delete"}, "id": {"1"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectExec(`DELETE FROM user_passkeys`).
+					WithArgs(1, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountPasskeys,
+		},
+		{
+			name: "delete someone else's passkey",
+			form: url.Values{"action": {"delete"}, "id": {"2"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectExec(`DELETE FROM user_passkeys`).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountPasskeys,
+		},
+		{
+			name: "database error",
+			form: url.Values{"action": {"delete"}, "id": {"1"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectExec(`DELETE FROM user_passkeys`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tc.setupMock != nil {
+				tc.setupMock(mock)
+			}
+
+			router.Use(setSessionUserID(1))
+			router.POST(pathAccountPasskeys, AccountPasskeysPost)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", pathAccountPasskeys, strings.NewReader(tc.form.Encode()))
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+
+			if tc.expectLocation != "" {
+				assert.Equal(t, tc.expectLocation, w.Header().Get("Location"))
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCaptureArg(t *testing.T) {
+	var value driver.Value
+
+	assert.True(t, captureArg{value: &value}.Match(time.Unix(0, 0)))
+	assert.Equal(t, time.Unix(0, 0), value)
+}
@@ -0,0 +1,170 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	emailer "github.com/Dobefu/go-web-starter/internal/email"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const sessionKeyPasswordReset = "passwordReset"
+
+func passwordResetActive(session sessions.Session) bool {
+	startedAt, ok := session.Get(sessionKeyPasswordReset).(int64)
+
+	return ok && time.Since(time.Unix(startedAt, 0)) < user.TokenTTLReset
+}
+
+func rotateSession(session sessions.Session, usr *user.User) error {
+	session.Clear()
+	session.Set("userID", usr.GetID())
+	sessionstore.Regenerate(session)
+
+	return session.Save()
+}
+
+func AccountPasswordPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	err := v.ValidateForm(c.Request)
+
+	if err != nil {
+		log.Error("Failed to parse form data", logger.Fields{"error": err.Error()})
+	}
+
+	session := getSession(c)
+	resetActive := passwordResetActive(session)
+
+	currentPassword := v.GetFormValue(c.Request, "current_password")
+	password := v.GetFormValue(c.Request, "password")
+	passwordConfirm := v.GetFormValue(c.Request, "password_confirm")
+
+	if !resetActive {
+		v.Required("current_password", currentPassword)
+	}
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user for the password change", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	v.Required("password", password)
+	v.PasswordPolicy("password", password, validator.PasswordContext{Username: usr.GetUsername(), Email: usr.GetEmail()})
+	v.Required("password_confirm", passwordConfirm)
+	v.PasswordsMatch("password", password, passwordConfirm)
+
+	if !resetActive && currentPassword != "" {
+		err = usr.CheckPassword(db, currentPassword)
+
+		if err != nil {
+			if !errors.Is(err, user.ErrInvalidCredentials) {
+				log.Error("Password check error during password change", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+				RenderRouteHTML(c, GenericErrorData(c))
+
+				return
+			}
+
+			log.Warn("Password change failed: invalid current password", logger.Fields{"userID": usr.GetID()})
+			v.AddFieldError("current_password", user.ErrInvalidCredentials.Error())
+		}
+	}
+
+	if v.HasErrors() {
+		route_utils.RedirectWithError(
+			c,
+			v,
+			nil,
+			"Please correct the errors below",
+			fmt.Sprintf("%s/edit", paths.PathAccount),
+		)
+
+		return
+	}
+
+	err = usr.ChangePassword(db, password)
+
+	if err != nil {
+		log.Error("Could not change the password", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	err = usr.RevokeTokens(db, user.TokenPurposeReset)
+
+	if err != nil {
+		log.Error("Could not revoke the password reset tokens", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+	}
+
+	err = rotateSession(session, usr)
+
+	if err != nil {
+		log.Error("Failed to rotate the session after a password change", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	// Anyone who knew the old password may still be signed in elsewhere.
+	err = newSessionBackend(c, db).DeleteByUser(c.Request.Context(), usr.GetID(), sessionstore.RecordID(session.ID()))
+
+	if err != nil {
+		log.Error("Could not sign out the other devices after a password change", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+	}
+
+	log.Info("Password changed", logger.Fields{"userID": usr.GetID()})
+
+	if resetActive {
+		auditEvent(c, audit.EventPasswordReset, usr.GetID(), usr.GetID(), nil)
+	} else {
+		auditEvent(c, audit.EventPasswordChanged, usr.GetID(), usr.GetID(), nil)
+	}
+
+	// The password has been changed at this point,
+	// so a failing notice should not look like a failed change.
+	err = newEmailSender().SendMail(
+		viper.GetString("site.email"),
+		[]string{usr.GetEmail()},
+		fmt.Sprintf("Your %s password was changed", viper.GetString("site.name")),
+		emailer.EmailBody{
+			Template: "email/password_changed",
+			Data: map[string]any{
+				"Username":  usr.GetUsername(),
+				"ChangedAt": time.Now().UTC().Format("Jan 2, 2006 at 15:04 MST"),
+			},
+		},
+	)
+
+	if err != nil {
+		log.Error("Failed to send the password changed email", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+	}
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: "Your password has been changed.",
+	})
+
+	c.Redirect(http.StatusSeeOther, paths.PathAccount)
+}
@@ -0,0 +1,287 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	server_utils "github.com/Dobefu/go-web-starter/internal/server/utils"
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
+	"github.com/Dobefu/go-web-starter/internal/templates"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var pathAccountPassword = paths.PathAccount + "/password"
+
+func setupAccountPasswordRouter(db database.DatabaseInterface, handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	router.Use(sessions.Sessions("mysession", cookie.NewStore([]byte("secret"))))
+	router.Use(middleware.Database(db))
+	router.Use(handlers...)
+
+	router.SetFuncMap(server_utils.TemplateFuncMap())
+	_ = templates.LoadTemplates(router)
+
+	router.POST(pathAccountPassword, AccountPasswordPost)
+
+	return router
+}
+
+func setPasswordReset(startedAt time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session := sessions.Default(c)
+		session.Set(sessionKeyPasswordReset, startedAt.Unix())
+
+		c.Next()
+	}
+}
+
+func accountPasswordRequest(fields map[string]string) *http.Request {
+	form := url.Values{}
+
+	for key, value := range fields {
+		form.Set(key, value)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, pathAccountPassword, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req
+}
+
+type memorySessionBackend struct {
+	fakeSessionBackend
+	saved map[string]*sessionstore.Record
+}
+
+func (b *memorySessionBackend) Get(_ context.Context, id string) (*sessionstore.Record, error) {
+	return b.saved[id], nil
+}
+
+func (b *memorySessionBackend) Save(_ context.Context, record *sessionstore.Record) error {
+	b.saved[record.ID] = record
+	return nil
+}
+
+func (b *memorySessionBackend) Delete(_ context.Context, id string) error {
+	delete(b.saved, id)
+	return nil
+}
+
+func TestRotateSession(t *testing.T) {
+	backend := &memorySessionBackend{saved: map[string]*sessionstore.Record{}}
+	handler := sessions.Sessions("mysession", sessionstore.New(backend, []byte("secret")))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	handler(c)
+
+	session := sessions.Default(c)
+	session.Set("userID", 1)
+	assert.NoError(t, session.Save())
+
+	oldID := session.ID()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	for _, cookie := range w.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = req
+	handler(c)
+
+	session = sessions.Default(c)
+	assert.Equal(t, oldID, session.ID())
+
+	session.Set(sessionKeyPasswordReset, time.Now().Unix())
+	session.Set("csrf_token", "token")
+
+	assert.NoError(t, rotateSession(session, newTestUserWithID(1)))
+	assert.Equal(t, 1, session.Get("userID"))
+	assert.Nil(t, session.Get(sessionKeyPasswordReset))
+	assert.Nil(t, session.Get("csrf_token"))
+	assert.NotEqual(t, oldID, session.ID())
+	assert.Len(t, backend.saved, 1)
+	assert.Contains(t, backend.saved, sessionstore.RecordID(session.ID()))
+	assert.NotEmpty(t, w.Result().Cookies())
+}
+
+func TestAccountPasswordPost(t *testing.T) {
+	passwordHash, err := user.HashPassword("oldpassword1")
+	assert.NoError(t, err)
+
+	valid := map[string]string{
+		"current_password": "oldpassword1",
+		"password":         "newpassword1",
+		"password_confirm": "newpassword1",
+	}
+
+	withFields := func(overrides map[string]string) map[string]string {
+		fields := map[string]string{}
+
+		for key, value := range valid {
+			fields[key] = value
+		}
+
+		for key, value := range overrides {
+			fields[key] = value
+		}
+
+		return fields
+	}
+
+	expectPasswordSaved := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`UPDATE users SET .+`).
+			WithArgs("username", "test@example.com", sqlmock.AnyArg(), true, sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+		mock.ExpectExec("DELETE FROM user_tokens").WithArgs(1, "reset").WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	tests := []struct {
+		name         string
+		fields       map[string]string
+		handlers     []gin.HandlerFunc
+		mockSetup    func(mock sqlmock.Sqlmock)
+		sendErr      error
+		signOutErr   error
+		wantStatus   int
+		wantLocation string
+		wantSent     bool
+	}{
+		{
+			name:         "success",
+			fields:       valid,
+			mockSetup:    expectPasswordSaved,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathAccount,
+			wantSent:     true,
+		},
+		{
+			name:         "success after a password reset",
+			fields:       withFields(map[string]string{"current_password": ""}),
+			handlers:     []gin.HandlerFunc{setPasswordReset(time.Now())},
+			mockSetup:    expectPasswordSaved,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathAccount,
+			wantSent:     true,
+		},
+		{
+			name:         "expired password reset",
+			fields:       withFields(map[string]string{"current_password": ""}),
+			handlers:     []gin.HandlerFunc{setPasswordReset(time.Now().Add(-2 * user.TokenTTLReset))},
+			mockSetup:    func(mock sqlmock.Sqlmock) {},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathAccount + "/edit",
+		},
+		{
+			name:         "wrong current password",
+			fields:       withFields(map[string]string{"current_password": "wrongpassword1"}),
+			mockSetup:    func(mock sqlmock.Sqlmock) {},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathAccount + "/edit",
+		},
+		{
+			name:         "weak password",
+			fields:       withFields(map[string]string{"password": "password", "password_confirm": "password"}),
+			mockSetup:    func(mock sqlmock.Sqlmock) {},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathAccount + "/edit",
+		},
+		{
+			name:         "password contains the email address",
+			fields:       withFields(map[string]string{"password": "test@example.com1", "password_confirm": "test@example.com1"}),
+			mockSetup:    func(mock sqlmock.Sqlmock) {},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathAccount + "/edit",
+		},
+		{
+			name:         "passwords do not match",
+			fields:       withFields(map[string]string{"password_confirm": "newpassword2"}),
+			mockSetup:    func(mock sqlmock.Sqlmock) {},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathAccount + "/edit",
+		},
+		{
+			name:   "save error",
+			fields: valid,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE users SET .+`).WillReturnError(errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "email error",
+			fields:       valid,
+			mockSetup:    expectPasswordSaved,
+			sendErr:      errors.New("smtp error"),
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathAccount,
+			wantSent:     true,
+		},
+		{
+			name:         "sign out error",
+			fields:       valid,
+			mockSetup:    expectPasswordSaved,
+			signOutErr:   errors.New("redis error"),
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathAccount,
+			wantSent:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := useRecordingEmailSender(t)
+			sender.err = tt.sendErr
+
+			backend := &fakeSessionBackend{deleteErr: tt.signOutErr}
+			patchSessionBackend(t, backend)
+
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			expectSessionUser(mock, passwordHash)
+			tt.mockSetup(mock)
+
+			handlers := append([]gin.HandlerFunc{setSessionUserID(1)}, tt.handlers...)
+
+			w := httptest.NewRecorder()
+			setupAccountPasswordRouter(db, handlers...).ServeHTTP(w, accountPasswordRequest(tt.fields))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if !tt.wantSent {
+				assert.Empty(t, sender.sent)
+				assert.Empty(t, backend.deletedUsers)
+
+				return
+			}
+
+			assert.Equal(t, []int{1}, backend.deletedUsers)
+			assert.Len(t, sender.sent, 1)
+			assert.Equal(t, []string{"test@example.com"}, sender.to[0])
+			assert.Equal(t, "email/password_changed", sender.sent[0].Template)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	emailer "github.com/Dobefu/go-web-starter/internal/email"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const errAccountRestoreInvalid = "This restore link is invalid or has expired."
+
+var (
+	findDeletedByEmail = user.FindDeletedByEmail
+	findDeletedByID    = user.FindDeletedByID
+)
+
+func deleteAccount(c *gin.Context, db database.DatabaseInterface, actorID int, usr *user.User, method string) (time.Time, error) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	grace := config.DeletionGracePeriod()
+
+	if grace == 0 {
+		if err := usr.Delete(db); err != nil {
+			return time.Time{}, err
+		}
+
+		removeAccountAvatar(c, log, usr)
+		revokeDeletedAccountAccess(c, log, db, usr)
+		auditEvent(c, audit.EventAccountDeleted, actorID, usr.GetID(), audit.Metadata{"username": usr.GetUsername(), "method": method})
+
+		return time.Time{}, nil
+	}
+
+	if err := usr.SoftDelete(db); err != nil {
+		return time.Time{}, err
+	}
+
+	revokeDeletedAccountAccess(c, log, db, usr)
+
+	purgeAt := usr.GetDeletedAt().Add(grace)
+	auditEvent(c, audit.EventAccountDeleted, actorID, usr.GetID(), audit.Metadata{"username": usr.GetUsername(), "method": method, "purge_at": purgeAt})
+
+	// The account has been deleted at this point,
+	// so a failing email should not look like a failed deletion.
+	if err := sendAccountDeletedEmail(db, usr, grace, purgeAt); err != nil {
+		log.Error("Failed to send the account deleted email", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+	}
+
+	return purgeAt, nil
+}
+
+func revokeDeletedAccountAccess(c *gin.Context, log *logger.Logger, db database.DatabaseInterface, usr *user.User) {
+	if err := revokeAccess(c, db, usr); err != nil {
+		log.Error("Could not revoke the sessions and API tokens of a deleted account", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+	}
+}
+
+func revokeAccess(c *gin.Context, db database.DatabaseInterface, usr *user.User) error {
+	err := newSessionBackend(c, db).DeleteByUser(c.Request.Context(), usr.GetID(), "")
+
+	if err != nil {
+		return err
+	}
+
+	return usr.RevokeAPITokens(db)
+}
+
+func sendAccountDeletedEmail(db database.DatabaseInterface, usr *user.User, grace time.Duration, purgeAt time.Time) error {
+	token, err := usr.CreateToken(db, user.TokenPurposeRestore, grace)
+
+	if err != nil {
+		return fmt.Errorf("failed to create the restore token: %w", err)
+	}
+
+	return newEmailSender().SendMail(
+		viper.GetString("site.email"),
+		[]string{usr.GetEmail()},
+		fmt.Sprintf("Your %s account has been deleted", viper.GetString("site.name")),
+		emailer.EmailBody{
+			Template: "email/account_deleted",
+			Data: map[string]any{
+				"Username": usr.GetUsername(),
+				"Token":    token,
+				"PurgeAt":  formatPurgeDate(purgeAt),
+			},
+		},
+	)
+}
+
+func formatPurgeDate(purgeAt time.Time) string {
+	return purgeAt.UTC().Format("Jan 2, 2006")
+}
+
+func findRestorableByEmail(db database.DatabaseInterface, email string) (*user.User, error) {
+	grace := config.DeletionGracePeriod()
+
+	if grace == 0 {
+		return nil, user.ErrInvalidCredentials
+	}
+
+	return findDeletedByEmail(db, email, time.Now().Add(-grace))
+}
+
+// restoreOnLogin restores a deleted account once the user has fully logged
+// in, so that the password alone does not restore an account that has
+// two-factor authentication enabled.
+func restoreOnLogin(c *gin.Context, db database.DatabaseInterface, usr *user.User) error {
+	if !usr.IsDeleted() {
+		return nil
+	}
+
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	err := usr.Restore(db)
+
+	if err != nil {
+		return err
+	}
+
+	log.Info("Account restored by logging in", logger.Fields{"userID": usr.GetID()})
+	auditEvent(c, audit.EventAccountRestored, usr.GetID(), usr.GetID(), audit.Metadata{"method": "login"})
+
+	if err = usr.RevokeTokens(db, user.TokenPurposeRestore); err != nil {
+		log.Error("Could not revoke the restore tokens", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+	}
+
+	return nil
+}
+
+func AccountRestore(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	token := v.GetFormValue(c.Request, "token")
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Could not get the database from the context", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	usr, err := user.RestoreWithToken(db, token)
+
+	if err != nil {
+		if !errors.Is(err, user.ErrInvalidToken) && !errors.Is(err, user.ErrNotDeleted) {
+			log.Error("Could not restore the account", logger.Fields{"error": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+
+		v.SetFlash(message.Message{Type: message.MessageTypeError, Body: errAccountRestoreInvalid})
+		c.Redirect(http.StatusSeeOther, paths.PathLogin)
+
+		return
+	}
+
+	log.Info("Account restored", logger.Fields{"userID": usr.GetID()})
+	auditEvent(c, audit.EventAccountRestored, usr.GetID(), usr.GetID(), audit.Metadata{"method": "link"})
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: "Your account has been restored. You can log in again.",
+	})
+
+	c.Redirect(http.StatusSeeOther, paths.PathLogin)
+}
@@ -0,0 +1,335 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var pathAccountRestore = paths.PathAccount + "/restore"
+
+func setDeletionGraceDays(t *testing.T, days int) {
+	t.Cleanup(func() { viper.Set("site.deletiongracedays", config.DefaultConfig.Site.DeletionGraceDays) })
+	viper.Set("site.deletiongracedays", days)
+}
+
+func useDeletedUsers(t *testing.T, users ...*user.User) {
+	origFindDeletedByEmail := findDeletedByEmail
+
+	t.Cleanup(func() { findDeletedByEmail = origFindDeletedByEmail })
+	findDeletedByEmail = func(_ database.DatabaseInterface, email string, _ time.Time) (*user.User, error) {
+		for _, usr := range users {
+			if usr.GetEmail() == email {
+				return usr, nil
+			}
+		}
+
+		return nil, user.ErrInvalidCredentials
+	}
+}
+
+func expectSoftDelete(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("UPDATE users SET deleted_at").WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectRevokeAccess(mock, 1)
+	mock.ExpectExec("DELETE FROM user_tokens").WithArgs(1, "restore").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO user_tokens").WithArgs(1, "restore", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func expectRevokeAccess(mock sqlmock.Sqlmock, userID int) {
+	mock.ExpectExec("DELETE FROM sessions").WithArgs(userID, "").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM user_api_tokens").WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestAccountRestore(t *testing.T) {
+	tests := []struct {
+		name       string
+		setupMock  func(mock sqlmock.Sqlmock)
+		wantStatus int
+		wantEvents []audit.EventType
+	}{
+		{
+			name: "success",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				now := time.Now()
+
+				mock.ExpectQuery(`UPDATE user_tokens SET consumed_at`).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "restore").
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+				mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1 AND deleted_at IS NOT NULL`).
+					WithArgs(1).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "username", "email", "password", "status", "created_at", "updated_at", "last_login", "avatar", "deleted_at"}).
+							AddRow(1, "username", "test@example.com", "", true, now, now, now, "", now),
+					)
+				mock.ExpectExec("UPDATE users SET deleted_at = NULL").WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantStatus: http.StatusSeeOther,
+			wantEvents: []audit.EventType{audit.EventAccountRestored},
+		},
+		{
+			name: "invalid token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE user_tokens SET consumed_at`).WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus: http.StatusSeeOther,
+			wantEvents: []audit.EventType{},
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE user_tokens SET consumed_at`).WillReturnError(errors.New("db fail"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantEvents: []audit.EventType{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditLog := useRecordingAuditLog(t)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			tt.setupMock(mock)
+			router.GET(pathAccountRestore, AccountRestore)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, pathAccountRestore+"?token=token", nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tt.wantEvents, auditLog.types())
+
+			if tt.wantStatus == http.StatusSeeOther {
+				assert.Equal(t, paths.PathLogin, w.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestLoginPostRestoresDeletedAccount(t *testing.T) {
+	hash, _ := user.HashPassword("pw")
+
+	origFindByEmail := findByEmail
+	origUserHasTwoFactor := userHasTwoFactor
+
+	t.Cleanup(func() {
+		findByEmail = origFindByEmail
+		userHasTwoFactor = origUserHasTwoFactor
+	})
+
+	findByEmail = func(database.DatabaseInterface, string) (*user.User, error) {
+		return nil, user.ErrInvalidCredentials
+	}
+
+	tests := []struct {
+		name          string
+		password      string
+		twoFactor     bool
+		noGracePeriod bool
+		setupMock     func(mock sqlmock.Sqlmock)
+		wantStatus    int
+		wantPath      string
+		wantEvents    []audit.EventType
+	}{
+		{
+			name:     "success",
+			password: "pw",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE users SET deleted_at = NULL").WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM user_tokens").WithArgs(1, "restore").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("UPDATE users SET username").WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+			},
+			wantStatus: http.StatusSeeOther,
+			wantPath:   paths.PathAccount,
+			wantEvents: []audit.EventType{audit.EventAccountRestored, audit.EventLoginSucceeded},
+		},
+		{
+			name:       "two-factor required",
+			password:   "pw",
+			twoFactor:  true,
+			wantStatus: http.StatusSeeOther,
+			wantPath:   pathLoginTwoFactor,
+			wantEvents: []audit.EventType{},
+		},
+		{
+			name:       "wrong password",
+			password:   "wrong",
+			wantStatus: http.StatusSeeOther,
+			wantPath:   paths.PathLogin,
+			wantEvents: []audit.EventType{audit.EventLoginFailed},
+		},
+		{
+			name:          "grace period disabled",
+			password:      "pw",
+			noGracePeriod: true,
+			wantStatus:    http.StatusSeeOther,
+			wantPath:      paths.PathLogin,
+			wantEvents:    []audit.EventType{audit.EventLoginFailed},
+		},
+		{
+			name:     "restore error",
+			password: "pw",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE users SET deleted_at = NULL").WillReturnError(errors.New("db fail"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantEvents: []audit.EventType{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMemoryLockoutStore(t)
+			auditLog := useRecordingAuditLog(t)
+			userHasTwoFactor = func(*user.User, database.DatabaseInterface) (bool, error) { return tt.twoFactor, nil }
+
+			if tt.noGracePeriod {
+				setDeletionGraceDays(t, 0)
+			}
+
+			useDeletedUsers(t, user.New(user.UserFields{
+				Id:        1,
+				Username:  "user",
+				Email:     "user@example.com",
+				Password:  hash,
+				Status:    true,
+				DeletedAt: time.Now().Add(-time.Hour),
+			}))
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tt.setupMock != nil {
+				tt.setupMock(mock)
+			}
+
+			router.POST(paths.PathLogin, LoginPost)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, loginRequest("user@example.com", tt.password))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantPath, w.Header().Get("Location"))
+			assert.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tt.wantEvents, auditLog.types())
+		})
+	}
+}
+
+func TestLoginTwoFactorPostRestoresDeletedAccount(t *testing.T) {
+	origFindByID := findByID
+	origFindDeletedByID := findDeletedByID
+	origUserVerifyTwoFactor := userVerifyTwoFactor
+
+	t.Cleanup(func() {
+		findByID = origFindByID
+		findDeletedByID = origFindDeletedByID
+		userVerifyTwoFactor = origUserVerifyTwoFactor
+	})
+
+	findByID = func(database.DatabaseInterface, int) (*user.User, error) {
+		return nil, errors.New("the user has been deleted")
+	}
+
+	userVerifyTwoFactor = func(*user.User, database.DatabaseInterface, string) error { return nil }
+
+	tests := []struct {
+		name       string
+		findErr    error
+		setupMock  func(mock sqlmock.Sqlmock)
+		wantStatus int
+		wantPath   string
+		wantEvents []audit.EventType
+	}{
+		{
+			name: "success",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE users SET deleted_at = NULL").WithArgs(sqlmock.AnyArg(), 42).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM user_tokens").WithArgs(42, "restore").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("UPDATE users SET username").WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+			},
+			wantStatus: http.StatusSeeOther,
+			wantPath:   paths.PathAccount,
+			wantEvents: []audit.EventType{audit.EventAccountRestored, audit.EventLoginSucceeded},
+		},
+		{
+			name:       "purged in the meantime",
+			findErr:    user.ErrNotDeleted,
+			wantStatus: http.StatusInternalServerError,
+			wantEvents: []audit.EventType{},
+		},
+		{
+			name: "restore error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE users SET deleted_at = NULL").WillReturnError(errors.New("db fail"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantEvents: []audit.EventType{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMemoryLockoutStore(t)
+			auditLog := useRecordingAuditLog(t)
+
+			findDeletedByID = func(_ database.DatabaseInterface, id int) (*user.User, error) {
+				if tt.findErr != nil {
+					return nil, tt.findErr
+				}
+
+				return user.New(user.UserFields{
+					Id:        id,
+					Username:  "user",
+					Email:     "user@example.com",
+					Status:    true,
+					DeletedAt: time.Now().Add(-time.Hour),
+				}), nil
+			}
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tt.setupMock != nil {
+				tt.setupMock(mock)
+			}
+
+			router.POST(
+				pathLoginTwoFactor,
+				setPendingTwoFactorLogin(time.Now(), 0),
+				func(c *gin.Context) {
+					sessions.Default(c).Set(sessionKeyTwoFactorRestore, true)
+					c.Next()
+				},
+				LoginTwoFactorPost,
+			)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, pathLoginTwoFactor, strings.NewReader("code=123456"))
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantPath, w.Header().Get("Location"))
+			assert.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tt.wantEvents, auditLog.types())
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+)
+
+const errAPIScopeRequired = "Select at least one scope"
+
+func accountTokensData(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface) (RouteData, error) {
+	tokens, err := usr.GetAPITokens(db)
+
+	if err != nil {
+		return RouteData{}, err
+	}
+
+	return RouteData{
+		Template:    "pages/account_tokens",
+		Title:       "API Tokens",
+		Description: "Use the API from your own scripts.",
+		HttpStatus:  http.StatusOK,
+		Data: map[string]any{
+			"Tokens":        tokens,
+			"Scopes":        user.APIScopes,
+			"NameMaxLength": user.APITokenNameMaxLength,
+		},
+		FormData: FormData{
+			Values: v.GetFormData(),
+			Errors: v.GetSessionErrors(),
+		},
+		CSRFToken: middleware.GetCSRFToken(c),
+	}, nil
+}
+
+func AccountTokens(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	data, err := accountTokensData(c, v, usr, db)
+
+	if err != nil {
+		log.Error("Could not get the API tokens", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	RenderRouteHTML(c, data)
+
+	v.ClearSession()
+}
+
+func AccountTokensPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	path := fmt.Sprintf("%s/tokens", paths.PathAccount)
+	err := v.ValidateForm(c.Request)
+
+	if err != nil {
+		log.Error("Failed to parse form data", logger.Fields{"error": err.Error()})
+	}
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	switch v.GetFormValue(c.Request, "action") {
+	case "create":
+		accountTokensCreate(c, v, usr, db, path)
+
+	case "revoke":
+		accountTokensRevoke(c, v, usr, db, path)
+
+	default:
+		route_utils.RedirectWithError(c, v, nil, "Unknown action", path)
+	}
+}
+
+func accountTokensCreate(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface, path string) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+
+	name := strings.TrimSpace(v.GetFormValue(c.Request, "name"))
+	scopes := c.Request.PostForm["scopes"]
+
+	v.Required("name", name)
+	v.MaxLength("name", name, user.APITokenNameMaxLength)
+
+	if len(scopes) == 0 {
+		v.AddFieldError("scopes", errAPIScopeRequired)
+	}
+
+	if v.HasErrors() {
+		route_utils.RedirectWithError(c, v, map[string]string{"name": name}, "Please correct the errors below", path)
+		return
+	}
+
+	token, err := usr.CreateAPIToken(db, name, scopes)
+
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidAPIScope) {
+			v.AddFieldError("scopes", err.Error())
+			route_utils.RedirectWithError(c, v, map[string]string{"name": name}, "Please correct the errors below", path)
+			return
+		}
+
+		log.Error("Could not create the API token", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("API token created", logger.Fields{"userID": usr.GetID()})
+
+	data, err := accountTokensData(c, v, usr, db)
+
+	if err != nil {
+		log.Error("Could not get the API tokens", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	// The token is rendered straight away instead of redirecting,
+	// so that it never has to be stored anywhere in plain text.
+	data.Data["NewToken"] = token
+
+	RenderRouteHTML(c, data)
+}
+
+func accountTokensRevoke(c *gin.Context, v *validator.Validator, usr *user.User, db database.DatabaseInterface, path string) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	id, err := strconv.Atoi(v.GetFormValue(c.Request, "id"))
+
+	if err != nil {
+		route_utils.RedirectWithError(c, v, nil, user.ErrAPITokenNotFound.Error(), path)
+		return
+	}
+
+	err = usr.RevokeAPIToken(db, id)
+
+	if err != nil {
+		if errors.Is(err, user.ErrAPITokenNotFound) {
+			route_utils.RedirectWithError(c, v, nil, user.ErrAPITokenNotFound.Error(), path)
+			return
+		}
+
+		log.Error("Could not revoke the API token", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("API token revoked", logger.Fields{"userID": usr.GetID()})
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: "The API token has been revoked."})
+	c.Redirect(http.StatusSeeOther, path)
+}
@@ -0,0 +1,206 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	pathAccountTokens = paths.PathAccount + "/tokens"
+
+	apiTokenColumns = []string{"id", "name", "scopes", "created_at", "last_used_at"}
+)
+
+func expectAPITokens(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT (.+) FROM user_api_tokens WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(apiTokenColumns).AddRow(1, "Deploy script", "account:read", time.Now(), nil))
+}
+
+func TestAccountTokens(t *testing.T) {
+	tests := []struct {
+		name         string
+		setupMock    func(mock sqlmock.Sqlmock)
+		expectStatus int
+		expectBody   string
+	}{
+		{
+			name: "success",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				expectAPITokens(mock)
+			},
+			expectStatus: http.StatusOK,
+			expectBody:   "Deploy script",
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectQuery(`SELECT (.+) FROM user_api_tokens`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			tc.setupMock(mock)
+
+			router.Use(setSessionUserID(1))
+			router.GET(pathAccountTokens, AccountTokens)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", pathAccountTokens, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tc.expectBody)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAccountTokensPost(t *testing.T) {
+	tests := []struct {
+		name           string
+		form           url.Values
+		setupMock      func(mock sqlmock.Sqlmock)
+		expectStatus   int
+		expectLocation string
+		expectBody     string
+	}{
+		{
+			name: "create",
+			form: url.Values{"action": {"create"}, "name": {"CI"}, "scopes": {"account:read", "account:write"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectExec(`INSERT INTO user_api_tokens`).
+					WithArgs(1, "CI", sqlmock.AnyArg(), "account:read,account:write", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectAPITokens(mock)
+			},
+			expectStatus: http.StatusOK,
+			expectBody:   "gws_",
+		},
+		{
+			name: "create without a name",
+			form: url.Values{"action": {"create"}, "name": {" "}, "scopes": {"account:read"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountTokens,
+		},
+		{
+			name: "create without scopes",
+			form: url.Values{"action": {"create"}, "name": {"CI"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountTokens,
+		},
+		{
+			name: "create with an unknown scope",
+			form: url.Values{"action": {"create"}, "name": {"CI"}, "scopes": {"admin"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountTokens,
+		},
+		{
+			name: "create database error",
+			form: url.Values{"action": {"create"}, "name": {"CI"}, "scopes": {"account:read"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectExec(`INSERT INTO user_api_tokens`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "revoke",
+			form: url.Values{"action": {"revoke"}, "id": {"1"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectExec(`DELETE FROM user_api_tokens`).
+					WithArgs(1, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountTokens,
+		},
+		{
+			name: "revoke with an invalid ID",
+			form: url.Values{"action": {"revoke"}, "id": {"abc"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountTokens,
+		},
+		{
+			name: "revoke someone else's token",
+			form: url.Values{"action": {"revoke"}, "id": {"2"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectExec(`DELETE FROM user_api_tokens`).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountTokens,
+		},
+		{
+			name: "revoke database error",
+			form: url.Values{"action": {"revoke"}, "id": {"1"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectExec(`DELETE FROM user_api_tokens`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "unknown action",
+			form: url.Values{"action": {"other"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountTokens,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			tc.setupMock(mock)
+
+			router.Use(setSessionUserID(1))
+			router.POST(pathAccountTokens, AccountTokensPost)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", pathAccountTokens, strings.NewReader(tc.form.Encode()))
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.Equal(t, tc.expectLocation, w.Header().Get("Location"))
+			assert.Contains(t, w.Body.String(), tc.expectBody)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/totp"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+func accountTwoFactorData(c *gin.Context, v *validator.Validator) RouteData {
+	return RouteData{
+		Template:    "pages/account_two_factor",
+		Title:       "Two-Factor Authentication",
+		Description: "Protect your account with an authenticator app.",
+		HttpStatus:  http.StatusOK,
+		Data:        map[string]any{},
+		FormData: FormData{
+			Values: v.GetFormData(),
+			Errors: v.GetSessionErrors(),
+		},
+		CSRFToken: middleware.GetCSRFToken(c),
+	}
+}
+
+func AccountTwoFactor(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	data := accountTwoFactorData(c, v)
+	tf, err := usr.GetTwoFactor(db)
+
+	if err != nil {
+		log.Error("Could not get the two-factor settings", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if tf.IsEnabled() {
+		codesLeft, err := usr.RecoveryCodesLeft(db)
+
+		if err != nil {
+			log.Error("Could not count the recovery codes", logger.Fields{"error": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+
+		data.Data["Enabled"] = true
+		data.Data["EnabledAt"] = *tf.ConfirmedAt
+		data.Data["RecoveryCodesLeft"] = codesLeft
+
+		RenderRouteHTML(c, data)
+		return
+	}
+
+	secret, err := usr.StartTwoFactorEnrollment(db)
+
+	if err != nil {
+		log.Error("Could not start the two-factor enrollment", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	data.Data["Secret"] = secret
+	// The otpauth scheme would otherwise be filtered out by html/template.
+	data.Data["URI"] = template.URL(totp.URI(viper.GetString("site.name"), usr.GetEmail(), secret))
+
+	RenderRouteHTML(c, data)
+}
+
+func AccountTwoFactorPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	err := v.ValidateForm(c.Request)
+
+	if err != nil {
+		log.Error("Failed to parse form data", map[string]any{"error": err.Error()})
+	}
+
+	switch v.GetFormValue(c.Request, "action") {
+	case "disable":
+		accountTwoFactorDisable(c, v)
+	case "recovery-codes":
+		accountTwoFactorRecoveryCodes(c, v)
+	default:
+		accountTwoFactorEnable(c, v)
+	}
+}
+
+func accountTwoFactorEnable(c *gin.Context, v *validator.Validator) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	path := fmt.Sprintf("%s/two-factor", paths.PathAccount)
+
+	code := v.GetFormValue(c.Request, "code")
+	v.Required("code", code)
+
+	if v.HasErrors() {
+		route_utils.RedirectWithError(c, v, nil, "Please correct the errors below", path)
+		return
+	}
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	recoveryCodes, err := usr.ConfirmTwoFactor(db, code)
+
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidTwoFactorCode) {
+			v.AddFieldError("code", user.ErrInvalidTwoFactorCode.Error())
+			route_utils.RedirectWithError(c, v, nil, user.ErrInvalidTwoFactorCode.Error(), path)
+
+			return
+		}
+
+		if errors.Is(err, user.ErrTwoFactorEnabled) || errors.Is(err, user.ErrTwoFactorNotEnrolled) {
+			c.Redirect(http.StatusSeeOther, path)
+			return
+		}
+
+		log.Error("Could not enable two-factor authentication", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Two-factor authentication enabled", logger.Fields{"userID": usr.GetID()})
+
+	// The recovery codes are rendered straight away instead of redirecting,
+	// so that they never have to be stored anywhere in plain text.
+	data := accountTwoFactorData(c, v)
+	data.Data["Enabled"] = true
+	data.Data["RecoveryCodes"] = recoveryCodes
+
+	RenderRouteHTML(c, data)
+}
+
+func checkPasswordAndTwoFactor(
+	c *gin.Context,
+	v *validator.Validator,
+	db database.DatabaseInterface,
+	usr *user.User,
+	path string,
+) bool {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+
+	password := v.GetFormValue(c.Request, "password")
+	code := v.GetFormValue(c.Request, "code")
+
+	v.Required("password", password)
+	v.Required("code", code)
+
+	if v.HasErrors() {
+		route_utils.RedirectWithError(c, v, nil, "Please correct the errors below", path)
+		return false
+	}
+
+	err := usr.CheckPassword(db, password)
+
+	if err == nil {
+		err = userVerifyTwoFactor(usr, db, code)
+	}
+
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			v.AddFieldError("password", user.ErrInvalidCredentials.Error())
+			route_utils.RedirectWithError(c, v, nil, user.ErrInvalidCredentials.Error(), path)
+
+			return false
+		}
+
+		if errors.Is(err, user.ErrInvalidTwoFactorCode) {
+			v.AddFieldError("code", user.ErrInvalidTwoFactorCode.Error())
+			route_utils.RedirectWithError(c, v, nil, user.ErrInvalidTwoFactorCode.Error(), path)
+
+			return false
+		}
+
+		log.Error("Could not verify the password or authentication code", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return false
+	}
+
+	return true
+}
+
+func accountTwoFactorDisable(c *gin.Context, v *validator.Validator) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	path := fmt.Sprintf("%s/two-factor", paths.PathAccount)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if !checkPasswordAndTwoFactor(c, v, db, usr, path) {
+		return
+	}
+
+	err = usr.DisableTwoFactor(db)
+
+	if err != nil {
+		log.Error("Could not disable two-factor authentication", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Two-factor authentication disabled", logger.Fields{"userID": usr.GetID()})
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: "Two-factor authentication has been disabled.",
+	})
+
+	c.Redirect(http.StatusSeeOther, paths.PathAccount)
+}
+
+func accountTwoFactorRecoveryCodes(c *gin.Context, v *validator.Validator) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	path := fmt.Sprintf("%s/two-factor", paths.PathAccount)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if !checkPasswordAndTwoFactor(c, v, db, usr, path) {
+		return
+	}
+
+	recoveryCodes, err := usr.RegenerateRecoveryCodes(db)
+
+	if err != nil {
+		log.Error("Could not regenerate the recovery codes", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	data := accountTwoFactorData(c, v)
+	data.Data["Enabled"] = true
+	data.Data["RecoveryCodes"] = recoveryCodes
+
+	RenderRouteHTML(c, data)
+}
@@ -0,0 +1,320 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var pathAccountTwoFactor = paths.PathAccount + "/two-factor"
+
+func setSessionUserID(userID int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session := sessions.Default(c)
+		session.Set("userID", userID)
+
+		c.Next()
+	}
+}
+
+func expectSessionUser(mock sqlmock.Sqlmock, passwordHash string) {
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(1).
+		WillReturnRows(
+			sqlmock.NewRows(
+				[]string{"id", "username", "email", "password", "status", "created_at", "updated_at", "last_login", "avatar"},
+			).
+				AddRow(1, "username", "test@example.com", passwordHash, true, now, now, now, ""),
+		)
+}
+
+func TestAccountTwoFactor(t *testing.T) {
+	tests := []struct {
+		name         string
+		loggedIn     bool
+		setupMock    func(mock sqlmock.Sqlmock)
+		expectStatus int
+		expectBody   []string
+	}{
+		{
+			name:         "not logged in",
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:     "lookup error",
+			loggedIn: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectQuery(`SELECT secret, last_used_step, confirmed_at FROM user_two_factor`).
+					WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:     "start enrollment",
+			loggedIn: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectQuery(`SELECT secret, last_used_step, confirmed_at FROM user_two_factor`).
+					WillReturnRows(sqlmock.NewRows([]string{"secret", "last_used_step", "confirmed_at"}))
+				mock.ExpectQuery(`SELECT secret, last_used_step, confirmed_at FROM user_two_factor`).
+					WillReturnRows(sqlmock.NewRows([]string{"secret", "last_used_step", "confirmed_at"}))
+				mock.ExpectExec(`INSERT INTO user_two_factor`).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectSessionUser(mock, "")
+			},
+			expectStatus: http.StatusOK,
+			expectBody:   []string{"otpauth://totp/", "Enable Two-Factor"},
+		},
+		{
+			name:     "enrollment error",
+			loggedIn: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectQuery(`SELECT secret, last_used_step, confirmed_at FROM user_two_factor`).
+					WillReturnRows(sqlmock.NewRows([]string{"secret", "last_used_step", "confirmed_at"}))
+				mock.ExpectQuery(`SELECT secret, last_used_step, confirmed_at FROM user_two_factor`).
+					WillReturnRows(sqlmock.NewRows([]string{"secret", "last_used_step", "confirmed_at"}))
+				mock.ExpectExec(`INSERT INTO user_two_factor`).
+					WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:     "enabled",
+			loggedIn: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectQuery(`SELECT secret, last_used_step, confirmed_at FROM user_two_factor`).
+					WillReturnRows(sqlmock.NewRows([]string{"secret", "last_used_step", "confirmed_at"}).AddRow("SECRET", 1, time.Now()))
+				mock.ExpectQuery(`SELECT COUNT\(\*\) FROM user_recovery_codes`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+				expectSessionUser(mock, "")
+			},
+			expectStatus: http.StatusOK,
+			expectBody:   []string{"7 recovery codes left", "Disable Two-Factor"},
+		},
+		{
+			name:     "count error",
+			loggedIn: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectQuery(`SELECT secret, last_used_step, confirmed_at FROM user_two_factor`).
+					WillReturnRows(sqlmock.NewRows([]string{"secret", "last_used_step", "confirmed_at"}).AddRow("SECRET", 1, time.Now()))
+				mock.ExpectQuery(`SELECT COUNT\(\*\) FROM user_recovery_codes`).
+					WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tc.loggedIn {
+				router.Use(setSessionUserID(1))
+			}
+
+			if tc.setupMock != nil {
+				tc.setupMock(mock)
+			}
+
+			router.GET(pathAccountTwoFactor, AccountTwoFactor)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", pathAccountTwoFactor, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+
+			for _, body := range tc.expectBody {
+				assert.Contains(t, w.Body.String(), body)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAccountTwoFactorPost(t *testing.T) {
+	origUserVerifyTwoFactor := userVerifyTwoFactor
+	defer func() { userVerifyTwoFactor = origUserVerifyTwoFactor }()
+
+	hash, _ := user.HashPassword("pw")
+
+	tests := []struct {
+		name           string
+		form           url.Values
+		verifyErr      error
+		setupMock      func(mock sqlmock.Sqlmock)
+		expectStatus   int
+		expectLocation string
+		expectBody     []string
+	}{
+		{
+			name:           "enable without code",
+			form:           url.Values{},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountTwoFactor,
+		},
+		{
+			name: "enable with invalid code",
+			form: url.Values{"code": {"abc"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectQuery(`SELECT secret, last_used_step, confirmed_at FROM user_two_factor`).
+					WillReturnRows(sqlmock.NewRows([]string{"secret", "last_used_step", "confirmed_at"}).AddRow("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", 0, nil))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountTwoFactor,
+		},
+		{
+			name: "enable without enrollment",
+			form: url.Values{"code": {"123456"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectQuery(`SELECT secret, last_used_step, confirmed_at FROM user_two_factor`).
+					WillReturnRows(sqlmock.NewRows([]string{"secret", "last_used_step", "confirmed_at"}))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountTwoFactor,
+		},
+		{
+			name: "enable error",
+			form: url.Values{"code": {"123456"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, "")
+				mock.ExpectQuery(`SELECT secret, last_used_step, confirmed_at FROM user_two_factor`).
+					WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "disable without password",
+			form:           url.Values{"action": {"disable"}, "code": {"123456"}},
+			setupMock:      func(mock sqlmock.Sqlmock) { expectSessionUser(mock, hash) },
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountTwoFactor,
+		},
+		{
+			name:           "disable with wrong password",
+			form:           url.Values{"action": {"disable"}, "password": {"wrong"}, "code": {"123456"}},
+			setupMock:      func(mock sqlmock.Sqlmock) { expectSessionUser(mock, hash) },
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountTwoFactor,
+		},
+		{
+			name:           "disable with wrong code",
+			form:           url.Values{"action": {"disable"}, "password": {"pw"}, "code": {"123456"}},
+			verifyErr:      user.ErrInvalidTwoFactorCode,
+			setupMock:      func(mock sqlmock.Sqlmock) { expectSessionUser(mock, hash) },
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathAccountTwoFactor,
+		},
+		{
+			name:         "disable with verification error",
+			form:         url.Values{"action": {"disable"}, "password": {"pw"}, "code": {"123456"}},
+			verifyErr:    errors.New("db fail"),
+			setupMock:    func(mock sqlmock.Sqlmock) { expectSessionUser(mock, hash) },
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "disable",
+			form: url.Values{"action": {"disable"}, "password": {"pw"}, "code": {"123456"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, hash)
+				mock.ExpectExec(`DELETE FROM user_recovery_codes`).WillReturnResult(sqlmock.NewResult(0, 10))
+				mock.ExpectExec(`DELETE FROM user_two_factor`).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathAccount,
+		},
+		{
+			name: "disable error",
+			form: url.Values{"action": {"disable"}, "password": {"pw"}, "code": {"123456"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, hash)
+				mock.ExpectExec(`DELETE FROM user_recovery_codes`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "regenerate recovery codes",
+			form: url.Values{"action": {"recovery-codes"}, "password": {"pw"}, "code": {"123456"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, hash)
+				mock.ExpectBegin()
+				mock.ExpectExec(`DELETE FROM user_recovery_codes`).WillReturnResult(sqlmock.NewResult(0, 10))
+
+				for range user.RecoveryCodeCount {
+					mock.ExpectExec(`INSERT INTO user_recovery_codes`).WillReturnResult(sqlmock.NewResult(1, 1))
+				}
+
+				mock.ExpectCommit()
+				expectSessionUser(mock, hash)
+			},
+			expectStatus: http.StatusOK,
+			expectBody:   []string{"Recovery Codes", "will not be", "shown again"},
+		},
+		{
+			name: "regenerate recovery codes error",
+			form: url.Values{"action": {"recovery-codes"}, "password": {"pw"}, "code": {"123456"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSessionUser(mock, hash)
+				mock.ExpectBegin().WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			userVerifyTwoFactor = func(*user.User, database.DatabaseInterface, string) error {
+				return tc.verifyErr
+			}
+
+			if tc.setupMock != nil {
+				tc.setupMock(mock)
+			}
+
+			router.Use(setSessionUserID(1))
+			router.POST(pathAccountTwoFactor, AccountTwoFactorPost)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", pathAccountTwoFactor, strings.NewReader(tc.form.Encode()))
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+
+			if tc.expectLocation != "" {
+				assert.Equal(t, tc.expectLocation, w.Header().Get("Location"))
+			}
+
+			for _, body := range tc.expectBody {
+				assert.Contains(t, w.Body.String(), body)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
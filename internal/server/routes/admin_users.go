@@ -0,0 +1,345 @@
+package routes
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	emailer "github.com/Dobefu/go-web-starter/internal/email"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	adminUsersPerPage = 25
+
+	errAdminSelf          = "You cannot do this to your own account."
+	errAdminDeleteConfirm = "Enter the username to confirm the deletion."
+)
+
+var pathAdminUsers = fmt.Sprintf("%s/users", paths.PathAdmin)
+
+var searchUsers = user.Search
+
+var userGetRoles = func(usr *user.User, db database.DatabaseInterface) ([]string, error) {
+	return usr.GetRoles(db)
+}
+
+func AdminIndex(c *gin.Context) {
+	c.Redirect(http.StatusSeeOther, pathAdminUsers)
+}
+
+func AdminUsers(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Could not get the database from the context", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	query := c.Query("q")
+	page, err := strconv.Atoi(c.Query("page"))
+
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	users, total, err := searchUsers(db, query, adminUsersPerPage, (page-1)*adminUsersPerPage)
+
+	if err != nil {
+		log.Error("Could not search the users", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	totalPages := max((total+adminUsersPerPage-1)/adminUsersPerPage, 1)
+
+	data := RouteData{
+		Template:   "pages/admin_users",
+		HttpStatus: http.StatusOK,
+
+		Title:       "Users",
+		Description: "Manage the users of the site.",
+
+		Data: map[string]any{
+			"Users":      users,
+			"Total":      total,
+			"Query":      query,
+			"Pagination": adminPagination(query, page, totalPages),
+		},
+	}
+
+	RenderRouteHTML(c, data)
+}
+
+func adminPagination(query string, page, totalPages int) map[string]any {
+	href := func(page int) string {
+		values := url.Values{}
+
+		if query != "" {
+			values.Set("q", query)
+		}
+
+		if page > 1 {
+			values.Set("page", strconv.Itoa(page))
+		}
+
+		if len(values) == 0 {
+			return pathAdminUsers
+		}
+
+		return fmt.Sprintf("%s?%s", pathAdminUsers, values.Encode())
+	}
+
+	pagination := map[string]any{
+		"Page":       page,
+		"TotalPages": totalPages,
+	}
+
+	if page > 1 {
+		pagination["PrevHref"] = href(min(page-1, totalPages))
+	}
+
+	if page < totalPages {
+		pagination["NextHref"] = href(page + 1)
+	}
+
+	return pagination
+}
+
+func getAdminUser(c *gin.Context, db database.DatabaseInterface) *user.User {
+	id, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil || id < 1 {
+		NotFound(c)
+		return nil
+	}
+
+	usr, err := findByID(db, id)
+
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			NotFound(c)
+			return nil
+		}
+
+		log := logger.New(config.GetLogLevel(), os.Stdout)
+		log.Error("Could not find the user", logger.Fields{"userID": id, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return nil
+	}
+
+	return usr
+}
+
+func AdminUser(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Could not get the database from the context", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	account := getAdminUser(c, db)
+
+	if account == nil {
+		return
+	}
+
+	roles, err := userGetRoles(account, db)
+
+	if err != nil {
+		log.Error("Could not get the roles of the user", logger.Fields{"userID": account.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	hasTwoFactor, err := userHasTwoFactor(account, db)
+
+	if err != nil {
+		log.Error("Could not check two-factor authentication", logger.Fields{"userID": account.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	impersonateBlocked, err := canImpersonate(db, getSession(c).Get("userID"), account)
+
+	if err != nil {
+		log.Error("Could not check whether the user can be impersonated", logger.Fields{"userID": account.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	data := RouteData{
+		Template:   "pages/admin_user",
+		HttpStatus: http.StatusOK,
+
+		Title:       account.GetUsername(),
+		Description: "View and manage a user.",
+
+		Data: map[string]any{
+			"Account":        account,
+			"Roles":          roles,
+			"HasTwoFactor":   hasTwoFactor,
+			"IsSelf":         getSession(c).Get("userID") == account.GetID(),
+			"CanImpersonate": impersonateBlocked == "",
+		},
+		CSRFToken: middleware.GetCSRFToken(c),
+	}
+
+	RenderRouteHTML(c, data)
+}
+
+func AdminUserPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	err := v.ValidateForm(c.Request)
+
+	if err != nil {
+		log.Error("Failed to parse form data", logger.Fields{"error": err.Error()})
+	}
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Could not get the database from the context", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	account := getAdminUser(c, db)
+
+	if account == nil {
+		return
+	}
+
+	adminID, _ := getSession(c).Get("userID").(int)
+	accountPath := fmt.Sprintf("%s/%d", pathAdminUsers, account.GetID())
+	action := v.GetFormValue(c.Request, "action")
+
+	if action == "impersonate" {
+		adminImpersonate(c, v, db, account)
+		return
+	}
+
+	if adminID == account.GetID() && action != "reset-password" {
+		v.SetFlash(message.Message{Type: message.MessageTypeError, Body: errAdminSelf})
+		c.Redirect(http.StatusSeeOther, accountPath)
+
+		return
+	}
+
+	var body string
+	redirectPath := accountPath
+
+	switch action {
+	case "activate", "deactivate":
+		account.SetStatus(action == "activate")
+		err = account.Save(db)
+
+		if err == nil && action == "deactivate" {
+			err = revokeAccess(c, db, account)
+		}
+
+		body = fmt.Sprintf("%s has been %sd.", account.GetUsername(), action)
+
+	case "reset-password":
+		err = adminResetPassword(db, account)
+		body = fmt.Sprintf("A password reset link has been sent to %s.", account.GetEmail())
+
+	case "delete":
+		if v.GetFormValue(c.Request, "confirm") != account.GetUsername() {
+			v.SetFlash(message.Message{Type: message.MessageTypeError, Body: errAdminDeleteConfirm})
+			c.Redirect(http.StatusSeeOther, accountPath)
+
+			return
+		}
+
+		var purgeAt time.Time
+		purgeAt, err = deleteAccount(c, db, adminID, account, "admin")
+		body = fmt.Sprintf("%s has been deleted.", account.GetUsername())
+		redirectPath = pathAdminUsers
+
+		if !purgeAt.IsZero() {
+			body = fmt.Sprintf("%s has been deleted. The account can be restored until %s.", account.GetUsername(), formatPurgeDate(purgeAt))
+		}
+
+	default:
+		v.SetFlash(message.Message{Type: message.MessageTypeError, Body: "Unknown action"})
+		c.Redirect(http.StatusSeeOther, accountPath)
+
+		return
+	}
+
+	if err != nil {
+		log.Error("Could not update the user", logger.Fields{"userID": account.GetID(), "action": action, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("User updated by an admin", logger.Fields{"userID": account.GetID(), "adminID": adminID, "action": action})
+	auditEvent(c, audit.EventUserAdminAction, adminID, account.GetID(), audit.Metadata{"action": action})
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: body})
+	c.Redirect(http.StatusSeeOther, redirectPath)
+}
+
+func adminResetPassword(db database.DatabaseInterface, usr *user.User) error {
+	err := usr.ChangePassword(db, rand.Text())
+
+	if err != nil {
+		return err
+	}
+
+	token, err := usr.CreateToken(db, user.TokenPurposeReset, user.TokenTTLReset)
+
+	if err != nil {
+		return err
+	}
+
+	return newEmailSender().SendMail(
+		viper.GetString("site.email"),
+		[]string{usr.GetEmail()},
+		fmt.Sprintf("Reset your %s password", viper.GetString("site.name")),
+		emailer.EmailBody{
+			Template: "email/forgot_password",
+			Data: map[string]any{
+				"Username": usr.GetUsername(),
+				"Token":    token,
+				"Email":    usr.GetEmail(),
+			},
+		},
+	)
+}
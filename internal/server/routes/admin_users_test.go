@@ -0,0 +1,470 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	server_utils "github.com/Dobefu/go-web-starter/internal/server/utils"
+	"github.com/Dobefu/go-web-starter/internal/templates"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAdminRouter(db database.DatabaseInterface, handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	router.Use(sessions.Sessions("mysession", cookie.NewStore([]byte("secret"))))
+	router.Use(middleware.Database(db))
+	router.Use(handlers...)
+
+	router.SetFuncMap(server_utils.TemplateFuncMap())
+	_ = templates.LoadTemplates(router)
+
+	router.GET("/admin/users", AdminUsers)
+	router.GET("/admin/users/:id", AdminUser)
+	router.POST("/admin/users/:id", AdminUserPost)
+
+	return router
+}
+
+func patchAdminUserLookups(t *testing.T, usr *user.User, findErr error, roles []string, rolesErr error) {
+	origFindByID := findByID
+	origGetRoles := userGetRoles
+
+	t.Cleanup(func() {
+		findByID = origFindByID
+		userGetRoles = origGetRoles
+	})
+
+	findByID = func(database.DatabaseInterface, int) (*user.User, error) { return usr, findErr }
+	userGetRoles = func(*user.User, database.DatabaseInterface) ([]string, error) { return roles, rolesErr }
+}
+
+func adminUserRequest(path, action string) *http.Request {
+	return adminUserFormRequest(path, url.Values{"action": {action}})
+}
+
+func adminUserFormRequest(path string, form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req
+}
+
+func TestAdminIndex(t *testing.T) {
+	router := gin.New()
+	router.GET("/admin", AdminIndex)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/admin/users", w.Header().Get("Location"))
+}
+
+func TestAdminUsers(t *testing.T) {
+	origSearchUsers := searchUsers
+	defer func() { searchUsers = origSearchUsers }()
+
+	tests := []struct {
+		name       string
+		path       string
+		users      []*user.User
+		total      int
+		searchErr  error
+		wantQuery  string
+		wantOffset int
+		wantStatus int
+		wantBody   []string
+	}{
+		{
+			name:       "first page",
+			path:       "/admin/users",
+			users:      []*user.User{newTestUserWithID(2)},
+			total:      1,
+			wantStatus: http.StatusOK,
+			wantBody:   []string{"1 user found", "/admin/users/2", "user@example.com"},
+		},
+		{
+			name:       "search and page",
+			path:       "/admin/users?q=user&page=3",
+			total:      60,
+			wantQuery:  "user",
+			wantOffset: 50,
+			wantStatus: http.StatusOK,
+			wantBody:   []string{"60 users found", `href="/admin/users?page=2&amp;q=user"`},
+		},
+		{
+			name:       "invalid page",
+			path:       "/admin/users?page=-1",
+			wantStatus: http.StatusOK,
+			wantBody:   []string{"0 users found"},
+		},
+		{
+			name:       "search error",
+			path:       "/admin/users",
+			searchErr:  errors.New("database error"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			var gotQuery string
+			var gotOffset int
+
+			searchUsers = func(_ database.DatabaseInterface, query string, limit, offset int) ([]*user.User, int, error) {
+				gotQuery = query
+				gotOffset = offset
+
+				assert.Equal(t, adminUsersPerPage, limit)
+
+				return tt.users, tt.total, tt.searchErr
+			}
+
+			w := httptest.NewRecorder()
+			setupAdminRouter(db).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantQuery, gotQuery)
+			assert.Equal(t, tt.wantOffset, gotOffset)
+
+			for _, want := range tt.wantBody {
+				assert.Contains(t, w.Body.String(), want)
+			}
+		})
+	}
+}
+
+func TestAdminPagination(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		page       int
+		totalPages int
+		want       map[string]any
+	}{
+		{
+			name:       "single page",
+			page:       1,
+			totalPages: 1,
+			want:       map[string]any{"Page": 1, "TotalPages": 1},
+		},
+		{
+			name:       "first of several",
+			page:       1,
+			totalPages: 3,
+			want:       map[string]any{"Page": 1, "TotalPages": 3, "NextHref": "/admin/users?page=2"},
+		},
+		{
+			name:       "second page with a query",
+			query:      "a b",
+			page:       2,
+			totalPages: 3,
+			want: map[string]any{
+				"Page":       2,
+				"TotalPages": 3,
+				"PrevHref":   "/admin/users?q=a+b",
+				"NextHref":   "/admin/users?page=3&q=a+b",
+			},
+		},
+		{
+			name:       "past the last page",
+			page:       9,
+			totalPages: 3,
+			want:       map[string]any{"Page": 9, "TotalPages": 3, "PrevHref": "/admin/users?page=3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, adminPagination(tt.query, tt.page, tt.totalPages))
+		})
+	}
+}
+
+func TestAdminUser(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		sessionID    int
+		usr          *user.User
+		findErr      error
+		roles        []string
+		rolesErr     error
+		hasTwoFactor bool
+		isAdmin      bool
+		wantStatus   int
+		wantBody     []string
+		wantNotBody  []string
+	}{
+		{
+			name:         "found",
+			path:         "/admin/users/2",
+			sessionID:    1,
+			usr:          newTestUserWithID(2),
+			roles:        []string{"admin", "editor"},
+			hasTwoFactor: true,
+			wantStatus:   http.StatusOK,
+			wantBody:     []string{"user@example.com", "admin, editor", "Enabled", "value=deactivate", "value=delete", "value=impersonate"},
+		},
+		{
+			name:        "another admin",
+			path:        "/admin/users/2",
+			sessionID:   1,
+			usr:         newTestUserWithID(2),
+			isAdmin:     true,
+			wantStatus:  http.StatusOK,
+			wantBody:    []string{"value=deactivate"},
+			wantNotBody: []string{"value=impersonate"},
+		},
+		{
+			name:        "own account",
+			path:        "/admin/users/2",
+			sessionID:   2,
+			usr:         newTestUserWithID(2),
+			wantStatus:  http.StatusOK,
+			wantBody:    []string{"None", "Disabled", "value=reset-password"},
+			wantNotBody: []string{"value=deactivate", "value=delete", "value=impersonate"},
+		},
+		{
+			name:       "invalid ID",
+			path:       "/admin/users/abc",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "not found",
+			path:       "/admin/users/2",
+			findErr:    user.ErrNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "lookup error",
+			path:       "/admin/users/2",
+			findErr:    errors.New("database error"),
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "roles error",
+			path:       "/admin/users/2",
+			usr:        newTestUserWithID(2),
+			rolesErr:   errors.New("database error"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			patchAdminUserLookups(t, tt.usr, tt.findErr, tt.roles, tt.rolesErr)
+			patchUserHasTwoFactor(t, tt.hasTwoFactor)
+			patchUserHasPermission(t, tt.isAdmin, nil)
+
+			w := httptest.NewRecorder()
+			setupAdminRouter(db, setSessionUserID(tt.sessionID)).
+				ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			for _, want := range tt.wantBody {
+				assert.Contains(t, w.Body.String(), want)
+			}
+
+			for _, notWant := range tt.wantNotBody {
+				assert.NotContains(t, w.Body.String(), notWant)
+			}
+		})
+	}
+}
+
+func TestAdminUserPost(t *testing.T) {
+	expectUserSaved := func(status bool) func(mock sqlmock.Sqlmock) {
+		return func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(`UPDATE users SET .+`).
+				WithArgs("user", "user@example.com", sqlmock.AnyArg(), status, sqlmock.AnyArg(), sqlmock.AnyArg(), 2).
+				WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+		}
+	}
+
+	tests := []struct {
+		name         string
+		action       string
+		confirm      string
+		sessionID    int
+		mockSetup    func(mock sqlmock.Sqlmock)
+		wantStatus   int
+		wantLocation string
+		wantTemplate string
+		wantEvents   []audit.EventType
+	}{
+		{
+			name:      "deactivate",
+			action:    "deactivate",
+			sessionID: 1,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				expectUserSaved(false)(mock)
+				expectRevokeAccess(mock, 2)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/admin/users/2",
+			wantEvents:   []audit.EventType{audit.EventUserAdminAction},
+		},
+		{
+			name:      "deactivate revoke error",
+			action:    "deactivate",
+			sessionID: 1,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				expectUserSaved(false)(mock)
+				mock.ExpectExec("DELETE FROM sessions").WillReturnError(errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "activate",
+			action:       "activate",
+			sessionID:    1,
+			mockSetup:    expectUserSaved(true),
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/admin/users/2",
+			wantEvents:   []audit.EventType{audit.EventUserAdminAction},
+		},
+		{
+			name:      "reset password",
+			action:    "reset-password",
+			sessionID: 1,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				expectUserSaved(true)(mock)
+				mock.ExpectExec("DELETE FROM user_tokens").WithArgs(2, user.TokenPurposeReset).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO user_tokens").WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/admin/users/2",
+			wantTemplate: "email/forgot_password",
+			wantEvents:   []audit.EventType{audit.EventUserAdminAction},
+		},
+		{
+			name:      "reset own password",
+			action:    "reset-password",
+			sessionID: 2,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				expectUserSaved(true)(mock)
+				mock.ExpectExec("DELETE FROM user_tokens").WithArgs(2, user.TokenPurposeReset).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO user_tokens").WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/admin/users/2",
+			wantTemplate: "email/forgot_password",
+			wantEvents:   []audit.EventType{audit.EventUserAdminAction},
+		},
+		{
+			name:      "delete",
+			action:    "delete",
+			confirm:   "user",
+			sessionID: 1,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE users SET deleted_at").WithArgs(sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectRevokeAccess(mock, 2)
+				mock.ExpectExec("DELETE FROM user_tokens").WithArgs(2, "restore").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO user_tokens").WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/admin/users",
+			wantTemplate: "email/account_deleted",
+			wantEvents:   []audit.EventType{audit.EventAccountDeleted, audit.EventUserAdminAction},
+		},
+		{
+			name:         "delete without confirmation",
+			action:       "delete",
+			confirm:      "someone else",
+			sessionID:    1,
+			mockSetup:    func(mock sqlmock.Sqlmock) {},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/admin/users/2",
+		},
+		{
+			name:         "delete own account",
+			action:       "delete",
+			sessionID:    2,
+			mockSetup:    func(mock sqlmock.Sqlmock) {},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/admin/users/2",
+		},
+		{
+			name:         "unknown action",
+			action:       "bogus",
+			sessionID:    1,
+			mockSetup:    func(mock sqlmock.Sqlmock) {},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/admin/users/2",
+		},
+		{
+			name:      "save error",
+			action:    "deactivate",
+			sessionID: 1,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE users SET .+`).WillReturnError(errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := useRecordingEmailSender(t)
+			auditLog := useRecordingAuditLog(t)
+
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			patchAdminUserLookups(t, newTestUserWithID(2), nil, nil, nil)
+			tt.mockSetup(mock)
+
+			w := httptest.NewRecorder()
+			setupAdminRouter(db, setSessionUserID(tt.sessionID)).
+				ServeHTTP(w, adminUserFormRequest("/admin/users/2", url.Values{"action": {tt.action}, "confirm": {tt.confirm}}))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+			assert.NoError(t, mock.ExpectationsWereMet())
+			assert.ElementsMatch(t, tt.wantEvents, auditLog.types())
+
+			if len(tt.wantEvents) > 0 {
+				event := auditLog.events[len(auditLog.events)-1]
+
+				assert.Equal(t, tt.sessionID, event.ActorID)
+				assert.Equal(t, 2, event.TargetID)
+				assert.Equal(t, tt.action, event.Metadata["action"])
+			}
+
+			if tt.wantTemplate == "" {
+				assert.Empty(t, sender.sent)
+				return
+			}
+
+			assert.Len(t, sender.sent, 1)
+			assert.Equal(t, []string{"user@example.com"}, sender.to[0])
+			assert.Equal(t, tt.wantTemplate, sender.sent[0].Template)
+		})
+	}
+}
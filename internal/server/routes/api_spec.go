@@ -0,0 +1,161 @@
+package routes
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/openapi"
+	"github.com/Dobefu/go-web-starter/internal/problem"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const apiVersion = "1.0.0"
+
+// APISpec describes every route of the JSON API. A route that is registered
+// in RegisterAPIRoutes must have an entry here, which the tests check.
+func APISpec() []openapi.Route {
+	v1 := paths.PathAPI + "/v1"
+
+	return []openapi.Route{
+		{
+			Method:      http.MethodGet,
+			Path:        paths.PathAPI + "/openapi.json",
+			OperationID: "getOpenAPIDocument",
+			Summary:     "Get the OpenAPI document of the API",
+			Tags:        []string{"Meta"},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Description: "The OpenAPI document", Body: map[string]any{}},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        paths.PathAPI + "/token",
+			OperationID: "getToken",
+			Summary:     "Describe the API token of the request",
+			Tags:        []string{"Tokens"},
+			Auth:        true,
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: apiTokenResponse{}},
+				apiProblemResponse(http.StatusUnauthorized),
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        v1 + "/register",
+			OperationID: "register",
+			Summary:     "Create an account",
+			Description: "The account has to be activated with the link in the email that is sent. Not available when registration is by invitation only. When legal documents have been published, accept_legal has to be true to accept their current versions.",
+			Tags:        []string{"Authentication"},
+			Request:     apiRegisterRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusCreated, Description: "The inactive account", Body: apiUserResponse{}},
+				apiProblemResponse(http.StatusForbidden),
+				apiProblemResponse(http.StatusUnprocessableEntity),
+				apiProblemResponse(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        v1 + "/login",
+			OperationID: "login",
+			Summary:     "Log in and issue an API token",
+			Description: "Accounts with two-factor authentication need to send an authentication code or a recovery code as well.",
+			Tags:        []string{"Authentication"},
+			Request:     apiLoginRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: apiLoginResponse{}},
+				apiProblemResponse(http.StatusUnauthorized),
+				apiProblemResponse(http.StatusForbidden),
+				apiProblemResponse(http.StatusUnprocessableEntity),
+				apiProblemResponse(http.StatusTooManyRequests),
+				apiProblemResponse(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        v1 + "/user",
+			OperationID: "getCurrentUser",
+			Summary:     "Get the user that the token belongs to",
+			Tags:        []string{"User"},
+			Auth:        true,
+			Scopes:      []string{user.APIScopeAccountRead},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: apiUserResponse{}},
+				apiProblemResponse(http.StatusUnauthorized),
+				apiProblemResponse(http.StatusForbidden),
+			},
+		},
+		{
+			Method:      http.MethodPatch,
+			Path:        v1 + "/user",
+			OperationID: "updateCurrentUser",
+			Summary:     "Change the profile of the user",
+			Tags:        []string{"User"},
+			Auth:        true,
+			Scopes:      []string{user.APIScopeAccountWrite},
+			Request:     apiUpdateUserRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: apiUserResponse{}},
+				apiProblemResponse(http.StatusUnauthorized),
+				apiProblemResponse(http.StatusForbidden),
+				apiProblemResponse(http.StatusUnprocessableEntity),
+				apiProblemResponse(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        v1 + "/user",
+			OperationID: "deleteCurrentUser",
+			Summary:     "Delete the account of the user",
+			Description: "The password has to be sent along, so that a leaked token is not enough to delete an account. The account can be restored during the grace period, by logging in on the website or with the link in the email that is sent.",
+			Tags:        []string{"User"},
+			Auth:        true,
+			Scopes:      []string{user.APIScopeAccountWrite},
+			Request:     apiDeleteUserRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusNoContent, Description: "The account was deleted"},
+				apiProblemResponse(http.StatusUnauthorized),
+				apiProblemResponse(http.StatusForbidden),
+				apiProblemResponse(http.StatusUnprocessableEntity),
+				apiProblemResponse(http.StatusInternalServerError),
+			},
+		},
+	}
+}
+
+func apiProblemResponse(status int) openapi.Response {
+	return openapi.Response{Status: status, Body: problem.Problem{}, ContentType: problem.ContentType}
+}
+
+func OpenAPIDocument() (*openapi.Document, error) {
+	var servers []openapi.Server
+
+	if host := viper.GetString("site.host"); host != "" {
+		servers = append(servers, openapi.Server{URL: host})
+	}
+
+	return openapi.Generate(
+		openapi.Info{Title: viper.GetString("site.name") + " API", Version: apiVersion},
+		servers,
+		APISpec(),
+	)
+}
+
+func OpenAPI(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	doc, err := OpenAPIDocument()
+
+	if err != nil {
+		log.Error("Failed to generate the OpenAPI document", logger.Fields{"error": err.Error()})
+		apiServerError(c)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, doc)
+}
@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/openapi"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPISpecCoversRoutes(t *testing.T) {
+	router := gin.New()
+	RegisterRoutes(router)
+
+	registered := map[string]bool{}
+
+	for _, route := range router.Routes() {
+		if route.Path == paths.PathAPI || strings.HasPrefix(route.Path, paths.PathAPI+"/") {
+			registered[route.Method+" "+route.Path] = true
+		}
+	}
+
+	specified := map[string]bool{}
+
+	for _, route := range APISpec() {
+		specified[route.Method+" "+route.Path] = true
+	}
+
+	for route := range registered {
+		assert.True(t, specified[route], "%s is registered, but has no entry in APISpec", route)
+	}
+
+	for route := range specified {
+		assert.True(t, registered[route], "%s is in APISpec, but is not registered", route)
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	tests := []struct {
+		name          string
+		host          string
+		expectServers []openapi.Server
+	}{
+		{
+			name:          "with host",
+			host:          "https://example.com",
+			expectServers: []openapi.Server{{URL: "https://example.com"}},
+		},
+		{
+			name: "without host",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Set("site.name", "Test Site")
+			viper.Set("site.host", tc.host)
+
+			doc, err := OpenAPIDocument()
+
+			assert.NoError(t, err)
+			assert.Equal(t, "Test Site API", doc.Info.Title)
+			assert.Equal(t, tc.expectServers, doc.Servers)
+			assert.Contains(t, doc.Paths, pathAPIUser)
+			assert.Contains(t, doc.Components.Schemas, "UserResponse")
+			assert.Contains(t, doc.Components.Schemas, "Problem")
+		})
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	viper.Set("site.name", "Test Site")
+	viper.Set("site.host", "http://localhost:8080")
+
+	router := gin.New()
+	RegisterAPIRoutes(router.Group(paths.PathAPI))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, paths.PathAPI+"/openapi.json", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, openapi.Version, body["openapi"])
+	assert.Contains(t, body["paths"], pathAPILogin)
+}
@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type apiTokenResponse struct {
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	User       struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+}
+
+func APITokenInfo(c *gin.Context) {
+	usr := middleware.GetAPIUser(c)
+	token := middleware.GetAPIToken(c)
+
+	if usr == nil || token == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+		return
+	}
+
+	response := apiTokenResponse{
+		Name:       token.Name,
+		Scopes:     token.Scopes,
+		CreatedAt:  token.CreatedAt,
+		LastUsedAt: token.LastUsedAt,
+	}
+
+	response.User.ID = usr.GetID()
+	response.User.Username = usr.GetUsername()
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,88 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/stretchr/testify/assert"
+)
+
+func expectAPIToken(mock sqlmock.Sqlmock, scopes string, passwordHash string) {
+	mock.ExpectQuery(`UPDATE user_api_tokens SET last_used_at`).
+		WillReturnRows(
+			sqlmock.NewRows(append([]string{"user_id"}, apiTokenColumns...)).
+				AddRow(1, 1, "CI", scopes, time.Now(), time.Now()),
+		)
+
+	expectSessionUser(mock, passwordHash)
+}
+
+func TestAPITokenInfo(t *testing.T) {
+	tests := []struct {
+		name         string
+		header       string
+		setupMock    func(mock sqlmock.Sqlmock)
+		expectStatus int
+	}{
+		{
+			name:   "success",
+			header: "Bearer gws_token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectAPIToken(mock, "account:read", "")
+			},
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "no token",
+			setupMock:    func(mock sqlmock.Sqlmock) {},
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "unknown token",
+			header: "Bearer gws_token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE user_api_tokens SET last_used_at`).
+					WillReturnRows(sqlmock.NewRows(append([]string{"user_id"}, apiTokenColumns...)))
+			},
+			expectStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			tc.setupMock(mock)
+			RegisterAPIRoutes(router.Group(paths.PathAPI))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", paths.PathAPI+"/token", nil)
+
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tc.expectStatus != http.StatusOK {
+				return
+			}
+
+			var body apiTokenResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, "CI", body.Name)
+			assert.Equal(t, []string{"account:read"}, body.Scopes)
+			assert.Equal(t, 1, body.User.ID)
+			assert.Equal(t, "username", body.User.Username)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/problem"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-gonic/gin"
+)
+
+type apiUserResponse struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newAPIUserResponse(usr *user.User) apiUserResponse {
+	return apiUserResponse{
+		ID:        usr.GetID(),
+		Username:  usr.GetUsername(),
+		Email:     usr.GetEmail(),
+		Active:    usr.GetStatus(),
+		CreatedAt: usr.GetCreatedAt(),
+		UpdatedAt: usr.GetUpdatedAt(),
+	}
+}
+
+func apiServerError(c *gin.Context) {
+	problem.Abort(c, problem.New(http.StatusInternalServerError, ""))
+}
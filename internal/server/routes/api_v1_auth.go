@@ -0,0 +1,344 @@
+package routes
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/problem"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAPILoginTokenName = "API login"
+	errTwoFactorCodeRequired = "An authentication code is required for this account"
+)
+
+type apiRegisterRequest struct {
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	AcceptLegal bool   `json:"accept_legal,omitempty"`
+}
+
+type apiLoginRequest struct {
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	Code      string `json:"code,omitempty"`
+	TokenName string `json:"token_name,omitempty"`
+}
+
+type apiLoginResponse struct {
+	Token string          `json:"token"`
+	User  apiUserResponse `json:"user"`
+}
+
+func APIRegister(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+
+	if config.InviteOnly() {
+		problem.Abort(c, problem.New(http.StatusForbidden, errRegistrationInviteOnly))
+		return
+	}
+
+	var req apiRegisterRequest
+
+	if err := v.ValidateJSON(c.Request, &req); err != nil {
+		problem.Abort(c, problem.FromValidator(v))
+		return
+	}
+
+	username := strings.TrimSpace(req.Username)
+	email := strings.TrimSpace(req.Email)
+
+	v.ValidEmail("email", email)
+	v.Required("email", email)
+
+	v.Required("username", username)
+	v.MinLength("username", username, 3)
+
+	v.Required("password", req.Password)
+	v.PasswordPolicy("password", req.Password, validator.PasswordContext{Username: username, Email: email})
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Failed to get database connection from context", nil)
+		apiServerError(c)
+
+		return
+	}
+
+	// The client has to show the current legal documents,
+	// since there is no form that lists them.
+	docs, err := findCurrentDocuments(db)
+
+	if err != nil {
+		log.Error("Could not find the legal documents", logger.Fields{"error": err.Error()})
+		apiServerError(c)
+
+		return
+	}
+
+	if len(docs) > 0 {
+		v.CheckField(req.AcceptLegal, "accept_legal", errRegisterNotAccepted)
+	}
+
+	if _, err = findByUsername(db, username); err == nil {
+		v.AddFieldError("username", "This username is already taken")
+	}
+
+	if _, err = findByEmail(db, email); err == nil {
+		v.AddFieldError("email", "This email address is already taken")
+	}
+
+	if v.HasErrors() {
+		problem.Abort(c, problem.FromValidator(v))
+		return
+	}
+
+	hashedPassword, err := user.HashPassword(req.Password)
+
+	if err != nil {
+		log.Error("Failed to save the user", logger.Fields{"err": err.Error()})
+		apiServerError(c)
+
+		return
+	}
+
+	usr := user.NewUser(username, email, hashedPassword, false)
+	err = usr.Save(db)
+
+	// The username or email address can still belong to a deleted
+	// account that has not been purged yet.
+	if errors.Is(err, user.ErrUserExists) {
+		v.AddFieldError("username", user.ErrUserExists.Error())
+		v.AddFieldError("email", user.ErrUserExists.Error())
+		problem.Abort(c, problem.FromValidator(v))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("Failed to save the user", logger.Fields{"err": err.Error()})
+		apiServerError(c)
+
+		return
+	}
+
+	if err = acceptDocuments(db, usr.GetID(), docs, c.ClientIP()); err != nil {
+		if deleteErr := usr.Delete(db); deleteErr != nil {
+			log.Error("Failed to remove the user after accepting the legal documents failed", logger.Fields{"userID": usr.GetID(), "error": deleteErr.Error()})
+		}
+
+		log.Error("Failed to accept the legal documents", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		apiServerError(c)
+
+		return
+	}
+
+	if err = sendRegisterVerifyEmail(db, usr); err != nil {
+		log.Error("Failed to send the registation email", logger.Fields{"err": err.Error()})
+		apiServerError(c)
+
+		return
+	}
+
+	log.Info("User registered through the API", logger.Fields{"userID": usr.GetID()})
+
+	c.JSON(http.StatusCreated, newAPIUserResponse(usr))
+}
+
+func APILogin(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+
+	var req apiLoginRequest
+
+	if err := v.ValidateJSON(c.Request, &req); err != nil {
+		problem.Abort(c, problem.FromValidator(v))
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	tokenName := strings.TrimSpace(req.TokenName)
+
+	if tokenName == "" {
+		tokenName = defaultAPILoginTokenName
+	}
+
+	v.ValidEmail("email", email)
+	v.Required("email", email)
+	v.Required("password", req.Password)
+	v.MaxLength("token_name", tokenName, user.APITokenNameMaxLength)
+
+	if v.HasErrors() {
+		problem.Abort(c, problem.FromValidator(v))
+		return
+	}
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Failed to get database connection from context", nil)
+		apiServerError(c)
+
+		return
+	}
+
+	throttle := newLoginThrottle(c, db, email)
+
+	if status := throttle.check(c); status.Blocked() {
+		log.Warn("API login blocked: too many failed attempts", logger.Fields{"email": email, "ip": c.ClientIP()})
+
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(status.RetryAfter.Seconds()))))
+		problem.Abort(c, problem.New(http.StatusTooManyRequests, loginThrottledMessage(status)))
+
+		return
+	}
+
+	foundUser, err := findByEmail(db, email)
+
+	if err == nil {
+		err = foundUser.CheckPassword(db, req.Password)
+	}
+
+	if err != nil {
+		if !errors.Is(err, user.ErrInvalidCredentials) {
+			log.Error("Database error during API login", logger.Fields{"email": email, "error": err.Error()})
+			apiServerError(c)
+
+			return
+		}
+
+		log.Warn("API login failed: invalid credentials", logger.Fields{"email": email})
+
+		targetID := 0
+
+		if foundUser != nil {
+			targetID = foundUser.GetID()
+		}
+
+		auditEvent(c, audit.EventLoginFailed, 0, targetID, audit.Metadata{"method": "api", "email": email})
+
+		if status := throttle.fail(c); status.JustLocked && foundUser != nil && foundUser.GetStatus() {
+			log.Warn("Account locked after too many failed login attempts", logger.Fields{"userID": foundUser.GetID()})
+
+			if err = sendUnlockEmail(db, foundUser); err != nil {
+				log.Error("Failed to send the unlock email", logger.Fields{"userID": foundUser.GetID(), "error": err.Error()})
+			}
+		}
+
+		problem.Abort(c, problem.New(http.StatusUnauthorized, user.ErrInvalidCredentials.Error()))
+
+		return
+	}
+
+	if !foundUser.GetStatus() {
+		log.Warn("An inactive user tried to log in through the API", logger.Fields{"mail": email})
+		problem.Abort(c, problem.New(http.StatusForbidden, user.ErrNotActive.Error()))
+
+		return
+	}
+
+	if !apiLoginTwoFactor(c, db, throttle, foundUser, req.Code) {
+		return
+	}
+
+	throttle.reset(c)
+
+	token, err := foundUser.CreateAPIToken(db, tokenName, apiScopeNames())
+
+	if err == nil {
+		err = foundUser.RecordLogin(db)
+	}
+
+	if err != nil {
+		log.Error("Failed to issue an API token after login", logger.Fields{"userID": foundUser.GetID(), "error": err.Error()})
+		apiServerError(c)
+
+		return
+	}
+
+	log.Info("API login successful", logger.Fields{"email": email, "userID": foundUser.GetID()})
+	auditEvent(c, audit.EventLoginSucceeded, foundUser.GetID(), foundUser.GetID(), audit.Metadata{"method": "api", "token": tokenName})
+
+	c.JSON(http.StatusOK, apiLoginResponse{Token: token, User: newAPIUserResponse(foundUser)})
+}
+
+// apiLoginTwoFactor verifies the second factor of users that have set one up.
+// A wrong code counts as a failed login, since the password is sent along with it.
+func apiLoginTwoFactor(
+	c *gin.Context,
+	db database.DatabaseInterface,
+	throttle *loginThrottle,
+	usr *user.User,
+	code string,
+) bool {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	hasTwoFactor, err := userHasTwoFactor(usr, db)
+
+	if err != nil {
+		log.Error("Failed to check two-factor authentication during API login", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		apiServerError(c)
+
+		return false
+	}
+
+	if !hasTwoFactor {
+		return true
+	}
+
+	code = strings.TrimSpace(code)
+
+	if code == "" {
+		p := problem.New(http.StatusUnauthorized, errTwoFactorCodeRequired)
+		p.Errors = map[string][]string{"code": {errTwoFactorCodeRequired}}
+
+		problem.Abort(c, p)
+
+		return false
+	}
+
+	err = userVerifyTwoFactor(usr, db, code)
+
+	if err != nil {
+		if !errors.Is(err, user.ErrInvalidTwoFactorCode) {
+			log.Error("Two-factor verification error during API login", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+			apiServerError(c)
+
+			return false
+		}
+
+		log.Warn("API login failed: invalid two-factor code", logger.Fields{"userID": usr.GetID()})
+		auditEvent(c, audit.EventLoginFailed, 0, usr.GetID(), audit.Metadata{"method": "api", "twoFactor": true})
+		throttle.fail(c)
+
+		problem.Abort(c, problem.New(http.StatusUnauthorized, user.ErrInvalidTwoFactorCode.Error()))
+
+		return false
+	}
+
+	return true
+}
+
+func apiScopeNames() []string {
+	scopes := make([]string, len(user.APIScopes))
+
+	for i, scope := range user.APIScopes {
+		scopes[i] = scope.Name
+	}
+
+	return scopes
+}
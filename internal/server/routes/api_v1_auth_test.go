@@ -0,0 +1,387 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/legal"
+	"github.com/Dobefu/go-web-starter/internal/lockout"
+	"github.com/Dobefu/go-web-starter/internal/problem"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	pathAPIRegister = paths.PathAPI + "/v1/register"
+	pathAPILogin    = paths.PathAPI + "/v1/login"
+	pathAPIUser     = paths.PathAPI + "/v1/user"
+)
+
+func apiRequest(method, path, body, token string) *http.Request {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "192.0.2.1:1234"
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return req
+}
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) problem.Problem {
+	var p problem.Problem
+
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, w.Code, p.Status)
+
+	return p
+}
+
+func TestAPIRegister(t *testing.T) {
+	now := time.Now()
+
+	viper.Set("site.name", "Test Site")
+	viper.Set("site.host", "http://localhost:8080")
+
+	notFound := func(database.DatabaseInterface, string) (*user.User, error) { return nil, errors.New("not found") }
+	taken := func(database.DatabaseInterface, string) (*user.User, error) { return &user.User{}, nil }
+
+	expectSave := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery("INSERT INTO users").
+			WithArgs("user", "test@example.com", sqlmock.AnyArg(), false, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "last_login"}).AddRow(1, now, now, now))
+		mock.ExpectExec("DELETE FROM user_tokens").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO user_tokens").WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		inviteOnly     bool
+		findByUsername func(database.DatabaseInterface, string) (*user.User, error)
+		docs           []legal.Document
+		docsErr        error
+		acceptErr      error
+		emailErr       error
+		setupMock      func(mock sqlmock.Sqlmock)
+		expectStatus   int
+		expectErrors   []string
+		expectAccepted [][]int
+	}{
+		{
+			name:         "invalid JSON",
+			body:         `{"username":`,
+			expectStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "missing fields",
+			body:         `{}`,
+			expectStatus: http.StatusUnprocessableEntity,
+			expectErrors: []string{"username", "email", "password"},
+		},
+		{
+			name:           "username taken",
+			body:           `{"username":"user","email":"test@example.com","password":"correct-horse-9"}`,
+			findByUsername: taken,
+			expectStatus:   http.StatusUnprocessableEntity,
+			expectErrors:   []string{"username"},
+		},
+		{
+			name:           "success",
+			body:           `{"username":"user","email":"test@example.com","password":"correct-horse-9"}`,
+			setupMock:      expectSave,
+			expectStatus:   http.StatusCreated,
+			expectAccepted: [][]int{{}},
+		},
+		{
+			name:           "legal documents accepted",
+			body:           `{"username":"user","email":"test@example.com","password":"correct-horse-9","accept_legal":true}`,
+			docs:           testLegalDocuments(),
+			setupMock:      expectSave,
+			expectStatus:   http.StatusCreated,
+			expectAccepted: [][]int{{1, 2}},
+		},
+		{
+			name:         "legal documents not accepted",
+			body:         `{"username":"user","email":"test@example.com","password":"correct-horse-9"}`,
+			docs:         testLegalDocuments(),
+			expectStatus: http.StatusUnprocessableEntity,
+			expectErrors: []string{"accept_legal"},
+		},
+		{
+			name:         "legal documents error",
+			body:         `{"username":"user","email":"test@example.com","password":"correct-horse-9","accept_legal":true}`,
+			docsErr:      errors.New("db fail"),
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:      "accept error",
+			body:      `{"username":"user","email":"test@example.com","password":"correct-horse-9","accept_legal":true}`,
+			docs:      testLegalDocuments(),
+			acceptErr: errors.New("db fail"),
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("INSERT INTO users").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "last_login"}).AddRow(1, now, now, now))
+				mock.ExpectExec("DELETE FROM users").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus:   http.StatusInternalServerError,
+			expectAccepted: [][]int{{1, 2}},
+		},
+		{
+			name:         "invite only",
+			body:         `{"username":"user","email":"test@example.com","password":"correct-horse-9"}`,
+			inviteOnly:   true,
+			expectStatus: http.StatusForbidden,
+		},
+		{
+			name:           "email error",
+			body:           `{"username":"user","email":"test@example.com","password":"correct-horse-9"}`,
+			emailErr:       errors.New("smtp error"),
+			setupMock:      expectSave,
+			expectStatus:   http.StatusInternalServerError,
+			expectAccepted: [][]int{{}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.findByUsername == nil {
+				tc.findByUsername = notFound
+			}
+
+			restoreFinders := patchFinders(tc.findByUsername, notFound)
+			defer restoreFinders()
+
+			setInviteOnly(t, tc.inviteOnly)
+			accepted := useLegalDocuments(t, tc.docs, tc.docsErr, tc.acceptErr)
+
+			sender := useRecordingEmailSender(t)
+			sender.err = tc.emailErr
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tc.setupMock != nil {
+				tc.setupMock(mock)
+			}
+
+			RegisterAPIRoutes(router.Group(paths.PathAPI))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, apiRequest("POST", pathAPIRegister, tc.body, ""))
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tc.expectAccepted, accepted.docIDs)
+
+			if tc.expectStatus == http.StatusCreated {
+				var body apiUserResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, 1, body.ID)
+				assert.False(t, body.Active)
+				assert.Len(t, sender.sent, 1)
+
+				return
+			}
+
+			p := decodeProblem(t, w)
+
+			for _, field := range tc.expectErrors {
+				assert.Contains(t, p.Errors, field)
+			}
+		})
+	}
+}
+
+func TestAPILogin(t *testing.T) {
+	viper.Set("site.name", "Test Site")
+	viper.Set("site.host", "http://localhost:8080")
+
+	hash, _ := user.HashPassword("pw")
+
+	origFindByEmail := findByEmail
+	origUserHasTwoFactor := userHasTwoFactor
+	origUserVerifyTwoFactor := userVerifyTwoFactor
+
+	t.Cleanup(func() {
+		findByEmail = origFindByEmail
+		userHasTwoFactor = origUserHasTwoFactor
+		userVerifyTwoFactor = origUserVerifyTwoFactor
+	})
+
+	expectIssueToken := func(mock sqlmock.Sqlmock) {
+		mock.ExpectExec("INSERT INTO user_api_tokens").
+			WithArgs(1, "Phone", sqlmock.AnyArg(), "account:read,account:write", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("UPDATE users").WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		active       bool
+		twoFactor    bool
+		attempts     map[string]lockout.Attempts
+		setupMock    func(mock sqlmock.Sqlmock)
+		expectStatus int
+		expectErrors []string
+		expectFailed bool
+	}{
+		{
+			name:         "invalid JSON",
+			body:         `[]`,
+			expectStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "missing fields",
+			body:         `{}`,
+			expectStatus: http.StatusUnprocessableEntity,
+			expectErrors: []string{"email", "password"},
+		},
+		{
+			name:         "unknown email",
+			body:         `{"email":"other@example.com","password":"pw"}`,
+			expectStatus: http.StatusUnauthorized,
+			expectFailed: true,
+		},
+		{
+			name:         "wrong password",
+			body:         `{"email":"user@example.com","password":"wrong"}`,
+			active:       true,
+			expectStatus: http.StatusUnauthorized,
+			expectFailed: true,
+		},
+		{
+			name:         "inactive user",
+			body:         `{"email":"user@example.com","password":"pw"}`,
+			expectStatus: http.StatusForbidden,
+		},
+		{
+			name:   "throttled",
+			body:   `{"email":"user@example.com","password":"pw"}`,
+			active: true,
+			attempts: map[string]lockout.Attempts{
+				lockout.AccountKey("user@example.com"): {Failures: 10, LastFailure: time.Now(), LockedUntil: time.Now().Add(time.Hour)},
+			},
+			expectStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:         "two-factor code required",
+			body:         `{"email":"user@example.com","password":"pw"}`,
+			active:       true,
+			twoFactor:    true,
+			expectStatus: http.StatusUnauthorized,
+			expectErrors: []string{"code"},
+		},
+		{
+			name:         "invalid two-factor code",
+			body:         `{"email":"user@example.com","password":"pw","code":"000000"}`,
+			active:       true,
+			twoFactor:    true,
+			expectStatus: http.StatusUnauthorized,
+			expectFailed: true,
+		},
+		{
+			name:         "success with two-factor code",
+			body:         `{"email":"user@example.com","password":"pw","code":"123456","token_name":"Phone"}`,
+			active:       true,
+			twoFactor:    true,
+			setupMock:    expectIssueToken,
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "success",
+			body:         `{"email":"user@example.com","password":"pw","token_name":"Phone"}`,
+			active:       true,
+			setupMock:    expectIssueToken,
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:   "token error",
+			body:   `{"email":"user@example.com","password":"pw"}`,
+			active: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO user_api_tokens").WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := useMemoryLockoutStore(t)
+			auditLog := useRecordingAuditLog(t)
+
+			if tc.attempts != nil {
+				store.attempts = tc.attempts
+			}
+
+			findByEmail = func(db database.DatabaseInterface, email string) (*user.User, error) {
+				if email != "user@example.com" {
+					return nil, user.ErrInvalidCredentials
+				}
+
+				return user.New(user.UserFields{Id: 1, Username: "user", Email: email, Password: hash, Status: tc.active}), nil
+			}
+
+			userHasTwoFactor = func(*user.User, database.DatabaseInterface) (bool, error) { return tc.twoFactor, nil }
+			userVerifyTwoFactor = func(_ *user.User, _ database.DatabaseInterface, code string) error {
+				if code != "123456" {
+					return user.ErrInvalidTwoFactorCode
+				}
+
+				return nil
+			}
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tc.setupMock != nil {
+				tc.setupMock(mock)
+			}
+
+			RegisterAPIRoutes(router.Group(paths.PathAPI))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, apiRequest("POST", pathAPILogin, tc.body, ""))
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tc.expectFailed, store.attempts[lockout.IPKey("192.0.2.1")].Failures > 0)
+			assert.Equal(t, tc.expectFailed, slices.Contains(auditLog.types(), audit.EventLoginFailed))
+			assert.Equal(t, tc.expectStatus == http.StatusOK, slices.Contains(auditLog.types(), audit.EventLoginSucceeded))
+
+			if tc.expectStatus == http.StatusOK {
+				var body apiLoginResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.True(t, strings.HasPrefix(body.Token, user.APITokenPrefix))
+				assert.Equal(t, "user", body.User.Username)
+
+				return
+			}
+
+			p := decodeProblem(t, w)
+
+			for _, field := range tc.expectErrors {
+				assert.Contains(t, p.Errors, field)
+			}
+
+			if tc.expectStatus == http.StatusTooManyRequests {
+				assert.NotEmpty(t, w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/problem"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+)
+
+type apiUpdateUserRequest struct {
+	Username string `json:"username"`
+}
+
+type apiDeleteUserRequest struct {
+	Password string `json:"password"`
+}
+
+func APICurrentUser(c *gin.Context) {
+	usr := middleware.GetAPIUser(c)
+
+	if usr == nil {
+		problem.Abort(c, problem.New(http.StatusUnauthorized, ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, newAPIUserResponse(usr))
+}
+
+func APIUpdateUser(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+
+	var req apiUpdateUserRequest
+
+	if err := v.ValidateJSON(c.Request, &req); err != nil {
+		problem.Abort(c, problem.FromValidator(v))
+		return
+	}
+
+	username := strings.TrimSpace(req.Username)
+
+	v.Required("username", username)
+	v.MinLength("username", username, 3)
+
+	usr := middleware.GetAPIUser(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		apiServerError(c)
+
+		return
+	}
+
+	if _, err = findByUsername(db, username); err == nil && usr.GetUsername() != username {
+		v.AddFieldError("username", "This username is already taken")
+	}
+
+	if v.HasErrors() {
+		problem.Abort(c, problem.FromValidator(v))
+		return
+	}
+
+	oldUsername := usr.GetUsername()
+
+	usr.SetUsername(username)
+	err = usr.Save(db)
+
+	if errors.Is(err, user.ErrUserExists) {
+		v.AddFieldError("username", "This username is already taken")
+		problem.Abort(c, problem.FromValidator(v))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("Could not update the user", logger.Fields{"error": err.Error()})
+		apiServerError(c)
+
+		return
+	}
+
+	auditEvent(c, audit.EventProfileUpdated, usr.GetID(), usr.GetID(), audit.Metadata{"old_username": oldUsername, "new_username": username, "method": "api"})
+
+	c.JSON(http.StatusOK, newAPIUserResponse(usr))
+}
+
+// APIDeleteUser deletes the account of the user. The password has to be sent
+// along, so that a leaked token is not enough to delete an account.
+func APIDeleteUser(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+
+	var req apiDeleteUserRequest
+
+	if err := v.ValidateJSON(c.Request, &req); err != nil {
+		problem.Abort(c, problem.FromValidator(v))
+		return
+	}
+
+	v.Required("password", req.Password)
+
+	if v.HasErrors() {
+		problem.Abort(c, problem.FromValidator(v))
+		return
+	}
+
+	usr := middleware.GetAPIUser(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		apiServerError(c)
+
+		return
+	}
+
+	err = usr.CheckPassword(db, req.Password)
+
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			log.Warn("Account deletion through the API failed: password mismatch", logger.Fields{"userID": usr.GetID()})
+
+			v.AddFieldError("password", user.ErrInvalidCredentials.Error())
+			problem.Abort(c, problem.FromValidator(v))
+		} else {
+			log.Error("Password check error during account deletion", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+			apiServerError(c)
+		}
+
+		return
+	}
+
+	if _, err = deleteAccount(c, db, usr.GetID(), usr, "api"); err != nil {
+		log.Error("Failed to delete user", logger.Fields{"user_id": usr.GetID(), "error": err.Error()})
+		apiServerError(c)
+
+		return
+	}
+
+	log.Info("User deleted through the API", logger.Fields{"userID": usr.GetID()})
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,248 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPICurrentUser(t *testing.T) {
+	tests := []struct {
+		name         string
+		token        string
+		scopes       string
+		expectStatus int
+	}{
+		{name: "success", token: "gws_token", scopes: "account:read", expectStatus: http.StatusOK},
+		{name: "missing scope", token: "gws_token", scopes: "account:write", expectStatus: http.StatusForbidden},
+		{name: "no token", expectStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tc.token != "" {
+				expectAPIToken(mock, tc.scopes, "")
+			}
+
+			RegisterAPIRoutes(router.Group(paths.PathAPI))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, apiRequest("GET", pathAPIUser, "", tc.token))
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tc.expectStatus != http.StatusOK {
+				decodeProblem(t, w)
+				return
+			}
+
+			var body apiUserResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, 1, body.ID)
+			assert.Equal(t, "username", body.Username)
+			assert.Equal(t, "test@example.com", body.Email)
+			assert.True(t, body.Active)
+		})
+	}
+}
+
+func TestAPIUpdateUser(t *testing.T) {
+	notFound := func(database.DatabaseInterface, string) (*user.User, error) { return nil, errors.New("not found") }
+	taken := func(database.DatabaseInterface, string) (*user.User, error) { return &user.User{}, nil }
+
+	tests := []struct {
+		name           string
+		body           string
+		scopes         string
+		findByUsername func(database.DatabaseInterface, string) (*user.User, error)
+		setupMock      func(mock sqlmock.Sqlmock)
+		expectStatus   int
+	}{
+		{
+			name:         "read-only token",
+			body:         `{"username":"newname"}`,
+			scopes:       "account:read",
+			expectStatus: http.StatusForbidden,
+		},
+		{
+			name:         "invalid JSON",
+			body:         `{"user":"newname"}`,
+			scopes:       "account:write",
+			expectStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "too short",
+			body:         `{"username":"ab"}`,
+			scopes:       "account:write",
+			expectStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "username taken",
+			body:           `{"username":"taken"}`,
+			scopes:         "account:write",
+			findByUsername: taken,
+			expectStatus:   http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "success",
+			body:   `{"username":"newname"}`,
+			scopes: "account:write",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("UPDATE users").
+					WithArgs("newname", "test@example.com", sqlmock.AnyArg(), true, sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+					WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+			},
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:   "database error",
+			body:   `{"username":"newname"}`,
+			scopes: "account:write",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("UPDATE users").WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.findByUsername == nil {
+				tc.findByUsername = notFound
+			}
+
+			restoreFinders := patchFinders(tc.findByUsername, notFound)
+			defer restoreFinders()
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			expectAPIToken(mock, tc.scopes, "")
+
+			if tc.setupMock != nil {
+				tc.setupMock(mock)
+			}
+
+			RegisterAPIRoutes(router.Group(paths.PathAPI))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, apiRequest("PATCH", pathAPIUser, tc.body, "gws_token"))
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tc.expectStatus != http.StatusOK {
+				decodeProblem(t, w)
+				return
+			}
+
+			var body apiUserResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, "newname", body.Username)
+		})
+	}
+}
+
+func TestAPIDeleteUser(t *testing.T) {
+	hash, _ := user.HashPassword("pw")
+
+	tests := []struct {
+		name          string
+		body          string
+		noGracePeriod bool
+		setupMock     func(mock sqlmock.Sqlmock)
+		expectStatus  int
+		expectErrors  []string
+		expectEmail   bool
+	}{
+		{
+			name:         "missing password",
+			body:         `{}`,
+			expectStatus: http.StatusUnprocessableEntity,
+			expectErrors: []string{"password"},
+		},
+		{
+			name:         "wrong password",
+			body:         `{"password":"wrong"}`,
+			expectStatus: http.StatusUnprocessableEntity,
+			expectErrors: []string{"password"},
+		},
+		{
+			name: "success",
+			body: `{"password":"pw"}`,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectSoftDelete(mock)
+			},
+			expectStatus: http.StatusNoContent,
+			expectEmail:  true,
+		},
+		{
+			name:          "without grace period",
+			body:          `{"password":"pw"}`,
+			noGracePeriod: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM users").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus: http.StatusNoContent,
+		},
+		{
+			name: "database error",
+			body: `{"password":"pw"}`,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE users SET deleted_at").WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			expectAPIToken(mock, "account:read,account:write", hash)
+
+			if tc.setupMock != nil {
+				tc.setupMock(mock)
+			}
+
+			if tc.noGracePeriod {
+				setDeletionGraceDays(t, 0)
+			}
+
+			sender := useRecordingEmailSender(t)
+			RegisterAPIRoutes(router.Group(paths.PathAPI))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, apiRequest("DELETE", pathAPIUser, tc.body, "gws_token"))
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tc.expectEmail, len(sender.sent) == 1)
+
+			if tc.expectStatus == http.StatusNoContent {
+				assert.Empty(t, w.Body.String())
+				return
+			}
+
+			p := decodeProblem(t, w)
+
+			for _, field := range tc.expectErrors {
+				assert.Contains(t, p.Errors, field)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package routes
+
+import (
+	"os"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+var recordAuditEvent = audit.Record
+
+func auditEvent(c *gin.Context, eventType audit.EventType, actorID int, targetID int, metadata audit.Metadata) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Could not record an audit event without a database", logger.Fields{"event": eventType})
+		return
+	}
+
+	// An admin that is logged in as the user is the one who acted.
+	if _, ok := c.Get(sessions.DefaultKey); ok && actorID > 0 {
+		if adminID, ok := impersonatorID(getSession(c)); ok && adminID != actorID {
+			metadata = withMetadata(metadata, "impersonatedUserID", actorID)
+			actorID = adminID
+		}
+	}
+
+	event := &audit.Event{
+		Type:      eventType,
+		ActorID:   actorID,
+		TargetID:  targetID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Metadata:  metadata,
+	}
+
+	if err = recordAuditEvent(db, event); err != nil {
+		log.Error("Failed to record an audit event", logger.Fields{"event": eventType, "error": err.Error()})
+	}
+}
+
+// withMetadata adds a value to a copy of the metadata of an event,
+// since the metadata may be shared with the caller.
+func withMetadata(metadata audit.Metadata, key string, value any) audit.Metadata {
+	copied := audit.Metadata{key: value}
+
+	for k, v := range metadata {
+		copied[k] = v
+	}
+
+	return copied
+}
@@ -0,0 +1,171 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingAuditLog struct {
+	events []audit.Event
+	err    error
+}
+
+func useRecordingAuditLog(t *testing.T) *recordingAuditLog {
+	log := &recordingAuditLog{}
+	recordAuditEventOrig := recordAuditEvent
+
+	t.Cleanup(func() { recordAuditEvent = recordAuditEventOrig })
+	recordAuditEvent = func(_ database.DatabaseInterface, event *audit.Event) error {
+		log.events = append(log.events, *event)
+		return log.err
+	}
+
+	return log
+}
+
+func (l *recordingAuditLog) types() []audit.EventType {
+	types := []audit.EventType{}
+
+	for _, event := range l.events {
+		types = append(types, event.Type)
+	}
+
+	return types
+}
+
+func TestAuditEvent(t *testing.T) {
+	tests := []struct {
+		name         string
+		withDB       bool
+		err          error
+		expectEvents int
+	}{
+		{name: "success", withDB: true, expectEvents: 1},
+		{name: "record error", withDB: true, err: errors.New("db fail"), expectEvents: 1},
+		{name: "no database"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			auditLog := useRecordingAuditLog(t)
+			auditLog.err = tc.err
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest("GET", "/", nil)
+			c.Request.RemoteAddr = "192.0.2.1:1234"
+			c.Request.Header.Set("User-Agent", "Firefox")
+
+			if tc.withDB {
+				c.Set("db", &MockDatabase{})
+			}
+
+			auditEvent(c, audit.EventLogout, 1, 2, audit.Metadata{"key": "value"})
+
+			assert.Len(t, auditLog.events, tc.expectEvents)
+
+			if tc.expectEvents > 0 {
+				assert.Equal(t, audit.Event{
+					Type:      audit.EventLogout,
+					ActorID:   1,
+					TargetID:  2,
+					IP:        "192.0.2.1",
+					UserAgent: "Firefox",
+					Metadata:  audit.Metadata{"key": "value"},
+				}, auditLog.events[0])
+			}
+		})
+	}
+}
+
+func TestAuditEventWhileImpersonating(t *testing.T) {
+	tests := []struct {
+		name         string
+		actorID      int
+		expectActor  int
+		expectMeta   audit.Metadata
+		impersonator bool
+	}{
+		{
+			name:         "user action",
+			actorID:      2,
+			impersonator: true,
+			expectActor:  9,
+			expectMeta:   audit.Metadata{"key": "value", "impersonatedUserID": 2},
+		},
+		{
+			name:         "admin action",
+			actorID:      9,
+			impersonator: true,
+			expectActor:  9,
+			expectMeta:   audit.Metadata{"key": "value"},
+		},
+		{
+			name:        "not impersonating",
+			actorID:     2,
+			expectActor: 2,
+			expectMeta:  audit.Metadata{"key": "value"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			auditLog := useRecordingAuditLog(t)
+			metadata := audit.Metadata{"key": "value"}
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(sessions.Sessions("mysession", cookie.NewStore([]byte("secret"))))
+			router.Use(middleware.Database(&MockDatabase{}))
+			router.Use(setSessionUserID(2))
+
+			if tc.impersonator {
+				router.Use(setSessionImpersonator(9))
+			}
+
+			router.GET("/", func(c *gin.Context) {
+				auditEvent(c, audit.EventProfileUpdated, tc.actorID, 2, metadata)
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Len(t, auditLog.events, 1)
+			assert.Equal(t, tc.expectActor, auditLog.events[0].ActorID)
+			assert.Equal(t, 2, auditLog.events[0].TargetID)
+			assert.Equal(t, tc.expectMeta, auditLog.events[0].Metadata)
+			assert.Equal(t, audit.Metadata{"key": "value"}, metadata)
+		})
+	}
+}
+
+func TestLogoutRecordsAuditEvent(t *testing.T) {
+	auditLog := useRecordingAuditLog(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(sessions.Sessions("mysession", cookie.NewStore([]byte("secret"))))
+	router.Use(middleware.Database(&MockDatabase{}))
+	router.Use(setSessionUserID(1))
+	router.GET("/", Logout)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, []audit.EventType{audit.EventLogout}, auditLog.types())
+	assert.Equal(t, 1, auditLog.events[0].TargetID)
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Forbidden(c *gin.Context) {
+	data := RouteData{
+		Template:   "pages/forbidden",
+		HttpStatus: http.StatusForbidden,
+
+		Title:       "Access Denied",
+		Description: "You do not have permission to view this page",
+	}
+
+	RenderRouteHTML(c, data)
+}
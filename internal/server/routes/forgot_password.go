@@ -1,11 +1,15 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/Dobefu/go-web-starter/internal/audit"
 	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
 	emailer "github.com/Dobefu/go-web-starter/internal/email"
 	"github.com/Dobefu/go-web-starter/internal/logger"
 	"github.com/Dobefu/go-web-starter/internal/message"
@@ -19,7 +23,8 @@ import (
 )
 
 const (
-	msgPasswdReset = "If your email address exists and has an account associated, password reset instructions have been sent."
+	msgPasswdReset      = "If your email address exists and has an account associated, password reset instructions have been sent."
+	msgPasswdResetLogin = "You have used your one-time login token. Please change your password."
 
 	errUserPasswdVerify = "Could not verify the password reset token."
 )
@@ -28,10 +33,9 @@ func ForgotPassword(c *gin.Context) {
 	v := validator.New()
 	v.SetContext(c)
 
-	email := v.GetFormValue(c.Request, "email")
 	token := v.GetFormValue(c.Request, "token")
 
-	if len(email) > 0 && len(token) > 0 {
+	if len(token) > 0 {
 		log := logger.New(config.GetLogLevel(), os.Stdout)
 		db, err := route_utils.GetDbFromContext(c)
 
@@ -42,33 +46,42 @@ func ForgotPassword(c *gin.Context) {
 			return
 		}
 
-		usr, err := user.FindByEmail(db, email)
+		usr, err := user.ConsumeToken(db, user.TokenPurposeReset, token)
 
-		if err != nil {
-			log.Warn("Could not get the user from the email address", logger.Fields{"err": err.Error()})
+		if err != nil && !errors.Is(err, user.ErrInvalidToken) {
+			log.Error("Could not verify the token", logger.Fields{"err": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
 
+		if err != nil || !usr.GetStatus() {
 			v.SetFlash(message.Message{
 				Type: message.MessageTypeError,
 				Body: errUserPasswdVerify,
 			})
 
-			c.Redirect(http.StatusSeeOther, paths.PathRegister)
+			c.Redirect(http.StatusSeeOther, paths.PathForgotPassword)
 			return
 		}
 
-		expectedToken := usr.CreateVerificationToken()
+		hasTwoFactor, err := userHasTwoFactor(usr, db)
 
-		if token != expectedToken || !usr.GetStatus() {
-			v.SetFlash(message.Message{
-				Type: message.MessageTypeError,
-				Body: errUserPasswdVerify,
-			})
+		if err != nil {
+			log.Error("Failed to check two-factor authentication during login", logger.Fields{"userID": usr.GetID(), "err": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
 
-			c.Redirect(http.StatusSeeOther, paths.PathRegister)
 			return
 		}
 
 		session := getSession(c)
+		session.Set(sessionKeyPasswordReset, time.Now().Unix())
+
+		if hasTwoFactor {
+			startTwoFactorLogin(c, session, usr)
+			return
+		}
+
 		err = usr.Login(db, session)
 
 		if err != nil {
@@ -78,9 +91,12 @@ func ForgotPassword(c *gin.Context) {
 			return
 		}
 
+		auditEvent(c, audit.EventLoginSucceeded, usr.GetID(), usr.GetID(), audit.Metadata{"method": "password-reset-link"})
+		notifyNewDevice(c, db, usr)
+
 		v.SetFlash(message.Message{
 			Type: message.MessageTypeSuccess,
-			Body: "You have used your one-time login token. Please change your password.",
+			Body: msgPasswdResetLogin,
 		})
 
 		c.Redirect(http.StatusSeeOther, fmt.Sprintf("%s/edit", paths.PathAccount))
@@ -159,37 +175,39 @@ func ForgotPasswordPost(c *gin.Context) {
 		"userID": foundUser.GetID(),
 	})
 
-	token := foundUser.CreateVerificationToken()
+	err = sendPasswordResetEmail(db, foundUser)
 
-	mail := emailer.New(
-		viper.GetString("email.host"),
-		viper.GetString("email.port"),
-		viper.GetString("email.identity"),
-		viper.GetString("email.user"),
-		viper.GetString("email.password"),
-	)
+	if err != nil {
+		log.Error("Failed to send the password reset email", logger.Fields{"err": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
 
-	err = mail.SendMail(
+		return
+	}
+
+	auditEvent(c, audit.EventPasswordResetRequested, 0, foundUser.GetID(), nil)
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: msgPasswdReset})
+	c.Redirect(http.StatusSeeOther, paths.PathLogin)
+}
+
+func sendPasswordResetEmail(db database.DatabaseInterface, usr *user.User) error {
+	token, err := usr.CreateToken(db, user.TokenPurposeReset, user.TokenTTLReset)
+
+	if err != nil {
+		return err
+	}
+
+	return newEmailSender().SendMail(
 		viper.GetString("site.email"),
-		[]string{email},
+		[]string{usr.GetEmail()},
 		fmt.Sprintf("Reset your %s password", viper.GetString("site.name")),
 		emailer.EmailBody{
 			Template: "email/forgot_password",
 			Data: map[string]any{
-				"Username": foundUser.GetUsername(),
+				"Username": usr.GetUsername(),
 				"Token":    token,
-				"Email":    email,
+				"Email":    usr.GetEmail(),
 			},
 		},
 	)
-
-	if err != nil {
-		log.Error("Failed to send the password reset email", logger.Fields{"err": err.Error()})
-		RenderRouteHTML(c, GenericErrorData(c))
-
-		return
-	}
-
-	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: msgPasswdReset})
-	c.Redirect(http.StatusSeeOther, paths.PathLogin)
 }
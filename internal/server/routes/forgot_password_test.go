@@ -1,13 +1,20 @@
 package routes
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/database"
 	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
 	server_utils "github.com/Dobefu/go-web-starter/internal/server/utils"
 	"github.com/Dobefu/go-web-starter/internal/templates"
+	"github.com/Dobefu/go-web-starter/internal/user"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
@@ -37,3 +44,136 @@ func TestForgotPassword(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+func TestForgotPasswordToken(t *testing.T) {
+	tests := []struct {
+		name         string
+		twoFactor    bool
+		setupMock    func(mock sqlmock.Sqlmock)
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name: "success",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "reset", true)
+				mock.ExpectQuery(`UPDATE users SET .+`).
+					WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+				expectRememberDevice(mock, false, true)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathAccount + "/edit",
+		},
+		{
+			name:      "two-factor required",
+			twoFactor: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "reset", true)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: pathLoginTwoFactor,
+		},
+		{
+			name: "invalid or expired token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE user_tokens SET consumed_at`).WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathForgotPassword,
+		},
+		{
+			name: "inactive user",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "reset", false)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathForgotPassword,
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE user_tokens SET consumed_at`).WillReturnError(errors.New("db fail"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patchUserHasTwoFactor(t, tt.twoFactor)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			router.GET(paths.PathForgotPassword, ForgotPassword)
+			tt.setupMock(mock)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", paths.PathForgotPassword+"?token=test-token", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestForgotPasswordPost(t *testing.T) {
+	viper.Set("site.name", "Test Site")
+	viper.Set("site.host", "http://localhost:8080")
+
+	activeUser := func(db database.DatabaseInterface, email string) (*user.User, error) {
+		return user.New(user.UserFields{Id: 1, Username: "username", Email: email, Status: true}), nil
+	}
+
+	tests := []struct {
+		name       string
+		setupMock  func(mock sqlmock.Sqlmock)
+		wantStatus int
+	}{
+		{
+			name: "success",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM user_tokens`).
+					WithArgs(1, "reset").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`INSERT INTO user_tokens`).
+					WithArgs(1, "reset", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			wantStatus: http.StatusSeeOther,
+		},
+		{
+			name: "token error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM user_tokens`).WillReturnError(errors.New("db fail"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restoreFinders := patchFinders(findByUsername, activeUser)
+			defer restoreFinders()
+
+			restoreEmail := patchEmailer()
+			defer restoreEmail()
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			router.POST(paths.PathForgotPassword, ForgotPasswordPost)
+			tt.setupMock(mock)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", paths.PathForgotPassword, strings.NewReader("email=test@example.com"))
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
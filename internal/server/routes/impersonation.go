@@ -0,0 +1,157 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+const sessionKeyImpersonatorID = middleware.SessionKeyImpersonatorID
+
+const (
+	errImpersonateAdmin    = "You cannot log in as another admin."
+	errImpersonateInactive = "You can only log in as an active user."
+	errNotImpersonating    = "You are not logged in as another user."
+)
+
+var pathImpersonationStop = fmt.Sprintf("%s/impersonation/stop", paths.PathAccount)
+
+func impersonatorID(session sessions.Session) (int, bool) {
+	id, ok := session.Get(sessionKeyImpersonatorID).(int)
+
+	return id, ok && id > 0
+}
+
+func canImpersonate(db database.DatabaseInterface, adminID any, account *user.User) (string, error) {
+	if adminID == account.GetID() {
+		return errAdminSelf, nil
+	}
+
+	if !account.GetStatus() {
+		return errImpersonateInactive, nil
+	}
+
+	isAdmin, err := userHasPermission(db, account.GetID(), user.PermissionUsersManage)
+
+	if err != nil {
+		return "", err
+	}
+
+	if isAdmin {
+		return errImpersonateAdmin, nil
+	}
+
+	return "", nil
+}
+
+func adminImpersonate(c *gin.Context, v *validator.Validator, db database.DatabaseInterface, account *user.User) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	session := getSession(c)
+	accountPath := fmt.Sprintf("%s/%d", pathAdminUsers, account.GetID())
+
+	adminID, ok := session.Get("userID").(int)
+
+	if !ok {
+		log.Error("Could not get the admin from the session", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	reason, err := canImpersonate(db, adminID, account)
+
+	if err != nil {
+		log.Error("Could not check whether the user can be impersonated", logger.Fields{"userID": account.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if reason != "" {
+		v.SetFlash(message.Message{Type: message.MessageTypeError, Body: reason})
+		c.Redirect(http.StatusSeeOther, accountPath)
+
+		return
+	}
+
+	session.Clear()
+	session.Set("userID", account.GetID())
+	session.Set(sessionKeyImpersonatorID, adminID)
+
+	if err = session.Save(); err != nil {
+		log.Error("Failed to save the session for the impersonation", logger.Fields{"userID": account.GetID(), "adminID": adminID, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Admin started impersonating a user", logger.Fields{"userID": account.GetID(), "adminID": adminID})
+	auditEvent(c, audit.EventImpersonationStarted, adminID, account.GetID(), nil)
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: fmt.Sprintf("You are now logged in as %s.", account.GetUsername()),
+	})
+
+	c.Redirect(http.StatusSeeOther, paths.PathAccount)
+}
+
+func ImpersonationStop(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	session := getSession(c)
+	adminID, ok := impersonatorID(session)
+
+	if !ok {
+		v.SetFlash(message.Message{Type: message.MessageTypeError, Body: errNotImpersonating})
+		c.Redirect(http.StatusSeeOther, paths.PathAccount)
+
+		return
+	}
+
+	userID, _ := session.Get("userID").(int)
+	usr := route_utils.GetUserFromSession(c)
+
+	session.Clear()
+	session.Set("userID", adminID)
+
+	if err := session.Save(); err != nil {
+		log.Error("Failed to restore the session of the admin", logger.Fields{"userID": userID, "adminID": adminID, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Admin stopped impersonating a user", logger.Fields{"userID": userID, "adminID": adminID})
+	auditEvent(c, audit.EventImpersonationStopped, adminID, userID, nil)
+
+	body := "You are logged in as yourself again."
+
+	if usr != nil {
+		body = fmt.Sprintf("You are no longer logged in as %s.", usr.GetUsername())
+	}
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: body})
+
+	if userID > 0 {
+		c.Redirect(http.StatusSeeOther, fmt.Sprintf("%s/%d", pathAdminUsers, userID))
+		return
+	}
+
+	c.Redirect(http.StatusSeeOther, pathAdminUsers)
+}
@@ -0,0 +1,306 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func patchUserHasPermission(t *testing.T, hasPermission bool, err error) {
+	origUserHasPermission := userHasPermission
+
+	t.Cleanup(func() { userHasPermission = origUserHasPermission })
+	userHasPermission = func(database.DatabaseInterface, int, string) (bool, error) { return hasPermission, err }
+}
+
+func setImpersonatorID(adminID int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessions.Default(c).Set(sessionKeyImpersonatorID, adminID)
+
+		c.Next()
+	}
+}
+
+func sessionUsers(router *gin.Engine, w *httptest.ResponseRecorder) string {
+	router.GET("/test/session", func(c *gin.Context) {
+		session := sessions.Default(c)
+		c.String(http.StatusOK, "%v/%v", session.Get("userID"), session.Get(sessionKeyImpersonatorID))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test/session", nil)
+
+	// The session is saved more than once, and only the last cookie counts.
+	if cookies := w.Result().Cookies(); len(cookies) > 0 {
+		req.AddCookie(cookies[len(cookies)-1])
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	return rec.Body.String()
+}
+
+func TestCanImpersonate(t *testing.T) {
+	inactive := user.New(user.UserFields{Id: 2})
+
+	tests := []struct {
+		name          string
+		adminID       any
+		account       *user.User
+		hasPermission bool
+		permissionErr error
+		wantReason    string
+		wantErr       bool
+	}{
+		{name: "allowed", adminID: 1, account: newTestUserWithID(2)},
+		{name: "self", adminID: 2, account: newTestUserWithID(2), wantReason: errAdminSelf},
+		{name: "inactive", adminID: 1, account: inactive, wantReason: errImpersonateInactive},
+		{name: "admin", adminID: 1, account: newTestUserWithID(2), hasPermission: true, wantReason: errImpersonateAdmin},
+		{name: "permission error", adminID: 1, account: newTestUserWithID(2), permissionErr: errors.New("db fail"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patchUserHasPermission(t, tt.hasPermission, tt.permissionErr)
+
+			reason, err := canImpersonate(nil, tt.adminID, tt.account)
+
+			assert.Equal(t, tt.wantReason, reason)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestAdminUserPostImpersonate(t *testing.T) {
+	tests := []struct {
+		name          string
+		sessionID     int
+		hasPermission bool
+		permissionErr error
+		wantStatus    int
+		wantLocation  string
+		wantSession   string
+		wantEvents    []audit.EventType
+	}{
+		{
+			name:         "success",
+			sessionID:    1,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/account",
+			wantSession:  "2/1",
+			wantEvents:   []audit.EventType{audit.EventImpersonationStarted},
+		},
+		{
+			name:          "another admin",
+			sessionID:     1,
+			hasPermission: true,
+			wantStatus:    http.StatusSeeOther,
+			wantLocation:  "/admin/users/2",
+			wantSession:   "/<nil>",
+			wantEvents:    []audit.EventType{},
+		},
+		{
+			name:         "own account",
+			sessionID:    2,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/admin/users/2",
+			wantSession:  "/<nil>",
+			wantEvents:   []audit.EventType{},
+		},
+		{
+			name:          "permission error",
+			sessionID:     1,
+			permissionErr: errors.New("db fail"),
+			wantStatus:    http.StatusInternalServerError,
+			wantEvents:    []audit.EventType{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditLog := useRecordingAuditLog(t)
+
+			db, _, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			patchAdminUserLookups(t, newTestUserWithID(2), nil, nil, nil)
+			patchUserHasPermission(t, tt.hasPermission, tt.permissionErr)
+
+			router := setupAdminRouter(db, setSessionUserID(tt.sessionID))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, adminUserRequest("/admin/users/2", "impersonate"))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+			assert.Equal(t, tt.wantEvents, auditLog.types())
+
+			if tt.wantStatus == http.StatusSeeOther {
+				assert.Contains(t, sessionUsers(setupAdminRouter(db), w), tt.wantSession)
+			}
+		})
+	}
+}
+
+func TestImpersonationStop(t *testing.T) {
+	tests := []struct {
+		name         string
+		handlers     []gin.HandlerFunc
+		wantLocation string
+		wantSession  string
+		wantEvents   []audit.EventType
+	}{
+		{
+			name:         "impersonating",
+			handlers:     []gin.HandlerFunc{setSessionUserID(2), setImpersonatorID(1)},
+			wantLocation: "/admin/users/2",
+			wantSession:  "1/<nil>",
+			wantEvents:   []audit.EventType{audit.EventImpersonationStopped},
+		},
+		{
+			name:         "not impersonating",
+			handlers:     []gin.HandlerFunc{setSessionUserID(2)},
+			wantLocation: "/account",
+			wantSession:  "/<nil>",
+			wantEvents:   []audit.EventType{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditLog := useRecordingAuditLog(t)
+
+			db, _, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			patchAdminUserLookups(t, newTestUserWithID(2), nil, nil, nil)
+
+			router := setupAdminRouter(db, tt.handlers...)
+			router.POST(pathImpersonationStop, ImpersonationStop)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, pathImpersonationStop, nil))
+
+			assert.Equal(t, http.StatusSeeOther, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+			assert.Equal(t, tt.wantEvents, auditLog.types())
+			assert.Contains(t, sessionUsers(setupAdminRouter(db), w), tt.wantSession)
+		})
+	}
+}
+
+func TestImpersonationBanner(t *testing.T) {
+	tests := []struct {
+		name       string
+		handlers   []gin.HandlerFunc
+		wantBanner bool
+	}{
+		{
+			name:       "impersonating",
+			handlers:   []gin.HandlerFunc{setSessionUserID(1), setImpersonatorID(3)},
+			wantBanner: true,
+		},
+		{
+			name:     "not impersonating",
+			handlers: []gin.HandlerFunc{setSessionUserID(1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			expectSessionUser(mock, "")
+
+			router := setupAdminRouter(db, tt.handlers...)
+			router.GET("/test/page", func(c *gin.Context) {
+				RenderRouteHTML(c, RouteData{Template: "pages/index", HttpStatus: http.StatusOK})
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test/page", nil))
+
+			body := w.Body.String()
+
+			if !tt.wantBanner {
+				assert.NotContains(t, body, pathImpersonationStop)
+				return
+			}
+
+			assert.Contains(t, body, "You are logged in as username")
+			assert.Contains(t, body, pathImpersonationStop)
+		})
+	}
+}
+
+func TestLogoutWhileImpersonating(t *testing.T) {
+	auditLog := useRecordingAuditLog(t)
+
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	router := setupAdminRouter(db, setSessionUserID(2), setImpersonatorID(1))
+	router.GET("/logout", Logout)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/logout", nil))
+
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, []audit.EventType{audit.EventImpersonationStopped}, auditLog.types())
+	assert.Equal(t, 1, auditLog.events[0].ActorID)
+	assert.Equal(t, 2, auditLog.events[0].TargetID)
+	assert.Contains(t, sessionUsers(setupAdminRouter(db), w), "<nil>/<nil>")
+}
+
+func TestImpersonationBlocksSensitiveRoutes(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodPost, path: "/account/edit"},
+		{method: http.MethodPost, path: "/account/avatar"},
+		{method: http.MethodPost, path: "/account/devices"},
+		{method: http.MethodPost, path: "/account/invites"},
+		{method: http.MethodPost, path: "/account/organizations"},
+		{method: http.MethodPost, path: "/account/organizations/1"},
+		{method: http.MethodPost, path: "/account/email"},
+		{method: http.MethodPost, path: "/account/password"},
+		{method: http.MethodGet, path: "/account/delete"},
+		{method: http.MethodPost, path: "/account/delete"},
+		{method: http.MethodPost, path: "/account/two-factor"},
+		{method: http.MethodGet, path: "/account/passkeys"},
+		{method: http.MethodPost, path: "/account/passkeys/register"},
+		{method: http.MethodPost, path: "/account/tokens"},
+		{method: http.MethodPost, path: "/account/export"},
+		{method: http.MethodGet, path: "/account/export/download"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			router, _, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			router.Use(setSessionUserID(2), setImpersonatorID(1))
+			RegisterAuthOnlyRoutes(router.Group("/"))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+	}
+}
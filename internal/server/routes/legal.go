@@ -0,0 +1,202 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/legal"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	errLegalNotAccepted    = "You must accept the documents to continue"
+	errLegalImpersonating  = "You cannot accept the documents on behalf of the user."
+	errRegisterNotAccepted = "You must accept the documents to register"
+)
+
+var findCurrentDocuments = legal.Current
+var findCurrentDocument = legal.CurrentByKind
+var findPendingDocuments = legal.Pending
+var acceptDocuments = legal.Accept
+
+func LegalDocument(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	kind, err := legal.ParseKind(c.Param("kind"))
+
+	if err != nil {
+		NotFound(c)
+		return
+	}
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Failed to get database connection from context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	doc, err := findCurrentDocument(db, kind)
+
+	if err != nil {
+		if errors.Is(err, legal.ErrDocumentNotFound) {
+			NotFound(c)
+			return
+		}
+
+		log.Error("Could not find the legal document", logger.Fields{"kind": kind, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	data := RouteData{
+		Template:   "pages/legal_document",
+		HttpStatus: http.StatusOK,
+
+		Title:       doc.Title(),
+		Description: doc.Title(),
+
+		Data: map[string]any{
+			"Document": doc,
+		},
+	}
+
+	RenderRouteHTML(c, data)
+}
+
+func LegalAccept(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	pending, err := findPendingDocuments(db, usr.GetID())
+
+	if err != nil {
+		log.Error("Could not find the pending legal documents", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if len(pending) == 0 {
+		c.Redirect(http.StatusSeeOther, paths.PathAccount)
+		return
+	}
+
+	_, impersonating := impersonatorID(getSession(c))
+
+	data := RouteData{
+		Template:   "pages/legal_accept",
+		HttpStatus: http.StatusOK,
+
+		Title:       "Updated Terms",
+		Description: "Accept the updated terms to continue",
+
+		Data: map[string]any{
+			"Documents":     pending,
+			"Impersonating": impersonating,
+		},
+
+		FormData: FormData{
+			Values: v.GetFormData(),
+			Errors: v.GetSessionErrors(),
+		},
+		CSRFToken: middleware.GetCSRFToken(c),
+	}
+
+	RenderRouteHTML(c, data)
+}
+
+func LegalAcceptPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	err := v.ValidateForm(c.Request)
+
+	if err != nil {
+		log.Error("Failed to parse form data", logger.Fields{"error": err.Error()})
+	}
+
+	if _, ok := impersonatorID(getSession(c)); ok {
+		v.SetFlash(message.Message{Type: message.MessageTypeError, Body: errLegalImpersonating})
+		c.Redirect(http.StatusSeeOther, paths.PathLegalAccept)
+
+		return
+	}
+
+	v.CheckField(v.GetFormValue(c.Request, "accept") == "on", "accept", errLegalNotAccepted)
+
+	if v.HasErrors() {
+		route_utils.RedirectWithError(c, v, nil, "Please correct the errors below", paths.PathLegalAccept)
+		return
+	}
+
+	usr := route_utils.GetUserFromSession(c)
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil || usr == nil {
+		log.Error("Could not get the user or database from the context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	pending, err := findPendingDocuments(db, usr.GetID())
+
+	if err != nil {
+		log.Error("Could not find the pending legal documents", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	pending = shownDocuments(c, pending)
+	err = acceptDocuments(db, usr.GetID(), pending, c.ClientIP())
+
+	if err != nil {
+		log.Error("Could not accept the legal documents", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("User accepted the legal documents", logger.Fields{"userID": usr.GetID(), "documents": len(pending)})
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: "Thank you for accepting the updated terms.",
+	})
+
+	c.Redirect(http.StatusSeeOther, paths.PathAccount)
+}
+
+func shownDocuments(c *gin.Context, docs []legal.Document) []legal.Document {
+	shown := c.Request.PostForm["documents"]
+
+	return slices.DeleteFunc(slices.Clone(docs), func(doc legal.Document) bool {
+		return !slices.Contains(shown, strconv.Itoa(doc.ID))
+	})
+}
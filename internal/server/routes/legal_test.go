@@ -0,0 +1,343 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/legal"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type legalAcceptances struct {
+	userIDs []int
+	docIDs  [][]int
+}
+
+func testLegalDocuments() []legal.Document {
+	return []legal.Document{
+		{ID: 1, Kind: legal.KindTerms, Version: "2026-10", Body: "The terms", PublishedAt: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Kind: legal.KindPrivacy, Version: "2026-09", Body: "The policy", PublishedAt: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)},
+	}
+}
+
+func useLegalDocuments(t *testing.T, docs []legal.Document, findErr error, acceptErr error) *legalAcceptances {
+	accepted := &legalAcceptances{}
+
+	origFindCurrentDocuments := findCurrentDocuments
+	origFindCurrentDocument := findCurrentDocument
+	origFindPendingDocuments := findPendingDocuments
+	origAcceptDocuments := acceptDocuments
+
+	t.Cleanup(func() {
+		findCurrentDocuments = origFindCurrentDocuments
+		findCurrentDocument = origFindCurrentDocument
+		findPendingDocuments = origFindPendingDocuments
+		acceptDocuments = origAcceptDocuments
+	})
+
+	findCurrentDocuments = func(_ database.DatabaseInterface) ([]legal.Document, error) {
+		return docs, findErr
+	}
+
+	findCurrentDocument = func(_ database.DatabaseInterface, kind legal.Kind) (*legal.Document, error) {
+		if findErr != nil {
+			return nil, findErr
+		}
+
+		for _, doc := range docs {
+			if doc.Kind == kind {
+				return &doc, nil
+			}
+		}
+
+		return nil, legal.ErrDocumentNotFound
+	}
+
+	findPendingDocuments = func(_ database.DatabaseInterface, _ int) ([]legal.Document, error) {
+		return docs, findErr
+	}
+
+	acceptDocuments = func(_ database.DatabaseInterface, userID int, accepting []legal.Document, _ string) error {
+		ids := []int{}
+
+		for _, doc := range accepting {
+			ids = append(ids, doc.ID)
+		}
+
+		accepted.userIDs = append(accepted.userIDs, userID)
+		accepted.docIDs = append(accepted.docIDs, ids)
+
+		return acceptErr
+	}
+
+	return accepted
+}
+
+func setSessionImpersonator(adminID int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessions.Default(c).Set(middleware.SessionKeyImpersonatorID, adminID)
+		c.Next()
+	}
+}
+
+func TestLegalDocument(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		docs         []legal.Document
+		findErr      error
+		useDB        bool
+		expectStatus int
+		expectBody   []string
+	}{
+		{
+			name:         "terms",
+			path:         "/legal/terms",
+			docs:         testLegalDocuments(),
+			useDB:        true,
+			expectStatus: http.StatusOK,
+			expectBody:   []string{"Terms of Service", "Version 2026-10", "October 1, 2026", "The terms"},
+		},
+		{
+			name:         "unknown kind",
+			path:         "/legal/cookies",
+			docs:         testLegalDocuments(),
+			useDB:        true,
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			name:         "not published",
+			path:         "/legal/privacy",
+			docs:         testLegalDocuments()[:1],
+			useDB:        true,
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			name:         "find error",
+			path:         "/legal/terms",
+			findErr:      errors.New("db fail"),
+			useDB:        true,
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "no database",
+			path:         "/legal/terms",
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useLegalDocuments(t, tt.docs, tt.findErr, nil)
+
+			router, _, mockDB := setupTestRouterWithMocks(t, tt.useDB)
+			defer func() { _ = mockDB.Close() }()
+
+			router.GET("/legal/:kind", LegalDocument)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectStatus, w.Code)
+
+			for _, body := range tt.expectBody {
+				assert.Contains(t, w.Body.String(), body)
+			}
+		})
+	}
+}
+
+func TestLegalAccept(t *testing.T) {
+	tests := []struct {
+		name           string
+		docs           []legal.Document
+		findErr        error
+		impersonating  bool
+		expectStatus   int
+		expectLocation string
+		expectBody     []string
+		expectNotBody  string
+	}{
+		{
+			name:         "pending documents",
+			docs:         testLegalDocuments(),
+			expectStatus: http.StatusOK,
+			expectBody:   []string{"Terms of Service", "Privacy Policy", "version 2026-10", "name=accept", `name=documents value="2"`},
+		},
+		{
+			name:           "nothing pending",
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathAccount,
+		},
+		{
+			name:          "impersonating",
+			docs:          testLegalDocuments(),
+			impersonating: true,
+			expectStatus:  http.StatusOK,
+			expectBody:    []string{"on their behalf"},
+			expectNotBody: "name=accept",
+		},
+		{
+			name:         "find error",
+			findErr:      errors.New("db fail"),
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useLegalDocuments(t, tt.docs, tt.findErr, nil)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			handlers := []gin.HandlerFunc{setSessionUserID(1)}
+
+			if tt.impersonating {
+				handlers = append(handlers, setSessionImpersonator(2))
+			}
+
+			router.GET(paths.PathLegalAccept, append(handlers, LegalAccept)...)
+			expectAvatarUser(mock, "")
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", paths.PathLegalAccept, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectStatus, w.Code)
+			assert.Equal(t, tt.expectLocation, w.Header().Get("Location"))
+
+			for _, body := range tt.expectBody {
+				assert.Contains(t, w.Body.String(), body)
+			}
+
+			if tt.expectNotBody != "" {
+				assert.NotContains(t, w.Body.String(), tt.expectNotBody)
+			}
+		})
+	}
+}
+
+func TestLegalAcceptNoUser(t *testing.T) {
+	useLegalDocuments(t, testLegalDocuments(), nil, nil)
+
+	router, _, mockDB := setupTestRouterWithMocks(t, false)
+	defer func() { _ = mockDB.Close() }()
+
+	router.GET(paths.PathLegalAccept, setSessionUserID(1), LegalAccept)
+	router.POST(paths.PathLegalAccept, setSessionUserID(1), LegalAcceptPost)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", paths.PathLegalAccept, nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", paths.PathLegalAccept, strings.NewReader("accept=on"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestLegalAcceptPost(t *testing.T) {
+	tests := []struct {
+		name           string
+		form           url.Values
+		findErr        error
+		acceptErr      error
+		impersonating  bool
+		expectUser     bool
+		expectStatus   int
+		expectLocation string
+		expectAccepted [][]int
+	}{
+		{
+			name:           "accept",
+			form:           url.Values{"accept": {"on"}, "documents": {"1", "2"}},
+			expectUser:     true,
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathAccount,
+			expectAccepted: [][]int{{1, 2}},
+		},
+		{
+			name:           "only the documents that were shown",
+			form:           url.Values{"accept": {"on"}, "documents": {"2"}},
+			expectUser:     true,
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathAccount,
+			expectAccepted: [][]int{{2}},
+		},
+		{
+			name:           "not accepted",
+			form:           url.Values{"documents": {"1", "2"}},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLegalAccept,
+		},
+		{
+			name:           "impersonating",
+			form:           url.Values{"accept": {"on"}, "documents": {"1", "2"}},
+			impersonating:  true,
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLegalAccept,
+		},
+		{
+			name:         "find error",
+			form:         url.Values{"accept": {"on"}, "documents": {"1", "2"}},
+			findErr:      errors.New("db fail"),
+			expectUser:   true,
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "accept error",
+			form:           url.Values{"accept": {"on"}, "documents": {"1"}},
+			acceptErr:      errors.New("db fail"),
+			expectUser:     true,
+			expectStatus:   http.StatusInternalServerError,
+			expectAccepted: [][]int{{1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accepted := useLegalDocuments(t, testLegalDocuments(), tt.findErr, tt.acceptErr)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			handlers := []gin.HandlerFunc{setSessionUserID(1)}
+
+			if tt.impersonating {
+				handlers = append(handlers, setSessionImpersonator(2))
+			}
+
+			router.POST(paths.PathLegalAccept, append(handlers, LegalAcceptPost)...)
+
+			if tt.expectUser {
+				expectAvatarUser(mock, "")
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", paths.PathLegalAccept, strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectStatus, w.Code)
+			assert.Equal(t, tt.expectLocation, w.Header().Get("Location"))
+			assert.Equal(t, tt.expectAccepted, accepted.docIDs)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/Dobefu/go-web-starter/internal/audit"
 	"github.com/Dobefu/go-web-starter/internal/config"
 	"github.com/Dobefu/go-web-starter/internal/logger"
 	"github.com/Dobefu/go-web-starter/internal/message"
@@ -17,6 +18,7 @@ import (
 )
 
 func Login(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
 	v := validator.New()
 	v.SetContext(c)
 
@@ -29,6 +31,10 @@ func Login(c *gin.Context) {
 		Title:       "Log In",
 		Description: "Sign in to your account",
 
+		Data: map[string]any{
+			"OIDCProviders": oidcProviders(log),
+			"MagicLink":     config.MagicLinkEnabled(),
+		},
 		FormData: FormData{
 			Values: v.GetFormData(),
 			Errors: v.GetSessionErrors(),
@@ -81,13 +87,36 @@ func LoginPost(c *gin.Context) {
 		return
 	}
 
+	throttle := newLoginThrottle(c, db, email)
+
+	if status := throttle.check(c); status.Blocked() {
+		log.Warn("Login blocked: too many failed attempts", logger.Fields{"email": email, "ip": c.ClientIP()})
+		route_utils.RedirectWithError(
+			c,
+			v,
+			map[string]string{
+				"email": email,
+			},
+			loginThrottledMessage(status),
+			paths.PathLogin,
+		)
+		return
+	}
+
 	foundUser, err := findByEmail(db, email)
 
+	// A deleted account can be restored by logging in during its grace period.
+	if errors.Is(err, user.ErrInvalidCredentials) {
+		foundUser, err = findRestorableByEmail(db, email)
+	}
+
 	if err != nil {
 		if errors.Is(err, user.ErrInvalidCredentials) {
 			v.AddFieldError("email", user.ErrInvalidCredentials.Error())
+			throttle.fail(c)
 
 			log.Warn("Login failed: invalid credentials (email not found)", map[string]any{"email": email})
+			auditEvent(c, audit.EventLoginFailed, 0, 0, audit.Metadata{"method": "password", "email": email})
 			route_utils.RedirectWithError(
 				c,
 				v,
@@ -105,13 +134,23 @@ func LoginPost(c *gin.Context) {
 		return
 	}
 
-	err = foundUser.CheckPassword(password)
+	err = foundUser.CheckPassword(db, password)
 
 	if err != nil {
 		if errors.Is(err, user.ErrInvalidCredentials) {
 			v.AddFieldError("email", user.ErrInvalidCredentials.Error())
 
 			log.Warn("Login failed: invalid credentials (password mismatch)", map[string]any{"email": email})
+			auditEvent(c, audit.EventLoginFailed, 0, foundUser.GetID(), audit.Metadata{"method": "password"})
+
+			if status := throttle.fail(c); status.JustLocked && foundUser.GetStatus() {
+				log.Warn("Account locked after too many failed login attempts", logger.Fields{"userID": foundUser.GetID()})
+
+				if err = sendUnlockEmail(db, foundUser); err != nil {
+					log.Error("Failed to send the unlock email", logger.Fields{"userID": foundUser.GetID(), "error": err.Error()})
+				}
+			}
+
 			route_utils.RedirectWithError(
 				c,
 				v,
@@ -143,7 +182,31 @@ func LoginPost(c *gin.Context) {
 		return
 	}
 
+	hasTwoFactor, err := userHasTwoFactor(foundUser, db)
+
+	if err != nil {
+		log.Error("Failed to check two-factor authentication during login", map[string]any{"email": email, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
 	session := getSession(c)
+
+	if hasTwoFactor {
+		startTwoFactorLogin(c, session, foundUser)
+		return
+	}
+
+	err = restoreOnLogin(c, db, foundUser)
+
+	if err != nil {
+		log.Error("Failed to restore the account during login", logger.Fields{"userID": foundUser.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
 	err = foundUser.Login(db, session)
 
 	if err != nil {
@@ -153,11 +216,21 @@ func LoginPost(c *gin.Context) {
 		return
 	}
 
+	throttle.reset(c)
+
 	log.Info("Login successful", map[string]any{
 		"email":  email,
 		"userID": foundUser.GetID(),
 	})
 
+	auditEvent(c, audit.EventLoginSucceeded, foundUser.GetID(), foundUser.GetID(), audit.Metadata{"method": "password"})
+	notifyNewDevice(c, db, foundUser)
+
 	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: "Successfully logged in!"})
 	c.Redirect(http.StatusSeeOther, paths.PathAccount)
 }
+
+func loginRedirectError(c *gin.Context, v *validator.Validator, msg string) {
+	v.SetFlash(message.Message{Type: message.MessageTypeError, Body: msg})
+	c.Redirect(http.StatusSeeOther, paths.PathLogin)
+}
@@ -0,0 +1,205 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	emailer "github.com/Dobefu/go-web-starter/internal/email"
+	"github.com/Dobefu/go-web-starter/internal/lockout"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	errLoginThrottled = "Too many failed login attempts. Please try again in %s."
+	errLoginLocked    = "This account has been locked because of too many failed login attempts. Please check your email to unlock it, or try again in %s."
+	errLoginUnlock    = "Could not unlock the account. The link may have expired."
+)
+
+var newLockoutStore = func(c *gin.Context, db database.DatabaseInterface) lockout.Store {
+	if r, err := route_utils.GetRedisFromContext(c); err == nil {
+		return lockout.NewRedisStore(r)
+	}
+
+	return lockout.NewDatabaseStore(db)
+}
+
+type loginThrottle struct {
+	log        *logger.Logger
+	account    *lockout.Limiter
+	ip         *lockout.Limiter
+	accountKey string
+	ipKey      string
+}
+
+func newLoginThrottle(c *gin.Context, db database.DatabaseInterface, email string) *loginThrottle {
+	store := newLockoutStore(c, db)
+
+	return &loginThrottle{
+		log:        logger.New(config.GetLogLevel(), os.Stdout),
+		account:    lockout.New(store, lockout.AccountPolicy),
+		ip:         lockout.New(store, lockout.IPPolicy),
+		accountKey: lockout.AccountKey(email),
+		ipKey:      lockout.IPKey(c.ClientIP()),
+	}
+}
+
+// check returns the status with the longest wait. When the attempts cannot be
+// read, the login is allowed, so that an outage does not lock out everyone.
+func (t *loginThrottle) check(c *gin.Context) lockout.Status {
+	account := t.checkKey(c, t.account, t.accountKey)
+
+	if account.Locked {
+		return account
+	}
+
+	ip := t.checkKey(c, t.ip, t.ipKey)
+
+	if ip.RetryAfter > account.RetryAfter {
+		return ip
+	}
+
+	return account
+}
+
+func (t *loginThrottle) checkKey(c *gin.Context, limiter *lockout.Limiter, key string) lockout.Status {
+	status, err := limiter.Check(c, key)
+
+	if err != nil {
+		t.log.Error("Could not check the failed login attempts", logger.Fields{"key": key, "error": err.Error()})
+	}
+
+	return status
+}
+
+func (t *loginThrottle) fail(c *gin.Context) lockout.Status {
+	_, err := t.ip.Fail(c, t.ipKey)
+
+	if err != nil {
+		t.log.Error("Could not record the failed login attempt", logger.Fields{"key": t.ipKey, "error": err.Error()})
+	}
+
+	status, err := t.account.Fail(c, t.accountKey)
+
+	if err != nil {
+		t.log.Error("Could not record the failed login attempt", logger.Fields{"key": t.accountKey, "error": err.Error()})
+	}
+
+	return status
+}
+
+func (t *loginThrottle) reset(c *gin.Context) {
+	err := t.account.Reset(c, t.accountKey)
+
+	if err != nil {
+		t.log.Error("Could not reset the failed login attempts", logger.Fields{"key": t.accountKey, "error": err.Error()})
+	}
+}
+
+func loginThrottledMessage(status lockout.Status) string {
+	retryAfter := formatRetryAfter(status.RetryAfter)
+
+	if status.Locked {
+		return fmt.Sprintf(errLoginLocked, retryAfter)
+	}
+
+	return fmt.Sprintf(errLoginThrottled, retryAfter)
+}
+
+func formatRetryAfter(d time.Duration) string {
+	if d > time.Minute {
+		return fmt.Sprintf("%d minutes", int(math.Ceil(d.Minutes())))
+	}
+
+	seconds := int(math.Ceil(d.Seconds()))
+
+	if seconds == 1 {
+		return "1 second"
+	}
+
+	return fmt.Sprintf("%d seconds", seconds)
+}
+
+func sendUnlockEmail(db database.DatabaseInterface, usr *user.User) error {
+	token, err := usr.CreateToken(db, user.TokenPurposeUnlock, user.TokenTTLUnlock)
+
+	if err != nil {
+		return err
+	}
+
+	return newEmailSender().SendMail(
+		viper.GetString("site.email"),
+		[]string{usr.GetEmail()},
+		fmt.Sprintf("Your %s account has been locked", viper.GetString("site.name")),
+		emailer.EmailBody{
+			Template: "email/login_unlock",
+			Data: map[string]any{
+				"Username": usr.GetUsername(),
+				"Token":    token,
+			},
+		},
+	)
+}
+
+func LoginUnlock(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	token := v.GetFormValue(c.Request, "token")
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Could not get the database from the context", logger.Fields{"err": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	usr, err := user.ConsumeToken(db, user.TokenPurposeUnlock, token)
+
+	if err != nil {
+		if !errors.Is(err, user.ErrInvalidToken) {
+			log.Error("Could not verify the token", logger.Fields{"err": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+
+		v.SetFlash(message.Message{Type: message.MessageTypeError, Body: errLoginUnlock})
+		c.Redirect(http.StatusSeeOther, paths.PathLogin)
+
+		return
+	}
+
+	err = lockout.New(newLockoutStore(c, db), lockout.AccountPolicy).Reset(c, lockout.AccountKey(usr.GetEmail()))
+
+	if err != nil {
+		log.Error("Could not unlock the account", logger.Fields{"userID": usr.GetID(), "err": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Account unlocked", logger.Fields{"userID": usr.GetID()})
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: "Your account has been unlocked. You can now log in.",
+	})
+
+	c.Redirect(http.StatusSeeOther, paths.PathLogin)
+}
@@ -0,0 +1,521 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/lockout"
+	"github.com/Dobefu/go-web-starter/internal/redis"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryLockoutStore struct {
+	attempts map[string]lockout.Attempts
+	err      error
+}
+
+func (s *memoryLockoutStore) Get(_ context.Context, key string) (*lockout.Attempts, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	attempts, ok := s.attempts[key]
+
+	if !ok {
+		return nil, nil
+	}
+
+	return &attempts, nil
+}
+
+func (s *memoryLockoutStore) Increment(_ context.Context, key string, window time.Duration) (*lockout.Attempts, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	attempts := s.attempts[key]
+
+	if time.Since(attempts.LastFailure) > window {
+		attempts = lockout.Attempts{}
+	}
+
+	attempts.Failures++
+	attempts.LastFailure = time.Now()
+	s.attempts[key] = attempts
+
+	return &attempts, nil
+}
+
+func (s *memoryLockoutStore) Lock(_ context.Context, key string, until time.Time) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+
+	attempts := s.attempts[key]
+
+	if attempts.LockedUntil.After(time.Now()) {
+		return false, nil
+	}
+
+	attempts.LockedUntil = until
+	s.attempts[key] = attempts
+
+	return true, nil
+}
+
+func (s *memoryLockoutStore) Delete(_ context.Context, key string) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	delete(s.attempts, key)
+
+	return nil
+}
+
+func useMemoryLockoutStore(t *testing.T) *memoryLockoutStore {
+	store := &memoryLockoutStore{attempts: map[string]lockout.Attempts{}}
+	newLockoutStoreOrig := newLockoutStore
+
+	t.Cleanup(func() { newLockoutStore = newLockoutStoreOrig })
+	newLockoutStore = func(*gin.Context, database.DatabaseInterface) lockout.Store { return store }
+
+	return store
+}
+
+func loginRequest(email, password string) *http.Request {
+	form := url.Values{"email": {email}, "password": {password}}
+	req, _ := http.NewRequest("POST", paths.PathLogin, strings.NewReader(form.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "192.0.2.1:1234"
+
+	return req
+}
+
+func TestLoginPostLockout(t *testing.T) {
+	viper.Set("site.name", "Test Site")
+	viper.Set("site.host", "http://localhost:8080")
+
+	hash, _ := user.HashPassword("pw")
+	accountKey := lockout.AccountKey("user@example.com")
+	ipKey := lockout.IPKey("192.0.2.1")
+
+	origFindByEmail := findByEmail
+	origUserHasTwoFactor := userHasTwoFactor
+
+	t.Cleanup(func() {
+		findByEmail = origFindByEmail
+		userHasTwoFactor = origUserHasTwoFactor
+	})
+
+	findByEmail = func(db database.DatabaseInterface, email string) (*user.User, error) {
+		if email != "user@example.com" {
+			return nil, user.ErrInvalidCredentials
+		}
+
+		return user.New(user.UserFields{Id: 1, Username: "user", Email: email, Password: hash, Status: true}), nil
+	}
+
+	userHasTwoFactor = func(*user.User, database.DatabaseInterface) (bool, error) { return false, nil }
+	useDeletedUsers(t)
+
+	tests := []struct {
+		name           string
+		email          string
+		password       string
+		twoFactor      bool
+		attempts       map[string]lockout.Attempts
+		setupMock      func(mock sqlmock.Sqlmock)
+		expectLocation string
+		expectUnlock   bool
+		check          func(t *testing.T, store *memoryLockoutStore)
+	}{
+		{
+			name:     "locked account",
+			email:    "user@example.com",
+			password: "pw",
+			attempts: map[string]lockout.Attempts{
+				accountKey: {Failures: 10, LastFailure: time.Now(), LockedUntil: time.Now().Add(time.Hour)},
+			},
+			expectLocation: paths.PathLogin,
+		},
+		{
+			name:     "throttled IP address",
+			email:    "user@example.com",
+			password: "pw",
+			attempts: map[string]lockout.Attempts{
+				ipKey: {Failures: 20, LastFailure: time.Now()},
+			},
+			expectLocation: paths.PathLogin,
+		},
+		{
+			name:           "unknown email is counted",
+			email:          "unknown@example.com",
+			password:       "pw",
+			expectLocation: paths.PathLogin,
+			check: func(t *testing.T, store *memoryLockoutStore) {
+				assert.Equal(t, 1, store.attempts[lockout.AccountKey("unknown@example.com")].Failures)
+				assert.Equal(t, 1, store.attempts[ipKey].Failures)
+			},
+		},
+		{
+			name:     "wrong password locks the account",
+			email:    "user@example.com",
+			password: "wrong",
+			attempts: map[string]lockout.Attempts{
+				accountKey: {Failures: 9, LastFailure: time.Now().Add(-10 * time.Minute)},
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM user_tokens`).WithArgs(1, "unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(`INSERT INTO user_tokens`).
+					WithArgs(1, "unlock", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			expectLocation: paths.PathLogin,
+			expectUnlock:   true,
+			check: func(t *testing.T, store *memoryLockoutStore) {
+				assert.True(t, store.attempts[accountKey].LockedUntil.After(time.Now()))
+			},
+		},
+		{
+			name:     "unlock email error",
+			email:    "user@example.com",
+			password: "wrong",
+			attempts: map[string]lockout.Attempts{
+				accountKey: {Failures: 9, LastFailure: time.Now().Add(-10 * time.Minute)},
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM user_tokens`).WillReturnError(errors.New("db fail"))
+			},
+			expectLocation: paths.PathLogin,
+		},
+		{
+			name:     "successful login resets the account",
+			email:    "user@example.com",
+			password: "pw",
+			attempts: map[string]lockout.Attempts{
+				accountKey: {Failures: 5, LastFailure: time.Now().Add(-10 * time.Minute)},
+				ipKey:      {Failures: 5, LastFailure: time.Now().Add(-10 * time.Minute)},
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE users SET .+`).
+					WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+			},
+			expectLocation: paths.PathAccount,
+			check: func(t *testing.T, store *memoryLockoutStore) {
+				assert.NotContains(t, store.attempts, accountKey)
+				assert.Contains(t, store.attempts, ipKey)
+			},
+		},
+		{
+			name:      "password alone does not reset a two-factor account",
+			email:     "user@example.com",
+			password:  "pw",
+			twoFactor: true,
+			attempts: map[string]lockout.Attempts{
+				accountKey: {Failures: 5, LastFailure: time.Now().Add(-10 * time.Minute)},
+			},
+			expectLocation: pathLoginTwoFactor,
+			check: func(t *testing.T, store *memoryLockoutStore) {
+				assert.Equal(t, 5, store.attempts[accountKey].Failures)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := useMemoryLockoutStore(t)
+			patchUserHasTwoFactor(t, tt.twoFactor)
+
+			for key, attempts := range tt.attempts {
+				store.attempts[key] = attempts
+			}
+
+			sender := useRecordingEmailSender(t)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tt.setupMock != nil {
+				tt.setupMock(mock)
+			}
+
+			router.POST(paths.PathLogin, LoginPost)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, loginRequest(tt.email, tt.password))
+
+			assert.Equal(t, http.StatusSeeOther, w.Code)
+			assert.Equal(t, tt.expectLocation, w.Header().Get("Location"))
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.check != nil {
+				tt.check(t, store)
+			}
+
+			if !tt.expectUnlock {
+				assert.Empty(t, sender.sent)
+				return
+			}
+
+			assert.Len(t, sender.sent, 1)
+			assert.Equal(t, "email/login_unlock", sender.sent[0].Template)
+		})
+	}
+}
+
+func TestLoginTwoFactorPostLockout(t *testing.T) {
+	accountKey := lockout.AccountKey("user@example.com")
+
+	origFindByID := findByID
+	origUserVerifyTwoFactor := userVerifyTwoFactor
+
+	t.Cleanup(func() {
+		findByID = origFindByID
+		userVerifyTwoFactor = origUserVerifyTwoFactor
+	})
+
+	findByID = func(_ database.DatabaseInterface, id int) (*user.User, error) {
+		return newTestUserWithID(id), nil
+	}
+
+	tests := []struct {
+		name           string
+		attempts       map[string]lockout.Attempts
+		verifyErr      error
+		setupMock      func(mock sqlmock.Sqlmock)
+		expectVerify   bool
+		expectLocation string
+		check          func(t *testing.T, store *memoryLockoutStore)
+	}{
+		{
+			name:           "invalid code is counted",
+			verifyErr:      user.ErrInvalidTwoFactorCode,
+			expectVerify:   true,
+			expectLocation: pathLoginTwoFactor,
+			check: func(t *testing.T, store *memoryLockoutStore) {
+				assert.Equal(t, 1, store.attempts[accountKey].Failures)
+			},
+		},
+		{
+			name: "invalid code locks the account",
+			attempts: map[string]lockout.Attempts{
+				accountKey: {Failures: 9, LastFailure: time.Now().Add(-10 * time.Minute)},
+			},
+			verifyErr: user.ErrInvalidTwoFactorCode,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM user_tokens").WithArgs(42, "unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO user_tokens").WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			expectVerify:   true,
+			expectLocation: pathLoginTwoFactor,
+			check: func(t *testing.T, store *memoryLockoutStore) {
+				assert.True(t, store.attempts[accountKey].LockedUntil.After(time.Now()))
+			},
+		},
+		{
+			name: "locked account",
+			attempts: map[string]lockout.Attempts{
+				accountKey: {Failures: 10, LastFailure: time.Now(), LockedUntil: time.Now().Add(time.Hour)},
+			},
+			expectLocation: paths.PathLogin,
+		},
+		{
+			name: "successful login resets the account",
+			attempts: map[string]lockout.Attempts{
+				accountKey: {Failures: 5, LastFailure: time.Now().Add(-10 * time.Minute)},
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE users SET .+`).
+					WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+				expectRememberDevice(mock, true, false)
+			},
+			expectVerify:   true,
+			expectLocation: paths.PathAccount,
+			check: func(t *testing.T, store *memoryLockoutStore) {
+				assert.NotContains(t, store.attempts, accountKey)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := useMemoryLockoutStore(t)
+
+			for key, attempts := range tt.attempts {
+				store.attempts[key] = attempts
+			}
+
+			restoreEmail := patchEmailer()
+			defer restoreEmail()
+
+			verified := false
+			userVerifyTwoFactor = func(*user.User, database.DatabaseInterface, string) error {
+				verified = true
+				return tt.verifyErr
+			}
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tt.setupMock != nil {
+				tt.setupMock(mock)
+			}
+
+			router.POST(pathLoginTwoFactor, setPendingTwoFactorLogin(time.Now(), 0), LoginTwoFactorPost)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", pathLoginTwoFactor, strings.NewReader("code=123456"))
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusSeeOther, w.Code)
+			assert.Equal(t, tt.expectLocation, w.Header().Get("Location"))
+			assert.Equal(t, tt.expectVerify, verified)
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.check != nil {
+				tt.check(t, store)
+			}
+		})
+	}
+}
+
+func TestLoginPostLockoutStoreError(t *testing.T) {
+	store := useMemoryLockoutStore(t)
+	store.err = errors.New("store fail")
+	useDeletedUsers(t)
+
+	origFindByEmail := findByEmail
+	t.Cleanup(func() { findByEmail = origFindByEmail })
+
+	findByEmail = func(database.DatabaseInterface, string) (*user.User, error) {
+		return nil, user.ErrInvalidCredentials
+	}
+
+	router, _, mockDB := setupTestRouterWithMocks(t, true)
+	defer func() { _ = mockDB.Close() }()
+
+	router.POST(paths.PathLogin, LoginPost)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, loginRequest("user@example.com", "pw"))
+
+	// The login attempt is still handled when the store is unavailable.
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, paths.PathLogin, w.Header().Get("Location"))
+}
+
+func TestLoginUnlock(t *testing.T) {
+	accountKey := lockout.AccountKey("test@example.com")
+
+	tests := []struct {
+		name           string
+		setupMock      func(mock sqlmock.Sqlmock)
+		storeErr       error
+		expectStatus   int
+		expectLocation string
+		expectUnlocked bool
+	}{
+		{
+			name: "success",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "unlock", true)
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+			expectUnlocked: true,
+		},
+		{
+			name: "invalid or expired token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE user_tokens SET consumed_at`).WillReturnError(sql.ErrNoRows)
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE user_tokens SET consumed_at`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "store error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "unlock", true)
+			},
+			storeErr:     errors.New("store fail"),
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := useMemoryLockoutStore(t)
+			store.attempts[accountKey] = lockout.Attempts{Failures: 10, LockedUntil: time.Now().Add(time.Hour)}
+			store.err = tt.storeErr
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			router.GET(paths.PathLogin+"/unlock", LoginUnlock)
+			tt.setupMock(mock)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", paths.PathLogin+"/unlock?token=test-token", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectStatus, w.Code)
+			assert.Equal(t, tt.expectLocation, w.Header().Get("Location"))
+			assert.Equal(t, !tt.expectUnlocked, store.attempts[accountKey].Failures > 0)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLoginUnlockWithoutDatabase(t *testing.T) {
+	router, _, mockDB := setupTestRouterWithMocks(t, false)
+	defer func() { _ = mockDB.Close() }()
+
+	router.GET(paths.PathLogin+"/unlock", LoginUnlock)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", paths.PathLogin+"/unlock?token=test-token", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestNewLockoutStore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	db, _, _ := sqlmock.New()
+	defer func() { _ = db.Close() }()
+
+	assert.IsType(t, &lockout.DatabaseStore{}, newLockoutStore(c, db))
+
+	c.Set("redis", redis.NewWithMockDB(nil, nil))
+	assert.IsType(t, &lockout.RedisStore{}, newLockoutStore(c, db))
+}
+
+func TestLoginThrottledMessage(t *testing.T) {
+	assert.Equal(t, "Too many failed login attempts. Please try again in 1 second.", loginThrottledMessage(lockout.Status{RetryAfter: 300 * time.Millisecond}))
+	assert.Equal(t, "Too many failed login attempts. Please try again in 8 seconds.", loginThrottledMessage(lockout.Status{RetryAfter: 8 * time.Second}))
+	assert.Contains(t, loginThrottledMessage(lockout.Status{RetryAfter: 29*time.Minute + time.Second, Locked: true}), "try again in 30 minutes")
+}
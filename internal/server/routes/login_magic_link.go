@@ -0,0 +1,271 @@
+package routes
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	emailer "github.com/Dobefu/go-web-starter/internal/email"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	sessionKeyMagicLink = "magicLink"
+
+	msgMagicLinkSent = "If your email address has an account associated, a login link has been sent to it. The link expires in 15 minutes."
+
+	errMagicLinkInvalid = "This login link is invalid or has expired. Please request a new one."
+	errMagicLinkBrowser = "This login link was requested from another browser. Please open it in the browser that you requested it from."
+)
+
+var pathLoginMagicLink = fmt.Sprintf("%s/magic-link", paths.PathLogin)
+
+var newEmailSender = func() emailer.EmailSender {
+	return emailer.New(
+		viper.GetString("email.host"),
+		viper.GetString("email.port"),
+		viper.GetString("email.identity"),
+		viper.GetString("email.user"),
+		viper.GetString("email.password"),
+	)
+}
+
+// magicLinkFingerprint is kept in the session of the browser that requested
+// the link, so that the link cannot be used from anywhere else.
+func magicLinkFingerprint(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func LoginMagicLink(c *gin.Context) {
+	if !config.MagicLinkEnabled() {
+		NotFound(c)
+		return
+	}
+
+	v := validator.New()
+	v.SetContext(c)
+
+	data := RouteData{
+		Template:   "pages/login_magic_link",
+		HttpStatus: http.StatusOK,
+
+		Title:       "Log In With Email",
+		Description: "Receive a link to log in without a password",
+
+		FormData: FormData{
+			Values: v.GetFormData(),
+			Errors: v.GetSessionErrors(),
+		},
+		CSRFToken: middleware.GetCSRFToken(c),
+	}
+
+	RenderRouteHTML(c, data)
+
+	v.ClearSession()
+}
+
+// LoginMagicLinkPost emails a login link. The response is the same whether
+// or not the email address has an account, so that it cannot be used to find out.
+func LoginMagicLinkPost(c *gin.Context) {
+	if !config.MagicLinkEnabled() {
+		NotFound(c)
+		return
+	}
+
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	err := v.ValidateForm(c.Request)
+
+	if err != nil {
+		log.Error("Failed to parse form data", logger.Fields{"error": err.Error()})
+	}
+
+	email := v.GetFormValue(c.Request, "email")
+
+	v.ValidEmail("email", email)
+	v.Required("email", email)
+
+	if v.HasErrors() {
+		route_utils.RedirectWithError(
+			c,
+			v,
+			map[string]string{
+				"email": email,
+			},
+			"Please correct the errors below",
+			pathLoginMagicLink,
+		)
+		return
+	}
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Failed to get database connection from context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	// A fingerprint is saved even when no link is sent,
+	// so that the session does not give away whether one was.
+	token := rand.Text()
+	foundUser, err := findByEmail(db, email)
+
+	if err == nil && foundUser.GetStatus() {
+		token, err = foundUser.CreateToken(db, user.TokenPurposeMagicLink, user.TokenTTLMagicLink)
+
+		if err != nil {
+			log.Error("Failed to create the magic link token", logger.Fields{"userID": foundUser.GetID(), "error": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+
+		err = newEmailSender().SendMail(
+			viper.GetString("site.email"),
+			[]string{foundUser.GetEmail()},
+			fmt.Sprintf("Log in to %s", viper.GetString("site.name")),
+			emailer.EmailBody{
+				Template: "email/login_magic_link",
+				Data: map[string]any{
+					"Username": foundUser.GetUsername(),
+					"Token":    token,
+				},
+			},
+		)
+
+		if err != nil {
+			log.Error("Failed to send the magic link email", logger.Fields{"userID": foundUser.GetID(), "error": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+
+		log.Info("Magic link sent", logger.Fields{"userID": foundUser.GetID()})
+	}
+
+	session := getSession(c)
+	session.Set(sessionKeyMagicLink, magicLinkFingerprint(token))
+	err = session.Save()
+
+	if err != nil {
+		log.Error("Failed to save session for the magic link", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: msgMagicLinkSent})
+	c.Redirect(http.StatusSeeOther, pathLoginMagicLink)
+}
+
+// The token is only consumed when it is opened in the browser that requested it,
+// so that opening it elsewhere does not use it up.
+func LoginMagicLinkVerify(c *gin.Context) {
+	if !config.MagicLinkEnabled() {
+		NotFound(c)
+		return
+	}
+
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	token := v.GetFormValue(c.Request, "token")
+	session := getSession(c)
+	fingerprint, _ := session.Get(sessionKeyMagicLink).(string)
+
+	if token == "" {
+		loginRedirectError(c, v, errMagicLinkInvalid)
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(fingerprint), []byte(magicLinkFingerprint(token))) != 1 {
+		log.Warn("A magic link was opened in another browser", nil)
+		loginRedirectError(c, v, errMagicLinkBrowser)
+
+		return
+	}
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Failed to get database connection from context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	usr, err := user.ConsumeToken(db, user.TokenPurposeMagicLink, token)
+
+	if err != nil {
+		if !errors.Is(err, user.ErrInvalidToken) {
+			log.Error("Could not verify the magic link token", logger.Fields{"error": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+
+		loginRedirectError(c, v, errMagicLinkInvalid)
+
+		return
+	}
+
+	session.Delete(sessionKeyMagicLink)
+
+	if !usr.GetStatus() {
+		log.Warn("An inactive user tried to log in with a magic link", logger.Fields{"userID": usr.GetID()})
+		loginRedirectError(c, v, user.ErrNotActive.Error())
+
+		return
+	}
+
+	hasTwoFactor, err := userHasTwoFactor(usr, db)
+
+	if err != nil {
+		log.Error("Failed to check two-factor authentication during login", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if hasTwoFactor {
+		startTwoFactorLogin(c, session, usr)
+		return
+	}
+
+	err = usr.Login(db, session)
+
+	if err != nil {
+		log.Error("Failed to save session after login", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Login successful", logger.Fields{"userID": usr.GetID(), "method": "magic-link"})
+	auditEvent(c, audit.EventLoginSucceeded, usr.GetID(), usr.GetID(), audit.Metadata{"method": "magic-link"})
+	notifyNewDevice(c, db, usr)
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: "Successfully logged in!"})
+	c.Redirect(http.StatusSeeOther, paths.PathAccount)
+}
@@ -0,0 +1,353 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	emailer "github.com/Dobefu/go-web-starter/internal/email"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	server_utils "github.com/Dobefu/go-web-starter/internal/server/utils"
+	"github.com/Dobefu/go-web-starter/internal/templates"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var pathLoginMagicLinkVerify = pathLoginMagicLink + "/verify"
+
+type recordingEmailSender struct {
+	sent []emailer.EmailBody
+	to   [][]string
+	err  error
+}
+
+func (s *recordingEmailSender) SendMail(_ string, to []string, _ string, body emailer.EmailBody) error {
+	s.sent = append(s.sent, body)
+	s.to = append(s.to, to)
+
+	return s.err
+}
+
+func useRecordingEmailSender(t *testing.T) *recordingEmailSender {
+	sender := &recordingEmailSender{}
+	origNewEmailSender := newEmailSender
+
+	t.Cleanup(func() { newEmailSender = origNewEmailSender })
+	newEmailSender = func() emailer.EmailSender { return sender }
+
+	return sender
+}
+
+func setupMagicLinkRouter(db database.DatabaseInterface, handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	router.Use(sessions.Sessions("mysession", cookie.NewStore([]byte("secret"))))
+	router.Use(middleware.Database(db))
+	router.Use(handlers...)
+
+	router.SetFuncMap(server_utils.TemplateFuncMap())
+	_ = templates.LoadTemplates(router)
+
+	router.GET(pathLoginMagicLink, LoginMagicLink)
+	router.POST(pathLoginMagicLink, LoginMagicLinkPost)
+	router.GET(pathLoginMagicLinkVerify, LoginMagicLinkVerify)
+
+	return router
+}
+
+func setMagicLinkFingerprint(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session := sessions.Default(c)
+		session.Set(sessionKeyMagicLink, magicLinkFingerprint(token))
+
+		c.Next()
+	}
+}
+
+func magicLinkRequest(email string) *http.Request {
+	form := url.Values{"email": {email}}
+
+	req := httptest.NewRequest(http.MethodPost, pathLoginMagicLink, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req
+}
+
+func verifyMagicLinkRequest(token string, cookies []*http.Cookie) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, pathLoginMagicLinkVerify+"?token="+url.QueryEscape(token), nil)
+
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+
+	return req
+}
+
+func TestLoginMagicLinkDisabled(t *testing.T) {
+	viper.Set("site.magiclink", false)
+	defer viper.Set("site.magiclink", true)
+
+	router := setupMagicLinkRouter(nil)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, pathLoginMagicLink, nil),
+		magicLinkRequest("test@example.com"),
+		verifyMagicLinkRequest("token", nil),
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+}
+
+func TestLoginMagicLink(t *testing.T) {
+	viper.Set("site.magiclink", true)
+
+	w := httptest.NewRecorder()
+	setupMagicLinkRouter(nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, pathLoginMagicLink, nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Send login link")
+}
+
+func TestLoginMagicLinkPost(t *testing.T) {
+	viper.Set("site.magiclink", true)
+
+	activeUser := func(database.DatabaseInterface, string) (*user.User, error) { return newTestUserWithID(1), nil }
+	inactiveUser := func(database.DatabaseInterface, string) (*user.User, error) {
+		return user.New(user.UserFields{Id: 1, Email: "user@example.com"}), nil
+	}
+	unknownUser := func(database.DatabaseInterface, string) (*user.User, error) { return nil, user.ErrInvalidCredentials }
+
+	tests := []struct {
+		name        string
+		email       string
+		findByEmail func(database.DatabaseInterface, string) (*user.User, error)
+		mockSetup   func(mock sqlmock.Sqlmock)
+		sendErr     error
+		wantStatus  int
+		wantSent    bool
+	}{
+		{
+			name:        "success",
+			email:       "user@example.com",
+			findByEmail: activeUser,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM user_tokens").WithArgs(1, "magic-link").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO user_tokens").WithArgs(1, "magic-link", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			wantStatus: http.StatusSeeOther,
+			wantSent:   true,
+		},
+		{
+			name:        "unknown email",
+			email:       "unknown@example.com",
+			findByEmail: unknownUser,
+			mockSetup:   func(mock sqlmock.Sqlmock) {},
+			wantStatus:  http.StatusSeeOther,
+		},
+		{
+			name:        "inactive user",
+			email:       "user@example.com",
+			findByEmail: inactiveUser,
+			mockSetup:   func(mock sqlmock.Sqlmock) {},
+			wantStatus:  http.StatusSeeOther,
+		},
+		{
+			name:        "invalid email",
+			email:       "invalid",
+			findByEmail: activeUser,
+			mockSetup:   func(mock sqlmock.Sqlmock) {},
+			wantStatus:  http.StatusSeeOther,
+		},
+		{
+			name:        "token error",
+			email:       "user@example.com",
+			findByEmail: activeUser,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM user_tokens").WillReturnError(errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:        "email error",
+			email:       "user@example.com",
+			findByEmail: activeUser,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM user_tokens").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO user_tokens").WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			sendErr:    errors.New("smtp error"),
+			wantStatus: http.StatusInternalServerError,
+			wantSent:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restoreFinders := patchFinders(nil, tt.findByEmail)
+			defer restoreFinders()
+
+			sender := useRecordingEmailSender(t)
+			sender.err = tt.sendErr
+
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			w := httptest.NewRecorder()
+			setupMagicLinkRouter(db).ServeHTTP(w, magicLinkRequest(tt.email))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantSent, len(sender.sent) == 1)
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.wantStatus == http.StatusSeeOther {
+				assert.Equal(t, pathLoginMagicLink, w.Header().Get("Location"))
+				assert.NotEmpty(t, w.Result().Cookies())
+			}
+		})
+	}
+}
+
+func TestLoginMagicLinkFlow(t *testing.T) {
+	viper.Set("site.magiclink", true)
+
+	restoreFinders := patchFinders(nil, func(database.DatabaseInterface, string) (*user.User, error) {
+		return newTestUserWithID(1), nil
+	})
+	defer restoreFinders()
+
+	patchUserHasTwoFactor(t, false)
+	sender := useRecordingEmailSender(t)
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec("DELETE FROM user_tokens").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO user_tokens").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	router := setupMagicLinkRouter(db)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, magicLinkRequest("user@example.com"))
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, []string{"user@example.com"}, sender.to[0])
+
+	token := sender.sent[0].Data["Token"].(string)
+	cookies := w.Result().Cookies()
+
+	t.Run("another browser", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, verifyMagicLinkRequest(token, nil))
+
+		assert.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Equal(t, paths.PathLogin, w.Header().Get("Location"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("same browser", func(t *testing.T) {
+		expectConsumeToken(mock, "magic-link", true)
+		mock.ExpectQuery(`UPDATE users SET .+`).WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+		expectRememberDevice(mock, false, true)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, verifyMagicLinkRequest(token, cookies))
+
+		assert.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Equal(t, paths.PathAccount, w.Header().Get("Location"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestLoginMagicLinkVerify(t *testing.T) {
+	viper.Set("site.magiclink", true)
+
+	tests := []struct {
+		name         string
+		token        string
+		hasTwoFactor bool
+		mockSetup    func(mock sqlmock.Sqlmock)
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:         "missing token",
+			token:        "",
+			mockSetup:    func(mock sqlmock.Sqlmock) {},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathLogin,
+		},
+		{
+			name:  "used or expired token",
+			token: "token",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE user_tokens SET consumed_at`).WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathLogin,
+		},
+		{
+			name:  "database error",
+			token: "token",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE user_tokens SET consumed_at`).WillReturnError(errors.New("database error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:  "inactive user",
+			token: "token",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "magic-link", false)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathLogin,
+		},
+		{
+			name:         "two-factor authentication",
+			token:        "token",
+			hasTwoFactor: true,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "magic-link", true)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: pathLoginTwoFactor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patchUserHasTwoFactor(t, tt.hasTwoFactor)
+
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			w := httptest.NewRecorder()
+			setupMagicLinkRouter(db, setMagicLinkFingerprint("token")).ServeHTTP(w, verifyMagicLinkRequest(tt.token, nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	emailer "github.com/Dobefu/go-web-starter/internal/email"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	msgLoginRevoked = "All devices have been signed out of your account. Check your email to choose a new password."
+
+	errLoginRevokeInvalid = "This link is invalid or has expired. If you do not recognize a sign-in, please reset your password."
+)
+
+var pathLoginNotMe = fmt.Sprintf("%s/not-me", paths.PathLogin)
+
+// The first device of a user is not reported, since there is nothing to
+// compare it to. The login has already succeeded, so failures are only logged.
+func notifyNewDevice(c *gin.Context, db database.DatabaseInterface, usr *user.User) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	userAgent := c.Request.UserAgent()
+	ip := c.ClientIP()
+
+	known, err := usr.HasKnownDevices(db)
+
+	if err != nil {
+		log.Error("Could not find the known devices", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		return
+	}
+
+	isNew, err := usr.RememberDevice(db, userAgent, ip)
+
+	if err != nil {
+		log.Error("Could not remember the device", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		return
+	}
+
+	if !known || !isNew {
+		return
+	}
+
+	device := sessionstore.DeviceName(userAgent)
+
+	log.Info("Login from a new device", logger.Fields{"userID": usr.GetID(), "device": device, "ip": ip})
+	auditEvent(c, audit.EventLoginNewDevice, usr.GetID(), usr.GetID(), audit.Metadata{"device": device})
+
+	err = sendNewDeviceEmail(db, usr, userAgent, ip)
+
+	if err != nil {
+		log.Error("Failed to send the new device email", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+	}
+}
+
+func sendNewDeviceEmail(db database.DatabaseInterface, usr *user.User, userAgent string, ip string) error {
+	token, err := usr.IssueToken(db, user.TokenPurposeLoginRevoke, user.TokenTTLLoginRevoke)
+
+	if err != nil {
+		return err
+	}
+
+	return newEmailSender().SendMail(
+		viper.GetString("site.email"),
+		[]string{usr.GetEmail()},
+		fmt.Sprintf("New sign-in to your %s account", viper.GetString("site.name")),
+		emailer.EmailBody{
+			Template: "email/login_new_device",
+			Data: map[string]any{
+				"Username":   usr.GetUsername(),
+				"LoggedInAt": time.Now().UTC().Format("Jan 2, 2006 at 15:04 MST"),
+				"Device":     sessionstore.DeviceName(userAgent),
+				"IP":         ip,
+				"Token":      token,
+				"DeviceID":   user.DeviceFingerprint(userAgent, ip),
+			},
+		},
+	)
+}
+
+// LoginNotMe is linked from the new device email. It only asks for a
+// confirmation, so that link scanners in mail clients cannot sign the user out.
+func LoginNotMe(c *gin.Context) {
+	v := validator.New()
+	v.SetContext(c)
+
+	token := v.GetFormValue(c.Request, "token")
+
+	if token == "" {
+		v.SetFlash(message.Message{Type: message.MessageTypeError, Body: errLoginRevokeInvalid})
+		c.Redirect(http.StatusSeeOther, paths.PathLogin)
+
+		return
+	}
+
+	data := RouteData{
+		Template:   "pages/login_not_me",
+		HttpStatus: http.StatusOK,
+
+		Title:       "Was This Not You?",
+		Description: "Sign out all devices and choose a new password",
+
+		Data: map[string]any{
+			"Token":  token,
+			"Device": v.GetFormValue(c.Request, "device"),
+		},
+		CSRFToken: middleware.GetCSRFToken(c),
+	}
+
+	RenderRouteHTML(c, data)
+}
+
+// LoginNotMePost signs out all devices and sends a password reset email,
+// since someone else may know the password.
+// It does not require a login, since the link may be opened on any device.
+func LoginNotMePost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	token := v.GetFormValue(c.Request, "token")
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Could not get the database from the context", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	usr, err := user.ConsumeToken(db, user.TokenPurposeLoginRevoke, token)
+
+	if err != nil {
+		if !errors.Is(err, user.ErrInvalidToken) {
+			log.Error("Could not verify the token", logger.Fields{"error": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+
+		v.SetFlash(message.Message{Type: message.MessageTypeError, Body: errLoginRevokeInvalid})
+		c.Redirect(http.StatusSeeOther, paths.PathLogin)
+
+		return
+	}
+
+	err = newSessionBackend(c, db).DeleteByUser(c.Request.Context(), usr.GetID(), "")
+
+	if err != nil {
+		log.Error("Could not sign out the devices", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	// The device is reported again if it is used after the password is reset.
+	if device := v.GetFormValue(c.Request, "device"); device != "" {
+		if err = usr.ForgetDevice(db, device); err != nil {
+			log.Error("Could not forget the device", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		}
+	}
+
+	log.Warn("Login reported as not made by the user", logger.Fields{"userID": usr.GetID()})
+	auditEvent(c, audit.EventLoginRevoked, usr.GetID(), usr.GetID(), nil)
+
+	err = sendPasswordResetEmail(db, usr)
+
+	if err != nil {
+		log.Error("Failed to send the password reset email", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	auditEvent(c, audit.EventPasswordResetRequested, 0, usr.GetID(), nil)
+
+	// The session of this browser has been removed from the store as well,
+	// and must not be saved again together with the flash message.
+	session := getSession(c)
+
+	if userID, ok := session.Get("userID").(int); ok && userID == usr.GetID() {
+		session.Clear()
+	}
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: msgLoginRevoked})
+	c.Redirect(http.StatusSeeOther, paths.PathLogin)
+}
@@ -0,0 +1,370 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const newDeviceUserAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0"
+
+func expectRememberDevice(mock sqlmock.Sqlmock, known bool, isNew bool) {
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM user_known_devices`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(known))
+	mock.ExpectQuery(`INSERT INTO user_known_devices`).
+		WillReturnRows(sqlmock.NewRows([]string{"new"}).AddRow(isNew))
+}
+
+func TestNotifyNewDevice(t *testing.T) {
+	tests := []struct {
+		name         string
+		setupMock    func(mock sqlmock.Sqlmock)
+		emailErr     error
+		expectEmail  bool
+		expectEvents []audit.EventType
+	}{
+		{
+			name: "new device",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectRememberDevice(mock, true, true)
+				expectIssueToken(mock, "login-revoke")
+			},
+			expectEmail:  true,
+			expectEvents: []audit.EventType{audit.EventLoginNewDevice},
+		},
+		{
+			name: "known device",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectRememberDevice(mock, true, false)
+			},
+			expectEvents: []audit.EventType{},
+		},
+		{
+			name: "first device",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectRememberDevice(mock, false, true)
+			},
+			expectEvents: []audit.EventType{},
+		},
+		{
+			name: "known devices error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM user_known_devices`).WillReturnError(errors.New("db fail"))
+			},
+			expectEvents: []audit.EventType{},
+		},
+		{
+			name: "remember error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM user_known_devices`).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectQuery(`INSERT INTO user_known_devices`).WillReturnError(errors.New("db fail"))
+			},
+			expectEvents: []audit.EventType{},
+		},
+		{
+			name: "token error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectRememberDevice(mock, true, true)
+				mock.ExpectExec(`INSERT INTO user_tokens`).WillReturnError(errors.New("db fail"))
+			},
+			expectEvents: []audit.EventType{audit.EventLoginNewDevice},
+		},
+		{
+			name: "email error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectRememberDevice(mock, true, true)
+				expectIssueToken(mock, "login-revoke")
+			},
+			emailErr:     errors.New("smtp fail"),
+			expectEmail:  true,
+			expectEvents: []audit.EventType{audit.EventLoginNewDevice},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := useRecordingEmailSender(t)
+			sender.err = tt.emailErr
+			auditLog := useRecordingAuditLog(t)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			usr := user.New(user.UserFields{Id: 1, Username: "username", Email: "test@example.com", Status: true})
+
+			router.GET("/", func(c *gin.Context) {
+				db, _ := route_utils.GetDbFromContext(c)
+				notifyNewDevice(c, db, usr)
+				c.Status(http.StatusOK)
+			})
+
+			tt.setupMock(mock)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set("User-Agent", newDeviceUserAgent)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectEvents, auditLog.types())
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if !tt.expectEmail {
+				assert.Empty(t, sender.sent)
+				return
+			}
+
+			assert.Len(t, sender.sent, 1)
+			assert.Equal(t, []string{"test@example.com"}, sender.to[0])
+			assert.Equal(t, "email/login_new_device", sender.sent[0].Template)
+
+			data := sender.sent[0].Data
+			assert.Equal(t, "Firefox on Linux", data["Device"])
+			assert.Equal(t, user.DeviceFingerprint(newDeviceUserAgent, data["IP"].(string)), data["DeviceID"])
+			assert.NotEmpty(t, data["Token"])
+			assert.NotEmpty(t, data["LoggedInAt"])
+		})
+	}
+}
+
+func TestLoginNotMe(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		expectStatus   int
+		expectLocation string
+	}{
+		{
+			name:         "success",
+			path:         pathLoginNotMe + "?token=test-token&device=test-device",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:           "missing token",
+			path:           pathLoginNotMe,
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeSessionBackend{}
+			patchSessionBackend(t, backend)
+
+			router, _, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			router.GET(pathLoginNotMe, LoginNotMe)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectStatus, w.Code)
+			assert.Equal(t, tt.expectLocation, w.Header().Get("Location"))
+			assert.Empty(t, backend.deletedUsers)
+
+			if tt.expectStatus == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `name=token value="test-token"`)
+				assert.Contains(t, w.Body.String(), `name=device value="test-device"`)
+			}
+		})
+	}
+}
+
+func TestLoginNotMePost(t *testing.T) {
+	fingerprint := user.DeviceFingerprint(newDeviceUserAgent, "203.0.113.5")
+
+	tests := []struct {
+		name           string
+		form           url.Values
+		useDB          bool
+		signedIn       bool
+		setupMock      func(mock sqlmock.Sqlmock)
+		deleteErr      error
+		emailErr       error
+		expectStatus   int
+		expectLocation string
+		expectSignOut  bool
+		expectEmail    bool
+		expectEvents   []audit.EventType
+	}{
+		{
+			name:  "success",
+			form:  url.Values{"token": {"test-token"}, "device": {fingerprint}},
+			useDB: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "login-revoke", true)
+				mock.ExpectExec(`DELETE FROM user_known_devices`).
+					WithArgs(1, fingerprint).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectCreateToken(mock, "reset")
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+			expectSignOut:  true,
+			expectEmail:    true,
+			expectEvents:   []audit.EventType{audit.EventLoginRevoked, audit.EventPasswordResetRequested},
+		},
+		{
+			name:     "signed in on this browser",
+			form:     url.Values{"token": {"test-token"}},
+			useDB:    true,
+			signedIn: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "login-revoke", true)
+				expectCreateToken(mock, "reset")
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+			expectSignOut:  true,
+			expectEmail:    true,
+			expectEvents:   []audit.EventType{audit.EventLoginRevoked, audit.EventPasswordResetRequested},
+		},
+		{
+			name:  "forget device error",
+			form:  url.Values{"token": {"test-token"}, "device": {fingerprint}},
+			useDB: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "login-revoke", true)
+				mock.ExpectExec(`DELETE FROM user_known_devices`).WillReturnError(errors.New("db fail"))
+				expectCreateToken(mock, "reset")
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+			expectSignOut:  true,
+			expectEmail:    true,
+			expectEvents:   []audit.EventType{audit.EventLoginRevoked, audit.EventPasswordResetRequested},
+		},
+		{
+			name:  "invalid or expired token",
+			form:  url.Values{"token": {"test-token"}},
+			useDB: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE user_tokens SET consumed_at`).WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+			expectEvents:   []audit.EventType{},
+		},
+		{
+			name:  "token error",
+			form:  url.Values{"token": {"test-token"}},
+			useDB: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE user_tokens SET consumed_at`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+			expectEvents: []audit.EventType{},
+		},
+		{
+			name:  "sign out error",
+			form:  url.Values{"token": {"test-token"}},
+			useDB: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "login-revoke", true)
+			},
+			deleteErr:     errors.New("redis fail"),
+			expectStatus:  http.StatusInternalServerError,
+			expectSignOut: true,
+			expectEvents:  []audit.EventType{},
+		},
+		{
+			name:  "email error",
+			form:  url.Values{"token": {"test-token"}},
+			useDB: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "login-revoke", true)
+				expectCreateToken(mock, "reset")
+			},
+			emailErr:      errors.New("smtp fail"),
+			expectStatus:  http.StatusInternalServerError,
+			expectSignOut: true,
+			expectEmail:   true,
+			expectEvents:  []audit.EventType{audit.EventLoginRevoked},
+		},
+		{
+			name:         "no database",
+			form:         url.Values{"token": {"test-token"}},
+			setupMock:    func(mock sqlmock.Sqlmock) {},
+			expectStatus: http.StatusInternalServerError,
+			expectEvents: []audit.EventType{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeSessionBackend{deleteErr: tt.deleteErr}
+			patchSessionBackend(t, backend)
+
+			sender := useRecordingEmailSender(t)
+			sender.err = tt.emailErr
+			auditLog := useRecordingAuditLog(t)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, tt.useDB)
+			defer func() { _ = mockDB.Close() }()
+
+			handlers := []gin.HandlerFunc{}
+
+			if tt.signedIn {
+				handlers = append(handlers, setSessionUserID(1))
+			}
+
+			router.POST(pathLoginNotMe, append(handlers, LoginNotMePost)...)
+			router.GET("/session", func(c *gin.Context) {
+				c.String(http.StatusOK, "%v", sessions.Default(c).Get("userID"))
+			})
+
+			tt.setupMock(mock)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", pathLoginNotMe, strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectStatus, w.Code)
+			assert.Equal(t, tt.expectLocation, w.Header().Get("Location"))
+			assert.Equal(t, tt.expectEvents, auditLog.types())
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.expectSignOut {
+				assert.Equal(t, []int{1}, backend.deletedUsers)
+				assert.Equal(t, "", backend.exceptID)
+			} else {
+				assert.Empty(t, backend.deletedUsers)
+			}
+
+			if tt.expectEmail {
+				assert.Len(t, sender.sent, 1)
+				assert.Equal(t, "email/forgot_password", sender.sent[0].Template)
+			} else {
+				assert.Empty(t, sender.sent)
+			}
+
+			if tt.signedIn {
+				w2 := httptest.NewRecorder()
+				req2, _ := http.NewRequest("GET", "/session", nil)
+
+				for _, cookie := range w.Result().Cookies() {
+					req2.AddCookie(cookie)
+				}
+
+				router.ServeHTTP(w2, req2)
+				assert.Equal(t, "<nil>", w2.Body.String())
+			}
+		})
+	}
+}
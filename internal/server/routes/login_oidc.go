@@ -0,0 +1,416 @@
+package routes
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/oidc"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+)
+
+const (
+	sessionKeyOIDCLogin = "oidcLogin"
+
+	oidcLoginTimeout     = 10 * time.Minute
+	oidcUsernameMinLen   = 3
+	oidcUsernameMaxLen   = 64
+	oidcUsernameAttempts = 5
+
+	errOIDCLoginFailed     = "Could not log in with %s. Please try again."
+	errOIDCLoginExpired    = "Your login with %s has expired. Please try again."
+	errOIDCEmailUnverified = "%s has not verified your email address, so it cannot be used to log in."
+	errOIDCAccountDeleted  = "The account with this email address has been deleted. Use the link in the email about the deletion to restore it."
+)
+
+var (
+	errOIDCLoginMissing       = errors.New("no OIDC login is in progress")
+	errOIDCRegistrationClosed = errors.New("registration is by invitation only")
+)
+
+var findByIdentity = user.FindByIdentity
+
+// getOIDCRegistry loads the identity providers from the configuration once,
+// so that the discovery documents of the issuers are cached between requests.
+var getOIDCRegistry = sync.OnceValues(func() (*oidc.Registry, error) {
+	var providers map[string]config.OIDCProvider
+	err := viper.UnmarshalKey("oidc.providers", &providers)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the OIDC providers: %w", err)
+	}
+
+	return oidc.NewRegistry(viper.GetString("site.host"), providers)
+})
+
+type oidcLogin struct {
+	Provider  string `json:"provider"`
+	State     string `json:"state"`
+	Nonce     string `json:"nonce"`
+	Verifier  string `json:"verifier"`
+	StartedAt int64  `json:"startedAt"`
+}
+
+func oidcProviders(log *logger.Logger) []*oidc.Provider {
+	registry, err := getOIDCRegistry()
+
+	if err != nil {
+		log.Error("Could not load the OIDC providers", logger.Fields{"error": err.Error()})
+		return nil
+	}
+
+	return registry.List()
+}
+
+func getOIDCProvider(c *gin.Context, log *logger.Logger) (*oidc.Provider, bool) {
+	registry, err := getOIDCRegistry()
+
+	if err != nil {
+		log.Error("Could not load the OIDC providers", logger.Fields{"error": err.Error()})
+		NotFound(c)
+
+		return nil, false
+	}
+
+	provider, ok := registry.Get(c.Param("provider"))
+
+	if !ok {
+		NotFound(c)
+		return nil, false
+	}
+
+	return provider, true
+}
+
+func saveOIDCLogin(session sessions.Session, login oidcLogin) error {
+	encoded, err := json.Marshal(login)
+
+	if err != nil {
+		return err
+	}
+
+	session.Set(sessionKeyOIDCLogin, string(encoded))
+
+	return session.Save()
+}
+
+// takeOIDCLogin returns the login that is in progress, and removes it
+// from the session so that the same state cannot be used twice.
+func takeOIDCLogin(session sessions.Session) (*oidcLogin, error) {
+	encoded, ok := session.Get(sessionKeyOIDCLogin).(string)
+
+	session.Delete(sessionKeyOIDCLogin)
+	_ = session.Save()
+
+	if !ok {
+		return nil, errOIDCLoginMissing
+	}
+
+	login := &oidcLogin{}
+	err := json.Unmarshal([]byte(encoded), login)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Since(time.Unix(login.StartedAt, 0)) > oidcLoginTimeout {
+		return nil, errOIDCLoginMissing
+	}
+
+	return login, nil
+}
+
+func LoginOIDC(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	provider, ok := getOIDCProvider(c, log)
+
+	if !ok {
+		return
+	}
+
+	login := oidcLogin{
+		Provider:  provider.ID,
+		State:     rand.Text(),
+		Nonce:     rand.Text(),
+		Verifier:  oauth2.GenerateVerifier(),
+		StartedAt: time.Now().Unix(),
+	}
+
+	authURL, err := provider.AuthCodeURL(c, login.State, login.Nonce, login.Verifier)
+
+	if err != nil {
+		log.Error("Could not start the OIDC login", logger.Fields{"provider": provider.ID, "error": err.Error()})
+		loginRedirectError(c, v, fmt.Sprintf(errOIDCLoginFailed, provider.Name))
+
+		return
+	}
+
+	err = saveOIDCLogin(getSession(c), login)
+
+	if err != nil {
+		log.Error("Failed to save session for the OIDC login", logger.Fields{"provider": provider.ID, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	c.Redirect(http.StatusSeeOther, authURL)
+}
+
+func LoginOIDCCallback(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	provider, ok := getOIDCProvider(c, log)
+
+	if !ok {
+		return
+	}
+
+	session := getSession(c)
+	login, err := takeOIDCLogin(session)
+
+	if err != nil ||
+		login.Provider != provider.ID ||
+		subtle.ConstantTimeCompare([]byte(login.State), []byte(c.Query("state"))) != 1 {
+		loginRedirectError(c, v, fmt.Sprintf(errOIDCLoginExpired, provider.Name))
+		return
+	}
+
+	if errCode := c.Query("error"); errCode != "" {
+		log.Warn("The identity provider did not complete the login", logger.Fields{"provider": provider.ID, "error": errCode})
+		loginRedirectError(c, v, fmt.Sprintf(errOIDCLoginFailed, provider.Name))
+
+		return
+	}
+
+	identity, err := provider.Exchange(c, c.Query("code"), login.Verifier, login.Nonce)
+
+	if err != nil {
+		log.Warn("Login failed: invalid OIDC response", logger.Fields{"provider": provider.ID, "error": err.Error()})
+		loginRedirectError(c, v, fmt.Sprintf(errOIDCLoginFailed, provider.Name))
+
+		return
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		log.Warn("Login failed: the OIDC email address is not verified", logger.Fields{"provider": provider.ID, "subject": identity.Subject})
+		loginRedirectError(c, v, fmt.Sprintf(errOIDCEmailUnverified, provider.Name))
+
+		return
+	}
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Failed to get database connection from context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	usr, err := resolveOIDCUser(db, provider.ID, identity)
+
+	if errors.Is(err, errOIDCRegistrationClosed) {
+		log.Warn("Login failed: registration is by invitation only", logger.Fields{"provider": provider.ID, "subject": identity.Subject})
+		loginRedirectError(c, v, errRegistrationInviteOnly)
+
+		return
+	}
+
+	if errors.Is(err, user.ErrNotActive) {
+		log.Warn("Login failed: the OIDC email address belongs to an inactive account", logger.Fields{"provider": provider.ID, "subject": identity.Subject})
+		loginRedirectError(c, v, user.ErrNotActive.Error())
+
+		return
+	}
+
+	if errors.Is(err, user.ErrUserExists) {
+		log.Warn("Login failed: the email address belongs to a deleted account", logger.Fields{"provider": provider.ID, "subject": identity.Subject})
+		loginRedirectError(c, v, errOIDCAccountDeleted)
+
+		return
+	}
+
+	if err != nil {
+		log.Error("Could not find or create the user for the OIDC login", logger.Fields{"provider": provider.ID, "subject": identity.Subject, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if !usr.GetStatus() {
+		log.Warn("An inactive user tried to log in with OIDC", logger.Fields{"userID": usr.GetID(), "provider": provider.ID})
+		loginRedirectError(c, v, user.ErrNotActive.Error())
+
+		return
+	}
+
+	hasTwoFactor, err := userHasTwoFactor(usr, db)
+
+	if err != nil {
+		log.Error("Failed to check two-factor authentication during login", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if hasTwoFactor {
+		startTwoFactorLogin(c, session, usr)
+		return
+	}
+
+	err = usr.Login(db, session)
+
+	if err != nil {
+		log.Error("Failed to save session after login", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("Login successful", logger.Fields{"userID": usr.GetID(), "provider": provider.ID})
+	auditEvent(c, audit.EventLoginSucceeded, usr.GetID(), usr.GetID(), audit.Metadata{"method": "oidc", "provider": provider.ID})
+	notifyNewDevice(c, db, usr)
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: "Successfully logged in!"})
+	c.Redirect(http.StatusSeeOther, paths.PathAccount)
+}
+
+func resolveOIDCUser(db database.DatabaseInterface, provider string, identity *oidc.Identity) (*user.User, error) {
+	usr, err := findByIdentity(db, provider, identity.Subject)
+
+	if err == nil {
+		return usr, user.UpdateIdentity(db, provider, identity.Subject, identity.Email)
+	}
+
+	if !errors.Is(err, user.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	usr, err = findByEmail(db, identity.Email)
+
+	if err != nil {
+		if !errors.Is(err, user.ErrInvalidCredentials) {
+			return nil, err
+		}
+
+		if config.InviteOnly() {
+			return nil, errOIDCRegistrationClosed
+		}
+
+		usr, err = createOIDCUser(db, identity)
+
+		if err != nil {
+			return nil, err
+		}
+	} else if !usr.GetStatus() {
+		// Anyone could have registered the address without verifying it,
+		// so the identity is only linked to accounts that are active.
+		return nil, user.ErrNotActive
+	}
+
+	err = usr.LinkIdentity(db, provider, identity.Subject, identity.Email)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return usr, nil
+}
+
+func createOIDCUser(db database.DatabaseInterface, identity *oidc.Identity) (*user.User, error) {
+	username, err := availableOIDCUsername(db, identity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := user.HashPassword(rand.Text())
+
+	if err != nil {
+		return nil, err
+	}
+
+	usr := user.NewUser(username, identity.Email, hashedPassword, true)
+	err = usr.Save(db)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to save new user: %w", err)
+	}
+
+	return usr, nil
+}
+
+func availableOIDCUsername(db database.DatabaseInterface, identity *oidc.Identity) (string, error) {
+	base := identity.PreferredUsername
+
+	if base == "" {
+		base = identity.Name
+	}
+
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	base = truncateUsername(strings.TrimSpace(base), oidcUsernameMaxLen)
+	username := base
+
+	if len([]rune(username)) < oidcUsernameMinLen {
+		username = base + oidcUsernameSuffix()
+	}
+
+	for range oidcUsernameAttempts {
+		_, err := findByUsername(db, username)
+
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			return username, nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		suffix := oidcUsernameSuffix()
+		username = truncateUsername(base, oidcUsernameMaxLen-len(suffix)) + suffix
+	}
+
+	return "", fmt.Errorf("could not find an available username for %q", base)
+}
+
+func oidcUsernameSuffix() string {
+	return "-" + strings.ToLower(rand.Text()[:6])
+}
+
+func truncateUsername(username string, maxLen int) string {
+	runes := []rune(username)
+
+	if len(runes) > maxLen {
+		return string(runes[:maxLen])
+	}
+
+	return username
+}
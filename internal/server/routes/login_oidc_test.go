@@ -0,0 +1,483 @@
+package routes
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/oidc"
+	"github.com/Dobefu/go-web-starter/internal/oidc/oidctest"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	server_utils "github.com/Dobefu/go-web-starter/internal/server/utils"
+	"github.com/Dobefu/go-web-starter/internal/templates"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+const pathLoginOIDC = paths.PathLogin + "/company"
+
+func useOIDCIssuer(t *testing.T, issuerURL string) {
+	registry, err := oidc.NewRegistry("http://localhost:4000", map[string]config.OIDCProvider{
+		"company": {
+			Name:         "Company SSO",
+			Issuer:       issuerURL,
+			ClientID:     oidctest.ClientID,
+			ClientSecret: oidctest.ClientSecret,
+		},
+	})
+	assert.NoError(t, err)
+
+	origGetOIDCRegistry := getOIDCRegistry
+	t.Cleanup(func() { getOIDCRegistry = origGetOIDCRegistry })
+
+	getOIDCRegistry = func() (*oidc.Registry, error) { return registry, nil }
+}
+
+func setupOIDCRouter(db database.DatabaseInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	router.Use(sessions.Sessions("mysession", cookie.NewStore([]byte("secret"))))
+
+	if db != nil {
+		router.Use(middleware.Database(db))
+	}
+
+	router.SetFuncMap(server_utils.TemplateFuncMap())
+	_ = templates.LoadTemplates(router)
+
+	router.GET(paths.PathLogin+"/:provider", LoginOIDC)
+	router.GET(paths.PathLogin+"/:provider/callback", LoginOIDCCallback)
+
+	return router
+}
+
+func startOIDCLogin(t *testing.T, router *gin.Engine, issuer *oidctest.Server) *http.Request {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, pathLoginOIDC, nil))
+
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Location"), issuer.URL+"/authorize"))
+
+	callback, err := issuer.Authorize(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, pathLoginOIDC+"/callback", callback.Path)
+
+	req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+
+	for _, c := range w.Result().Cookies() {
+		req.AddCookie(c)
+	}
+
+	return req
+}
+
+func patchOIDCUserLookups(t *testing.T, identityFn func(database.DatabaseInterface, string, string) (*user.User, error), hasTwoFactor bool) {
+	origFindByIdentity := findByIdentity
+
+	t.Cleanup(func() { findByIdentity = origFindByIdentity })
+	findByIdentity = identityFn
+
+	patchUserHasTwoFactor(t, hasTwoFactor)
+}
+
+func expectOIDCLogin(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("UPDATE users").WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+	expectRememberDevice(mock, false, true)
+}
+
+func TestLoginOIDCCallback(t *testing.T) {
+	notLinked := func(database.DatabaseInterface, string, string) (*user.User, error) {
+		return nil, user.ErrIdentityNotFound
+	}
+	linked := func(database.DatabaseInterface, string, string) (*user.User, error) {
+		return newTestUserWithID(1), nil
+	}
+	notFound := func(database.DatabaseInterface, string) (*user.User, error) {
+		return nil, user.ErrInvalidCredentials
+	}
+	found := func(database.DatabaseInterface, string) (*user.User, error) {
+		return newTestUserWithID(1), nil
+	}
+	inactive := func(database.DatabaseInterface, string) (*user.User, error) {
+		return user.New(user.UserFields{Id: 1, Email: "sso@example.com"}), nil
+	}
+
+	verified := oidctest.Claims{
+		Subject:           "subject",
+		Email:             "sso@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "sso-user",
+	}
+
+	tests := []struct {
+		name           string
+		claims         oidctest.Claims
+		findByIdentity func(database.DatabaseInterface, string, string) (*user.User, error)
+		findByEmail    func(database.DatabaseInterface, string) (*user.User, error)
+		hasTwoFactor   bool
+		inviteOnly     bool
+		mockSetup      func(mock sqlmock.Sqlmock)
+		expectStatus   int
+		expectLocation string
+	}{
+		{
+			name:           "linked identity",
+			claims:         verified,
+			findByIdentity: linked,
+			findByEmail:    notFound,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE user_identities").
+					WithArgs("sso@example.com", sqlmock.AnyArg(), "company", "subject").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectOIDCLogin(mock)
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathAccount,
+		},
+		{
+			name:           "links an existing user by email",
+			claims:         verified,
+			findByIdentity: notLinked,
+			findByEmail:    found,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO user_identities").
+					WithArgs(1, "company", "subject", "sso@example.com", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectOIDCLogin(mock)
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathAccount,
+		},
+		{
+			name:           "creates a new user",
+			claims:         verified,
+			findByIdentity: notLinked,
+			findByEmail:    notFound,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				now := time.Now()
+
+				mock.ExpectQuery("INSERT INTO users").
+					WithArgs("sso-user", "sso@example.com", sqlmock.AnyArg(), true, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "last_login"}).AddRow(2, now, now, now))
+				mock.ExpectExec("INSERT INTO user_identities").
+					WithArgs(2, "company", "subject", "sso@example.com", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectOIDCLogin(mock)
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathAccount,
+		},
+		{
+			name:           "does not create a user when registration is by invitation only",
+			claims:         verified,
+			findByIdentity: notLinked,
+			findByEmail:    notFound,
+			inviteOnly:     true,
+			mockSetup:      func(mock sqlmock.Sqlmock) {},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+		},
+		{
+			name:           "links an existing user when registration is by invitation only",
+			claims:         verified,
+			findByIdentity: notLinked,
+			findByEmail:    found,
+			inviteOnly:     true,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO user_identities").WillReturnResult(sqlmock.NewResult(1, 1))
+				expectOIDCLogin(mock)
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathAccount,
+		},
+		{
+			name:           "unverified email",
+			claims:         oidctest.Claims{Subject: "subject", Email: "sso@example.com"},
+			findByIdentity: linked,
+			findByEmail:    found,
+			mockSetup:      func(mock sqlmock.Sqlmock) {},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+		},
+		{
+			name:           "does not link an inactive user",
+			claims:         verified,
+			findByIdentity: notLinked,
+			findByEmail:    inactive,
+			mockSetup:      func(mock sqlmock.Sqlmock) {},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+		},
+		{
+			name:           "email address of a deleted account",
+			claims:         verified,
+			findByIdentity: notLinked,
+			findByEmail:    notFound,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("INSERT INTO users").WillReturnError(&pq.Error{Code: "23505"})
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+		},
+		{
+			name:           "two-factor authentication",
+			claims:         verified,
+			findByIdentity: linked,
+			findByEmail:    notFound,
+			hasTwoFactor:   true,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE user_identities").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathLoginTwoFactor,
+		},
+		{
+			name:   "database error",
+			claims: verified,
+			findByIdentity: func(database.DatabaseInterface, string, string) (*user.User, error) {
+				return nil, errors.New("database error")
+			},
+			findByEmail:  notFound,
+			mockSetup:    func(mock sqlmock.Sqlmock) {},
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := oidctest.NewServer(t)
+			issuer.SetClaims(tt.claims)
+			useOIDCIssuer(t, issuer.URL)
+			setInviteOnly(t, tt.inviteOnly)
+
+			patchOIDCUserLookups(t, tt.findByIdentity, tt.hasTwoFactor)
+			restoreFinders := patchFinders(
+				func(database.DatabaseInterface, string) (*user.User, error) { return nil, user.ErrInvalidCredentials },
+				tt.findByEmail,
+			)
+			defer restoreFinders()
+
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			router := setupOIDCRouter(db)
+			req := startOIDCLogin(t, router, issuer)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectStatus, w.Code)
+			assert.Equal(t, tt.expectLocation, w.Header().Get("Location"))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLoginOIDCCallbackInvalidState(t *testing.T) {
+	issuer := oidctest.NewServer(t)
+	useOIDCIssuer(t, issuer.URL)
+
+	router := setupOIDCRouter(nil)
+
+	tests := []struct {
+		name    string
+		request func(req *http.Request) *http.Request
+	}{
+		{
+			name: "tampered state",
+			request: func(req *http.Request) *http.Request {
+				query := req.URL.Query()
+				query.Set("state", "tampered")
+				req.URL.RawQuery = query.Encode()
+
+				return req
+			},
+		},
+		{
+			name: "no login in progress",
+			request: func(req *http.Request) *http.Request {
+				return httptest.NewRequest(http.MethodGet, req.URL.RequestURI(), nil)
+			},
+		},
+		{
+			name: "denied by the identity provider",
+			request: func(req *http.Request) *http.Request {
+				query := req.URL.Query()
+				query.Del("code")
+				query.Set("error", "access_denied")
+				req.URL.RawQuery = query.Encode()
+
+				return req
+			},
+		},
+		{
+			name: "invalid code",
+			request: func(req *http.Request) *http.Request {
+				query := req.URL.Query()
+				query.Set("code", "invalid")
+				req.URL.RawQuery = query.Encode()
+
+				return req
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.request(startOIDCLogin(t, router, issuer))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusSeeOther, w.Code)
+			assert.Equal(t, paths.PathLogin, w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestLoginOIDCCallbackStateIsSingleUse(t *testing.T) {
+	issuer := oidctest.NewServer(t)
+	useOIDCIssuer(t, issuer.URL)
+	patchOIDCUserLookups(t, func(database.DatabaseInterface, string, string) (*user.User, error) {
+		return newTestUserWithID(1), nil
+	}, false)
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec("UPDATE user_identities").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectOIDCLogin(mock)
+
+	router := setupOIDCRouter(db)
+	req := startOIDCLogin(t, router, issuer)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, paths.PathAccount, w.Header().Get("Location"))
+
+	replay := httptest.NewRequest(http.MethodGet, req.URL.RequestURI(), nil)
+
+	for _, c := range w.Result().Cookies() {
+		replay.AddCookie(c)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, replay)
+	assert.Equal(t, paths.PathLogin, w.Header().Get("Location"))
+}
+
+func TestLoginOIDC(t *testing.T) {
+	t.Run("unknown provider", func(t *testing.T) {
+		useOIDCIssuer(t, "http://127.0.0.1:0")
+
+		w := httptest.NewRecorder()
+		setupOIDCRouter(nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, paths.PathLogin+"/other", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("unknown provider callback", func(t *testing.T) {
+		useOIDCIssuer(t, "http://127.0.0.1:0")
+
+		w := httptest.NewRecorder()
+		setupOIDCRouter(nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, paths.PathLogin+"/other/callback", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("issuer unreachable", func(t *testing.T) {
+		issuer := oidctest.NewServer(t)
+		useOIDCIssuer(t, issuer.URL+"/missing")
+
+		w := httptest.NewRecorder()
+		setupOIDCRouter(nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, pathLoginOIDC, nil))
+
+		assert.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Equal(t, paths.PathLogin, w.Header().Get("Location"))
+	})
+
+	t.Run("registry error", func(t *testing.T) {
+		origGetOIDCRegistry := getOIDCRegistry
+		defer func() { getOIDCRegistry = origGetOIDCRegistry }()
+
+		getOIDCRegistry = func() (*oidc.Registry, error) { return nil, oidc.ErrInvalidSiteHost }
+
+		w := httptest.NewRecorder()
+		setupOIDCRouter(nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, pathLoginOIDC, nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Nil(t, oidcProviders(logger.New(logger.ErrorLevel, io.Discard)))
+	})
+}
+
+func TestAvailableOIDCUsername(t *testing.T) {
+	taken := map[string]bool{"taken": true}
+
+	restoreFinders := patchFinders(func(_ database.DatabaseInterface, username string) (*user.User, error) {
+		if username == "error" {
+			return nil, errors.New("database error")
+		}
+
+		if taken[username] {
+			return &user.User{}, nil
+		}
+
+		return nil, user.ErrInvalidCredentials
+	}, nil)
+	defer restoreFinders()
+
+	tests := []struct {
+		name       string
+		identity   oidc.Identity
+		wantPrefix string
+		wantExact  bool
+		wantErr    bool
+	}{
+		{"preferred username", oidc.Identity{PreferredUsername: "sso-user", Name: "SSO User", Email: "sso@example.com"}, "sso-user", true, false},
+		{"name", oidc.Identity{Name: "SSO User", Email: "sso@example.com"}, "SSO User", true, false},
+		{"email", oidc.Identity{Email: "sso@example.com"}, "sso", true, false},
+		{"taken", oidc.Identity{PreferredUsername: "taken"}, "taken-", false, false},
+		{"too short", oidc.Identity{PreferredUsername: "ab"}, "ab-", false, false},
+		{"too long", oidc.Identity{PreferredUsername: strings.Repeat("a", 80)}, strings.Repeat("a", 64), true, false},
+		{"database error", oidc.Identity{PreferredUsername: "error"}, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username, err := availableOIDCUsername(nil, &tt.identity)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			if tt.wantExact {
+				assert.Equal(t, tt.wantPrefix, username)
+				return
+			}
+
+			assert.True(t, strings.HasPrefix(username, tt.wantPrefix))
+			assert.Len(t, username, len(tt.wantPrefix)+6)
+		})
+	}
+}
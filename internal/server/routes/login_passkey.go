@@ -0,0 +1,156 @@
+package routes
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	errPasskeyLoginFailed  = "The passkey could not be verified. Please try again."
+	errPasskeyLoginExpired = "Your passkey login has expired. Please try again."
+)
+
+var findByPasskeyUserHandle = user.FindByPasskeyUserHandle
+
+func LoginPasskeyOptions(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+
+	wa, err := newWebAuthn()
+
+	if err != nil {
+		log.Error("Could not set up WebAuthn", logger.Fields{"error": err.Error()})
+		passkeyError(c, http.StatusInternalServerError, errPasskeyLoginFailed)
+
+		return
+	}
+
+	assertion, sessionData, err := wa.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+
+	if err != nil {
+		log.Error("Could not start the passkey login", logger.Fields{"error": err.Error()})
+		passkeyError(c, http.StatusInternalServerError, errPasskeyLoginFailed)
+
+		return
+	}
+
+	err = savePasskeySession(getSession(c), sessionKeyPasskeyLogin, sessionData)
+
+	if err != nil {
+		log.Error("Failed to save session for the passkey login", logger.Fields{"error": err.Error()})
+		passkeyError(c, http.StatusInternalServerError, errPasskeyLoginFailed)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, assertion)
+}
+
+func LoginPasskeyPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	session := getSession(c)
+	sessionData, err := takePasskeySession(session, sessionKeyPasskeyLogin)
+
+	if err != nil {
+		passkeyError(c, http.StatusBadRequest, errPasskeyLoginExpired)
+		return
+	}
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Failed to get database connection from context", nil)
+		passkeyError(c, http.StatusInternalServerError, errPasskeyLoginFailed)
+
+		return
+	}
+
+	wa, err := newWebAuthn()
+
+	if err != nil {
+		log.Error("Could not set up WebAuthn", logger.Fields{"error": err.Error()})
+		passkeyError(c, http.StatusInternalServerError, errPasskeyLoginFailed)
+
+		return
+	}
+
+	webAuthnUser, credential, err := wa.FinishPasskeyLogin(
+		func(rawID, userHandle []byte) (webauthn.User, error) {
+			return findByPasskeyUserHandle(db, userHandle)
+		},
+		*sessionData,
+		c.Request,
+	)
+
+	if err != nil {
+		log.Warn("Login failed: invalid passkey", logger.Fields{"error": err.Error()})
+		auditEvent(c, audit.EventLoginFailed, 0, 0, audit.Metadata{"method": "passkey"})
+		passkeyError(c, http.StatusUnauthorized, errPasskeyLoginFailed)
+
+		return
+	}
+
+	usr := webAuthnUser.(*user.WebAuthnUser)
+
+	if !usr.GetStatus() {
+		log.Warn("An inactive user tried to log in with a passkey", logger.Fields{"userID": usr.GetID()})
+		passkeyError(c, http.StatusForbidden, user.ErrNotActive.Error())
+
+		return
+	}
+
+	err = usr.UpdatePasskeyUsage(db, credential)
+
+	if err != nil {
+		log.Error("Could not update the passkey after login", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		passkeyError(c, http.StatusInternalServerError, errPasskeyLoginFailed)
+
+		return
+	}
+
+	if credential.Authenticator.CloneWarning {
+		log.Warn("The sign counter of a passkey went backwards", logger.Fields{"userID": usr.GetID()})
+	}
+
+	clearTwoFactorLogin(session)
+	err = usr.Login(db, session)
+
+	if err != nil {
+		log.Error("Failed to save session after passkey login", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		passkeyError(c, http.StatusInternalServerError, errPasskeyLoginFailed)
+
+		return
+	}
+
+	log.Info("Login successful", map[string]any{
+		"email":   usr.GetEmail(),
+		"userID":  usr.GetID(),
+		"passkey": true,
+	})
+
+	auditEvent(c, audit.EventLoginSucceeded, usr.GetID(), usr.GetID(), audit.Metadata{"method": "passkey"})
+	notifyNewDevice(c, db, usr.User)
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: "Successfully logged in!"})
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "ok",
+		"redirect": paths.PathAccount,
+	})
+}
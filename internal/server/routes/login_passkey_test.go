@@ -0,0 +1,195 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/descope/virtualwebauthn"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	pathLoginPasskey        = paths.PathLogin + "/passkey"
+	pathLoginPasskeyOptions = paths.PathLogin + "/passkey/options"
+)
+
+func setupPasskeyLoginRouter(t *testing.T) (*gin.Engine, sqlmock.Sqlmock) {
+	router, mock, mockDB := setupTestRouterWithMocks(t, true)
+	t.Cleanup(func() { _ = mockDB.Close() })
+
+	router.POST(pathLoginPasskeyOptions, LoginPasskeyOptions)
+	router.POST(pathLoginPasskey, LoginPasskeyPost)
+
+	return router, mock
+}
+
+func startPasskeyLogin(
+	t *testing.T,
+	router *gin.Engine,
+	rp virtualwebauthn.RelyingParty,
+	passkey testPasskey,
+) (string, []*http.Cookie) {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, passkeyRequest(pathLoginPasskeyOptions, "", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	options, err := virtualwebauthn.ParseAssertionOptions(w.Body.String())
+	assert.NoError(t, err)
+	assert.Empty(t, options.AllowCredentials)
+
+	passkey.authenticator.Options.UserHandle = testPasskeyUserHandle
+	response := virtualwebauthn.CreateAssertionResponse(rp, passkey.authenticator, passkey.credential, *options)
+
+	return response, w.Result().Cookies()
+}
+
+func expectPasskeyUser(mock sqlmock.Sqlmock, status bool, passkey testPasskey) {
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE passkey_user_handle = \$1`).
+		WithArgs(testPasskeyUserHandle).
+		WillReturnRows(
+			sqlmock.NewRows(
+				[]string{"id", "username", "email", "password", "status", "created_at", "updated_at", "last_login", "avatar"},
+			).
+				AddRow(1, "username", "test@example.com", "", status, now, now, now, ""),
+		)
+
+	expectPasskeys(mock, passkey.row)
+}
+
+func TestLoginPasskey(t *testing.T) {
+	passkey := registerTestPasskey(t)
+	router, mock := setupPasskeyLoginRouter(t)
+
+	response, cookies := startPasskeyLogin(t, router, passkey.rp, passkey)
+
+	expectPasskeyUser(mock, true, passkey)
+	mock.ExpectExec(`UPDATE user_passkeys SET sign_count`).
+		WithArgs(sqlmock.AnyArg(), false, sqlmock.AnyArg(), sqlmock.AnyArg(), 1, passkey.credential.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE users SET .+`).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+	expectRememberDevice(mock, false, true)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, passkeyRequest(pathLoginPasskey, response, cookies))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok","redirect":"/account"}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// The challenge is removed from the session once it has been used.
+	cookies = w.Result().Cookies()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, passkeyRequest(pathLoginPasskey, response, cookies))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLoginPasskeyErrors(t *testing.T) {
+	passkey := registerTestPasskey(t)
+
+	t.Run("no ceremony in progress", func(t *testing.T) {
+		router, _ := setupPasskeyLoginRouter(t)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, passkeyRequest(pathLoginPasskey, "{}", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), errPasskeyLoginExpired)
+	})
+
+	t.Run("inactive user", func(t *testing.T) {
+		router, mock := setupPasskeyLoginRouter(t)
+		response, cookies := startPasskeyLogin(t, router, passkey.rp, passkey)
+
+		expectPasskeyUser(mock, false, passkey)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, passkeyRequest(pathLoginPasskey, response, cookies))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), user.ErrNotActive.Error())
+	})
+
+	t.Run("unknown passkey", func(t *testing.T) {
+		router, mock := setupPasskeyLoginRouter(t)
+
+		other := passkey
+		other.credential = virtualwebauthn.NewCredential(virtualwebauthn.KeyTypeEC2)
+
+		response, cookies := startPasskeyLogin(t, router, passkey.rp, other)
+
+		expectPasskeyUser(mock, true, passkey)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, passkeyRequest(pathLoginPasskey, response, cookies))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), errPasskeyLoginFailed)
+	})
+
+	t.Run("wrong origin", func(t *testing.T) {
+		router, mock := setupPasskeyLoginRouter(t)
+
+		evil := passkey.rp
+		evil.Origin = "http://evil.example"
+
+		response, cookies := startPasskeyLogin(t, router, evil, passkey)
+
+		expectPasskeyUser(mock, true, passkey)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, passkeyRequest(pathLoginPasskey, response, cookies))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("update error", func(t *testing.T) {
+		router, mock := setupPasskeyLoginRouter(t)
+		response, cookies := startPasskeyLogin(t, router, passkey.rp, passkey)
+
+		expectPasskeyUser(mock, true, passkey)
+		mock.ExpectExec(`UPDATE user_passkeys SET sign_count`).WillReturnError(errors.New("db fail"))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, passkeyRequest(pathLoginPasskey, response, cookies))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("login error", func(t *testing.T) {
+		router, mock := setupPasskeyLoginRouter(t)
+		response, cookies := startPasskeyLogin(t, router, passkey.rp, passkey)
+
+		expectPasskeyUser(mock, true, passkey)
+		mock.ExpectExec(`UPDATE user_passkeys SET sign_count`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`UPDATE users SET .+`).WillReturnError(errors.New("db fail"))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, passkeyRequest(pathLoginPasskey, response, cookies))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestLoginPasskeyOptionsInvalidHost(t *testing.T) {
+	setupPasskeyConfig(t)
+	viper.Set("site.host", "localhost")
+
+	router, _ := setupPasskeyLoginRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, passkeyRequest(pathLoginPasskeyOptions, "", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -18,6 +19,7 @@ import (
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 
 	"errors"
 	"strings"
@@ -26,7 +28,6 @@ import (
 	"github.com/Dobefu/go-web-starter/internal/database"
 	"github.com/Dobefu/go-web-starter/internal/server/middleware"
 	"github.com/Dobefu/go-web-starter/internal/user"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func setupTestRouter(useDBMiddleware bool) (*gin.Engine, sqlmock.Sqlmock, *sql.DB, error) {
@@ -36,12 +37,12 @@ func setupTestRouter(useDBMiddleware bool) (*gin.Engine, sqlmock.Sqlmock, *sql.D
 	mockDB, mockSQL, err := sqlmock.New()
 
 	now := time.Now()
-	mockSQL.ExpectQuery(`SELECT (id, username, email, password, status, created_at, updated_at, last_login) FROM users .+`).
+	mockSQL.ExpectQuery(`SELECT (id, username, email, password, status, created_at, updated_at, last_login, avatar) FROM users .+`).
 		WillReturnRows(
 			sqlmock.NewRows(
-				[]string{"id", "username", "email", "password", "status", "created_at", "updated_at", "last_login"},
+				[]string{"id", "username", "email", "password", "status", "created_at", "updated_at", "last_login", "avatar"},
 			).
-				AddRow(1, "username", "test@example.com", "hash", true, now, now, now),
+				AddRow(1, "username", "test@example.com", "hash", true, now, now, now, ""),
 		)
 
 	if err != nil {
@@ -80,16 +81,23 @@ type mockUser struct {
 	checkPasswordFunc func(string) error
 }
 
-func (m *mockUser) CheckPassword(password string) error {
+func (m *mockUser) CheckPassword(db database.DatabaseInterface, password string) error {
 	if m.checkPasswordFunc != nil {
 		return m.checkPasswordFunc(password)
 	}
 
-	return m.User.CheckPassword(password)
+	return m.User.CheckPassword(db, password)
 }
 
 func (m *mockUser) GetID() int { return 42 }
 
+type argon2idArg struct{}
+
+func (argon2idArg) Match(v driver.Value) bool {
+	hash, ok := v.(string)
+	return ok && strings.HasPrefix(hash, "$argon2id$")
+}
+
 func setUserPassword(u *user.User, hash string) {
 	userVal := reflect.ValueOf(u).Elem()
 	passwordField := userVal.FieldByName("password")
@@ -141,12 +149,17 @@ func TestLoginPost(t *testing.T) {
 
 	origFindByEmail := findByEmail
 	origGetSession := getSession
+	origUserHasTwoFactor := userHasTwoFactor
 
 	defer func() {
 		findByEmail = origFindByEmail
 		getSession = origGetSession
+		userHasTwoFactor = origUserHasTwoFactor
 	}()
 
+	useMemoryLockoutStore(t)
+	useDeletedUsers(t)
+
 	tests := []testCase{
 		{
 			name:           "missing form fields",
@@ -181,8 +194,8 @@ func TestLoginPost(t *testing.T) {
 			expectStatus:   http.StatusSeeOther,
 			expectLocation: paths.PathLogin,
 			setupMock: func(router *gin.Engine, mock sqlmock.Sqlmock, tc *testCase) {
-				hash, _ := bcrypt.GenerateFromPassword([]byte("notpw"), bcrypt.DefaultCost)
-				setUserPassword(&tc.foundUser.User, string(hash))
+				hash, _ := user.HashPassword("notpw")
+				setUserPassword(&tc.foundUser.User, hash)
 			},
 		},
 		{
@@ -233,6 +246,56 @@ func TestLoginPost(t *testing.T) {
 				mock.ExpectQuery(`UPDATE users SET username = \$1, email = \$2, password = \$3, status = \$4, updated_at = \$5, last_login = \$6 WHERE id = \$7 RETURNING updated_at`).
 					WithArgs("", "", sqlmock.AnyArg(), true, sqlmock.AnyArg(), sqlmock.AnyArg(), 42).
 					WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+				expectRememberDevice(mock, false, true)
+			},
+		},
+		{
+			name:           "outdated hash is replaced",
+			form:           url.Values{"email": {"user@example.com"}, "password": {"pw"}},
+			setDBInContext: true,
+			foundUser:      &mockUser{User: *user.NewUser("", "", "", true)},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathAccount,
+			setupMock: func(router *gin.Engine, mock sqlmock.Sqlmock, tc *testCase) {
+				hash, _ := bcrypt.GenerateFromPassword([]byte("pw"), bcrypt.MinCost)
+				setUserPassword(&tc.foundUser.User, string(hash))
+
+				userVal := reflect.ValueOf(&tc.foundUser.User).Elem()
+				idField := userVal.FieldByName("id")
+				reflect.NewAt(idField.Type(), unsafe.Pointer(idField.UnsafeAddr())).Elem().SetInt(42)
+
+				for range 2 {
+					mock.ExpectQuery(`UPDATE users SET username = \$1, email = \$2, password = \$3, status = \$4, updated_at = \$5, last_login = \$6 WHERE id = \$7 RETURNING updated_at`).
+						WithArgs("", "", argon2idArg{}, true, sqlmock.AnyArg(), sqlmock.AnyArg(), 42).
+						WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+				}
+			},
+		},
+		{
+			name:           "two-factor required",
+			form:           url.Values{"email": {"user@example.com"}, "password": {"pw"}},
+			hashPassword:   true,
+			setDBInContext: true,
+			foundUser:      &mockUser{User: *user.NewUser("", "", "", true)},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin + "/two-factor",
+			setupMock: func(router *gin.Engine, mock sqlmock.Sqlmock, tc *testCase) {
+				userHasTwoFactor = func(*user.User, database.DatabaseInterface) (bool, error) {
+					return true, nil
+				}
+			},
+		},
+		{
+			name:           "two-factor lookup error",
+			form:           url.Values{"email": {"user@example.com"}, "password": {"pw"}},
+			hashPassword:   true,
+			setDBInContext: true,
+			foundUser:      &mockUser{User: *user.NewUser("", "", "", true)},
+			expectStatus:   http.StatusInternalServerError,
+			setupMock: func(router *gin.Engine, mock sqlmock.Sqlmock, tc *testCase) {
+				userHasTwoFactor = func(*user.User, database.DatabaseInterface) (bool, error) {
+					return false, errors.New("db fail")
+				}
 			},
 		},
 		{
@@ -286,6 +349,9 @@ func TestLoginPost(t *testing.T) {
 			}
 
 			getSession = origGetSession
+			userHasTwoFactor = func(*user.User, database.DatabaseInterface) (bool, error) {
+				return false, nil
+			}
 
 			w := httptest.NewRecorder()
 			var req *http.Request
@@ -299,8 +365,8 @@ func TestLoginPost(t *testing.T) {
 			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 
 			if tc.hashPassword && tc.foundUser != nil {
-				hash, _ := bcrypt.GenerateFromPassword([]byte("pw"), bcrypt.DefaultCost)
-				setUserPassword(&tc.foundUser.User, string(hash))
+				hash, _ := user.HashPassword("pw")
+				setUserPassword(&tc.foundUser.User, hash)
 			}
 
 			if tc.setupMock != nil {
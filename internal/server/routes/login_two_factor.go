@@ -0,0 +1,280 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/logger"
+	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sessionKeyTwoFactorUserID    = "twoFactorUserID"
+	sessionKeyTwoFactorStartedAt = "twoFactorStartedAt"
+	sessionKeyTwoFactorAttempts  = "twoFactorAttempts"
+	sessionKeyTwoFactorRestore   = "twoFactorRestore"
+
+	twoFactorLoginTimeout     = 5 * time.Minute
+	twoFactorLoginMaxAttempts = 5
+
+	errTwoFactorLoginExpired = "Your login attempt has expired. Please log in again."
+)
+
+var userHasTwoFactor = func(usr *user.User, db database.DatabaseInterface) (bool, error) {
+	return usr.HasTwoFactor(db)
+}
+
+var userVerifyTwoFactor = func(usr *user.User, db database.DatabaseInterface, code string) error {
+	return usr.VerifyTwoFactor(db, code)
+}
+
+func startTwoFactorLogin(c *gin.Context, session sessions.Session, usr *user.User) {
+	session.Set(sessionKeyTwoFactorUserID, usr.GetID())
+	session.Set(sessionKeyTwoFactorStartedAt, time.Now().Unix())
+	session.Set(sessionKeyTwoFactorAttempts, 0)
+
+	// A deleted account is only restored once the second factor is verified.
+	if usr.IsDeleted() {
+		session.Set(sessionKeyTwoFactorRestore, true)
+	}
+
+	err := session.Save()
+
+	if err != nil {
+		log := logger.New(config.GetLogLevel(), os.Stdout)
+		log.Error("Failed to save session for the two-factor login step", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("%s/two-factor", paths.PathLogin))
+}
+
+func clearTwoFactorLogin(session sessions.Session) {
+	session.Delete(sessionKeyTwoFactorUserID)
+	session.Delete(sessionKeyTwoFactorStartedAt)
+	session.Delete(sessionKeyTwoFactorAttempts)
+	session.Delete(sessionKeyTwoFactorRestore)
+}
+
+func getTwoFactorLoginUserID(session sessions.Session) (int, bool) {
+	userID, ok := session.Get(sessionKeyTwoFactorUserID).(int)
+
+	if !ok {
+		return 0, false
+	}
+
+	startedAt, ok := session.Get(sessionKeyTwoFactorStartedAt).(int64)
+
+	if !ok || time.Since(time.Unix(startedAt, 0)) > twoFactorLoginTimeout {
+		return 0, false
+	}
+
+	return userID, true
+}
+
+func findTwoFactorLoginUser(db database.DatabaseInterface, session sessions.Session, userID int) (*user.User, error) {
+	if restore, _ := session.Get(sessionKeyTwoFactorRestore).(bool); restore {
+		return findDeletedByID(db, userID)
+	}
+
+	return findByID(db, userID)
+}
+
+func LoginTwoFactor(c *gin.Context) {
+	v := validator.New()
+	v.SetContext(c)
+
+	session := getSession(c)
+
+	if _, ok := getTwoFactorLoginUserID(session); !ok {
+		c.Redirect(http.StatusSeeOther, paths.PathLogin)
+		return
+	}
+
+	data := RouteData{
+		Template:   "pages/login_two_factor",
+		HttpStatus: http.StatusOK,
+
+		Title:       "Two-Factor Authentication",
+		Description: "Enter your authentication code",
+
+		FormData: FormData{
+			Values: v.GetFormData(),
+			Errors: v.GetSessionErrors(),
+		},
+		CSRFToken: middleware.GetCSRFToken(c),
+	}
+
+	RenderRouteHTML(c, data)
+
+	v.ClearSession()
+}
+
+func LoginTwoFactorPost(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	v := validator.New()
+	v.SetContext(c)
+
+	path := fmt.Sprintf("%s/two-factor", paths.PathLogin)
+	err := v.ValidateForm(c.Request)
+
+	if err != nil {
+		log.Error("Failed to parse form data", map[string]any{"error": err.Error()})
+	}
+
+	session := getSession(c)
+	userID, ok := getTwoFactorLoginUserID(session)
+
+	if !ok {
+		clearTwoFactorLogin(session)
+		session.Delete(sessionKeyPasswordReset)
+		_ = session.Save()
+
+		v.SetFlash(message.Message{Type: message.MessageTypeError, Body: errTwoFactorLoginExpired})
+		c.Redirect(http.StatusSeeOther, paths.PathLogin)
+
+		return
+	}
+
+	code := v.GetFormValue(c.Request, "code")
+	v.Required("code", code)
+
+	if v.HasErrors() {
+		route_utils.RedirectWithError(c, v, nil, "Please correct the errors below", path)
+		return
+	}
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Failed to get database connection from context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	usr, err := findTwoFactorLoginUser(db, session, userID)
+
+	if err != nil {
+		log.Error("Could not find the user for the two-factor login step", logger.Fields{"userID": userID, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	// The failed codes count towards the same limits as the failed passwords,
+	// since logging in again would otherwise reset the attempts of the session.
+	throttle := newLoginThrottle(c, db, usr.GetEmail())
+
+	if status := throttle.check(c); status.Blocked() {
+		log.Warn("Two-factor login blocked: too many failed attempts", logger.Fields{"userID": userID, "ip": c.ClientIP()})
+
+		clearTwoFactorLogin(session)
+		session.Delete(sessionKeyPasswordReset)
+		_ = session.Save()
+
+		loginRedirectError(c, v, loginThrottledMessage(status))
+
+		return
+	}
+
+	err = userVerifyTwoFactor(usr, db, code)
+
+	if err != nil {
+		if !errors.Is(err, user.ErrInvalidTwoFactorCode) {
+			log.Error("Two-factor verification error during login", logger.Fields{"userID": userID, "error": err.Error()})
+			RenderRouteHTML(c, GenericErrorData(c))
+
+			return
+		}
+
+		log.Warn("Login failed: invalid two-factor code", logger.Fields{"userID": userID})
+		auditEvent(c, audit.EventLoginFailed, 0, userID, audit.Metadata{"method": "two-factor"})
+
+		if status := throttle.fail(c); status.JustLocked && usr.GetStatus() {
+			log.Warn("Account locked after too many failed login attempts", logger.Fields{"userID": userID})
+
+			if err = sendUnlockEmail(db, usr); err != nil {
+				log.Error("Failed to send the unlock email", logger.Fields{"userID": userID, "error": err.Error()})
+			}
+		}
+
+		attempts, _ := session.Get(sessionKeyTwoFactorAttempts).(int)
+		attempts++
+
+		if attempts >= twoFactorLoginMaxAttempts {
+			clearTwoFactorLogin(session)
+			session.Delete(sessionKeyPasswordReset)
+			_ = session.Save()
+
+			v.SetFlash(message.Message{Type: message.MessageTypeError, Body: errTwoFactorLoginExpired})
+			c.Redirect(http.StatusSeeOther, paths.PathLogin)
+
+			return
+		}
+
+		session.Set(sessionKeyTwoFactorAttempts, attempts)
+		_ = session.Save()
+
+		v.AddFieldError("code", user.ErrInvalidTwoFactorCode.Error())
+		route_utils.RedirectWithError(c, v, nil, user.ErrInvalidTwoFactorCode.Error(), path)
+
+		return
+	}
+
+	clearTwoFactorLogin(session)
+	err = restoreOnLogin(c, db, usr)
+
+	if err != nil {
+		log.Error("Failed to restore the account during login", logger.Fields{"userID": userID, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	err = usr.Login(db, session)
+
+	if err != nil {
+		log.Error("Failed to save session after two-factor login", logger.Fields{"userID": userID, "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	throttle.reset(c)
+
+	log.Info("Login successful", map[string]any{
+		"email":     usr.GetEmail(),
+		"userID":    usr.GetID(),
+		"twoFactor": true,
+	})
+
+	auditEvent(c, audit.EventLoginSucceeded, usr.GetID(), usr.GetID(), audit.Metadata{"twoFactor": true})
+	notifyNewDevice(c, db, usr)
+
+	// A one-time login from a password reset link goes on to the new password.
+	if passwordResetActive(session) {
+		v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: msgPasswdResetLogin})
+		c.Redirect(http.StatusSeeOther, fmt.Sprintf("%s/edit", paths.PathAccount))
+
+		return
+	}
+
+	v.SetFlash(message.Message{Type: message.MessageTypeSuccess, Body: "Successfully logged in!"})
+	c.Redirect(http.StatusSeeOther, paths.PathAccount)
+}
@@ -0,0 +1,298 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/user"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var pathLoginTwoFactor = paths.PathLogin + "/two-factor"
+
+func newTestUserWithID(id int) *user.User {
+	usr := user.NewUser("user", "user@example.com", "", true)
+
+	idField := reflect.ValueOf(usr).Elem().FieldByName("id")
+	reflect.NewAt(idField.Type(), unsafe.Pointer(idField.UnsafeAddr())).Elem().SetInt(int64(id))
+
+	return usr
+}
+
+func patchUserHasTwoFactor(t *testing.T, hasTwoFactor bool) {
+	origUserHasTwoFactor := userHasTwoFactor
+
+	t.Cleanup(func() { userHasTwoFactor = origUserHasTwoFactor })
+	userHasTwoFactor = func(*user.User, database.DatabaseInterface) (bool, error) { return hasTwoFactor, nil }
+}
+
+func setPendingTwoFactorLogin(startedAt time.Time, attempts int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session := sessions.Default(c)
+		session.Set(sessionKeyTwoFactorUserID, 42)
+		session.Set(sessionKeyTwoFactorStartedAt, startedAt.Unix())
+		session.Set(sessionKeyTwoFactorAttempts, attempts)
+
+		c.Next()
+	}
+}
+
+func TestGetTwoFactorLoginUserID(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    any
+		startedAt any
+		wantID    int
+		wantOk    bool
+	}{
+		{"no pending login", nil, nil, 0, false},
+		{"missing start time", 42, nil, 0, false},
+		{"expired", 42, time.Now().Add(-twoFactorLoginTimeout - time.Minute).Unix(), 0, false},
+		{"pending", 42, time.Now().Unix(), 42, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, _, mockDB := setupTestRouterWithMocks(t, false)
+			defer func() { _ = mockDB.Close() }()
+
+			router.GET("/", func(c *gin.Context) {
+				session := sessions.Default(c)
+
+				if tc.userID != nil {
+					session.Set(sessionKeyTwoFactorUserID, tc.userID)
+				}
+
+				if tc.startedAt != nil {
+					session.Set(sessionKeyTwoFactorStartedAt, tc.startedAt)
+				}
+
+				userID, ok := getTwoFactorLoginUserID(session)
+				assert.Equal(t, tc.wantID, userID)
+				assert.Equal(t, tc.wantOk, ok)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			router.ServeHTTP(w, req)
+		})
+	}
+}
+
+func TestLoginTwoFactor(t *testing.T) {
+	tests := []struct {
+		name           string
+		pending        bool
+		expectStatus   int
+		expectLocation string
+	}{
+		{"no pending login", false, http.StatusSeeOther, paths.PathLogin},
+		{"pending login", true, http.StatusOK, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, _, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			if tc.pending {
+				router.Use(setPendingTwoFactorLogin(time.Now(), 0))
+			}
+
+			router.GET(pathLoginTwoFactor, LoginTwoFactor)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", pathLoginTwoFactor, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.Equal(t, tc.expectLocation, w.Header().Get("Location"))
+
+			if tc.pending {
+				assert.Contains(t, w.Body.String(), "one-time-code")
+			}
+		})
+	}
+}
+
+func TestLoginTwoFactorPost(t *testing.T) {
+	origFindByID := findByID
+	origUserVerifyTwoFactor := userVerifyTwoFactor
+
+	defer func() {
+		findByID = origFindByID
+		userVerifyTwoFactor = origUserVerifyTwoFactor
+	}()
+
+	tests := []struct {
+		name           string
+		pending        bool
+		startedAt      time.Time
+		attempts       int
+		form           url.Values
+		passwordReset  bool
+		findErr        error
+		verifyErr      error
+		setupMock      func(mock sqlmock.Sqlmock)
+		expectStatus   int
+		expectLocation string
+	}{
+		{
+			name:           "no pending login",
+			form:           url.Values{"code": {"123456"}},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+		},
+		{
+			name:           "expired",
+			pending:        true,
+			startedAt:      time.Now().Add(-twoFactorLoginTimeout - time.Minute),
+			form:           url.Values{"code": {"123456"}},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+		},
+		{
+			name:           "missing code",
+			pending:        true,
+			startedAt:      time.Now(),
+			form:           url.Values{},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathLoginTwoFactor,
+		},
+		{
+			name:         "user lookup error",
+			pending:      true,
+			startedAt:    time.Now(),
+			form:         url.Values{"code": {"123456"}},
+			findErr:      errors.New("db fail"),
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "verification error",
+			pending:      true,
+			startedAt:    time.Now(),
+			form:         url.Values{"code": {"123456"}},
+			verifyErr:    errors.New("db fail"),
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "invalid code",
+			pending:        true,
+			startedAt:      time.Now(),
+			form:           url.Values{"code": {"123456"}},
+			verifyErr:      user.ErrInvalidTwoFactorCode,
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: pathLoginTwoFactor,
+		},
+		{
+			name:           "too many attempts",
+			pending:        true,
+			startedAt:      time.Now(),
+			attempts:       twoFactorLoginMaxAttempts - 1,
+			form:           url.Values{"code": {"123456"}},
+			verifyErr:      user.ErrInvalidTwoFactorCode,
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+		},
+		{
+			name:      "login error",
+			pending:   true,
+			startedAt: time.Now(),
+			form:      url.Values{"code": {"123456"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE users SET .+`).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:      "success",
+			pending:   true,
+			startedAt: time.Now(),
+			form:      url.Values{"code": {"123456"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE users SET .+`).
+					WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+				expectRememberDevice(mock, true, false)
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathAccount,
+		},
+		{
+			name:          "success after a password reset link",
+			pending:       true,
+			startedAt:     time.Now(),
+			passwordReset: true,
+			form:          url.Values{"code": {"123456"}},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE users SET .+`).
+					WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+				expectRememberDevice(mock, true, false)
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathAccount + "/edit",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			findByID = func(db database.DatabaseInterface, id int) (*user.User, error) {
+				assert.Equal(t, 42, id)
+
+				if tc.findErr != nil {
+					return nil, tc.findErr
+				}
+
+				return newTestUserWithID(id), nil
+			}
+
+			userVerifyTwoFactor = func(*user.User, database.DatabaseInterface, string) error {
+				return tc.verifyErr
+			}
+
+			if tc.setupMock != nil {
+				tc.setupMock(mock)
+			}
+
+			if tc.pending {
+				router.Use(setPendingTwoFactorLogin(tc.startedAt, tc.attempts))
+			}
+
+			if tc.passwordReset {
+				router.Use(func(c *gin.Context) {
+					sessions.Default(c).Set(sessionKeyPasswordReset, time.Now().Unix())
+					c.Next()
+				})
+			}
+
+			router.POST(pathLoginTwoFactor, LoginTwoFactorPost)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", pathLoginTwoFactor, strings.NewReader(tc.form.Encode()))
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+
+			if tc.expectLocation != "" {
+				assert.Equal(t, tc.expectLocation, w.Header().Get("Location"))
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"net/http"
 
+	"github.com/Dobefu/go-web-starter/internal/audit"
 	"github.com/Dobefu/go-web-starter/internal/message"
 	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
 	"github.com/Dobefu/go-web-starter/internal/validator"
@@ -12,6 +13,17 @@ import (
 
 func Logout(c *gin.Context) {
 	session := sessions.Default(c)
+
+	if userID, ok := session.Get("userID").(int); ok {
+		// Logging out also ends an impersonation,
+		// so the admin does not return to their own session.
+		if adminID, ok := impersonatorID(session); ok {
+			auditEvent(c, audit.EventImpersonationStopped, adminID, userID, nil)
+		} else {
+			auditEvent(c, audit.EventLogout, userID, userID, nil)
+		}
+	}
+
 	session.Clear()
 	_ = session.Save()
 
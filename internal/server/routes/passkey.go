@@ -0,0 +1,56 @@
+package routes
+
+import (
+	"errors"
+
+	"github.com/Dobefu/go-web-starter/internal/passkey"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/spf13/viper"
+)
+
+const (
+	sessionKeyPasskeyLogin        = "passkeyLogin"
+	sessionKeyPasskeyRegistration = "passkeyRegistration"
+)
+
+var errPasskeyCeremonyMissing = errors.New("no passkey ceremony is in progress")
+
+var newWebAuthn = func() (*webauthn.WebAuthn, error) {
+	return passkey.New(viper.GetString("site.name"), viper.GetString("site.host"))
+}
+
+func savePasskeySession(session sessions.Session, key string, data *webauthn.SessionData) error {
+	encoded, err := passkey.EncodeSession(data)
+
+	if err != nil {
+		return err
+	}
+
+	session.Set(key, encoded)
+
+	return session.Save()
+}
+
+// takePasskeySession returns the state of the ceremony that is in progress,
+// and removes it from the session so that a challenge can only be used once.
+func takePasskeySession(session sessions.Session, key string) (*webauthn.SessionData, error) {
+	encoded, ok := session.Get(key).(string)
+
+	session.Delete(key)
+	_ = session.Save()
+
+	if !ok {
+		return nil, errPasskeyCeremonyMissing
+	}
+
+	return passkey.DecodeSession(encoded)
+}
+
+func passkeyError(c *gin.Context, status int, message string) {
+	c.JSON(status, gin.H{
+		"status": "error",
+		"error":  message,
+	})
+}
@@ -6,4 +6,8 @@ const (
 	PathLogout         = "/logout"
 	PathForgotPassword = "/forgot-password"
 	PathAccount        = "/account"
+	PathAdmin          = "/admin"
+	PathAPI            = "/api"
+	PathLegal          = "/legal"
+	PathLegalAccept    = "/legal/accept"
 )
@@ -1,11 +1,15 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 
+	"github.com/Dobefu/go-web-starter/internal/audit"
 	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/database"
 	emailer "github.com/Dobefu/go-web-starter/internal/email"
 	"github.com/Dobefu/go-web-starter/internal/logger"
 	"github.com/Dobefu/go-web-starter/internal/message"
@@ -19,15 +23,70 @@ import (
 	"github.com/spf13/viper"
 )
 
+const (
+	errRegistrationInviteOnly = "Registration is by invitation only."
+	errInviteInvalid          = "This invitation is invalid or has expired."
+)
+
 var findByEmail = user.FindByEmail
 var findByUsername = user.FindByUsername
+var findByID = user.FindByID
+var findInvite = user.FindInvite
 var getSession = sessions.Default
 
+func registerPath(inviteToken string) string {
+	if inviteToken == "" {
+		return paths.PathRegister
+	}
+
+	return fmt.Sprintf("%s?invite=%s", paths.PathRegister, url.QueryEscape(inviteToken))
+}
+
 func Register(c *gin.Context) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
 	v := validator.New()
 	v.SetContext(c)
 
 	csrfToken := middleware.GetCSRFToken(c)
+	inviteToken := c.Query("invite")
+
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err != nil {
+		log.Error("Failed to get database connection from context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	var invite *user.Invite
+
+	if inviteToken != "" {
+		invite, err = findInvite(db, inviteToken)
+
+		if err != nil {
+			if !errors.Is(err, user.ErrInvalidInvite) {
+				log.Error("Could not find the invitation", logger.Fields{"error": err.Error()})
+				RenderRouteHTML(c, GenericErrorData(c))
+
+				return
+			}
+
+			v.SetFlash(message.Message{Type: message.MessageTypeError, Body: errInviteInvalid})
+			c.Redirect(http.StatusSeeOther, paths.PathRegister)
+
+			return
+		}
+	}
+
+	docs, err := findCurrentDocuments(db)
+
+	if err != nil {
+		log.Error("Could not find the legal documents", logger.Fields{"error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
 
 	data := RouteData{
 		Template:   "pages/register",
@@ -36,6 +95,13 @@ func Register(c *gin.Context) {
 		Title:       "Register",
 		Description: "Register a new account",
 
+		Data: map[string]any{
+			"Invite":      invite,
+			"InviteToken": inviteToken,
+			"InviteOnly":  config.InviteOnly(),
+			"Documents":   docs,
+		},
+
 		FormData: FormData{
 			Values: v.GetFormData(),
 			Errors: v.GetSessionErrors(),
@@ -61,27 +127,65 @@ func RegisterPost(c *gin.Context) {
 	username := v.GetFormValue(c.Request, "username")
 	password := v.GetFormValue(c.Request, "password")
 	passwordConfirm := v.GetFormValue(c.Request, "password_confirm")
+	inviteToken := v.GetFormValue(c.Request, "invite")
 
-	v.ValidEmail("email", email)
-	v.Required("email", email)
+	db, err := route_utils.GetDbFromContext(c)
 
-	v.Required("username", username)
-	v.MinLength("username", username, 3)
+	if err != nil {
+		log.Error("Failed to get database connection from context", nil)
+		RenderRouteHTML(c, GenericErrorData(c))
 
-	v.Required("password", password)
-	v.MinLength("password", password, 8)
-	v.Required("password_confirm", passwordConfirm)
-	v.PasswordsMatch("password", password, passwordConfirm)
+		return
+	}
 
-	db, err := route_utils.GetDbFromContext(c)
+	var invite *user.Invite
+
+	if inviteToken != "" {
+		invite, err = findInvite(db, inviteToken)
+
+		if err != nil {
+			if !errors.Is(err, user.ErrInvalidInvite) {
+				log.Error("Could not find the invitation", logger.Fields{"error": err.Error()})
+				RenderRouteHTML(c, GenericErrorData(c))
+
+				return
+			}
+
+			route_utils.RedirectWithError(c, v, nil, errInviteInvalid, paths.PathRegister)
+			return
+		}
+
+		// The invitation is only valid for the address it was sent to.
+		email = invite.Email
+	} else if config.InviteOnly() {
+		route_utils.RedirectWithError(c, v, nil, errRegistrationInviteOnly, paths.PathRegister)
+		return
+	}
+
+	docs, err := findCurrentDocuments(db)
 
 	if err != nil {
-		log.Error("Failed to get database connection from context", nil)
+		log.Error("Could not find the legal documents", logger.Fields{"error": err.Error()})
 		RenderRouteHTML(c, GenericErrorData(c))
 
 		return
 	}
 
+	if len(docs) > 0 {
+		v.CheckField(v.GetFormValue(c.Request, "accept") == "on", "accept", errRegisterNotAccepted)
+	}
+
+	v.ValidEmail("email", email)
+	v.Required("email", email)
+
+	v.Required("username", username)
+	v.MinLength("username", username, 3)
+
+	v.Required("password", password)
+	v.PasswordPolicy("password", password, validator.PasswordContext{Username: username, Email: email})
+	v.Required("password_confirm", passwordConfirm)
+	v.PasswordsMatch("password", password, passwordConfirm)
+
 	_, err = findByUsername(db, username)
 
 	if err == nil {
@@ -103,7 +207,7 @@ func RegisterPost(c *gin.Context) {
 				"email":    email,
 			},
 			"Please correct the errors below",
-			paths.PathRegister,
+			registerPath(inviteToken),
 		)
 		return
 	}
@@ -117,9 +221,27 @@ func RegisterPost(c *gin.Context) {
 		return
 	}
 
-	usr := user.NewUser(username, email, hashedPassword, false)
+	// Following the link in the invitation proves that the email address
+	// belongs to the user, so the account does not need to be verified.
+	usr := user.NewUser(username, email, hashedPassword, invite != nil)
 	err = usr.Save(db)
 
+	// The username or email address can still belong to a deleted
+	// account that has not been purged yet.
+	if errors.Is(err, user.ErrUserExists) {
+		route_utils.RedirectWithError(
+			c,
+			v,
+			map[string]string{
+				"username": username,
+				"email":    email,
+			},
+			user.ErrUserExists.Error(),
+			registerPath(inviteToken),
+		)
+		return
+	}
+
 	if err != nil {
 		log.Error("Failed to save the user", logger.Fields{"err": err.Error()})
 		RenderRouteHTML(c, GenericErrorData(c))
@@ -127,29 +249,25 @@ func RegisterPost(c *gin.Context) {
 		return
 	}
 
-	token := usr.CreateVerificationToken()
+	err = acceptDocuments(db, usr.GetID(), shownDocuments(c, docs), c.ClientIP())
 
-	mail := emailer.New(
-		viper.GetString("email.host"),
-		viper.GetString("email.port"),
-		viper.GetString("email.identity"),
-		viper.GetString("email.user"),
-		viper.GetString("email.password"),
-	)
+	if err != nil {
+		if deleteErr := usr.Delete(db); deleteErr != nil {
+			log.Error("Failed to remove the user after accepting the legal documents failed", logger.Fields{"userID": usr.GetID(), "error": deleteErr.Error()})
+		}
 
-	err = mail.SendMail(
-		viper.GetString("site.email"),
-		[]string{email},
-		fmt.Sprintf("Activate your %s account", viper.GetString("site.name")),
-		emailer.EmailBody{
-			Template: "email/register_verify",
-			Data: map[string]any{
-				"Username": username,
-				"Token":    token,
-				"Email":    email,
-			},
-		},
-	)
+		log.Error("Failed to accept the legal documents", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	if invite != nil {
+		registerWithInvite(c, v, db, usr, invite)
+		return
+	}
+
+	err = sendRegisterVerifyEmail(db, usr)
 
 	if err != nil {
 		log.Error("Failed to send the registation email", logger.Fields{"err": err.Error()})
@@ -165,3 +283,59 @@ func RegisterPost(c *gin.Context) {
 
 	c.Redirect(http.StatusSeeOther, fmt.Sprintf("%s/verify?email=%s", paths.PathRegister, email))
 }
+
+// registerWithInvite accepts the invitation for the account that has just been
+// created with it. When that fails, the account is removed again, so that the
+// invitation can be used for another attempt.
+func registerWithInvite(c *gin.Context, v *validator.Validator, db database.DatabaseInterface, usr *user.User, invite *user.Invite) {
+	log := logger.New(config.GetLogLevel(), os.Stdout)
+	err := invite.Accept(db, usr)
+
+	if err != nil {
+		if deleteErr := usr.Delete(db); deleteErr != nil {
+			log.Error("Failed to remove the user after the invitation failed", logger.Fields{"userID": usr.GetID(), "error": deleteErr.Error()})
+		}
+
+		if errors.Is(err, user.ErrInvalidInvite) {
+			route_utils.RedirectWithError(c, v, nil, errInviteInvalid, paths.PathRegister)
+			return
+		}
+
+		log.Error("Failed to accept the invitation", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
+	log.Info("User registered with an invitation", logger.Fields{"userID": usr.GetID(), "inviterID": invite.InviterID})
+	auditEvent(c, audit.EventInviteAccepted, usr.GetID(), usr.GetID(), audit.Metadata{"inviter_id": invite.InviterID, "role": invite.Role})
+
+	v.SetFlash(message.Message{
+		Type: message.MessageTypeSuccess,
+		Body: "Your account has been created! You can log in now.",
+	})
+
+	c.Redirect(http.StatusSeeOther, paths.PathLogin)
+}
+
+func sendRegisterVerifyEmail(db database.DatabaseInterface, usr *user.User) error {
+	token, err := usr.CreateToken(db, user.TokenPurposeVerify, user.TokenTTLVerify)
+
+	if err != nil {
+		return fmt.Errorf("failed to create the verification token: %w", err)
+	}
+
+	return newEmailSender().SendMail(
+		viper.GetString("site.email"),
+		[]string{usr.GetEmail()},
+		fmt.Sprintf("Activate your %s account", viper.GetString("site.name")),
+		emailer.EmailBody{
+			Template: "email/register_verify",
+			Data: map[string]any{
+				"Username": usr.GetUsername(),
+				"Token":    token,
+				"Email":    usr.GetEmail(),
+			},
+		},
+	)
+}
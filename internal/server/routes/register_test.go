@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
@@ -11,13 +12,17 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/audit"
+	"github.com/Dobefu/go-web-starter/internal/config"
 	"github.com/Dobefu/go-web-starter/internal/database"
 	email "github.com/Dobefu/go-web-starter/internal/email"
+	"github.com/Dobefu/go-web-starter/internal/legal"
 	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
 	"github.com/Dobefu/go-web-starter/internal/user"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 
@@ -134,10 +139,28 @@ func TestRegisterPost(t *testing.T) {
 			expectStatus:   http.StatusSeeOther,
 		},
 		{
-			name:           "success",
+			name:           "common password",
 			fields:         map[string]string{"username": "user", "email": "test@example.com", "password": "password123", "password_confirm": "password123"},
 			findByUsername: defaultFind,
 			findByEmail:    defaultFind,
+			mockDB:         true,
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathRegister,
+		},
+		{
+			name:           "password contains the username",
+			fields:         map[string]string{"username": "johnny", "email": "test@example.com", "password": "johnny-2024!", "password_confirm": "johnny-2024!"},
+			findByUsername: defaultFind,
+			findByEmail:    defaultFind,
+			mockDB:         true,
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathRegister,
+		},
+		{
+			name:           "success",
+			fields:         map[string]string{"username": "user", "email": "test@example.com", "password": "correct-horse-9", "password_confirm": "correct-horse-9"},
+			findByUsername: defaultFind,
+			findByEmail:    defaultFind,
 			mockSuccessDB:  true,
 			expectStatus:   http.StatusSeeOther,
 			expectLocation: fmt.Sprintf("%s/verify?email=test@example.com", paths.PathRegister),
@@ -146,6 +169,8 @@ func TestRegisterPost(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
+			useLegalDocuments(t, nil, nil, nil)
+
 			restoreFinders := patchFinders(tc.findByUsername, tc.findByEmail)
 			defer restoreFinders()
 
@@ -160,6 +185,12 @@ func TestRegisterPost(t *testing.T) {
 				m.ExpectQuery("INSERT INTO users").
 					WithArgs("user", "test@example.com", sqlmock.AnyArg(), false, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
 					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "last_login"}).AddRow(1, now, now, now))
+				m.ExpectExec("DELETE FROM user_tokens").
+					WithArgs(1, "verify").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectExec("INSERT INTO user_tokens").
+					WithArgs(1, "verify", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
 
 				db = dbSQL
 
@@ -186,3 +217,380 @@ func TestRegisterPost(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterPostTakenByDeletedAccount(t *testing.T) {
+	notFound := func(database.DatabaseInterface, string) (*user.User, error) { return nil, user.ErrInvalidCredentials }
+
+	restoreFinders := patchFinders(notFound, notFound)
+	defer restoreFinders()
+
+	useLegalDocuments(t, nil, nil, nil)
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("INSERT INTO users").WillReturnError(&pq.Error{Code: "23505"})
+
+	router := setupRouter(db)
+	w := makeRequest(router, makeForm(map[string]string{
+		"username":         "user",
+		"email":            "test@example.com",
+		"password":         "correct-horse-9",
+		"password_confirm": "correct-horse-9",
+	}))
+
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, paths.PathRegister, w.Header().Get("Location"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func setInviteOnly(t *testing.T, inviteOnly bool) {
+	t.Cleanup(func() { viper.Set("site.inviteonly", config.DefaultConfig.Site.InviteOnly) })
+	viper.Set("site.inviteonly", inviteOnly)
+}
+
+// useFindInvite makes "valid-token" the only invitation that can be found,
+// unless err is set, which is then returned for every token.
+func useFindInvite(t *testing.T, invite *user.Invite, err error) {
+	origFindInvite := findInvite
+
+	t.Cleanup(func() { findInvite = origFindInvite })
+	findInvite = func(_ database.DatabaseInterface, token string) (*user.Invite, error) {
+		if err != nil {
+			return nil, err
+		}
+
+		if token != "valid-token" {
+			return nil, user.ErrInvalidInvite
+		}
+
+		return invite, nil
+	}
+}
+
+func TestRegister(t *testing.T) {
+	invite := &user.Invite{ID: 5, Email: "invited@example.com", InviterID: 2}
+
+	tests := []struct {
+		name            string
+		query           string
+		inviteOnly      bool
+		findErr         error
+		docs            []legal.Document
+		docsErr         error
+		expectStatus    int
+		expectLocation  string
+		expectBody      []string
+		expectNotInBody string
+	}{
+		{
+			name:            "open registration",
+			expectStatus:    http.StatusOK,
+			expectBody:      []string{"password-confirm"},
+			expectNotInBody: "by invitation only",
+		},
+		{
+			name:         "legal documents",
+			docs:         testLegalDocuments(),
+			expectStatus: http.StatusOK,
+			expectBody:   []string{"name=accept", `href="/legal/terms"`, `href="/legal/privacy"`, `name=documents value="1"`},
+		},
+		{
+			name:            "no legal documents",
+			expectStatus:    http.StatusOK,
+			expectNotInBody: "name=accept",
+		},
+		{
+			name:         "legal documents error",
+			docsErr:      errors.New("db fail"),
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:            "invite only",
+			inviteOnly:      true,
+			expectStatus:    http.StatusOK,
+			expectBody:      []string{"by invitation only"},
+			expectNotInBody: "password-confirm",
+		},
+		{
+			name:         "invite",
+			query:        "?invite=valid-token",
+			inviteOnly:   true,
+			expectStatus: http.StatusOK,
+			expectBody:   []string{"invited@example.com", "readonly", "valid-token"},
+		},
+		{
+			name:           "invalid invite",
+			query:          "?invite=other-token",
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathRegister,
+		},
+		{
+			name:         "invite lookup error",
+			query:        "?invite=valid-token",
+			findErr:      errors.New("db fail"),
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setInviteOnly(t, tc.inviteOnly)
+			useFindInvite(t, invite, tc.findErr)
+			useLegalDocuments(t, tc.docs, tc.docsErr, nil)
+
+			router, _, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			router.GET(paths.PathRegister, Register)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", paths.PathRegister+tc.query, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.Equal(t, tc.expectLocation, w.Header().Get("Location"))
+
+			for _, body := range tc.expectBody {
+				assert.Contains(t, w.Body.String(), body)
+			}
+
+			if tc.expectNotInBody != "" {
+				assert.NotContains(t, w.Body.String(), tc.expectNotInBody)
+			}
+		})
+	}
+}
+
+func TestRegisterPostWithInvite(t *testing.T) {
+	now := time.Now()
+	invite := &user.Invite{ID: 5, Email: "invited@example.com", Role: "editor", InviterID: 2}
+	notFound := func(database.DatabaseInterface, string) (*user.User, error) { return nil, user.ErrInvalidCredentials }
+
+	fields := map[string]string{
+		"username":         "user",
+		"email":            "other@example.com",
+		"password":         "correct-horse-9",
+		"password_confirm": "correct-horse-9",
+	}
+
+	expectInsertUser := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery("INSERT INTO users").
+			WithArgs("user", "invited@example.com", sqlmock.AnyArg(), true, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "last_login"}).AddRow(7, now, now, now))
+	}
+
+	tests := []struct {
+		name            string
+		token           string
+		inviteOnly      bool
+		findErr         error
+		passwordConfirm string
+		setupMock       func(mock sqlmock.Sqlmock)
+		expectStatus    int
+		expectLocation  string
+		expectEvents    []audit.EventType
+	}{
+		{
+			name:           "invite only without invite",
+			inviteOnly:     true,
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathRegister,
+		},
+		{
+			name:           "invalid invite",
+			token:          "other-token",
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathRegister,
+		},
+		{
+			name:         "invite lookup error",
+			token:        "valid-token",
+			findErr:      errors.New("db fail"),
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:            "validation error",
+			token:           "valid-token",
+			passwordConfirm: "something-else",
+			expectStatus:    http.StatusSeeOther,
+			expectLocation:  paths.PathRegister + "?invite=valid-token",
+		},
+		{
+			name:       "success",
+			token:      "valid-token",
+			inviteOnly: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectInsertUser(mock)
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE user_invites").
+					WithArgs(sqlmock.AnyArg(), 7, 5).
+					WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(nil))
+				mock.ExpectCommit()
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathLogin,
+			expectEvents:   []audit.EventType{audit.EventInviteAccepted},
+		},
+		{
+			name:  "invite used in the meantime",
+			token: "valid-token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectInsertUser(mock)
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE user_invites").WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+				mock.ExpectExec("DELETE FROM users").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathRegister,
+		},
+		{
+			name:  "accept error",
+			token: "valid-token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectInsertUser(mock)
+				mock.ExpectBegin().WillReturnError(errors.New("db fail"))
+				mock.ExpectExec("DELETE FROM users").WithArgs(7).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setInviteOnly(t, tc.inviteOnly)
+			useFindInvite(t, invite, tc.findErr)
+			useLegalDocuments(t, nil, nil, nil)
+			auditLog := useRecordingAuditLog(t)
+			sender := useRecordingEmailSender(t)
+
+			restoreFinders := patchFinders(notFound, notFound)
+			defer restoreFinders()
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			router.POST(paths.PathRegister, RegisterPost)
+
+			if tc.setupMock != nil {
+				tc.setupMock(mock)
+			}
+
+			form := makeForm(fields)
+			form.Set("invite", tc.token)
+
+			if tc.passwordConfirm != "" {
+				form.Set("password_confirm", tc.passwordConfirm)
+			}
+
+			w := makeRequest(router, form)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.Equal(t, tc.expectLocation, w.Header().Get("Location"))
+			assert.Equal(t, append([]audit.EventType{}, tc.expectEvents...), auditLog.types())
+			assert.Empty(t, sender.sent)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRegisterPostLegalDocuments(t *testing.T) {
+	now := time.Now()
+	notFound := func(database.DatabaseInterface, string) (*user.User, error) { return nil, user.ErrInvalidCredentials }
+
+	expectInsertUser := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery("INSERT INTO users").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "last_login"}).AddRow(7, now, now, now))
+	}
+
+	tests := []struct {
+		name           string
+		accept         bool
+		docsErr        error
+		acceptErr      error
+		setupMock      func(mock sqlmock.Sqlmock)
+		expectStatus   int
+		expectLocation string
+		expectAccepted [][]int
+	}{
+		{
+			name:   "accepted",
+			accept: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectInsertUser(mock)
+				mock.ExpectExec("DELETE FROM user_tokens").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO user_tokens").WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: fmt.Sprintf("%s/verify?email=test@example.com", paths.PathRegister),
+			expectAccepted: [][]int{{1, 2}},
+		},
+		{
+			name:           "not accepted",
+			expectStatus:   http.StatusSeeOther,
+			expectLocation: paths.PathRegister,
+		},
+		{
+			name:         "documents error",
+			accept:       true,
+			docsErr:      errors.New("db fail"),
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:      "accept error",
+			accept:    true,
+			acceptErr: errors.New("db fail"),
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectInsertUser(mock)
+				mock.ExpectExec("DELETE FROM users").WithArgs(7).WillReturnError(errors.New("db fail"))
+			},
+			expectStatus:   http.StatusInternalServerError,
+			expectAccepted: [][]int{{1, 2}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			accepted := useLegalDocuments(t, testLegalDocuments(), tc.docsErr, tc.acceptErr)
+			useRecordingEmailSender(t)
+
+			restoreFinders := patchFinders(notFound, notFound)
+			defer restoreFinders()
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			router.POST(paths.PathRegister, RegisterPost)
+
+			if tc.setupMock != nil {
+				tc.setupMock(mock)
+			}
+
+			form := makeForm(map[string]string{
+				"username":         "user",
+				"email":            "test@example.com",
+				"password":         "correct-horse-9",
+				"password_confirm": "correct-horse-9",
+			})
+			form["documents"] = []string{"1", "2"}
+
+			if tc.accept {
+				form.Set("accept", "on")
+			}
+
+			w := makeRequest(router, form)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.Equal(t, tc.expectLocation, w.Header().Get("Location"))
+			assert.Equal(t, tc.expectAccepted, accepted.docIDs)
+
+			if tc.expectAccepted != nil {
+				assert.Equal(t, []int{7}, accepted.userIDs)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
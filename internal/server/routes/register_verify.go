@@ -1,9 +1,11 @@
 package routes
 
 import (
+	"errors"
 	"net/http"
 	"os"
 
+	"github.com/Dobefu/go-web-starter/internal/audit"
 	"github.com/Dobefu/go-web-starter/internal/config"
 	"github.com/Dobefu/go-web-starter/internal/logger"
 	"github.com/Dobefu/go-web-starter/internal/message"
@@ -59,23 +61,16 @@ func RegisterVerify(c *gin.Context) {
 		return
 	}
 
-	usr, err := user.FindByEmail(db, email)
+	usr, err := user.ConsumeToken(db, user.TokenPurposeVerify, token)
 
-	if err != nil {
-		log.Warn("Could not get the user from the email address", logger.Fields{"err": err.Error()})
-
-		v.SetFlash(message.Message{
-			Type: message.MessageTypeError,
-			Body: errUserAccountVerify,
-		})
+	if err != nil && !errors.Is(err, user.ErrInvalidToken) {
+		log.Error("Could not verify the token", logger.Fields{"err": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
 
-		c.Redirect(http.StatusSeeOther, paths.PathRegister)
 		return
 	}
 
-	expectedToken := usr.CreateVerificationToken()
-
-	if token != expectedToken || usr.GetStatus() {
+	if err != nil || usr.GetStatus() {
 		v.SetFlash(message.Message{
 			Type: message.MessageTypeError,
 			Body: errUserAccountVerify,
@@ -95,7 +90,24 @@ func RegisterVerify(c *gin.Context) {
 		return
 	}
 
+	auditEvent(c, audit.EventAccountVerified, usr.GetID(), usr.GetID(), nil)
+
+	hasTwoFactor, err := userHasTwoFactor(usr, db)
+
+	if err != nil {
+		log.Error("Failed to check two-factor authentication during login", logger.Fields{"userID": usr.GetID(), "err": err.Error()})
+		RenderRouteHTML(c, GenericErrorData(c))
+
+		return
+	}
+
 	session := getSession(c)
+
+	if hasTwoFactor {
+		startTwoFactorLogin(c, session, usr)
+		return
+	}
+
 	err = usr.Login(db, session)
 
 	if err != nil {
@@ -1,10 +1,15 @@
 package routes
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
 	server_utils "github.com/Dobefu/go-web-starter/internal/server/utils"
 	"github.com/Dobefu/go-web-starter/internal/templates"
 	"github.com/gin-contrib/sessions"
@@ -60,3 +65,96 @@ func TestRegisterVerify(t *testing.T) {
 		})
 	}
 }
+
+func expectConsumeToken(mock sqlmock.Sqlmock, purpose string, status bool) {
+	now := time.Now()
+
+	mock.ExpectQuery(`UPDATE user_tokens SET consumed_at`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), purpose).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		WithArgs(1).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "username", "email", "password", "status", "created_at", "updated_at", "last_login", "avatar"}).
+				AddRow(1, "username", "test@example.com", "", status, now, now, now, ""),
+		)
+}
+
+func TestRegisterVerifyToken(t *testing.T) {
+	tests := []struct {
+		name         string
+		twoFactor    bool
+		setupMock    func(mock sqlmock.Sqlmock)
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name: "success",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "verify", false)
+				mock.ExpectQuery(`UPDATE users SET .+`).
+					WithArgs("username", "test@example.com", "", true, sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+					WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+				mock.ExpectQuery(`UPDATE users SET .+`).
+					WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/",
+		},
+		{
+			name:      "two-factor required",
+			twoFactor: true,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "verify", false)
+				mock.ExpectQuery(`UPDATE users SET .+`).
+					WithArgs("username", "test@example.com", "", true, sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+					WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: pathLoginTwoFactor,
+		},
+		{
+			name: "invalid or expired token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE user_tokens SET consumed_at`).WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathRegister,
+		},
+		{
+			name: "already verified",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectConsumeToken(mock, "verify", true)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: paths.PathRegister,
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`UPDATE user_tokens SET consumed_at`).WillReturnError(errors.New("db fail"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patchUserHasTwoFactor(t, tt.twoFactor)
+
+			router, mock, mockDB := setupTestRouterWithMocks(t, true)
+			defer func() { _ = mockDB.Close() }()
+
+			router.GET(paths.PathRegister+"/verify", RegisterVerify)
+			tt.setupMock(mock)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", paths.PathRegister+"/verify?email=test@example.com&token=test-token", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -3,15 +3,20 @@ package routes
 import (
 	"bytes"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/logger"
 	"github.com/Dobefu/go-web-starter/internal/message"
+	"github.com/Dobefu/go-web-starter/internal/server/middleware"
 	route_utils "github.com/Dobefu/go-web-starter/internal/server/routes/utils"
+	"github.com/Dobefu/go-web-starter/internal/storage"
 	"github.com/Dobefu/go-web-starter/internal/templates"
 	"github.com/Dobefu/go-web-starter/internal/user"
 	"github.com/Dobefu/go-web-starter/internal/validator"
+	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
 )
@@ -29,6 +34,20 @@ func RenderRouteHTML(c *gin.Context, routeData RouteData) {
 
 	currentUser := route_utils.GetUserFromSession(c)
 
+	if currentUser != nil {
+		loadUserPermissions(c, currentUser)
+	}
+
+	// The banner of an impersonation has a form to stop it on every page,
+	// including those that have no form of their own.
+	_, impersonating := impersonatorID(sessions.Default(c))
+
+	if impersonating && currentUser != nil && routeData.CSRFToken == "" {
+		routeData.CSRFToken = middleware.GetCSRFToken(c)
+	}
+
+	store, _ := route_utils.GetStorageFromContext(c)
+
 	data := struct {
 		RouteData
 		SiteName  string
@@ -39,6 +58,9 @@ func RenderRouteHTML(c *gin.Context, routeData RouteData) {
 		Href      string
 		Messages  []message.Message
 		User      *user.User
+		Storage   storage.Storage
+
+		Impersonating bool
 	}{
 		RouteData: routeData,
 		SiteName:  viper.GetString("site.name"),
@@ -49,6 +71,9 @@ func RenderRouteHTML(c *gin.Context, routeData RouteData) {
 		Href:      c.Request.URL.Path,
 		Messages:  v.GetMessages(),
 		User:      currentUser,
+		Storage:   store,
+
+		Impersonating: impersonating && currentUser != nil,
 	}
 
 	if gin.Mode() == gin.DebugMode {
@@ -76,3 +101,16 @@ func RenderRouteHTML(c *gin.Context, routeData RouteData) {
 
 	c.HTML(data.HttpStatus, data.Template, data)
 }
+
+func loadUserPermissions(c *gin.Context, usr *user.User) {
+	db, err := route_utils.GetDbFromContext(c)
+
+	if err == nil {
+		err = usr.LoadPermissions(db)
+	}
+
+	if err != nil {
+		log := logger.New(config.GetLogLevel(), os.Stdout)
+		log.Error("Failed to load the permissions of the user", logger.Fields{"userID": usr.GetID(), "error": err.Error()})
+	}
+}
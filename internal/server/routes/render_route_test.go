@@ -6,7 +6,10 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/database"
 	"github.com/Dobefu/go-web-starter/internal/templates"
+	"github.com/Dobefu/go-web-starter/internal/user"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
@@ -88,3 +91,33 @@ func TestRenderRouteHTML(t *testing.T) {
 		assert.NotNil(t, c.Errors.Last())
 	})
 }
+
+func TestLoadUserPermissions(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery("SELECT DISTINCT rp.permission").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow(user.PermissionUsersManage))
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Set("db", database.DatabaseInterface(db))
+
+		usr := newTestUserWithID(1)
+		loadUserPermissions(c, usr)
+
+		assert.True(t, usr.HasPermission(user.PermissionUsersManage))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no database", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+		usr := newTestUserWithID(1)
+		loadUserPermissions(c, usr)
+
+		assert.False(t, usr.HasPermission(user.PermissionUsersManage))
+	})
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/Dobefu/go-web-starter/internal/server/middleware"
 	"github.com/Dobefu/go-web-starter/internal/server/routes/paths"
+	"github.com/Dobefu/go-web-starter/internal/user"
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,8 +15,17 @@ func RegisterRoutes(router gin.IRouter) {
 
 	router.GET("/robots.txt", RobotsTxt)
 
+	router.GET(fmt.Sprintf("%s/email/confirm", paths.PathAccount), AccountEmailConfirm)
+	router.GET(fmt.Sprintf("%s/email/revert", paths.PathAccount), AccountEmailRevert)
+	router.GET(fmt.Sprintf("%s/restore", paths.PathAccount), AccountRestore)
+	router.GET(pathLoginNotMe, LoginNotMe)
+	router.POST(pathLoginNotMe, LoginNotMePost)
+	router.GET(fmt.Sprintf("%s/:kind", paths.PathLegal), LegalDocument)
+
 	RegisterAnonOnlyRoutes(router.Group("/"))
 	RegisterAuthOnlyRoutes(router.Group("/"))
+	RegisterAdminRoutes(router.Group(paths.PathAdmin))
+	RegisterAPIRoutes(router.Group(paths.PathAPI))
 }
 
 func RegisterAnonOnlyRoutes(rg *gin.RouterGroup) {
@@ -23,6 +33,16 @@ func RegisterAnonOnlyRoutes(rg *gin.RouterGroup) {
 
 	rg.GET(paths.PathLogin, Login)
 	rg.POST(paths.PathLogin, LoginPost)
+	rg.GET(fmt.Sprintf("%s/two-factor", paths.PathLogin), LoginTwoFactor)
+	rg.POST(fmt.Sprintf("%s/two-factor", paths.PathLogin), LoginTwoFactorPost)
+	rg.POST(fmt.Sprintf("%s/passkey/options", paths.PathLogin), LoginPasskeyOptions)
+	rg.POST(fmt.Sprintf("%s/passkey", paths.PathLogin), LoginPasskeyPost)
+	rg.GET(fmt.Sprintf("%s/unlock", paths.PathLogin), LoginUnlock)
+	rg.GET(fmt.Sprintf("%s/magic-link", paths.PathLogin), LoginMagicLink)
+	rg.POST(fmt.Sprintf("%s/magic-link", paths.PathLogin), LoginMagicLinkPost)
+	rg.GET(fmt.Sprintf("%s/magic-link/verify", paths.PathLogin), LoginMagicLinkVerify)
+	rg.GET(fmt.Sprintf("%s/:provider", paths.PathLogin), LoginOIDC)
+	rg.GET(fmt.Sprintf("%s/:provider/callback", paths.PathLogin), LoginOIDCCallback)
 	rg.GET(paths.PathRegister, Register)
 	rg.POST(paths.PathRegister, RegisterPost)
 	rg.GET(fmt.Sprintf("%s/verify", paths.PathRegister), RegisterVerify)
@@ -34,9 +54,71 @@ func RegisterAuthOnlyRoutes(rg *gin.RouterGroup) {
 	rg.Use(middleware.AuthOnly())
 
 	rg.GET(paths.PathLogout, Logout)
+	rg.POST(pathImpersonationStop, ImpersonationStop)
+	rg.GET(paths.PathLegalAccept, LegalAccept)
+	rg.POST(paths.PathLegalAccept, LegalAcceptPost)
+
+	// The routes above stay available to users that still have to accept the
+	// current versions of the legal documents, so that they can do so or leave.
+	rg.Use(middleware.RequireLegalAcceptance())
+
 	rg.GET(paths.PathAccount, Account)
 	rg.GET(fmt.Sprintf("%s/edit", paths.PathAccount), AccountEdit)
-	rg.POST(fmt.Sprintf("%s/edit", paths.PathAccount), AccountEditPost)
-	rg.GET(fmt.Sprintf("%s/delete", paths.PathAccount), AccountDelete)
-	rg.POST(fmt.Sprintf("%s/delete", paths.PathAccount), AccountDeletePost)
+	rg.GET(fmt.Sprintf("%s/devices", paths.PathAccount), AccountDevices)
+	rg.GET(fmt.Sprintf("%s/invites", paths.PathAccount), AccountInvites)
+	rg.GET(pathAccountOrganizations, AccountOrganizations)
+	rg.GET(fmt.Sprintf("%s/:id", pathAccountOrganizations), AccountOrganization)
+	rg.GET(fmt.Sprintf("%s/activity", paths.PathAccount), AccountActivity)
+
+	// Admins that are logged in as the user can look around, but cannot change
+	// anything on behalf of the user.
+	sensitive := rg.Group("", middleware.BlockImpersonation())
+
+	sensitive.POST(fmt.Sprintf("%s/edit", paths.PathAccount), AccountEditPost)
+	sensitive.POST(pathAccountAvatar, AccountAvatarPost)
+	sensitive.POST(fmt.Sprintf("%s/devices", paths.PathAccount), AccountDevicesPost)
+	sensitive.POST(fmt.Sprintf("%s/invites", paths.PathAccount), AccountInvitesPost)
+	sensitive.POST(pathAccountOrganizations, AccountOrganizationsPost)
+	sensitive.POST(fmt.Sprintf("%s/:id", pathAccountOrganizations), AccountOrganizationPost)
+	sensitive.POST(fmt.Sprintf("%s/email", paths.PathAccount), AccountEmailPost)
+	sensitive.POST(fmt.Sprintf("%s/password", paths.PathAccount), AccountPasswordPost)
+	sensitive.GET(fmt.Sprintf("%s/delete", paths.PathAccount), AccountDelete)
+	sensitive.POST(fmt.Sprintf("%s/delete", paths.PathAccount), AccountDeletePost)
+	sensitive.GET(fmt.Sprintf("%s/two-factor", paths.PathAccount), AccountTwoFactor)
+	sensitive.POST(fmt.Sprintf("%s/two-factor", paths.PathAccount), AccountTwoFactorPost)
+	sensitive.GET(fmt.Sprintf("%s/passkeys", paths.PathAccount), AccountPasskeys)
+	sensitive.POST(fmt.Sprintf("%s/passkeys", paths.PathAccount), AccountPasskeysPost)
+	sensitive.POST(fmt.Sprintf("%s/passkeys/options", paths.PathAccount), AccountPasskeysOptions)
+	sensitive.POST(fmt.Sprintf("%s/passkeys/register", paths.PathAccount), AccountPasskeysRegister)
+	sensitive.GET(fmt.Sprintf("%s/tokens", paths.PathAccount), AccountTokens)
+	sensitive.POST(fmt.Sprintf("%s/tokens", paths.PathAccount), AccountTokensPost)
+	sensitive.POST(fmt.Sprintf("%s/export", paths.PathAccount), AccountExportPost)
+	sensitive.GET(fmt.Sprintf("%s/export/download", paths.PathAccount), AccountExportDownload)
+}
+
+func RegisterAdminRoutes(rg *gin.RouterGroup) {
+	rg.Use(middleware.RequirePermission(user.PermissionUsersManage), middleware.RequireLegalAcceptance())
+
+	rg.GET("", AdminIndex)
+	rg.GET("/users", AdminUsers)
+	rg.GET("/users/:id", AdminUser)
+	rg.POST("/users/:id", AdminUserPost)
+}
+
+func RegisterAPIRoutes(rg *gin.RouterGroup) {
+	rg.GET("/openapi.json", OpenAPI)
+	rg.GET("/token", middleware.APIAuth(), APITokenInfo)
+
+	RegisterAPIV1Routes(rg.Group("/v1"))
+}
+
+func RegisterAPIV1Routes(rg *gin.RouterGroup) {
+	rg.POST("/register", APIRegister)
+	rg.POST("/login", APILogin)
+
+	authenticated := rg.Group("", middleware.APIAuth())
+
+	authenticated.GET("/user", middleware.RequireScope(user.APIScopeAccountRead), APICurrentUser)
+	authenticated.PATCH("/user", middleware.RequireScope(user.APIScopeAccountWrite), APIUpdateUser)
+	authenticated.DELETE("/user", middleware.RequireScope(user.APIScopeAccountWrite), APIDeleteUser)
 }
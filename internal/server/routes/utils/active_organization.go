@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"github.com/Dobefu/go-web-starter/internal/organization"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+const SessionKeyOrganizationID = "organizationID"
+
+func ActiveOrganization(c *gin.Context, memberships []organization.Membership) *organization.Membership {
+	session := sessions.Default(c)
+	activeID, _ := session.Get(SessionKeyOrganizationID).(int)
+
+	for i := range memberships {
+		if memberships[i].Organization.GetID() == activeID {
+			return &memberships[i]
+		}
+	}
+
+	if len(memberships) == 0 {
+		if activeID != 0 {
+			session.Delete(SessionKeyOrganizationID)
+			_ = session.Save()
+		}
+
+		return nil
+	}
+
+	session.Set(SessionKeyOrganizationID, memberships[0].Organization.GetID())
+	_ = session.Save()
+
+	return &memberships[0]
+}
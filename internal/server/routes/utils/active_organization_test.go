@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/organization"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func testMemberships() []organization.Membership {
+	return []organization.Membership{
+		{Organization: organization.New(organization.OrganizationFields{Id: 3, Name: "Acme"}), Role: organization.RoleOwner},
+		{Organization: organization.New(organization.OrganizationFields{Id: 5, Name: "Globex"}), Role: organization.RoleMember},
+	}
+}
+
+func newOrganizationContext(activeID any) *gin.Context {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	sessions.Sessions("mysession", cookie.NewStore([]byte("secret")))(c)
+
+	if activeID != nil {
+		sessions.Default(c).Set(SessionKeyOrganizationID, activeID)
+	}
+
+	return c
+}
+
+func TestActiveOrganization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name          string
+		activeID      any
+		memberships   []organization.Membership
+		expectID      int
+		expectSession any
+	}{
+		{
+			name:          "active organization",
+			activeID:      5,
+			memberships:   testMemberships(),
+			expectID:      5,
+			expectSession: 5,
+		},
+		{
+			name:          "no active organization",
+			memberships:   testMemberships(),
+			expectID:      3,
+			expectSession: 3,
+		},
+		{
+			name:          "left the active organization",
+			activeID:      7,
+			memberships:   testMemberships(),
+			expectID:      3,
+			expectSession: 3,
+		},
+		{
+			name:        "no organizations",
+			activeID:    7,
+			memberships: []organization.Membership{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newOrganizationContext(tc.activeID)
+			membership := ActiveOrganization(c, tc.memberships)
+
+			if tc.expectID == 0 {
+				assert.Nil(t, membership)
+			} else {
+				assert.Equal(t, tc.expectID, membership.Organization.GetID())
+			}
+
+			assert.Equal(t, tc.expectSession, sessions.Default(c).Get(SessionKeyOrganizationID))
+		})
+	}
+}
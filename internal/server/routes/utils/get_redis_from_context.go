@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"errors"
+
+	"github.com/Dobefu/go-web-starter/internal/redis"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errRedisNotFound  = errors.New("redis not found in context")
+	errRedisWrongType = errors.New("redis in context is not of type RedisInterface")
+)
+
+func GetRedisFromContext(c *gin.Context) (r redis.RedisInterface, err error) {
+	redisVal, exists := c.Get("redis")
+
+	if !exists {
+		return nil, errRedisNotFound
+	}
+
+	r, ok := redisVal.(redis.RedisInterface)
+
+	if !ok {
+		return nil, errRedisWrongType
+	}
+
+	return r, nil
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/redis"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockRedis struct {
+	redis.RedisInterface
+}
+
+func TestGetRedisFromContextErrRedisNotFound(t *testing.T) {
+	t.Parallel()
+
+	ctx := gin.Context{}
+
+	r, err := GetRedisFromContext(&ctx)
+	assert.EqualError(t, err, errRedisNotFound.Error())
+	assert.Nil(t, r)
+}
+
+func TestGetRedisFromContextErrRedisWrongType(t *testing.T) {
+	t.Parallel()
+
+	ctx := gin.Context{}
+	ctx.Set("redis", "bogus")
+
+	r, err := GetRedisFromContext(&ctx)
+	assert.EqualError(t, err, errRedisWrongType.Error())
+	assert.Nil(t, r)
+}
+
+func TestGetRedisFromContextSuccess(t *testing.T) {
+	t.Parallel()
+
+	ctx := gin.Context{}
+	ctx.Set("redis", &MockRedis{})
+
+	r, err := GetRedisFromContext(&ctx)
+	assert.NoError(t, err)
+	assert.NotNil(t, r)
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"errors"
+
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errStorageNotFound  = errors.New("storage not found in context")
+	errStorageWrongType = errors.New("storage in context is not of type Storage")
+)
+
+func GetStorageFromContext(c *gin.Context) (store storage.Storage, err error) {
+	storeVal, exists := c.Get("storage")
+
+	if !exists {
+		return nil, errStorageNotFound
+	}
+
+	store, ok := storeVal.(storage.Storage)
+
+	if !ok {
+		return nil, errStorageWrongType
+	}
+
+	return store, nil
+}
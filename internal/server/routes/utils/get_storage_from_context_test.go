@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStorageFromContextErrStorageNotFound(t *testing.T) {
+	t.Parallel()
+
+	ctx := gin.Context{}
+
+	store, err := GetStorageFromContext(&ctx)
+	assert.EqualError(t, err, errStorageNotFound.Error())
+	assert.Nil(t, store)
+}
+
+func TestGetStorageFromContextErrStorageWrongType(t *testing.T) {
+	t.Parallel()
+
+	ctx := gin.Context{}
+	ctx.Set("storage", "bogus")
+
+	store, err := GetStorageFromContext(&ctx)
+	assert.EqualError(t, err, errStorageWrongType.Error())
+	assert.Nil(t, store)
+}
+
+func TestGetStorageFromContextSuccess(t *testing.T) {
+	t.Parallel()
+
+	ctx := gin.Context{}
+	ctx.Set("storage", storage.NewLocalStorage("uploads", "/uploads"))
+
+	store, err := GetStorageFromContext(&ctx)
+	assert.NoError(t, err)
+	assert.NotNil(t, store)
+}
@@ -56,5 +56,13 @@ func GetUserFromSession(c *gin.Context) *user.User {
 		return nil
 	}
 
+	// Deactivated users are logged out of every session they still have.
+	if !currentUser.GetStatus() {
+		session.Clear()
+		_ = session.Save()
+
+		return nil
+	}
+
 	return currentUser
 }
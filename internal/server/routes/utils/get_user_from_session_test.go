@@ -30,7 +30,7 @@ func TestGetCurrentUser(t *testing.T) {
 	origUserFindByID := userFindByID
 	defer func() { userFindByID = origUserFindByID }()
 
-	mockedUser := &user.User{}
+	mockedUser := user.New(user.UserFields{Id: 1, Status: true})
 
 	testCases := []struct {
 		name        string
@@ -82,6 +82,17 @@ func TestGetCurrentUser(t *testing.T) {
 			},
 			mockedUser,
 		},
+		{
+			"user deactivated",
+			1,
+			&mockDB{},
+			func() {
+				userFindByID = func(db database.DatabaseInterface, id int) (*user.User, error) {
+					return user.New(user.UserFields{Id: 1}), nil
+				}
+			},
+			nil,
+		},
 		{
 			"userID cannot be parsed to int",
 			"not-an-int",
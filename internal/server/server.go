@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/Dobefu/go-web-starter/internal/config"
@@ -14,10 +15,11 @@ import (
 	"github.com/Dobefu/go-web-starter/internal/server/middleware"
 	"github.com/Dobefu/go-web-starter/internal/server/routes"
 	server_utils "github.com/Dobefu/go-web-starter/internal/server/utils"
+	"github.com/Dobefu/go-web-starter/internal/sessionstore"
 	"github.com/Dobefu/go-web-starter/internal/static"
+	"github.com/Dobefu/go-web-starter/internal/storage"
 	"github.com/Dobefu/go-web-starter/internal/templates"
 	"github.com/gin-contrib/sessions"
-	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
 )
@@ -36,7 +38,9 @@ const (
 	errStaticFSInit  = "failed to initialize static file system: %v"
 	errDatabaseInit  = "failed to initialize database: %v"
 	errRedisInit     = "failed to initialize Redis: %v"
+	errStorageInit   = "failed to initialize file storage: %v"
 	errSessionDecode = "failed to decode session secret: %v"
+	errProxiesInit   = "failed to set the trusted proxies: %v"
 )
 
 type Router interface {
@@ -83,6 +87,16 @@ func getRedisConfig() config.Redis {
 	}
 }
 
+func registerUploads(router gin.IRouter) {
+	storageConfig := config.GetStorage()
+
+	if !strings.EqualFold(storageConfig.Driver, storage.DriverLocal) || !strings.HasPrefix(storageConfig.Local.URL, "/") {
+		return
+	}
+
+	router.Static(storageConfig.Local.URL, storageConfig.Local.Dir)
+}
+
 func defaultNew(port int) (ServerInterface, error) {
 	log := logger.New(config.GetLogLevel(), os.Stdout)
 
@@ -95,6 +109,11 @@ func defaultNew(port int) (ServerInterface, error) {
 	}
 
 	router := gin.New()
+
+	if err := router.SetTrustedProxies(config.TrustedProxies()); err != nil {
+		return nil, fmt.Errorf(errProxiesInit, err)
+	}
+
 	router.SetFuncMap(server_utils.TemplateFuncMap())
 	log.Trace("Initializing router with template functions", nil)
 
@@ -113,6 +132,7 @@ func defaultNew(port int) (ServerInterface, error) {
 	log.Trace("Static file system initialized", nil)
 
 	router.StaticFS("/static", staticFS)
+	registerUploads(router)
 
 	dbConfig := getDatabaseConfig()
 	db, err := database.New(dbConfig, log)
@@ -134,6 +154,12 @@ func defaultNew(port int) (ServerInterface, error) {
 		log.Trace("Redis connection established", nil)
 	}
 
+	fileStorage, err := storage.FromConfig()
+
+	if err != nil {
+		return nil, fmt.Errorf(errStorageInit, err)
+	}
+
 	srv := &Server{
 		router: &routerWrapper{
 			Router:  router,
@@ -151,7 +177,15 @@ func defaultNew(port int) (ServerInterface, error) {
 		return nil, fmt.Errorf(errSessionDecode, err)
 	}
 
-	store := cookie.NewStore(decodedSecret)
+	// Sessions are kept in Redis when it is enabled, and in the database
+	// otherwise, so that they can be listed and revoked.
+	var sessionBackend sessionstore.Backend = sessionstore.NewDatabaseBackend(db)
+
+	if redisClient != nil {
+		sessionBackend = sessionstore.NewRedisBackend(redisClient)
+	}
+
+	store := sessionstore.New(sessionBackend, decodedSecret)
 	store.Options(sessions.Options{
 		Path:     sessionPath,
 		MaxAge:   int(sessionMaxAge.Seconds()),
@@ -160,15 +194,19 @@ func defaultNew(port int) (ServerInterface, error) {
 		SameSite: sessionSameSite,
 	})
 
+	router.Use(middleware.ClientIP())
 	router.Use(sessions.Sessions(sessionCookieName, store))
 
 	router.Use(gin.Recovery())
 	router.Use(middleware.Logger())
 	router.Use(middleware.Database(srv.db))
+	router.Use(middleware.Storage(fileStorage))
 	router.Use(middleware.CSRF())
 	router.Use(middleware.Flash())
 
 	if redisConfig.Enable && srv.redis != nil {
+		router.Use(middleware.Redis(srv.redis))
+
 		limiter := middleware.NewRateLimiterWithRedis(srv.redis, rateLimitRequests, rateLimitWindow)
 		router.Use(limiter.Middleware())
 	} else {
@@ -176,11 +214,12 @@ func defaultNew(port int) (ServerInterface, error) {
 	}
 
 	router.Use(middleware.CorsHeaders())
-	router.Use(middleware.CspHeaders())
+	router.Use(middleware.CspHeaders(fileStorage))
 	router.Use(middleware.CacheHeaders())
 	log.Trace("Middleware initialized", nil)
 
 	router.NoRoute(routes.NotFound)
+	middleware.SetForbiddenHandler(routes.Forbidden)
 	srv.registerRoutes()
 	log.Trace("Routes registered", nil)
 
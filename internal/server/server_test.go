@@ -4,7 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -148,6 +151,76 @@ func (m *MockRedis) SetWithTTL(ctx context.Context, key string, value any) (*red
 	return args.Get(0).(*redisClient.StatusCmd), args.Error(1)
 }
 
+func (m *MockRedis) Del(ctx context.Context, keys ...string) (*redisClient.IntCmd, error) {
+	args := m.Called(ctx, keys)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*redisClient.IntCmd), args.Error(1)
+}
+
+func (m *MockRedis) SAdd(ctx context.Context, key string, members ...any) (*redisClient.IntCmd, error) {
+	args := m.Called(ctx, key, members)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*redisClient.IntCmd), args.Error(1)
+}
+
+func (m *MockRedis) SMembers(ctx context.Context, key string) (*redisClient.StringSliceCmd, error) {
+	args := m.Called(ctx, key)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*redisClient.StringSliceCmd), args.Error(1)
+}
+
+func (m *MockRedis) SRem(ctx context.Context, key string, members ...any) (*redisClient.IntCmd, error) {
+	args := m.Called(ctx, key, members)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*redisClient.IntCmd), args.Error(1)
+}
+
+func (m *MockRedis) Incr(ctx context.Context, key string) (*redisClient.IntCmd, error) {
+	args := m.Called(ctx, key)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*redisClient.IntCmd), args.Error(1)
+}
+
+func (m *MockRedis) Expire(ctx context.Context, key string, expiration time.Duration) (*redisClient.BoolCmd, error) {
+	args := m.Called(ctx, key, expiration)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*redisClient.BoolCmd), args.Error(1)
+}
+
+func (m *MockRedis) SetNX(ctx context.Context, key string, value any, expiration time.Duration) (*redisClient.BoolCmd, error) {
+	args := m.Called(ctx, key, value, expiration)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*redisClient.BoolCmd), args.Error(1)
+}
+
 func newTestServer(port int) ServerInterface {
 	gin.SetMode(gin.TestMode)
 	mockRouter := &MockRouter{}
@@ -270,6 +343,15 @@ func TestDefaultNewErrors(t *testing.T) {
 	assert.ErrorContains(t, err, "failed to initialize database")
 }
 
+func TestDefaultNewTrustedProxiesError(t *testing.T) {
+	viper.Set("server.trustedproxies", []string{"not-an-ip"})
+	defer viper.Set("server.trustedproxies", []string{})
+
+	srv, err := defaultNew(8080)
+	assert.Nil(t, srv)
+	assert.ErrorContains(t, err, "failed to set the trusted proxies")
+}
+
 func TestDefaultNewRedisError(t *testing.T) {
 	originalMode := gin.Mode()
 	defer gin.SetMode(originalMode)
@@ -311,6 +393,37 @@ func TestDefaultNewRedisError(t *testing.T) {
 	assert.ErrorContains(t, err, "failed to initialize Redis")
 }
 
+func TestDefaultNewStorageError(t *testing.T) {
+	originalMode := gin.Mode()
+	defer gin.SetMode(originalMode)
+
+	gin.SetMode(gin.TestMode)
+
+	err := os.MkdirAll("static", 0755)
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll("static") }()
+
+	originalDBNew := database.New
+
+	database.New = func(cfg config.Database, log *logger.Logger) (database.DatabaseInterface, error) {
+		mockDB := new(MockDatabase)
+		mockDB.On("Close").Return(nil)
+		mockDB.On("Ping").Return(nil)
+
+		return mockDB, nil
+	}
+
+	defer func() { database.New = originalDBNew }()
+
+	viper.Set("storage.driver", "ftp")
+	defer viper.Set("storage.driver", config.DefaultConfig.Storage.Driver)
+
+	srv, err := defaultNew(8080)
+	assert.Nil(t, srv)
+	assert.ErrorContains(t, err, "unknown storage driver: ftp")
+	assert.ErrorContains(t, err, "failed to initialize file storage")
+}
+
 func TestDefaultNewRedisDisabled(t *testing.T) {
 	originalMode := gin.Mode()
 	defer gin.SetMode(originalMode)
@@ -381,6 +494,44 @@ func TestGetRedisConfig(t *testing.T) {
 	assert.Equal(t, 0, config.DB)
 }
 
+func TestRegisterUploads(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer viper.Reset()
+
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "avatars"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "avatars", "1-64.png"), []byte("image"), 0644))
+
+	testCases := []struct {
+		name         string
+		driver       string
+		url          string
+		expectStatus int
+	}{
+		{name: "local storage", driver: "local", url: "/uploads", expectStatus: http.StatusOK},
+		{name: "local storage on another host", driver: "local", url: "https://cdn.example.com", expectStatus: http.StatusNotFound},
+		{name: "s3 storage", driver: "s3", url: "/uploads", expectStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("storage.driver", tc.driver)
+			viper.Set("storage.local.dir", dir)
+			viper.Set("storage.local.url", tc.url)
+
+			router := gin.New()
+			registerUploads(router)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/uploads/avatars/1-64.png", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectStatus, w.Code)
+		})
+	}
+}
+
 func TestStart(t *testing.T) {
 	port := 8080
 	srv := newTestServer(port)
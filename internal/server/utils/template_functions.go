@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/Dobefu/go-web-starter/internal/static"
+	"github.com/Dobefu/go-web-starter/internal/user"
 )
 
 func TemplateFuncMap() template.FuncMap {
@@ -75,5 +76,8 @@ func TemplateFuncMap() template.FuncMap {
 		"trimTrailingNewline": func(s string) string {
 			return strings.TrimRight(s, "\r\n")
 		},
+		"HasPermission": func(usr *user.User, permission string) bool {
+			return usr != nil && usr.HasPermission(permission)
+		},
 	}
 }
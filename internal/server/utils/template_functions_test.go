@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io/fs"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/Dobefu/go-web-starter/internal/static"
+	"github.com/Dobefu/go-web-starter/internal/user"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -238,3 +240,24 @@ func TestTrimTrailingNewline(t *testing.T) {
 		})
 	}
 }
+
+func TestHasPermission(t *testing.T) {
+	hasPermission := TemplateFuncMap()["HasPermission"].(func(*user.User, string) bool)
+
+	usr := user.New(user.UserFields{Id: 1})
+
+	assert.False(t, hasPermission(nil, user.PermissionUsersManage))
+	assert.False(t, hasPermission(usr, user.PermissionUsersManage))
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("SELECT DISTINCT rp.permission").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow(user.PermissionUsersManage))
+
+	assert.NoError(t, usr.LoadPermissions(db))
+	assert.True(t, hasPermission(usr, user.PermissionUsersManage))
+	assert.False(t, hasPermission(usr, user.PermissionRolesManage))
+}
@@ -0,0 +1,147 @@
+package sessionstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+)
+
+const (
+	findSessionQuery = `SELECT id, user_id, data, user_agent, ip, created_at, last_seen_at, expires_at FROM sessions WHERE id = $1 AND expires_at > $2`
+
+	// Saving also removes the sessions that have expired,
+	// so that the table does not grow without bounds.
+	saveSessionQuery = `WITH expired AS (DELETE FROM sessions WHERE expires_at <= $9) INSERT INTO sessions (id, user_id, data, user_agent, ip, created_at, last_seen_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (id) DO UPDATE SET user_id = EXCLUDED.user_id, data = EXCLUDED.data, user_agent = EXCLUDED.user_agent, ip = EXCLUDED.ip, last_seen_at = EXCLUDED.last_seen_at, expires_at = EXCLUDED.expires_at`
+
+	deleteSessionQuery      = `DELETE FROM sessions WHERE id = $1`
+	listUserSessionsQuery   = `SELECT id, user_id, data, user_agent, ip, created_at, last_seen_at, expires_at FROM sessions WHERE user_id = $1 AND expires_at > $2 ORDER BY last_seen_at DESC`
+	deleteUserSessionsQuery = `DELETE FROM sessions WHERE user_id = $1 AND id <> $2`
+)
+
+type DatabaseBackend struct {
+	db database.DatabaseInterface
+}
+
+func NewDatabaseBackend(db database.DatabaseInterface) *DatabaseBackend {
+	return &DatabaseBackend{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRecord(row rowScanner) (*Record, error) {
+	record := &Record{}
+	var userID sql.NullInt64
+
+	err := row.Scan(
+		&record.ID,
+		&userID,
+		&record.Data,
+		&record.UserAgent,
+		&record.IP,
+		&record.CreatedAt,
+		&record.LastSeenAt,
+		&record.ExpiresAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	record.UserID = int(userID.Int64)
+
+	return record, nil
+}
+
+func (b *DatabaseBackend) Get(_ context.Context, id string) (*Record, error) {
+	record, err := scanRecord(b.db.QueryRow(findSessionQuery, id, timeNow()))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to find session: %w", err)
+	}
+
+	return record, nil
+}
+
+func (b *DatabaseBackend) Save(_ context.Context, record *Record) error {
+	var userID sql.NullInt64
+
+	if record.UserID > 0 {
+		userID = sql.NullInt64{Int64: int64(record.UserID), Valid: true}
+	}
+
+	_, err := b.db.Exec(
+		saveSessionQuery,
+		record.ID,
+		userID,
+		record.Data,
+		record.UserAgent,
+		record.IP,
+		record.CreatedAt,
+		record.LastSeenAt,
+		record.ExpiresAt,
+		timeNow(),
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return nil
+}
+
+func (b *DatabaseBackend) Delete(_ context.Context, id string) error {
+	_, err := b.db.Exec(deleteSessionQuery, id)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	return nil
+}
+
+func (b *DatabaseBackend) ListByUser(_ context.Context, userID int) ([]*Record, error) {
+	rows, err := b.db.Query(listUserSessionsQuery, userID, timeNow())
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	records := []*Record{}
+
+	for rows.Next() {
+		record, err := scanRecord(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+
+		records = append(records, record)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	return records, nil
+}
+
+func (b *DatabaseBackend) DeleteByUser(_ context.Context, userID int, exceptID string) error {
+	_, err := b.db.Exec(deleteUserSessionsQuery, userID, exceptID)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	return nil
+}
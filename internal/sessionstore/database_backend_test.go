@@ -0,0 +1,219 @@
+package sessionstore
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var sessionColumns = []string{"id", "user_id", "data", "user_agent", "ip", "created_at", "last_seen_at", "expires_at"}
+
+func TestDatabaseBackendGet(t *testing.T) {
+	now := freezeTime(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		rows    *sqlmock.Rows
+		err     error
+		want    *Record
+		wantErr bool
+	}{
+		{
+			name: "success",
+			rows: sqlmock.NewRows(sessionColumns).AddRow("id", 1, []byte("data"), "agent", "192.0.2.1", *now, *now, now.Add(time.Hour)),
+			want: &Record{
+				ID:         "id",
+				UserID:     1,
+				Data:       []byte("data"),
+				UserAgent:  "agent",
+				IP:         "192.0.2.1",
+				CreatedAt:  *now,
+				LastSeenAt: *now,
+				ExpiresAt:  now.Add(time.Hour),
+			},
+		},
+		{
+			name: "anonymous",
+			rows: sqlmock.NewRows(sessionColumns).AddRow("id", nil, []byte("data"), "", "", *now, *now, now.Add(time.Hour)),
+			want: &Record{
+				ID:         "id",
+				Data:       []byte("data"),
+				CreatedAt:  *now,
+				LastSeenAt: *now,
+				ExpiresAt:  now.Add(time.Hour),
+			},
+		},
+		{
+			name: "not found",
+			err:  sql.ErrNoRows,
+		},
+		{
+			name:    "database error",
+			err:     sql.ErrConnDone,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			query := mock.ExpectQuery(regexp.QuoteMeta(findSessionQuery)).WithArgs("id", *now)
+
+			if tt.err != nil {
+				query.WillReturnError(tt.err)
+			} else {
+				query.WillReturnRows(tt.rows)
+			}
+
+			record, err := NewDatabaseBackend(db).Get(ctx, "id")
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, record)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDatabaseBackendSave(t *testing.T) {
+	now := freezeTime(t)
+	ctx := context.Background()
+
+	db, mock, _ := sqlmock.New()
+	defer func() { _ = db.Close() }()
+
+	backend := NewDatabaseBackend(db)
+	record := &Record{ID: "id", Data: []byte("data"), CreatedAt: *now, LastSeenAt: *now, ExpiresAt: now.Add(time.Hour)}
+
+	mock.ExpectExec(regexp.QuoteMeta(saveSessionQuery)).
+		WithArgs("id", sql.NullInt64{}, []byte("data"), "", "", *now, *now, now.Add(time.Hour), *now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(saveSessionQuery)).
+		WithArgs("id", sql.NullInt64{Int64: 1, Valid: true}, []byte("data"), "", "", *now, *now, now.Add(time.Hour), *now).
+		WillReturnError(sql.ErrConnDone)
+
+	assert.NoError(t, backend.Save(ctx, record))
+
+	record.UserID = 1
+	assert.ErrorIs(t, backend.Save(ctx, record), sql.ErrConnDone)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDatabaseBackendDelete(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec(regexp.QuoteMeta(deleteSessionQuery)).WithArgs("id").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(deleteSessionQuery)).WithArgs("id").WillReturnError(sql.ErrConnDone)
+
+	backend := NewDatabaseBackend(db)
+
+	assert.NoError(t, backend.Delete(context.Background(), "id"))
+	assert.ErrorIs(t, backend.Delete(context.Background(), "id"), sql.ErrConnDone)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDatabaseBackendListByUser(t *testing.T) {
+	now := freezeTime(t)
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantIDs   []string
+		wantErr   bool
+	}{
+		{
+			name: "success",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(listUserSessionsQuery)).
+					WithArgs(1, *now).
+					WillReturnRows(
+						sqlmock.NewRows(sessionColumns).
+							AddRow("first", 1, []byte{}, "", "", *now, *now, *now).
+							AddRow("second", 1, []byte{}, "", "", *now, *now, *now),
+					)
+			},
+			wantIDs: []string{"first", "second"},
+		},
+		{
+			name: "query error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(listUserSessionsQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+		{
+			name: "scan error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(listUserSessionsQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("first"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "rows error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(listUserSessionsQuery)).
+					WillReturnRows(
+						sqlmock.NewRows(sessionColumns).
+							AddRow("first", 1, []byte{}, "", "", *now, *now, *now).
+							RowError(0, sql.ErrConnDone),
+					)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			records, err := NewDatabaseBackend(db).ListByUser(context.Background(), 1)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			ids := []string{}
+
+			for _, record := range records {
+				ids = append(ids, record.ID)
+			}
+
+			assert.Equal(t, tt.wantIDs, ids)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDatabaseBackendDeleteByUser(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec(regexp.QuoteMeta(deleteUserSessionsQuery)).WithArgs(1, "current").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(deleteUserSessionsQuery)).WithArgs(1, "current").WillReturnError(sql.ErrConnDone)
+
+	backend := NewDatabaseBackend(db)
+
+	assert.NoError(t, backend.DeleteByUser(context.Background(), 1, "current"))
+	assert.ErrorIs(t, backend.DeleteByUser(context.Background(), 1, "current"), sql.ErrConnDone)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,55 @@
+package sessionstore
+
+import "strings"
+
+type userAgentMatch struct {
+	name    string
+	needles []string
+}
+
+// The order matters, since most browsers also claim to be the ones before them.
+var (
+	browsers = []userAgentMatch{
+		{"Edge", []string{"Edg/", "EdgA/", "EdgiOS/"}},
+		{"Opera", []string{"OPR/", "Opera"}},
+		{"Firefox", []string{"Firefox/", "FxiOS/"}},
+		{"Chrome", []string{"Chrome/", "CriOS/"}},
+		{"Safari", []string{"Safari/"}},
+	}
+
+	operatingSystems = []userAgentMatch{
+		{"Android", []string{"Android"}},
+		{"iOS", []string{"iPhone", "iPad", "iPod"}},
+		{"Windows", []string{"Windows"}},
+		{"macOS", []string{"Macintosh", "Mac OS X"}},
+		{"ChromeOS", []string{"CrOS"}},
+		{"Linux", []string{"Linux"}},
+	}
+)
+
+func (r *Record) Device() string {
+	return DeviceName(r.UserAgent)
+}
+
+func DeviceName(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := matchUserAgent(userAgent, browsers, "Unknown browser")
+	os := matchUserAgent(userAgent, operatingSystems, "an unknown system")
+
+	return browser + " on " + os
+}
+
+func matchUserAgent(userAgent string, matches []userAgentMatch, fallback string) string {
+	for _, match := range matches {
+		for _, needle := range match.needles {
+			if strings.Contains(userAgent, needle) {
+				return match.name
+			}
+		}
+	}
+
+	return fallback
+}
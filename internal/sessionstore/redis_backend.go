@@ -0,0 +1,167 @@
+package sessionstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/Dobefu/go-web-starter/internal/redis"
+	redisClient "github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeyPrefix     = "session:"
+	redisUserKeyPrefix = "user_sessions:"
+)
+
+type RedisBackend struct {
+	redis redis.RedisInterface
+}
+
+func NewRedisBackend(redis redis.RedisInterface) *RedisBackend {
+	return &RedisBackend{redis: redis}
+}
+
+func userKey(userID int) string {
+	return redisUserKeyPrefix + strconv.Itoa(userID)
+}
+
+func (b *RedisBackend) Get(ctx context.Context, id string) (*Record, error) {
+	cmd, err := b.redis.Get(ctx, redisKeyPrefix+id)
+
+	if err != nil {
+		if errors.Is(err, redisClient.Nil) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to find session: %w", err)
+	}
+
+	record := &Record{}
+	err = json.Unmarshal([]byte(cmd.Val()), record)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse session: %w", err)
+	}
+
+	return record, nil
+}
+
+func (b *RedisBackend) Save(ctx context.Context, record *Record) error {
+	ttl := record.ExpiresAt.Sub(timeNow())
+
+	if ttl <= 0 {
+		return b.Delete(ctx, record.ID)
+	}
+
+	value, err := json.Marshal(record)
+
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	_, err = b.redis.Set(ctx, redisKeyPrefix+record.ID, value, ttl)
+
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	if record.UserID > 0 {
+		_, err = b.redis.SAdd(ctx, userKey(record.UserID), record.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (b *RedisBackend) Delete(ctx context.Context, id string) error {
+	record, err := b.Get(ctx, id)
+
+	if err != nil || record == nil {
+		return err
+	}
+
+	return b.delete(ctx, record.UserID, id)
+}
+
+func (b *RedisBackend) delete(ctx context.Context, userID int, id string) error {
+	_, err := b.redis.Del(ctx, redisKeyPrefix+id)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	if userID > 0 {
+		_, err = b.redis.SRem(ctx, userKey(userID), id)
+
+		if err != nil {
+			return fmt.Errorf("failed to delete session: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (b *RedisBackend) ListByUser(ctx context.Context, userID int) ([]*Record, error) {
+	cmd, err := b.redis.SMembers(ctx, userKey(userID))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	records := []*Record{}
+
+	for _, id := range cmd.Val() {
+		record, err := b.Get(ctx, id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if record == nil {
+			_, err = b.redis.SRem(ctx, userKey(userID), id)
+
+			if err != nil {
+				return nil, fmt.Errorf("failed to list sessions: %w", err)
+			}
+
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	slices.SortFunc(records, func(a, b *Record) int {
+		return b.LastSeenAt.Compare(a.LastSeenAt)
+	})
+
+	return records, nil
+}
+
+func (b *RedisBackend) DeleteByUser(ctx context.Context, userID int, exceptID string) error {
+	cmd, err := b.redis.SMembers(ctx, userKey(userID))
+
+	if err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	for _, id := range cmd.Val() {
+		if id == exceptID {
+			continue
+		}
+
+		err = b.delete(ctx, userID, id)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
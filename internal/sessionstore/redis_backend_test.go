@@ -0,0 +1,287 @@
+package sessionstore
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/redis"
+	redisClient "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockRedis struct {
+	mock.Mock
+	redis.RedisInterface
+}
+
+func (m *mockRedis) Get(ctx context.Context, key string) (*redisClient.StringCmd, error) {
+	args := m.Called(ctx, key)
+	cmd := redisClient.NewStringCmd(ctx)
+	cmd.SetVal(args.String(0))
+
+	return cmd, args.Error(1)
+}
+
+func (m *mockRedis) Set(ctx context.Context, key string, value any, expiration time.Duration) (*redisClient.StatusCmd, error) {
+	args := m.Called(ctx, key, value, expiration)
+
+	return redisClient.NewStatusCmd(ctx), args.Error(0)
+}
+
+func (m *mockRedis) Del(ctx context.Context, keys ...string) (*redisClient.IntCmd, error) {
+	args := m.Called(ctx, keys)
+
+	return redisClient.NewIntCmd(ctx), args.Error(0)
+}
+
+func (m *mockRedis) SAdd(ctx context.Context, key string, members ...any) (*redisClient.IntCmd, error) {
+	args := m.Called(ctx, key, members)
+
+	return redisClient.NewIntCmd(ctx), args.Error(0)
+}
+
+func (m *mockRedis) SMembers(ctx context.Context, key string) (*redisClient.StringSliceCmd, error) {
+	args := m.Called(ctx, key)
+	cmd := redisClient.NewStringSliceCmd(ctx)
+	cmd.SetVal(args.Get(0).([]string))
+
+	return cmd, args.Error(1)
+}
+
+func (m *mockRedis) SRem(ctx context.Context, key string, members ...any) (*redisClient.IntCmd, error) {
+	args := m.Called(ctx, key, members)
+
+	return redisClient.NewIntCmd(ctx), args.Error(0)
+}
+
+func encodeRecord(t *testing.T, record *Record) string {
+	value, err := json.Marshal(record)
+	assert.NoError(t, err)
+
+	return string(value)
+}
+
+func TestRedisBackendGet(t *testing.T) {
+	now := freezeTime(t)
+	ctx := context.Background()
+	record := &Record{ID: "id", UserID: 1, Data: []byte("data"), CreatedAt: now.UTC(), LastSeenAt: now.UTC(), ExpiresAt: now.Add(time.Hour).UTC()}
+
+	tests := []struct {
+		name    string
+		value   string
+		err     error
+		want    *Record
+		wantErr bool
+	}{
+		{
+			name:  "success",
+			value: encodeRecord(t, record),
+			want:  record,
+		},
+		{
+			name: "not found",
+			err:  redisClient.Nil,
+		},
+		{
+			name:    "redis error",
+			err:     errBackend,
+			wantErr: true,
+		},
+		{
+			name:    "invalid value",
+			value:   "bogus",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockRedis{}
+			client.On("Get", ctx, "session:id").Return(tt.value, tt.err)
+
+			got, err := NewRedisBackend(client).Get(ctx, "id")
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRedisBackendSave(t *testing.T) {
+	now := freezeTime(t)
+	ctx := context.Background()
+
+	t.Run("anonymous", func(t *testing.T) {
+		client := &mockRedis{}
+		record := &Record{ID: "id", ExpiresAt: now.Add(time.Hour)}
+		value, _ := json.Marshal(record)
+
+		client.On("Set", ctx, "session:id", value, time.Hour).Return(nil)
+
+		assert.NoError(t, NewRedisBackend(client).Save(ctx, record))
+		client.AssertExpectations(t)
+	})
+
+	t.Run("user", func(t *testing.T) {
+		client := &mockRedis{}
+		record := &Record{ID: "id", UserID: 1, ExpiresAt: now.Add(time.Hour)}
+		value, _ := json.Marshal(record)
+
+		client.On("Set", ctx, "session:id", value, time.Hour).Return(nil)
+		client.On("SAdd", ctx, "user_sessions:1", []any{"id"}).Return(nil)
+
+		assert.NoError(t, NewRedisBackend(client).Save(ctx, record))
+		client.AssertExpectations(t)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		client := &mockRedis{}
+		client.On("Get", ctx, "session:id").Return("", redisClient.Nil)
+
+		assert.NoError(t, NewRedisBackend(client).Save(ctx, &Record{ID: "id", ExpiresAt: *now}))
+		client.AssertExpectations(t)
+	})
+
+	t.Run("set error", func(t *testing.T) {
+		client := &mockRedis{}
+		client.On("Set", ctx, "session:id", mock.Anything, time.Hour).Return(errBackend)
+
+		err := NewRedisBackend(client).Save(ctx, &Record{ID: "id", UserID: 1, ExpiresAt: now.Add(time.Hour)})
+		assert.ErrorIs(t, err, errBackend)
+	})
+
+	t.Run("index error", func(t *testing.T) {
+		client := &mockRedis{}
+		client.On("Set", ctx, "session:id", mock.Anything, time.Hour).Return(nil)
+		client.On("SAdd", ctx, "user_sessions:1", []any{"id"}).Return(errBackend)
+
+		err := NewRedisBackend(client).Save(ctx, &Record{ID: "id", UserID: 1, ExpiresAt: now.Add(time.Hour)})
+		assert.ErrorIs(t, err, errBackend)
+	})
+}
+
+func TestRedisBackendDelete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		client := &mockRedis{}
+		client.On("Get", ctx, "session:id").Return(encodeRecord(t, &Record{ID: "id", UserID: 1}), nil)
+		client.On("Del", ctx, []string{"session:id"}).Return(nil)
+		client.On("SRem", ctx, "user_sessions:1", []any{"id"}).Return(nil)
+
+		assert.NoError(t, NewRedisBackend(client).Delete(ctx, "id"))
+		client.AssertExpectations(t)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		client := &mockRedis{}
+		client.On("Get", ctx, "session:id").Return("", redisClient.Nil)
+
+		assert.NoError(t, NewRedisBackend(client).Delete(ctx, "id"))
+		client.AssertExpectations(t)
+	})
+
+	t.Run("del error", func(t *testing.T) {
+		client := &mockRedis{}
+		client.On("Get", ctx, "session:id").Return(encodeRecord(t, &Record{ID: "id", UserID: 1}), nil)
+		client.On("Del", ctx, []string{"session:id"}).Return(errBackend)
+
+		assert.ErrorIs(t, NewRedisBackend(client).Delete(ctx, "id"), errBackend)
+	})
+
+	t.Run("index error", func(t *testing.T) {
+		client := &mockRedis{}
+		client.On("Get", ctx, "session:id").Return(encodeRecord(t, &Record{ID: "id", UserID: 1}), nil)
+		client.On("Del", ctx, []string{"session:id"}).Return(nil)
+		client.On("SRem", ctx, "user_sessions:1", []any{"id"}).Return(errBackend)
+
+		assert.ErrorIs(t, NewRedisBackend(client).Delete(ctx, "id"), errBackend)
+	})
+}
+
+func TestRedisBackendListByUser(t *testing.T) {
+	now := freezeTime(t)
+	ctx := context.Background()
+
+	older := &Record{ID: "older", UserID: 1, LastSeenAt: now.Add(-time.Hour).UTC()}
+	newer := &Record{ID: "newer", UserID: 1, LastSeenAt: now.UTC()}
+
+	t.Run("success", func(t *testing.T) {
+		client := &mockRedis{}
+		client.On("SMembers", ctx, "user_sessions:1").Return([]string{"older", "expired", "newer"}, nil)
+		client.On("Get", ctx, "session:older").Return(encodeRecord(t, older), nil)
+		client.On("Get", ctx, "session:expired").Return("", redisClient.Nil)
+		client.On("Get", ctx, "session:newer").Return(encodeRecord(t, newer), nil)
+		client.On("SRem", ctx, "user_sessions:1", []any{"expired"}).Return(nil)
+
+		records, err := NewRedisBackend(client).ListByUser(ctx, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*Record{newer, older}, records)
+		client.AssertExpectations(t)
+	})
+
+	t.Run("members error", func(t *testing.T) {
+		client := &mockRedis{}
+		client.On("SMembers", ctx, "user_sessions:1").Return([]string{}, errBackend)
+
+		_, err := NewRedisBackend(client).ListByUser(ctx, 1)
+		assert.ErrorIs(t, err, errBackend)
+	})
+
+	t.Run("get error", func(t *testing.T) {
+		client := &mockRedis{}
+		client.On("SMembers", ctx, "user_sessions:1").Return([]string{"older"}, nil)
+		client.On("Get", ctx, "session:older").Return("", errBackend)
+
+		_, err := NewRedisBackend(client).ListByUser(ctx, 1)
+		assert.ErrorIs(t, err, errBackend)
+	})
+
+	t.Run("cleanup error", func(t *testing.T) {
+		client := &mockRedis{}
+		client.On("SMembers", ctx, "user_sessions:1").Return([]string{"expired"}, nil)
+		client.On("Get", ctx, "session:expired").Return("", redisClient.Nil)
+		client.On("SRem", ctx, "user_sessions:1", []any{"expired"}).Return(errBackend)
+
+		_, err := NewRedisBackend(client).ListByUser(ctx, 1)
+		assert.ErrorIs(t, err, errBackend)
+	})
+}
+
+func TestRedisBackendDeleteByUser(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		client := &mockRedis{}
+		client.On("SMembers", ctx, "user_sessions:1").Return([]string{"current", "other"}, nil)
+		client.On("Del", ctx, []string{"session:other"}).Return(nil)
+		client.On("SRem", ctx, "user_sessions:1", []any{"other"}).Return(nil)
+
+		assert.NoError(t, NewRedisBackend(client).DeleteByUser(ctx, 1, "current"))
+		client.AssertExpectations(t)
+	})
+
+	t.Run("members error", func(t *testing.T) {
+		client := &mockRedis{}
+		client.On("SMembers", ctx, "user_sessions:1").Return([]string{}, errBackend)
+
+		assert.ErrorIs(t, NewRedisBackend(client).DeleteByUser(ctx, 1, "current"), errBackend)
+	})
+
+	t.Run("delete error", func(t *testing.T) {
+		client := &mockRedis{}
+		client.On("SMembers", ctx, "user_sessions:1").Return([]string{"other"}, nil)
+		client.On("Del", ctx, []string{"session:other"}).Return(errBackend)
+
+		assert.ErrorIs(t, NewRedisBackend(client).DeleteByUser(ctx, 1, "current"), errBackend)
+	})
+}
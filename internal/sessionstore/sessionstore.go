@@ -0,0 +1,261 @@
+package sessionstore
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gorilla/securecookie"
+	gsessions "github.com/gorilla/sessions"
+)
+
+const (
+	sessionKeyUserID = "userID"
+
+	// sessionKeyRegenerate is never stored, it only asks Save for a new token.
+	sessionKeyRegenerate = "_regenerate"
+
+	userAgentMaxLength = 512
+
+	lastSeenInterval = time.Minute
+)
+
+type Record struct {
+	ID         string
+	UserID     int
+	Data       []byte
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+}
+
+func (r *Record) Value(key string) any {
+	values := map[any]any{}
+	err := securecookie.GobEncoder{}.Deserialize(r.Data, &values)
+
+	if err != nil {
+		return nil
+	}
+
+	return values[key]
+}
+
+type Backend interface {
+	Get(ctx context.Context, id string) (*Record, error)
+	Save(ctx context.Context, record *Record) error
+	Delete(ctx context.Context, id string) error
+	ListByUser(ctx context.Context, userID int) ([]*Record, error)
+	DeleteByUser(ctx context.Context, userID int, exceptID string) error
+}
+
+var timeNow = time.Now
+
+type clientIPKey struct{}
+
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// RecordID returns the ID under which the session with the given token is stored.
+// Only a hash of the token is stored, so that the sessions cannot be taken
+// over by anyone who can read the backend.
+func RecordID(token string) string {
+	if token == "" {
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func Regenerate(session sessions.Session) {
+	session.Set(sessionKeyRegenerate, true)
+}
+
+type Store struct {
+	backend Backend
+	codecs  []securecookie.Codec
+	options *gsessions.Options
+}
+
+func New(backend Backend, keyPairs ...[]byte) *Store {
+	return &Store{
+		backend: backend,
+		codecs:  securecookie.CodecsFromPairs(keyPairs...),
+		options: &gsessions.Options{Path: "/"},
+	}
+}
+
+func (s *Store) Options(options sessions.Options) {
+	s.options = options.ToGorillaOptions()
+}
+
+func (s *Store) Get(r *http.Request, name string) (*gsessions.Session, error) {
+	return gsessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *Store) New(r *http.Request, name string) (*gsessions.Session, error) {
+	session := gsessions.NewSession(s, name)
+	options := *s.options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+
+	if err != nil {
+		return session, nil
+	}
+
+	var token string
+
+	if securecookie.DecodeMulti(name, cookie.Value, &token, s.codecs...) != nil {
+		return session, nil
+	}
+
+	record, err := s.backend.Get(r.Context(), RecordID(token))
+
+	if err != nil || record == nil {
+		return session, err
+	}
+
+	err = securecookie.GobEncoder{}.Deserialize(record.Data, &session.Values)
+
+	if err != nil {
+		return session, err
+	}
+
+	session.ID = token
+	session.IsNew = false
+
+	if now := timeNow(); now.Sub(record.LastSeenAt) >= lastSeenInterval {
+		record.LastSeenAt = now
+		record.UserAgent = truncateUserAgent(r.UserAgent())
+		record.IP = ClientIP(r)
+
+		err = s.backend.Save(r.Context(), record)
+	}
+
+	return session, err
+}
+
+// Save stores the session and refreshes the cookie. The session gets a new token
+// whenever the user changes, so that a token from before a login cannot be used after it,
+// and when it has been asked for with Regenerate.
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *gsessions.Session) error {
+	ctx := r.Context()
+
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			err := s.backend.Delete(ctx, RecordID(session.ID))
+
+			if err != nil {
+				return err
+			}
+		}
+
+		http.SetCookie(w, gsessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	now := timeNow()
+	userID, _ := session.Values[sessionKeyUserID].(int)
+	regenerate, _ := session.Values[sessionKeyRegenerate].(bool)
+	createdAt := now
+
+	delete(session.Values, sessionKeyRegenerate)
+
+	if session.ID != "" {
+		existing, err := s.backend.Get(ctx, RecordID(session.ID))
+
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case existing == nil:
+			session.ID = ""
+		case existing.UserID != userID || regenerate:
+			err = s.backend.Delete(ctx, existing.ID)
+
+			if err != nil {
+				return err
+			}
+
+			session.ID = ""
+		default:
+			createdAt = existing.CreatedAt
+		}
+	}
+
+	if session.ID == "" {
+		session.ID = rand.Text()
+	}
+
+	data, err := securecookie.GobEncoder{}.Serialize(session.Values)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.backend.Save(ctx, &Record{
+		ID:         RecordID(session.ID),
+		UserID:     userID,
+		Data:       data,
+		UserAgent:  truncateUserAgent(r.UserAgent()),
+		IP:         ClientIP(r),
+		CreatedAt:  createdAt,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.lifetime(session)),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, gsessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+func (s *Store) lifetime(session *gsessions.Session) time.Duration {
+	if session.Options.MaxAge > 0 {
+		return time.Duration(session.Options.MaxAge) * time.Second
+	}
+
+	return 24 * time.Hour
+}
+
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > userAgentMaxLength {
+		return strings.ToValidUTF8(userAgent[:userAgentMaxLength], "")
+	}
+
+	return userAgent
+}
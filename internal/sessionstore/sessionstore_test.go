@@ -0,0 +1,410 @@
+package sessionstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gorilla/securecookie"
+	"github.com/stretchr/testify/assert"
+)
+
+var errBackend = errors.New("backend error")
+
+type memoryBackend struct {
+	records map[string]*Record
+	err     error
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{records: map[string]*Record{}}
+}
+
+func (b *memoryBackend) Get(_ context.Context, id string) (*Record, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	record, ok := b.records[id]
+
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *record
+	return &copied, nil
+}
+
+func (b *memoryBackend) Save(_ context.Context, record *Record) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	copied := *record
+	b.records[record.ID] = &copied
+
+	return nil
+}
+
+func (b *memoryBackend) Delete(_ context.Context, id string) error {
+	delete(b.records, id)
+	return b.err
+}
+
+func (b *memoryBackend) ListByUser(_ context.Context, userID int) ([]*Record, error) {
+	return nil, b.err
+}
+
+func (b *memoryBackend) DeleteByUser(_ context.Context, userID int, exceptID string) error {
+	return b.err
+}
+
+func freezeTime(t *testing.T) *time.Time {
+	now := time.Unix(1000000, 0)
+	timeNowOrig := timeNow
+
+	t.Cleanup(func() { timeNow = timeNowOrig })
+	timeNow = func() time.Time { return now }
+
+	return &now
+}
+
+func newTestStore(backend Backend) *Store {
+	store := New(backend, []byte("secret"))
+	store.Options(sessions.Options{Path: "/", MaxAge: 3600, HttpOnly: true})
+
+	return store
+}
+
+func newTestRequest(cookies ...*http.Cookie) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:140.0) Gecko/20100101 Firefox/140.0")
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+
+	return r
+}
+
+func saveSession(t *testing.T, store *Store, values map[any]any) *http.Cookie {
+	session, err := store.New(newTestRequest(), "session")
+	assert.NoError(t, err)
+
+	for key, value := range values {
+		session.Values[key] = value
+	}
+
+	w := httptest.NewRecorder()
+	assert.NoError(t, store.Save(newTestRequest(), w, session))
+
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+
+	return cookies[0]
+}
+
+func TestRecordID(t *testing.T) {
+	assert.Equal(t, "", RecordID(""))
+	assert.Len(t, RecordID("token"), 64)
+	assert.NotEqual(t, "token", RecordID("token"))
+	assert.Equal(t, RecordID("token"), RecordID("token"))
+}
+
+func TestRecordValue(t *testing.T) {
+	data, err := securecookie.GobEncoder{}.Serialize(map[any]any{"userID": 1})
+	assert.NoError(t, err)
+
+	record := &Record{Data: data}
+	assert.Equal(t, 1, record.Value("userID"))
+	assert.Nil(t, record.Value("other"))
+
+	record.Data = []byte("bogus")
+	assert.Nil(t, record.Value("userID"))
+}
+
+func TestClientIP(t *testing.T) {
+	r := newTestRequest()
+	assert.Equal(t, "192.0.2.1", ClientIP(r))
+
+	r.RemoteAddr = "bogus"
+	assert.Equal(t, "bogus", ClientIP(r))
+
+	r = r.WithContext(WithClientIP(r.Context(), "198.51.100.1"))
+	assert.Equal(t, "198.51.100.1", ClientIP(r))
+}
+
+func TestTruncateUserAgent(t *testing.T) {
+	assert.Equal(t, "agent", truncateUserAgent("agent"))
+	assert.Len(t, truncateUserAgent(strings.Repeat("a", 600)), userAgentMaxLength)
+	assert.Len(t, truncateUserAgent(strings.Repeat("a", 511)+"é"), userAgentMaxLength-1)
+}
+
+func TestStoreSaveAndLoad(t *testing.T) {
+	now := freezeTime(t)
+	backend := newMemoryBackend()
+	store := newTestStore(backend)
+
+	cookie := saveSession(t, store, map[any]any{"userID": 1, "key": "value"})
+	assert.Len(t, backend.records, 1)
+
+	var token string
+	assert.NoError(t, securecookie.DecodeMulti("session", cookie.Value, &token, store.codecs...))
+
+	record := backend.records[RecordID(token)]
+	assert.NotNil(t, record)
+	assert.Equal(t, 1, record.UserID)
+	assert.Equal(t, "192.0.2.1", record.IP)
+	assert.Contains(t, record.UserAgent, "Firefox")
+	assert.Equal(t, *now, record.CreatedAt)
+	assert.Equal(t, now.Add(time.Hour), record.ExpiresAt)
+
+	session, err := store.New(newTestRequest(cookie), "session")
+	assert.NoError(t, err)
+	assert.False(t, session.IsNew)
+	assert.Equal(t, token, session.ID)
+	assert.Equal(t, "value", session.Values["key"])
+}
+
+func TestStoreNew(t *testing.T) {
+	freezeTime(t)
+
+	tests := []struct {
+		name      string
+		cookie    func(store *Store, backend *memoryBackend) *http.Cookie
+		wantNew   bool
+		wantErr   bool
+		wantValue any
+	}{
+		{
+			name:    "no cookie",
+			cookie:  func(*Store, *memoryBackend) *http.Cookie { return nil },
+			wantNew: true,
+		},
+		{
+			name: "invalid cookie",
+			cookie: func(*Store, *memoryBackend) *http.Cookie {
+				return &http.Cookie{Name: "session", Value: "bogus"}
+			},
+			wantNew: true,
+		},
+		{
+			name: "revoked session",
+			cookie: func(store *Store, backend *memoryBackend) *http.Cookie {
+				cookie := saveSession(t, store, map[any]any{"key": "value"})
+				backend.records = map[string]*Record{}
+
+				return cookie
+			},
+			wantNew: true,
+		},
+		{
+			name: "backend error",
+			cookie: func(store *Store, backend *memoryBackend) *http.Cookie {
+				cookie := saveSession(t, store, map[any]any{"key": "value"})
+				backend.err = errBackend
+
+				return cookie
+			},
+			wantNew: true,
+			wantErr: true,
+		},
+		{
+			name: "corrupt data",
+			cookie: func(store *Store, backend *memoryBackend) *http.Cookie {
+				cookie := saveSession(t, store, map[any]any{"key": "value"})
+
+				for _, record := range backend.records {
+					record.Data = []byte("bogus")
+				}
+
+				return cookie
+			},
+			wantNew: true,
+			wantErr: true,
+		},
+		{
+			name: "existing session",
+			cookie: func(store *Store, backend *memoryBackend) *http.Cookie {
+				return saveSession(t, store, map[any]any{"key": "value"})
+			},
+			wantValue: "value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newMemoryBackend()
+			store := newTestStore(backend)
+
+			var cookies []*http.Cookie
+
+			if cookie := tt.cookie(store, backend); cookie != nil {
+				cookies = append(cookies, cookie)
+			}
+
+			session, err := store.New(newTestRequest(cookies...), "session")
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantNew, session.IsNew)
+			assert.Equal(t, tt.wantValue, session.Values["key"])
+			assert.Equal(t, 3600, session.Options.MaxAge)
+		})
+	}
+}
+
+func TestStoreNewUpdatesLastSeen(t *testing.T) {
+	now := freezeTime(t)
+	backend := newMemoryBackend()
+	store := newTestStore(backend)
+
+	cookie := saveSession(t, store, map[any]any{"userID": 1})
+	*now = now.Add(30 * time.Second)
+
+	r := newTestRequest(cookie)
+	r.RemoteAddr = "198.51.100.1:1234"
+
+	_, err := store.New(r, "session")
+	assert.NoError(t, err)
+
+	for _, record := range backend.records {
+		assert.Equal(t, "192.0.2.1", record.IP)
+	}
+
+	*now = now.Add(time.Minute)
+
+	_, err = store.New(r, "session")
+	assert.NoError(t, err)
+
+	for _, record := range backend.records {
+		assert.Equal(t, "198.51.100.1", record.IP)
+		assert.Equal(t, *now, record.LastSeenAt)
+		assert.Equal(t, now.Add(-90*time.Second), record.CreatedAt)
+	}
+}
+
+func TestStoreSaveRotatesTokenWhenTheUserChanges(t *testing.T) {
+	now := freezeTime(t)
+	backend := newMemoryBackend()
+	store := newTestStore(backend)
+
+	cookie := saveSession(t, store, map[any]any{"key": "value"})
+	session, err := store.New(newTestRequest(cookie), "session")
+	assert.NoError(t, err)
+
+	anonymousToken := session.ID
+	createdAt := *now
+	*now = now.Add(time.Minute)
+
+	session.Values["other"] = "value"
+	assert.NoError(t, store.Save(newTestRequest(), httptest.NewRecorder(), session))
+	assert.Equal(t, anonymousToken, session.ID)
+	assert.Equal(t, createdAt, backend.records[RecordID(session.ID)].CreatedAt)
+
+	session.Values["userID"] = 1
+	assert.NoError(t, store.Save(newTestRequest(), httptest.NewRecorder(), session))
+	assert.NotEqual(t, anonymousToken, session.ID)
+	assert.Len(t, backend.records, 1)
+	assert.Nil(t, backend.records[RecordID(anonymousToken)])
+	assert.Equal(t, *now, backend.records[RecordID(session.ID)].CreatedAt)
+}
+
+func TestStoreSaveRegeneratesToken(t *testing.T) {
+	freezeTime(t)
+	backend := newMemoryBackend()
+	store := newTestStore(backend)
+
+	cookie := saveSession(t, store, map[any]any{"userID": 1})
+	session, err := store.New(newTestRequest(cookie), "session")
+	assert.NoError(t, err)
+
+	oldToken := session.ID
+	session.Values[sessionKeyRegenerate] = true
+
+	assert.NoError(t, store.Save(newTestRequest(), httptest.NewRecorder(), session))
+	assert.NotEqual(t, oldToken, session.ID)
+	assert.Len(t, backend.records, 1)
+	assert.Nil(t, backend.records[RecordID(oldToken)])
+	assert.NotContains(t, session.Values, sessionKeyRegenerate)
+
+	// The request is not repeated on the next save.
+	newToken := session.ID
+	assert.NoError(t, store.Save(newTestRequest(), httptest.NewRecorder(), session))
+	assert.Equal(t, newToken, session.ID)
+}
+
+func TestStoreSaveErrors(t *testing.T) {
+	freezeTime(t)
+
+	t.Run("lookup error", func(t *testing.T) {
+		backend := newMemoryBackend()
+		store := newTestStore(backend)
+
+		cookie := saveSession(t, store, nil)
+		session, _ := store.New(newTestRequest(cookie), "session")
+		backend.err = errBackend
+
+		assert.ErrorIs(t, store.Save(newTestRequest(), httptest.NewRecorder(), session), errBackend)
+	})
+
+	t.Run("save error", func(t *testing.T) {
+		backend := newMemoryBackend()
+		store := newTestStore(backend)
+
+		session, _ := store.New(newTestRequest(), "session")
+		backend.err = errBackend
+
+		assert.ErrorIs(t, store.Save(newTestRequest(), httptest.NewRecorder(), session), errBackend)
+	})
+
+	t.Run("encode error", func(t *testing.T) {
+		store := newTestStore(newMemoryBackend())
+
+		session, _ := store.New(newTestRequest(), "session")
+		session.Values["key"] = func() {}
+
+		assert.Error(t, store.Save(newTestRequest(), httptest.NewRecorder(), session))
+	})
+}
+
+func TestStoreSaveDeletesExpiredSessions(t *testing.T) {
+	freezeTime(t)
+	backend := newMemoryBackend()
+	store := newTestStore(backend)
+
+	cookie := saveSession(t, store, map[any]any{"userID": 1})
+	session, err := store.Get(newTestRequest(cookie), "session")
+	assert.NoError(t, err)
+
+	session.Options.MaxAge = -1
+	w := httptest.NewRecorder()
+
+	assert.NoError(t, store.Save(newTestRequest(), w, session))
+	assert.Empty(t, backend.records)
+	assert.Equal(t, -1, w.Result().Cookies()[0].MaxAge)
+
+	backend.err = errBackend
+	session.ID = "token"
+	assert.ErrorIs(t, store.Save(newTestRequest(), httptest.NewRecorder(), session), errBackend)
+}
+
+func TestStoreLifetime(t *testing.T) {
+	freezeTime(t)
+	backend := newMemoryBackend()
+	store := New(backend, []byte("secret"))
+
+	session, _ := store.New(newTestRequest(), "session")
+	assert.NoError(t, store.Save(newTestRequest(), httptest.NewRecorder(), session))
+	assert.Equal(t, 24*time.Hour, store.lifetime(session))
+}
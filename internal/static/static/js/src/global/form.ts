@@ -5,7 +5,7 @@ const beforeUnloadHandler = (e: Event): void => {
 }
 
 const formElements = document.querySelectorAll<HTMLInputElement>(
-  ':is(input:not([type=search],[type=hidden]),textarea,[contenteditable]):not(data-ignore-dirty)',
+  ':is(input:not([type=search],[type=hidden]),textarea,[contenteditable]):not([data-ignore-dirty])',
 )
 
 formElements.forEach((formElement) => {
@@ -0,0 +1,176 @@
+interface PasskeyResponse {
+  error?: string
+  redirect?: string
+  publicKey?: Record<string, unknown>
+}
+
+const base64UrlToBuffer = (value: string): ArrayBuffer => {
+  const base64 = value.replace(/-/g, '+').replace(/_/g, '/')
+  const padded = base64.padEnd(
+    base64.length + ((4 - (base64.length % 4)) % 4),
+    '=',
+  )
+
+  return Uint8Array.from(atob(padded), (c) => c.charCodeAt(0)).buffer
+}
+
+const bufferToBase64Url = (buffer: ArrayBuffer): string =>
+  btoa(String.fromCharCode(...new Uint8Array(buffer)))
+    .replace(/\+/g, '-')
+    .replace(/\//g, '_')
+    .replace(/=+$/, '')
+
+const decodeCredentialList = (
+  list: unknown,
+): PublicKeyCredentialDescriptor[] | undefined => {
+  if (!Array.isArray(list)) {
+    return undefined
+  }
+
+  return list.map((descriptor: { id: string; type: 'public-key' }) => ({
+    ...descriptor,
+    id: base64UrlToBuffer(descriptor.id),
+  }))
+}
+
+const encodeCredential = (credential: PublicKeyCredential): object => {
+  const response = credential.response
+  const encoded: Record<string, unknown> = {
+    clientDataJSON: bufferToBase64Url(response.clientDataJSON),
+  }
+
+  if (response instanceof AuthenticatorAttestationResponse) {
+    encoded.attestationObject = bufferToBase64Url(response.attestationObject)
+    encoded.transports = response.getTransports()
+  }
+
+  if (response instanceof AuthenticatorAssertionResponse) {
+    encoded.authenticatorData = bufferToBase64Url(response.authenticatorData)
+    encoded.signature = bufferToBase64Url(response.signature)
+
+    if (response.userHandle) {
+      encoded.userHandle = bufferToBase64Url(response.userHandle)
+    }
+  }
+
+  return {
+    id: credential.id,
+    rawId: bufferToBase64Url(credential.rawId),
+    type: credential.type,
+    authenticatorAttachment: credential.authenticatorAttachment,
+    clientExtensionResults: credential.getClientExtensionResults(),
+    response: encoded,
+  }
+}
+
+const postJson = async (
+  url: string,
+  csrfToken: string,
+  body?: object,
+): Promise<PasskeyResponse> => {
+  const response = await fetch(url, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      'X-CSRF-Token': csrfToken,
+    },
+    body: body ? JSON.stringify(body) : undefined,
+  })
+
+  const data = await response.json()
+
+  if (!response.ok) {
+    throw new Error(data.error ?? 'Something went wrong. Please try again.')
+  }
+
+  return data
+}
+
+const startPasskeyCeremony = async (
+  container: HTMLElement,
+  isRegistration: boolean,
+): Promise<void> => {
+  const csrfToken = container.dataset.csrfToken ?? ''
+  const options = await postJson(
+    container.dataset.optionsUrl ?? '',
+    csrfToken,
+  )
+  const publicKey = options.publicKey
+
+  if (!publicKey) {
+    throw new Error('Something went wrong. Please try again.')
+  }
+
+  publicKey.challenge = base64UrlToBuffer(publicKey.challenge as string)
+  publicKey.allowCredentials = decodeCredentialList(publicKey.allowCredentials)
+  publicKey.excludeCredentials = decodeCredentialList(
+    publicKey.excludeCredentials,
+  )
+
+  if (isRegistration) {
+    const user = publicKey.user as { id: string }
+    publicKey.user = { ...user, id: base64UrlToBuffer(user.id) }
+  }
+
+  const credential = isRegistration
+    ? await navigator.credentials.create({
+        publicKey: publicKey as unknown as PublicKeyCredentialCreationOptions,
+      })
+    : await navigator.credentials.get({
+        publicKey: publicKey as unknown as PublicKeyCredentialRequestOptions,
+      })
+
+  if (!(credential instanceof PublicKeyCredential)) {
+    throw new Error('No passkey was selected.')
+  }
+
+  const submitUrl = new URL(
+    container.dataset.submitUrl ?? '',
+    window.location.href,
+  )
+  const name = container.querySelector<HTMLInputElement>('[data-passkey-name]')
+
+  if (name?.value) {
+    submitUrl.searchParams.set('name', name.value)
+  }
+
+  const result = await postJson(
+    submitUrl.toString(),
+    csrfToken,
+    encodeCredential(credential),
+  )
+
+  window.location.href = result.redirect ?? window.location.href
+}
+
+document
+  .querySelectorAll<HTMLElement>('[data-passkey-login],[data-passkey-register]')
+  .forEach((container) => {
+    const button = container.querySelector<HTMLButtonElement>('button')
+    const error = container.querySelector<HTMLElement>('[data-passkey-error]')
+
+    if (!button || !window.PublicKeyCredential) {
+      container.classList.add('hidden')
+      return
+    }
+
+    button.addEventListener('click', async () => {
+      button.disabled = true
+      error?.classList.add('hidden')
+
+      try {
+        await startPasskeyCeremony(
+          container,
+          container.hasAttribute('data-passkey-register'),
+        )
+      } catch (e) {
+        if (error) {
+          error.textContent =
+            e instanceof Error ? e.message : 'Something went wrong.'
+          error.classList.remove('hidden')
+        }
+      } finally {
+        button.disabled = false
+      }
+    })
+  })
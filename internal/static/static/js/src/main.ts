@@ -1,2 +1,3 @@
 import './components/layout/header'
 import './global/form'
+import './global/passkey'
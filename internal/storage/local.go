@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+type LocalStorage struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocalStorage(dir, baseURL string) *LocalStorage {
+	return &LocalStorage{dir: dir, baseURL: baseURL}
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes the file to a temporary file first, and moves it in place once
+// it is complete, so that a failed upload never leaves half a file behind.
+func (s *LocalStorage) Put(_ context.Context, key string, body io.Reader, _ string) error {
+	dest, err := s.path(key)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(dest), 0o755)
+
+	if err != nil {
+		return fmt.Errorf("failed to create the directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".upload-*")
+
+	if err != nil {
+		return fmt.Errorf("failed to create the file: %w", err)
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = io.Copy(tmp, body)
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write the file: %w", err)
+	}
+
+	err = os.Chmod(tmp.Name(), 0o644)
+
+	if err != nil {
+		return fmt.Errorf("failed to write the file: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), dest)
+
+	if err != nil {
+		return fmt.Errorf("failed to write the file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *LocalStorage) Delete(_ context.Context, key string) error {
+	dest, err := s.path(key)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(dest)
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete the file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return joinURL(s.baseURL, key)
+}
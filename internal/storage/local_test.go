@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStoragePut(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalStorage(dir, "/uploads")
+
+	err := store.Put(context.Background(), "avatars/1/photo.png", strings.NewReader("image"), "image/png")
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "avatars", "1", "photo.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "image", string(data))
+
+	err = store.Put(context.Background(), "avatars/1/photo.png", strings.NewReader("other"), "image/png")
+	assert.NoError(t, err)
+
+	data, err = os.ReadFile(filepath.Join(dir, "avatars", "1", "photo.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "other", string(data))
+}
+
+func TestLocalStoragePutErrors(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalStorage(dir, "/uploads")
+
+	err := store.Put(context.Background(), "../photo.png", strings.NewReader("image"), "image/png")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	err = store.Put(context.Background(), "photo.png", iotest.ErrReader(errors.New("read fail")), "image/png")
+	assert.Error(t, err)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "file"), nil, 0o644))
+
+	err = store.Put(context.Background(), "file/photo.png", strings.NewReader("image"), "image/png")
+	assert.Error(t, err)
+}
+
+func TestLocalStorageDelete(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalStorage(dir, "/uploads")
+
+	assert.NoError(t, store.Put(context.Background(), "photo.png", strings.NewReader("image"), "image/png"))
+	assert.NoError(t, store.Delete(context.Background(), "photo.png"))
+
+	_, err := os.Stat(filepath.Join(dir, "photo.png"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	assert.NoError(t, store.Delete(context.Background(), "photo.png"))
+	assert.ErrorIs(t, store.Delete(context.Background(), "/photo.png"), ErrInvalidKey)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "full", "sub"), 0o755))
+	assert.Error(t, store.Delete(context.Background(), "full"))
+}
+
+func TestLocalStorageURL(t *testing.T) {
+	assert.Equal(t, "/uploads/avatars/photo.png", NewLocalStorage("", "/uploads").URL("avatars/photo.png"))
+	assert.Equal(t, "/uploads/avatars/photo.png", NewLocalStorage("", "/uploads/").URL("avatars/photo.png"))
+	assert.Equal(t, "https://cdn.example.com/photo.png", NewLocalStorage("", "https://cdn.example.com").URL("photo.png"))
+}
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+)
+
+const (
+	s3Service       = "s3"
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	s3ClientTimeout = 30 * time.Second
+
+	s3ErrorMaxBytes = 512
+)
+
+var s3TimeNow = time.Now
+
+type S3Storage struct {
+	cfg    config.StorageS3
+	client *http.Client
+}
+
+func NewS3Storage(cfg config.StorageS3) *S3Storage {
+	return &S3Storage{
+		cfg:    cfg,
+		client: &http.Client{Timeout: s3ClientTimeout},
+	}
+}
+
+func (s *S3Storage) objectURL(key string) (*url.URL, error) {
+	endpoint, err := url.Parse(s.cfg.Endpoint)
+
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint: %q", s.cfg.Endpoint)
+	}
+
+	escapedKey := uriEncode(key, false)
+
+	if s.cfg.PathStyle {
+		return url.Parse(fmt.Sprintf("%s://%s/%s/%s", endpoint.Scheme, endpoint.Host, uriEncode(s.cfg.Bucket, true), escapedKey))
+	}
+
+	return url.Parse(fmt.Sprintf("%s://%s.%s/%s", endpoint.Scheme, s.cfg.Bucket, endpoint.Host, escapedKey))
+}
+
+// Put reads the whole body, since the signature of the request
+// includes a hash of it.
+func (s *S3Storage) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	data, err := io.ReadAll(body)
+
+	if err != nil {
+		return fmt.Errorf("failed to read the file: %w", err)
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, data)
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	return s.do(req, data, false)
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+
+	if err != nil {
+		return err
+	}
+
+	// The object being gone already is what a delete is meant to achieve.
+	return s.do(req, nil, true)
+}
+
+func (s *S3Storage) URL(key string) string {
+	if s.cfg.PublicURL != "" {
+		return joinURL(s.cfg.PublicURL, uriEncode(key, false))
+	}
+
+	objectURL, err := s.objectURL(key)
+
+	if err != nil {
+		return ""
+	}
+
+	return objectURL.String()
+}
+
+func (s *S3Storage) newRequest(ctx context.Context, method, key string, data []byte) (*http.Request, error) {
+	if !validKey(key) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+
+	objectURL, err := s.objectURL(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, objectURL.String(), body)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the request: %w", err)
+	}
+
+	return req, nil
+}
+
+func (s *S3Storage) do(req *http.Request, data []byte, allowNotFound bool) error {
+	hash := sha256.Sum256(data)
+	s.sign(req, hex.EncodeToString(hash[:]), s3TimeNow())
+
+	resp, err := s.client.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("S3 request failed: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 == 2 || (allowNotFound && resp.StatusCode == http.StatusNotFound) {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, s3ErrorMaxBytes))
+
+	return fmt.Errorf("S3 request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+}
+
+func (s *S3Storage) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	var canonicalHeaders strings.Builder
+
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, s.cfg.Region, s3Service)
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3Algorithm, amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm,
+		s.cfg.AccessKey,
+		scope,
+		signedHeaders,
+		signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+func uriEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+)
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+var (
+	ErrInvalidKey    = errors.New("invalid storage key")
+	ErrUnknownDriver = errors.New("unknown storage driver")
+)
+
+// Storage keeps uploaded files under a key, such as "avatars/1/photo.png".
+// Keys are relative, slash-separated paths. Deleting a file that
+// does not exist is not an error.
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+func FromConfig() (Storage, error) {
+	cfg := config.GetStorage()
+
+	switch strings.ToLower(cfg.Driver) {
+	case DriverLocal:
+		return NewLocalStorage(cfg.Local.Dir, cfg.Local.URL), nil
+	case DriverS3:
+		return NewS3Storage(cfg.S3), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownDriver, cfg.Driver)
+}
+
+// validKey rejects keys that could point outside of the storage,
+// such as absolute paths and paths that contain "..".
+func validKey(key string) bool {
+	if key == "" || strings.ContainsAny(key, "\\\x00") {
+		return false
+	}
+
+	return !path.IsAbs(key) && path.Clean(key) == key && !strings.HasPrefix(key, "../") && key != ".." && key != "."
+}
+
+func joinURL(base, key string) string {
+	return strings.TrimRight(base, "/") + "/" + key
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromConfig(t *testing.T) {
+	defer viper.Reset()
+
+	testCases := []struct {
+		name      string
+		driver    string
+		expectErr error
+		expected  any
+	}{
+		{name: "default", expected: &LocalStorage{}},
+		{name: "local", driver: "local", expected: &LocalStorage{}},
+		{name: "s3", driver: "S3", expected: &S3Storage{}},
+		{name: "unknown driver", driver: "ftp", expectErr: ErrUnknownDriver},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Reset()
+
+			if tc.driver != "" {
+				viper.Set("storage.driver", tc.driver)
+			}
+
+			store, err := FromConfig()
+
+			if tc.expectErr != nil {
+				assert.ErrorIs(t, err, tc.expectErr)
+				assert.Nil(t, store)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.IsType(t, tc.expected, store)
+		})
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	testCases := []struct {
+		key      string
+		expected bool
+	}{
+		{key: "avatars/1/photo.png", expected: true},
+		{key: "photo.png", expected: true},
+		{key: ""},
+		{key: "."},
+		{key: ".."},
+		{key: "../photo.png"},
+		{key: "avatars/../../photo.png"},
+		{key: "/etc/passwd"},
+		{key: "avatars//photo.png"},
+		{key: "avatars/"},
+		{key: "avatars\\photo.png"},
+		{key: "photo\x00.png"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			assert.Equal(t, tc.expected, validKey(tc.key))
+		})
+	}
+}
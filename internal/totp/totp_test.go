@@ -0,0 +1,110 @@
+package totp
+
+import (
+	"encoding/base32"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The shared secret from the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	_, err = decodeSecret(secret)
+	assert.NoError(t, err)
+}
+
+func TestGenerateSecretError(t *testing.T) {
+	origRandRead := randRead
+	defer func() { randRead = origRandRead }()
+
+	randRead = func(b []byte) (int, error) { return 0, errors.New("no entropy") }
+
+	_, err := GenerateSecret()
+	assert.ErrorContains(t, err, "no entropy")
+}
+
+func TestGenerateCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.want, func(t *testing.T) {
+			t.Parallel()
+
+			code, err := GenerateCode(rfcSecret, TimeStep(time.Unix(tc.unix, 0)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, code)
+		})
+	}
+}
+
+func TestGenerateCodeInvalidSecret(t *testing.T) {
+	_, err := GenerateCode("not base32!", 1)
+	assert.ErrorIs(t, err, ErrInvalidSecret)
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := TimeStep(now)
+
+	previous, _ := GenerateCode(rfcSecret, step-1)
+	tooOld, _ := GenerateCode(rfcSecret, step-2)
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOk   bool
+	}{
+		{"current step", "081804", 0, step, true},
+		{"with spaces", "081 804", 0, step, true},
+		{"previous step", previous, 0, step - 1, true},
+		{"outside of the skew", tooOld, 0, 0, false},
+		{"already used", "081804", step, 0, false},
+		{"wrong length", "12345", 0, 0, false},
+		{"wrong code", "000000", 0, 0, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotStep, ok := Validate(rfcSecret, tc.code, now, tc.lastStep)
+			assert.Equal(t, tc.wantOk, ok)
+			assert.Equal(t, tc.wantStep, gotStep)
+		})
+	}
+}
+
+func TestValidateInvalidSecret(t *testing.T) {
+	_, ok := Validate("!!", "123456", time.Now(), 0)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Go Web Starter", "user@example.com", "ABC")
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Go%20Web%20Starter:user@example.com?"))
+	assert.Contains(t, uri, "secret=ABC")
+	assert.Contains(t, uri, "issuer=Go+Web+Starter")
+	assert.Contains(t, uri, "digits=6")
+	assert.Contains(t, uri, "period=30")
+}
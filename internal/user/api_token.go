@@ -0,0 +1,213 @@
+package user
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+)
+
+const (
+	APITokenNameMaxLength = 64
+
+	// APITokenPrefix makes tokens easy to recognise,
+	// for example by secret scanners when one is committed by accident.
+	APITokenPrefix = "gws_"
+
+	APIScopeAccountRead  = "account:read"
+	APIScopeAccountWrite = "account:write"
+
+	apiTokenScopeSplitter = ","
+
+	apiTokenColumns           = `id, name, scopes, created_at, last_used_at`
+	findAPITokensByUserQuery  = `SELECT ` + apiTokenColumns + ` FROM user_api_tokens WHERE user_id = $1 ORDER BY created_at, id`
+	insertAPITokenQuery       = `INSERT INTO user_api_tokens (user_id, name, token_hash, scopes, created_at) VALUES ($1, $2, $3, $4, $5)`
+	deleteAPITokenQuery       = `DELETE FROM user_api_tokens WHERE id = $1 AND user_id = $2`
+	revokeAPITokensQuery      = `DELETE FROM user_api_tokens WHERE user_id = $1`
+	authenticateAPITokenQuery = `UPDATE user_api_tokens SET last_used_at = $1 WHERE token_hash = $2 RETURNING user_id, ` + apiTokenColumns
+)
+
+var APIScopes = []APIScope{
+	{Name: APIScopeAccountRead, Description: "Read your account details"},
+	{Name: APIScopeAccountWrite, Description: "Change and delete your account"},
+}
+
+var (
+	ErrAPITokenNotFound = errors.New("the API token could not be found")
+	ErrInvalidAPIToken  = errors.New("the API token is invalid or has been revoked")
+	ErrInvalidAPIScope  = errors.New("unknown API token scope")
+)
+
+var apiTokenTimeNow = time.Now
+
+type APIScope struct {
+	Name        string
+	Description string
+}
+
+type APIToken struct {
+	ID         int
+	Name       string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+func (token *APIToken) HasScope(scope string) bool {
+	return slices.Contains(token.Scopes, scope)
+}
+
+func isAPIScope(scope string) bool {
+	return slices.ContainsFunc(APIScopes, func(s APIScope) bool { return s.Name == scope })
+}
+
+func (user *User) CreateAPIToken(db database.DatabaseInterface, name string, scopes []string) (string, error) {
+	for _, scope := range scopes {
+		if !isAPIScope(scope) {
+			return "", fmt.Errorf("%w: %s", ErrInvalidAPIScope, scope)
+		}
+	}
+
+	token := APITokenPrefix + rand.Text()
+
+	_, err := db.Exec(
+		insertAPITokenQuery,
+		user.id,
+		name,
+		hashToken(token),
+		strings.Join(scopes, apiTokenScopeSplitter),
+		apiTokenTimeNow(),
+	)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to save API token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (user *User) GetAPITokens(db database.DatabaseInterface) ([]APIToken, error) {
+	rows, err := db.Query(findAPITokensByUserQuery, user.id)
+
+	if err != nil {
+		return nil, fmt.Errorf("error finding API tokens: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	tokens := []APIToken{}
+
+	for rows.Next() {
+		var token *APIToken
+		token, err = scanAPIToken(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, *token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error finding API tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (user *User) RevokeAPIToken(db database.DatabaseInterface, id int) error {
+	result, err := db.Exec(deleteAPITokenQuery, id, user.id)
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke API token: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrAPITokenNotFound
+	}
+
+	return nil
+}
+
+func (user *User) RevokeAPITokens(db database.DatabaseInterface) error {
+	_, err := db.Exec(revokeAPITokensQuery, user.id)
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke API tokens: %w", err)
+	}
+
+	return nil
+}
+
+func AuthenticateAPIToken(db database.DatabaseInterface, token string) (*User, *APIToken, error) {
+	if !strings.HasPrefix(token, APITokenPrefix) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	var userID int
+	apiToken, err := scanAPIToken(db.QueryRow(authenticateAPITokenQuery, apiTokenTimeNow(), hashToken(token)), &userID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrInvalidAPIToken
+		}
+
+		return nil, nil, err
+	}
+
+	user, err := FindByID(db, userID)
+
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil, ErrInvalidAPIToken
+		}
+
+		return nil, nil, err
+	}
+
+	if !user.status {
+		return nil, nil, ErrNotActive
+	}
+
+	return user, apiToken, nil
+}
+
+type apiTokenScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIToken(row apiTokenScanner, dest ...any) (*APIToken, error) {
+	token := &APIToken{}
+
+	var (
+		scopes     string
+		lastUsedAt sql.NullTime
+	)
+
+	err := row.Scan(append(dest, &token.ID, &token.Name, &scopes, &token.CreatedAt, &lastUsedAt)...)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("error scanning API token: %w", err)
+	}
+
+	token.Scopes = []string{}
+
+	if scopes != "" {
+		token.Scopes = strings.Split(scopes, apiTokenScopeSplitter)
+	}
+
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return token, nil
+}
@@ -0,0 +1,296 @@
+package user
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var apiTokenRowColumns = []string{"id", "name", "scopes", "created_at", "last_used_at"}
+
+func freezeAPITokenTime(t *testing.T) time.Time {
+	now := time.Unix(testUpdatedAtUnix, 0)
+	apiTokenTimeNowOrig := apiTokenTimeNow
+
+	t.Cleanup(func() { apiTokenTimeNow = apiTokenTimeNowOrig })
+	apiTokenTimeNow = func() time.Time { return now }
+
+	return now
+}
+
+func TestAPITokenHasScope(t *testing.T) {
+	token := &APIToken{Scopes: []string{APIScopeAccountRead}}
+
+	assert.True(t, token.HasScope(APIScopeAccountRead))
+	assert.False(t, token.HasScope(APIScopeAccountWrite))
+}
+
+func TestCreateAPIToken(t *testing.T) {
+	now := freezeAPITokenTime(t)
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(insertAPITokenQuery)).
+			WithArgs(testUserID, "CI", sqlmock.AnyArg(), "account:read,account:write", now).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		user := setupUserTests()
+		token, err := user.CreateAPIToken(db, "CI", []string{APIScopeAccountRead, APIScopeAccountWrite})
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(token, APITokenPrefix))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid scope", func(t *testing.T) {
+		user := setupUserTests()
+		_, err := user.CreateAPIToken(nil, "CI", []string{"admin"})
+
+		assert.ErrorIs(t, err, ErrInvalidAPIScope)
+	})
+
+	t.Run("save error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(insertAPITokenQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		_, err := user.CreateAPIToken(db, "CI", nil)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+}
+
+func TestGetAPITokens(t *testing.T) {
+	usedAt := time.Unix(testUpdatedAtUnix, 0)
+	createdAt := time.Unix(testCreatedAtUnix, 0)
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findAPITokensByUserQuery)).WithArgs(testUserID).WillReturnRows(
+			sqlmock.NewRows(apiTokenRowColumns).
+				AddRow(1, "CI", "account:read", createdAt, usedAt).
+				AddRow(2, "Unused", "", createdAt, nil),
+		)
+
+		user := setupUserTests()
+		tokens, err := user.GetAPITokens(db)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []APIToken{
+			{ID: 1, Name: "CI", Scopes: []string{APIScopeAccountRead}, CreatedAt: createdAt, LastUsedAt: &usedAt},
+			{ID: 2, Name: "Unused", Scopes: []string{}, CreatedAt: createdAt},
+		}, tokens)
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findAPITokensByUserQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		_, err := user.GetAPITokens(db)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+
+	t.Run("scan error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findAPITokensByUserQuery)).WillReturnRows(
+			sqlmock.NewRows([]string{"id"}).AddRow(1),
+		)
+
+		user := setupUserTests()
+		_, err := user.GetAPITokens(db)
+
+		assert.ErrorContains(t, err, "error scanning API token")
+	})
+
+	t.Run("rows error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findAPITokensByUserQuery)).WillReturnRows(
+			sqlmock.NewRows(apiTokenRowColumns).
+				AddRow(1, "CI", "", createdAt, nil).
+				RowError(0, sql.ErrConnDone),
+		)
+
+		user := setupUserTests()
+		_, err := user.GetAPITokens(db)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+}
+
+func TestRevokeAPIToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  sql.Result
+		err     error
+		wantErr error
+	}{
+		{name: "success", result: sqlmock.NewResult(0, 1)},
+		{name: "not found", result: sqlmock.NewResult(0, 0), wantErr: ErrAPITokenNotFound},
+		{name: "database error", err: sql.ErrConnDone, wantErr: sql.ErrConnDone},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			exec := mock.ExpectExec(regexp.QuoteMeta(deleteAPITokenQuery)).WithArgs(3, testUserID)
+
+			if tc.err != nil {
+				exec.WillReturnError(tc.err)
+			} else {
+				exec.WillReturnResult(tc.result)
+			}
+
+			user := setupUserTests()
+			err := user.RevokeAPIToken(db, 3)
+
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRevokeAPITokens(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(revokeAPITokensQuery)).WithArgs(testUserID).WillReturnResult(sqlmock.NewResult(0, 2))
+
+		user := setupUserTests()
+		assert.NoError(t, user.RevokeAPITokens(db))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(revokeAPITokensQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		assert.ErrorIs(t, user.RevokeAPITokens(db), sql.ErrConnDone)
+	})
+}
+
+func TestAuthenticateAPIToken(t *testing.T) {
+	now := freezeAPITokenTime(t)
+	createdAt := time.Unix(testCreatedAtUnix, 0)
+	token := APITokenPrefix + "token"
+
+	expectToken := func(mock sqlmock.Sqlmock) *sqlmock.ExpectedQuery {
+		return mock.ExpectQuery(regexp.QuoteMeta(authenticateAPITokenQuery)).WithArgs(now, hashToken(token))
+	}
+
+	tokenRow := func() *sqlmock.Rows {
+		return sqlmock.NewRows(append([]string{"user_id"}, apiTokenRowColumns...)).
+			AddRow(testUserID, 1, "CI", "account:read", createdAt, now)
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		setupMock func(mock sqlmock.Sqlmock)
+		wantErr   error
+		wantOther bool
+	}{
+		{
+			name:  "success",
+			token: token,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectToken(mock).WillReturnRows(tokenRow())
+				mock.ExpectQuery(regexp.QuoteMeta(findUserByIDQuery)).WithArgs(testUserID).
+					WillReturnRows(userRow(testUserID, testUsername, testEmail, "hash", true, createdAt, createdAt, createdAt))
+			},
+		},
+		{
+			name:      "missing prefix",
+			token:     "token",
+			setupMock: func(mock sqlmock.Sqlmock) {},
+			wantErr:   ErrInvalidAPIToken,
+		},
+		{
+			name:  "unknown token",
+			token: token,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectToken(mock).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrInvalidAPIToken,
+		},
+		{
+			name:  "database error",
+			token: token,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectToken(mock).WillReturnError(sql.ErrConnDone)
+			},
+			wantOther: true,
+		},
+		{
+			name:  "user not found",
+			token: token,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectToken(mock).WillReturnRows(tokenRow())
+				mock.ExpectQuery(regexp.QuoteMeta(findUserByIDQuery)).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrInvalidAPIToken,
+		},
+		{
+			name:  "inactive user",
+			token: token,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectToken(mock).WillReturnRows(tokenRow())
+				mock.ExpectQuery(regexp.QuoteMeta(findUserByIDQuery)).WithArgs(testUserID).
+					WillReturnRows(userRow(testUserID, testUsername, testEmail, "hash", false, createdAt, createdAt, createdAt))
+			},
+			wantErr: ErrNotActive,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tc.setupMock(mock)
+
+			user, apiToken, err := AuthenticateAPIToken(db, tc.token)
+
+			switch {
+			case tc.wantErr != nil:
+				assert.ErrorIs(t, err, tc.wantErr)
+			case tc.wantOther:
+				assert.Error(t, err)
+				assert.NotErrorIs(t, err, ErrInvalidAPIToken)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, testUserID, user.GetID())
+				assert.Equal(t, "CI", apiToken.Name)
+				assert.Equal(t, []string{APIScopeAccountRead}, apiToken.Scopes)
+				assert.Equal(t, now, *apiToken.LastUsedAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package user
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/avatar"
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/storage"
+)
+
+const updateUserAvatarQuery = `UPDATE users SET avatar = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL`
+
+var avatarTimeNow = time.Now
+
+func (user *User) GetAvatar() string {
+	return user.avatar
+}
+
+func (user *User) HasAvatar() bool {
+	return user.avatar != ""
+}
+
+func (user *User) GetAvatarURL(store storage.Storage, size int) string {
+	if user.avatar == "" || store == nil {
+		return ""
+	}
+
+	return store.URL(avatar.Key(user.avatar, size))
+}
+
+func (user *User) SetAvatar(db database.DatabaseInterface, key string) error {
+	now := avatarTimeNow()
+	result, err := db.Exec(updateUserAvatarQuery, key, now, user.id)
+
+	if err != nil {
+		return fmt.Errorf("failed to update avatar: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("user with ID %d not found", user.id)
+	}
+
+	user.avatar = key
+	user.updatedAt = now
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package user
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAvatarURL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		avatar   string
+		store    storage.Storage
+		expected string
+	}{
+		{name: "avatar", avatar: "avatars/1/abc", store: storage.NewLocalStorage("uploads", "/uploads"), expected: "/uploads/avatars/1/abc-64.png"},
+		{name: "no avatar", store: storage.NewLocalStorage("uploads", "/uploads")},
+		{name: "no storage", avatar: "avatars/1/abc"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := New(UserFields{Id: testUserID, Avatar: tc.avatar})
+
+			assert.Equal(t, tc.avatar, user.GetAvatar())
+			assert.Equal(t, tc.avatar != "", user.HasAvatar())
+			assert.Equal(t, tc.expected, user.GetAvatarURL(tc.store, 64))
+		})
+	}
+}
+
+func TestSetAvatar(t *testing.T) {
+	now := time.Unix(testUpdatedAtUnix+1, 0)
+	origAvatarTimeNow := avatarTimeNow
+	defer func() { avatarTimeNow = origAvatarTimeNow }()
+	avatarTimeNow = func() time.Time { return now }
+
+	testCases := []struct {
+		name      string
+		key       string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "set",
+			key:  "avatars/1/abc",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(updateUserAvatarQuery)).
+					WithArgs("avatars/1/abc", now, testUserID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "remove",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(updateUserAvatarQuery)).
+					WithArgs("", now, testUserID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "user not found",
+			key:  "avatars/1/abc",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(updateUserAvatarQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: true,
+		},
+		{
+			name: "database error",
+			key:  "avatars/1/abc",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(updateUserAvatarQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tc.mockSetup(mock)
+
+			user := setupUserTests()
+			user.avatar = "avatars/1/old"
+
+			err := user.SetAvatar(db, tc.key)
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tc.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, "avatars/1/old", user.GetAvatar())
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.key, user.GetAvatar())
+			assert.Equal(t, now, user.GetUpdatedAt())
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/lib/pq"
+)
+
+const (
+	pqUniqueViolation = "23505"
+
+	upsertEmailChangeQuery  = `INSERT INTO user_email_changes (user_id, new_email, old_email, requested_at, confirmed_at) VALUES ($1, $2, $3, $4, NULL) ON CONFLICT (user_id) DO UPDATE SET new_email = EXCLUDED.new_email, old_email = EXCLUDED.old_email, requested_at = EXCLUDED.requested_at, confirmed_at = NULL`
+	findPendingEmailQuery   = `SELECT new_email FROM user_email_changes WHERE user_id = $1 AND confirmed_at IS NULL AND requested_at > $2`
+	confirmEmailChangeQuery = `WITH change AS (UPDATE user_email_changes SET confirmed_at = $2 WHERE user_id = $1 AND confirmed_at IS NULL AND requested_at > $3 RETURNING new_email) UPDATE users SET email = change.new_email, updated_at = $2 FROM change WHERE users.id = $1 RETURNING users.email`
+	deleteEmailChangeQuery  = `DELETE FROM user_email_changes WHERE user_id = $1 RETURNING old_email, confirmed_at IS NOT NULL`
+	updateUserEmailQuery    = `UPDATE users SET email = $1, updated_at = $2 WHERE id = $3`
+)
+
+var (
+	ErrNoEmailChange = errors.New("there is no email address change to confirm")
+	ErrEmailTaken    = errors.New("this email address is already in use")
+)
+
+var emailChangeTimeNow = time.Now
+
+func (user *User) RequestEmailChange(db database.DatabaseInterface, newEmail string) error {
+	_, err := db.Exec(upsertEmailChangeQuery, user.id, newEmail, user.email, emailChangeTimeNow())
+
+	if err != nil {
+		return fmt.Errorf("failed to request email change: %w", err)
+	}
+
+	return nil
+}
+
+func (user *User) GetPendingEmail(db database.DatabaseInterface) (string, error) {
+	var email string
+	err := db.QueryRow(findPendingEmailQuery, user.id, emailChangeTimeNow().Add(-TokenTTLEmailChange)).Scan(&email)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("failed to get pending email: %w", err)
+	}
+
+	return email, nil
+}
+
+func (user *User) ConfirmEmailChange(db database.DatabaseInterface) error {
+	now := emailChangeTimeNow()
+
+	var email string
+	err := db.QueryRow(confirmEmailChangeQuery, user.id, now, now.Add(-TokenTTLEmailChange)).Scan(&email)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoEmailChange
+		}
+
+		if isUniqueViolation(err) {
+			return ErrEmailTaken
+		}
+
+		return fmt.Errorf("failed to confirm email change: %w", err)
+	}
+
+	user.email = email
+	user.updatedAt = now
+
+	return nil
+}
+
+func (user *User) RevertEmailChange(db database.DatabaseInterface) error {
+	tx, err := db.Begin()
+
+	if err != nil {
+		return fmt.Errorf("failed to revert email change: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	var oldEmail string
+	var confirmed bool
+	err = tx.QueryRow(deleteEmailChangeQuery, user.id).Scan(&oldEmail, &confirmed)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoEmailChange
+		}
+
+		return fmt.Errorf("failed to revert email change: %w", err)
+	}
+
+	now := emailChangeTimeNow()
+
+	if confirmed {
+		_, err = tx.Exec(updateUserEmailQuery, oldEmail, now, user.id)
+
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrEmailTaken
+			}
+
+			return fmt.Errorf("failed to revert email change: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to revert email change: %w", err)
+	}
+
+	if confirmed {
+		user.email = oldEmail
+		user.updatedAt = now
+	}
+
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation
+}
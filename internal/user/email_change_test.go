@@ -0,0 +1,268 @@
+package user
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func freezeEmailChangeTime(t *testing.T) time.Time {
+	now := time.Unix(testUpdatedAtUnix, 0)
+	emailChangeTimeNowOrig := emailChangeTimeNow
+
+	t.Cleanup(func() { emailChangeTimeNow = emailChangeTimeNowOrig })
+	emailChangeTimeNow = func() time.Time { return now }
+
+	return now
+}
+
+func TestRequestEmailChange(t *testing.T) {
+	now := freezeEmailChangeTime(t)
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(upsertEmailChangeQuery)).
+			WithArgs(testUserID, "new@example.com", testEmail, now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		user := setupUserTests()
+		assert.NoError(t, user.RequestEmailChange(db, "new@example.com"))
+		assert.Equal(t, testEmail, user.GetEmail())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(upsertEmailChangeQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		assert.ErrorIs(t, user.RequestEmailChange(db, "new@example.com"), sql.ErrConnDone)
+	})
+}
+
+func TestGetPendingEmail(t *testing.T) {
+	now := freezeEmailChangeTime(t)
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "pending",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findPendingEmailQuery)).
+					WithArgs(testUserID, now.Add(-TokenTTLEmailChange)).
+					WillReturnRows(sqlmock.NewRows([]string{"new_email"}).AddRow("new@example.com"))
+			},
+			want: "new@example.com",
+		},
+		{
+			name: "none",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findPendingEmailQuery)).WillReturnError(sql.ErrNoRows)
+			},
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findPendingEmailQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			user := setupUserTests()
+			email, err := user.GetPendingEmail(db)
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, sql.ErrConnDone)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, email)
+		})
+	}
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	now := freezeEmailChangeTime(t)
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   error
+		wantEmail string
+	}{
+		{
+			name: "success",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(confirmEmailChangeQuery)).
+					WithArgs(testUserID, now, now.Add(-TokenTTLEmailChange)).
+					WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("new@example.com"))
+			},
+			wantEmail: "new@example.com",
+		},
+		{
+			name: "no pending change",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(confirmEmailChangeQuery)).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr:   ErrNoEmailChange,
+			wantEmail: testEmail,
+		},
+		{
+			name: "email taken",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(confirmEmailChangeQuery)).WillReturnError(&pq.Error{Code: pqUniqueViolation})
+			},
+			wantErr:   ErrEmailTaken,
+			wantEmail: testEmail,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(confirmEmailChangeQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr:   sql.ErrConnDone,
+			wantEmail: testEmail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			user := setupUserTests()
+			err := user.ConfirmEmailChange(db)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantEmail, user.GetEmail())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRevertEmailChange(t *testing.T) {
+	now := freezeEmailChangeTime(t)
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   error
+		wantEmail string
+	}{
+		{
+			name: "confirmed change",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(deleteEmailChangeQuery)).
+					WithArgs(testUserID).
+					WillReturnRows(sqlmock.NewRows([]string{"old_email", "confirmed"}).AddRow("old@example.com", true))
+				mock.ExpectExec(regexp.QuoteMeta(updateUserEmailQuery)).
+					WithArgs("old@example.com", now, testUserID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantEmail: "old@example.com",
+		},
+		{
+			name: "pending change",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(deleteEmailChangeQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"old_email", "confirmed"}).AddRow(testEmail, false))
+				mock.ExpectCommit()
+			},
+			wantEmail: testEmail,
+		},
+		{
+			name: "no change",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(deleteEmailChangeQuery)).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			wantErr:   ErrNoEmailChange,
+			wantEmail: testEmail,
+		},
+		{
+			name: "old email taken",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(deleteEmailChangeQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"old_email", "confirmed"}).AddRow("old@example.com", true))
+				mock.ExpectExec(regexp.QuoteMeta(updateUserEmailQuery)).WillReturnError(&pq.Error{Code: pqUniqueViolation})
+				mock.ExpectRollback()
+			},
+			wantErr:   ErrEmailTaken,
+			wantEmail: testEmail,
+		},
+		{
+			name: "begin error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin().WillReturnError(sql.ErrConnDone)
+			},
+			wantErr:   sql.ErrConnDone,
+			wantEmail: testEmail,
+		},
+		{
+			name: "commit error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(deleteEmailChangeQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"old_email", "confirmed"}).AddRow(testEmail, false))
+				mock.ExpectCommit().WillReturnError(sql.ErrConnDone)
+			},
+			wantErr:   sql.ErrConnDone,
+			wantEmail: testEmail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			user := setupUserTests()
+			err := user.RevertEmailChange(db)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantEmail, user.GetEmail())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+)
+
+const (
+	findUserByIdentityQuery = `SELECT u.id, u.username, u.email, u.password, u.status, u.created_at, u.updated_at, u.last_login, u.avatar FROM users u JOIN user_identities i ON i.user_id = u.id WHERE i.provider = $1 AND i.subject = $2 AND u.deleted_at IS NULL`
+	insertIdentityQuery     = `INSERT INTO user_identities (user_id, provider, subject, email, created_at, last_used_at) VALUES ($1, $2, $3, $4, $5, $5)`
+	updateIdentityQuery     = `UPDATE user_identities SET email = $1, last_used_at = $2 WHERE provider = $3 AND subject = $4`
+	findIdentitiesQuery     = `SELECT provider, subject, email, created_at, last_used_at FROM user_identities WHERE user_id = $1 ORDER BY created_at, id`
+)
+
+var ErrIdentityNotFound = errors.New("no user is linked to this identity")
+
+type Identity struct {
+	Provider   string
+	Subject    string
+	Email      string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+func FindByIdentity(db database.DatabaseInterface, provider, subject string) (*User, error) {
+	user := &User{}
+	row := db.QueryRow(findUserByIdentityQuery, provider, subject)
+
+	err := row.Scan(
+		&user.id,
+		&user.username,
+		&user.email,
+		&user.password,
+		&user.status,
+		&user.createdAt,
+		&user.updatedAt,
+		&user.lastLogin,
+		&user.avatar,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIdentityNotFound
+		}
+
+		return nil, fmt.Errorf("error finding user by identity: %w", err)
+	}
+
+	return user, nil
+}
+
+func (user *User) LinkIdentity(db database.DatabaseInterface, provider, subject, email string) error {
+	_, err := db.Exec(insertIdentityQuery, user.id, provider, subject, email, time.Now())
+
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	return nil
+}
+
+func UpdateIdentity(db database.DatabaseInterface, provider, subject, email string) error {
+	_, err := db.Exec(updateIdentityQuery, email, time.Now(), provider, subject)
+
+	if err != nil {
+		return fmt.Errorf("failed to update identity: %w", err)
+	}
+
+	return nil
+}
+
+func (user *User) GetIdentities(db database.DatabaseInterface) ([]Identity, error) {
+	rows, err := db.Query(findIdentitiesQuery, user.id)
+
+	if err != nil {
+		return nil, fmt.Errorf("error finding identities: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	identities := []Identity{}
+
+	for rows.Next() {
+		var (
+			identity   Identity
+			lastUsedAt sql.NullTime
+		)
+
+		err = rows.Scan(&identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt, &lastUsedAt)
+
+		if err != nil {
+			return nil, fmt.Errorf("error scanning identity: %w", err)
+		}
+
+		if lastUsedAt.Valid {
+			identity.LastUsedAt = &lastUsedAt.Time
+		}
+
+		identities = append(identities, identity)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error finding identities: %w", err)
+	}
+
+	return identities, nil
+}
@@ -0,0 +1,167 @@
+package user
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindByIdentity(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "success",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findUserByIdentityQuery)).
+					WithArgs("company", "subject").
+					WillReturnRows(userRow(testUserID, testUsername, testEmail, "hash", true, now, now, now))
+			},
+		},
+		{
+			name: "not linked",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findUserByIdentityQuery)).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrIdentityNotFound,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findUserByIdentityQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			user, err := FindByIdentity(db, "company", "subject")
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, user)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, testUserID, user.GetID())
+			assert.Equal(t, testEmail, user.GetEmail())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLinkIdentity(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(insertIdentityQuery)).
+			WithArgs(testUserID, "company", "subject", testEmail, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		user := setupUserTests()
+		assert.NoError(t, user.LinkIdentity(db, "company", "subject", testEmail))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(insertIdentityQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		assert.ErrorIs(t, user.LinkIdentity(db, "company", "subject", testEmail), sql.ErrConnDone)
+	})
+}
+
+func TestUpdateIdentity(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(updateIdentityQuery)).
+			WithArgs(testEmail, sqlmock.AnyArg(), "company", "subject").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, UpdateIdentity(db, "company", "subject", testEmail))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(updateIdentityQuery)).WillReturnError(sql.ErrConnDone)
+
+		assert.ErrorIs(t, UpdateIdentity(db, "company", "subject", testEmail), sql.ErrConnDone)
+	})
+}
+
+func TestGetIdentities(t *testing.T) {
+	now := time.Now()
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findIdentitiesQuery)).
+			WithArgs(testUserID).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"provider", "subject", "email", "created_at", "last_used_at"}).
+					AddRow("company", "subject", testEmail, now, now).
+					AddRow("other", "subject2", testEmail, now, nil),
+			)
+
+		user := setupUserTests()
+		identities, err := user.GetIdentities(db)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Identity{
+			{Provider: "company", Subject: "subject", Email: testEmail, CreatedAt: now, LastUsedAt: &now},
+			{Provider: "other", Subject: "subject2", Email: testEmail, CreatedAt: now},
+		}, identities)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findIdentitiesQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		_, err := user.GetIdentities(db)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+
+	t.Run("scan error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findIdentitiesQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"provider"}).AddRow("company"))
+
+		user := setupUserTests()
+		_, err := user.GetIdentities(db)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,200 @@
+package user
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+)
+
+const (
+	InviteTTL = 7 * 24 * time.Hour
+
+	inviteColumns             = `i.id, i.email, COALESCE(r.name, ''), COALESCE(i.inviter_user_id, 0), i.created_at, i.expires_at`
+	deletePendingInvitesQuery = `DELETE FROM user_invites WHERE email = $1 AND accepted_at IS NULL`
+	insertInviteQuery         = `INSERT INTO user_invites (email, role_id, inviter_user_id, token_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	findInviteQuery           = `SELECT ` + inviteColumns + ` FROM user_invites i LEFT JOIN roles r ON r.id = i.role_id WHERE i.token_hash = $1 AND i.accepted_at IS NULL AND i.expires_at > $2`
+	findInvitesByUserQuery    = `SELECT ` + inviteColumns + ` FROM user_invites i LEFT JOIN roles r ON r.id = i.role_id WHERE i.inviter_user_id = $1 AND i.accepted_at IS NULL AND i.expires_at > $2 ORDER BY i.created_at DESC, i.id DESC`
+	acceptInviteQuery         = `UPDATE user_invites SET accepted_at = $1, accepted_user_id = $2 WHERE id = $3 AND accepted_at IS NULL AND expires_at > $1 RETURNING role_id`
+	revokeInviteQuery         = `DELETE FROM user_invites WHERE id = $1 AND inviter_user_id = $2 AND accepted_at IS NULL`
+)
+
+var (
+	ErrInvalidInvite  = errors.New("the invitation is invalid or has expired")
+	ErrInviteNotFound = errors.New("the invitation could not be found")
+)
+
+var inviteTimeNow = time.Now
+
+type Invite struct {
+	ID        int
+	Email     string
+	Role      string
+	InviterID int
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+func CreateInvite(db database.DatabaseInterface, inviterID int, email string, role string) (string, error) {
+	var roleID sql.NullInt64
+
+	if role != "" {
+		id, err := findRoleID(db, role)
+
+		if err != nil {
+			return "", err
+		}
+
+		roleID = sql.NullInt64{Int64: int64(id), Valid: true}
+	}
+
+	_, err := db.Exec(deletePendingInvitesQuery, email)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to revoke previous invitations: %w", err)
+	}
+
+	token := rand.Text()
+	now := inviteTimeNow()
+
+	_, err = db.Exec(
+		insertInviteQuery,
+		email,
+		roleID,
+		sql.NullInt64{Int64: int64(inviterID), Valid: inviterID > 0},
+		hashToken(token),
+		now,
+		now.Add(InviteTTL),
+	)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to save invitation: %w", err)
+	}
+
+	return token, nil
+}
+
+func FindInvite(db database.DatabaseInterface, token string) (*Invite, error) {
+	if token == "" {
+		return nil, ErrInvalidInvite
+	}
+
+	invite, err := scanInvite(db.QueryRow(findInviteQuery, hashToken(token), inviteTimeNow()))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidInvite
+		}
+
+		return nil, fmt.Errorf("error finding invitation: %w", err)
+	}
+
+	return invite, nil
+}
+
+func (user *User) GetInvites(db database.DatabaseInterface) ([]Invite, error) {
+	rows, err := db.Query(findInvitesByUserQuery, user.id, inviteTimeNow())
+
+	if err != nil {
+		return nil, fmt.Errorf("error finding invitations: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	invites := []Invite{}
+
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("error scanning invitation: %w", err)
+		}
+
+		invites = append(invites, *invite)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error finding invitations: %w", err)
+	}
+
+	return invites, nil
+}
+
+func (user *User) RevokeInvite(db database.DatabaseInterface, id int) error {
+	result, err := db.Exec(revokeInviteQuery, id, user.id)
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrInviteNotFound
+	}
+
+	return nil
+}
+
+func (invite *Invite) Accept(db database.DatabaseInterface, user *User) error {
+	tx, err := db.Begin()
+
+	if err != nil {
+		return fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	now := inviteTimeNow()
+
+	var roleID sql.NullInt64
+	err = tx.QueryRow(acceptInviteQuery, now, user.id, invite.ID).Scan(&roleID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidInvite
+		}
+
+		return fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	if roleID.Valid {
+		_, err = tx.Exec(grantRoleQuery, user.id, roleID.Int64, now)
+
+		if err != nil {
+			return fmt.Errorf("failed to grant the role of the invitation: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	user.permissions = nil
+
+	return nil
+}
+
+type inviteScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanInvite(row inviteScanner) (*Invite, error) {
+	invite := &Invite{}
+
+	err := row.Scan(
+		&invite.ID,
+		&invite.Email,
+		&invite.Role,
+		&invite.InviterID,
+		&invite.CreatedAt,
+		&invite.ExpiresAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
@@ -0,0 +1,331 @@
+package user
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var inviteColumnNames = []string{"id", "email", "role", "inviter_user_id", "created_at", "expires_at"}
+
+func freezeInviteTime(t *testing.T) time.Time {
+	now := time.Unix(testUpdatedAtUnix, 0)
+	inviteTimeNowOrig := inviteTimeNow
+
+	t.Cleanup(func() { inviteTimeNow = inviteTimeNowOrig })
+	inviteTimeNow = func() time.Time { return now }
+
+	return now
+}
+
+func TestCreateInvite(t *testing.T) {
+	now := freezeInviteTime(t)
+
+	tests := []struct {
+		name      string
+		role      string
+		setupMock func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "without role",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(deletePendingInvitesQuery)).WithArgs(testEmail).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(insertInviteQuery)).
+					WithArgs(testEmail, sql.NullInt64{}, sql.NullInt64{Int64: testUserID, Valid: true}, sqlmock.AnyArg(), now, now.Add(InviteTTL)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{
+			name: "with role",
+			role: RoleAdmin,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findRoleIDQuery)).WithArgs(RoleAdmin).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec(regexp.QuoteMeta(deletePendingInvitesQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(insertInviteQuery)).
+					WithArgs(testEmail, sql.NullInt64{Int64: 3, Valid: true}, sqlmock.AnyArg(), sqlmock.AnyArg(), now, now.Add(InviteTTL)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{
+			name: "unknown role",
+			role: "unknown",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findRoleIDQuery)).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrRoleNotFound,
+		},
+		{
+			name: "revoke error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(deletePendingInvitesQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name: "insert error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(deletePendingInvitesQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(insertInviteQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			token, err := CreateInvite(db, testUserID, testEmail, tt.role)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, token)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestFindInvite(t *testing.T) {
+	now := freezeInviteTime(t)
+
+	tests := []struct {
+		name      string
+		token     string
+		setupMock func(mock sqlmock.Sqlmock)
+		want      *Invite
+		wantErr   error
+	}{
+		{
+			name:  "success",
+			token: "token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findInviteQuery)).
+					WithArgs(hashToken("token"), now).
+					WillReturnRows(sqlmock.NewRows(inviteColumnNames).AddRow(1, testEmail, RoleAdmin, testUserID, now, now.Add(InviteTTL)))
+			},
+			want: &Invite{ID: 1, Email: testEmail, Role: RoleAdmin, InviterID: testUserID, CreatedAt: now, ExpiresAt: now.Add(InviteTTL)},
+		},
+		{
+			name:      "empty token",
+			setupMock: func(mock sqlmock.Sqlmock) {},
+			wantErr:   ErrInvalidInvite,
+		},
+		{
+			name:  "not found",
+			token: "token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findInviteQuery)).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrInvalidInvite,
+		},
+		{
+			name:  "database error",
+			token: "token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findInviteQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			invite, err := FindInvite(db, tt.token)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, invite)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetInvites(t *testing.T) {
+	now := freezeInviteTime(t)
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findInvitesByUserQuery)).
+			WithArgs(testUserID, now).
+			WillReturnRows(sqlmock.NewRows(inviteColumnNames).AddRow(1, testEmail, "", testUserID, now, now.Add(InviteTTL)))
+
+		user := setupUserTests()
+		invites, err := user.GetInvites(db)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Invite{{ID: 1, Email: testEmail, InviterID: testUserID, CreatedAt: now, ExpiresAt: now.Add(InviteTTL)}}, invites)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findInvitesByUserQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		_, err := user.GetInvites(db)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+
+	t.Run("scan error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findInvitesByUserQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		user := setupUserTests()
+		_, err := user.GetInvites(db)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestRevokeInvite(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  sql.Result
+		err     error
+		wantErr error
+	}{
+		{name: "success", result: sqlmock.NewResult(0, 1)},
+		{name: "not found", result: sqlmock.NewResult(0, 0), wantErr: ErrInviteNotFound},
+		{name: "database error", err: sql.ErrConnDone, wantErr: sql.ErrConnDone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			expectation := mock.ExpectExec(regexp.QuoteMeta(revokeInviteQuery)).WithArgs(2, testUserID)
+
+			if tt.err != nil {
+				expectation.WillReturnError(tt.err)
+			} else {
+				expectation.WillReturnResult(tt.result)
+			}
+
+			user := setupUserTests()
+			err := user.RevokeInvite(db, 2)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAcceptInvite(t *testing.T) {
+	now := freezeInviteTime(t)
+
+	tests := []struct {
+		name      string
+		setupMock func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "without role",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(acceptInviteQuery)).
+					WithArgs(now, testUserID, 5).
+					WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(nil))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "with role",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(acceptInviteQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(3))
+				mock.ExpectExec(regexp.QuoteMeta(grantRoleQuery)).
+					WithArgs(testUserID, int64(3), now).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "already accepted",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(acceptInviteQuery)).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			wantErr: ErrInvalidInvite,
+		},
+		{
+			name: "begin error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin().WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name: "accept error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(acceptInviteQuery)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name: "grant error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(acceptInviteQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(3))
+				mock.ExpectExec(regexp.QuoteMeta(grantRoleQuery)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name: "commit error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(acceptInviteQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(nil))
+				mock.ExpectCommit().WillReturnError(sql.ErrTxDone)
+			},
+			wantErr: sql.ErrTxDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.setupMock(mock)
+
+			user := setupUserTests()
+			invite := &Invite{ID: 5, Email: testEmail}
+			err := invite.Accept(db, &user)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
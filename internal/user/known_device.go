@@ -0,0 +1,115 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+)
+
+const (
+	// Addresses in the same network are treated as the same location,
+	// so that a new address from the same provider is not a new device.
+	knownDeviceIPv4Bits = 24
+	knownDeviceIPv6Bits = 48
+
+	hasKnownDevicesQuery   = `SELECT EXISTS (SELECT 1 FROM user_known_devices WHERE user_id = $1)`
+	rememberDeviceQuery    = `INSERT INTO user_known_devices (user_id, fingerprint, user_agent, ip, first_seen_at, last_seen_at) VALUES ($1, $2, $3, $4, $5, $5) ON CONFLICT (user_id, fingerprint) DO UPDATE SET user_agent = EXCLUDED.user_agent, ip = EXCLUDED.ip, last_seen_at = EXCLUDED.last_seen_at RETURNING first_seen_at = last_seen_at`
+	forgetKnownDeviceQuery = `DELETE FROM user_known_devices WHERE user_id = $1 AND fingerprint = $2`
+	findKnownDevicesQuery  = `SELECT user_agent, ip, first_seen_at, last_seen_at FROM user_known_devices WHERE user_id = $1 ORDER BY last_seen_at DESC, id DESC`
+)
+
+type KnownDevice struct {
+	UserAgent   string
+	IP          string
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time
+}
+
+var knownDeviceTimeNow = time.Now
+
+// DeviceFingerprint identifies a device by its user agent and the network
+// of its IP address. Only a hash is returned, so it can be put in a link.
+func DeviceFingerprint(userAgent string, ip string) string {
+	hash := sha256.Sum256([]byte(userAgent + "\x00" + ipNetwork(ip)))
+	return hex.EncodeToString(hash[:])
+}
+
+func ipNetwork(ip string) string {
+	parsed := net.ParseIP(ip)
+
+	if parsed == nil {
+		return ip
+	}
+
+	if ipv4 := parsed.To4(); ipv4 != nil {
+		return ipv4.Mask(net.CIDRMask(knownDeviceIPv4Bits, 32)).String()
+	}
+
+	return parsed.Mask(net.CIDRMask(knownDeviceIPv6Bits, 128)).String()
+}
+
+func (user *User) HasKnownDevices(db database.DatabaseInterface) (bool, error) {
+	var exists bool
+	err := db.QueryRow(hasKnownDevicesQuery, user.id).Scan(&exists)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to find known devices: %w", err)
+	}
+
+	return exists, nil
+}
+
+func (user *User) RememberDevice(db database.DatabaseInterface, userAgent string, ip string) (bool, error) {
+	var isNew bool
+	err := db.QueryRow(rememberDeviceQuery, user.id, DeviceFingerprint(userAgent, ip), userAgent, ip, knownDeviceTimeNow()).Scan(&isNew)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to remember device: %w", err)
+	}
+
+	return isNew, nil
+}
+
+func (user *User) ForgetDevice(db database.DatabaseInterface, fingerprint string) error {
+	_, err := db.Exec(forgetKnownDeviceQuery, user.id, fingerprint)
+
+	if err != nil {
+		return fmt.Errorf("failed to forget device: %w", err)
+	}
+
+	return nil
+}
+
+func (user *User) GetKnownDevices(db database.DatabaseInterface) ([]KnownDevice, error) {
+	rows, err := db.Query(findKnownDevicesQuery, user.id)
+
+	if err != nil {
+		return nil, fmt.Errorf("error finding known devices: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	devices := []KnownDevice{}
+
+	for rows.Next() {
+		var device KnownDevice
+
+		err = rows.Scan(&device.UserAgent, &device.IP, &device.FirstSeenAt, &device.LastSeenAt)
+
+		if err != nil {
+			return nil, fmt.Errorf("error scanning known device: %w", err)
+		}
+
+		devices = append(devices, device)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error finding known devices: %w", err)
+	}
+
+	return devices, nil
+}
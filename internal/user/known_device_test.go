@@ -0,0 +1,247 @@
+package user
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testUserAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0"
+
+func TestDeviceFingerprint(t *testing.T) {
+	testCases := []struct {
+		name      string
+		userAgent string
+		ip        string
+		other     string
+		same      bool
+	}{
+		{name: "same IPv4 network", userAgent: testUserAgent, ip: "203.0.113.5", other: "203.0.113.200", same: true},
+		{name: "other IPv4 network", userAgent: testUserAgent, ip: "203.0.113.5", other: "198.51.100.5"},
+		{name: "same IPv6 network", userAgent: testUserAgent, ip: "2001:db8:1::1", other: "2001:db8:1:ffff::1", same: true},
+		{name: "other IPv6 network", userAgent: testUserAgent, ip: "2001:db8:1::1", other: "2001:db8:2::1"},
+		{name: "invalid address", userAgent: testUserAgent, ip: "unknown", other: "unknown", same: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fingerprint := DeviceFingerprint(tc.userAgent, tc.ip)
+
+			assert.Len(t, fingerprint, 64)
+			assert.Equal(t, tc.same, fingerprint == DeviceFingerprint(tc.userAgent, tc.other))
+			assert.NotEqual(t, fingerprint, DeviceFingerprint("curl/8.0", tc.ip))
+		})
+	}
+}
+
+func TestHasKnownDevices(t *testing.T) {
+	testCases := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		expected  bool
+		wantErr   bool
+	}{
+		{
+			name: "known devices",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(hasKnownDevicesQuery)).
+					WithArgs(testUserID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			expected: true,
+		},
+		{
+			name: "no known devices",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(hasKnownDevicesQuery)).
+					WithArgs(testUserID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(hasKnownDevicesQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tc.mockSetup(mock)
+
+			user := setupUserTests()
+			known, err := user.HasKnownDevices(db)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected, known)
+		})
+	}
+}
+
+func TestRememberDevice(t *testing.T) {
+	now := time.Unix(testUpdatedAtUnix, 0)
+	origKnownDeviceTimeNow := knownDeviceTimeNow
+	defer func() { knownDeviceTimeNow = origKnownDeviceTimeNow }()
+	knownDeviceTimeNow = func() time.Time { return now }
+
+	fingerprint := DeviceFingerprint(testUserAgent, "203.0.113.5")
+
+	testCases := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		expected  bool
+		wantErr   bool
+	}{
+		{
+			name: "new device",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(rememberDeviceQuery)).
+					WithArgs(testUserID, fingerprint, testUserAgent, "203.0.113.5", now).
+					WillReturnRows(sqlmock.NewRows([]string{"new"}).AddRow(true))
+			},
+			expected: true,
+		},
+		{
+			name: "known device",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(rememberDeviceQuery)).
+					WithArgs(testUserID, fingerprint, testUserAgent, "203.0.113.5", now).
+					WillReturnRows(sqlmock.NewRows([]string{"new"}).AddRow(false))
+			},
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(rememberDeviceQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tc.mockSetup(mock)
+
+			user := setupUserTests()
+			isNew, err := user.RememberDevice(db, testUserAgent, "203.0.113.5")
+			assert.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected, isNew)
+		})
+	}
+}
+
+func TestForgetDevice(t *testing.T) {
+	testCases := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "success",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(forgetKnownDeviceQuery)).
+					WithArgs(testUserID, "fingerprint").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(forgetKnownDeviceQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tc.mockSetup(mock)
+
+			user := setupUserTests()
+			err := user.ForgetDevice(db, "fingerprint")
+			assert.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestGetKnownDevices(t *testing.T) {
+	now := time.Now()
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findKnownDevicesQuery)).
+			WithArgs(testUserID).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"user_agent", "ip", "first_seen_at", "last_seen_at"}).
+					AddRow(testUserAgent, "192.0.2.1", now, now),
+			)
+
+		user := setupUserTests()
+		devices, err := user.GetKnownDevices(db)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []KnownDevice{{UserAgent: testUserAgent, IP: "192.0.2.1", FirstSeenAt: now, LastSeenAt: now}}, devices)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findKnownDevicesQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		_, err := user.GetKnownDevices(db)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+
+	t.Run("scan error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findKnownDevicesQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"user_agent"}).AddRow(testUserAgent))
+
+		user := setupUserTests()
+		_, err := user.GetKnownDevices(db)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("rows error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findKnownDevicesQuery)).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"user_agent", "ip", "first_seen_at", "last_seen_at"}).
+					AddRow(testUserAgent, "192.0.2.1", now, now).
+					RowError(0, sql.ErrConnDone),
+			)
+
+		user := setupUserTests()
+		_, err := user.GetKnownDevices(db)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+}
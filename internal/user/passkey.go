@@ -0,0 +1,303 @@
+package user
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	PasskeyNameMaxLength     = 64
+	passkeyTransportSplitter = ","
+
+	// The handle is random, as recommended by the WebAuthn specification,
+	// which also limits it to 64 bytes.
+	passkeyUserHandleLength = 64
+
+	findUserByPasskeyUserHandleQuery = `SELECT id, username, email, password, status, created_at, updated_at, last_login, avatar FROM users WHERE passkey_user_handle = $1 AND deleted_at IS NULL`
+
+	// The handle is only generated once, so that the passkeys
+	// of the user keep pointing to them.
+	passkeyUserHandleQuery = `UPDATE users SET passkey_user_handle = COALESCE(passkey_user_handle, $1) WHERE id = $2 RETURNING passkey_user_handle`
+
+	passkeyColumns          = `id, name, credential_id, public_key, attestation_type, attestation_format, transports, flags, aaguid, sign_count, clone_warning, attachment, attestation, created_at, last_used_at`
+	findPasskeysByUserQuery = `SELECT ` + passkeyColumns + ` FROM user_passkeys WHERE user_id = $1 ORDER BY created_at, id`
+	insertPasskeyQuery      = `INSERT INTO user_passkeys (user_id, name, credential_id, public_key, attestation_type, attestation_format, transports, flags, aaguid, sign_count, clone_warning, attachment, attestation, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+	updatePasskeyUsageQuery = `UPDATE user_passkeys SET sign_count = $1, clone_warning = $2, flags = $3, last_used_at = $4 WHERE user_id = $5 AND credential_id = $6`
+	renamePasskeyQuery      = `UPDATE user_passkeys SET name = $1 WHERE id = $2 AND user_id = $3`
+	deletePasskeyQuery      = `DELETE FROM user_passkeys WHERE id = $1 AND user_id = $2`
+)
+
+var ErrPasskeyNotFound = errors.New("the passkey could not be found")
+
+var passkeyTimeNow = time.Now
+
+type Passkey struct {
+	ID         int
+	Name       string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	Credential webauthn.Credential
+}
+
+type WebAuthnUser struct {
+	*User
+	handle   []byte
+	passkeys []Passkey
+}
+
+func (u *WebAuthnUser) WebAuthnID() []byte {
+	return u.handle
+}
+
+func (u *WebAuthnUser) WebAuthnName() string {
+	return u.email
+}
+
+func (u *WebAuthnUser) WebAuthnDisplayName() string {
+	return u.username
+}
+
+func (u *WebAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.passkeys))
+
+	for i, passkey := range u.passkeys {
+		credentials[i] = passkey.Credential
+	}
+
+	return credentials
+}
+
+func FindByPasskeyUserHandle(db database.DatabaseInterface, handle []byte) (*WebAuthnUser, error) {
+	if len(handle) == 0 || len(handle) > passkeyUserHandleLength {
+		return nil, ErrPasskeyNotFound
+	}
+
+	user := &User{}
+	row := db.QueryRow(findUserByPasskeyUserHandleQuery, handle)
+
+	err := row.Scan(
+		&user.id,
+		&user.username,
+		&user.email,
+		&user.password,
+		&user.status,
+		&user.createdAt,
+		&user.updatedAt,
+		&user.lastLogin,
+		&user.avatar,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPasskeyNotFound
+		}
+
+		return nil, fmt.Errorf("error finding user by passkey user handle: %w", err)
+	}
+
+	passkeys, err := user.GetPasskeys(db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnUser{User: user, handle: handle, passkeys: passkeys}, nil
+}
+
+func (user *User) GetWebAuthnUser(db database.DatabaseInterface) (*WebAuthnUser, error) {
+	passkeys, err := user.GetPasskeys(db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	handle := make([]byte, passkeyUserHandleLength)
+	_, _ = rand.Read(handle)
+
+	err = db.QueryRow(passkeyUserHandleQuery, handle, user.id).Scan(&handle)
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting passkey user handle: %w", err)
+	}
+
+	return &WebAuthnUser{User: user, handle: handle, passkeys: passkeys}, nil
+}
+
+func (user *User) GetPasskeys(db database.DatabaseInterface) ([]Passkey, error) {
+	rows, err := db.Query(findPasskeysByUserQuery, user.id)
+
+	if err != nil {
+		return nil, fmt.Errorf("error finding passkeys: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	passkeys := []Passkey{}
+
+	for rows.Next() {
+		passkey, err := scanPasskey(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		passkeys = append(passkeys, *passkey)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error finding passkeys: %w", err)
+	}
+
+	return passkeys, nil
+}
+
+func scanPasskey(rows *sql.Rows) (*Passkey, error) {
+	passkey := &Passkey{}
+	credential := &passkey.Credential
+
+	var (
+		transports  string
+		flags       int16
+		signCount   int64
+		attachment  string
+		attestation []byte
+		lastUsedAt  sql.NullTime
+	)
+
+	err := rows.Scan(
+		&passkey.ID,
+		&passkey.Name,
+		&credential.ID,
+		&credential.PublicKey,
+		&credential.AttestationType,
+		&credential.AttestationFormat,
+		&transports,
+		&flags,
+		&credential.Authenticator.AAGUID,
+		&signCount,
+		&credential.Authenticator.CloneWarning,
+		&attachment,
+		&attestation,
+		&passkey.CreatedAt,
+		&lastUsedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("error scanning passkey: %w", err)
+	}
+
+	if transports != "" {
+		for transport := range strings.SplitSeq(transports, passkeyTransportSplitter) {
+			credential.Transport = append(credential.Transport, protocol.AuthenticatorTransport(transport))
+		}
+	}
+
+	credential.Flags = webauthn.NewCredentialFlags(protocol.AuthenticatorFlags(flags))
+	credential.Authenticator.SignCount = uint32(signCount)
+	credential.Authenticator.Attachment = protocol.AuthenticatorAttachment(attachment)
+
+	if len(attestation) > 0 {
+		err = json.Unmarshal(attestation, &credential.Attestation)
+
+		if err != nil {
+			return nil, fmt.Errorf("error decoding passkey attestation: %w", err)
+		}
+	}
+
+	if lastUsedAt.Valid {
+		passkey.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return passkey, nil
+}
+
+func (user *User) AddPasskey(db database.DatabaseInterface, name string, credential *webauthn.Credential) error {
+	attestation, err := json.Marshal(credential.Attestation)
+
+	if err != nil {
+		return fmt.Errorf("failed to encode passkey attestation: %w", err)
+	}
+
+	transports := make([]string, len(credential.Transport))
+
+	for i, transport := range credential.Transport {
+		transports[i] = string(transport)
+	}
+
+	_, err = db.Exec(insertPasskeyQuery,
+		user.id,
+		name,
+		credential.ID,
+		credential.PublicKey,
+		credential.AttestationType,
+		credential.AttestationFormat,
+		strings.Join(transports, passkeyTransportSplitter),
+		int16(credential.Flags.ProtocolValue()),
+		credential.Authenticator.AAGUID,
+		int64(credential.Authenticator.SignCount),
+		credential.Authenticator.CloneWarning,
+		string(credential.Authenticator.Attachment),
+		attestation,
+		passkeyTimeNow(),
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to save passkey: %w", err)
+	}
+
+	return nil
+}
+
+func (user *User) UpdatePasskeyUsage(db database.DatabaseInterface, credential *webauthn.Credential) error {
+	_, err := db.Exec(updatePasskeyUsageQuery,
+		int64(credential.Authenticator.SignCount),
+		credential.Authenticator.CloneWarning,
+		int16(credential.Flags.ProtocolValue()),
+		passkeyTimeNow(),
+		user.id,
+		credential.ID,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update passkey: %w", err)
+	}
+
+	return nil
+}
+
+func (user *User) RenamePasskey(db database.DatabaseInterface, id int, name string) error {
+	result, err := db.Exec(renamePasskeyQuery, name, id, user.id)
+
+	if err != nil {
+		return fmt.Errorf("failed to rename passkey: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrPasskeyNotFound
+	}
+
+	return nil
+}
+
+func (user *User) DeletePasskey(db database.DatabaseInterface, id int) error {
+	result, err := db.Exec(deletePasskeyQuery, id, user.id)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete passkey: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrPasskeyNotFound
+	}
+
+	return nil
+}
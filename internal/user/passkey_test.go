@@ -0,0 +1,389 @@
+package user
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+)
+
+var passkeyRowColumns = []string{
+	"id", "name", "credential_id", "public_key", "attestation_type", "attestation_format", "transports",
+	"flags", "aaguid", "sign_count", "clone_warning", "attachment", "attestation", "created_at", "last_used_at",
+}
+
+func testCredential() *webauthn.Credential {
+	return &webauthn.Credential{
+		ID:                []byte("credential-id"),
+		PublicKey:         []byte("public-key"),
+		AttestationType:   "none",
+		AttestationFormat: "none",
+		Transport:         []protocol.AuthenticatorTransport{protocol.Internal, protocol.Hybrid},
+		Flags:             webauthn.NewCredentialFlags(protocol.FlagUserPresent | protocol.FlagUserVerified),
+		Authenticator: webauthn.Authenticator{
+			AAGUID:     []byte("aaguid"),
+			SignCount:  3,
+			Attachment: protocol.Platform,
+		},
+		Attestation: webauthn.CredentialAttestation{
+			ClientDataJSON: []byte("{}"),
+		},
+	}
+}
+
+var testPasskeyUserHandle = []byte("random passkey user handle")
+
+func expectPasskeyUserHandle(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(passkeyUserHandleQuery)).
+		WithArgs(sqlmock.AnyArg(), testUserID).
+		WillReturnRows(sqlmock.NewRows([]string{"passkey_user_handle"}).AddRow(testPasskeyUserHandle))
+}
+
+func passkeyRow(lastUsedAt any) *sqlmock.Rows {
+	return sqlmock.NewRows(passkeyRowColumns).AddRow(
+		7, "Laptop", []byte("credential-id"), []byte("public-key"), "none", "none", "internal,hybrid",
+		int64(protocol.FlagUserPresent|protocol.FlagUserVerified), []byte("aaguid"), int64(3), false, "platform",
+		[]byte(`{"clientDataJSON":"e30="}`), time.Unix(testCreatedAtUnix, 0), lastUsedAt,
+	)
+}
+
+func TestWebAuthnUser(t *testing.T) {
+	user := setupUserTests()
+	webAuthnUser := &WebAuthnUser{User: &user, handle: testPasskeyUserHandle}
+
+	assert.Equal(t, testPasskeyUserHandle, webAuthnUser.WebAuthnID())
+	assert.Equal(t, testEmail, webAuthnUser.WebAuthnName())
+	assert.Equal(t, testUsername, webAuthnUser.WebAuthnDisplayName())
+	assert.Empty(t, webAuthnUser.WebAuthnCredentials())
+}
+
+func TestGetPasskeys(t *testing.T) {
+	usedAt := time.Unix(testUpdatedAtUnix, 0)
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findPasskeysByUserQuery)).WithArgs(testUserID).WillReturnRows(passkeyRow(usedAt))
+
+		user := setupUserTests()
+		passkeys, err := user.GetPasskeys(db)
+		assert.NoError(t, err)
+		assert.Len(t, passkeys, 1)
+
+		passkey := passkeys[0]
+		want := testCredential()
+
+		assert.Equal(t, 7, passkey.ID)
+		assert.Equal(t, "Laptop", passkey.Name)
+		assert.Equal(t, usedAt, *passkey.LastUsedAt)
+		assert.Equal(t, want.ID, passkey.Credential.ID)
+		assert.Equal(t, want.Transport, passkey.Credential.Transport)
+		assert.Equal(t, want.Flags, passkey.Credential.Flags)
+		assert.Equal(t, want.Authenticator, passkey.Credential.Authenticator)
+		assert.Equal(t, want.Attestation.ClientDataJSON, passkey.Credential.Attestation.ClientDataJSON)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("never used", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findPasskeysByUserQuery)).WillReturnRows(passkeyRow(nil))
+		expectPasskeyUserHandle(mock)
+
+		user := setupUserTests()
+		webAuthnUser, err := user.GetWebAuthnUser(db)
+		assert.NoError(t, err)
+		assert.Equal(t, testPasskeyUserHandle, webAuthnUser.WebAuthnID())
+		assert.Len(t, webAuthnUser.WebAuthnCredentials(), 1)
+		assert.Nil(t, webAuthnUser.passkeys[0].LastUsedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("new user handle", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		var generated []byte
+
+		mock.ExpectQuery(regexp.QuoteMeta(findPasskeysByUserQuery)).WillReturnRows(sqlmock.NewRows(passkeyRowColumns))
+		mock.ExpectQuery(regexp.QuoteMeta(passkeyUserHandleQuery)).
+			WithArgs(captureHandle{&generated}, testUserID).
+			WillReturnRows(sqlmock.NewRows([]string{"passkey_user_handle"}).AddRow(testPasskeyUserHandle))
+
+		user := setupUserTests()
+		webAuthnUser, err := user.GetWebAuthnUser(db)
+		assert.NoError(t, err)
+		assert.Len(t, generated, passkeyUserHandleLength)
+		assert.NotEqual(t, make([]byte, passkeyUserHandleLength), generated)
+		assert.Equal(t, testPasskeyUserHandle, webAuthnUser.WebAuthnID())
+	})
+
+	t.Run("user handle error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findPasskeysByUserQuery)).WillReturnRows(sqlmock.NewRows(passkeyRowColumns))
+		mock.ExpectQuery(regexp.QuoteMeta(passkeyUserHandleQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		_, err := user.GetWebAuthnUser(db)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findPasskeysByUserQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		_, err := user.GetWebAuthnUser(db)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+
+	t.Run("invalid attestation", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findPasskeysByUserQuery)).WillReturnRows(
+			sqlmock.NewRows(passkeyRowColumns).AddRow(
+				7, "Laptop", []byte("id"), []byte("key"), "", "", "", 0, nil, 0, false, "", []byte("{"), time.Now(), nil,
+			),
+		)
+
+		user := setupUserTests()
+		_, err := user.GetPasskeys(db)
+		assert.ErrorContains(t, err, "error decoding passkey attestation")
+	})
+
+	t.Run("scan error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findPasskeysByUserQuery)).WillReturnRows(
+			sqlmock.NewRows([]string{"id"}).AddRow(7),
+		)
+
+		user := setupUserTests()
+		_, err := user.GetPasskeys(db)
+		assert.ErrorContains(t, err, "error scanning passkey")
+	})
+}
+
+type captureHandle struct {
+	handle *[]byte
+}
+
+func (c captureHandle) Match(v driver.Value) bool {
+	handle, ok := v.([]byte)
+	*c.handle = handle
+
+	return ok
+}
+
+func TestFindByPasskeyUserHandle(t *testing.T) {
+	userColumns := []string{"id", "username", "email", "password", "status", "created_at", "updated_at", "last_login", "avatar"}
+
+	t.Run("invalid handle", func(t *testing.T) {
+		_, err := FindByPasskeyUserHandle(nil, nil)
+		assert.ErrorIs(t, err, ErrPasskeyNotFound)
+
+		_, err = FindByPasskeyUserHandle(nil, make([]byte, passkeyUserHandleLength+1))
+		assert.ErrorIs(t, err, ErrPasskeyNotFound)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		now := time.Now()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findUserByPasskeyUserHandleQuery)).WithArgs(testPasskeyUserHandle).WillReturnRows(
+			sqlmock.NewRows(userColumns).AddRow(testUserID, testUsername, testEmail, "hash", true, now, now, now, ""),
+		)
+		mock.ExpectQuery(regexp.QuoteMeta(findPasskeysByUserQuery)).WithArgs(testUserID).WillReturnRows(passkeyRow(nil))
+
+		webAuthnUser, err := FindByPasskeyUserHandle(db, testPasskeyUserHandle)
+		assert.NoError(t, err)
+		assert.Equal(t, testUserID, webAuthnUser.GetID())
+		assert.Equal(t, testPasskeyUserHandle, webAuthnUser.WebAuthnID())
+		assert.Len(t, webAuthnUser.WebAuthnCredentials(), 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findUserByPasskeyUserHandleQuery)).WillReturnError(sql.ErrNoRows)
+
+		_, err := FindByPasskeyUserHandle(db, testPasskeyUserHandle)
+		assert.ErrorIs(t, err, ErrPasskeyNotFound)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findUserByPasskeyUserHandleQuery)).WillReturnError(sql.ErrConnDone)
+
+		_, err := FindByPasskeyUserHandle(db, testPasskeyUserHandle)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+
+	t.Run("passkeys error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		now := time.Now()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findUserByPasskeyUserHandleQuery)).WillReturnRows(
+			sqlmock.NewRows(userColumns).AddRow(testUserID, testUsername, testEmail, "hash", true, now, now, now, ""),
+		)
+		mock.ExpectQuery(regexp.QuoteMeta(findPasskeysByUserQuery)).WillReturnError(sql.ErrConnDone)
+
+		_, err := FindByPasskeyUserHandle(db, testPasskeyUserHandle)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+}
+
+func TestAddPasskey(t *testing.T) {
+	credential := testCredential()
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(insertPasskeyQuery)).
+			WithArgs(
+				testUserID, "Laptop", credential.ID, credential.PublicKey, "none", "none", "internal,hybrid",
+				int16(protocol.FlagUserPresent|protocol.FlagUserVerified), credential.Authenticator.AAGUID,
+				int64(3), false, "platform", sqlmock.AnyArg(), sqlmock.AnyArg(),
+			).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		user := setupUserTests()
+		assert.NoError(t, user.AddPasskey(db, "Laptop", credential))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(insertPasskeyQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		assert.ErrorIs(t, user.AddPasskey(db, "Laptop", credential), sql.ErrConnDone)
+	})
+}
+
+func TestUpdatePasskeyUsage(t *testing.T) {
+	credential := testCredential()
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(updatePasskeyUsageQuery)).
+			WithArgs(int64(3), false, int16(protocol.FlagUserPresent|protocol.FlagUserVerified), sqlmock.AnyArg(), testUserID, credential.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		user := setupUserTests()
+		assert.NoError(t, user.UpdatePasskeyUsage(db, credential))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(updatePasskeyUsageQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		assert.ErrorIs(t, user.UpdatePasskeyUsage(db, credential), sql.ErrConnDone)
+	})
+}
+
+func TestRenameAndDeletePasskey(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		result  func(mock *sqlmock.ExpectedExec)
+		call    func(user *User, db *sql.DB) error
+		wantErr error
+	}{
+		{
+			name:  "rename",
+			query: renamePasskeyQuery,
+			result: func(e *sqlmock.ExpectedExec) {
+				e.WithArgs("Phone", 7, testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			call: func(user *User, db *sql.DB) error { return user.RenamePasskey(db, 7, "Phone") },
+		},
+		{
+			name:    "rename someone else's passkey",
+			query:   renamePasskeyQuery,
+			result:  func(e *sqlmock.ExpectedExec) { e.WillReturnResult(sqlmock.NewResult(0, 0)) },
+			call:    func(user *User, db *sql.DB) error { return user.RenamePasskey(db, 8, "Phone") },
+			wantErr: ErrPasskeyNotFound,
+		},
+		{
+			name:    "rename error",
+			query:   renamePasskeyQuery,
+			result:  func(e *sqlmock.ExpectedExec) { e.WillReturnError(sql.ErrConnDone) },
+			call:    func(user *User, db *sql.DB) error { return user.RenamePasskey(db, 7, "Phone") },
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name:   "delete",
+			query:  deletePasskeyQuery,
+			result: func(e *sqlmock.ExpectedExec) { e.WithArgs(7, testUserID).WillReturnResult(sqlmock.NewResult(0, 1)) },
+			call:   func(user *User, db *sql.DB) error { return user.DeletePasskey(db, 7) },
+		},
+		{
+			name:    "delete someone else's passkey",
+			query:   deletePasskeyQuery,
+			result:  func(e *sqlmock.ExpectedExec) { e.WillReturnResult(sqlmock.NewResult(0, 0)) },
+			call:    func(user *User, db *sql.DB) error { return user.DeletePasskey(db, 8) },
+			wantErr: ErrPasskeyNotFound,
+		},
+		{
+			name:    "delete error",
+			query:   deletePasskeyQuery,
+			result:  func(e *sqlmock.ExpectedExec) { e.WillReturnError(sql.ErrConnDone) },
+			call:    func(user *User, db *sql.DB) error { return user.DeletePasskey(db, 7) },
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tc.result(mock.ExpectExec(regexp.QuoteMeta(tc.query)))
+
+			user := setupUserTests()
+			err := tc.call(&user, db)
+
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+)
+
+const (
+	RoleAdmin = "admin"
+
+	PermissionUsersManage = "users.manage"
+	PermissionRolesManage = "roles.manage"
+)
+
+const (
+	findRoleIDQuery          = `SELECT id FROM roles WHERE name = $1`
+	listRolesQuery           = `SELECT name FROM roles ORDER BY name`
+	findUserRolesQuery       = `SELECT r.name FROM roles r JOIN user_roles ur ON ur.role_id = r.id WHERE ur.user_id = $1 ORDER BY r.name`
+	findUserPermissionsQuery = `SELECT DISTINCT rp.permission FROM role_permissions rp JOIN user_roles ur ON ur.role_id = rp.role_id WHERE ur.user_id = $1 ORDER BY rp.permission`
+	hasPermissionQuery       = `SELECT EXISTS (SELECT 1 FROM role_permissions rp JOIN user_roles ur ON ur.role_id = rp.role_id JOIN users u ON u.id = ur.user_id AND u.status AND u.deleted_at IS NULL WHERE ur.user_id = $1 AND rp.permission = $2)`
+	grantRoleQuery           = `INSERT INTO user_roles (user_id, role_id, created_at) VALUES ($1, $2, $3) ON CONFLICT (user_id, role_id) DO NOTHING`
+	revokeRoleQuery          = `DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2`
+)
+
+var (
+	ErrRoleNotFound   = errors.New("the role does not exist")
+	ErrRoleNotGranted = errors.New("the user does not have this role")
+)
+
+func HasPermissionByID(db database.DatabaseInterface, userID int, permission string) (bool, error) {
+	var hasPermission bool
+	err := db.QueryRow(hasPermissionQuery, userID, permission).Scan(&hasPermission)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to check permission: %w", err)
+	}
+
+	return hasPermission, nil
+}
+
+func (user *User) LoadPermissions(db database.DatabaseInterface) error {
+	permissions, err := queryStrings(db, findUserPermissionsQuery, user.id)
+
+	if err != nil {
+		return fmt.Errorf("failed to load permissions: %w", err)
+	}
+
+	user.permissions = make(map[string]bool, len(permissions))
+
+	for _, permission := range permissions {
+		user.permissions[permission] = true
+	}
+
+	return nil
+}
+
+func (user *User) HasPermission(permission string) bool {
+	return user.permissions[permission]
+}
+
+func ListRoles(db database.DatabaseInterface) ([]string, error) {
+	roles, err := queryStrings(db, listRolesQuery)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+func (user *User) GetRoles(db database.DatabaseInterface) ([]string, error) {
+	roles, err := queryStrings(db, findUserRolesQuery, user.id)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+func (user *User) GrantRole(db database.DatabaseInterface, role string) error {
+	roleID, err := findRoleID(db, role)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(grantRoleQuery, user.id, roleID, time.Now())
+
+	if err != nil {
+		return fmt.Errorf("failed to grant role: %w", err)
+	}
+
+	user.permissions = nil
+
+	return nil
+}
+
+func (user *User) RevokeRole(db database.DatabaseInterface, role string) error {
+	roleID, err := findRoleID(db, role)
+
+	if err != nil {
+		return err
+	}
+
+	result, err := db.Exec(revokeRoleQuery, user.id, roleID)
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke role: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke role: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrRoleNotGranted
+	}
+
+	user.permissions = nil
+
+	return nil
+}
+
+func findRoleID(db database.DatabaseInterface, role string) (int, error) {
+	var roleID int
+	err := db.QueryRow(findRoleIDQuery, role).Scan(&roleID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrRoleNotFound
+		}
+
+		return 0, fmt.Errorf("failed to find role: %w", err)
+	}
+
+	return roleID, nil
+}
+
+func queryStrings(db database.DatabaseInterface, query string, args ...any) ([]string, error) {
+	rows, err := db.Query(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	values := []string{}
+
+	for rows.Next() {
+		var value string
+
+		if err = rows.Scan(&value); err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
@@ -0,0 +1,273 @@
+package user
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasPermissionByID(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(hasPermissionQuery)).
+			WithArgs(testUserID, PermissionUsersManage).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		hasPermission, err := HasPermissionByID(db, testUserID, PermissionUsersManage)
+		assert.NoError(t, err)
+		assert.True(t, hasPermission)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(hasPermissionQuery)).WillReturnError(sql.ErrConnDone)
+
+		hasPermission, err := HasPermissionByID(db, testUserID, PermissionUsersManage)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.False(t, hasPermission)
+	})
+}
+
+func TestLoadPermissions(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findUserPermissionsQuery)).
+			WithArgs(testUserID).
+			WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow(PermissionRolesManage).AddRow(PermissionUsersManage))
+
+		user := setupUserTests()
+		assert.False(t, user.HasPermission(PermissionUsersManage))
+
+		assert.NoError(t, user.LoadPermissions(db))
+		assert.True(t, user.HasPermission(PermissionUsersManage))
+		assert.True(t, user.HasPermission(PermissionRolesManage))
+		assert.False(t, user.HasPermission("other.permission"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findUserPermissionsQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		assert.ErrorIs(t, user.LoadPermissions(db), sql.ErrConnDone)
+		assert.False(t, user.HasPermission(PermissionUsersManage))
+	})
+
+	t.Run("scan error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findUserPermissionsQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow(nil))
+
+		user := setupUserTests()
+		assert.Error(t, user.LoadPermissions(db))
+	})
+}
+
+func TestListRoles(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(listRolesQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow(RoleAdmin).AddRow("editor"))
+
+		roles, err := ListRoles(db)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{RoleAdmin, "editor"}, roles)
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(listRolesQuery)).WillReturnError(sql.ErrConnDone)
+
+		_, err := ListRoles(db)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+}
+
+func TestGetRoles(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findUserRolesQuery)).
+			WithArgs(testUserID).
+			WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow(RoleAdmin))
+
+		user := setupUserTests()
+		roles, err := user.GetRoles(db)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{RoleAdmin}, roles)
+	})
+
+	t.Run("no roles", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findUserRolesQuery)).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+		user := setupUserTests()
+		roles, err := user.GetRoles(db)
+		assert.NoError(t, err)
+		assert.Empty(t, roles)
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findUserRolesQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		_, err := user.GetRoles(db)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+}
+
+func TestGrantRole(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "success",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findRoleIDQuery)).
+					WithArgs(RoleAdmin).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec(regexp.QuoteMeta(grantRoleQuery)).
+					WithArgs(testUserID, 3, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "unknown role",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findRoleIDQuery)).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrRoleNotFound,
+		},
+		{
+			name: "find error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findRoleIDQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name: "insert error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findRoleIDQuery)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec(regexp.QuoteMeta(grantRoleQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			user := setupUserTests()
+			err := user.GrantRole(db, RoleAdmin)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRevokeRole(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "success",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findRoleIDQuery)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec(regexp.QuoteMeta(revokeRoleQuery)).
+					WithArgs(testUserID, 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "not granted",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findRoleIDQuery)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec(regexp.QuoteMeta(revokeRoleQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: ErrRoleNotGranted,
+		},
+		{
+			name: "unknown role",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findRoleIDQuery)).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrRoleNotFound,
+		},
+		{
+			name: "delete error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findRoleIDQuery)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec(regexp.QuoteMeta(revokeRoleQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+		{
+			name: "rows affected error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findRoleIDQuery)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec(regexp.QuoteMeta(revokeRoleQuery)).WillReturnResult(sqlmock.NewErrorResult(sql.ErrConnDone))
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			user := setupUserTests()
+			err := user.RevokeRole(db, RoleAdmin)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
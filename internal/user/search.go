@@ -0,0 +1,79 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+)
+
+const (
+	countUsersQuery  = `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND ($1 = '' OR username ILIKE $1 OR email ILIKE $1)`
+	searchUsersQuery = `SELECT id, username, email, password, status, created_at, updated_at, last_login, avatar FROM users WHERE deleted_at IS NULL AND ($1 = '' OR username ILIKE $1 OR email ILIKE $1) ORDER BY id LIMIT $2 OFFSET $3`
+	deleteUserQuery  = `DELETE FROM users WHERE id = $1`
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func Search(db database.DatabaseInterface, query string, limit, offset int) ([]*User, int, error) {
+	pattern := ""
+
+	if query = strings.TrimSpace(query); query != "" {
+		pattern = "%" + likeEscaper.Replace(query) + "%"
+	}
+
+	var total int
+	err := db.QueryRow(countUsersQuery, pattern).Scan(&total)
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	rows, err := db.Query(searchUsersQuery, pattern, limit, offset)
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	users := []*User{}
+
+	for rows.Next() {
+		user := &User{}
+
+		err = rows.Scan(
+			&user.id,
+			&user.username,
+			&user.email,
+			&user.password,
+			&user.status,
+			&user.createdAt,
+			&user.updatedAt,
+			&user.lastLogin,
+			&user.avatar,
+		)
+
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+func (user *User) Delete(db database.DatabaseInterface) error {
+	_, err := db.Exec(deleteUserQuery, user.id)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package user
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		query     string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantUsers int
+		wantTotal int
+		wantErr   bool
+	}{
+		{
+			name:  "all users",
+			query: "  ",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countUsersQuery)).
+					WithArgs("").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(30))
+				mock.ExpectQuery(regexp.QuoteMeta(searchUsersQuery)).
+					WithArgs("", 2, 10).
+					WillReturnRows(
+						userRow(1, testUsername, testEmail, "hash", true, now, now, now).
+							AddRow(2, "other", "other@user.com", "hash", false, now, now, now, "avatars/2/abc"),
+					)
+			},
+			wantUsers: 2,
+			wantTotal: 30,
+		},
+		{
+			name:  "escapes wildcards",
+			query: "50%_off\\",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countUsersQuery)).
+					WithArgs(`%50\%\_off\\%`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectQuery(regexp.QuoteMeta(searchUsersQuery)).
+					WithArgs(`%50\%\_off\\%`, 2, 10).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password", "status", "created_at", "updated_at", "last_login", "avatar"}))
+			},
+		},
+		{
+			name: "count error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countUsersQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+		{
+			name: "search error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countUsersQuery)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectQuery(regexp.QuoteMeta(searchUsersQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+		{
+			name: "scan error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countUsersQuery)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectQuery(regexp.QuoteMeta(searchUsersQuery)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			users, total, err := Search(db, tt.query, 2, 10)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, users, tt.wantUsers)
+				assert.Equal(t, tt.wantTotal, total)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(deleteUserQuery)).WithArgs(testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		user := setupUserTests()
+		assert.NoError(t, user.Delete(db))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(deleteUserQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		assert.ErrorIs(t, user.Delete(db), sql.ErrConnDone)
+	})
+}
@@ -0,0 +1,170 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+)
+
+const (
+	deletedUserColumns          = `id, username, email, password, status, created_at, updated_at, last_login, avatar, deleted_at`
+	softDeleteUserQuery         = `UPDATE users SET deleted_at = $1, updated_at = $1 WHERE id = $2 AND deleted_at IS NULL`
+	restoreUserQuery            = `UPDATE users SET deleted_at = NULL, updated_at = $1 WHERE id = $2 AND deleted_at IS NOT NULL`
+	findDeletedUserByEmailQuery = `SELECT ` + deletedUserColumns + ` FROM users WHERE email = $1 AND deleted_at > $2`
+	findDeletedUserByIDQuery    = `SELECT ` + deletedUserColumns + ` FROM users WHERE id = $1 AND deleted_at IS NOT NULL`
+	purgeDeletedUsersQuery      = `DELETE FROM users WHERE deleted_at <= $1 RETURNING avatar`
+)
+
+var ErrNotDeleted = errors.New("the user has not been deleted")
+
+var softDeleteTimeNow = time.Now
+
+func (user *User) GetDeletedAt() (deletedAt time.Time) {
+	return user.deletedAt
+}
+
+func (user *User) IsDeleted() bool {
+	return !user.deletedAt.IsZero()
+}
+
+func (user *User) SoftDelete(db database.DatabaseInterface) error {
+	now := softDeleteTimeNow()
+	result, err := db.Exec(softDeleteUserQuery, now, user.id)
+
+	if err != nil {
+		return fmt.Errorf("failed to soft-delete user: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+
+	user.deletedAt = now
+	user.updatedAt = now
+
+	return nil
+}
+
+func (user *User) Restore(db database.DatabaseInterface) error {
+	now := softDeleteTimeNow()
+	result, err := db.Exec(restoreUserQuery, now, user.id)
+
+	if err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotDeleted
+	}
+
+	user.deletedAt = time.Time{}
+	user.updatedAt = now
+
+	return nil
+}
+
+// FindDeletedByEmail finds a soft-deleted user that was deleted after the
+// given time, so that an account past its grace period cannot be restored.
+func FindDeletedByEmail(db database.DatabaseInterface, email string, deletedAfter time.Time) (*User, error) {
+	user, err := scanDeletedUser(db.QueryRow(findDeletedUserByEmailQuery, email, deletedAfter))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidCredentials
+		}
+
+		return nil, fmt.Errorf("error finding deleted user by email: %w", err)
+	}
+
+	return user, nil
+}
+
+func FindDeletedByID(db database.DatabaseInterface, id int) (*User, error) {
+	user, err := scanDeletedUser(db.QueryRow(findDeletedUserByIDQuery, id))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotDeleted
+		}
+
+		return nil, fmt.Errorf("error finding deleted user by ID: %w", err)
+	}
+
+	return user, nil
+}
+
+func RestoreWithToken(db database.DatabaseInterface, token string) (*User, error) {
+	userID, err := consumeToken(db, TokenPurposeRestore, token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := FindDeletedByID(db, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = user.Restore(db); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func PurgeDeleted(db database.DatabaseInterface, deletedBefore time.Time) (purged int64, avatars []string, err error) {
+	rows, err := db.Query(purgeDeletedUsersQuery, deletedBefore)
+
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to purge deleted users: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var avatar string
+
+		if err = rows.Scan(&avatar); err != nil {
+			return 0, nil, fmt.Errorf("failed to purge deleted users: %w", err)
+		}
+
+		purged++
+
+		if avatar != "" {
+			avatars = append(avatars, avatar)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("failed to purge deleted users: %w", err)
+	}
+
+	return purged, avatars, nil
+}
+
+func scanDeletedUser(row *sql.Row) (*User, error) {
+	user := &User{}
+
+	err := row.Scan(
+		&user.id,
+		&user.username,
+		&user.email,
+		&user.password,
+		&user.status,
+		&user.createdAt,
+		&user.updatedAt,
+		&user.lastLogin,
+		&user.avatar,
+		&user.deletedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
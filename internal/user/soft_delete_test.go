@@ -0,0 +1,365 @@
+package user
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var deletedUserColumnNames = []string{"id", "username", "email", "password", "status", "created_at", "updated_at", "last_login", "avatar", "deleted_at"}
+
+func freezeSoftDeleteTime(t *testing.T) time.Time {
+	now := time.Unix(testUpdatedAtUnix, 0)
+	softDeleteTimeNowOrig := softDeleteTimeNow
+
+	t.Cleanup(func() { softDeleteTimeNow = softDeleteTimeNowOrig })
+	softDeleteTimeNow = func() time.Time { return now }
+
+	return now
+}
+
+func TestSoftDelete(t *testing.T) {
+	now := freezeSoftDeleteTime(t)
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "success",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(softDeleteUserQuery)).WithArgs(now, testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "already deleted",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(softDeleteUserQuery)).WithArgs(now, testUserID).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: true,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(softDeleteUserQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			user := setupUserTests()
+			err := user.SoftDelete(db)
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.False(t, user.IsDeleted())
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, user.IsDeleted())
+			assert.Equal(t, now, user.GetDeletedAt())
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	now := freezeSoftDeleteTime(t)
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "success",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(restoreUserQuery)).WithArgs(now, testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "not deleted",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(restoreUserQuery)).WithArgs(now, testUserID).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: ErrNotDeleted,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(restoreUserQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			user := setupUserTests()
+			user.deletedAt = now.Add(-time.Hour)
+			err := user.Restore(db)
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.True(t, user.IsDeleted())
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.False(t, user.IsDeleted())
+		})
+	}
+}
+
+func TestFindDeletedByEmail(t *testing.T) {
+	now := time.Now()
+	deletedAfter := now.Add(-30 * 24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "success",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findDeletedUserByEmailQuery)).
+					WithArgs(testEmail, deletedAfter).
+					WillReturnRows(sqlmock.NewRows(deletedUserColumnNames).AddRow(testUserID, testUsername, testEmail, "hash", true, now, now, now, "", now))
+			},
+		},
+		{
+			name: "not found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findDeletedUserByEmailQuery)).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findDeletedUserByEmailQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			user, err := FindDeletedByEmail(db, testEmail, deletedAfter)
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, user)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, testUserID, user.GetID())
+			assert.Equal(t, now, user.GetDeletedAt())
+		})
+	}
+}
+
+func TestFindDeletedByID(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "success",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findDeletedUserByIDQuery)).
+					WithArgs(testUserID).
+					WillReturnRows(sqlmock.NewRows(deletedUserColumnNames).AddRow(testUserID, testUsername, testEmail, "hash", true, now, now, now, "", now))
+			},
+		},
+		{
+			name: "not deleted",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findDeletedUserByIDQuery)).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrNotDeleted,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findDeletedUserByIDQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			user, err := FindDeletedByID(db, testUserID)
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, user)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, now, user.GetDeletedAt())
+		})
+	}
+}
+
+func TestRestoreWithToken(t *testing.T) {
+	now := freezeTokenTime(t)
+	freezeSoftDeleteTime(t)
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "success",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(consumeTokenQuery)).
+					WithArgs(now, hashToken("token"), "restore").
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+				mock.ExpectQuery(regexp.QuoteMeta(findDeletedUserByIDQuery)).
+					WithArgs(testUserID).
+					WillReturnRows(sqlmock.NewRows(deletedUserColumnNames).AddRow(testUserID, testUsername, testEmail, "hash", true, now, now, now, "", now))
+				mock.ExpectExec(regexp.QuoteMeta(restoreUserQuery)).WithArgs(now, testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "invalid token",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(consumeTokenQuery)).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "not deleted",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(consumeTokenQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+				mock.ExpectQuery(regexp.QuoteMeta(findDeletedUserByIDQuery)).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrNotDeleted,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(consumeTokenQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+				mock.ExpectQuery(regexp.QuoteMeta(findDeletedUserByIDQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			user, err := RestoreWithToken(db, "token")
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, user)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, testUserID, user.GetID())
+			assert.False(t, user.IsDeleted())
+		})
+	}
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	before := time.Now().Add(-30 * 24 * time.Hour)
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(purgeDeletedUsersQuery)).
+			WithArgs(before).
+			WillReturnRows(sqlmock.NewRows([]string{"avatar"}).AddRow("avatars/1/abc").AddRow("").AddRow("avatars/3/def"))
+
+		purged, avatars, err := PurgeDeleted(db, before)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), purged)
+		assert.Equal(t, []string{"avatars/1/abc", "avatars/3/def"}, avatars)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(purgeDeletedUsersQuery)).WillReturnError(sql.ErrConnDone)
+
+		_, _, err := PurgeDeleted(db, before)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+
+	t.Run("row error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(purgeDeletedUsersQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"avatar"}).AddRow("").RowError(0, sql.ErrConnDone))
+
+		_, _, err := PurgeDeleted(db, before)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+}
@@ -0,0 +1,178 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+)
+
+type TokenPurpose string
+
+const (
+	TokenPurposeVerify      TokenPurpose = "verify"
+	TokenPurposeReset       TokenPurpose = "reset"
+	TokenPurposeEmailChange TokenPurpose = "email-change"
+	TokenPurposeUnlock      TokenPurpose = "unlock"
+	TokenPurposeMagicLink   TokenPurpose = "magic-link"
+	TokenPurposeEmailRevert TokenPurpose = "email-revert"
+	TokenPurposeRestore     TokenPurpose = "restore"
+	TokenPurposeLoginRevoke TokenPurpose = "login-revoke"
+)
+
+const (
+	TokenTTLVerify      = 24 * time.Hour
+	TokenTTLReset       = time.Hour
+	TokenTTLEmailChange = 24 * time.Hour
+	TokenTTLUnlock      = time.Hour
+	TokenTTLMagicLink   = 15 * time.Minute
+	TokenTTLEmailRevert = 7 * 24 * time.Hour
+	TokenTTLLoginRevoke = 7 * 24 * time.Hour
+
+	deleteUserTokensQuery    = `DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND consumed_at IS NULL`
+	insertTokenQuery         = `INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)`
+	consumeTokenQuery        = `UPDATE user_tokens SET consumed_at = $1 WHERE token_hash = $2 AND purpose = $3 AND consumed_at IS NULL AND expires_at > $1 RETURNING user_id`
+	deleteExpiredTokensQuery = `DELETE FROM user_tokens WHERE expires_at <= $1 OR consumed_at IS NOT NULL`
+	findTokensByUserQuery    = `SELECT purpose, created_at, expires_at, consumed_at FROM user_tokens WHERE user_id = $1 ORDER BY created_at, id`
+)
+
+var ErrInvalidToken = errors.New("the token is invalid or has expired")
+
+var tokenTimeNow = time.Now
+
+type Token struct {
+	Purpose    TokenPurpose
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	ConsumedAt *time.Time
+}
+
+func (user *User) CreateToken(db database.DatabaseInterface, purpose TokenPurpose, ttl time.Duration) (string, error) {
+	_, err := db.Exec(deleteUserTokensQuery, user.id, string(purpose))
+
+	if err != nil {
+		return "", fmt.Errorf("failed to revoke previous tokens: %w", err)
+	}
+
+	return user.IssueToken(db, purpose, ttl)
+}
+
+func (user *User) IssueToken(db database.DatabaseInterface, purpose TokenPurpose, ttl time.Duration) (string, error) {
+	token := rand.Text()
+	now := tokenTimeNow()
+
+	_, err := db.Exec(insertTokenQuery, user.id, string(purpose), hashToken(token), now.Add(ttl), now)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to save token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (user *User) RevokeTokens(db database.DatabaseInterface, purpose TokenPurpose) error {
+	_, err := db.Exec(deleteUserTokensQuery, user.id, string(purpose))
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+
+	return nil
+}
+
+func ConsumeToken(db database.DatabaseInterface, purpose TokenPurpose, token string) (*User, error) {
+	userID, err := consumeToken(db, purpose, token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := FindByID(db, userID)
+
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+
+	return user, err
+}
+
+func consumeToken(db database.DatabaseInterface, purpose TokenPurpose, token string) (int, error) {
+	if token == "" {
+		return 0, ErrInvalidToken
+	}
+
+	var userID int
+	err := db.QueryRow(consumeTokenQuery, tokenTimeNow(), hashToken(token), string(purpose)).Scan(&userID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidToken
+		}
+
+		return 0, fmt.Errorf("failed to consume token: %w", err)
+	}
+
+	return userID, nil
+}
+
+func (user *User) GetTokens(db database.DatabaseInterface) ([]Token, error) {
+	rows, err := db.Query(findTokensByUserQuery, user.id)
+
+	if err != nil {
+		return nil, fmt.Errorf("error finding tokens: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	tokens := []Token{}
+
+	for rows.Next() {
+		var (
+			token      Token
+			purpose    string
+			consumedAt sql.NullTime
+		)
+
+		err = rows.Scan(&purpose, &token.CreatedAt, &token.ExpiresAt, &consumedAt)
+
+		if err != nil {
+			return nil, fmt.Errorf("error scanning token: %w", err)
+		}
+
+		token.Purpose = TokenPurpose(purpose)
+
+		if consumedAt.Valid {
+			token.ConsumedAt = &consumedAt.Time
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error finding tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func DeleteExpiredTokens(db database.DatabaseInterface) (int64, error) {
+	result, err := db.Exec(deleteExpiredTokensQuery, tokenTimeNow())
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired tokens: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// Tokens are random and long enough that a plain SHA-256 hash
+// cannot be brute-forced.
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,306 @@
+package user
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func freezeTokenTime(t *testing.T) time.Time {
+	now := time.Unix(testUpdatedAtUnix, 0)
+	tokenTimeNowOrig := tokenTimeNow
+
+	t.Cleanup(func() { tokenTimeNow = tokenTimeNowOrig })
+	tokenTimeNow = func() time.Time { return now }
+
+	return now
+}
+
+func TestCreateToken(t *testing.T) {
+	now := freezeTokenTime(t)
+
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "success",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(deleteUserTokensQuery)).
+					WithArgs(testUserID, "reset").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(insertTokenQuery)).
+					WithArgs(testUserID, "reset", sqlmock.AnyArg(), now.Add(TokenTTLReset), now).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{
+			name: "revoke error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(deleteUserTokensQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+		{
+			name: "insert error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(deleteUserTokensQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(insertTokenQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			user := setupUserTests()
+			token, err := user.CreateToken(db, TokenPurposeReset, TokenTTLReset)
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, sql.ErrConnDone)
+				assert.Empty(t, token)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotEmpty(t, token)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestIssueToken(t *testing.T) {
+	now := freezeTokenTime(t)
+
+	db, mock, _ := sqlmock.New()
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec(regexp.QuoteMeta(insertTokenQuery)).
+		WithArgs(testUserID, "login-revoke", sqlmock.AnyArg(), now.Add(TokenTTLLoginRevoke), now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(insertTokenQuery)).WillReturnError(sql.ErrConnDone)
+
+	user := setupUserTests()
+	token, err := user.IssueToken(db, TokenPurposeLoginRevoke, TokenTTLLoginRevoke)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	token, err = user.IssueToken(db, TokenPurposeLoginRevoke, TokenTTLLoginRevoke)
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+	assert.Empty(t, token)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateTokenIsRandom(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer func() { _ = db.Close() }()
+
+	hashes := make([]string, 2)
+
+	for i := range hashes {
+		mock.ExpectExec(regexp.QuoteMeta(deleteUserTokensQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(insertTokenQuery)).WillReturnResult(sqlmock.NewResult(1, 1))
+
+		user := setupUserTests()
+		token, err := user.CreateToken(db, TokenPurposeVerify, TokenTTLVerify)
+		assert.NoError(t, err)
+
+		hashes[i] = hashToken(token)
+	}
+
+	assert.NotEqual(t, hashes[0], hashes[1])
+}
+
+func TestConsumeToken(t *testing.T) {
+	now := freezeTokenTime(t)
+
+	tests := []struct {
+		name      string
+		token     string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name:  "success",
+			token: "token",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(consumeTokenQuery)).
+					WithArgs(now, hashToken("token"), "verify").
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+				mock.ExpectQuery(regexp.QuoteMeta(findUserByIDQuery)).
+					WithArgs(testUserID).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"id", "username", "email", "password", "status", "created_at", "updated_at", "last_login", "avatar"}).
+							AddRow(testUserID, testUsername, testEmail, "hash", false, now, now, now, ""),
+					)
+			},
+		},
+		{
+			name:      "empty token",
+			token:     "",
+			mockSetup: func(mock sqlmock.Sqlmock) {},
+			wantErr:   ErrInvalidToken,
+		},
+		{
+			name:  "invalid, used or expired token",
+			token: "token",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(consumeTokenQuery)).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:  "deleted user",
+			token: "token",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(consumeTokenQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+				mock.ExpectQuery(regexp.QuoteMeta(findUserByIDQuery)).WithArgs(testUserID).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:  "database error",
+			token: "token",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(consumeTokenQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tt.mockSetup(mock)
+
+			user, err := ConsumeToken(db, TokenPurposeVerify, tt.token)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, user)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, testUserID, user.GetID())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDeleteExpiredTokens(t *testing.T) {
+	now := freezeTokenTime(t)
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(deleteExpiredTokensQuery)).WithArgs(now).WillReturnResult(sqlmock.NewResult(0, 3))
+
+		deleted, err := DeleteExpiredTokens(db)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), deleted)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(deleteExpiredTokensQuery)).WillReturnError(sql.ErrConnDone)
+
+		_, err := DeleteExpiredTokens(db)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+}
+
+func TestRevokeTokens(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(deleteUserTokensQuery)).
+			WithArgs(testUserID, "email-change").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		user := setupUserTests()
+		assert.NoError(t, user.RevokeTokens(db, TokenPurposeEmailChange))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectExec(regexp.QuoteMeta(deleteUserTokensQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		assert.ErrorIs(t, user.RevokeTokens(db, TokenPurposeEmailChange), sql.ErrConnDone)
+	})
+}
+
+func TestGetTokens(t *testing.T) {
+	now := time.Now()
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTokensByUserQuery)).
+			WithArgs(testUserID).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"purpose", "created_at", "expires_at", "consumed_at"}).
+					AddRow("verify", now, now, now).
+					AddRow("reset", now, now, nil),
+			)
+
+		user := setupUserTests()
+		tokens, err := user.GetTokens(db)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Token{
+			{Purpose: TokenPurposeVerify, CreatedAt: now, ExpiresAt: now, ConsumedAt: &now},
+			{Purpose: TokenPurposeReset, CreatedAt: now, ExpiresAt: now},
+		}, tokens)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTokensByUserQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		_, err := user.GetTokens(db)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+
+	t.Run("scan error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTokensByUserQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"purpose"}).AddRow("verify"))
+
+		user := setupUserTests()
+		_, err := user.GetTokens(db)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,308 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/totp"
+)
+
+const (
+	RecoveryCodeCount       = 10
+	recoveryCodeLength      = 10
+	recoveryCodeGroupLength = 5
+
+	errTwoFactorEnable       = "failed to enable two-factor authentication: %w"
+	errRecoveryCodesGenerate = "failed to generate recovery codes: %w"
+
+	findTwoFactorQuery          = `SELECT secret, last_used_step, confirmed_at FROM user_two_factor WHERE user_id = $1`
+	upsertTwoFactorQuery        = `INSERT INTO user_two_factor (user_id, secret, last_used_step, confirmed_at, created_at) VALUES ($1, $2, 0, NULL, $3) ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, confirmed_at = NULL, created_at = EXCLUDED.created_at`
+	confirmTwoFactorQuery       = `UPDATE user_two_factor SET confirmed_at = $1, last_used_step = $2 WHERE user_id = $3`
+	updateTwoFactorStepQuery    = `UPDATE user_two_factor SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1`
+	deleteTwoFactorQuery        = `DELETE FROM user_two_factor WHERE user_id = $1`
+	deleteRecoveryCodesQuery    = `DELETE FROM user_recovery_codes WHERE user_id = $1`
+	insertRecoveryCodeQuery     = `INSERT INTO user_recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, $3)`
+	useRecoveryCodeQuery        = `UPDATE user_recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`
+	countRecoveryCodesLeftQuery = `SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+)
+
+var (
+	ErrInvalidTwoFactorCode = errors.New("the authentication code is invalid")
+	ErrTwoFactorNotEnrolled = errors.New("two-factor authentication has not been set up")
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+)
+
+var (
+	twoFactorTimeNow        = time.Now
+	twoFactorGenerateSecret = totp.GenerateSecret
+)
+
+type TwoFactor struct {
+	Secret       string
+	LastUsedStep int64
+	ConfirmedAt  *time.Time
+}
+
+func (tf *TwoFactor) IsEnabled() bool {
+	return tf != nil && tf.ConfirmedAt != nil
+}
+
+func (user *User) GetTwoFactor(db database.DatabaseInterface) (*TwoFactor, error) {
+	tf := &TwoFactor{}
+	var confirmedAt sql.NullTime
+
+	err := db.QueryRow(findTwoFactorQuery, user.id).Scan(&tf.Secret, &tf.LastUsedStep, &confirmedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("error finding two-factor settings: %w", err)
+	}
+
+	if confirmedAt.Valid {
+		tf.ConfirmedAt = &confirmedAt.Time
+	}
+
+	return tf, nil
+}
+
+func (user *User) HasTwoFactor(db database.DatabaseInterface) (bool, error) {
+	tf, err := user.GetTwoFactor(db)
+
+	if err != nil {
+		return false, err
+	}
+
+	return tf.IsEnabled(), nil
+}
+
+func (user *User) StartTwoFactorEnrollment(db database.DatabaseInterface) (string, error) {
+	tf, err := user.GetTwoFactor(db)
+
+	if err != nil {
+		return "", err
+	}
+
+	if tf.IsEnabled() {
+		return "", ErrTwoFactorEnabled
+	}
+
+	if tf != nil {
+		return tf.Secret, nil
+	}
+
+	secret, err := twoFactorGenerateSecret()
+
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.Exec(upsertTwoFactorQuery, user.id, secret, twoFactorTimeNow())
+
+	if err != nil {
+		return "", fmt.Errorf("failed to save two-factor secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+func (user *User) ConfirmTwoFactor(db database.DatabaseInterface, code string) ([]string, error) {
+	tf, err := user.GetTwoFactor(db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tf == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	if tf.IsEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	step, ok := totp.Validate(tf.Secret, code, twoFactorTimeNow(), tf.LastUsedStep)
+
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes := generateRecoveryCodes()
+	tx, err := db.Begin()
+
+	if err != nil {
+		return nil, fmt.Errorf(errTwoFactorEnable, err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(confirmTwoFactorQuery, twoFactorTimeNow(), step, user.id)
+
+	if err != nil {
+		return nil, fmt.Errorf(errTwoFactorEnable, err)
+	}
+
+	err = user.replaceRecoveryCodes(tx, codes)
+
+	if err != nil {
+		return nil, fmt.Errorf(errTwoFactorEnable, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf(errTwoFactorEnable, err)
+	}
+
+	return codes, nil
+}
+
+func (user *User) VerifyTwoFactor(db database.DatabaseInterface, code string) error {
+	tf, err := user.GetTwoFactor(db)
+
+	if err != nil {
+		return err
+	}
+
+	if !tf.IsEnabled() {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := totp.Validate(tf.Secret, code, twoFactorTimeNow(), tf.LastUsedStep)
+
+	if ok {
+		result, err := db.Exec(updateTwoFactorStepQuery, step, user.id)
+
+		if err != nil {
+			return fmt.Errorf("failed to update two-factor state: %w", err)
+		}
+
+		// Another request may have used the same code in the meantime.
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+
+		return nil
+	}
+
+	return user.useRecoveryCode(db, code)
+}
+
+func (user *User) useRecoveryCode(db database.DatabaseInterface, code string) error {
+	normalized := normalizeRecoveryCode(code)
+
+	if len(normalized) != recoveryCodeLength {
+		return ErrInvalidTwoFactorCode
+	}
+
+	result, err := db.Exec(useRecoveryCodeQuery, twoFactorTimeNow(), user.id, hashRecoveryCode(normalized))
+
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func (user *User) RecoveryCodesLeft(db database.DatabaseInterface) (count int, err error) {
+	err = db.QueryRow(countRecoveryCodesLeftQuery, user.id).Scan(&count)
+
+	if err != nil {
+		return 0, fmt.Errorf("error counting recovery codes: %w", err)
+	}
+
+	return count, nil
+}
+
+func (user *User) RegenerateRecoveryCodes(db database.DatabaseInterface) ([]string, error) {
+	codes := generateRecoveryCodes()
+	tx, err := db.Begin()
+
+	if err != nil {
+		return nil, fmt.Errorf(errRecoveryCodesGenerate, err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	err = user.replaceRecoveryCodes(tx, codes)
+
+	if err != nil {
+		return nil, fmt.Errorf(errRecoveryCodesGenerate, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf(errRecoveryCodesGenerate, err)
+	}
+
+	return codes, nil
+}
+
+func (user *User) replaceRecoveryCodes(tx *sql.Tx, codes []string) error {
+	_, err := tx.Exec(deleteRecoveryCodesQuery, user.id)
+
+	if err != nil {
+		return err
+	}
+
+	for _, code := range codes {
+		_, err = tx.Exec(insertRecoveryCodeQuery, user.id, hashRecoveryCode(normalizeRecoveryCode(code)), twoFactorTimeNow())
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (user *User) DisableTwoFactor(db database.DatabaseInterface) error {
+	_, err := db.Exec(deleteRecoveryCodesQuery, user.id)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	_, err = db.Exec(deleteTwoFactorQuery, user.id)
+
+	if err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	return nil
+}
+
+func generateRecoveryCodes() []string {
+	codes := make([]string, RecoveryCodeCount)
+
+	for i := range codes {
+		code := strings.ToLower(rand.Text()[:recoveryCodeLength])
+		codes[i] = fmt.Sprintf("%s-%s", code[:recoveryCodeGroupLength], code[recoveryCodeGroupLength:])
+	}
+
+	return codes
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// Recovery codes are random and long enough that a plain SHA-256 hash
+// cannot be brute-forced, unlike user-chosen passwords.
+func hashRecoveryCode(code string) string {
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
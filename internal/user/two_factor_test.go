@@ -0,0 +1,462 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTwoFactorSecret = "JBSWY3DPEHPK3PXP"
+
+func twoFactorRow(lastUsedStep int64, confirmedAt any) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"secret", "last_used_step", "confirmed_at"}).
+		AddRow(testTwoFactorSecret, lastUsedStep, confirmedAt)
+}
+
+func currentTestCode(t *testing.T) (string, int64) {
+	step := totp.TimeStep(time.Now())
+	code, err := totp.GenerateCode(testTwoFactorSecret, step)
+	assert.NoError(t, err)
+
+	return code, step
+}
+
+func TestGetTwoFactor(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		mockSetup   func(mock sqlmock.Sqlmock)
+		wantNil     bool
+		wantEnabled bool
+		wantErr     bool
+	}{
+		{
+			name: "not enrolled",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WithArgs(testUserID).WillReturnError(sql.ErrNoRows)
+			},
+			wantNil: true,
+		},
+		{
+			name: "pending",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WithArgs(testUserID).WillReturnRows(twoFactorRow(0, nil))
+			},
+		},
+		{
+			name: "enabled",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WithArgs(testUserID).WillReturnRows(twoFactorRow(0, now))
+			},
+			wantEnabled: true,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WithArgs(testUserID).WillReturnError(sql.ErrConnDone)
+			},
+			wantNil: true,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer func() { _ = db.Close() }()
+
+			tc.mockSetup(mock)
+			tc.mockSetup(mock)
+
+			user := setupUserTests()
+			tf, err := user.GetTwoFactor(db)
+
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantNil, tf == nil)
+			assert.Equal(t, tc.wantEnabled, tf.IsEnabled())
+
+			hasTwoFactor, _ := user.HasTwoFactor(db)
+			assert.Equal(t, tc.wantEnabled, hasTwoFactor)
+		})
+	}
+}
+
+func TestStartTwoFactorEnrollment(t *testing.T) {
+	origGenerateSecret := twoFactorGenerateSecret
+	defer func() { twoFactorGenerateSecret = origGenerateSecret }()
+
+	twoFactorGenerateSecret = func() (string, error) { return testTwoFactorSecret, nil }
+
+	t.Run("new enrollment", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnError(sql.ErrNoRows)
+		mock.ExpectExec(regexp.QuoteMeta(upsertTwoFactorQuery)).
+			WithArgs(testUserID, testTwoFactorSecret, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		user := setupUserTests()
+		secret, err := user.StartTwoFactorEnrollment(db)
+
+		assert.NoError(t, err)
+		assert.Equal(t, testTwoFactorSecret, secret)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("pending enrollment is reused", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnRows(twoFactorRow(0, nil))
+
+		user := setupUserTests()
+		secret, err := user.StartTwoFactorEnrollment(db)
+
+		assert.NoError(t, err)
+		assert.Equal(t, testTwoFactorSecret, secret)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already enabled", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnRows(twoFactorRow(0, time.Now()))
+
+		user := setupUserTests()
+		_, err := user.StartTwoFactorEnrollment(db)
+
+		assert.ErrorIs(t, err, ErrTwoFactorEnabled)
+	})
+
+	t.Run("save error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnError(sql.ErrNoRows)
+		mock.ExpectExec(regexp.QuoteMeta(upsertTwoFactorQuery)).WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		_, err := user.StartTwoFactorEnrollment(db)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+
+	t.Run("secret generation error", func(t *testing.T) {
+		twoFactorGenerateSecret = func() (string, error) { return "", errors.New("no entropy") }
+
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnError(sql.ErrNoRows)
+
+		user := setupUserTests()
+		_, err := user.StartTwoFactorEnrollment(db)
+
+		assert.ErrorContains(t, err, "no entropy")
+	})
+}
+
+func TestConfirmTwoFactor(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		code, step := currentTestCode(t)
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnRows(twoFactorRow(0, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(confirmTwoFactorQuery)).
+			WithArgs(sqlmock.AnyArg(), step, testUserID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(deleteRecoveryCodesQuery)).WithArgs(testUserID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		for range RecoveryCodeCount {
+			mock.ExpectExec(regexp.QuoteMeta(insertRecoveryCodeQuery)).WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+
+		mock.ExpectCommit()
+
+		user := setupUserTests()
+		codes, err := user.ConfirmTwoFactor(db, code)
+
+		assert.NoError(t, err)
+		assert.Len(t, codes, RecoveryCodeCount)
+		assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, codes[0])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnRows(twoFactorRow(0, nil))
+
+		user := setupUserTests()
+		_, err := user.ConfirmTwoFactor(db, "000000x")
+
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("not enrolled", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnError(sql.ErrNoRows)
+
+		user := setupUserTests()
+		_, err := user.ConfirmTwoFactor(db, "123456")
+
+		assert.ErrorIs(t, err, ErrTwoFactorNotEnrolled)
+	})
+
+	t.Run("already enabled", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnRows(twoFactorRow(0, time.Now()))
+
+		user := setupUserTests()
+		_, err := user.ConfirmTwoFactor(db, "123456")
+
+		assert.ErrorIs(t, err, ErrTwoFactorEnabled)
+	})
+
+	t.Run("recovery code error rolls back", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		code, _ := currentTestCode(t)
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnRows(twoFactorRow(0, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(confirmTwoFactorQuery)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(deleteRecoveryCodesQuery)).WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		user := setupUserTests()
+		_, err := user.ConfirmTwoFactor(db, code)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("transaction error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		code, _ := currentTestCode(t)
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnRows(twoFactorRow(0, nil))
+		mock.ExpectBegin().WillReturnError(sql.ErrConnDone)
+
+		user := setupUserTests()
+		_, err := user.ConfirmTwoFactor(db, code)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+}
+
+func TestVerifyTwoFactor(t *testing.T) {
+	t.Run("valid authenticator code", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		code, step := currentTestCode(t)
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnRows(twoFactorRow(0, time.Now()))
+		mock.ExpectExec(regexp.QuoteMeta(updateTwoFactorStepQuery)).WithArgs(step, testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		user := setupUserTests()
+		assert.NoError(t, user.VerifyTwoFactor(db, code))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("code used concurrently", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		code, _ := currentTestCode(t)
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnRows(twoFactorRow(0, time.Now()))
+		mock.ExpectExec(regexp.QuoteMeta(updateTwoFactorStepQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		user := setupUserTests()
+		assert.ErrorIs(t, user.VerifyTwoFactor(db, code), ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("replayed code", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		code, step := currentTestCode(t)
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnRows(twoFactorRow(step+1, time.Now()))
+
+		user := setupUserTests()
+		assert.ErrorIs(t, user.VerifyTwoFactor(db, code), ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("valid recovery code", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnRows(twoFactorRow(0, time.Now()))
+		mock.ExpectExec(regexp.QuoteMeta(useRecoveryCodeQuery)).
+			WithArgs(sqlmock.AnyArg(), testUserID, hashRecoveryCode("abcdefghij")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		user := setupUserTests()
+		assert.NoError(t, user.VerifyTwoFactor(db, " ABCDE-fghij "))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown recovery code", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnRows(twoFactorRow(0, time.Now()))
+		mock.ExpectExec(regexp.QuoteMeta(useRecoveryCodeQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		user := setupUserTests()
+		assert.ErrorIs(t, user.VerifyTwoFactor(db, "abcde-fghij"), ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("wrong length", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnRows(twoFactorRow(0, time.Now()))
+
+		user := setupUserTests()
+		assert.ErrorIs(t, user.VerifyTwoFactor(db, "abc"), ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectQuery(regexp.QuoteMeta(findTwoFactorQuery)).WillReturnRows(twoFactorRow(0, nil))
+
+		user := setupUserTests()
+		assert.ErrorIs(t, user.VerifyTwoFactor(db, "123456"), ErrTwoFactorNotEnrolled)
+	})
+}
+
+func TestRecoveryCodesLeft(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(regexp.QuoteMeta(countRecoveryCodesLeftQuery)).WithArgs(testUserID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+	mock.ExpectQuery(regexp.QuoteMeta(countRecoveryCodesLeftQuery)).WithArgs(testUserID).
+		WillReturnError(sql.ErrConnDone)
+
+	user := setupUserTests()
+
+	count, err := user.RecoveryCodesLeft(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, count)
+
+	_, err = user.RecoveryCodesLeft(db)
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+}
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(deleteRecoveryCodesQuery)).WithArgs(testUserID).WillReturnResult(sqlmock.NewResult(0, 3))
+
+		for range RecoveryCodeCount {
+			mock.ExpectExec(regexp.QuoteMeta(insertRecoveryCodeQuery)).WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+
+		mock.ExpectCommit()
+
+		user := setupUserTests()
+		codes, err := user.RegenerateRecoveryCodes(db)
+
+		assert.NoError(t, err)
+		assert.Len(t, codes, RecoveryCodeCount)
+		assert.NotEqual(t, codes[0], codes[1])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("insert error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(deleteRecoveryCodesQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(insertRecoveryCodeQuery)).WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		user := setupUserTests()
+		_, err := user.RegenerateRecoveryCodes(db)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDisableTwoFactor(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(mock sqlmock.Sqlmock)
+		wantErr   bool
+	}{
+		{
+			name: "success",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(deleteRecoveryCodesQuery)).WithArgs(testUserID).WillReturnResult(sqlmock.NewResult(0, 10))
+				mock.ExpectExec(regexp.QuoteMeta(deleteTwoFactorQuery)).WithArgs(testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "recovery code error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(deleteRecoveryCodesQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+		{
+			name: "two-factor error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(deleteRecoveryCodesQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(deleteTwoFactorQuery)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, _ := sqlmock.New()
+			defer func() { _ = db.Close() }()
+
+			tc.mockSetup(mock)
+
+			user := setupUserTests()
+			err := user.DisableTwoFactor(db)
+
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
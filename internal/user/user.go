@@ -6,24 +6,23 @@ import (
 	"fmt"
 	"time"
 
-	"crypto/sha256"
-	"encoding/hex"
-
 	"github.com/Dobefu/go-web-starter/internal/database"
+	"github.com/Dobefu/go-web-starter/internal/password"
 	"github.com/gin-contrib/sessions"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var (
 	ErrInvalidCredentials = errors.New("invalid email or password")
 	ErrNotActive          = errors.New("the user is not active")
+	ErrUserExists         = errors.New("this username or email address is already in use")
+	ErrNotFound           = errors.New("the user could not be found")
 )
 
 const (
 	insertUserQuery         = `INSERT INTO users (username, email, password, status, created_at, updated_at, last_login) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at, last_login`
-	findUserByEmailQuery    = `SELECT id, username, email, password, status, created_at, updated_at, last_login FROM users WHERE email = $1`
-	findUserByUsernameQuery = `SELECT id, username, email, password, status, created_at, updated_at, last_login FROM users WHERE username = $1`
-	findUserByIDQuery       = `SELECT id, username, email, password, status, created_at, updated_at, last_login FROM users WHERE id = $1`
+	findUserByEmailQuery    = `SELECT id, username, email, password, status, created_at, updated_at, last_login, avatar FROM users WHERE email = $1 AND deleted_at IS NULL`
+	findUserByUsernameQuery = `SELECT id, username, email, password, status, created_at, updated_at, last_login, avatar FROM users WHERE username = $1 AND deleted_at IS NULL`
+	findUserByIDQuery       = `SELECT id, username, email, password, status, created_at, updated_at, last_login, avatar FROM users WHERE id = $1 AND deleted_at IS NULL`
 	updateUserQuery         = `UPDATE users SET username = $1, email = $2, password = $3, status = $4, updated_at = $5, last_login = $6 WHERE id = $7 RETURNING updated_at`
 )
 
@@ -36,6 +35,10 @@ type User struct {
 	createdAt time.Time
 	updatedAt time.Time
 	lastLogin time.Time
+
+	avatar      string
+	deletedAt   time.Time
+	permissions map[string]bool
 }
 
 type UserFields struct {
@@ -47,6 +50,8 @@ type UserFields struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	LastLogin time.Time
+	Avatar    string
+	DeletedAt time.Time
 }
 
 func (user *User) GetID() (id int) {
@@ -85,17 +90,51 @@ func (user *User) GetLastLogin() (lastLogin time.Time) {
 	return user.lastLogin
 }
 
-func (user *User) CheckPassword(password string) error {
-	err := bcrypt.CompareHashAndPassword([]byte(user.password), []byte(password))
+func (user *User) CheckPassword(db database.DatabaseInterface, plainPassword string) error {
+	hasher, err := password.FromConfig()
+
+	if err != nil {
+		return err
+	}
+
+	needsRehash, err := hasher.Verify(user.password, plainPassword)
 
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		if errors.Is(err, password.ErrMismatch) {
 			return ErrInvalidCredentials
 		}
 
 		return fmt.Errorf("error comparing password hash: %w", err)
 	}
 
+	if needsRehash {
+		err = user.ChangePassword(db, plainPassword)
+
+		if err != nil {
+			return fmt.Errorf("failed to rehash password: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (user *User) ChangePassword(db database.DatabaseInterface, plainPassword string) error {
+	hashedPassword, err := HashPassword(plainPassword)
+
+	if err != nil {
+		return err
+	}
+
+	oldPassword := user.password
+	user.password = hashedPassword
+
+	err = user.Save(db)
+
+	if err != nil {
+		user.password = oldPassword
+		return err
+	}
+
 	return nil
 }
 
@@ -114,6 +153,10 @@ func (user *User) Save(db database.DatabaseInterface) (err error) {
 		err = row.Scan(&user.id, &user.createdAt, &user.updatedAt, &user.lastLogin)
 
 		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrUserExists
+			}
+
 			return err
 		}
 
@@ -134,6 +177,10 @@ func (user *User) Save(db database.DatabaseInterface) (err error) {
 	err = row.Scan(&updatedAt)
 
 	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrUserExists
+		}
+
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
@@ -154,6 +201,7 @@ func FindByEmail(db database.DatabaseInterface, email string) (*User, error) {
 		&user.createdAt,
 		&user.updatedAt,
 		&user.lastLogin,
+		&user.avatar,
 	)
 
 	if err != nil {
@@ -183,6 +231,7 @@ func FindByUsername(
 		&user.createdAt,
 		&user.updatedAt,
 		&user.lastLogin,
+		&user.avatar,
 	)
 
 	if err != nil {
@@ -209,11 +258,12 @@ func FindByID(db database.DatabaseInterface, id int) (*User, error) {
 		&user.createdAt,
 		&user.updatedAt,
 		&user.lastLogin,
+		&user.avatar,
 	)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("user with ID %d not found", id)
+			return nil, ErrNotFound
 		}
 
 		return nil, fmt.Errorf("error finding user by ID: %w", err)
@@ -222,14 +272,14 @@ func FindByID(db database.DatabaseInterface, id int) (*User, error) {
 	return user, nil
 }
 
-func HashPassword(password string) (string, error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func HashPassword(plainPassword string) (string, error) {
+	hasher, err := password.FromConfig()
 
 	if err != nil {
-		return "", fmt.Errorf("failed to hash password: %w", err)
+		return "", err
 	}
 
-	return string(hashedBytes), nil
+	return hasher.Hash(plainPassword)
 }
 
 func NewUser(username, email, hashedPassword string, status bool) *User {
@@ -297,24 +347,11 @@ func New(fields UserFields) *User {
 		createdAt: fields.CreatedAt,
 		updatedAt: fields.UpdatedAt,
 		lastLogin: fields.LastLogin,
+		avatar:    fields.Avatar,
+		deletedAt: fields.DeletedAt,
 	}
 }
 
-func (user *User) CreateVerificationToken() string {
-	data := fmt.Sprintf(
-		"%d:%s:%d:%s:%t:%s",
-		user.updatedAt.UnixNano(),
-		user.email,
-		user.id,
-		user.password,
-		user.status,
-		user.lastLogin,
-	)
-
-	hash := sha256.Sum256([]byte(data))
-	return hex.EncodeToString(hash[:])
-}
-
 func (user *User) Login(db database.DatabaseInterface, session sessions.Session) (err error) {
 	session.Set("userID", user.id)
 	err = session.Save()
@@ -323,12 +360,11 @@ func (user *User) Login(db database.DatabaseInterface, session sessions.Session)
 		return err
 	}
 
-	user.lastLogin = time.Now()
-	err = user.Save(db)
+	return user.RecordLogin(db)
+}
 
-	if err != nil {
-		return err
-	}
+func (user *User) RecordLogin(db database.DatabaseInterface) error {
+	user.lastLogin = time.Now()
 
-	return nil
+	return user.Save(db)
 }
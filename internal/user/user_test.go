@@ -4,10 +4,15 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/Dobefu/go-web-starter/internal/password"
+	"github.com/lib/pq"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -121,6 +126,17 @@ func TestUserSave(t *testing.T) {
 			},
 			wantErr: sql.ErrNoRows.Error(),
 		},
+		{
+			name: "insert taken by deleted user",
+			mockSetup: func(mock sqlmock.Sqlmock, user *User, now time.Time) {
+				user.id = 0
+
+				mock.ExpectQuery(regexp.QuoteMeta(insertUserQuery)).
+					WithArgs(user.username, user.email, user.password, user.status, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnError(&pq.Error{Code: pqUniqueViolation})
+			},
+			wantErr: ErrUserExists.Error(),
+		},
 		{
 			name: "insert success",
 			mockSetup: func(mock sqlmock.Sqlmock, user *User, now time.Time) {
@@ -154,6 +170,17 @@ func TestUserSave(t *testing.T) {
 			},
 			wantErr: sql.ErrNoRows.Error(),
 		},
+		{
+			name: "update taken by deleted user",
+			mockSetup: func(mock sqlmock.Sqlmock, user *User, now time.Time) {
+				user.id = 42
+
+				mock.ExpectQuery(regexp.QuoteMeta(updateUserQuery)).
+					WithArgs(user.username, user.email, user.password, user.status, sqlmock.AnyArg(), sqlmock.AnyArg(), user.id).
+					WillReturnError(&pq.Error{Code: pqUniqueViolation})
+			},
+			wantErr: ErrUserExists.Error(),
+		},
 		{
 			name: "update success",
 			mockSetup: func(mock sqlmock.Sqlmock, user *User, now time.Time) {
@@ -207,10 +234,15 @@ func TestCheckPassword(t *testing.T) {
 	hashed, err := HashPassword("supersecret")
 	assert.NoError(t, err)
 
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("supersecret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
 	tests := []struct {
 		name         string
 		userPassword string
 		input        string
+		rehashErr    error
+		wantRehash   bool
 		wantErr      error
 		wantOtherErr bool
 	}{
@@ -233,15 +265,50 @@ func TestCheckPassword(t *testing.T) {
 			wantErr:      nil,
 			wantOtherErr: true,
 		},
+		{
+			name:         "outdated hash",
+			userPassword: string(bcryptHash),
+			input:        "supersecret",
+			wantRehash:   true,
+		},
+		{
+			name:         "outdated hash with invalid password",
+			userPassword: string(bcryptHash),
+			input:        "wrongpassword",
+			wantErr:      ErrInvalidCredentials,
+		},
+		{
+			name:         "rehash error",
+			userPassword: string(bcryptHash),
+			input:        "supersecret",
+			rehashErr:    sql.ErrConnDone,
+			wantRehash:   true,
+			wantErr:      sql.ErrConnDone,
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
+			db, mock, cleanup := setupMockDB(t)
+			defer cleanup()
+
 			user := setupUserTests()
 			user.password = tc.userPassword
-			err := user.CheckPassword(tc.input)
+
+			if tc.wantRehash {
+				query := mock.ExpectQuery(regexp.QuoteMeta(updateUserQuery)).
+					WithArgs(user.username, user.email, sqlmock.AnyArg(), user.status, sqlmock.AnyArg(), sqlmock.AnyArg(), user.id)
+
+				if tc.rehashErr != nil {
+					query.WillReturnError(tc.rehashErr)
+				} else {
+					query.WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+				}
+			}
+
+			err := user.CheckPassword(db, tc.input)
 
 			if tc.wantErr != nil {
 				assert.ErrorIs(t, err, tc.wantErr)
@@ -251,10 +318,88 @@ func TestCheckPassword(t *testing.T) {
 			} else {
 				assert.NoError(t, err)
 			}
+
+			if tc.wantRehash && tc.rehashErr == nil {
+				assert.True(t, strings.HasPrefix(user.password, "$argon2id$"))
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCheckPasswordInvalidConfig(t *testing.T) {
+	viper.Set("password.algorithm", "bogus")
+	defer viper.Set("password.algorithm", config.DefaultConfig.Password.Algorithm)
+
+	user := setupUserTests()
+	assert.ErrorIs(t, user.CheckPassword(nil, "supersecret"), password.ErrUnknownAlgorithm)
+
+	_, err := HashPassword("supersecret")
+	assert.ErrorIs(t, err, password.ErrUnknownAlgorithm)
+}
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{name: "success"},
+		{name: "save error", err: sql.ErrConnDone, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, cleanup := setupMockDB(t)
+			defer cleanup()
+
+			user := setupUserTests()
+			user.password = "oldhash"
+
+			query := mock.ExpectQuery(regexp.QuoteMeta(updateUserQuery)).
+				WithArgs(user.username, user.email, sqlmock.AnyArg(), user.status, sqlmock.AnyArg(), sqlmock.AnyArg(), user.id)
+
+			if tc.err != nil {
+				query.WillReturnError(tc.err)
+			} else {
+				query.WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+			}
+
+			err := user.ChangePassword(db, "newpassword1")
+
+			if tc.wantErr {
+				assert.ErrorIs(t, err, tc.err)
+				assert.Equal(t, "oldhash", user.password)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, user.CheckPassword(db, "newpassword1"))
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 }
 
+func TestRecordLogin(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	user := setupUserTests()
+
+	mock.ExpectQuery(regexp.QuoteMeta(updateUserQuery)).
+		WithArgs(user.username, user.email, user.password, user.status, sqlmock.AnyArg(), sqlmock.AnyArg(), user.id).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+
+	err := user.RecordLogin(db)
+
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), user.GetLastLogin(), time.Second)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestHashPassword(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -272,7 +417,7 @@ func TestHashPassword(t *testing.T) {
 			if tc.wantOK {
 				assert.NoError(t, err)
 				assert.NotEmpty(t, hashed)
-				assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hashed), []byte(tc.input)))
+				assert.True(t, strings.HasPrefix(hashed, "$argon2id$"))
 			} else {
 				assert.Error(t, err)
 			}
@@ -315,8 +460,8 @@ func setupMockDB(t *testing.T) (*mockDatabase, sqlmock.Sqlmock, func()) {
 
 func userRow(id int, username, email, password string, status bool, createdAt, updatedAt, lastLogin time.Time) *sqlmock.Rows {
 	return sqlmock.
-		NewRows([]string{"id", "username", "email", "password", "status", "created_at", "updated_at", "last_login"}).
-		AddRow(id, username, email, password, status, createdAt, updatedAt, lastLogin)
+		NewRows([]string{"id", "username", "email", "password", "status", "created_at", "updated_at", "last_login", "avatar"}).
+		AddRow(id, username, email, password, status, createdAt, updatedAt, lastLogin, "")
 }
 
 func TestFindByEmail(t *testing.T) {
@@ -410,7 +555,7 @@ func TestFindByID(t *testing.T) {
 					WithArgs(testUserID).
 					WillReturnError(sql.ErrNoRows)
 			},
-			expectErr: "not found",
+			expectErr: ErrNotFound.Error(),
 			expectNil: true,
 		},
 		{
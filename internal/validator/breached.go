@@ -0,0 +1,72 @@
+package validator
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const breachPrefixLength = 5
+
+type BreachChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+type RangeDirectory struct {
+	dir string
+}
+
+func NewRangeDirectory(dir string) *RangeDirectory {
+	return &RangeDirectory{dir: dir}
+}
+
+func (d *RangeDirectory) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:breachPrefixLength], hash[breachPrefixLength:]
+
+	file, err := d.open(prefix)
+
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to open breached passwords: %w", err)
+	}
+
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		lineSuffix, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+
+		// Padded copies of the range files contain suffixes with a count of zero.
+		if strings.EqualFold(lineSuffix, suffix) && count != "0" {
+			return true, nil
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read breached passwords: %w", err)
+	}
+
+	return false, nil
+}
+
+func (d *RangeDirectory) open(prefix string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(d.dir, prefix+".txt"))
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.Open(filepath.Join(d.dir, prefix))
+	}
+
+	return file, err
+}
@@ -0,0 +1,100 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The SHA-1 hash of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8.
+const (
+	breachedPrefix = "5BAA6"
+	breachedSuffix = "1E4C9B93F3F0682250B6CF8331B7EE68FD8"
+)
+
+func writeRangeFile(t *testing.T, dir, name string, lines ...string) {
+	err := os.WriteFile(filepath.Join(dir, name), []byte(strings.Join(lines, "\r\n")), 0o600)
+	assert.NoError(t, err)
+}
+
+func TestRangeDirectoryIsBreached(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T, dir string)
+		password string
+		want     bool
+		wantErr  bool
+	}{
+		{
+			name: "breached",
+			setup: func(t *testing.T, dir string) {
+				writeRangeFile(t, dir, breachedPrefix+".txt", "003D68EB55068C33ACE09247EE4C639306B:3", breachedSuffix+":52256179")
+			},
+			password: "password",
+			want:     true,
+		},
+		{
+			name: "file without an extension",
+			setup: func(t *testing.T, dir string) {
+				writeRangeFile(t, dir, breachedPrefix, strings.ToLower(breachedSuffix)+":1")
+			},
+			password: "password",
+			want:     true,
+		},
+		{
+			name: "padding entry",
+			setup: func(t *testing.T, dir string) {
+				writeRangeFile(t, dir, breachedPrefix+".txt", breachedSuffix+":0")
+			},
+			password: "password",
+		},
+		{
+			name: "not in the file",
+			setup: func(t *testing.T, dir string) {
+				writeRangeFile(t, dir, breachedPrefix+".txt", "003D68EB55068C33ACE09247EE4C639306B:3")
+			},
+			password: "password",
+		},
+		{
+			name:     "no file for the prefix",
+			setup:    func(t *testing.T, dir string) {},
+			password: "password",
+		},
+		{
+			name: "unreadable file",
+			setup: func(t *testing.T, dir string) {
+				assert.NoError(t, os.Mkdir(filepath.Join(dir, breachedPrefix+".txt"), 0o700))
+			},
+			password: "password",
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tc.setup(t, dir)
+
+			breached, err := NewRangeDirectory(dir).IsBreached(tc.password)
+
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.want, breached)
+		})
+	}
+}
+
+func TestRangeDirectoryOpenError(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "file")
+	writeRangeFile(t, filepath.Dir(dir), "file", "")
+
+	_, err := NewRangeDirectory(dir).IsBreached("password")
+	assert.Error(t, err)
+}
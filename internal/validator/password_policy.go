@@ -0,0 +1,168 @@
+package validator
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+)
+
+const (
+	msgPasswordGuessable = "This password is too easy to guess. Make it longer, or mix in other kinds of characters"
+	msgPasswordCommon    = "This password is too common. Please choose another one"
+	msgPasswordPersonal  = "Passwords must not contain your username or email address"
+	msgPasswordBreached  = "This password has appeared in a data breach. Please choose another one"
+
+	PasswordMinEntropy = 40
+
+	// Usernames and email addresses that are shorter than this are not
+	// looked for in passwords, since they would match too easily.
+	passwordContextMinLength = 3
+)
+
+//go:embed data/common_passwords.txt
+var commonPasswordsFile []byte
+
+var commonPasswords = parseCommonPasswords(commonPasswordsFile)
+
+var newBreachChecker = func() BreachChecker {
+	dir := config.GetPassword().BreachedPasswords
+
+	if dir == "" {
+		return nil
+	}
+
+	return NewRangeDirectory(dir)
+}
+
+type PasswordContext struct {
+	Username string
+	Email    string
+}
+
+func parseCommonPasswords(file []byte) map[string]bool {
+	passwords := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(file))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		passwords[strings.ToLower(line)] = true
+	}
+
+	return passwords
+}
+
+func (v *Validator) PasswordPolicy(field, value string, context PasswordContext) {
+	errorCount := len(v.fieldErrors[field])
+	v.StrongPassword(field, value)
+
+	if len(v.fieldErrors[field]) > errorCount {
+		return
+	}
+
+	lower := strings.ToLower(value)
+
+	switch {
+	case commonPasswords[lower]:
+		v.AddFieldError(field, msgPasswordCommon)
+
+	case containsPersonalInfo(lower, context):
+		v.AddFieldError(field, msgPasswordPersonal)
+
+	case passwordEntropy(value) < PasswordMinEntropy:
+		v.AddFieldError(field, msgPasswordGuessable)
+
+	case isBreached(value):
+		v.AddFieldError(field, msgPasswordBreached)
+	}
+}
+
+func containsPersonalInfo(password string, context PasswordContext) bool {
+	email := strings.ToLower(strings.TrimSpace(context.Email))
+	localPart, _, _ := strings.Cut(email, "@")
+
+	for _, value := range []string{strings.ToLower(strings.TrimSpace(context.Username)), localPart} {
+		if len(value) >= passwordContextMinLength && strings.Contains(password, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// passwordEntropy estimates the strength of a password in bits, from the kinds
+// of characters it uses. A character that repeats the one before it is not counted,
+// so that padding a password with the same character does not make it stronger.
+func passwordEntropy(password string) float64 {
+	var hasLower, hasUpper, hasDigit, hasSymbol, hasOther bool
+	var length int
+	var previous rune = -1
+
+	for _, r := range password {
+		switch {
+		case r > unicode.MaxASCII:
+			hasOther = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+
+		if r != previous {
+			length++
+		}
+
+		previous = r
+	}
+
+	pool := 0
+
+	for _, class := range []struct {
+		used bool
+		size int
+	}{
+		{hasLower, 26},
+		{hasUpper, 26},
+		{hasDigit, 10},
+		{hasSymbol, 33},
+		{hasOther, 100},
+	} {
+		if class.used {
+			pool += class.size
+		}
+	}
+
+	if pool == 0 {
+		return 0
+	}
+
+	return float64(length) * math.Log2(float64(pool))
+}
+
+// isBreached looks the password up in the breached passwords, when they are enabled.
+// The check is skipped when the files cannot be read, so that a missing copy
+// does not keep people from signing up.
+func isBreached(password string) bool {
+	checker := newBreachChecker()
+
+	if checker == nil {
+		return false
+	}
+
+	breached, err := checker.IsBreached(password)
+
+	return err == nil && breached
+}
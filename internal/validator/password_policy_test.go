@@ -0,0 +1,111 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Dobefu/go-web-starter/internal/config"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockBreachChecker struct {
+	breached bool
+	err      error
+}
+
+func (m *mockBreachChecker) IsBreached(string) (bool, error) {
+	return m.breached, m.err
+}
+
+func patchBreachChecker(t *testing.T, checker BreachChecker) {
+	newBreachCheckerOrig := newBreachChecker
+
+	t.Cleanup(func() { newBreachChecker = newBreachCheckerOrig })
+	newBreachChecker = func() BreachChecker { return checker }
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	context := PasswordContext{Username: "johnny", Email: "j.doe@example.com"}
+
+	cases := []struct {
+		name     string
+		password string
+		checker  BreachChecker
+		expected string
+	}{
+		{"valid password", "correct-horse-9", nil, ""},
+		{"too short", "pass1", nil, fmt.Sprintf(msgPasswordLength, PasswordMinLength, PasswordMaxLength)},
+		{"letters only", "correcthorse", nil, msgPasswordWeak},
+		{"common password", "Password123", nil, msgPasswordCommon},
+		{"contains the username", "Johnny-2024!", nil, msgPasswordPersonal},
+		{"contains the email address", "j.doe@example.com1", nil, msgPasswordPersonal},
+		{"repeated characters", "aaaaaaaaaaa1", nil, msgPasswordGuessable},
+		{"breached", "correct-horse-9", &mockBreachChecker{breached: true}, msgPasswordBreached},
+		{"breach check error", "correct-horse-9", &mockBreachChecker{breached: true, err: errors.New("read error")}, ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			patchBreachChecker(t, tc.checker)
+
+			v := New()
+			v.PasswordPolicy("password", tc.password, context)
+
+			if tc.expected == "" {
+				assert.True(t, v.isValid)
+				assert.Empty(t, v.fieldErrors["password"])
+			} else {
+				assert.False(t, v.isValid)
+				assert.Equal(t, []string{tc.expected}, v.fieldErrors["password"])
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyKeepsEarlierErrors(t *testing.T) {
+	v := New()
+	v.AddFieldError("password", msgFieldRequired)
+	v.PasswordPolicy("password", "Password123", PasswordContext{})
+
+	assert.Equal(t, []string{msgFieldRequired, msgPasswordCommon}, v.fieldErrors["password"])
+}
+
+func TestContainsPersonalInfo(t *testing.T) {
+	context := PasswordContext{Username: " Jo ", Email: "mail@example.com"}
+
+	assert.False(t, containsPersonalInfo("jo-secret-1", context))
+	assert.True(t, containsPersonalInfo("my-mail-1", context))
+	assert.False(t, containsPersonalInfo("anything", PasswordContext{}))
+}
+
+func TestPasswordEntropy(t *testing.T) {
+	assert.Zero(t, passwordEntropy(""))
+	assert.InDelta(t, 8*5.17, passwordEntropy("abcdefg1"), 0.1)
+	assert.InDelta(t, 2*5.17, passwordEntropy("aaaaaaa1"), 0.1)
+	assert.Greater(t, passwordEntropy("Correct-Horse-9"), passwordEntropy("correct-horse-9"))
+	assert.Greater(t, passwordEntropy("wachtwoord-ü"), passwordEntropy("wachtwoord-u"))
+}
+
+func TestCommonPasswords(t *testing.T) {
+	passwords := parseCommonPasswords([]byte("# comment\n\nPassword1\n  qwerty123  \n"))
+
+	assert.Equal(t, map[string]bool{"password1": true, "qwerty123": true}, passwords)
+	assert.NotEmpty(t, commonPasswords)
+
+	for password := range commonPasswords {
+		assert.Equal(t, strings.ToLower(password), password)
+	}
+}
+
+func TestNewBreachChecker(t *testing.T) {
+	defer viper.Set("password.breachedpasswords", config.DefaultConfig.Password.BreachedPasswords)
+
+	viper.Set("password.breachedpasswords", "")
+	assert.Nil(t, newBreachChecker())
+
+	viper.Set("password.breachedpasswords", t.TempDir())
+	assert.IsType(t, &RangeDirectory{}, newBreachChecker())
+}
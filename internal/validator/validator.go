@@ -3,9 +3,13 @@ package validator
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/mail"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/Dobefu/go-web-starter/internal/message"
@@ -19,10 +23,20 @@ const (
 
 	msgFieldRequired  = "This field is required"
 	msgFormProcessing = "Failed to process form data"
+	msgJSONProcessing = "The request body must be a valid JSON object"
 	msgMinLength      = "This field must be at least %d characters long"
 	msgMaxLength      = "This field must be no more than %d characters long"
 	msgEmailInvalid   = "This is not a valid email address"
 	msgPasswordsMatch = "The passwords do not match"
+	msgPasswordLength = "Passwords must be between %d and %d characters long"
+	msgPasswordWeak   = "Passwords must contain letters as well as numbers or symbols"
+	msgFileTooLarge   = "The file must be no larger than %d MB"
+
+	PasswordMinLength = 8
+	PasswordMaxLength = 1024
+
+	JSONMaxBytes  = 1 << 20
+	FormMaxMemory = 8 << 20
 )
 
 type Validator struct {
@@ -153,8 +167,37 @@ func (v *Validator) PasswordsMatch(field, password1 string, password2 string) {
 	v.CheckField(password1 == password2, field, msgPasswordsMatch)
 }
 
+func (v *Validator) StrongPassword(field, value string) {
+	length := utf8.RuneCountInString(value)
+
+	if length < PasswordMinLength || length > PasswordMaxLength {
+		v.AddFieldError(field, fmt.Sprintf(msgPasswordLength, PasswordMinLength, PasswordMaxLength))
+		return
+	}
+
+	hasLetter := strings.IndexFunc(value, unicode.IsLetter) >= 0
+	hasOther := strings.IndexFunc(value, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsSpace(r) }) >= 0
+
+	v.CheckField(hasLetter && hasOther, field, msgPasswordWeak)
+}
+
+func (v *Validator) RequiredFile(field string, file *multipart.FileHeader) {
+	v.CheckField(file != nil && file.Size > 0, field, msgFieldRequired)
+}
+
+func (v *Validator) MaxFileSize(field string, file *multipart.FileHeader, max int64) {
+	v.CheckField(file == nil || file.Size <= max, field, fmt.Sprintf(msgFileTooLarge, max>>20))
+}
+
 func (v *Validator) ValidateForm(r *http.Request) error {
-	err := r.ParseForm()
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if mediaType == "multipart/form-data" {
+		err = r.ParseMultipartForm(FormMaxMemory)
+	} else {
+		err = r.ParseForm()
+	}
 
 	if err != nil {
 		v.AddFormError(msgFormProcessing)
@@ -164,6 +207,20 @@ func (v *Validator) ValidateForm(r *http.Request) error {
 	return nil
 }
 
+func (v *Validator) ValidateJSON(r *http.Request, dest any) error {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, JSONMaxBytes))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(dest)
+
+	if err != nil {
+		v.AddFormError(msgJSONProcessing)
+		return err
+	}
+
+	return nil
+}
+
 func (v *Validator) GetFormValue(r *http.Request, field string) string {
 	rawInput := r.FormValue(field)
 	var b strings.Builder
@@ -180,6 +237,14 @@ func (v *Validator) GetFormValue(r *http.Request, field string) string {
 	return b.String()
 }
 
+func (v *Validator) GetFormFile(r *http.Request, field string) *multipart.FileHeader {
+	if r.MultipartForm == nil || len(r.MultipartForm.File[field]) == 0 {
+		return nil
+	}
+
+	return r.MultipartForm.File[field][0]
+}
+
 func (v *Validator) SetFlash(msg message.Message) {
 	if v.context == nil {
 		return
@@ -1,7 +1,9 @@
 package validator
 
 import (
+	"bytes"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -218,6 +220,39 @@ func TestPasswordsMatch(t *testing.T) {
 	assert.Empty(t, v.fieldErrors)
 }
 
+func TestStrongPassword(t *testing.T) {
+	cases := []struct {
+		name     string
+		password string
+		expected string
+	}{
+		{"valid password", "password123", ""},
+		{"symbols", "correct-horse", ""},
+		{"too short", "pass1", fmt.Sprintf(msgPasswordLength, PasswordMinLength, PasswordMaxLength)},
+		{"too long", strings.Repeat("a1", 513), fmt.Sprintf(msgPasswordLength, PasswordMinLength, PasswordMaxLength)},
+		{"too short in characters", "pässwö1", fmt.Sprintf(msgPasswordLength, PasswordMinLength, PasswordMaxLength)},
+		{"long passphrase", strings.Repeat("correct horse battery staple ", 4) + "1", ""},
+		{"letters only", "password", msgPasswordWeak},
+		{"numbers only", "12345678", msgPasswordWeak},
+		{"letters and spaces", "pass word", msgPasswordWeak},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := New()
+			v.StrongPassword("password", tc.password)
+
+			if tc.expected == "" {
+				assert.True(t, v.isValid)
+				assert.Empty(t, v.fieldErrors["password"])
+			} else {
+				assert.False(t, v.isValid)
+				assert.Equal(t, []string{tc.expected}, v.fieldErrors["password"])
+			}
+		})
+	}
+}
+
 func TestValidateForm(t *testing.T) {
 	v := New()
 
@@ -233,6 +268,114 @@ func TestValidateForm(t *testing.T) {
 	assert.Contains(t, v.formErrors, msgFormProcessing)
 }
 
+func newMultipartRequest(t *testing.T, fields map[string]string, files map[string]string) *http.Request {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for name, value := range fields {
+		assert.NoError(t, writer.WriteField(name, value))
+	}
+
+	for name, content := range files {
+		part, err := writer.CreateFormFile(name, name+".png")
+		assert.NoError(t, err)
+
+		_, err = part.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, writer.Close())
+
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return req
+}
+
+func TestValidateFormMultipart(t *testing.T) {
+	v := New()
+
+	req := newMultipartRequest(t, map[string]string{"action": "upload"}, map[string]string{"avatar": "image"})
+	err := v.ValidateForm(req)
+	assert.NoError(t, err)
+	assert.True(t, v.isValid)
+	assert.Equal(t, "upload", v.GetFormValue(req, "action"))
+
+	file := v.GetFormFile(req, "avatar")
+	assert.NotNil(t, file)
+	assert.Equal(t, int64(5), file.Size)
+	assert.Nil(t, v.GetFormFile(req, "other"))
+
+	req = httptest.NewRequest("POST", "/", strings.NewReader("--missing--"))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=boundary")
+	err = v.ValidateForm(req)
+	assert.Error(t, err)
+	assert.Contains(t, v.formErrors, msgFormProcessing)
+
+	req = httptest.NewRequest("POST", "/", nil)
+	assert.Nil(t, v.GetFormFile(req, "avatar"))
+}
+
+func TestFileChecks(t *testing.T) {
+	testCases := []struct {
+		name         string
+		file         *multipart.FileHeader
+		expectErrors []string
+	}{
+		{name: "valid", file: &multipart.FileHeader{Size: 1 << 20}},
+		{name: "missing", expectErrors: []string{msgFieldRequired}},
+		{name: "empty", file: &multipart.FileHeader{}, expectErrors: []string{msgFieldRequired}},
+		{name: "too large", file: &multipart.FileHeader{Size: 2<<20 + 1}, expectErrors: []string{"The file must be no larger than 2 MB"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := New()
+			v.RequiredFile("file", tc.file)
+			v.MaxFileSize("file", tc.file, 2<<20)
+
+			assert.Equal(t, tc.expectErrors, v.GetFieldErrors()["file"])
+		})
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	var dest struct {
+		Email string `json:"email"`
+	}
+
+	cases := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"valid object", `{"email":"test@example.com"}`, false},
+		{"empty body", ``, true},
+		{"invalid JSON", `{"email":`, true},
+		{"unknown field", `{"mail":"test@example.com"}`, true},
+		{"wrong type", `{"email":1}`, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := New()
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tc.body))
+			err := v.ValidateJSON(req, &dest)
+
+			if tc.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, v.formErrors, msgJSONProcessing)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, v.isValid)
+				assert.Equal(t, "test@example.com", dest.Email)
+			}
+		})
+	}
+}
+
 func TestGetFormValue(t *testing.T) {
 	v := New()
 	cases := []struct {